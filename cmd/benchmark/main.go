@@ -33,6 +33,7 @@ import (
 	claudevision "github.com/vbonduro/kitchinv/internal/vision/claude"
 	geminivision "github.com/vbonduro/kitchinv/internal/vision/gemini"
 	ollamavision "github.com/vbonduro/kitchinv/internal/vision/ollama"
+	openaivision "github.com/vbonduro/kitchinv/internal/vision/openai"
 )
 
 // fileURICache maps fixture name to Gemini File API URI (expires after 48h).
@@ -288,14 +289,19 @@ func newAnalyzer(cfg *config.Config) (vision.VisionAnalyzer, string, error) {
 		if cfg.ClaudeAPIKey == "" {
 			return nil, "", fmt.Errorf("CLAUDE_API_KEY must be set when VISION_BACKEND=claude")
 		}
-		return claudevision.NewClaudeAnalyzer(cfg.ClaudeAPIKey, cfg.ClaudeModel), cfg.ClaudeModel, nil
+		return claudevision.NewClaudeAnalyzer(cfg.ClaudeAPIKey, cfg.ClaudeModel).WithPrompt(cfg.VisionPrompt).WithMaxTokens(cfg.ClaudeMaxTokens).WithPromptCaching(cfg.ClaudePromptCache).WithExtraction(cfg.ClaudeExtraction).WithStream(cfg.ClaudeStream), cfg.ClaudeModel, nil
 	case "gemini":
 		if cfg.GeminiAPIKey == "" {
 			return nil, "", fmt.Errorf("GEMINI_API_KEY must be set when VISION_BACKEND=gemini")
 		}
-		return geminivision.NewGeminiAnalyzer(cfg.GeminiAPIKey, cfg.GeminiModel), cfg.GeminiModel, nil
+		return geminivision.NewGeminiAnalyzer(cfg.GeminiAPIKey, cfg.GeminiModel).WithPrompt(cfg.VisionPrompt), cfg.GeminiModel, nil
+	case "openai-compatible":
+		if cfg.OpenAIModel == "" {
+			return nil, "", fmt.Errorf("OPENAI_MODEL must be set when VISION_BACKEND=openai-compatible")
+		}
+		return openaivision.NewOpenAIAnalyzer(cfg.OpenAIBaseURL, cfg.OpenAIModel, cfg.OpenAIAPIKey).WithPrompt(cfg.VisionPrompt).WithStream(cfg.OpenAIStream), cfg.OpenAIModel, nil
 	default:
-		return ollamavision.NewOllamaAnalyzer(cfg.OllamaHost, cfg.OllamaModel), cfg.OllamaModel, nil
+		return ollamavision.NewOllamaAnalyzer(cfg.OllamaHost, cfg.OllamaModel).WithPrompt(cfg.VisionPrompt).WithAPI(cfg.OllamaAPI), cfg.OllamaModel, nil
 	}
 }
 
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/vbonduro/kitchinv/internal/audit"
+	"github.com/vbonduro/kitchinv/internal/config"
+	"github.com/vbonduro/kitchinv/internal/db"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/logging"
+	"github.com/vbonduro/kitchinv/internal/openfoodfacts"
+	"github.com/vbonduro/kitchinv/internal/photostore"
+	"github.com/vbonduro/kitchinv/internal/photostore/local"
+	"github.com/vbonduro/kitchinv/internal/photostore/memory"
+	"github.com/vbonduro/kitchinv/internal/service"
+	"github.com/vbonduro/kitchinv/internal/store"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+// commonFlags are accepted by every command and override the matching
+// environment variables.
+type commonFlags struct {
+	configFile string
+	dbPath     string
+	photoPath  string
+	logLevel   string
+}
+
+func (f *commonFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.configFile, "config", "", "YAML or TOML config file (overrides CONFIG_FILE)")
+	fs.StringVar(&f.dbPath, "db", "", "SQLite database path (overrides DB_PATH)")
+	fs.StringVar(&f.photoPath, "photos", "", "photo directory (overrides PHOTO_LOCAL_PATH)")
+	fs.StringVar(&f.logLevel, "log-level", "", "debug, info, warn or error (overrides LOG_LEVEL)")
+}
+
+// loadConfig loads the configuration with the flags applied over it and
+// exits listing every problem if it is invalid.
+func loadConfig(f commonFlags) *config.Config {
+	if f.configFile != "" {
+		// Load reads CONFIG_FILE itself, so the flag has to go through it.
+		_ = os.Setenv("CONFIG_FILE", f.configFile)
+	}
+	cfg := config.Load()
+	if f.dbPath != "" {
+		cfg.DBPath = f.dbPath
+	}
+	if f.photoPath != "" {
+		cfg.PhotoPath = f.photoPath
+	}
+	if f.logLevel != "" {
+		cfg.LogLevel = f.logLevel
+	}
+	if errs := cfg.Validate(); len(errs) > 0 {
+		fmt.Fprintln(os.Stderr, "kitchinv: invalid configuration:")
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, "  -", err)
+		}
+		os.Exit(1)
+	}
+	return cfg
+}
+
+// newLogger creates the logger from cfg and makes it the default. ring, if
+// not nil, also keeps recent warnings and errors.
+func newLogger(cfg *config.Config, ring *logging.Ring) (*slog.Logger, func()) {
+	logger, cleanup, err := logging.New(logging.Options{
+		Level:  cfg.LogLevel,
+		Format: cfg.LogFormat,
+		Source: cfg.LogSource,
+		File:   cfg.LogFile,
+		Rotate: logging.RotateOptions{
+			MaxSize:    cfg.LogMaxSize,
+			MaxBackups: cfg.LogMaxBackups,
+			MaxAge:     cfg.LogMaxAge,
+			Compress:   cfg.LogCompress,
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kitchinv: failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	if ring != nil {
+		logger = slog.New(ring.Handler(logger.Handler()))
+	}
+	slog.SetDefault(logger)
+	for _, w := range config.UnknownEnv(os.Environ()) {
+		logger.Warn(w)
+	}
+	return logger, cleanup
+}
+
+// openDB opens the database in cfg. Migrations are applied unless
+// skipMigrations is set.
+func openDB(cfg *config.Config, skipMigrations bool) (*sql.DB, error) {
+	return db.Open(cfg.DBPath, db.Options{
+		BusyTimeout:    cfg.DBBusyTimeout,
+		Synchronous:    cfg.DBSynchronous,
+		MaxOpenConns:   cfg.DBMaxOpenConns,
+		MaxIdleConns:   cfg.DBMaxIdleConns,
+		SkipMigrations: skipMigrations,
+	})
+}
+
+// app is what the commands that touch the inventory share: the database,
+// photo store, vision backend and the service wired from them.
+type app struct {
+	db       *sql.DB
+	photoStg photostore.PhotoStore
+	analyzer vision.VisionAnalyzer
+	service  *service.AreaService
+	logger   *slog.Logger
+	// stores hold prepared statements to close before the database.
+	stores []io.Closer
+}
+
+// openApp opens the database and photo store and wires the service as cfg
+// configures it. Background work such as the purge loop is left to the
+// caller. Close the app when done.
+func openApp(cfg *config.Config, logger *slog.Logger) (*app, error) {
+	if cfg.DemoMode {
+		// Demo uploads must never reach a paid or local model.
+		cfg.VisionBackend = "fake"
+	}
+	visionAnalyzer, err := newVisionAnalyzer(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("vision backend misconfigured: %w", err)
+	}
+	shelfLife, err := service.ParseShelfLife(cfg.ShelfLife)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHELF_LIFE: %w", err)
+	}
+	askPrompt, err := service.ParseAskPrompt(cfg.AskPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ASK_PROMPT: %w", err)
+	}
+	photoStg, err := newPhotoStore(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize photo store: %w", err)
+	}
+	database, err := openDB(cfg, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	areaStore := store.NewAreaStore(database)
+	photoStore := store.NewPhotoStore(database)
+	itemStore := store.NewItemStore(database)
+	itemEditStore := store.NewItemEditStore(database)
+	snapshotStore := store.NewSnapshotStore(database)
+	overrideStore := store.NewOverrideStore(database)
+
+	areaService := service.NewAreaService(areaStore, photoStore, itemStore, itemEditStore, snapshotStore, overrideStore, visionAnalyzer, photoStg, logger).
+		WithDB(database).
+		WithAttentionWeights(service.AttentionWeights{
+			StalePerDay:  cfg.AttentionStalePerDay,
+			StaleMaxDays: cfg.AttentionStaleMaxDays,
+			NoPhoto:      cfg.AttentionNoPhoto,
+			Empty:        cfg.AttentionEmpty,
+			OutOfStock:   cfg.AttentionOutOfStock,
+		}).
+		WithAreaRetention(cfg.AreaRetention).
+		WithStaleAfter(cfg.StalePhotoAfter).
+		WithMaxConcurrentAnalyses(cfg.MaxConcurrentAnalyses).
+		WithAnalysisTimeout(cfg.VisionTimeout).
+		WithKeepItemsOnEmpty(cfg.KeepItemsOnEmptyAnalysis).
+		WithIgnoreStore(store.NewIgnoreStore(database)).
+		WithStapleStore(store.NewStapleStore(database)).
+		WithAuditLog(audit.NewStore(database)).
+		WithWebhooks(store.NewWebhookStore(database)).
+		WithExpiryEvents(store.NewExpiryEventStore(database)).
+		WithShelfLife(shelfLife).
+		WithSuggestPrompt(cfg.SuggestPrompt).
+		WithSuggestMaxItems(cfg.SuggestMaxItems).
+		WithItemLimits(itemLimits(cfg)).
+		WithAskPrompt(askPrompt).
+		WithHistory(store.NewHistoryStore(database), cfg.HistoryMaxPerArea)
+	if cfg.IgnoreItemsEnabled {
+		areaService.WithIgnoreList(cfg.IgnoreItems)
+	}
+	if cfg.OpenFoodFactsURL != "" {
+		areaService.WithBarcodeLookup(openfoodfacts.NewClient(cfg.OpenFoodFactsURL), store.NewBarcodeStore(database))
+	}
+	if cfg.VisionBackend == "claude" {
+		areaService.WithTokenPrices(service.TokenPrices{
+			InputPerMTok:  cfg.ClaudeInputCostPerMTok,
+			OutputPerMTok: cfg.ClaudeOutputCostPerMTok,
+		})
+	}
+	return &app{db: database, photoStg: photoStg, analyzer: visionAnalyzer, service: areaService, logger: logger,
+		stores: []io.Closer{areaStore, photoStore, itemStore}}, nil
+}
+
+// itemLimits returns the configured item name and quantity limits, shared
+// by the handlers that reject long text and the service that cuts it.
+func itemLimits(cfg *config.Config) domain.ItemLimits {
+	return domain.ItemLimits{Name: cfg.ItemNameMaxLength, Quantity: cfg.ItemQuantityMaxLength}
+}
+
+// newPhotoStore creates the PHOTO_BACKEND store.
+func newPhotoStore(cfg *config.Config, logger *slog.Logger) (photostore.PhotoStore, error) {
+	if cfg.PhotoBackend == "memory" {
+		logger.Warn("photos are kept in memory and lost when kitchinv stops")
+		return memory.New(), nil
+	}
+	return local.NewLocalPhotoStore(cfg.PhotoPath)
+}
+
+// Close waits for the service's webhook deliveries, then closes the stores
+// and the database.
+func (a *app) Close() {
+	a.service.Close()
+	for _, s := range a.stores {
+		if err := s.Close(); err != nil {
+			a.logger.Error("failed to close store", "error", err)
+		}
+	}
+	if err := db.Close(a.db); err != nil {
+		a.logger.Error("failed to close database", "error", err)
+	}
+}
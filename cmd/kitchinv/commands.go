@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/db"
+	"github.com/vbonduro/kitchinv/internal/service"
+)
+
+// runMigrate applies every pending migration ("up", the default), reverts
+// the latest ("down"), or lists them all ("status").
+func runMigrate(args []string) error {
+	var common commonFlags
+	fs := newFlagSet("migrate", &common)
+	positional := parseArgs(fs, args)
+	action := "up"
+	if len(positional) > 0 {
+		action = positional[0]
+	}
+	if len(positional) > 1 || (action != "up" && action != "down" && action != "status") {
+		return errors.New("usage: kitchinv migrate [up|down|status]")
+	}
+	cfg := loadConfig(common)
+	logger, cleanup := newLogger(cfg, nil)
+	defer cleanup()
+
+	database, err := openDB(cfg, true)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := db.Close(database); err != nil {
+			logger.Error("failed to close database", "error", err)
+		}
+	}()
+
+	switch action {
+	case "down":
+		m, err := db.MigrateDown(database)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("reverted %06d_%s\n", m.Version, m.Name)
+		return nil
+	case "up":
+		if err := db.Migrate(database); err != nil {
+			return err
+		}
+	}
+	status, err := db.Migrations(database)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, m := range status {
+		state := "pending"
+		if m.Applied {
+			state = "applied"
+		}
+		fmt.Fprintf(tw, "%06d\t%s\t%s\n", m.Version, m.Name, state)
+	}
+	return tw.Flush()
+}
+
+// runExport writes the inventory as JSON, which import reads back, or as
+// the Markdown report.
+func runExport(args []string) (err error) {
+	var common commonFlags
+	fs := newFlagSet("export", &common)
+	format := fs.String("format", "json", "json or markdown")
+	out := fs.String("out", "-", "file to write, or - for stdout")
+	includeEmpty := fs.Bool("include-empty", false, "list areas without items in the Markdown report")
+	if len(parseArgs(fs, args)) > 0 {
+		return errors.New("usage: kitchinv export [-format=json|markdown] [-out=file]")
+	}
+	if *format != "json" && *format != "markdown" {
+		return fmt.Errorf("unknown format %q; use json or markdown", *format)
+	}
+	cfg := loadConfig(common)
+	logger, cleanup := newLogger(cfg, nil)
+	defer cleanup()
+	a, err := openApp(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	w := io.Writer(os.Stdout)
+	if *out != "-" {
+		f, createErr := os.Create(*out)
+		if createErr != nil {
+			return createErr
+		}
+		// A failed close can mean the export never reached the disk.
+		defer func() {
+			if cerr := f.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}()
+		w = f
+	}
+	ctx := context.Background()
+	if *format == "markdown" {
+		return a.service.WriteMarkdownReport(ctx, w, time.Now(), *includeEmpty)
+	}
+	inv, err := a.service.ExportInventory(ctx, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(inv)
+}
+
+// runImport adds the areas and items of a JSON export; see
+// service.ImportInventory.
+func runImport(args []string) error {
+	var common commonFlags
+	fs := newFlagSet("import", &common)
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 {
+		return errors.New("usage: kitchinv import file (or - for stdin)")
+	}
+	cfg := loadConfig(common)
+	logger, cleanup := newLogger(cfg, nil)
+	defer cleanup()
+
+	r := io.Reader(os.Stdin)
+	if path := positional[0]; path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		r = f
+	}
+	var inv service.InventoryExport
+	if err := json.NewDecoder(r).Decode(&inv); err != nil {
+		return fmt.Errorf("failed to read export: %w", err)
+	}
+
+	a, err := openApp(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+	res, err := a.service.ImportInventory(context.Background(), &inv)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created %d areas and %d items\n", res.AreasCreated, res.ItemsCreated)
+	return nil
+}
+
+// runAnalyze uploads a photo to an area as the web UI would, running the
+// configured vision backend, and prints the area's items afterwards.
+func runAnalyze(args []string) error {
+	var common commonFlags
+	fs := newFlagSet("analyze", &common)
+	areaID := fs.Int64("area", 0, "ID of the area the photo is of")
+	image := fs.String("image", "", "photo to analyse")
+	if len(parseArgs(fs, args)) > 0 || *areaID <= 0 || *image == "" {
+		return errors.New("usage: kitchinv analyze -area=ID -image=path")
+	}
+	data, err := os.ReadFile(*image)
+	if err != nil {
+		return err
+	}
+	cfg := loadConfig(common)
+	logger, cleanup := newLogger(cfg, nil)
+	defer cleanup()
+	a, err := openApp(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	ctx := context.Background()
+	area, err := a.service.GetArea(ctx, *areaID)
+	if err != nil {
+		return err
+	}
+	if area == nil {
+		return fmt.Errorf("area %d not found", *areaID)
+	}
+	photo, items, err := a.service.UploadPhoto(ctx, area.ID, data, http.DetectContentType(data))
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s: %d items\n", area.Path(), len(items))
+	if photo != nil && photo.Truncated {
+		fmt.Fprintln(tw, "analysis may be incomplete: the model stopped at its output limit; re-run with higher limits")
+	}
+	if photo != nil && photo.ItemsKept {
+		fmt.Fprintln(tw, "nothing was detected in the photo; the previous items were kept")
+	}
+	for _, item := range items {
+		fmt.Fprintf(tw, "%s\t%s\n", item.Name, item.Quantity)
+	}
+	return tw.Flush()
+}
@@ -1,71 +1,304 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
 	"log/slog"
+	"maps"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/vbonduro/kitchinv/internal/config"
-	"github.com/vbonduro/kitchinv/internal/db"
+	"github.com/vbonduro/kitchinv/internal/export"
 	"github.com/vbonduro/kitchinv/internal/logging"
-	"github.com/vbonduro/kitchinv/internal/photostore/local"
+	"github.com/vbonduro/kitchinv/internal/mqtt"
 	"github.com/vbonduro/kitchinv/internal/service"
-	"github.com/vbonduro/kitchinv/internal/store"
+	"github.com/vbonduro/kitchinv/internal/tracing"
 	"github.com/vbonduro/kitchinv/internal/vision"
 	claudevision "github.com/vbonduro/kitchinv/internal/vision/claude"
+	fakevision "github.com/vbonduro/kitchinv/internal/vision/fake"
 	geminivision "github.com/vbonduro/kitchinv/internal/vision/gemini"
 	ollamavision "github.com/vbonduro/kitchinv/internal/vision/ollama"
+	openaivision "github.com/vbonduro/kitchinv/internal/vision/openai"
 	"github.com/vbonduro/kitchinv/internal/web"
 	"github.com/vbonduro/kitchinv/internal/web/templates"
 )
 
-func main() {
-	cfg := config.Load()
+// adminErrorCount is how many recent warnings and errors the admin page
+// shows.
+const adminErrorCount = 50
+
+const usage = `Usage: kitchinv [command] [flags]
+
+Commands:
+  serve      run the web server (the default)
+  migrate    apply, revert or list database migrations: migrate [up|down|status]
+  export     write the inventory out: export [-format=json|markdown] [-out=file]
+  import     add areas and items from a JSON export: import file
+  analyze    analyse a photo of an area without the server: analyze -area=ID -image=path
 
-	logger, cleanup, err := logging.New(cfg.LogLevel, cfg.LogFile)
+Every command reads the same environment variables as the server; flags
+override them. Run "kitchinv <command> -h" for a command's flags.
+`
+
+func main() {
+	cmd, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	var err error
+	switch cmd {
+	case "serve":
+		err = runServe(args)
+	case "migrate":
+		err = runMigrate(args)
+	case "export":
+		err = runExport(args)
+	case "import":
+		err = runImport(args)
+	case "analyze":
+		err = runAnalyze(args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "kitchinv: unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
 	if err != nil {
-		log.Fatalf("failed to initialize logger: %v", err)
+		fmt.Fprintf(os.Stderr, "kitchinv %s: %v\n", cmd, err)
+		os.Exit(1)
+	}
+}
+
+// newFlagSet returns a flag set for cmd with the common flags registered.
+func newFlagSet(cmd string, common *commonFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet("kitchinv "+cmd, flag.ExitOnError)
+	common.register(fs)
+	return fs
+}
+
+// parseArgs parses args with fs and returns the positional arguments. Unlike
+// fs.Parse it keeps reading flags after them, so both
+// "migrate status -db=x" and "migrate -db=x status" work.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		_ = fs.Parse(args) // ExitOnError
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// runServe runs the web server until it fails.
+func runServe(args []string) error {
+	var common commonFlags
+	fs := newFlagSet("serve", &common)
+	listen := fs.String("listen", "", "address to listen on (overrides LISTEN_ADDR)")
+	if len(parseArgs(fs, args)) > 0 {
+		return errors.New("usage: kitchinv serve [flags]")
+	}
+	if *listen != "" {
+		// Set before loading so Validate checks the address actually used.
+		_ = os.Setenv("LISTEN_ADDR", *listen)
+	}
+	cfg := loadConfig(common)
+
+	var recentErrors *logging.Ring
+	if cfg.AdminPage {
+		// Keep recent warnings and errors for the admin page.
+		recentErrors = logging.NewRing(adminErrorCount, slog.LevelWarn)
 	}
+	logger, cleanup := newLogger(cfg, recentErrors)
 	defer cleanup()
-	slog.SetDefault(logger)
+	stopTracing, err := startTracing(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
 
-	database, err := db.Open(cfg.DBPath)
+	a, err := openApp(cfg, logger)
 	if err != nil {
-		logger.Error("failed to open database", "error", err)
-		return
+		return err
 	}
-	defer func() {
-		if err := database.Close(); err != nil {
-			logger.Error("failed to close database", "error", err)
+	defer a.Close()
+	areaService := a.service
+
+	// Nothing is analysing yet, so any photo still marked running was left
+	// behind by the previous process.
+	if _, err := areaService.SweepInterruptedAnalyses(context.Background(), time.Now()); err != nil {
+		logger.Error("failed to sweep interrupted analyses", "error", err)
+	}
+	go areaService.RunPurgeLoop(context.Background(), time.Hour)
+	if cfg.MQTTBroker != "" {
+		pub, err := mqtt.NewPublisher(mqtt.Options{
+			Broker:   cfg.MQTTBroker,
+			ClientID: cfg.MQTTClientID,
+			Username: cfg.MQTTUsername,
+			Password: cfg.MQTTPassword,
+			Logger:   logger,
+		}, cfg.MQTTTopicPrefix, cfg.MQTTDiscoveryPrefix)
+		if err != nil {
+			return fmt.Errorf("invalid MQTT configuration: %w", err)
 		}
-	}()
+		logger.Info("publishing inventory over mqtt", "broker", cfg.MQTTBroker, "topic_prefix", cfg.MQTTTopicPrefix)
+		areaService.WithInventoryPublisher(pub)
+		go pub.Run(context.Background(), areaService.PublishInventory)
+	}
+	go checkVisionBackend(context.Background(), areaService, a.analyzer, cfg, logger)
+	server := web.NewServer(areaService, templates.FS, a.photoStg, logger).
+		WithMaxPhotoSize(cfg.MaxPhotoSize).
+		WithItemPageSize(cfg.ItemPageSize).
+		WithItemLimits(itemLimits(cfg)).
+		WithUploadRateLimit(cfg.UploadRatePerMinute, cfg.UploadRateBurst).
+		WithAskRateLimit(cfg.AskRatePerMinute, cfg.AskRateBurst).
+		WithCalendarToken(cfg.CalendarToken).
+		WithBaseURL(cfg.BaseURL)
+	pageSize, err := export.ParsePageSize(cfg.PDFPageSize)
+	if err != nil {
+		return fmt.Errorf("invalid PDF_PAGE_SIZE: %w", err)
+	}
+	server.WithPDFPageSize(pageSize)
+	proxies, err := web.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	server.WithTrustedProxies(proxies)
+	server.WithSecurityHeaders(web.SecurityHeaders{
+		ContentSecurityPolicy:   cfg.ContentSecurityPolicy,
+		StrictTransportSecurity: cfg.StrictTransportSecurity,
+		FrameOptions:            cfg.FrameOptions,
+		ContentTypeOptions:      cfg.ContentTypeOptions,
+		ReferrerPolicy:          cfg.ReferrerPolicy,
+	})
+	server.WithRequestLog(web.RequestLogOptions{
+		ExcludePaths:     cfg.RequestLogExclude,
+		SuccessLevel:     logging.ParseLevel(cfg.RequestLogSuccessLevel),
+		ClientErrorLevel: logging.ParseLevel(cfg.RequestLogClientErrorLevel),
+		ServerErrorLevel: logging.ParseLevel(cfg.RequestLogServerErrorLevel),
+		SlowThreshold:    cfg.RequestLogSlow,
+	})
+	routeTimeouts, err := cfg.RouteTimeouts()
+	if err != nil {
+		return fmt.Errorf("invalid REQUEST_TIMEOUT_ROUTES: %w", err)
+	}
+	timeouts := web.DefaultRequestTimeouts()
+	timeouts.Default = cfg.RequestTimeout
+	maps.Copy(timeouts.Routes, routeTimeouts)
+	server.WithRequestTimeouts(timeouts)
 
-	areaStore := store.NewAreaStore(database)
-	photoStore := store.NewPhotoStore(database)
-	itemStore := store.NewItemStore(database)
-	itemEditStore := store.NewItemEditStore(database)
-	snapshotStore := store.NewSnapshotStore(database)
-	overrideStore := store.NewOverrideStore(database)
+	if cfg.DebugEndpoints {
+		server.WithDebugEndpoints()
+	}
+	if cfg.TestMode {
+		// Validate has refused a non-loopback LISTEN_ADDR unless overridden;
+		// the listener itself is checked once it is open.
+		logger.Warn("TEST MODE ENABLED: POST /control/reset deletes every area, item and photo; never run this on real data",
+			"listen_addr", cfg.ListenAddr, "allow_remote", cfg.TestModeAllowRemote)
+		photoPath := cfg.PhotoPath
+		if cfg.PhotoBackend != "local" {
+			photoPath = "" // nothing on disk to clear
+		}
+		server.EnableTestMode(a.db, photoPath)
+	}
+	if cfg.AdminPage {
+		server.WithAdmin(web.AdminInfo{Settings: cfg.Redacted(), DBPath: cfg.DBPath, Errors: recentErrors})
+	}
+	if cfg.Timezone != "" {
+		// Go falls back to UTC for an unknown TZ; fail loudly instead.
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid TZ %q: %w", cfg.Timezone, err)
+		}
+		server.WithLocation(loc)
+	}
+	if dir := devTemplatesDir(cfg); dir != "" {
+		logger.Warn("serving templates from disk, parsed on every request", "dir", dir)
+		server.WithTemplateReload(os.DirFS(dir))
+	}
 
-	visionAnalyzer, err := newVisionAnalyzer(cfg, logger)
-	if err != nil {
-		logger.Error("vision backend misconfigured", "error", err)
-		os.Exit(1)
+	if cfg.DemoMode {
+		logger.Warn("demo mode enabled: existing data will be replaced with the demo dataset", "reset_interval", cfg.DemoResetInterval)
+		if err := areaService.ResetDemo(context.Background()); err != nil {
+			return fmt.Errorf("failed to seed demo data: %w", err)
+		}
+		go areaService.RunDemoResetLoop(context.Background(), cfg.DemoResetInterval)
+		server.WithDemoMode()
+	}
+
+	if err := configureTLS(cfg, server, logger); err != nil {
+		return err
 	}
 
-	photoStg, err := local.NewLocalPhotoStore(cfg.PhotoPath)
+	ln, err := web.Listen(cfg.ListenAddr, cfg.ListenSocketMode)
 	if err != nil {
-		logger.Error("failed to initialize photo store", "error", err)
-		return
+		return fmt.Errorf("failed to listen on %s: %w", cfg.ListenAddr, err)
+	}
+	// A socket passed by systemd can be reachable whatever LISTEN_ADDR says.
+	if cfg.TestMode && !cfg.TestModeAllowRemote && !web.IsLoopback(ln.Addr()) {
+		_ = ln.Close()
+		return fmt.Errorf("TEST_MODE exposes endpoints that delete everything, but %s accepts remote connections; listen on a loopback address, or set TEST_MODE_ALLOW_REMOTE=true if the network is isolated", ln.Addr())
 	}
+	// Stop cleanly on SIGINT or SIGTERM, so a Unix socket's file is removed.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return server.Serve(ctx, ln)
+}
 
-	areaService := service.NewAreaService(areaStore, photoStore, itemStore, itemEditStore, snapshotStore, overrideStore, visionAnalyzer, photoStg, logger).WithDB(database)
-	server := web.NewServer(areaService, templates.FS, photoStg, logger)
+// configureTLS serves HTTPS with the configured certificate, or one
+// generated for this host with TLS_SELF_SIGNED.
+func configureTLS(cfg *config.Config, server *web.Server, logger *slog.Logger) error {
+	certFile, keyFile := cfg.TLSCertFile, cfg.TLSKeyFile
+	if cfg.TLSSelfSigned {
+		var err error
+		certFile, keyFile, err = web.SelfSignedCert(cfg.SelfSignedDir())
+		if err != nil {
+			return fmt.Errorf("failed to set up self-signed certificate: %w", err)
+		}
+		logger.Info("using self-signed certificate; trust it on each device after checking its fingerprint", "cert", certFile)
+	}
+	if certFile == "" {
+		return nil
+	}
+	server.WithTLS(certFile, keyFile).WithHTTPRedirect(cfg.HTTPRedirectAddr)
+	return nil
+}
 
-	if err := server.ListenAndServe(cfg.ListenAddr); err != nil {
-		logger.Error("server error", "error", err)
+// startTracing exports spans to the configured OTLP endpoint, if any. The
+// returned function sends the spans still queued, waiting at most a few
+// seconds for the collector.
+func startTracing(cfg *config.Config, logger *slog.Logger) (func(), error) {
+	endpoint := cfg.TracesEndpoint()
+	if endpoint == "" {
+		return func() {}, nil
 	}
+	headers, err := tracing.ParseHeaders(cfg.OTelHeaders)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS: %w", err)
+	}
+	// OTLP/HTTP collectors take JSON on the same endpoint as protobuf, so a
+	// protocol set for other services in a shared environment needn't stop
+	// kitchinv starting.
+	if cfg.OTelProtocol != "http/json" {
+		logger.Warn("OTEL_EXPORTER_OTLP_PROTOCOL is not supported; sending traces as http/json", "protocol", cfg.OTelProtocol)
+	}
+	shutdown := tracing.Setup(tracing.Options{Endpoint: endpoint, Headers: headers, ServiceName: cfg.OTelServiceName})
+	logger.Info("exporting traces", "endpoint", endpoint, "service_name", cfg.OTelServiceName)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			logger.Warn("failed to export final spans", "error", err)
+		}
+	}, nil
 }
 
 func newVisionAnalyzer(cfg *config.Config, logger *slog.Logger) (vision.VisionAnalyzer, error) {
@@ -74,16 +307,116 @@ func newVisionAnalyzer(cfg *config.Config, logger *slog.Logger) (vision.VisionAn
 		if cfg.ClaudeAPIKey == "" {
 			return nil, fmt.Errorf("CLAUDE_API_KEY must be set when VISION_BACKEND=claude")
 		}
-		logger.Info("using Claude vision backend", "model", cfg.ClaudeModel)
-		return claudevision.NewClaudeAnalyzer(cfg.ClaudeAPIKey, cfg.ClaudeModel), nil
+		logger.Info("using Claude vision backend", "model", cfg.ClaudeModel, "max_tokens", cfg.ClaudeMaxTokens, "prompt_cache", cfg.ClaudePromptCache,
+			"extraction", cfg.ClaudeExtraction, "stream", cfg.ClaudeStream)
+		return claudevision.NewClaudeAnalyzer(cfg.ClaudeAPIKey, cfg.ClaudeModel).
+			WithPrompt(cfg.VisionPrompt).
+			WithMaxTokens(cfg.ClaudeMaxTokens).
+			WithPromptCaching(cfg.ClaudePromptCache).
+			WithExtraction(cfg.ClaudeExtraction).
+			WithStream(cfg.ClaudeStream), nil
 	case "gemini":
 		if cfg.GeminiAPIKey == "" {
 			return nil, fmt.Errorf("GEMINI_API_KEY must be set when VISION_BACKEND=gemini")
 		}
 		logger.Info("using Gemini vision backend", "model", cfg.GeminiModel)
-		return geminivision.NewGeminiAnalyzer(cfg.GeminiAPIKey, cfg.GeminiModel), nil
+		return geminivision.NewGeminiAnalyzer(cfg.GeminiAPIKey, cfg.GeminiModel).WithPrompt(cfg.VisionPrompt), nil
+	case "openai-compatible":
+		if cfg.OpenAIModel == "" {
+			return nil, fmt.Errorf("OPENAI_MODEL must be set when VISION_BACKEND=openai-compatible")
+		}
+		logger.Info("using OpenAI-compatible vision backend", "base_url", cfg.OpenAIBaseURL, "model", cfg.OpenAIModel, "stream", cfg.OpenAIStream)
+		return openaivision.NewOpenAIAnalyzer(cfg.OpenAIBaseURL, cfg.OpenAIModel, cfg.OpenAIAPIKey).
+			WithPrompt(cfg.VisionPrompt).
+			WithStream(cfg.OpenAIStream), nil
+	case "fake":
+		logger.Info("using fake vision backend")
+		return fakevision.NewFakeAnalyzer(), nil
 	default:
-		logger.Info("using Ollama vision backend", "model", cfg.OllamaModel)
-		return ollamavision.NewOllamaAnalyzer(cfg.OllamaHost, cfg.OllamaModel), nil
+		if cfg.OllamaAPI != ollamavision.APIGenerate && cfg.OllamaAPI != ollamavision.APIChat {
+			return nil, fmt.Errorf("OLLAMA_API must be %q or %q, got %q", ollamavision.APIGenerate, ollamavision.APIChat, cfg.OllamaAPI)
+		}
+		logger.Info("using Ollama vision backend", "model", cfg.OllamaModel, "api", cfg.OllamaAPI)
+		return ollamavision.NewOllamaAnalyzer(cfg.OllamaHost, cfg.OllamaModel).
+			WithPrompt(cfg.VisionPrompt).
+			WithAPI(cfg.OllamaAPI).
+			WithOptions(ollamaOptions(cfg)), nil
+	}
+}
+
+// devTemplatesDir returns the directory to serve templates from in
+// development, or "" to use the embedded templates.
+func devTemplatesDir(cfg *config.Config) string {
+	if cfg.DevTemplatesDir != "" {
+		return cfg.DevTemplatesDir
+	}
+	if cfg.TemplateDevReload {
+		return "internal/web/templates"
+	}
+	return ""
+}
+
+// ollamaOptions maps the Ollama generation settings from cfg, leaving out
+// those configured to use the model's default.
+func ollamaOptions(cfg *config.Config) ollamavision.Options {
+	opts := ollamavision.Options{
+		NumPredict: cfg.OllamaNumPredict,
+		NumCtx:     cfg.OllamaNumCtx,
+		KeepAlive:  cfg.OllamaKeepAlive,
+	}
+	if cfg.OllamaTemperature >= 0 {
+		t := cfg.OllamaTemperature
+		opts.Temperature = &t
+	}
+	return opts
+}
+
+// checkVisionBackend runs the vision pre-flight check so misconfiguration is
+// reported at startup rather than on the first upload. A missing Ollama
+// model is pulled when OLLAMA_AUTO_PULL is set. Failures are only logged and
+// surfaced through the service's vision status; the server still starts.
+func checkVisionBackend(ctx context.Context, svc *service.AreaService, analyzer vision.VisionAnalyzer, cfg *config.Config, logger *slog.Logger) {
+	err := svc.CheckVision(ctx)
+	if err == nil {
+		return
+	}
+	ol, isOllama := analyzer.(*ollamavision.OllamaAnalyzer)
+	if !isOllama || !errors.Is(err, ollamavision.ErrModelNotFound) {
+		logger.Error("vision backend check failed", "backend", cfg.VisionBackend, "error", err)
+		return
+	}
+	if !cfg.OllamaAutoPull {
+		logger.Error("ollama model is not installed; uploads will fail until it is pulled",
+			"model", cfg.OllamaModel, "command", "ollama pull "+cfg.OllamaModel, "hint", "or set OLLAMA_AUTO_PULL=1")
+		return
+	}
+
+	logger.Info("pulling ollama model", "model", cfg.OllamaModel)
+	lastStatus, lastPct := "", int64(-1)
+	err = ol.Pull(ctx, func(status string, completed, total int64) {
+		// Layer downloads report byte counts many times a second; only log
+		// status changes and every 10%.
+		pct := int64(-1)
+		if total > 0 {
+			pct = completed * 100 / total / 10 * 10
+		}
+		if status == lastStatus && pct == lastPct {
+			return
+		}
+		lastStatus, lastPct = status, pct
+		if pct >= 0 {
+			logger.Info("ollama pull progress", "model", cfg.OllamaModel, "status", status, "percent", pct)
+		} else {
+			logger.Info("ollama pull progress", "model", cfg.OllamaModel, "status", status)
+		}
+	})
+	if err != nil {
+		logger.Error("failed to pull ollama model", "model", cfg.OllamaModel, "error", err)
+		return
+	}
+	if err := svc.CheckVision(ctx); err != nil {
+		logger.Error("vision backend check failed after pull", "backend", cfg.VisionBackend, "error", err)
+		return
 	}
+	logger.Info("ollama model pulled", "model", cfg.OllamaModel)
 }
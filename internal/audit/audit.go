@@ -0,0 +1,139 @@
+// Package audit records destructive actions, such as deleting an area or
+// replacing its items on re-upload, so users can find out what removed part
+// of their inventory. Entries are written to SQLite today; callers depend
+// only on Store's methods so the sink can move elsewhere later.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/db"
+)
+
+// Actions recorded in the log.
+const (
+	ActionAreaDelete   = "area.delete"
+	ActionAreaRestore  = "area.restore"
+	ActionPhotoDelete  = "photo.delete"
+	ActionItemDelete   = "item.delete"
+	ActionItemsReplace = "items.replace"
+	ActionItemsBulk    = "items.bulk"
+)
+
+// Entity types an entry refers to.
+const (
+	EntityArea  = "area"
+	EntityPhoto = "photo"
+	EntityItem  = "item"
+)
+
+// Entry is one audited action.
+type Entry struct {
+	ID         int64          `json:"id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	Action     string         `json:"action"`
+	EntityType string         `json:"entity_type"`
+	EntityID   int64          `json:"entity_id"`
+	AreaID     *int64         `json:"area_id,omitempty"`
+	Detail     map[string]any `json:"detail,omitempty"`
+	RequestID  string         `json:"request_id,omitempty"`
+}
+
+// Filter narrows List. Zero fields don't filter.
+type Filter struct {
+	AreaID int64
+	Since  time.Time // inclusive
+	Until  time.Time // exclusive
+	Limit  int
+}
+
+// Store persists audit entries.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore creates a Store backed by db.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Record appends e to the log. CreatedAt and ID are assigned by the store.
+func (s *Store) Record(ctx context.Context, e Entry) error {
+	detail := []byte("{}")
+	if len(e.Detail) > 0 {
+		var err error
+		if detail, err = json.Marshal(e.Detail); err != nil {
+			return fmt.Errorf("failed to encode audit detail: %w", err)
+		}
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO audit_log (action, entity_type, entity_id, area_id, detail, request_id)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, e.Action, e.EntityType, e.EntityID, e.AreaID, string(detail), e.RequestID); err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// List returns entries matching f, newest first.
+func (s *Store) List(ctx context.Context, f Filter) ([]*Entry, error) {
+	var where []string
+	var args []any
+	if f.AreaID != 0 {
+		where = append(where, "area_id = ?")
+		args = append(args, f.AreaID)
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, db.FormatTime(f.Since))
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, db.FormatTime(f.Until))
+	}
+	query := `SELECT id, created_at, action, entity_type, entity_id, area_id, detail, request_id FROM audit_log`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	limit := f.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("failed to close rows", "error", err)
+		}
+	}()
+
+	var entries []*Entry
+	for rows.Next() {
+		e := &Entry{}
+		var detail string
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.Action, &e.EntityType, &e.EntityID, &e.AreaID, &detail, &e.RequestID); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		if detail != "" && detail != "{}" {
+			if err := json.Unmarshal([]byte(detail), &e.Detail); err != nil {
+				return nil, fmt.Errorf("failed to decode audit detail: %w", err)
+			}
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit entries: %w", err)
+	}
+	return entries, nil
+}
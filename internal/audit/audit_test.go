@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/db"
+)
+
+func TestStoreRecordAndList(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+	s := NewStore(d)
+	ctx := context.Background()
+
+	fridge, pantry := int64(1), int64(2)
+	require.NoError(t, s.Record(ctx, Entry{
+		Action: ActionAreaDelete, EntityType: EntityArea, EntityID: fridge, AreaID: &fridge,
+		Detail: map[string]any{"name": "Fridge"}, RequestID: "req-1",
+	}))
+	require.NoError(t, s.Record(ctx, Entry{
+		Action: ActionItemDelete, EntityType: EntityItem, EntityID: 7, AreaID: &pantry,
+	}))
+	_, err = d.ExecContext(ctx, `UPDATE audit_log SET created_at = '2020-01-01 00:00:00' WHERE action = ?`, ActionAreaDelete)
+	require.NoError(t, err)
+
+	all, err := s.List(ctx, Filter{})
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, ActionItemDelete, all[0].Action, "newest first")
+	assert.Nil(t, all[0].Detail)
+	assert.Equal(t, map[string]any{"name": "Fridge"}, all[1].Detail)
+	assert.Equal(t, "req-1", all[1].RequestID)
+
+	byArea, err := s.List(ctx, Filter{AreaID: fridge})
+	require.NoError(t, err)
+	require.Len(t, byArea, 1)
+	assert.Equal(t, ActionAreaDelete, byArea[0].Action)
+
+	recent, err := s.List(ctx, Filter{Since: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)})
+	require.NoError(t, err)
+	require.Len(t, recent, 1)
+	assert.Equal(t, ActionItemDelete, recent[0].Action)
+
+	old, err := s.List(ctx, Filter{Until: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Limit: 5})
+	require.NoError(t, err)
+	require.Len(t, old, 1)
+	assert.Equal(t, ActionAreaDelete, old[0].Action)
+}
@@ -2,16 +2,27 @@ package config
 
 import (
 	"log/slog"
+	"math"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
 )
 
 type Config struct {
+	// ConfigFile is the YAML or TOML file settings were read from, if any;
+	// see file.go. fileErr is why it couldn't be read, for Validate.
+	ConfigFile string
+	fileErr    error
+
 	ListenAddr    string
 	DBPath        string
 	VisionBackend string
 	OllamaHost    string
 	OllamaModel   string
+	OllamaAPI     string
 	ClaudeAPIKey  string
 	ClaudeModel   string
 	GeminiAPIKey  string
@@ -20,15 +31,274 @@ type Config struct {
 	PhotoPath     string
 	LogLevel      string
 	LogFile       string
+
+	// LogFormat is "json" or "text"; LogSource adds the source file and
+	// line to each record.
+	LogFormat string
+	LogSource bool
+
+	// LogFile rotation: the size it is rotated at (0 never), how many
+	// rotated files to keep (0 all), how old they may get (0 forever) and
+	// whether to gzip them. SIGHUP reopens the file for external rotation.
+	LogMaxSize    int64
+	LogMaxBackups int
+	LogMaxAge     time.Duration
+	LogCompress   bool
+
+	// ListenSocketMode is the permissions of the socket file when
+	// ListenAddr is "unix:" followed by a path.
+	ListenSocketMode os.FileMode
+
+	// TLSCertFile and TLSKeyFile serve HTTPS with that PEM certificate and
+	// key. TLSSelfSigned instead generates a certificate for this host on
+	// first start and keeps it in TLSDir, the "tls" directory next to the
+	// database unless set. HTTPRedirectAddr, when set, also listens for
+	// plain HTTP there and redirects it to HTTPS.
+	TLSCertFile      string
+	TLSKeyFile       string
+	TLSSelfSigned    bool
+	TLSDir           string
+	HTTPRedirectAddr string
+
+	// TrustedProxies are the CIDR ranges, addresses, or "unix" for a Unix
+	// socket peer, whose X-Forwarded-For, X-Real-IP and X-Forwarded-Proto
+	// headers are believed. Empty trusts nobody.
+	TrustedProxies []string
+
+	// Security headers sent with every response; an empty value leaves the
+	// header out, for when a reverse proxy sets its own. "{nonce}" in
+	// ContentSecurityPolicy is replaced with each page's script nonce.
+	// StrictTransportSecurity is only sent over HTTPS.
+	ContentSecurityPolicy   string
+	StrictTransportSecurity string
+	FrameOptions            string
+	ContentTypeOptions      string
+	ReferrerPolicy          string
+
+	// Request logging: paths left out unless they fail or are slow (a
+	// trailing "/" covers everything under it), the level for each status
+	// class, and the duration from which a request is logged as slow at
+	// warn or above (0 turns that off).
+	RequestLogExclude          []string
+	RequestLogSuccessLevel     string
+	RequestLogClientErrorLevel string
+	RequestLogServerErrorLevel string
+	RequestLogSlow             time.Duration
+
+	// RequestTimeout is how long a request may take before it is answered
+	// with 503; 0 means no limit. RequestTimeoutRoutes are "pattern=duration"
+	// entries, keyed by route pattern such as "GET /export.pdf", that change
+	// the budget of single routes, with 0 exempting one.
+	RequestTimeout       time.Duration
+	RequestTimeoutRoutes []string
+
+	// OpenTelemetry tracing, read from the standard OTEL_ variables and off
+	// unless an endpoint is set. OTelTracesEndpoint is the full URL spans
+	// are sent to; OTelEndpoint is a collector base URL that "/v1/traces"
+	// is appended to. Only the http/json protocol is supported.
+	// OTelHeaders is comma-separated key=value pairs added to each export.
+	OTelEndpoint       string
+	OTelTracesEndpoint string
+	OTelProtocol       string
+	OTelHeaders        string
+	OTelServiceName    string
+
+	// DebugEndpoints exposes troubleshooting routes such as the raw vision
+	// response of an area's latest photo.
+	DebugEndpoints bool
+	// AdminPage serves /admin, a status page showing this configuration
+	// with secrets redacted, storage use and recent errors.
+	AdminPage bool
+	// TestMode exposes POST /control/reset, which deletes every row and
+	// photo, for end-to-end test stacks. It is refused on a LISTEN_ADDR
+	// other than loopback unless TestModeAllowRemote is set.
+	TestMode            bool
+	TestModeAllowRemote bool
+
+	// DevTemplatesDir, when set, serves templates from that directory
+	// instead of the embedded copy, re-parsing them on every request so
+	// edits show on refresh. TemplateDevReload does the same with the
+	// source tree's template directory.
+	DevTemplatesDir string
+	// Timezone is the IANA zone dates are shown in, e.g. "Europe/London".
+	// Empty uses the process's local zone.
+	Timezone          string
+	TemplateDevReload bool
+
+	// Ollama generation options. A negative temperature and zero token
+	// counts leave the model's defaults; an empty keep-alive uses Ollama's.
+	OllamaTemperature float64
+	OllamaNumPredict  int
+	OllamaNumCtx      int
+	OllamaKeepAlive   string
+
+	// OpenAI-compatible backend (LM Studio, llama.cpp server, vLLM, ...).
+	// OpenAIBaseURL includes the version path; the API key is optional.
+	OpenAIBaseURL string
+	OpenAIModel   string
+	OpenAIAPIKey  string
+	OpenAIStream  bool
+
+	// OllamaAutoPull pulls the configured model on startup when Ollama
+	// doesn't have it, instead of only logging the pull command.
+	OllamaAutoPull bool
+
+	// VisionPrompt replaces the backend's default user prompt (appended to
+	// it for Ollama); empty keeps the built-in prompt. Per-area prompts
+	// still take precedence.
+	VisionPrompt string
+	// ClaudeMaxTokens caps the length of Claude's response.
+	ClaudeMaxTokens int
+	// ClaudePromptCache sends the instructions as a cached system prompt.
+	ClaudePromptCache bool
+	// ClaudeExtraction is how Claude returns items: "text" for a JSON
+	// reply or "tools" for one tool call per item.
+	ClaudeExtraction string
+	// ClaudeStream requests streamed replies from Claude.
+	ClaudeStream bool
+
+	// Claude prices in US dollars per million tokens, used to estimate each
+	// analysis's cost. The defaults match claude-opus-4-6.
+	ClaudeInputCostPerMTok  float64
+	ClaudeOutputCostPerMTok float64
+
+	// SQLite tuning; zero values fall back to db.DefaultOptions.
+	DBBusyTimeout  time.Duration
+	DBSynchronous  string
+	DBMaxOpenConns int
+	DBMaxIdleConns int
+
+	// MaxConcurrentAnalyses caps in-flight vision analyses; 0 means unlimited.
+	MaxConcurrentAnalyses int
+
+	// VisionTimeout bounds a single vision call; 0 disables the limit.
+	VisionTimeout time.Duration
+
+	// KeepItemsOnEmptyAnalysis keeps an area's items when a photo's analysis
+	// detects none, until the user confirms the replacement; false replaces
+	// them with the empty result.
+	KeepItemsOnEmptyAnalysis bool
+
+	// MaxPhotoSize is the largest accepted photo upload in bytes.
+	MaxPhotoSize int64
+
+	// ItemPageSize is how many items an area or search page shows before
+	// offering to load more.
+	ItemPageSize int
+
+	// ItemNameMaxLength and ItemQuantityMaxLength bound an item's name and
+	// quantity in characters; 0 removes the limit.
+	ItemNameMaxLength     int
+	ItemQuantityMaxLength int
+
+	// Per-client token bucket for photo uploads; a rate of 0 disables it.
+	UploadRatePerMinute float64
+	UploadRateBurst     int
+
+	// DemoMode seeds the embedded demo dataset, makes the UI read-only
+	// except for uploads, and resets the data every DemoResetInterval.
+	DemoMode          bool
+	DemoResetInterval time.Duration
+
+	// IgnoreItems are built-in ignore-list patterns; detections matching them
+	// or a user-added entry are discarded. IgnoreItemsEnabled=false turns the
+	// filtering off entirely.
+	IgnoreItems        []string
+	IgnoreItemsEnabled bool
+
+	// AreaRetention is how long a deleted area stays restorable before purge.
+	AreaRetention time.Duration
+	// StalePhotoAfter is the photo age past which an area is flagged for
+	// re-photographing. 0 disables the flag.
+	StalePhotoAfter time.Duration
+
+	// MQTT publishing of area inventories; an empty MQTTBroker turns it off.
+	// An empty MQTTDiscoveryPrefix skips Home Assistant discovery configs.
+	MQTTBroker          string
+	MQTTUsername        string
+	MQTTPassword        string
+	MQTTClientID        string
+	MQTTTopicPrefix     string
+	MQTTDiscoveryPrefix string
+
+	// SuggestPrompt replaces the recipe-suggestion prompt; empty keeps the
+	// built-in one. SuggestMaxItems caps the items sent with it.
+	SuggestPrompt   string
+	SuggestMaxItems int
+
+	// AskPrompt replaces the text/template the /ask question is rendered
+	// into; empty keeps the built-in one. Per-client token bucket for /ask;
+	// a rate of 0 disables it.
+	AskPrompt        string
+	AskRatePerMinute float64
+	AskRateBurst     int
+
+	// OpenFoodFactsURL is the Open Food Facts server scanned barcodes are
+	// looked up on; empty turns lookups off, naming items after the barcode.
+	OpenFoodFactsURL string
+
+	// HistoryMaxPerArea caps the inventory history points kept per area, one
+	// per analysis; 0 keeps them all.
+	HistoryMaxPerArea int
+
+	// CalendarToken enables the /calendar.ics expiry feed for requests
+	// carrying it; empty turns the feed off. ShelfLife entries
+	// ("keyword=days") estimate expiry dates from when items were added.
+	CalendarToken string
+	ShelfLife     []string
+
+	// BaseURL is the public URL kitchinv is reached at, which QR code labels
+	// link to; empty uses the host each request was addressed to.
+	BaseURL string
+
+	// PDFPageSize is the paper size of the /export.pdf report, "a4" or
+	// "letter".
+	PDFPageSize string
+
+	// Needs-attention score weights; see service.AttentionWeights.
+	AttentionStalePerDay  float64
+	AttentionStaleMaxDays int
+	AttentionNoPhoto      float64
+	AttentionEmpty        float64
+	AttentionOutOfStock   float64
 }
 
+// DefaultIgnoreItems are storage fixtures and packaging that vision models
+// commonly report as items.
+const DefaultIgnoreItems = "shelf,shelves,drawer,crisper drawer,door shelf,plastic container,glass container,empty container"
+
+// DefaultContentSecurityPolicy only runs <script> elements from kitchinv
+// itself or carrying the page's nonce. Inline event handler attributes are
+// still allowed by script-src-attr, as the templates use them.
+const DefaultContentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self' 'nonce-{nonce}'; " +
+	"script-src-attr 'unsafe-inline'; " +
+	"style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data:; " +
+	"connect-src 'self'"
+
+// DefaultShelfLife is a rough refrigerated shelf life, in days, for common
+// perishables.
+const DefaultShelfLife = "milk=7d,cream=7d,yogurt=14d,yoghurt=14d,cheese=28d,cream cheese=14d,butter=30d,egg=28d," +
+	"bread=5d,chicken=2d,beef=3d,pork=3d,fish=2d,salmon=2d,ham=5d,bacon=7d,lettuce=5d,spinach=5d,berry=5d," +
+	"juice=7d,hummus=7d,tofu=5d,leftovers=3d"
+
+// Load reads the configuration from the environment, falling back to the
+// file named by CONFIG_FILE for variables that aren't set. Keys in the file
+// that aren't settings are logged.
 func Load() *Config {
-	return &Config{
+	path := os.Getenv("CONFIG_FILE")
+	fileErr := loadFile(path)
+	cfg := &Config{
+		ConfigFile: path,
+		fileErr:    fileErr,
+
 		ListenAddr:    getEnv("LISTEN_ADDR", ":8080"),
 		DBPath:        getEnv("DB_PATH", "/data/kitchinv.db"),
 		VisionBackend: getEnv("VISION_BACKEND", "ollama"),
 		OllamaHost:    getEnv("OLLAMA_HOST", "http://localhost:11434"),
 		OllamaModel:   getEnv("OLLAMA_MODEL", "moondream"),
+		OllamaAPI:     getEnv("OLLAMA_API", "generate"),
 		ClaudeAPIKey:  getSecret("CLAUDE_API_KEY", "CLAUDE_API_KEY_FILE"),
 		ClaudeModel:   getEnv("CLAUDE_MODEL", "claude-opus-4-6"),
 		GeminiAPIKey:  getSecret("GEMINI_API_KEY", "GEMINI_API_KEY_FILE"),
@@ -37,25 +307,283 @@ func Load() *Config {
 		PhotoPath:     getEnv("PHOTO_LOCAL_PATH", "/data/photos"),
 		LogLevel:      getEnv("LOG_LEVEL", "info"),
 		LogFile:       getEnv("LOG_FILE", ""),
+
+		LogFormat: getEnv("LOG_FORMAT", "json"),
+		LogSource: getEnvBool("LOG_SOURCE", false),
+
+		LogMaxSize:    getEnvBytesOrOff("LOG_MAX_SIZE", 100<<20),
+		LogMaxBackups: getEnvInt("LOG_MAX_BACKUPS", 5),
+		LogMaxAge:     getEnvDuration("LOG_MAX_AGE", 0),
+		LogCompress:   getEnvBool("LOG_COMPRESS", true),
+
+		ListenSocketMode: getEnvFileMode("LISTEN_SOCKET_MODE", 0o660),
+
+		TLSCertFile:      getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:       getEnv("TLS_KEY_FILE", ""),
+		TLSSelfSigned:    getEnvBool("TLS_SELF_SIGNED", false),
+		TLSDir:           getEnv("TLS_DIR", ""),
+		HTTPRedirectAddr: getEnv("HTTP_REDIRECT_ADDR", ""),
+
+		TrustedProxies: getEnvList("TRUSTED_PROXIES", ""),
+
+		ContentSecurityPolicy:   getEnv("CONTENT_SECURITY_POLICY", DefaultContentSecurityPolicy),
+		StrictTransportSecurity: getEnv("STRICT_TRANSPORT_SECURITY", ""),
+		FrameOptions:            getEnv("FRAME_OPTIONS", "DENY"),
+		ContentTypeOptions:      getEnv("CONTENT_TYPE_OPTIONS", "nosniff"),
+		ReferrerPolicy:          getEnv("REFERRER_POLICY", "strict-origin-when-cross-origin"),
+
+		RequestLogExclude:          getEnvList("REQUEST_LOG_EXCLUDE", "/readyz,/healthz,/metrics,/static/"),
+		RequestLogSuccessLevel:     getEnv("REQUEST_LOG_SUCCESS_LEVEL", "info"),
+		RequestLogClientErrorLevel: getEnv("REQUEST_LOG_CLIENT_ERROR_LEVEL", "warn"),
+		RequestLogServerErrorLevel: getEnv("REQUEST_LOG_SERVER_ERROR_LEVEL", "error"),
+		RequestLogSlow:             getEnvDuration("REQUEST_LOG_SLOW", 2*time.Second),
+
+		RequestTimeout:       getEnvDuration("REQUEST_TIMEOUT", 15*time.Second),
+		RequestTimeoutRoutes: getEnvList("REQUEST_TIMEOUT_ROUTES", ""),
+
+		OTelEndpoint:       getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		OTelTracesEndpoint: getEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", ""),
+		OTelProtocol:       getEnv("OTEL_EXPORTER_OTLP_PROTOCOL", "http/json"),
+		OTelHeaders:        getEnv("OTEL_EXPORTER_OTLP_HEADERS", ""),
+		OTelServiceName:    getEnv("OTEL_SERVICE_NAME", "kitchinv"),
+
+		DebugEndpoints: getEnvBool("DEBUG_ENDPOINTS", false),
+		AdminPage:      getEnvBool("ADMIN_PAGE", false),
+
+		TestMode:            getEnvBool("TEST_MODE", false),
+		TestModeAllowRemote: getEnvBool("TEST_MODE_ALLOW_REMOTE", false),
+
+		DevTemplatesDir:   getEnv("DEV_TEMPLATES_DIR", ""),
+		Timezone:          getEnv("TZ", ""),
+		TemplateDevReload: getEnvBool("TEMPLATE_DEV_RELOAD", false),
+
+		OllamaTemperature: getEnvFloat("OLLAMA_TEMPERATURE", 0.2),
+		OllamaNumPredict:  getEnvInt("OLLAMA_NUM_PREDICT", 0),
+		OllamaNumCtx:      getEnvInt("OLLAMA_NUM_CTX", 0),
+		OllamaKeepAlive:   getEnv("OLLAMA_KEEP_ALIVE", "10m"),
+
+		OllamaAutoPull: getEnvBool("OLLAMA_AUTO_PULL", false),
+
+		OpenAIBaseURL: getEnv("OPENAI_BASE_URL", "http://localhost:1234/v1"),
+		OpenAIModel:   getEnv("OPENAI_MODEL", ""),
+		OpenAIAPIKey:  getSecret("OPENAI_API_KEY", "OPENAI_API_KEY_FILE"),
+		OpenAIStream:  getEnvBool("OPENAI_STREAM", false),
+
+		VisionPrompt:      getEnvOrFile("VISION_PROMPT", "VISION_PROMPT_FILE"),
+		ClaudeMaxTokens:   getEnvInt("CLAUDE_MAX_TOKENS", 4096),
+		ClaudePromptCache: getEnvBool("CLAUDE_PROMPT_CACHE", false),
+		ClaudeExtraction:  getEnv("CLAUDE_EXTRACTION", "text"),
+		ClaudeStream:      getEnvBool("CLAUDE_STREAM", false),
+
+		ClaudeInputCostPerMTok:  getEnvFloat("CLAUDE_INPUT_COST_PER_MTOK", 5),
+		ClaudeOutputCostPerMTok: getEnvFloat("CLAUDE_OUTPUT_COST_PER_MTOK", 25),
+
+		DBBusyTimeout:  getEnvDuration("DB_BUSY_TIMEOUT", 5*time.Second),
+		DBSynchronous:  getEnv("DB_SYNCHRONOUS", "NORMAL"),
+		DBMaxOpenConns: getEnvInt("DB_MAX_OPEN_CONNS", 10),
+		DBMaxIdleConns: getEnvInt("DB_MAX_IDLE_CONNS", 5),
+
+		MaxConcurrentAnalyses: getEnvInt("MAX_CONCURRENT_ANALYSES", 2),
+
+		VisionTimeout: getEnvDuration("VISION_TIMEOUT", 5*time.Minute),
+
+		KeepItemsOnEmptyAnalysis: getEnvBool("KEEP_ITEMS_ON_EMPTY_ANALYSIS", true),
+
+		MaxPhotoSize: getEnvBytes("MAX_PHOTO_SIZE", 50<<20),
+
+		ItemPageSize: getEnvInt("ITEM_PAGE_SIZE", 50),
+
+		ItemNameMaxLength:     getEnvInt("ITEM_NAME_MAX_LENGTH", 200),
+		ItemQuantityMaxLength: getEnvInt("ITEM_QUANTITY_MAX_LENGTH", 100),
+
+		UploadRatePerMinute: getEnvFloat("UPLOAD_RATE_PER_MINUTE", 6),
+		UploadRateBurst:     getEnvInt("UPLOAD_RATE_BURST", 3),
+
+		DemoMode:          getEnvBool("DEMO_MODE", false),
+		DemoResetInterval: getEnvDuration("DEMO_RESET_INTERVAL", time.Hour),
+
+		IgnoreItems:        getEnvList("IGNORE_ITEMS", DefaultIgnoreItems),
+		IgnoreItemsEnabled: getEnvBool("IGNORE_ITEMS_ENABLED", true),
+
+		AreaRetention:   getEnvDuration("AREA_RETENTION", 30*24*time.Hour),
+		StalePhotoAfter: getEnvDuration("STALE_PHOTO_AFTER", 14*24*time.Hour),
+
+		MQTTBroker:          getEnv("MQTT_BROKER", ""),
+		MQTTUsername:        getEnv("MQTT_USERNAME", ""),
+		MQTTPassword:        getSecret("MQTT_PASSWORD", "MQTT_PASSWORD_FILE"),
+		MQTTClientID:        getEnv("MQTT_CLIENT_ID", "kitchinv"),
+		MQTTTopicPrefix:     getEnv("MQTT_TOPIC_PREFIX", "kitchinv"),
+		MQTTDiscoveryPrefix: getEnv("MQTT_DISCOVERY_PREFIX", "homeassistant"),
+
+		SuggestPrompt:   getEnvOrFile("SUGGEST_PROMPT", "SUGGEST_PROMPT_FILE"),
+		SuggestMaxItems: getEnvInt("SUGGEST_MAX_ITEMS", 100),
+
+		AskPrompt:        getEnvOrFile("ASK_PROMPT", "ASK_PROMPT_FILE"),
+		AskRatePerMinute: getEnvFloat("ASK_RATE_PER_MINUTE", 10),
+		AskRateBurst:     getEnvInt("ASK_RATE_BURST", 5),
+
+		OpenFoodFactsURL: getEnv("OPENFOODFACTS_URL", "https://world.openfoodfacts.org"),
+
+		HistoryMaxPerArea: getEnvInt("HISTORY_MAX_PER_AREA", 365),
+
+		CalendarToken: getSecret("CALENDAR_TOKEN", "CALENDAR_TOKEN_FILE"),
+		ShelfLife:     getEnvList("SHELF_LIFE", DefaultShelfLife),
+
+		BaseURL:     getEnv("BASE_URL", ""),
+		PDFPageSize: getEnv("PDF_PAGE_SIZE", "a4"),
+
+		AttentionStalePerDay:  getEnvFloat("ATTENTION_STALE_PER_DAY", 1),
+		AttentionStaleMaxDays: getEnvInt("ATTENTION_STALE_MAX_DAYS", 60),
+		AttentionNoPhoto:      getEnvFloat("ATTENTION_NO_PHOTO", 30),
+		AttentionEmpty:        getEnvFloat("ATTENTION_EMPTY", 40),
+		AttentionOutOfStock:   getEnvFloat("ATTENTION_OUT_OF_STOCK", 5),
+	}
+	for _, key := range unknownFileKeys() {
+		slog.Warn("unknown setting in config file, ignoring", "file", path, "key", strings.ToLower(key))
 	}
+	return cfg
 }
 
 func getEnv(key, defaultVal string) string {
-	if val, exists := os.LookupEnv(key); exists {
+	if val, exists := lookupEnv(key); exists {
 		return val
 	}
 	return defaultVal
 }
 
+// getEnvInt returns the integer value of key, or defaultVal when the variable
+// is unset or not a valid integer. Invalid values are logged.
+func getEnvInt(key string, defaultVal int) int {
+	val, exists := lookupEnv(key)
+	if !exists || val == "" {
+		return defaultVal
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil {
+		slog.Warn("invalid integer config value, using default", "env", key, "value", val, "default", defaultVal)
+		return defaultVal
+	}
+	return n
+}
+
+// getEnvBool returns the boolean value of key ("1", "true", "0", "false", ...),
+// or defaultVal when the variable is unset or unparseable. Invalid values are logged.
+func getEnvBool(key string, defaultVal bool) bool {
+	val, exists := lookupEnv(key)
+	if !exists || val == "" {
+		return defaultVal
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(val))
+	if err != nil {
+		slog.Warn("invalid boolean config value, using default", "env", key, "value", val, "default", defaultVal)
+		return defaultVal
+	}
+	return b
+}
+
+// getEnvFloat returns the float value of key, or defaultVal when the variable
+// is unset or not a valid number. Invalid values are logged.
+func getEnvFloat(key string, defaultVal float64) float64 {
+	val, exists := lookupEnv(key)
+	if !exists || val == "" {
+		return defaultVal
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+	if err != nil {
+		slog.Warn("invalid numeric config value, using default", "env", key, "value", val, "default", defaultVal)
+		return defaultVal
+	}
+	return f
+}
+
+// getEnvDuration returns the duration value of key (e.g. "5s", "250ms"), or
+// defaultVal when the variable is unset or unparseable. Invalid values are logged.
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	val, exists := lookupEnv(key)
+	if !exists || val == "" {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(val))
+	if err != nil {
+		slog.Warn("invalid duration config value, using default", "env", key, "value", val, "default", defaultVal)
+		return defaultVal
+	}
+	return d
+}
+
+// getEnvFileMode returns the octal permissions in key (e.g. "0660"), or
+// defaultVal when the variable is unset or not valid permissions. Invalid
+// values are logged.
+func getEnvFileMode(key string, defaultVal os.FileMode) os.FileMode {
+	val, exists := lookupEnv(key)
+	if !exists || val == "" {
+		return defaultVal
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(val), 8, 32)
+	if err != nil || n > 0o777 {
+		slog.Warn("invalid file mode config value, using default", "env", key, "value", val, "default", defaultVal.String())
+		return defaultVal
+	}
+	return os.FileMode(n)
+}
+
+// getEnvList splits the comma-separated value of key (or defaultVal when unset)
+// into trimmed, non-empty entries. Setting the variable to an empty string
+// yields an empty list.
+func getEnvList(key, defaultVal string) []string {
+	var out []string
+	for _, v := range strings.Split(getEnv(key, defaultVal), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// getEnvBytes returns the size value of key in bytes (e.g. "20MB", "50MiB",
+// "1048576"), or defaultVal when the variable is unset, unparseable or zero.
+// Invalid values are logged.
+func getEnvBytes(key string, defaultVal int64) int64 {
+	val, exists := lookupEnv(key)
+	if !exists || val == "" {
+		return defaultVal
+	}
+	n, err := humanize.ParseBytes(strings.TrimSpace(val))
+	if err != nil || n == 0 || n > math.MaxInt64 {
+		slog.Warn("invalid size config value, using default", "env", key, "value", val, "default", defaultVal)
+		return defaultVal
+	}
+	return int64(n)
+}
+
+// getEnvBytesOrOff is getEnvBytes, except that "0" turns the setting off
+// and yields 0.
+func getEnvBytesOrOff(key string, defaultVal int64) int64 {
+	if val, _ := lookupEnv(key); strings.TrimSpace(val) == "0" {
+		return 0
+	}
+	return getEnvBytes(key, defaultVal)
+}
+
 // getSecret reads a secret value from a file if the fileEnvKey env var is set,
 // otherwise falls back to the plain envKey env var. File contents are trimmed
 // of whitespace so keys stored with a trailing newline work correctly.
 // If the file path is set but the file cannot be read, returns empty string.
 func getSecret(envKey, fileEnvKey string) string {
-	if path, exists := os.LookupEnv(fileEnvKey); exists && path != "" {
+	return readEnvOrFile(envKey, fileEnvKey, "secret")
+}
+
+// getEnvOrFile is getSecret for values that are not secret but may be too
+// long to set inline, such as prompts.
+func getEnvOrFile(envKey, fileEnvKey string) string {
+	return readEnvOrFile(envKey, fileEnvKey, "config")
+}
+
+func readEnvOrFile(envKey, fileEnvKey, kind string) string {
+	if path, exists := lookupEnv(fileEnvKey); exists && path != "" {
 		data, err := os.ReadFile(path)
 		if err != nil {
-			slog.Error("failed to read secret file", "env", fileEnvKey, "path", path, "error", err)
+			slog.Error("failed to read "+kind+" file", "env", fileEnvKey, "path", path, "error", err)
 			return ""
 		}
 		return strings.TrimSpace(string(data))
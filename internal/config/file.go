@@ -0,0 +1,322 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Settings can also come from a YAML or TOML file named by CONFIG_FILE. Its
+// keys are the environment variable names, in any case, so
+//
+//	vision_backend: claude
+//	claude_api_key_file: /run/secrets/claude
+//
+// sets VISION_BACKEND and CLAUDE_API_KEY_FILE. An environment variable
+// overrides the same key in the file, and a *_FILE secret, wherever it is
+// set, overrides the plain variable.
+
+var (
+	envMu      sync.Mutex
+	knownEnv   = map[string]bool{} // every key Load has read
+	fileValues map[string]string   // from CONFIG_FILE, keyed by upper-case name
+)
+
+// lookupEnv returns key from the environment, falling back to the config
+// file, and remembers key as one kitchinv reads.
+func lookupEnv(key string) (string, bool) {
+	envMu.Lock()
+	defer envMu.Unlock()
+	knownEnv[key] = true
+	if val, ok := os.LookupEnv(key); ok {
+		return val, true
+	}
+	val, ok := fileValues[key]
+	return val, ok
+}
+
+// loadFile reads path into fileValues for the helpers to fall back on. It
+// returns the error rather than logging it so Validate can report it with
+// everything else.
+func loadFile(path string) error {
+	envMu.Lock()
+	defer envMu.Unlock()
+	fileValues = nil
+	if path == "" {
+		return nil
+	}
+	vals, err := readConfigFile(path)
+	if err != nil {
+		return fmt.Errorf("CONFIG_FILE %q: %w", path, err)
+	}
+	fileValues = vals
+	return nil
+}
+
+// unknownFileKeys returns the config file's keys that Load didn't read,
+// sorted.
+func unknownFileKeys() []string {
+	envMu.Lock()
+	defer envMu.Unlock()
+	var out []string
+	for k := range fileValues {
+		if !knownEnv[k] {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// readConfigFile parses a YAML (.yaml, .yml) or TOML (.toml) file of
+// top-level settings. Lists are joined with commas, as the list variables
+// are written in the environment.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		raw, err = parseTOML(data)
+	default:
+		return nil, fmt.Errorf("unsupported file type; use .yaml, .yml or .toml")
+	}
+	if err != nil {
+		return nil, err
+	}
+	vals := make(map[string]string, len(raw))
+	for k, v := range raw {
+		s, err := settingString(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		vals[strings.ToUpper(k)] = s
+	}
+	return vals, nil
+}
+
+// settingString formats a parsed value the way it would be written in an
+// environment variable.
+func settingString(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	case []any:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			s, err := settingString(e)
+			if err != nil {
+				return "", err
+			}
+			if _, nested := e.([]any); nested {
+				return "", fmt.Errorf("nested lists are not supported")
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v; settings are top-level strings, numbers, booleans or lists", v)
+	}
+}
+
+// parseTOML parses the subset of TOML a flat settings file needs: top-level
+// key = value pairs whose values are strings (basic, literal and their
+// multi-line forms), integers, floats, booleans, or single-line arrays of
+// those. Tables are rejected, as every setting is top-level.
+func parseTOML(data []byte) (map[string]any, error) {
+	out := map[string]any{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		if text[0] == '[' {
+			return nil, fmt.Errorf("line %d: tables are not supported; settings are top-level keys", line)
+		}
+		key, rest, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", line)
+		}
+		key = strings.TrimSpace(key)
+		if k, err := strconv.Unquote(key); err == nil {
+			key = k
+		}
+		if _, dup := out[key]; dup {
+			return nil, fmt.Errorf("line %d: %s is set twice", line, key)
+		}
+		rest = strings.TrimSpace(rest)
+
+		// Multi-line strings run until the closing delimiter.
+		if delim := rest[:min(3, len(rest))]; delim == `"""` || delim == "'''" {
+			body := rest[3:]
+			for !strings.Contains(body, delim) {
+				if !sc.Scan() {
+					return nil, fmt.Errorf("line %d: unterminated multi-line string", line)
+				}
+				line++
+				body += "\n" + sc.Text()
+			}
+			end := strings.Index(body, delim)
+			if tail := strings.TrimSpace(body[end+3:]); tail != "" && tail[0] != '#' {
+				return nil, fmt.Errorf("line %d: unexpected %q after string", line, tail)
+			}
+			s := strings.TrimPrefix(body[:end], "\n") // a newline right after the opening delimiter is trimmed
+			if delim == `"""` {
+				var err error
+				if s, err = unescapeTOML(s); err != nil {
+					return nil, fmt.Errorf("line %d: %w", line, err)
+				}
+			}
+			out[key] = s
+			continue
+		}
+
+		v, tail, err := parseTOMLValue(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if tail = strings.TrimSpace(tail); tail != "" && tail[0] != '#' {
+			return nil, fmt.Errorf("line %d: unexpected %q after value", line, tail)
+		}
+		out[key] = v
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// parseTOMLValue parses one value from the start of s and returns the rest.
+func parseTOMLValue(s string) (any, string, error) {
+	switch {
+	case s == "":
+		return nil, "", fmt.Errorf("missing value")
+	case s[0] == '"':
+		end := 1
+		for ; end < len(s); end++ {
+			if s[end] == '\\' {
+				end++
+			} else if s[end] == '"' {
+				break
+			}
+		}
+		if end >= len(s) {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		v, err := unescapeTOML(s[1:end])
+		return v, s[end+1:], err
+	case s[0] == '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	case s[0] == '[':
+		var list []any
+		rest := strings.TrimSpace(s[1:])
+		for {
+			if rest != "" && rest[0] == ']' {
+				return list, rest[1:], nil
+			}
+			v, tail, err := parseTOMLValue(rest)
+			if err != nil {
+				return nil, "", err
+			}
+			list = append(list, v)
+			rest = strings.TrimSpace(tail)
+			if rest != "" && rest[0] == ',' {
+				rest = strings.TrimSpace(rest[1:])
+			} else if rest == "" || rest[0] != ']' {
+				return nil, "", fmt.Errorf("arrays must be on one line")
+			}
+		}
+	}
+	end := strings.IndexAny(s, " \t,]#")
+	if end < 0 {
+		end = len(s)
+	}
+	word, rest := s[:end], s[end:]
+	switch word {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	}
+	clean := strings.ReplaceAll(word, "_", "")
+	if n, err := strconv.ParseInt(clean, 0, 64); err == nil {
+		return n, rest, nil
+	}
+	if f, err := strconv.ParseFloat(clean, 64); err == nil {
+		return f, rest, nil
+	}
+	return nil, "", fmt.Errorf("invalid value %q", word)
+}
+
+// unescapeTOML resolves the escapes of a TOML basic string.
+func unescapeTOML(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i == len(s) {
+			return "", fmt.Errorf("invalid escape at end of string")
+		}
+		switch c := s[i]; c {
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(c)
+		case 'u', 'U':
+			n := 4
+			if c == 'U' {
+				n = 8
+			}
+			if i+n >= len(s) {
+				return "", fmt.Errorf("invalid escape \\%c", c)
+			}
+			r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape \\%c%s", c, s[i+1:i+1+n])
+			}
+			b.WriteRune(rune(r))
+			i += n
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", c)
+		}
+	}
+	return b.String(), nil
+}
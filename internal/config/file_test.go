@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	t.Setenv("CONFIG_FILE", path)
+	return path
+}
+
+func TestLoadYAMLFile(t *testing.T) {
+	path := writeConfigFile(t, "kitchinv.yaml", `
+vision_backend: gemini
+GEMINI_MODEL: gemini-2.5-pro
+max_concurrent_analyses: 4
+ollama_temperature: 0.5
+vision_timeout: 90s
+openai_stream: true
+ignore_items: [shelf, drawer]
+`)
+	cfg := Load()
+	assert.Equal(t, path, cfg.ConfigFile)
+	assert.Equal(t, "gemini", cfg.VisionBackend)
+	assert.Equal(t, "gemini-2.5-pro", cfg.GeminiModel, "keys are case-insensitive")
+	assert.Equal(t, 4, cfg.MaxConcurrentAnalyses)
+	assert.Equal(t, 0.5, cfg.OllamaTemperature)
+	assert.Equal(t, 90*time.Second, cfg.VisionTimeout)
+	assert.True(t, cfg.OpenAIStream)
+	assert.Equal(t, []string{"shelf", "drawer"}, cfg.IgnoreItems)
+	assert.Equal(t, "moondream", cfg.OllamaModel, "unset keys keep their defaults")
+}
+
+func TestLoadTOMLFile(t *testing.T) {
+	writeConfigFile(t, "kitchinv.toml", `
+# kitchinv settings
+vision_backend = "openai-compatible"   # LM Studio
+openai_model = 'qwen2.5-vl'
+item_page_size = 1_000
+upload_rate_per_minute = 2.5
+demo_mode = false
+shelf_life = ["milk=5d", "bread=3d"]
+vision_prompt = """
+Also list "spices".
+Tab:\there"""
+`)
+	cfg := Load()
+	assert.Equal(t, "openai-compatible", cfg.VisionBackend)
+	assert.Equal(t, "qwen2.5-vl", cfg.OpenAIModel)
+	assert.Equal(t, 1000, cfg.ItemPageSize)
+	assert.Equal(t, 2.5, cfg.UploadRatePerMinute)
+	assert.False(t, cfg.DemoMode)
+	assert.Equal(t, []string{"milk=5d", "bread=3d"}, cfg.ShelfLife)
+	assert.Equal(t, "Also list \"spices\".\nTab:\there", cfg.VisionPrompt)
+}
+
+// Precedence, lowest first: the config file, the environment, then a
+// *_FILE secret from either.
+func TestLoadPrecedence(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "claude_key")
+	require.NoError(t, os.WriteFile(secret, []byte("key-from-secret\n"), 0o600))
+
+	writeConfigFile(t, "kitchinv.yaml", `
+claude_model: model-from-file
+ollama_model: model-from-file
+claude_api_key: key-from-file
+gemini_api_key: key-from-file
+mqtt_password_file: `+secret+`
+`)
+	t.Setenv("OLLAMA_MODEL", "model-from-env")
+	t.Setenv("GEMINI_API_KEY", "key-from-env")
+	t.Setenv("CLAUDE_API_KEY", "key-from-env")
+	t.Setenv("CLAUDE_API_KEY_FILE", secret)
+
+	cfg := Load()
+	assert.Equal(t, "model-from-file", cfg.ClaudeModel, "file < default")
+	assert.Equal(t, "model-from-env", cfg.OllamaModel, "env < file")
+	assert.Equal(t, "key-from-env", cfg.GeminiAPIKey, "env overrides the file's secret")
+	assert.Equal(t, "key-from-secret", cfg.ClaudeAPIKey, "a secret file overrides env")
+	assert.Equal(t, "key-from-secret", cfg.MQTTPassword, "*_FILE can be set in the config file too")
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"missing.yaml":  "",
+		"bad.yaml":      "vision_backend: [",
+		"nested.yaml":   "mqtt:\n  broker: tcp://x\n",
+		"table.toml":    "[mqtt]\nbroker = \"tcp://x\"\n",
+		"unquoted.toml": "vision_backend = claude\n",
+		"settings.json": "{}",
+	} {
+		path := filepath.Join(t.TempDir(), name)
+		if name != "missing.yaml" {
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		}
+		t.Setenv("CONFIG_FILE", path)
+		t.Setenv("DB_PATH", filepath.Join(t.TempDir(), "kitchinv.db"))
+		t.Setenv("PHOTO_LOCAL_PATH", t.TempDir())
+
+		errs := Load().Validate()
+		require.Len(t, errs, 1, name)
+		assert.Contains(t, errs[0].Error(), "CONFIG_FILE", name)
+	}
+}
+
+func TestUnknownFileKeys(t *testing.T) {
+	writeConfigFile(t, "kitchinv.yaml", "ollama_model: llava\nolama_host: http://gpu:11434\n")
+	Load()
+	assert.Equal(t, []string{"OLAMA_HOST"}, unknownFileKeys())
+}
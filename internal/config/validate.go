@@ -0,0 +1,286 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VisionBackends, PhotoBackends and LogLevels are the accepted
+// VISION_BACKEND, PHOTO_BACKEND and log level values.
+var (
+	VisionBackends = []string{"ollama", "claude", "gemini", "openai-compatible", "fake"}
+	PhotoBackends  = []string{"local", "memory"}
+	LogLevels      = []string{"debug", "info", "warn", "error"}
+)
+
+// envPrefix is a prefix some deployments put on every variable out of
+// habit. kitchinv reads none with it, so one set is almost certainly a typo.
+const envPrefix = "KITCHINV_"
+
+// Validate checks the configuration for values that would otherwise only
+// fail later, or silently fall back to a default: an unreadable config
+// file, unknown backend names and log levels, missing credentials, unusable
+// paths, an unparseable listen address, incomplete TLS settings, malformed
+// trusted proxies, an unusable trace exporter and test mode on a reachable
+// address. It reports every
+// problem found rather than stopping at the first. Directories for the database,
+// photos, log file and self-signed certificate are created if missing.
+func (c *Config) Validate() []error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	if c.fileErr != nil {
+		errs = append(errs, c.fileErr)
+	}
+
+	switch {
+	case !slices.Contains(VisionBackends, c.VisionBackend):
+		add("VISION_BACKEND %q is not one of %s", c.VisionBackend, strings.Join(VisionBackends, ", "))
+	case c.DemoMode:
+		// Demo mode always uses the fake backend, so no credentials are needed.
+	case c.VisionBackend == "claude" && c.ClaudeAPIKey == "":
+		add("CLAUDE_API_KEY (or CLAUDE_API_KEY_FILE) must be set when VISION_BACKEND=claude")
+	case c.VisionBackend == "claude" && c.ClaudeExtraction != "text" && c.ClaudeExtraction != "tools":
+		add("CLAUDE_EXTRACTION %q is not one of text, tools", c.ClaudeExtraction)
+	case c.VisionBackend == "gemini" && c.GeminiAPIKey == "":
+		add("GEMINI_API_KEY (or GEMINI_API_KEY_FILE) must be set when VISION_BACKEND=gemini")
+	case c.VisionBackend == "openai-compatible" && c.OpenAIModel == "":
+		add("OPENAI_MODEL must be set when VISION_BACKEND=openai-compatible")
+	case c.VisionBackend == "ollama" && c.OllamaAPI != "generate" && c.OllamaAPI != "chat":
+		add("OLLAMA_API %q is not one of generate, chat", c.OllamaAPI)
+	}
+	if !slices.Contains(PhotoBackends, c.PhotoBackend) {
+		add("PHOTO_BACKEND %q is not one of %s", c.PhotoBackend, strings.Join(PhotoBackends, ", "))
+	}
+
+	if err := checkListenAddr(c.ListenAddr); err != nil {
+		add("LISTEN_ADDR %q: %w", c.ListenAddr, err)
+	} else if c.TestMode && !c.TestModeAllowRemote && !isLoopbackAddr(c.ListenAddr) {
+		add("TEST_MODE exposes endpoints that delete everything; set LISTEN_ADDR to a loopback address such as 127.0.0.1:8080, or TEST_MODE_ALLOW_REMOTE=true if the network is isolated")
+	}
+	c.validateTLS(add)
+	for _, l := range []struct{ key, value string }{
+		{"LOG_LEVEL", c.LogLevel},
+		{"REQUEST_LOG_SUCCESS_LEVEL", c.RequestLogSuccessLevel},
+		{"REQUEST_LOG_CLIENT_ERROR_LEVEL", c.RequestLogClientErrorLevel},
+		{"REQUEST_LOG_SERVER_ERROR_LEVEL", c.RequestLogServerErrorLevel},
+	} {
+		if !slices.Contains(LogLevels, strings.ToLower(l.value)) {
+			add("%s %q is not one of %s", l.key, l.value, strings.Join(LogLevels, ", "))
+		}
+	}
+	for _, p := range c.TrustedProxies {
+		if !validProxy(p) {
+			add("TRUSTED_PROXIES entry %q is not a CIDR range, an IP address or \"unix\"", p)
+		}
+	}
+	if _, err := c.RouteTimeouts(); err != nil {
+		add("REQUEST_TIMEOUT_ROUTES: %w", err)
+	}
+	if ep := c.TracesEndpoint(); ep != "" {
+		if u, err := url.Parse(ep); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("OTLP traces endpoint %q is not an http or https URL", ep)
+		}
+	}
+	if !isMemoryDB(c.DBPath) {
+		if err := checkWritableDir(filepath.Dir(c.DBPath)); err != nil {
+			add("DB_PATH %q: %w", c.DBPath, err)
+		}
+	}
+	if c.PhotoBackend == "local" {
+		if err := checkWritableDir(c.PhotoPath); err != nil {
+			add("PHOTO_LOCAL_PATH %q: %w", c.PhotoPath, err)
+		}
+	}
+	if c.LogFile != "" {
+		if err := checkWritableDir(filepath.Dir(c.LogFile)); err != nil {
+			add("LOG_FILE %q: %w", c.LogFile, err)
+		}
+	}
+	return errs
+}
+
+// validateTLS checks that HTTPS is configured one way or the other, and
+// that a redirect listener has HTTPS to redirect to.
+func (c *Config) validateTLS(add func(format string, args ...any)) {
+	switch {
+	case (c.TLSCertFile == "") != (c.TLSKeyFile == ""):
+		add("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	case c.TLSCertFile != "" && c.TLSSelfSigned:
+		add("TLS_SELF_SIGNED can't be combined with TLS_CERT_FILE; unset one of them")
+	case c.TLSCertFile != "":
+		for _, f := range []struct{ key, path string }{{"TLS_CERT_FILE", c.TLSCertFile}, {"TLS_KEY_FILE", c.TLSKeyFile}} {
+			if _, err := os.Stat(f.path); err != nil {
+				add("%s %q: %w", f.key, f.path, err)
+			}
+		}
+	case c.TLSSelfSigned:
+		if dir := c.SelfSignedDir(); dir == "" {
+			add("TLS_DIR must be set when TLS_SELF_SIGNED is used with an in-memory DB_PATH")
+		} else if err := checkWritableDir(dir); err != nil {
+			add("TLS_DIR %q: %w", dir, err)
+		}
+	}
+	if c.HTTPRedirectAddr == "" {
+		return
+	}
+	if !c.TLSEnabled() {
+		add("HTTP_REDIRECT_ADDR needs TLS_CERT_FILE or TLS_SELF_SIGNED to redirect to")
+	} else if strings.HasPrefix(c.HTTPRedirectAddr, "unix:") {
+		add("HTTP_REDIRECT_ADDR %q: must be a TCP address", c.HTTPRedirectAddr)
+	} else if err := checkListenAddr(c.HTTPRedirectAddr); err != nil {
+		add("HTTP_REDIRECT_ADDR %q: %w", c.HTTPRedirectAddr, err)
+	}
+}
+
+// validProxy reports whether p is a TRUSTED_PROXIES entry.
+func validProxy(p string) bool {
+	if p == "unix" {
+		return true
+	}
+	if _, err := netip.ParsePrefix(p); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(p)
+	return err == nil
+}
+
+// TLSEnabled reports whether the server is configured to serve HTTPS.
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" || c.TLSSelfSigned
+}
+
+// SelfSignedDir returns where the TLS_SELF_SIGNED certificate is kept:
+// TLSDir, or a "tls" directory next to the database. It is empty for an
+// in-memory database without TLSDir.
+func (c *Config) SelfSignedDir() string {
+	if c.TLSDir != "" {
+		return c.TLSDir
+	}
+	if isMemoryDB(c.DBPath) {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(c.DBPath), "tls")
+}
+
+// RouteTimeouts parses RequestTimeoutRoutes into budgets keyed by route
+// pattern.
+func (c *Config) RouteTimeouts() (map[string]time.Duration, error) {
+	out := make(map[string]time.Duration, len(c.RequestTimeoutRoutes))
+	for _, entry := range c.RequestTimeoutRoutes {
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("entry %q is not pattern=duration", entry)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(entry[i+1:]))
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("entry %q has an invalid duration", entry)
+		}
+		out[strings.TrimSpace(entry[:i])] = d
+	}
+	return out, nil
+}
+
+// TracesEndpoint returns the URL spans are exported to, or "" when tracing
+// is off. OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is used as given;
+// OTEL_EXPORTER_OTLP_ENDPOINT gets the traces path appended.
+func (c *Config) TracesEndpoint() string {
+	if c.OTelTracesEndpoint != "" {
+		return c.OTelTracesEndpoint
+	}
+	if c.OTelEndpoint != "" {
+		return strings.TrimSuffix(c.OTelEndpoint, "/") + "/v1/traces"
+	}
+	return ""
+}
+
+// UnknownEnv returns a warning for each KITCHINV_-prefixed variable in
+// environ, which is in os.Environ's form. Load must have been called so the
+// warnings can name the variable that was probably meant.
+func UnknownEnv(environ []string) []string {
+	envMu.Lock()
+	defer envMu.Unlock()
+	var out []string
+	for _, kv := range environ {
+		key, _, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(key, envPrefix) || knownEnv[key] {
+			continue
+		}
+		msg := fmt.Sprintf("%s is not a kitchinv setting and is ignored", key)
+		if trimmed := strings.TrimPrefix(key, envPrefix); knownEnv[trimmed] {
+			msg += fmt.Sprintf("; did you mean %s?", trimmed)
+		}
+		out = append(out, msg)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// checkListenAddr accepts host:port addresses with a numeric port, such as
+// ":8080" or "127.0.0.1:8080", and Unix socket paths such as
+// "unix:/run/kitchinv.sock" in a writable directory.
+func checkListenAddr(addr string) error {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		if path == "" {
+			return fmt.Errorf("socket path is empty")
+		}
+		return checkWritableDir(filepath.Dir(path))
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("port %q is not a number from 0 to 65535", port)
+	}
+	return nil
+}
+
+// isLoopbackAddr reports whether addr only listens on the loopback
+// interface or a Unix socket. An empty host listens on every interface.
+func isLoopbackAddr(addr string) bool {
+	if strings.HasPrefix(addr, "unix:") {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// isMemoryDB reports whether path names an in-memory SQLite database, which
+// has no directory to check.
+func isMemoryDB(path string) bool {
+	return path == ":memory:" || strings.HasPrefix(path, "file:")
+}
+
+// checkWritableDir creates dir if it is missing and checks a file can be
+// created in it.
+func checkWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, ".kitchinv-check-*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+	name := f.Name()
+	_ = f.Close()
+	_ = os.Remove(name)
+	return nil
+}
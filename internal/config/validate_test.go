@@ -0,0 +1,229 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validConfig returns a configuration that passes Validate, with its paths
+// in a temporary directory.
+func validConfig(t *testing.T) *Config {
+	dir := t.TempDir()
+	t.Setenv("DB_PATH", filepath.Join(dir, "data", "kitchinv.db"))
+	t.Setenv("PHOTO_LOCAL_PATH", filepath.Join(dir, "data", "photos"))
+	return Load()
+}
+
+func TestValidate(t *testing.T) {
+	cfg := validConfig(t)
+	assert.Empty(t, cfg.Validate())
+	assert.DirExists(t, cfg.PhotoPath, "missing directories are created")
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.VisionBackend = "olama"
+	cfg.PhotoBackend = "s3"
+	cfg.ListenAddr = "8080"
+
+	var msgs []string
+	for _, err := range cfg.Validate() {
+		msgs = append(msgs, err.Error())
+	}
+	require.Len(t, msgs, 3)
+	assert.Contains(t, msgs[0], `VISION_BACKEND "olama" is not one of ollama, claude`)
+	assert.Contains(t, msgs[1], `PHOTO_BACKEND "s3"`)
+	assert.Contains(t, msgs[2], `LISTEN_ADDR "8080"`)
+}
+
+func TestValidateBackendCredentials(t *testing.T) {
+	for backend, want := range map[string]string{
+		"claude":            "CLAUDE_API_KEY",
+		"gemini":            "GEMINI_API_KEY",
+		"openai-compatible": "OPENAI_MODEL",
+	} {
+		cfg := validConfig(t)
+		cfg.VisionBackend = backend
+		errs := cfg.Validate()
+		require.Len(t, errs, 1, backend)
+		assert.Contains(t, errs[0].Error(), want)
+
+		cfg.DemoMode = true
+		assert.Empty(t, cfg.Validate(), "demo mode never calls the backend")
+	}
+
+	cfg := validConfig(t)
+	cfg.OllamaAPI = "completions"
+	require.Len(t, cfg.Validate(), 1)
+
+	cfg = validConfig(t)
+	cfg.VisionBackend = "claude"
+	cfg.ClaudeAPIKey = "sk-test"
+	cfg.ClaudeExtraction = "pipes"
+	errs := cfg.Validate()
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "CLAUDE_EXTRACTION")
+}
+
+func TestValidateListenAddr(t *testing.T) {
+	for addr, ok := range map[string]bool{
+		":8080":          true,
+		"127.0.0.1:80":   true,
+		"[::1]:8080":     true,
+		"localhost":      false,
+		":http":          false,
+		":99999":         false,
+		"0.0.0.0:8080:1": false,
+		"unix:":          false,
+		"unix:" + filepath.Join(t.TempDir(), "kitchinv.sock"): true,
+	} {
+		assert.Equal(t, ok, checkListenAddr(addr) == nil, addr)
+	}
+}
+
+func TestValidateTestModeListenAddr(t *testing.T) {
+	for addr, ok := range map[string]bool{
+		"127.0.0.1:8080": true,
+		"[::1]:8080":     true,
+		"localhost:8080": true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"10.0.0.5:8080":  false,
+		"unix:" + filepath.Join(t.TempDir(), "kitchinv.sock"): true,
+	} {
+		cfg := validConfig(t)
+		cfg.TestMode = true
+		cfg.ListenAddr = addr
+		assert.Equal(t, ok, len(cfg.Validate()) == 0, addr)
+
+		cfg.TestModeAllowRemote = true
+		assert.Empty(t, cfg.Validate(), addr)
+	}
+}
+
+func TestValidateTLS(t *testing.T) {
+	dir := t.TempDir()
+	cert := filepath.Join(dir, "cert.pem")
+	require.NoError(t, os.WriteFile(cert, []byte("cert"), 0o644))
+
+	cfg := validConfig(t)
+	cfg.TLSCertFile = cert
+	assert.Len(t, cfg.Validate(), 1, "a certificate needs its key")
+
+	cfg.TLSKeyFile = filepath.Join(dir, "missing.pem")
+	errs := cfg.Validate()
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "TLS_KEY_FILE")
+
+	cfg.TLSKeyFile = cert
+	cfg.HTTPRedirectAddr = ":80"
+	assert.Empty(t, cfg.Validate())
+
+	cfg.TLSSelfSigned = true
+	assert.Len(t, cfg.Validate(), 1, "self-signed and given certificates conflict")
+
+	cfg = validConfig(t)
+	cfg.TLSSelfSigned = true
+	assert.Empty(t, cfg.Validate())
+	assert.DirExists(t, cfg.SelfSignedDir())
+
+	cfg.DBPath = ":memory:"
+	assert.Len(t, cfg.Validate(), 1, "an in-memory database has no directory to keep the certificate in")
+
+	cfg = validConfig(t)
+	cfg.HTTPRedirectAddr = ":80"
+	assert.Len(t, cfg.Validate(), 1, "nothing to redirect to without TLS")
+}
+
+func TestValidateTrustedProxies(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.TrustedProxies = []string{"10.0.0.0/8", "172.18.0.2", "fd00::/8", "unix"}
+	assert.Empty(t, cfg.Validate())
+
+	cfg.TrustedProxies = []string{"traefik"}
+	assert.Len(t, cfg.Validate(), 1)
+}
+
+func TestValidateRequestTimeoutRoutes(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.RequestTimeoutRoutes = []string{"GET /export.pdf=2m", "POST /suggest=0"}
+	assert.Empty(t, cfg.Validate())
+	routes, err := cfg.RouteTimeouts()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{"GET /export.pdf": 2 * time.Minute, "POST /suggest": 0}, routes)
+
+	cfg.RequestTimeoutRoutes = []string{"GET /export.pdf"}
+	assert.Len(t, cfg.Validate(), 1)
+	cfg.RequestTimeoutRoutes = []string{"GET /export.pdf=soon"}
+	assert.Len(t, cfg.Validate(), 1)
+}
+
+func TestValidateTracing(t *testing.T) {
+	cfg := validConfig(t)
+	assert.Empty(t, cfg.TracesEndpoint(), "tracing is off by default")
+
+	cfg.OTelEndpoint = "http://collector:4318/"
+	assert.Empty(t, cfg.Validate())
+	assert.Equal(t, "http://collector:4318/v1/traces", cfg.TracesEndpoint())
+	cfg.OTelTracesEndpoint = "https://otlp.example.com/traces"
+	assert.Equal(t, "https://otlp.example.com/traces", cfg.TracesEndpoint(), "the traces endpoint is used as given")
+
+	cfg.OTelProtocol = "http/protobuf"
+	assert.Empty(t, cfg.Validate(), "another protocol is warned about at startup, not refused")
+	cfg.OTelTracesEndpoint = "collector:4318"
+	assert.Len(t, cfg.Validate(), 1)
+}
+
+func TestValidateLogLevels(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.LogLevel = "DEBUG"
+	assert.Empty(t, cfg.Validate(), "levels are case-insensitive")
+
+	cfg.RequestLogClientErrorLevel = "warning"
+	errs := cfg.Validate()
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "REQUEST_LOG_CLIENT_ERROR_LEVEL")
+}
+
+func TestValidateUnwritablePath(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write anywhere")
+	}
+	cfg := validConfig(t)
+	ro := t.TempDir()
+	require.NoError(t, os.Chmod(ro, 0o555))
+	cfg.DBPath = filepath.Join(ro, "kitchinv.db")
+	errs := cfg.Validate()
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "not writable")
+}
+
+func TestValidatePathUnderFile(t *testing.T) {
+	cfg := validConfig(t)
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	require.NoError(t, os.WriteFile(file, nil, 0o644))
+	cfg.PhotoPath = filepath.Join(file, "photos")
+	errs := cfg.Validate()
+	require.Len(t, errs, 1)
+	assert.True(t, strings.HasPrefix(errs[0].Error(), "PHOTO_LOCAL_PATH"))
+}
+
+func TestUnknownEnv(t *testing.T) {
+	Load()
+	warnings := UnknownEnv([]string{
+		"KITCHINV_OLLAMA_MODEL=llava",
+		"KITCHINV_COLOUR=blue",
+		"OLLAMA_MODEL=moondream",
+		"HOME=/root",
+	})
+	assert.Equal(t, []string{
+		"KITCHINV_COLOUR is not a kitchinv setting and is ignored",
+		"KITCHINV_OLLAMA_MODEL is not a kitchinv setting and is ignored; did you mean OLLAMA_MODEL?",
+	}, warnings)
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io/fs"
 	"log/slog"
-	"sort"
 	"strings"
 	"time"
 
@@ -32,12 +31,70 @@ func OpenForTesting() (*sql.DB, error) {
 }
 
 
-func Open(dbPath string) (*sql.DB, error) {
-	// cache=shared enables multiple connections to share the same in-memory page
-	// cache. mode=rwc creates the file if it does not exist. WAL mode allows
+// Options tunes how Open configures the SQLite connection pool. Zero values
+// fall back to the corresponding field in DefaultOptions.
+type Options struct {
+	// BusyTimeout is how long a connection waits on a locked database before
+	// returning SQLITE_BUSY.
+	BusyTimeout time.Duration
+	// Synchronous is the PRAGMA synchronous level: OFF, NORMAL, FULL or EXTRA.
+	Synchronous string
+	// MaxOpenConns and MaxIdleConns bound the database/sql pool.
+	MaxOpenConns int
+	MaxIdleConns int
+	// SkipMigrations leaves the schema as it is, for callers that manage
+	// migrations themselves, like `kitchinv migrate`.
+	SkipMigrations bool
+}
+
+// DefaultOptions are suitable for a home server: writers wait up to five
+// seconds for the lock, and NORMAL sync is durable enough under WAL.
+var DefaultOptions = Options{
+	BusyTimeout:  5 * time.Second,
+	Synchronous:  "NORMAL",
+	MaxOpenConns: 10,
+	MaxIdleConns: 5,
+}
+
+func (o Options) withDefaults() Options {
+	if o.BusyTimeout <= 0 {
+		o.BusyTimeout = DefaultOptions.BusyTimeout
+	}
+	switch strings.ToUpper(o.Synchronous) {
+	case "OFF", "NORMAL", "FULL", "EXTRA":
+		o.Synchronous = strings.ToUpper(o.Synchronous)
+	default:
+		if o.Synchronous != "" {
+			slog.Warn("unknown sqlite synchronous level, using default", "value", o.Synchronous, "default", DefaultOptions.Synchronous)
+		}
+		o.Synchronous = DefaultOptions.Synchronous
+	}
+	if o.MaxOpenConns <= 0 {
+		o.MaxOpenConns = DefaultOptions.MaxOpenConns
+	}
+	if o.MaxIdleConns <= 0 {
+		o.MaxIdleConns = DefaultOptions.MaxIdleConns
+	}
+	if o.MaxIdleConns > o.MaxOpenConns {
+		o.MaxIdleConns = o.MaxOpenConns
+	}
+	return o
+}
+
+func Open(dbPath string, opts Options) (*sql.DB, error) {
+	opts = opts.withDefaults()
+
+	// mode=rwc creates the file if it does not exist. WAL mode allows
 	// concurrent reads alongside a single writer, which matters for a web server.
 	// foreign_keys=on enforces referential integrity, which SQLite disables by default.
-	dsn := fmt.Sprintf("file:%s?cache=shared&mode=rwc&_journal_mode=WAL&_pragma=foreign_keys(1)", dbPath)
+	// busy_timeout and synchronous are applied via _pragma so every pooled
+	// connection gets them, not just the first one. The shared cache is
+	// deliberately not used: it replaces SQLite's busy handler with table-level
+	// locks that fail immediately with "database is locked". _txlock=immediate
+	// takes the write lock at BEGIN, where busy_timeout applies, instead of on
+	// the first write inside the transaction, where SQLite gives up at once.
+	dsn := fmt.Sprintf("file:%s?mode=rwc&_journal_mode=WAL&_txlock=immediate&_pragma=foreign_keys(1)&_pragma=busy_timeout(%d)&_pragma=synchronous(%s)",
+		dbPath, opts.BusyTimeout.Milliseconds(), opts.Synchronous)
 	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -48,12 +105,16 @@ func Open(dbPath string) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	// Configure connection pool. SQLite WAL allows concurrent reads with a
-	// single writer; 10 open connections is sufficient for a home server.
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(5)
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	db.SetMaxIdleConns(opts.MaxIdleConns)
 	db.SetConnMaxLifetime(30 * time.Minute)
 
+	checkIntegrity(db)
+
+	if opts.SkipMigrations {
+		return db, nil
+	}
+
 	// Run migrations
 	if err := runMigrations(db); err != nil {
 		if cerr := db.Close(); cerr != nil {
@@ -65,6 +126,46 @@ func Open(dbPath string) (*sql.DB, error) {
 	return db, nil
 }
 
+// Close runs PRAGMA optimize so SQLite can refresh query planner statistics,
+// then closes the database. An optimize failure is logged, not returned.
+func Close(db *sql.DB) error {
+	if _, err := db.Exec("PRAGMA optimize"); err != nil {
+		slog.Warn("sqlite optimize failed", "error", err)
+	}
+	return db.Close()
+}
+
+// checkIntegrity runs PRAGMA integrity_check and logs a warning if SQLite
+// reports any problems. Startup continues either way; a damaged database is
+// better surfaced in the logs than by refusing to serve.
+func checkIntegrity(db *sql.DB) {
+	rows, err := db.Query("PRAGMA integrity_check")
+	if err != nil {
+		slog.Warn("sqlite integrity check failed to run", "error", err)
+		return
+	}
+	defer func() { _ = rows.Close() }()
+
+	var problems []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			slog.Warn("sqlite integrity check failed to run", "error", err)
+			return
+		}
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		slog.Warn("sqlite integrity check failed to run", "error", err)
+		return
+	}
+	if len(problems) > 0 {
+		slog.Warn("sqlite integrity check reported problems", "problems", problems)
+	}
+}
+
 // execMigration runs a single migration SQL file on a dedicated connection
 // with foreign_keys temporarily disabled. This is required for migrations that
 // recreate tables (SQLite's only way to drop columns or change constraints),
@@ -87,83 +188,23 @@ func execMigration(db *sql.DB, sqlStr string) error {
 }
 
 func runMigrations(db *sql.DB) error {
-	// Create migrations table
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS schema_migrations (
-			version INTEGER PRIMARY KEY,
-			dirty BOOLEAN NOT NULL DEFAULT 0
-		)
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to create migrations table: %w", err)
+	if err := createMigrationsTable(db); err != nil {
+		return err
 	}
-
-	// Get list of migration files
-	entries, err := fs.ReadDir(migrationsFS, "migrations")
+	migrations, err := listMigrations()
 	if err != nil {
-		return fmt.Errorf("failed to read migrations directory: %w", err)
-	}
-
-	// Group migrations by version
-	type migration struct {
-		version int
-		name    string
-		isUp    bool
-	}
-
-	migrations := make(map[int]*migration)
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		name := entry.Name()
-		// Parse version from filename (e.g., "000001_create_areas.up.sql")
-		parts := strings.Split(name, "_")
-		if len(parts) < 3 {
-			continue
-		}
-
-		version := 0
-		if _, err := fmt.Sscanf(parts[0], "%d", &version); err != nil {
-			slog.Warn("skipping migration file", "file", name, "error", err)
-			continue
-		}
-
-		// Only up migrations are applied; down migrations are embedded but not
-		// executed. Rollback is not currently supported.
-		isUp := strings.HasSuffix(name, ".up.sql")
-		if !isUp && !strings.HasSuffix(name, ".down.sql") {
-			continue
-		}
-
-		if _, exists := migrations[version]; !exists {
-			migrations[version] = &migration{version: version}
-		}
-
-		if isUp {
-			migrations[version].isUp = true
-		}
-		migrations[version].name = name
-	}
-
-	// Sort migrations by version
-	var versions []int
-	for v := range migrations {
-		versions = append(versions, v)
+		return err
 	}
-	sort.Ints(versions)
 
 	// Apply migrations in order
-	for _, version := range versions {
-		m := migrations[version]
-		if !m.isUp {
+	for _, m := range migrations {
+		if m.up == "" {
 			continue
 		}
 
 		// Check if already applied
 		var applied int
-		err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE version = ?", version).Scan(&applied)
+		err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE version = ?", m.version).Scan(&applied)
 		if err != nil {
 			return fmt.Errorf("failed to check migration status: %w", err)
 		}
@@ -173,17 +214,17 @@ func runMigrations(db *sql.DB) error {
 		}
 
 		// Read and execute migration
-		data, err := fs.ReadFile(migrationsFS, fmt.Sprintf("migrations/%s", m.name))
+		data, err := fs.ReadFile(migrationsFS, "migrations/"+m.up)
 		if err != nil {
-			return fmt.Errorf("failed to read migration %s: %w", m.name, err)
+			return fmt.Errorf("failed to read migration %s: %w", m.up, err)
 		}
 
 		if err := execMigration(db, string(data)); err != nil {
-			return fmt.Errorf("failed to apply migration %s: %w", m.name, err)
+			return fmt.Errorf("failed to apply migration %s: %w", m.up, err)
 		}
 
 		// Record migration
-		if _, err := db.Exec("INSERT INTO schema_migrations (version) VALUES (?)", version); err != nil {
+		if _, err := db.Exec("INSERT INTO schema_migrations (version) VALUES (?)", m.version); err != nil {
 			return fmt.Errorf("failed to record migration: %w", err)
 		}
 	}
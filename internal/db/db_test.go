@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -127,7 +128,7 @@ func TestOpen(t *testing.T) {
 	dir := t.TempDir()
 	dbPath := filepath.Join(dir, "test.db")
 
-	db, err := Open(dbPath)
+	db, err := Open(dbPath, Options{})
 	require.NoError(t, err)
 	t.Cleanup(func() { assert.NoError(t, db.Close()) })
 
@@ -143,3 +144,23 @@ func TestOpen(t *testing.T) {
 	err = db.QueryRow("SELECT COUNT(*) FROM areas").Scan(&count)
 	assert.NoError(t, err, "areas table should exist after Open")
 }
+
+func TestOpenAppliesOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "opts.db")
+	d, err := Open(path, Options{BusyTimeout: 1234 * time.Millisecond, Synchronous: "full"})
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, Close(d)) })
+
+	var busy int
+	require.NoError(t, d.QueryRow("PRAGMA busy_timeout").Scan(&busy))
+	assert.Equal(t, 1234, busy)
+
+	// PRAGMA synchronous reports FULL as 2.
+	var sync int
+	require.NoError(t, d.QueryRow("PRAGMA synchronous").Scan(&sync))
+	assert.Equal(t, 2, sync)
+
+	var fk int
+	require.NoError(t, d.QueryRow("PRAGMA foreign_keys").Scan(&fk))
+	assert.Equal(t, 1, fk)
+}
@@ -0,0 +1,26 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/vbonduro/kitchinv/internal/textfold"
+	"modernc.org/sqlite"
+)
+
+// Register textfold(text) on every connection, for migrations that fill
+// folded columns from existing rows.
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("textfold", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch v := args[0].(type) {
+		case nil:
+			return nil, nil
+		case string:
+			return textfold.Fold(v), nil
+		case []byte:
+			return textfold.Fold(string(v)), nil
+		default:
+			return nil, fmt.Errorf("textfold: unsupported argument type %T", v)
+		}
+	})
+}
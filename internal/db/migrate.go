@@ -0,0 +1,168 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// migration is a schema version and the files that apply and revert it.
+type migration struct {
+	version int
+	name    string // e.g. "create_areas"
+	up      string // file names; empty if missing
+	down    string
+}
+
+// MigrationStatus reports whether one migration has been applied.
+type MigrationStatus struct {
+	Version int
+	Name    string
+	Applied bool
+}
+
+// ErrNoMigrations is returned by MigrateDown when nothing is applied.
+var ErrNoMigrations = errors.New("no migrations are applied")
+
+func createMigrationsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			dirty BOOLEAN NOT NULL DEFAULT 0
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	return nil
+}
+
+// listMigrations returns the embedded migrations in version order. Files
+// are named like "000001_create_areas.up.sql".
+func listMigrations() ([]*migration, error) {
+	entries, err := fs.ReadDir(migrationsFS, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+	byVersion := make(map[int]*migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		file := entry.Name()
+		base, isUp := strings.CutSuffix(file, ".up.sql")
+		if !isUp {
+			var isDown bool
+			if base, isDown = strings.CutSuffix(file, ".down.sql"); !isDown {
+				continue
+			}
+		}
+		prefix, name, ok := strings.Cut(base, "_")
+		if !ok {
+			continue
+		}
+		version := 0
+		if _, err := fmt.Sscanf(prefix, "%d", &version); err != nil {
+			slog.Warn("skipping migration file", "file", file, "error", err)
+			continue
+		}
+		m := byVersion[version]
+		if m == nil {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		}
+		if isUp {
+			m.up = file
+		} else {
+			m.down = file
+		}
+	}
+	out := make([]*migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].version < out[j].version })
+	return out, nil
+}
+
+// Migrate applies every migration not yet applied. Open does this unless
+// Options.SkipMigrations is set.
+func Migrate(db *sql.DB) error {
+	return runMigrations(db)
+}
+
+// MigrateDown reverts the most recently applied migration with its down
+// file and returns it. It returns ErrNoMigrations when none is applied.
+func MigrateDown(db *sql.DB) (MigrationStatus, error) {
+	if err := createMigrationsTable(db); err != nil {
+		return MigrationStatus{}, err
+	}
+	var version int
+	err := db.QueryRow("SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1").Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return MigrationStatus{}, ErrNoMigrations
+	}
+	if err != nil {
+		return MigrationStatus{}, fmt.Errorf("failed to find latest migration: %w", err)
+	}
+	migrations, err := listMigrations()
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+	var m *migration
+	for _, candidate := range migrations {
+		if candidate.version == version {
+			m = candidate
+		}
+	}
+	if m == nil || m.down == "" {
+		return MigrationStatus{}, fmt.Errorf("migration %d has no down file", version)
+	}
+	data, err := fs.ReadFile(migrationsFS, "migrations/"+m.down)
+	if err != nil {
+		return MigrationStatus{}, fmt.Errorf("failed to read migration %s: %w", m.down, err)
+	}
+	if err := execMigration(db, string(data)); err != nil {
+		return MigrationStatus{}, fmt.Errorf("failed to revert migration %s: %w", m.down, err)
+	}
+	if _, err := db.Exec("DELETE FROM schema_migrations WHERE version = ?", version); err != nil {
+		return MigrationStatus{}, fmt.Errorf("failed to record migration: %w", err)
+	}
+	return MigrationStatus{Version: m.version, Name: m.name}, nil
+}
+
+// Migrations lists every embedded migration and whether it is applied.
+func Migrations(db *sql.DB) ([]MigrationStatus, error) {
+	if err := createMigrationsTable(db); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]bool)
+	rows, err := db.Query("SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+		}
+		applied[v] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %w", err)
+	}
+	migrations, err := listMigrations()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		out[i] = MigrationStatus{Version: m.version, Name: m.name, Applied: applied[m.version]}
+	}
+	return out, nil
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateStatusAndDown(t *testing.T) {
+	d, err := Open(filepath.Join(t.TempDir(), "m.db"), Options{SkipMigrations: true})
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	status, err := Migrations(d)
+	require.NoError(t, err)
+	require.NotEmpty(t, status)
+	assert.Equal(t, MigrationStatus{Version: 1, Name: "create_areas"}, status[0])
+	for _, s := range status {
+		assert.False(t, s.Applied, "SkipMigrations leaves the schema alone")
+	}
+
+	require.NoError(t, Migrate(d))
+	status, err = Migrations(d)
+	require.NoError(t, err)
+	latest := status[len(status)-1]
+	assert.True(t, latest.Applied)
+
+	reverted, err := MigrateDown(d)
+	require.NoError(t, err)
+	assert.Equal(t, latest.Version, reverted.Version)
+	status, err = Migrations(d)
+	require.NoError(t, err)
+	assert.False(t, status[len(status)-1].Applied)
+	assert.True(t, status[len(status)-2].Applied)
+
+	require.NoError(t, Migrate(d))
+	status, err = Migrations(d)
+	require.NoError(t, err)
+	assert.True(t, status[len(status)-1].Applied)
+}
+
+// TestMigrateDownAll reverts every migration and applies them again, which
+// checks each down file undoes its up file.
+func TestMigrateDownAll(t *testing.T) {
+	d, err := Open(filepath.Join(t.TempDir(), "m.db"), Options{})
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	for {
+		_, err := MigrateDown(d)
+		if errors.Is(err, ErrNoMigrations) {
+			break
+		}
+		require.NoError(t, err)
+	}
+	var tables int
+	require.NoError(t, d.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name NOT IN ('schema_migrations', 'sqlite_sequence')").Scan(&tables))
+	assert.Zero(t, tables)
+
+	require.NoError(t, Migrate(d))
+}
+
+// TestItemNameFoldedBackfill checks the migration adding name_folded fills
+// it for items created before it.
+func TestItemNameFoldedBackfill(t *testing.T) {
+	d, err := Open(filepath.Join(t.TempDir(), "m.db"), Options{})
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	for {
+		reverted, err := MigrateDown(d)
+		require.NoError(t, err)
+		if reverted.Name == "item_name_folded" {
+			break
+		}
+	}
+
+	res, err := d.Exec("INSERT INTO areas (name) VALUES ('Fridge')")
+	require.NoError(t, err)
+	areaID, err := res.LastInsertId()
+	require.NoError(t, err)
+	_, err = d.Exec("INSERT INTO items (area_id, name, quantity) VALUES (?, 'Crème Fraîche', '1'), (?, 'Milk', '1')", areaID, areaID)
+	require.NoError(t, err)
+
+	require.NoError(t, Migrate(d))
+	rows, err := d.Query("SELECT name_folded FROM items ORDER BY id")
+	require.NoError(t, err)
+	defer func() { _ = rows.Close() }()
+	var folded []string
+	for rows.Next() {
+		var f string
+		require.NoError(t, rows.Scan(&f))
+		folded = append(folded, f)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"creme fraiche", "milk"}, folded)
+}
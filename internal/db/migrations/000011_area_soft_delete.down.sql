@@ -0,0 +1,22 @@
+-- Trashed areas cannot coexist with the restored UNIQUE(name) constraint, so
+-- they are hard-deleted first. Foreign keys are off while migrations run, so
+-- dependent rows are removed explicitly rather than by cascade.
+DELETE FROM items WHERE area_id IN (SELECT id FROM areas WHERE deleted_at IS NOT NULL);
+DELETE FROM photos WHERE area_id IN (SELECT id FROM areas WHERE deleted_at IS NOT NULL);
+DELETE FROM area_snapshots WHERE area_id IN (SELECT id FROM areas WHERE deleted_at IS NOT NULL);
+DELETE FROM override_rule_areas WHERE area_id IN (SELECT id FROM areas WHERE deleted_at IS NOT NULL);
+DELETE FROM areas WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE areas_new (
+    id         INTEGER  PRIMARY KEY AUTOINCREMENT,
+    name       TEXT     NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    updated_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    sort_order INTEGER  NOT NULL DEFAULT 0
+);
+
+INSERT INTO areas_new (id, name, created_at, updated_at, sort_order)
+SELECT id, name, created_at, updated_at, sort_order FROM areas;
+
+DROP TABLE areas;
+ALTER TABLE areas_new RENAME TO areas;
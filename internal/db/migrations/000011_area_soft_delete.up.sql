@@ -0,0 +1,21 @@
+-- Soft delete for areas. deleted_at marks an area as trashed until it is either
+-- restored or purged after the retention window. The inline UNIQUE on name is
+-- replaced by a partial index so a new area can reuse the name of a trashed one.
+-- Foreign keys are disabled by the migration runner for the duration of this file.
+CREATE TABLE areas_new (
+    id         INTEGER  PRIMARY KEY AUTOINCREMENT,
+    name       TEXT     NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    updated_at DATETIME NOT NULL DEFAULT (datetime('now')),
+    sort_order INTEGER  NOT NULL DEFAULT 0,
+    deleted_at DATETIME
+);
+
+INSERT INTO areas_new (id, name, created_at, updated_at, sort_order)
+SELECT id, name, created_at, updated_at, sort_order FROM areas;
+
+DROP TABLE areas;
+ALTER TABLE areas_new RENAME TO areas;
+
+CREATE UNIQUE INDEX idx_areas_name_live ON areas(name) WHERE deleted_at IS NULL;
+CREATE INDEX idx_areas_deleted_at ON areas(deleted_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_photos_analysis_status;
+ALTER TABLE photos DROP COLUMN analysis_error;
+ALTER TABLE photos DROP COLUMN analysis_status;
@@ -0,0 +1,8 @@
+-- Track vision analysis per photo so a restart mid-analysis can be detected
+-- instead of leaving the area in the analysing state forever. Existing photos
+-- predate the column and are treated as complete.
+ALTER TABLE photos ADD COLUMN analysis_status TEXT NOT NULL DEFAULT 'complete'
+    CHECK(analysis_status IN ('running', 'complete', 'failed'));
+ALTER TABLE photos ADD COLUMN analysis_error TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_photos_analysis_status ON photos(analysis_status);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_photos_storage_key;
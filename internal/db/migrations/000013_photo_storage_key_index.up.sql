@@ -0,0 +1,3 @@
+-- Photo files are content-addressed, so several rows can share a storage key.
+-- The file is only removed once no row references it; index the lookup.
+CREATE INDEX IF NOT EXISTS idx_photos_storage_key ON photos(storage_key);
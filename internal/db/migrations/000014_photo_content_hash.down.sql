@@ -0,0 +1 @@
+ALTER TABLE photos DROP COLUMN content_hash;
@@ -0,0 +1,4 @@
+-- SHA-256 of the uploaded bytes, used to recognise a re-upload of the area's
+-- latest photo and skip analysing it again. Empty for photos uploaded before
+-- the column existed.
+ALTER TABLE photos ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
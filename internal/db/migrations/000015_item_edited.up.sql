@@ -0,0 +1,7 @@
+-- Items the user created or corrected are kept when a new photo is analysed;
+-- only machine-generated ones are replaced. Backfill from the item source and
+-- the edit history so existing corrections are protected too.
+ALTER TABLE items ADD COLUMN edited INTEGER NOT NULL DEFAULT 0;
+
+UPDATE items SET edited = 1
+WHERE source = 'user' OR id IN (SELECT item_id FROM item_edits);
@@ -0,0 +1,3 @@
+-- Optional per-area instructions sent to the vision backend in place of its
+-- default prompt. NULL means use the default.
+ALTER TABLE areas ADD COLUMN prompt TEXT;
@@ -0,0 +1,8 @@
+-- User-managed names that are dropped from vision results before items are
+-- stored, on top of the IGNORE_ITEMS built-ins. A leading or trailing '*'
+-- makes the pattern match names ending or starting with the rest.
+CREATE TABLE ignored_items (
+    id         INTEGER  PRIMARY KEY AUTOINCREMENT,
+    pattern    TEXT     NOT NULL UNIQUE COLLATE NOCASE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+-- The vision backend's unparsed reply for the photo, kept for debugging
+-- analyses that came back empty. Truncated by the service before storing.
+ALTER TABLE photos ADD COLUMN raw_response TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE photos DROP COLUMN eval_duration_ms;
+ALTER TABLE photos DROP COLUMN cost_usd;
+ALTER TABLE photos DROP COLUMN output_tokens;
+ALTER TABLE photos DROP COLUMN input_tokens;
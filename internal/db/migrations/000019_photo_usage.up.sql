@@ -0,0 +1,6 @@
+-- Token usage and estimated cost of the photo's vision analysis, as reported
+-- by the backend. Zero when not reported.
+ALTER TABLE photos ADD COLUMN input_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE photos ADD COLUMN output_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE photos ADD COLUMN cost_usd REAL NOT NULL DEFAULT 0;
+ALTER TABLE photos ADD COLUMN eval_duration_ms INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE areas DROP COLUMN kind;
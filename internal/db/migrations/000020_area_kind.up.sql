@@ -0,0 +1,3 @@
+-- What the area is (fridge, freezer, ...), used for its icon and to pick a
+-- tailored vision prompt. Existing areas start as 'other'.
+ALTER TABLE areas ADD COLUMN kind TEXT NOT NULL DEFAULT 'other';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_areas_name_live;
+CREATE UNIQUE INDEX idx_areas_name_live ON areas(name) WHERE deleted_at IS NULL;
+DROP INDEX IF EXISTS idx_areas_parent_id;
+ALTER TABLE areas DROP COLUMN parent_id;
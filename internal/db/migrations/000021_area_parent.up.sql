@@ -0,0 +1,9 @@
+-- Optional parent for a sub-area such as a freezer basket or pantry shelf.
+-- Only one level of nesting is allowed; that is enforced by the service.
+ALTER TABLE areas ADD COLUMN parent_id INTEGER REFERENCES areas(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_areas_parent_id ON areas(parent_id);
+
+-- Names only need to be unique among siblings, so two freezers can each
+-- have a "Top basket".
+DROP INDEX IF EXISTS idx_areas_name_live;
+CREATE UNIQUE INDEX idx_areas_name_live ON areas(COALESCE(parent_id, 0), name) WHERE deleted_at IS NULL;
@@ -0,0 +1,3 @@
+-- Bumped on every edit so concurrent editors can detect that an item changed
+-- under them.
+ALTER TABLE items ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,15 @@
+-- Destructive actions (deletes, restores, item replacement), kept so a user
+-- can find out what removed their inventory. area_id is denormalised from the
+-- entity so the log can be filtered by area after the area itself is purged.
+CREATE TABLE audit_log (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    created_at  DATETIME NOT NULL DEFAULT (datetime('now')),
+    action      TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id   INTEGER NOT NULL,
+    area_id     INTEGER,
+    detail      TEXT NOT NULL DEFAULT '{}',
+    request_id  TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX idx_audit_log_area_id ON audit_log(area_id, created_at);
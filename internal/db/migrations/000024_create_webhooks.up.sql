@@ -0,0 +1,23 @@
+-- Outbound webhooks. events is a comma-separated list of event types the
+-- hook is subscribed to; secret signs each payload with HMAC-SHA256.
+CREATE TABLE webhooks (
+    id         INTEGER  PRIMARY KEY AUTOINCREMENT,
+    url        TEXT     NOT NULL,
+    secret     TEXT     NOT NULL,
+    events     TEXT     NOT NULL,
+    enabled    INTEGER  NOT NULL DEFAULT 1,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One row per delivery attempt, trimmed to the most recent per webhook.
+CREATE TABLE webhook_deliveries (
+    id          INTEGER  PRIMARY KEY AUTOINCREMENT,
+    webhook_id  INTEGER  NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event       TEXT     NOT NULL,
+    attempt     INTEGER  NOT NULL,
+    status_code INTEGER  NOT NULL DEFAULT 0,
+    error       TEXT     NOT NULL DEFAULT '',
+    duration_ms INTEGER  NOT NULL DEFAULT 0,
+    created_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, id);
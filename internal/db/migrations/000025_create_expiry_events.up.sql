@@ -0,0 +1,8 @@
+-- The calendar event last published for each item's estimated expiry date.
+-- sequence is bumped whenever the date changes so calendar clients replace the
+-- event instead of keeping the old one.
+CREATE TABLE expiry_events (
+    item_id  INTEGER PRIMARY KEY REFERENCES items(id) ON DELETE CASCADE,
+    due_date TEXT NOT NULL,
+    sequence INTEGER NOT NULL DEFAULT 0
+);
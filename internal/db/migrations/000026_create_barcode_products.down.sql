@@ -0,0 +1 @@
+DROP TABLE barcode_products;
@@ -0,0 +1,10 @@
+-- Open Food Facts lookups, cached so scanning the same product again doesn't
+-- need the network. found is 0 for barcodes the database doesn't know, which
+-- are cached too but for less time.
+CREATE TABLE barcode_products (
+    barcode    TEXT PRIMARY KEY,
+    name       TEXT NOT NULL DEFAULT '',
+    quantity   TEXT NOT NULL DEFAULT '',
+    found      INTEGER NOT NULL DEFAULT 1,
+    fetched_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
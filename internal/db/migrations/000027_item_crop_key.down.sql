@@ -0,0 +1 @@
+ALTER TABLE items DROP COLUMN crop_key;
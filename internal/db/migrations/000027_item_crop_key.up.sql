@@ -0,0 +1,3 @@
+-- Photo-store key of the thumbnail cropped around the item's bounding box;
+-- NULL when the vision backend gave no box.
+ALTER TABLE items ADD COLUMN crop_key TEXT;
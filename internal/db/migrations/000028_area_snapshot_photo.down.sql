@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_area_snapshots_photo_id;
+ALTER TABLE area_snapshots DROP COLUMN photo_id;
@@ -0,0 +1,5 @@
+-- The photo whose analysis replaced the snapshotted items; NULL for
+-- snapshots taken before this column existed.
+ALTER TABLE area_snapshots ADD COLUMN photo_id INTEGER;
+
+CREATE INDEX idx_area_snapshots_photo_id ON area_snapshots(photo_id);
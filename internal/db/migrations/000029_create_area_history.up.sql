@@ -0,0 +1,12 @@
+-- The inventory an area was left with after each successful analysis, for
+-- charting how it trends. Unlike area_snapshots, a row is written for every
+-- analysis, including an area's first.
+CREATE TABLE IF NOT EXISTS area_history (
+    id          INTEGER  PRIMARY KEY AUTOINCREMENT,
+    area_id     INTEGER  NOT NULL REFERENCES areas(id) ON DELETE CASCADE,
+    recorded_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    item_count  INTEGER  NOT NULL,
+    items       TEXT     NOT NULL
+);
+
+CREATE INDEX idx_area_history_area_id ON area_history(area_id, id);
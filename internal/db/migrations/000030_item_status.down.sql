@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_items_status_consumed_at;
+ALTER TABLE items DROP COLUMN consumed_at;
+ALTER TABLE items DROP COLUMN status;
@@ -0,0 +1,7 @@
+-- Items leave the inventory by being used up or thrown away rather than
+-- deleted, so waste can be reported. consumed_at is when either happened;
+-- re-analysis only replaces active items.
+ALTER TABLE items ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE items ADD COLUMN consumed_at DATETIME;
+
+CREATE INDEX idx_items_status_consumed_at ON items(status, consumed_at);
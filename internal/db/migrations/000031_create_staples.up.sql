@@ -0,0 +1,12 @@
+-- Items the household always wants on hand. pattern is matched against item
+-- names by word, ignoring case and plurals; min_quantity is how many should
+-- be in stock, across every area unless area_id narrows it to one.
+CREATE TABLE staples (
+    id           INTEGER  PRIMARY KEY AUTOINCREMENT,
+    pattern      TEXT     NOT NULL,
+    min_quantity REAL     NOT NULL DEFAULT 1,
+    area_id      INTEGER  REFERENCES areas(id) ON DELETE CASCADE,
+    created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_staples_pattern_area ON staples(pattern COLLATE NOCASE, IFNULL(area_id, 0));
@@ -0,0 +1,7 @@
+-- name_folded is the item name without diacritics and in lower case, as
+-- textfold.Fold gives it, so searches match "jalapeño" from "jalapeno".
+-- The store keeps it in step with name; textfold() is registered by the db
+-- package for this backfill.
+ALTER TABLE items ADD COLUMN name_folded TEXT NOT NULL DEFAULT '';
+
+UPDATE items SET name_folded = textfold(name);
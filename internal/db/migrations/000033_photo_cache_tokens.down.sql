@@ -0,0 +1,2 @@
+ALTER TABLE photos DROP COLUMN cache_read_tokens;
+ALTER TABLE photos DROP COLUMN cache_write_tokens;
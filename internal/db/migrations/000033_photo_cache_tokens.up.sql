@@ -0,0 +1,5 @@
+-- Prompt-cache usage of the photo's vision analysis: input tokens written to
+-- and read from the cache, which input_tokens leaves out. Zero when the
+-- backend doesn't cache or didn't report it.
+ALTER TABLE photos ADD COLUMN cache_write_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE photos ADD COLUMN cache_read_tokens INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE photos DROP COLUMN truncated;
+ALTER TABLE photos DROP COLUMN stop_reason;
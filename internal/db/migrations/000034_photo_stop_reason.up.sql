@@ -0,0 +1,5 @@
+-- Why the vision backend stopped generating for the photo, as it reported it
+-- ("end_turn", "max_tokens", "length", ...), and whether that was its output
+-- limit, in which case the item list may be incomplete.
+ALTER TABLE photos ADD COLUMN stop_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE photos ADD COLUMN truncated INTEGER NOT NULL DEFAULT 0;
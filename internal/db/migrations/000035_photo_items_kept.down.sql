@@ -0,0 +1 @@
+ALTER TABLE photos DROP COLUMN items_kept;
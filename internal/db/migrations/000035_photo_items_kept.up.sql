@@ -0,0 +1,3 @@
+-- Set when the photo's analysis detected nothing and the area's previous
+-- items were kept rather than wiped, until the user keeps or replaces them.
+ALTER TABLE photos ADD COLUMN items_kept INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE staples DROP COLUMN min_unit;
+ALTER TABLE items DROP COLUMN quantity_unit;
+ALTER TABLE items DROP COLUMN quantity_value;
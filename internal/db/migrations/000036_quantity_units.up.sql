@@ -0,0 +1,13 @@
+-- quantity_value and quantity_unit are the item's quantity as units.Parse
+-- reads it: an amount in grams ('g'), millilitres ('ml') or a count
+-- ('count'), or NULL and '' when the text has no amount. The store keeps
+-- them in step with quantity; quantity_value() and quantity_unit() are
+-- registered by the db package for this backfill.
+ALTER TABLE items ADD COLUMN quantity_value REAL;
+ALTER TABLE items ADD COLUMN quantity_unit TEXT NOT NULL DEFAULT '';
+
+UPDATE items SET quantity_value = quantity_value(quantity), quantity_unit = quantity_unit(quantity);
+
+-- A staple's minimum can be a weight or volume ("2 l" of milk) as well as a
+-- count; min_quantity is in min_unit.
+ALTER TABLE staples ADD COLUMN min_unit TEXT NOT NULL DEFAULT 'count';
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Reset deletes every row from every table except schema_migrations, leaving
+// the schema as it is, and restarts AUTOINCREMENT ids. The tables are found
+// by introspection so ones added by later migrations are cleared too.
+// Shadow tables of virtual tables are left to their owner. It exists for
+// end-to-end tests and must never be reachable in a real deployment.
+func Reset(db *sql.DB) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	// Tables are cleared in whatever order introspection returns, so the
+	// foreign keys between them are off for the duration.
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer func() { _, _ = conn.ExecContext(ctx, "PRAGMA foreign_keys = ON") }()
+
+	tables, err := resettableTables(ctx, conn)
+	if err != nil {
+		return err
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, name := range tables {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+quoteIdent(name)); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// resettableTables lists the tables Reset clears: every table and virtual
+// table in the main schema but schema_migrations and SQLite's own, followed
+// by sqlite_sequence if AUTOINCREMENT created it.
+func resettableTables(ctx context.Context, conn *sql.Conn) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT name FROM pragma_table_list
+		WHERE schema = 'main' AND type IN ('table', 'virtual')
+		  AND name NOT LIKE 'sqlite!_%' ESCAPE '!' AND name != 'schema_migrations'
+		ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var seq int
+	if err := conn.QueryRowContext(ctx, "SELECT count(*) FROM sqlite_master WHERE name = 'sqlite_sequence'").Scan(&seq); err != nil {
+		return nil, err
+	}
+	if seq > 0 {
+		tables = append(tables, "sqlite_sequence")
+	}
+	return tables, nil
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReset(t *testing.T) {
+	d, err := Open(filepath.Join(t.TempDir(), "r.db"), Options{})
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	res, err := d.Exec("INSERT INTO areas (name) VALUES ('Fridge')")
+	require.NoError(t, err)
+	areaID, err := res.LastInsertId()
+	require.NoError(t, err)
+	_, err = d.Exec("INSERT INTO items (area_id, name, quantity) VALUES (?, 'Milk', '1')", areaID)
+	require.NoError(t, err)
+	// A table no reset code knows about by name.
+	_, err = d.Exec("CREATE TABLE later_feature (id INTEGER PRIMARY KEY, v TEXT); INSERT INTO later_feature (v) VALUES ('x')")
+	require.NoError(t, err)
+
+	require.NoError(t, Reset(d))
+
+	for _, table := range []string{"areas", "items", "later_feature"} {
+		var n int
+		require.NoError(t, d.QueryRow("SELECT count(*) FROM "+table).Scan(&n))
+		assert.Zero(t, n, table)
+	}
+	status, err := Migrations(d)
+	require.NoError(t, err)
+	assert.True(t, status[len(status)-1].Applied, "the migration history is kept")
+
+	res, err = d.Exec("INSERT INTO areas (name) VALUES ('Pantry')")
+	require.NoError(t, err)
+	id, err := res.LastInsertId()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id, "ids start again from 1")
+}
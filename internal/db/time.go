@@ -0,0 +1,10 @@
+package db
+
+import "time"
+
+// FormatTime formats t the way SQLite's CURRENT_TIMESTAMP and datetime('now')
+// store timestamps, so a bound parameter compares correctly, as text, against
+// DATETIME columns SQLite filled in.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/vbonduro/kitchinv/internal/units"
+	"modernc.org/sqlite"
+)
+
+// Register quantity_value(text) and quantity_unit(text) on every
+// connection, for migrations that fill the parsed quantity columns from
+// existing rows. Text with no amount gives NULL and an empty unit.
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("quantity_value", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		q, ok, err := parseArg("quantity_value", args[0])
+		if err != nil || !ok {
+			return nil, err
+		}
+		return q.Value, nil
+	})
+	sqlite.MustRegisterDeterministicScalarFunction("quantity_unit", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		q, ok, err := parseArg("quantity_unit", args[0])
+		if err != nil || !ok {
+			return "", err
+		}
+		return string(q.Unit), nil
+	})
+}
+
+// parseArg parses a quantity passed to the SQL function fn.
+func parseArg(fn string, v driver.Value) (units.Quantity, bool, error) {
+	switch v := v.(type) {
+	case nil:
+		return units.Quantity{}, false, nil
+	case string:
+		q, ok := units.Parse(v)
+		return q, ok, nil
+	case []byte:
+		q, ok := units.Parse(string(v))
+		return q, ok, nil
+	default:
+		return units.Quantity{}, false, fmt.Errorf("%s: unsupported argument type %T", fn, v)
+	}
+}
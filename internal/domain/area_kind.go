@@ -0,0 +1,58 @@
+package domain
+
+// AreaKind classifies a storage area. It picks the card icon and, when the
+// area has no custom prompt, a vision prompt suited to what it holds.
+type AreaKind string
+
+const (
+	AreaKindFridge    AreaKind = "fridge"
+	AreaKindFreezer   AreaKind = "freezer"
+	AreaKindPantry    AreaKind = "pantry"
+	AreaKindSpiceRack AreaKind = "spice_rack"
+	AreaKindOther     AreaKind = "other"
+)
+
+// AreaKinds lists every kind in the order the UI offers them.
+var AreaKinds = []AreaKind{AreaKindFridge, AreaKindFreezer, AreaKindPantry, AreaKindSpiceRack, AreaKindOther}
+
+// ParseAreaKind returns the kind named s, reporting false for unknown names.
+func ParseAreaKind(s string) (AreaKind, bool) {
+	for _, k := range AreaKinds {
+		if string(k) == s {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// Label is the kind's display name.
+func (k AreaKind) Label() string {
+	switch k {
+	case AreaKindFridge:
+		return "Fridge"
+	case AreaKindFreezer:
+		return "Freezer"
+	case AreaKindPantry:
+		return "Pantry"
+	case AreaKindSpiceRack:
+		return "Spice rack"
+	default:
+		return "Other"
+	}
+}
+
+// Icon is the emoji shown beside the area's name.
+func (k AreaKind) Icon() string {
+	switch k {
+	case AreaKindFridge:
+		return "🧊"
+	case AreaKindFreezer:
+		return "❄️"
+	case AreaKindPantry:
+		return "🥫"
+	case AreaKindSpiceRack:
+		return "🌶️"
+	default:
+		return "📦"
+	}
+}
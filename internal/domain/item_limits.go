@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ItemLimits bound the length, in characters, of an item's name and
+// quantity. A limit of 0 or less means no limit.
+type ItemLimits struct {
+	Name     int
+	Quantity int
+}
+
+// DefaultItemLimits are long enough for any real product name and quantity
+// while keeping a runaway model answer from breaking the layout.
+var DefaultItemLimits = ItemLimits{Name: 200, Quantity: 100}
+
+// FieldError reports what is wrong with one field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// CleanItemText trims s and drops control characters, such as newlines and
+// escape sequences, which have no place in a one-line field.
+func CleanItemText(s string) string {
+	if strings.IndexFunc(s, unicode.IsControl) >= 0 {
+		s = strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) {
+				return -1
+			}
+			return r
+		}, s)
+	}
+	return strings.TrimSpace(s)
+}
+
+// Check returns what is wrong with an item's name and quantity, which
+// should already be cleaned with CleanItemText: a missing name or either
+// field over its limit.
+func (l ItemLimits) Check(name, quantity string) []FieldError {
+	var errs []FieldError
+	switch {
+	case name == "":
+		errs = append(errs, FieldError{Field: "name", Message: "item name required"})
+	case l.Name > 0 && utf8.RuneCountInString(name) > l.Name:
+		errs = append(errs, FieldError{Field: "name", Message: fmt.Sprintf("item name must be at most %d characters", l.Name)})
+	}
+	if l.Quantity > 0 && utf8.RuneCountInString(quantity) > l.Quantity {
+		errs = append(errs, FieldError{Field: "quantity", Message: fmt.Sprintf("quantity must be at most %d characters", l.Quantity)})
+	}
+	return errs
+}
+
+// Clamp cleans name and quantity and cuts each to its limit, for text from
+// sources that can't be asked to try again, such as the vision model.
+func (l ItemLimits) Clamp(name, quantity string) (string, string) {
+	return truncate(CleanItemText(name), l.Name), truncate(CleanItemText(quantity), l.Quantity)
+}
+
+// truncate cuts s to at most n characters, dropping any space the cut
+// leaves at the end.
+func truncate(s string, n int) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	i := 0
+	for range n {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return strings.TrimRightFunc(s[:i], unicode.IsSpace)
+}
@@ -1,22 +1,100 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 type Area struct {
-	ID        int64
-	Name      string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID   int64
+	Name string
+	// Prompt replaces the vision backend's default instructions when
+	// analysing this area's photos. Empty means use the default.
+	Prompt string
+	Kind   AreaKind
+	// ParentID is set on a sub-area, such as a basket inside a freezer.
+	// ParentName is filled in alongside it for display.
+	ParentID   *int64
+	ParentName string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Path is the area's name qualified by its parent's, e.g.
+// "Freezer › Top basket".
+func (a *Area) Path() string {
+	if a.ParentName == "" {
+		return a.Name
+	}
+	return a.ParentName + " › " + a.Name
 }
 
 type Photo struct {
-	ID         int64
-	AreaID     int64
-	StorageKey string
-	MimeType   string
-	UploadedAt time.Time
+	ID             int64               `json:"ID"`
+	AreaID         int64               `json:"AreaID"`
+	StorageKey     string              `json:"-"`
+	MimeType       string              `json:"MimeType"`
+	ContentHash    string              `json:"ContentHash,omitempty"` // hex SHA-256 of the image
+	UploadedAt     time.Time           `json:"UploadedAt"`
+	AnalysisStatus PhotoAnalysisStatus `json:"AnalysisStatus,omitempty"`
+	AnalysisError  string              `json:"AnalysisError,omitempty"`
+	// StopReason is why the vision backend stopped generating, as it
+	// reported it. Truncated means it stopped at its output limit, so the
+	// photo's items may be incomplete.
+	StopReason string `json:"StopReason,omitempty"`
+	Truncated  bool   `json:"Truncated,omitempty"`
+	// ItemsKept means the analysis detected nothing, so the area's previous
+	// items were kept instead of being replaced, pending the user's say.
+	ItemsKept bool `json:"ItemsKept,omitempty"`
+	AnalysisUsage
+}
+
+// AnalysisUsage is what a photo's vision analysis consumed. Zero fields were
+// not reported by the backend (e.g. Ollama has no cost, Claude no timing).
+type AnalysisUsage struct {
+	InputTokens    int     `json:"InputTokens,omitempty"`
+	OutputTokens   int     `json:"OutputTokens,omitempty"`
+	CostUSD        float64 `json:"CostUSD,omitempty"` // estimated from the configured token prices
+	EvalDurationMs int64   `json:"EvalDurationMs,omitempty"`
+	// CacheWriteTokens and CacheReadTokens are input tokens written to and
+	// read from the backend's prompt cache, on top of InputTokens.
+	CacheWriteTokens int `json:"CacheWriteTokens,omitempty"`
+	CacheReadTokens  int `json:"CacheReadTokens,omitempty"`
 }
 
+// TotalTokens is InputTokens plus OutputTokens.
+func (u AnalysisUsage) TotalTokens() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// EvalSeconds is EvalDurationMs in seconds, for display.
+func (u AnalysisUsage) EvalSeconds() float64 {
+	return float64(u.EvalDurationMs) / 1000
+}
+
+// MonthlyUsage totals the analysis usage of photos uploaded in one month.
+type MonthlyUsage struct {
+	Month    string `json:"Month"` // YYYY-MM
+	Analyses int    `json:"Analyses"`
+	AnalysisUsage
+}
+
+// MonthlyWaste counts the items used up and thrown away in one month.
+type MonthlyWaste struct {
+	Month     string `json:"Month"` // YYYY-MM
+	Consumed  int    `json:"Consumed"`
+	Discarded int    `json:"Discarded"`
+}
+
+// PhotoAnalysisStatus tracks the vision analysis of an uploaded photo.
+type PhotoAnalysisStatus string
+
+const (
+	PhotoAnalysisRunning  PhotoAnalysisStatus = "running"
+	PhotoAnalysisComplete PhotoAnalysisStatus = "complete"
+	PhotoAnalysisFailed   PhotoAnalysisStatus = "failed"
+)
+
 // ItemSource indicates how an item was originally created.
 type ItemSource string
 
@@ -25,28 +103,173 @@ const (
 	ItemSourceUser ItemSource = "user"
 )
 
+// ItemStatus is whether an item is still in the inventory. Items leave it by
+// being used up or thrown away rather than deleted, so waste can be counted.
+type ItemStatus string
+
+const (
+	ItemStatusActive    ItemStatus = "active"
+	ItemStatusConsumed  ItemStatus = "consumed"
+	ItemStatusDiscarded ItemStatus = "discarded"
+	// ItemStatusAll is not a status; in an ItemPage it lists every item.
+	ItemStatusAll ItemStatus = "all"
+)
+
+// ParseItemStatus validates an item status filter, including ItemStatusAll.
+func ParseItemStatus(s string) (ItemStatus, bool) {
+	switch ItemStatus(s) {
+	case ItemStatusActive, ItemStatusConsumed, ItemStatusDiscarded, ItemStatusAll:
+		return ItemStatus(s), true
+	}
+	return "", false
+}
+
 type Item struct {
 	ID        int64      `json:"ID"`
 	AreaID    int64      `json:"AreaID"`
 	PhotoID   *int64     `json:"PhotoID,omitempty"`
 	Name      string     `json:"Name"`
 	Quantity  string     `json:"Quantity"`
+	// QuantityValue and QuantityUnit are Quantity as units.Parse reads it,
+	// in grams, millilitres or a count; nil and "" when it has no amount.
+	QuantityValue *float64 `json:"QuantityValue,omitempty"`
+	QuantityUnit  string   `json:"QuantityUnit,omitempty"`
 	Source    ItemSource `json:"Source"`
 	BBoxes    [][]float64 `json:"BBoxes,omitempty"`
+	CropKey   string     `json:"-"` // thumbnail cropped from the photo; empty if there is none
+	Edited    bool       `json:"Edited,omitempty"` // created or corrected by the user; kept across re-analysis
+	Version   int64      `json:"Version"`          // bumped on every edit; see ErrItemConflict
+	Status    ItemStatus `json:"Status"`
+	ConsumedAt *time.Time `json:"ConsumedAt,omitempty"` // when it was used up or thrown away
 	CreatedAt time.Time  `json:"CreatedAt"`
 	UpdatedAt time.Time  `json:"UpdatedAt"`
 }
 
+// DetectedItem is an item photo analysis found, ready to be stored: one per
+// name, with every box it was seen in.
+type DetectedItem struct {
+	Name     string
+	Quantity string
+	BBoxes   [][]float64
+}
+
+// ErrItemConflict is returned when an item update names a version that is no
+// longer current because someone else edited the item first.
+var ErrItemConflict = errors.New("item was changed by someone else")
+
+// RecentItem is an item in the cross-area recent feed, with the name of the
+// area it is in.
+type RecentItem struct {
+	Item
+	AreaName string `json:"AreaName"`
+}
+
+// ItemPreview is what an area card shows of an area's active items: how
+// many there are, how many are out of stock and the first few by name.
+type ItemPreview struct {
+	Count      int
+	OutOfStock int // items whose quantity reads as zero
+	Items      []*Item
+}
+
+// ItemSort names an order for a page of items.
+type ItemSort string
+
+const (
+	ItemSortName      ItemSort = "name"       // A to Z
+	ItemSortCreatedAt ItemSort = "created_at" // newest first
+	ItemSortQuantity  ItemSort = "quantity"   // largest leading number first
+)
+
+// ParseItemSort returns the sort named s, reporting false for unknown names.
+func ParseItemSort(s string) (ItemSort, bool) {
+	switch ItemSort(s) {
+	case ItemSortName, ItemSortCreatedAt, ItemSortQuantity:
+		return ItemSort(s), true
+	}
+	return "", false
+}
+
+// ItemPage selects a window of a sorted item list. The zero value is every
+// active item sorted by name.
+type ItemPage struct {
+	Sort   ItemSort
+	Limit  int // 0 means no limit
+	Offset int
+	Status ItemStatus // "" means active
+}
+
+// SearchField names an item field a search matches the query against.
+type SearchField string
+
+const (
+	SearchName     SearchField = "name"
+	SearchQuantity SearchField = "quantity"
+)
+
+// ParseSearchField returns the field named s, reporting false for unknown
+// names.
+func ParseSearchField(s string) (SearchField, bool) {
+	switch SearchField(s) {
+	case SearchName, SearchQuantity:
+		return SearchField(s), true
+	}
+	return "", false
+}
+
+// SearchFilter narrows an item search. The zero value matches names in
+// every area.
+type SearchFilter struct {
+	AreaIDs []int64       // only items directly in these areas; empty means all
+	Fields  []SearchField // fields the query may match; empty means the name
+}
+
+// ItemOpKind names the action performed by one entry of a bulk item edit.
+type ItemOpKind string
+
+const (
+	ItemOpCreate ItemOpKind = "create"
+	ItemOpUpdate ItemOpKind = "update"
+	ItemOpDelete ItemOpKind = "delete"
+)
+
+// ItemOp is one entry of a bulk item edit. ID is required for update and
+// delete; Name is required for create and update. A non-zero Version on an
+// update or delete must match the stored one, as for a single item edit.
+type ItemOp struct {
+	Op       ItemOpKind `json:"op"`
+	ID       int64      `json:"id,omitempty"`
+	Version  int64      `json:"version,omitempty"`
+	Name     string     `json:"name,omitempty"`
+	Quantity string     `json:"quantity,omitempty"`
+}
+
+// ItemOpFailure reports why the bulk entry at Index was rejected.
+type ItemOpFailure struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
 // SnapshotItem is a lightweight item record stored inside a snapshot.
 type SnapshotItem struct {
 	Name     string `json:"name"`
 	Quantity string `json:"quantity,omitempty"`
 }
 
+// HistoryPoint is an area's inventory after one successful analysis.
+type HistoryPoint struct {
+	AreaID     int64
+	RecordedAt time.Time
+	ItemCount  int
+	Items      []SnapshotItem `json:",omitempty"`
+}
+
 // Snapshot captures the item list for an area at a point in time.
 type Snapshot struct {
-	ID      int64
-	AreaID  int64
+	ID     int64
+	AreaID int64
+	// PhotoID is the photo whose analysis replaced these items.
+	PhotoID *int64
 	TakenAt time.Time
 	Items   []SnapshotItem
 }
@@ -75,6 +298,59 @@ type OverrideRule struct {
 	CreatedAt            time.Time
 }
 
+// IgnoredItem is a user-added ignore-list entry; detections whose name
+// matches Pattern are discarded at upload time.
+type IgnoredItem struct {
+	ID        int64
+	Pattern   string
+	CreatedAt time.Time
+}
+
+// Staple is an item the household always wants on hand, matched against item
+// names by Pattern.
+type Staple struct {
+	ID          int64     `json:"ID"`
+	Pattern     string    `json:"Pattern"`
+	MinQuantity float64   `json:"MinQuantity"`
+	MinUnit     string    `json:"MinUnit"`          // "count", "g" or "ml"; see package units
+	AreaID      *int64    `json:"AreaID,omitempty"` // nil counts items in every area
+	CreatedAt   time.Time `json:"CreatedAt"`
+}
+
+// Webhook is an outbound HTTP callback. Each event in Events is POSTed to
+// URL as JSON, signed with Secret.
+type Webhook struct {
+	ID        int64
+	URL       string
+	Secret    string `json:"-"`
+	Events    []string
+	Enabled   bool
+	CreatedAt time.Time
+}
+
+// BarcodeProduct is a cached barcode lookup. Found is false when the product
+// database had no product for the barcode.
+type BarcodeProduct struct {
+	Barcode   string
+	Name      string
+	Quantity  string
+	Found     bool
+	FetchedAt time.Time
+}
+
+// WebhookDelivery records one attempt to deliver an event to a webhook.
+// StatusCode is 0 when no response was received; Error then says why.
+type WebhookDelivery struct {
+	ID         int64
+	WebhookID  int64
+	Event      string
+	Attempt    int
+	StatusCode int
+	Error      string
+	DurationMS int64
+	CreatedAt  time.Time
+}
+
 // EditSuggestion represents a user rename that can be turned into an override rule.
 type EditSuggestion struct {
 	ItemID   int64
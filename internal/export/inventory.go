@@ -0,0 +1,269 @@
+// Package export renders the inventory as documents for printing and
+// sharing outside the app.
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg" // decoder for image.DecodeConfig
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/service"
+)
+
+// PageSize is a paper size in PDF points (1/72 inch).
+type PageSize struct {
+	Name          string
+	Width, Height float64
+}
+
+var (
+	A4     = PageSize{Name: "a4", Width: 595.28, Height: 841.89}
+	Letter = PageSize{Name: "letter", Width: 612, Height: 792}
+)
+
+// ParsePageSize returns the page size named s, "a4" or "letter", ignoring
+// case. An empty name is A4.
+func ParsePageSize(s string) (PageSize, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", A4.Name:
+		return A4, nil
+	case Letter.Name:
+		return Letter, nil
+	}
+	return PageSize{}, fmt.Errorf("unknown page size %q, want a4 or letter", s)
+}
+
+// Source is the inventory a document is made from; *service.AreaService
+// implements it.
+type Source interface {
+	EachReportArea(ctx context.Context, includeEmpty bool, fn func(*service.ReportArea) error) error
+	AreaThumbnail(ctx context.Context, areaID int64, maxSide int) ([]byte, error)
+}
+
+// Options control how the inventory PDF is made.
+type Options struct {
+	// PageSize defaults to A4.
+	PageSize PageSize
+	// GeneratedAt is printed under the title, in its own location.
+	GeneratedAt time.Time
+	// IncludeEmpty keeps areas without items.
+	IncludeEmpty bool
+	// Logger receives photos that couldn't be embedded; nil discards them.
+	Logger *slog.Logger
+}
+
+// Layout, in points unless noted.
+const (
+	margin       = 48.0
+	titleSize    = 18.0
+	headingSize  = 13.0
+	bodySize     = 10.0
+	noteSize     = 9.0
+	rowHeight    = 15.0
+	photoMaxW    = 180.0
+	photoMaxH    = 135.0
+	qtyColumn    = 0.65 // where the quantity column starts, as a fraction of the width
+	thumbMaxSide = 480  // pixels; about 190 dpi at the largest size a photo is drawn
+)
+
+// WritePDF writes the inventory to w as a PDF: a section per area with its
+// latest photo, when it has one, above a table of its items. Each page is
+// written as soon as it is full, and each area's photo when it is reached.
+func WritePDF(ctx context.Context, w io.Writer, src Source, opts Options) error {
+	if opts.PageSize.Width == 0 {
+		opts.PageSize = A4
+	}
+	d := &pdfDoc{pdf: newPDFWriter(w), src: src, opts: opts}
+	d.catalog, d.pages = d.pdf.reserve(), d.pdf.reserve()
+	d.font, d.bold = d.pdf.reserve(), d.pdf.reserve()
+	d.pdf.object(d.catalog, fmt.Sprintf("/Type /Catalog /Pages %d 0 R", d.pages))
+	d.pdf.object(d.font, "/Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding")
+	d.pdf.object(d.bold, "/Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding")
+
+	d.newPage()
+	d.text(margin, d.y-titleSize, true, titleSize, "Kitchen inventory")
+	d.y -= titleSize + 6
+	d.note(margin, d.y-noteSize, "Generated "+opts.GeneratedAt.Format("2006-01-02 15:04 MST"))
+	d.y -= noteSize + 10
+
+	written := false
+	err := src.EachReportArea(ctx, opts.IncludeEmpty, func(a *service.ReportArea) error {
+		written = true
+		return d.area(ctx, a)
+	})
+	if err != nil {
+		return err
+	}
+	if !written {
+		d.text(margin, d.y-bodySize-8, false, bodySize, "No items.")
+	}
+	d.endPage()
+
+	kids := make([]string, len(d.pageNums))
+	for i, n := range d.pageNums {
+		kids[i] = fmt.Sprintf("%d 0 R", n)
+	}
+	d.pdf.object(d.pages, fmt.Sprintf("/Type /Pages /Kids [%s] /Count %d", strings.Join(kids, " "), len(kids)))
+	return d.pdf.finish(d.catalog)
+}
+
+// pdfDoc lays out the inventory PDF page by page.
+type pdfDoc struct {
+	pdf  *pdfWriter
+	src  Source
+	opts Options
+
+	catalog, pages, font, bold int
+	pageNums                   []int
+
+	content bytes.Buffer // the current page's drawing operators
+	images  []int        // image objects the current page draws
+	y       float64      // top of the free space on the current page
+}
+
+func (d *pdfDoc) width() float64 { return d.opts.PageSize.Width - 2*margin }
+
+func (d *pdfDoc) newPage() {
+	d.content.Reset()
+	d.images = d.images[:0]
+	d.y = d.opts.PageSize.Height - margin
+}
+
+// endPage writes out the current page, numbered in its footer, and its
+// content.
+func (d *pdfDoc) endPage() {
+	footer := fmt.Sprintf("Page %d", len(d.pageNums)+1)
+	d.note(d.opts.PageSize.Width-margin-textWidth(footer, false, noteSize), margin/2, footer)
+	page, content := d.pdf.reserve(), d.pdf.reserve()
+	var xobjects strings.Builder
+	for _, n := range d.images {
+		fmt.Fprintf(&xobjects, " /Im%d %d 0 R", n, n)
+	}
+	d.pdf.compressedStream(content, "", d.content.Bytes())
+	d.pdf.object(page, fmt.Sprintf("/Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Contents %d 0 R "+
+		"/Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> /XObject <<%s >> >>",
+		d.pages, d.opts.PageSize.Width, d.opts.PageSize.Height, content, d.font, d.bold, xobjects.String()))
+	d.pdf.flush()
+	d.pageNums = append(d.pageNums, page)
+}
+
+// ensure starts a new page unless h points are left above the bottom
+// margin.
+func (d *pdfDoc) ensure(h float64) {
+	if d.y-h < margin {
+		d.endPage()
+		d.newPage()
+	}
+}
+
+// text draws s with its baseline at (x, y).
+func (d *pdfDoc) text(x, y float64, bold bool, size float64, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&d.content, "BT /%s %.1f Tf %.2f %.2f Td %s Tj ET\n", font, size, x, y, pdfText(s))
+}
+
+// note draws s small and grey.
+func (d *pdfDoc) note(x, y float64, s string) {
+	d.content.WriteString("0.4 g\n")
+	d.text(x, y, false, noteSize, s)
+	d.content.WriteString("0 g\n")
+}
+
+// rule draws a horizontal line across the content width at y.
+func (d *pdfDoc) rule(y, grey float64) {
+	fmt.Fprintf(&d.content, "%.2f G 0.5 w %.2f %.2f m %.2f %.2f l S\n", grey, margin, y, margin+d.width(), y)
+}
+
+// area lays out one area's section. The heading is kept with the photo and
+// the first row of the table.
+func (d *pdfDoc) area(ctx context.Context, a *service.ReportArea) error {
+	photo, pw, ph, err := d.photo(ctx, a.ID)
+	if err != nil {
+		return err
+	}
+	d.ensure(headingSize + 14 + ph + 2*rowHeight)
+	d.y -= 14
+	d.text(margin, d.y-headingSize, true, headingSize, fitText(a.Path, true, headingSize, d.width()))
+	d.y -= headingSize + 6
+	if photo != 0 {
+		d.y -= ph
+		fmt.Fprintf(&d.content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", pw, ph, margin, d.y, photo)
+		d.images = append(d.images, photo)
+		d.y -= 6
+	}
+	if len(a.Items) == 0 {
+		d.y -= rowHeight
+		d.note(margin, d.y+4, "No items.")
+		return nil
+	}
+	d.tableHeader()
+	for _, it := range a.Items {
+		if d.y-rowHeight < margin {
+			d.endPage()
+			d.newPage()
+			d.tableHeader()
+		}
+		d.y -= rowHeight
+		qtyX := margin + d.width()*qtyColumn
+		d.text(margin, d.y+4, false, bodySize, fitText(it.Name, false, bodySize, qtyX-margin-8))
+		d.text(qtyX, d.y+4, false, bodySize, fitText(it.Quantity, false, bodySize, margin+d.width()-qtyX))
+		d.rule(d.y, 0.85)
+	}
+	return nil
+}
+
+// tableHeader draws the item table's column headings.
+func (d *pdfDoc) tableHeader() {
+	d.y -= rowHeight
+	d.text(margin, d.y+4, true, bodySize, "Item")
+	d.text(margin+d.width()*qtyColumn, d.y+4, true, bodySize, "Quantity")
+	d.rule(d.y, 0)
+}
+
+// photo embeds the area's latest photo as an image object and returns its
+// number with the size to draw it at. It returns 0 when the area has no
+// photo that can be embedded.
+func (d *pdfDoc) photo(ctx context.Context, areaID int64) (num int, w, h float64, err error) {
+	data, err := d.src.AreaThumbnail(ctx, areaID, thumbMaxSide)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, 0, 0, err
+		}
+		d.warn("failed to load area photo for pdf", "area_id", areaID, "error", err)
+		return 0, 0, 0, nil
+	}
+	if data == nil {
+		return 0, 0, 0, nil
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		d.warn("area photo is not a usable jpeg", "area_id", areaID, "error", err)
+		return 0, 0, 0, nil
+	}
+	colorSpace := "/DeviceRGB"
+	if cfg.ColorModel == color.GrayModel {
+		colorSpace = "/DeviceGray"
+	}
+	// PDF readers decode JPEGs themselves, so the file is embedded as is.
+	num = d.pdf.reserve()
+	d.pdf.stream(num, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode",
+		cfg.Width, cfg.Height, colorSpace), data)
+	scale := min(photoMaxW/float64(cfg.Width), photoMaxH/float64(cfg.Height))
+	return num, float64(cfg.Width) * scale, float64(cfg.Height) * scale, nil
+}
+
+func (d *pdfDoc) warn(msg string, args ...any) {
+	if d.opts.Logger != nil {
+		d.opts.Logger.Warn(msg, args...)
+	}
+}
@@ -0,0 +1,169 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/service"
+)
+
+type fakeSource struct {
+	areas  []*service.ReportArea
+	photos map[int64][]byte
+	err    error
+}
+
+func (f *fakeSource) EachReportArea(_ context.Context, includeEmpty bool, fn func(*service.ReportArea) error) error {
+	for _, a := range f.areas {
+		if len(a.Items) == 0 && !includeEmpty {
+			continue
+		}
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return f.err
+}
+
+func (f *fakeSource) AreaThumbnail(_ context.Context, areaID int64, _ int) ([]byte, error) {
+	if areaID == 99 {
+		return nil, errors.New("photo file missing")
+	}
+	return f.photos[areaID], nil
+}
+
+func testJPEG(t *testing.T) []byte {
+	var b bytes.Buffer
+	require.NoError(t, jpeg.Encode(&b, image.NewRGBA(image.Rect(0, 0, 40, 30)), nil))
+	return b.Bytes()
+}
+
+// pdfContents returns the decompressed page content streams of doc, after
+// checking every cross-reference offset points at its object.
+func pdfContents(t *testing.T, doc []byte) string {
+	t.Helper()
+	require.True(t, bytes.HasPrefix(doc, []byte("%PDF-1.4\n")))
+	require.True(t, bytes.HasSuffix(doc, []byte("%%EOF\n")))
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(doc)
+	require.NotNil(t, m)
+	xref, err := strconv.Atoi(string(m[1]))
+	require.NoError(t, err)
+	lines := strings.Split(string(doc[xref:]), "\n")
+	require.Equal(t, "xref", lines[0])
+	for i, line := range lines[3:] {
+		if !strings.HasSuffix(line, " n ") {
+			break
+		}
+		off, err := strconv.Atoi(line[:10])
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(doc[off:], fmt.Appendf(nil, "%d 0 obj\n", i+1)), "object %d offset", i+1)
+	}
+
+	var out strings.Builder
+	for _, m := range regexp.MustCompile(`(?s)/Filter /FlateDecode /Length (\d+) >>\nstream\n`).FindAllSubmatchIndex(doc, -1) {
+		n, _ := strconv.Atoi(string(doc[m[2]:m[3]]))
+		zr, err := zlib.NewReader(bytes.NewReader(doc[m[1] : m[1]+n]))
+		require.NoError(t, err)
+		data, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		out.Write(data)
+	}
+	return out.String()
+}
+
+func TestWritePDF(t *testing.T) {
+	src := &fakeSource{
+		areas: []*service.ReportArea{
+			{ID: 1, Path: "Fridge", Items: []*domain.Item{{Name: "Milk (whole)", Quantity: "1 carton"}}},
+			{ID: 2, Path: "Pantry", Items: []*domain.Item{{Name: "Crème fraîche", Quantity: "2"}}},
+			{ID: 3, Path: "Freezer › Basket"},
+			{ID: 99, Path: "Shed", Items: []*domain.Item{{Name: "Jam"}}},
+		},
+		photos: map[int64][]byte{1: testJPEG(t)},
+	}
+	var b bytes.Buffer
+	err := WritePDF(context.Background(), &b, src, Options{
+		GeneratedAt: time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	doc := b.String()
+	content := pdfContents(t, b.Bytes())
+
+	assert.Contains(t, doc, "/MediaBox [0 0 595.28 841.89]", "A4 by default")
+	assert.Contains(t, doc, "/Type /Pages /Kids [")
+	assert.Contains(t, doc, "/Count 1")
+	assert.Equal(t, 1, strings.Count(doc, "/Subtype /Image /Width 40 /Height 30 /ColorSpace /DeviceRGB"), "only the area with a photo has one")
+	assert.Contains(t, content, "(Generated 2026-03-14 09:30 UTC)")
+	assert.Contains(t, content, `(Milk \(whole\))`)
+	assert.Contains(t, content, "(Cr\xe8me fra\xeeche)", "text is WinAnsi encoded")
+	assert.Contains(t, content, "(Shed)", "an unreadable photo leaves the area without one")
+	assert.NotContains(t, content, "Basket", "empty areas are skipped")
+
+	b.Reset()
+	require.NoError(t, WritePDF(context.Background(), &b, src, Options{PageSize: Letter, IncludeEmpty: true}))
+	assert.Contains(t, b.String(), "/MediaBox [0 0 612.00 792.00]")
+	assert.Contains(t, pdfContents(t, b.Bytes()), "(Freezer \x9b Basket)")
+}
+
+func TestWritePDF_Pages(t *testing.T) {
+	items := make([]*domain.Item, 120)
+	for i := range items {
+		items[i] = &domain.Item{Name: fmt.Sprintf("Item %d", i), Quantity: "1"}
+	}
+	var b bytes.Buffer
+	src := &fakeSource{areas: []*service.ReportArea{{ID: 1, Path: "Pantry", Items: items}}}
+	require.NoError(t, WritePDF(context.Background(), &b, src, Options{}))
+	content := pdfContents(t, b.Bytes())
+	assert.Contains(t, b.String(), "/Count 3")
+	assert.Contains(t, content, "(Page 3)")
+	assert.Equal(t, 3, strings.Count(content, "(Quantity)"), "the table header is repeated on each page")
+	assert.Contains(t, content, "(Item 119)")
+}
+
+func TestWritePDF_Empty(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, WritePDF(context.Background(), &b, &fakeSource{}, Options{}))
+	assert.Contains(t, pdfContents(t, b.Bytes()), "(No items.)")
+
+	err := WritePDF(context.Background(), io.Discard, &fakeSource{err: errors.New("db gone")}, Options{})
+	assert.EqualError(t, err, "db gone")
+}
+
+func TestParsePageSize(t *testing.T) {
+	for in, want := range map[string]PageSize{"": A4, "A4": A4, " letter ": Letter} {
+		got, err := ParsePageSize(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	_, err := ParsePageSize("legal")
+	assert.Error(t, err)
+}
+
+func TestFitText(t *testing.T) {
+	assert.Equal(t, "Milk", fitText("Milk", false, 10, 100))
+	got := fitText(strings.Repeat("Long name ", 10), false, 10, 100)
+	assert.True(t, strings.HasSuffix(got, "…"))
+	assert.LessOrEqual(t, textWidth(got, false, 10), 100.0)
+	assert.Equal(t, "", fitText("Milk", true, 10, 1))
+}
+
+func TestPDFText(t *testing.T) {
+	assert.Equal(t, `(a\\b \(c\) d)`, pdfText("a\\b (c) d"))
+	assert.Equal(t, "(\x80 5 \x97 ok ?)", pdfText("€ 5 — ok 日"))
+	assert.Equal(t, "(two lines)", pdfText("two\nlines"))
+}
@@ -0,0 +1,202 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// pdfWriter writes a PDF file object by object, so pages can be sent as soon
+// as they are laid out. Object numbers are handed out by reserve and may be
+// written in any order; finish writes the cross-reference table that lets
+// readers find them.
+type pdfWriter struct {
+	w       *bufio.Writer
+	n       int64   // bytes written so far
+	offsets []int64 // offsets[i] is where object i+1 starts; 0 until written
+	err     error
+}
+
+func newPDFWriter(w io.Writer) *pdfWriter {
+	p := &pdfWriter{w: bufio.NewWriter(w)}
+	// The comment's high bytes mark the file as binary for transfer tools.
+	p.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+	return p
+}
+
+func (p *pdfWriter) printf(format string, args ...any) {
+	if p.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(p.w, format, args...)
+	p.n += int64(n)
+	p.err = err
+}
+
+func (p *pdfWriter) write(b []byte) {
+	if p.err != nil {
+		return
+	}
+	n, err := p.w.Write(b)
+	p.n += int64(n)
+	p.err = err
+}
+
+// reserve allocates an object number to be written later.
+func (p *pdfWriter) reserve() int {
+	p.offsets = append(p.offsets, 0)
+	return len(p.offsets)
+}
+
+// object writes object num as the dictionary dict, given without its
+// enclosing << >>.
+func (p *pdfWriter) object(num int, dict string) {
+	p.offsets[num-1] = p.n
+	p.printf("%d 0 obj\n<< %s >>\nendobj\n", num, dict)
+}
+
+// stream writes object num as a stream of data described by dict, which
+// shouldn't give the length.
+func (p *pdfWriter) stream(num int, dict string, data []byte) {
+	p.offsets[num-1] = p.n
+	p.printf("%d 0 obj\n<< %s /Length %d >>\nstream\n", num, dict, len(data))
+	p.write(data)
+	p.printf("\nendstream\nendobj\n")
+}
+
+// compressedStream writes data as a Flate-compressed stream.
+func (p *pdfWriter) compressedStream(num int, dict string, data []byte) {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	_, _ = zw.Write(data) // writes to a bytes.Buffer can't fail
+	_ = zw.Close()
+	p.stream(num, strings.TrimSpace(dict+" /Filter /FlateDecode"), b.Bytes())
+}
+
+// flush sends everything written so far on to the underlying writer.
+func (p *pdfWriter) flush() {
+	if p.err == nil {
+		p.err = p.w.Flush()
+	}
+}
+
+// finish writes the cross-reference table and trailer for a document whose
+// catalog is object root, and flushes. It returns the first error any write
+// hit.
+func (p *pdfWriter) finish(root int) error {
+	for i, off := range p.offsets {
+		if off == 0 && p.err == nil {
+			p.err = fmt.Errorf("pdf object %d was reserved but never written", i+1)
+		}
+	}
+	xref := p.n
+	p.printf("xref\n0 %d\n0000000000 65535 f \n", len(p.offsets)+1)
+	for _, off := range p.offsets {
+		p.printf("%010d 00000 n \n", off)
+	}
+	p.printf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(p.offsets)+1, root, xref)
+	p.flush()
+	return p.err
+}
+
+// pdfText returns s as a PDF string literal in WinAnsiEncoding, the encoding
+// the document's standard fonts use. Characters it can't represent become
+// "?".
+func pdfText(s string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range s {
+		c := winAnsi(r)
+		switch c {
+		case '\\', '(', ')':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// winAnsiExtras maps the characters WinAnsiEncoding places in 0x80–0x9F.
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91,
+	'’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98,
+	'™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// winAnsi returns r's WinAnsiEncoding code. Control characters become
+// spaces and anything else unrepresentable becomes '?'.
+func winAnsi(r rune) byte {
+	switch {
+	case r < 0x20:
+		return ' '
+	case r < 0x7F, r >= 0xA0 && r <= 0xFF:
+		return byte(r)
+	}
+	if c, ok := winAnsiExtras[r]; ok {
+		return c
+	}
+	return '?'
+}
+
+// helveticaWidths and helveticaBoldWidths are the widths of the printable
+// ASCII characters, from space, in thousandths of the font size, taken from
+// the fonts' Adobe metrics.
+var (
+	helveticaWidths = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	helveticaBoldWidths = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+)
+
+// textWidth estimates the width of s set in the regular or bold font at
+// size points. Characters outside ASCII count as an average letter.
+func textWidth(s string, bold bool, size float64) float64 {
+	widths := &helveticaWidths
+	if bold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for _, r := range s {
+		if r >= ' ' && r <= '~' {
+			total += widths[r-' ']
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// fitText shortens s with an ellipsis until it fits in width.
+func fitText(s string, bold bool, size, width float64) string {
+	if textWidth(s, bold, size) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		t := strings.TrimRight(string(runes), " ") + "…"
+		if textWidth(t, bold, size) <= width {
+			return t
+		}
+	}
+	return ""
+}
@@ -0,0 +1,97 @@
+// Package ical writes iCalendar (RFC 5545) feeds of all-day events, enough
+// for calendar apps to subscribe to.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineOctets is the longest content line RFC 5545 allows before folding,
+// excluding the CRLF.
+const maxLineOctets = 75
+
+// Calendar is a feed of events.
+type Calendar struct {
+	// ProdID identifies the program that made the feed.
+	ProdID string
+	// Name is shown by clients as the calendar's title.
+	Name   string
+	Events []Event
+}
+
+// Event is an all-day event.
+type Event struct {
+	// UID identifies the event across feed refreshes; a client replaces
+	// the event it holds under a UID when Sequence is higher.
+	UID         string
+	Sequence    int
+	Date        time.Time // only the year, month and day are used
+	Summary     string
+	Description string
+	// Stamp is when the event was last written out.
+	Stamp time.Time
+}
+
+// Encode writes cal to w.
+func Encode(w io.Writer, cal Calendar) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", cal.ProdID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if cal.Name != "" {
+		line("X-WR-CALNAME", escapeText(cal.Name))
+	}
+	for _, ev := range cal.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", escapeText(ev.UID))
+		line("DTSTAMP", ev.Stamp.UTC().Format("20060102T150405Z"))
+		line("SEQUENCE", fmt.Sprint(ev.Sequence))
+		line("DTSTART;VALUE=DATE", ev.Date.Format("20060102"))
+		line("DTEND;VALUE=DATE", ev.Date.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY", escapeText(ev.Summary))
+		if ev.Description != "" {
+			line("DESCRIPTION", escapeText(ev.Description))
+		}
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escapeText escapes a TEXT property value: backslashes, semicolons, commas
+// and newlines.
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// writeFolded writes a content line, folding it onto continuation lines
+// (CRLF and a space) so no line exceeds 75 octets. Folds never split a UTF-8
+// sequence.
+func writeFolded(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		_, _ = w.WriteString(s[:cut])
+		_, _ = w.WriteString("\r\n ")
+		s = s[cut:]
+		limit = maxLineOctets - 1 // the leading space counts
+	}
+	_, _ = w.WriteString(s)
+	_, _ = w.WriteString("\r\n")
+}
@@ -0,0 +1,64 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	var b strings.Builder
+	err := Encode(&b, Calendar{
+		ProdID: "-//kitchinv//EN",
+		Name:   "kitchinv",
+		Events: []Event{{
+			UID:         "item-7@kitchinv",
+			Sequence:    2,
+			Date:        time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC),
+			Summary:     "Milk, 2%; organic expires — Fridge",
+			Description: "line one\nback\\slash",
+			Stamp:       time.Date(2026, 3, 24, 9, 30, 0, 0, time.FixedZone("X", 3600)),
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//kitchinv//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:kitchinv",
+		"BEGIN:VEVENT",
+		"UID:item-7@kitchinv",
+		"DTSTAMP:20260324T083000Z",
+		"SEQUENCE:2",
+		"DTSTART;VALUE=DATE:20260331",
+		"DTEND;VALUE=DATE:20260401",
+		`SUMMARY:Milk\, 2%\; organic expires — Fridge`,
+		`DESCRIPTION:line one\nback\\slash`,
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n"), b.String())
+}
+
+func TestEncodeFoldsLongLines(t *testing.T) {
+	var b strings.Builder
+	summary := strings.Repeat("é", 100) // two octets each
+	require.NoError(t, Encode(&b, Calendar{ProdID: "x", Events: []Event{{UID: "u", Summary: summary}}}))
+
+	var unfolded string
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+		if rest, ok := strings.CutPrefix(line, " "); ok {
+			unfolded += rest
+			continue
+		}
+		unfolded += "\n" + line
+	}
+	assert.Contains(t, unfolded, "\nSUMMARY:"+summary+"\n", "folds fall between characters and unfold to the original")
+}
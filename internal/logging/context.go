@@ -0,0 +1,39 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+)
+
+type (
+	loggerKey    struct{}
+	requestIDKey struct{}
+)
+
+// NewContext returns a copy of ctx carrying logger. Request-scoped middleware
+// uses it to attach attributes such as the request ID to every log line
+// emitted while serving the request.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx by NewContext, or fallback if
+// there is none.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return fallback
+}
+
+// WithRequestID returns a copy of ctx carrying the ID of the request being
+// served, for records that outlive the log such as the audit log.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID stored by WithRequestID, or "" outside a request.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,27 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromContext(t *testing.T) {
+	fallback := slog.Default()
+	assert.Same(t, fallback, FromContext(context.Background(), fallback))
+
+	var buf bytes.Buffer
+	scoped := slog.New(slog.NewJSONHandler(&buf, nil)).With("request_id", "abc")
+	ctx := NewContext(context.Background(), scoped)
+
+	FromContext(ctx, fallback).Info("hello")
+	assert.Contains(t, buf.String(), `"request_id":"abc"`)
+}
+
+func TestRequestID(t *testing.T) {
+	assert.Empty(t, RequestID(context.Background()))
+	assert.Equal(t, "abc", RequestID(WithRequestID(context.Background(), "abc")))
+}
@@ -4,35 +4,93 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
 )
 
-// New creates a *slog.Logger writing JSON to stderr and optionally to logFile.
-// The returned cleanup func closes the log file if one was opened; callers must
-// defer it. Callers that want package-level slog calls to use this logger should
-// call slog.SetDefault(logger) after construction.
-func New(level, logFile string) (*slog.Logger, func(), error) {
-	lvl := parseLevel(level)
+// Formats are the accepted Options.Format values.
+var Formats = []string{"json", "text"}
 
+// Options configure New.
+type Options struct {
+	Level  string // see ParseLevel
+	Format string // "json" or "text"; anything else falls back to json
+	Source bool   // add the source file and line of each call
+	File   string // also append to this file when set
+	Rotate RotateOptions
+}
+
+// New creates a *slog.Logger writing to stderr and optionally to opts.File,
+// which is rotated according to opts.Rotate. SIGHUP reopens the file, so an
+// external tool such as logrotate can move it aside. An unknown format is
+// logged as a warning and JSON used, rather than failing startup. The
+// returned cleanup func flushes and closes the log file if one was opened;
+// callers must defer it, and must not log after calling it. Callers that
+// want package-level slog calls to use this logger should call
+// slog.SetDefault(logger) after construction.
+func New(opts Options) (*slog.Logger, func(), error) {
 	writers := []io.Writer{os.Stderr}
 	cleanup := func() {}
 
-	if logFile != "" {
-		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if opts.File != "" {
+		f, err := OpenRotatingFile(opts.File, opts.Rotate)
 		if err != nil {
 			return nil, nil, err
 		}
 		writers = append(writers, f)
-		cleanup = func() { _ = f.Close() }
+		stop := reopenOnHangup(f)
+		cleanup = func() {
+			stop()
+			_ = f.Close()
+		}
 	}
 
 	w := io.MultiWriter(writers...)
-	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lvl})
+	hopts := &slog.HandlerOptions{Level: ParseLevel(opts.Level), AddSource: opts.Source}
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "text":
+		handler = slog.NewTextHandler(w, hopts)
+	default:
+		handler = slog.NewJSONHandler(w, hopts)
+	}
 	logger := slog.New(handler)
+	if f := strings.ToLower(opts.Format); f != "" && !slices.Contains(Formats, f) {
+		logger.Warn("unknown log format, using json", "format", opts.Format, "formats", strings.Join(Formats, ", "))
+	}
 	return logger, cleanup, nil
 }
 
-func parseLevel(s string) slog.Level {
-	switch s {
+// reopenOnHangup reopens f each time the process receives SIGHUP, until the
+// returned func is called.
+func reopenOnHangup(f *RotatingFile) (stop func()) {
+	hup := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-hup:
+				if err := f.Reopen(); err != nil {
+					slog.Error("failed to reopen log file", "error", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(hup)
+		close(done)
+	}
+}
+
+// ParseLevel returns the level named "debug", "info", "warn" or "error", in
+// any case, treating anything else as info.
+func ParseLevel(s string) slog.Level {
+	switch strings.ToLower(s) {
 	case "debug":
 		return slog.LevelDebug
 	case "warn":
@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFormat(t *testing.T) {
+	for format, want := range map[string]any{
+		"":     &slog.JSONHandler{},
+		"json": &slog.JSONHandler{},
+		"text": &slog.TextHandler{},
+		"TEXT": &slog.TextHandler{},
+	} {
+		logger, cleanup, err := New(Options{Format: format})
+		require.NoError(t, err)
+		cleanup()
+		assert.IsType(t, want, logger.Handler(), "format %q", format)
+	}
+}
+
+func TestNewUnknownFormatFallsBackToJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kitchinv.log")
+	logger, cleanup, err := New(Options{Format: "logfmt", File: path})
+	require.NoError(t, err, "a bad format doesn't stop startup")
+	assert.IsType(t, &slog.JSONHandler{}, logger.Handler())
+	cleanup()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"level":"WARN"`)
+	assert.Contains(t, string(data), `"format":"logfmt"`)
+}
+
+func TestNewSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kitchinv.log")
+	logger, cleanup, err := New(Options{Format: "text", Source: true, File: path})
+	require.NoError(t, err)
+	logger.Info("hello")
+	cleanup()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "source=")
+	assert.Contains(t, string(data), "logging_test.go:")
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReopensLogFileOnSIGHUP(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kitchinv.log")
+	logger, cleanup, err := New(Options{Level: "info", File: path})
+	require.NoError(t, err)
+	defer cleanup()
+
+	logger.Info("before")
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond, "the file is reopened at its path")
+	logger.Info("after")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"msg":"after"`)
+	assert.NotContains(t, string(data), `"msg":"before"`)
+}
@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a log record kept by a Ring.
+type Entry struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	Attrs   string // the record's attributes in slog's text format
+}
+
+// Ring keeps the most recent log records at or above a level in memory, so
+// the admin page can show recent errors without reading the log. It is safe
+// for concurrent use.
+type Ring struct {
+	level slog.Level
+
+	mu      sync.Mutex
+	entries []Entry // circular once full; next is the oldest
+	next    int
+	buf     bytes.Buffer // formats attributes; guarded by mu
+}
+
+// NewRing returns a Ring holding the last size records at level or above.
+func NewRing(size int, level slog.Level) *Ring {
+	return &Ring{level: level, entries: make([]Entry, 0, size)}
+}
+
+// Handler returns a handler that passes every record on to next and also
+// keeps those at the ring's level or above.
+func (r *Ring) Handler(next slog.Handler) slog.Handler {
+	text := slog.NewTextHandler(&r.buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey || a.Key == slog.MessageKey) {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	return &ringHandler{ring: r, next: next, text: text}
+}
+
+// Entries returns the kept records, newest first.
+func (r *Ring) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Entry, 0, len(r.entries))
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		out = append(out, r.entries[(r.next+i)%len(r.entries)])
+	}
+	return out
+}
+
+func (r *Ring) add(ctx context.Context, text slog.Handler, rec slog.Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf.Reset()
+	_ = text.Handle(ctx, rec)
+	e := Entry{Time: rec.Time, Level: rec.Level, Message: rec.Message, Attrs: strings.TrimSpace(r.buf.String())}
+	if len(r.entries) < cap(r.entries) {
+		r.entries = append(r.entries, e)
+		return
+	}
+	if len(r.entries) == 0 {
+		return
+	}
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+}
+
+// ringHandler tees records into a Ring. text carries the attributes and
+// groups added with WithAttrs and WithGroup, so kept entries show them too.
+type ringHandler struct {
+	ring *Ring
+	next slog.Handler
+	text slog.Handler
+}
+
+func (h *ringHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.ring.level || h.next.Enabled(ctx, level)
+}
+
+func (h *ringHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if rec.Level >= h.ring.level {
+		h.ring.add(ctx, h.text, rec)
+	}
+	if !h.next.Enabled(ctx, rec.Level) {
+		return nil
+	}
+	return h.next.Handle(ctx, rec)
+}
+
+func (h *ringHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ringHandler{ring: h.ring, next: h.next.WithAttrs(attrs), text: h.text.WithAttrs(attrs)}
+}
+
+func (h *ringHandler) WithGroup(name string) slog.Handler {
+	return &ringHandler{ring: h.ring, next: h.next.WithGroup(name), text: h.text.WithGroup(name)}
+}
@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRing(t *testing.T) {
+	var out bytes.Buffer
+	ring := NewRing(2, slog.LevelWarn)
+	logger := slog.New(ring.Handler(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelError})))
+
+	logger.Info("ignored")
+	logger.With("area_id", 3).WithGroup("req").Warn("slow upload", "ms", 900)
+	logger.Error("first failure", "error", "boom")
+	logger.Error("second failure")
+
+	entries := ring.Entries()
+	require.Len(t, entries, 2, "only the last two are kept")
+	assert.Equal(t, "second failure", entries[0].Message)
+	assert.Equal(t, "first failure", entries[1].Message)
+	assert.Equal(t, slog.LevelError, entries[1].Level)
+	assert.Equal(t, "error=boom", entries[1].Attrs)
+
+	// Records still reach the wrapped handler, at its own level.
+	assert.Contains(t, out.String(), "first failure")
+	assert.NotContains(t, out.String(), "slow upload")
+	assert.NotContains(t, out.String(), "ignored")
+}
+
+func TestRingKeepsAttrsAndGroups(t *testing.T) {
+	ring := NewRing(5, slog.LevelWarn)
+	logger := slog.New(ring.Handler(slog.NewJSONHandler(&bytes.Buffer{}, nil)))
+
+	logger.With("area_id", 3).WithGroup("req").Warn("slow upload", "ms", 900)
+
+	entries := ring.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, "area_id=3 req.ms=900", entries[0].Attrs)
+	assert.False(t, entries[0].Time.IsZero())
+}
+
+func TestRingEnabled(t *testing.T) {
+	ring := NewRing(5, slog.LevelWarn)
+	h := ring.Handler(slog.NewJSONHandler(&bytes.Buffer{}, &slog.HandlerOptions{Level: slog.LevelError}))
+	assert.False(t, h.Enabled(context.Background(), slog.LevelInfo))
+	assert.True(t, h.Enabled(context.Background(), slog.LevelWarn), "the ring wants warnings the next handler drops")
+}
@@ -0,0 +1,246 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat stamps rotated files, e.g. kitchinv-2025-06-01T12-00-00.000.log.
+// It sorts chronologically and has no characters that are awkward in file
+// names.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotateOptions control when a RotatingFile starts a new file and which old
+// ones it keeps.
+type RotateOptions struct {
+	MaxSize    int64         // bytes written before rotating; 0 never rotates by size
+	MaxBackups int           // rotated files kept; 0 keeps them all
+	MaxAge     time.Duration // rotated files older than this are removed; 0 keeps them
+	Compress   bool          // gzip rotated files
+}
+
+// RotatingFile is an io.Writer appending to a log file that is renamed
+// aside with a timestamp once it reaches MaxSize, with old copies pruned
+// and optionally compressed in the background. It is safe for concurrent
+// use.
+type RotatingFile struct {
+	path string
+	opts RotateOptions
+	now  func() time.Time
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+
+	millMu sync.Mutex     // serialises compressing and pruning backups
+	mills  sync.WaitGroup // background mill runs, waited for by Close
+}
+
+// OpenRotatingFile opens path for appending, creating it if needed.
+func OpenRotatingFile(path string, opts RotateOptions) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, opts: opts, now: time.Now}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if it would take the file past MaxSize.
+// A single write larger than MaxSize still goes to one file.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	if r.opts.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.opts.MaxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate starts a new file now, whatever its size.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return os.ErrClosed
+	}
+	return r.rotate()
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	r.f = nil
+	if err := os.Rename(r.path, r.backupName(r.now())); err != nil && !os.IsNotExist(err) {
+		// Keep logging to the old file rather than losing lines.
+		_ = r.open()
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err := r.open(); err != nil {
+		return fmt.Errorf("failed to open new log file: %w", err)
+	}
+	r.mills.Add(1)
+	go func() {
+		defer r.mills.Done()
+		r.mill()
+	}()
+	return nil
+}
+
+// Reopen closes the file and opens path again without rotating, so a file
+// moved aside by an external tool such as logrotate is let go of.
+func (r *RotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return os.ErrClosed
+	}
+	_ = r.f.Close()
+	r.f = nil
+	return r.open()
+}
+
+// Close flushes the file to disk, closes it and waits for background
+// compression and pruning to finish. Writes after Close fail.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.f != nil {
+		_ = r.f.Sync()
+		err = r.f.Close()
+		r.f = nil
+	}
+	r.mu.Unlock()
+	r.mills.Wait()
+	return err
+}
+
+// backupName returns the name the current file is renamed to at t:
+// dir/name-<timestamp>.ext.
+func (r *RotatingFile) backupName(t time.Time) string {
+	dir, base := filepath.Split(r.path)
+	ext := filepath.Ext(base)
+	return filepath.Join(dir, strings.TrimSuffix(base, ext)+"-"+t.UTC().Format(backupTimeFormat)+ext)
+}
+
+type backup struct {
+	path string
+	at   time.Time
+}
+
+// backups returns the rotated copies of the file, newest first.
+func (r *RotatingFile) backups() ([]backup, error) {
+	dir, base := filepath.Split(r.path)
+	ext := filepath.Ext(base)
+	prefix := strings.TrimSuffix(base, ext) + "-"
+	entries, err := os.ReadDir(filepath.Clean(dir))
+	if err != nil {
+		return nil, err
+	}
+	var out []backup
+	for _, e := range entries {
+		name := e.Name()
+		stamp, ok := strings.CutPrefix(name, prefix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		stamp = strings.TrimSuffix(strings.TrimSuffix(stamp, ".gz"), ext)
+		at, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue // another file that happens to share the prefix
+		}
+		out = append(out, backup{path: filepath.Join(dir, name), at: at})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].at.After(out[j].at) })
+	return out, nil
+}
+
+// mill removes backups beyond MaxBackups or older than MaxAge and
+// compresses the rest. Failures are reported on stderr, as the log itself
+// is what is being maintained.
+func (r *RotatingFile) mill() {
+	r.millMu.Lock()
+	defer r.millMu.Unlock()
+	list, err := r.backups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kitchinv: failed to list log backups: %v\n", err)
+		return
+	}
+	cutoff := time.Time{}
+	if r.opts.MaxAge > 0 {
+		cutoff = r.now().Add(-r.opts.MaxAge)
+	}
+	for i, b := range list {
+		if (r.opts.MaxBackups > 0 && i >= r.opts.MaxBackups) || b.at.Before(cutoff) {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "kitchinv: failed to remove old log: %v\n", err)
+			}
+			continue
+		}
+		if r.opts.Compress && !strings.HasSuffix(b.path, ".gz") {
+			if err := compressFile(b.path); err != nil {
+				fmt.Fprintf(os.Stderr, "kitchinv: failed to compress old log: %v\n", err)
+			}
+		}
+	}
+}
+
+// compressFile gzips path to path.gz and removes the original.
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(path + ".gz")
+		}
+	}()
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	_ = src.Close() // before removing it, which Windows requires
+	return os.Remove(path)
+}
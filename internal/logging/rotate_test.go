@@ -0,0 +1,124 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRotatingFile opens a rotating kitchinv.log in a temporary
+// directory whose clock advances a second on every reading, so each
+// rotation gets its own name.
+func newTestRotatingFile(t *testing.T, opts RotateOptions) (*RotatingFile, string) {
+	t.Helper()
+	dir := t.TempDir()
+	r, err := OpenRotatingFile(filepath.Join(dir, "kitchinv.log"), opts)
+	require.NoError(t, err)
+	clock := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	return r, dir
+}
+
+func logFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotatingFileRotatesPastMaxSize(t *testing.T) {
+	r, dir := newTestRotatingFile(t, RotateOptions{MaxSize: 10})
+	for _, line := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n"} {
+		_, err := r.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, r.Close())
+
+	assert.Equal(t, []string{
+		"kitchinv-2025-06-01T12-00-01.000.log",
+		"kitchinv-2025-06-01T12-00-02.000.log",
+		"kitchinv.log",
+	}, logFiles(t, dir))
+	data, err := os.ReadFile(filepath.Join(dir, "kitchinv-2025-06-01T12-00-01.000.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "aaaaaa\n", string(data), "lines aren't split across files")
+	data, err = os.ReadFile(filepath.Join(dir, "kitchinv.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "cccccc\n", string(data))
+
+	_, err = r.Write([]byte("late\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestRotatingFileKeepsMaxBackupsCompressed(t *testing.T) {
+	r, dir := newTestRotatingFile(t, RotateOptions{MaxSize: 5, MaxBackups: 2, Compress: true})
+	for _, line := range []string{"one\n", "two\n", "three\n", "four\n"} {
+		_, err := r.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, r.Close())
+
+	assert.Equal(t, []string{
+		"kitchinv-2025-06-01T12-00-02.000.log.gz",
+		"kitchinv-2025-06-01T12-00-03.000.log.gz",
+		"kitchinv.log",
+	}, logFiles(t, dir))
+	f, err := os.Open(filepath.Join(dir, "kitchinv-2025-06-01T12-00-03.000.log.gz"))
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	zr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	data, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, "three\n", string(data))
+}
+
+func TestRotatingFileRemovesBackupsPastMaxAge(t *testing.T) {
+	r, dir := newTestRotatingFile(t, RotateOptions{MaxAge: time.Hour})
+	old := filepath.Join(dir, "kitchinv-2025-05-01T00-00-00.000.log")
+	require.NoError(t, os.WriteFile(old, []byte("old\n"), 0600))
+	unrelated := filepath.Join(dir, "kitchinv-notes.log")
+	require.NoError(t, os.WriteFile(unrelated, []byte("keep\n"), 0600))
+
+	require.NoError(t, r.Rotate())
+	require.NoError(t, r.Close())
+
+	assert.NoFileExists(t, old)
+	assert.FileExists(t, unrelated, "files that aren't backups are left alone")
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+	r, dir := newTestRotatingFile(t, RotateOptions{})
+	path := filepath.Join(dir, "kitchinv.log")
+	_, err := r.Write([]byte("before\n"))
+	require.NoError(t, err)
+
+	// What logrotate does before sending SIGHUP.
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, r.Reopen())
+	_, err = r.Write([]byte("after\n"))
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "after\n", string(data))
+	data, err = os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, "before\n", string(data))
+}
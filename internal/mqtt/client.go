@@ -0,0 +1,271 @@
+// Package mqtt is a minimal MQTT 3.1.1 client for publishing retained state:
+// QoS 0 PUBLISH only, with a last-will message and automatic reconnection.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// DefaultKeepAlive is how often an idle connection is pinged.
+const DefaultKeepAlive = 30 * time.Second
+
+// Reconnect backoff bounds.
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+// writeTimeout bounds a single write to the broker.
+const writeTimeout = 10 * time.Second
+
+// Message is a message to publish.
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// Options configures a Client.
+type Options struct {
+	// Broker is the broker URL: tcp:// or mqtt:// for plain connections,
+	// ssl://, tls:// or mqtts:// for TLS. The port defaults to 1883 or 8883.
+	Broker    string
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration
+	// Will is published by the broker if the connection drops uncleanly.
+	Will *Message
+	// OnConnect runs after each successful (re)connection, before queued
+	// messages are sent.
+	OnConnect func()
+	Logger    *slog.Logger
+}
+
+// Client publishes messages to an MQTT broker. Publish never blocks: messages
+// are queued, and while the broker is unreachable only the newest message for
+// each topic is kept, which is all retained state needs.
+type Client struct {
+	opts    Options
+	network string
+	addr    string
+	tls     *tls.Config
+
+	// minDelay is the first reconnect delay; it doubles up to maxReconnectDelay.
+	minDelay time.Duration
+
+	mu      sync.Mutex
+	pending map[string]Message
+	order   []string // topics in pending, oldest first
+	wake    chan struct{}
+}
+
+// NewClient validates opts and returns a Client. Nothing connects until Run
+// is called.
+func NewClient(opts Options) (*Client, error) {
+	u, err := url.Parse(opts.Broker)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid MQTT broker URL %q", opts.Broker)
+	}
+	c := &Client{
+		opts:     opts,
+		network:  "tcp",
+		minDelay: minReconnectDelay,
+		pending:  make(map[string]Message),
+		wake:     make(chan struct{}, 1),
+	}
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		port = "8883"
+		c.tls = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	default:
+		return nil, fmt.Errorf("unsupported MQTT broker scheme %q", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	c.addr = net.JoinHostPort(u.Hostname(), port)
+	if c.opts.KeepAlive <= 0 {
+		c.opts.KeepAlive = DefaultKeepAlive
+	}
+	if c.opts.ClientID == "" {
+		c.opts.ClientID = "kitchinv"
+	}
+	if c.opts.Logger == nil {
+		c.opts.Logger = slog.Default()
+	}
+	return c, nil
+}
+
+// Publish queues m for sending, replacing any unsent message for the same
+// topic.
+func (c *Client) Publish(m Message) {
+	c.mu.Lock()
+	if _, ok := c.pending[m.Topic]; !ok {
+		c.order = append(c.order, m.Topic)
+	}
+	c.pending[m.Topic] = m
+	c.mu.Unlock()
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run connects to the broker and sends queued messages until ctx is
+// cancelled, reconnecting with exponential backoff whenever the connection
+// fails. On cancellation it sends what is queued, then the will message
+// (the broker drops the will on a clean disconnect), and disconnects.
+func (c *Client) Run(ctx context.Context) {
+	log := c.opts.Logger
+	delay := c.minDelay
+	for {
+		conn, err := c.connect(ctx)
+		if err == nil {
+			log.Info("mqtt connected", "broker", c.addr)
+			delay = c.minDelay
+			if c.opts.OnConnect != nil {
+				c.opts.OnConnect()
+			}
+			err = c.serve(ctx, conn)
+			_ = conn.Close()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warn("mqtt connection failed, retrying", "broker", c.addr, "retry_in", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// connect dials the broker and completes the CONNECT handshake.
+func (c *Client) connect(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: writeTimeout}
+	var conn net.Conn
+	var err error
+	if c.tls != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: c.tls}).DialContext(ctx, c.network, c.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, c.network, c.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keepAlive := uint16(min(c.opts.KeepAlive/time.Second, 65535))
+	if err := c.write(conn, connectPacket(c.opts.ClientID, c.opts.Username, c.opts.Password, keepAlive, c.opts.Will)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(writeTimeout))
+	p, err := readPacket(bufio.NewReader(conn))
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to read CONNACK: %w", err)
+	}
+	if p.kind() != packetConnack || len(p.body) != 2 {
+		_ = conn.Close()
+		return nil, errors.New("broker did not answer CONNECT with CONNACK")
+	}
+	if code := p.body[1]; code != 0 {
+		_ = conn.Close()
+		reason, ok := connackReasons[code]
+		if !ok {
+			reason = fmt.Sprintf("return code %d", code)
+		}
+		return nil, fmt.Errorf("broker refused connection: %s", reason)
+	}
+	return conn, nil
+}
+
+// serve sends queued messages and keeps the connection alive until it fails
+// or ctx is cancelled.
+func (c *Client) serve(ctx context.Context, conn net.Conn) error {
+	readErr := make(chan error, 1)
+	go func() {
+		// The broker sends nothing unprompted but PINGRESP, so a read
+		// deadline of 1.5 keep-alives detects a dead connection.
+		r := bufio.NewReader(conn)
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(c.opts.KeepAlive * 3 / 2))
+			if _, err := readPacket(r); err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(c.opts.KeepAlive)
+	defer ping.Stop()
+	if err := c.flush(conn); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			if c.opts.Will != nil {
+				c.Publish(*c.opts.Will)
+			}
+			if err := c.flush(conn); err != nil {
+				return err
+			}
+			return c.write(conn, []byte{packetDisconnect, 0})
+		case err := <-readErr:
+			return err
+		case <-c.wake:
+			if err := c.flush(conn); err != nil {
+				return err
+			}
+		case <-ping.C:
+			if err := c.write(conn, []byte{packetPingreq, 0}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// flush sends every queued message. Messages not sent because of a write
+// error go back on the queue unless a newer one for the topic has arrived.
+func (c *Client) flush(conn net.Conn) error {
+	c.mu.Lock()
+	pending, order := c.pending, c.order
+	c.pending, c.order = make(map[string]Message), nil
+	c.mu.Unlock()
+
+	for i, topic := range order {
+		if err := c.write(conn, publishPacket(pending[topic])); err != nil {
+			c.mu.Lock()
+			for _, t := range order[i:] {
+				if _, newer := c.pending[t]; !newer {
+					c.pending[t] = pending[t]
+					c.order = append(c.order, t)
+				}
+			}
+			c.mu.Unlock()
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) write(conn net.Conn, b []byte) error {
+	_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := conn.Write(b)
+	return err
+}
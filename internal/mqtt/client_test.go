@@ -0,0 +1,266 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// brokerConn is what the fake broker saw on one connection.
+type brokerConn struct {
+	clientID, username, password string
+	will                         *Message
+	keepAlive                    uint16
+}
+
+// fakeBroker accepts MQTT connections, answers CONNECT and PINGREQ, and
+// records every PUBLISH.
+type fakeBroker struct {
+	t        *testing.T
+	ln       net.Listener
+	connack  byte // return code sent in CONNACK
+	mu       sync.Mutex
+	conns    []brokerConn
+	received []Message
+	disconn  int
+	active   net.Conn
+	notify   chan struct{}
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	b := &fakeBroker{t: t, ln: ln, notify: make(chan struct{}, 100)}
+	t.Cleanup(func() { _ = ln.Close() })
+	go b.accept()
+	return b
+}
+
+func (b *fakeBroker) url() string { return "tcp://" + b.ln.Addr().String() }
+
+func (b *fakeBroker) accept() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		go b.serve(conn)
+	}
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	p, err := readPacket(r)
+	if err != nil || p.kind() != packetConnect {
+		return
+	}
+	bc := parseConnect(b.t, p.body)
+	b.mu.Lock()
+	b.conns = append(b.conns, bc)
+	b.active = conn
+	code := b.connack
+	b.mu.Unlock()
+	if _, err := conn.Write([]byte{packetConnack, 2, 0, code}); err != nil || code != 0 {
+		return
+	}
+	b.signal()
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		switch p.kind() {
+		case packetPublish:
+			topic, payload, err := readString(p.body)
+			require.NoError(b.t, err)
+			b.mu.Lock()
+			b.received = append(b.received, Message{Topic: topic, Payload: payload, Retain: p.header&publishRetain != 0})
+			b.mu.Unlock()
+		case packetPingreq:
+			_, _ = conn.Write([]byte{packetPingresp, 0})
+		case packetDisconnect:
+			b.mu.Lock()
+			b.disconn++
+			b.mu.Unlock()
+			b.signal()
+			return
+		}
+		b.signal()
+	}
+}
+
+func (b *fakeBroker) signal() {
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+// waitFor polls cond, woken by broker activity, until it holds or a second
+// passes.
+func (b *fakeBroker) waitFor(cond func() bool) bool {
+	deadline := time.After(time.Second)
+	for {
+		b.mu.Lock()
+		ok := cond()
+		b.mu.Unlock()
+		if ok {
+			return true
+		}
+		select {
+		case <-b.notify:
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			return false
+		}
+	}
+}
+
+// dropConnection closes the current client connection without a DISCONNECT.
+func (b *fakeBroker) dropConnection() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.active != nil {
+		_ = b.active.Close()
+	}
+}
+
+func parseConnect(t *testing.T, body []byte) brokerConn {
+	t.Helper()
+	name, rest, err := readString(body)
+	require.NoError(t, err)
+	require.Equal(t, "MQTT", name)
+	require.Equal(t, byte(4), rest[0], "protocol level")
+	flags := rest[1]
+	bc := brokerConn{keepAlive: binary.BigEndian.Uint16(rest[2:4])}
+	rest = rest[4:]
+	bc.clientID, rest, err = readString(rest)
+	require.NoError(t, err)
+	if flags&flagWill != 0 {
+		var topic, payload string
+		topic, rest, err = readString(rest)
+		require.NoError(t, err)
+		payload, rest, err = readString(rest)
+		require.NoError(t, err)
+		bc.will = &Message{Topic: topic, Payload: []byte(payload), Retain: flags&flagWillRetain != 0}
+	}
+	if flags&flagUsername != 0 {
+		bc.username, rest, err = readString(rest)
+		require.NoError(t, err)
+	}
+	if flags&flagPassword != 0 {
+		bc.password, _, err = readString(rest)
+		require.NoError(t, err)
+	}
+	return bc
+}
+
+// readString reads a length-prefixed string from the front of b and returns
+// the rest.
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errors.New("short string length")
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, errors.New("short string")
+	}
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
+
+func TestClientPublishesAndReconnects(t *testing.T) {
+	broker := newFakeBroker(t)
+	var connects int
+	var mu sync.Mutex
+	c, err := NewClient(Options{
+		Broker: broker.url(), ClientID: "kitchen", Username: "ha", Password: "pw",
+		KeepAlive: time.Minute,
+		Will:      &Message{Topic: "kitchinv/status", Payload: []byte("offline"), Retain: true},
+		OnConnect: func() { mu.Lock(); connects++; mu.Unlock() },
+	})
+	require.NoError(t, err)
+	c.minDelay = 10 * time.Millisecond
+
+	// Queued before connecting; only the newest message per topic is kept.
+	c.Publish(Message{Topic: "a", Payload: []byte("1"), Retain: true})
+	c.Publish(Message{Topic: "b", Payload: []byte("x")})
+	c.Publish(Message{Topic: "a", Payload: []byte("2"), Retain: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { c.Run(ctx); close(done) }()
+
+	require.True(t, broker.waitFor(func() bool { return len(broker.received) == 2 }))
+	broker.mu.Lock()
+	assert.Equal(t, []Message{{Topic: "a", Payload: []byte("2"), Retain: true}, {Topic: "b", Payload: []byte("x")}}, broker.received)
+	bc := broker.conns[0]
+	broker.mu.Unlock()
+	assert.Equal(t, "kitchen", bc.clientID)
+	assert.Equal(t, "ha", bc.username)
+	assert.Equal(t, "pw", bc.password)
+	assert.Equal(t, uint16(60), bc.keepAlive)
+	assert.Equal(t, &Message{Topic: "kitchinv/status", Payload: []byte("offline"), Retain: true}, bc.will)
+
+	broker.dropConnection()
+	require.True(t, broker.waitFor(func() bool { return len(broker.conns) == 2 }), "client reconnects")
+	c.Publish(Message{Topic: "c", Payload: []byte("after")})
+	require.True(t, broker.waitFor(func() bool { return len(broker.received) == 3 }))
+
+	cancel()
+	<-done
+	require.True(t, broker.waitFor(func() bool { return broker.disconn == 1 }))
+	broker.mu.Lock()
+	assert.Equal(t, "kitchinv/status", broker.received[len(broker.received)-1].Topic, "the will is published on a clean shutdown")
+	broker.mu.Unlock()
+	mu.Lock()
+	assert.Equal(t, 2, connects)
+	mu.Unlock()
+}
+
+func TestClientConnectRefused(t *testing.T) {
+	broker := newFakeBroker(t)
+	broker.connack = 4
+	c, err := NewClient(Options{Broker: broker.url()})
+	require.NoError(t, err)
+
+	_, err = c.connect(context.Background())
+	assert.ErrorContains(t, err, "bad user name or password")
+}
+
+func TestNewClientBrokerURL(t *testing.T) {
+	for broker, addr := range map[string]string{
+		"tcp://broker":        "broker:1883",
+		"mqtt://broker:1884":  "broker:1884",
+		"mqtts://broker":      "broker:8883",
+		"ssl://10.0.0.2:9000": "10.0.0.2:9000",
+	} {
+		c, err := NewClient(Options{Broker: broker})
+		require.NoError(t, err, broker)
+		assert.Equal(t, addr, c.addr, broker)
+	}
+	for _, broker := range []string{"", "broker:1883", "http://broker"} {
+		_, err := NewClient(Options{Broker: broker})
+		assert.Error(t, err, broker)
+	}
+}
+
+func TestRemainingLengthRoundTrip(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 16383, 16384, 2097151, 2097152} {
+		b := appendRemainingLength([]byte{packetPublish}, n)
+		b = append(b, make([]byte, n)...)
+		p, err := readPacket(bufio.NewReader(bytes.NewReader(b)))
+		require.NoError(t, err, n)
+		assert.Len(t, p.body, n)
+	}
+}
@@ -0,0 +1,162 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MQTT 3.1.1 control packet types, already shifted into the high nibble of
+// the fixed header.
+const (
+	packetConnect    byte = 1 << 4
+	packetConnack    byte = 2 << 4
+	packetPublish    byte = 3 << 4
+	packetPingreq    byte = 12 << 4
+	packetPingresp   byte = 13 << 4
+	packetDisconnect byte = 14 << 4
+)
+
+// Connect flag bits.
+const (
+	flagCleanSession byte = 1 << 1
+	flagWill         byte = 1 << 2
+	flagWillRetain   byte = 1 << 5
+	flagPassword     byte = 1 << 6
+	flagUsername     byte = 1 << 7
+)
+
+// publishRetain is the PUBLISH fixed-header flag asking the broker to keep
+// the message for future subscribers.
+const publishRetain byte = 1
+
+// maxRemainingLength is the largest body the 4-byte length encoding allows.
+const maxRemainingLength = 268435455
+
+// connackReasons describes the CONNACK return codes that refuse a connection.
+var connackReasons = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// packet is a decoded control packet: its first header byte and its body.
+type packet struct {
+	header byte
+	body   []byte
+}
+
+func (p packet) kind() byte { return p.header & 0xF0 }
+
+// connectPacket encodes a CONNECT for a clean session.
+func connectPacket(clientID, username, password string, keepAliveSecs uint16, will *Message) []byte {
+	var body []byte
+	body = appendString(body, "MQTT")
+	body = append(body, 4) // protocol level 3.1.1
+
+	flags := flagCleanSession
+	if will != nil {
+		flags |= flagWill
+		if will.Retain {
+			flags |= flagWillRetain
+		}
+	}
+	if username != "" {
+		flags |= flagUsername
+		if password != "" {
+			flags |= flagPassword
+		}
+	}
+	body = append(body, flags)
+	body = binary.BigEndian.AppendUint16(body, keepAliveSecs)
+
+	body = appendString(body, clientID)
+	if will != nil {
+		body = appendString(body, will.Topic)
+		body = appendBytes(body, will.Payload)
+	}
+	if username != "" {
+		body = appendString(body, username)
+		if password != "" {
+			body = appendString(body, password)
+		}
+	}
+	return encodePacket(packetConnect, body)
+}
+
+// publishPacket encodes a QoS 0 PUBLISH.
+func publishPacket(m Message) []byte {
+	header := packetPublish
+	if m.Retain {
+		header |= publishRetain
+	}
+	body := appendString(nil, m.Topic)
+	body = append(body, m.Payload...)
+	return encodePacket(header, body)
+}
+
+func encodePacket(header byte, body []byte) []byte {
+	out := []byte{header}
+	out = appendRemainingLength(out, len(body))
+	return append(out, body...)
+}
+
+func appendString(b []byte, s string) []byte {
+	return appendBytes(b, []byte(s))
+}
+
+func appendBytes(b, data []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
+
+// appendRemainingLength appends n in MQTT's variable-length encoding: seven
+// bits per byte, least significant first, high bit set on all but the last.
+func appendRemainingLength(b []byte, n int) []byte {
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+// readPacket reads one control packet from r.
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	n, mult := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return packet{}, errors.New("malformed remaining length")
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		n += int(digit&0x7F) * mult
+		if digit&0x80 == 0 {
+			break
+		}
+		mult *= 128
+	}
+	if n > maxRemainingLength {
+		return packet{}, fmt.Errorf("packet too large: %d bytes", n)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{header: header, body: body}, nil
+}
@@ -0,0 +1,180 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// Availability payloads published to the status topic.
+const (
+	PayloadOnline  = "online"
+	PayloadOffline = "offline"
+)
+
+// publisher is the part of Client that Publisher uses.
+type publisher interface {
+	Publish(m Message)
+}
+
+// Publisher mirrors area inventories to retained MQTT topics:
+//
+//	<prefix>/status            "online", or "offline" once disconnected
+//	<prefix>/areas/<id>/items  JSON: area name, item count and items
+//
+// With a discovery prefix it also publishes a Home Assistant MQTT discovery
+// config for each area, so every area appears as a sensor whose state is its
+// item count and whose attributes hold the item list.
+type Publisher struct {
+	client          publisher
+	conn            *Client
+	prefix          string
+	discoveryPrefix string
+	nodeID          string
+	logger          *slog.Logger
+
+	ctx     context.Context
+	refresh func(ctx context.Context) error
+}
+
+// areaState is the JSON published to an area's items topic.
+type areaState struct {
+	ID        int64       `json:"id"`
+	Name      string      `json:"name"`
+	Count     int         `json:"count"`
+	Items     []itemState `json:"items"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+type itemState struct {
+	Name     string `json:"name"`
+	Quantity string `json:"quantity"`
+}
+
+// discoveryConfig is a Home Assistant MQTT sensor discovery payload.
+type discoveryConfig struct {
+	Name                string          `json:"name"`
+	UniqueID            string          `json:"unique_id"`
+	StateTopic          string          `json:"state_topic"`
+	ValueTemplate       string          `json:"value_template"`
+	JSONAttributesTopic string          `json:"json_attributes_topic"`
+	AvailabilityTopic   string          `json:"availability_topic"`
+	UnitOfMeasurement   string          `json:"unit_of_measurement"`
+	Icon                string          `json:"icon"`
+	Device              discoveryDevice `json:"device"`
+}
+
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+}
+
+// NewPublisher creates a Publisher and the Client it publishes through;
+// opts.Will and opts.OnConnect are set by the Publisher. An empty
+// discoveryPrefix turns off Home Assistant discovery.
+func NewPublisher(opts Options, topicPrefix, discoveryPrefix string) (*Publisher, error) {
+	p := newPublisher(nil, topicPrefix, discoveryPrefix, opts.ClientID, opts.Logger)
+	opts.Will = &Message{Topic: p.statusTopic(), Payload: []byte(PayloadOffline), Retain: true}
+	opts.OnConnect = p.onConnect
+	c, err := NewClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	p.client, p.conn = c, c
+	return p, nil
+}
+
+func newPublisher(client publisher, topicPrefix, discoveryPrefix, nodeID string, logger *slog.Logger) *Publisher {
+	if nodeID == "" {
+		nodeID = "kitchinv"
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Publisher{
+		client:          client,
+		prefix:          topicPrefix,
+		discoveryPrefix: discoveryPrefix,
+		nodeID:          nodeID,
+		logger:          logger,
+	}
+}
+
+// Run keeps the broker connection up until ctx is cancelled. After every
+// (re)connection it marks kitchinv online and calls refresh in the
+// background, which should republish every area so retained state written
+// while disconnected, or by another instance, is replaced.
+func (p *Publisher) Run(ctx context.Context, refresh func(ctx context.Context) error) {
+	p.ctx, p.refresh = ctx, refresh
+	p.conn.Run(ctx)
+}
+
+func (p *Publisher) onConnect() {
+	p.client.Publish(Message{Topic: p.statusTopic(), Payload: []byte(PayloadOnline), Retain: true})
+	if p.refresh == nil {
+		return
+	}
+	go func() {
+		if err := p.refresh(p.ctx); err != nil {
+			p.logger.Error("failed to republish inventory over mqtt", "error", err)
+		}
+	}()
+}
+
+// PublishArea publishes area's current items, and its discovery config when
+// discovery is on.
+func (p *Publisher) PublishArea(area *domain.Area, items []*domain.Item) {
+	state := areaState{ID: area.ID, Name: area.Name, Count: len(items), Items: make([]itemState, 0, len(items)), UpdatedAt: area.UpdatedAt.UTC()}
+	for _, it := range items {
+		state.Items = append(state.Items, itemState{Name: it.Name, Quantity: it.Quantity})
+	}
+	if p.discoveryPrefix != "" {
+		p.publishJSON(p.discoveryTopic(area.ID), discoveryConfig{
+			Name:                area.Name,
+			UniqueID:            fmt.Sprintf("%s_area_%d", p.nodeID, area.ID),
+			StateTopic:          p.areaTopic(area.ID),
+			ValueTemplate:       "{{ value_json.count }}",
+			JSONAttributesTopic: p.areaTopic(area.ID),
+			AvailabilityTopic:   p.statusTopic(),
+			UnitOfMeasurement:   "items",
+			Icon:                "mdi:fridge-outline",
+			Device:              discoveryDevice{Identifiers: []string{p.nodeID}, Name: "kitchinv", Manufacturer: "kitchinv"},
+		})
+	}
+	p.publishJSON(p.areaTopic(area.ID), state)
+}
+
+// RemoveArea clears an area's retained topics, which also removes its Home
+// Assistant sensor.
+func (p *Publisher) RemoveArea(areaID int64) {
+	p.client.Publish(Message{Topic: p.areaTopic(areaID), Retain: true})
+	if p.discoveryPrefix != "" {
+		p.client.Publish(Message{Topic: p.discoveryTopic(areaID), Retain: true})
+	}
+}
+
+func (p *Publisher) publishJSON(topic string, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		p.logger.Error("failed to encode mqtt payload", "topic", topic, "error", err)
+		return
+	}
+	p.client.Publish(Message{Topic: topic, Payload: b, Retain: true})
+}
+
+func (p *Publisher) statusTopic() string {
+	return p.prefix + "/status"
+}
+
+func (p *Publisher) areaTopic(areaID int64) string {
+	return fmt.Sprintf("%s/areas/%d/items", p.prefix, areaID)
+}
+
+func (p *Publisher) discoveryTopic(areaID int64) string {
+	return fmt.Sprintf("%s/sensor/%s/area_%d/config", p.discoveryPrefix, p.nodeID, areaID)
+}
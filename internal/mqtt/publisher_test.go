@@ -0,0 +1,72 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+type recordingClient struct {
+	msgs []Message
+}
+
+func (r *recordingClient) Publish(m Message) { r.msgs = append(r.msgs, m) }
+
+func TestPublisherPublishArea(t *testing.T) {
+	rc := &recordingClient{}
+	p := newPublisher(rc, "kitchinv", "homeassistant", "kitchen", nil)
+	updated := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	p.PublishArea(&domain.Area{ID: 3, Name: "Fridge", UpdatedAt: updated}, []*domain.Item{
+		{Name: "Milk", Quantity: "2"}, {Name: "Eggs", Quantity: "6"},
+	})
+
+	require.Len(t, rc.msgs, 2)
+	cfg := rc.msgs[0]
+	assert.Equal(t, "homeassistant/sensor/kitchen/area_3/config", cfg.Topic)
+	assert.True(t, cfg.Retain)
+	var disc map[string]any
+	require.NoError(t, json.Unmarshal(cfg.Payload, &disc))
+	assert.Equal(t, "Fridge", disc["name"])
+	assert.Equal(t, "kitchen_area_3", disc["unique_id"])
+	assert.Equal(t, "kitchinv/areas/3/items", disc["state_topic"])
+	assert.Equal(t, "kitchinv/areas/3/items", disc["json_attributes_topic"])
+	assert.Equal(t, "kitchinv/status", disc["availability_topic"])
+	assert.Equal(t, "{{ value_json.count }}", disc["value_template"])
+
+	state := rc.msgs[1]
+	assert.Equal(t, "kitchinv/areas/3/items", state.Topic)
+	assert.True(t, state.Retain)
+	assert.JSONEq(t, `{"id":3,"name":"Fridge","count":2,"updated_at":"2026-03-01T12:00:00Z",
+		"items":[{"name":"Milk","quantity":"2"},{"name":"Eggs","quantity":"6"}]}`, string(state.Payload))
+}
+
+func TestPublisherWithoutDiscovery(t *testing.T) {
+	rc := &recordingClient{}
+	p := newPublisher(rc, "home/kitchen", "", "", nil)
+
+	p.PublishArea(&domain.Area{ID: 1, Name: "Pantry"}, nil)
+	require.Len(t, rc.msgs, 1)
+	assert.Equal(t, "home/kitchen/areas/1/items", rc.msgs[0].Topic)
+	assert.Contains(t, string(rc.msgs[0].Payload), `"items":[]`)
+
+	p.RemoveArea(1)
+	require.Len(t, rc.msgs, 2)
+	assert.Equal(t, Message{Topic: "home/kitchen/areas/1/items", Retain: true}, rc.msgs[1])
+}
+
+func TestPublisherRemoveAreaClearsDiscovery(t *testing.T) {
+	rc := &recordingClient{}
+	p := newPublisher(rc, "kitchinv", "homeassistant", "", nil)
+
+	p.RemoveArea(7)
+	assert.Equal(t, []Message{
+		{Topic: "kitchinv/areas/7/items", Retain: true},
+		{Topic: "homeassistant/sensor/kitchinv/area_7/config", Retain: true},
+	}, rc.msgs)
+}
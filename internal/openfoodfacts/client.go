@@ -0,0 +1,117 @@
+// Package openfoodfacts looks up packaged food by barcode in the Open Food
+// Facts database (https://world.openfoodfacts.org).
+package openfoodfacts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the public Open Food Facts server.
+const DefaultBaseURL = "https://world.openfoodfacts.org"
+
+// DefaultTimeout bounds a whole lookup, so a slow API can't hold up adding
+// an item.
+const DefaultTimeout = 10 * time.Second
+
+// userAgent identifies kitchinv, as Open Food Facts asks of API clients.
+const userAgent = "kitchinv (+https://github.com/vbonduro/kitchinv)"
+
+// ErrNotFound is returned by Lookup when Open Food Facts has no product for
+// the barcode.
+var ErrNotFound = errors.New("product not found")
+
+// Product is what Lookup learns about a barcode.
+type Product struct {
+	Barcode string
+	Name    string
+	// Quantity is the package size as printed, e.g. "500 g"; often empty.
+	Quantity string
+}
+
+// Client queries the Open Food Facts product API.
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewClient creates a Client for the API at baseURL, e.g. DefaultBaseURL or
+// a self-hosted mirror.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: DefaultTimeout},
+	}
+}
+
+// productResponse is the part of GET /api/v2/product/{barcode} we read.
+type productResponse struct {
+	Status  int `json:"status"`
+	Product struct {
+		ProductName   string `json:"product_name"`
+		ProductNameEN string `json:"product_name_en"`
+		GenericName   string `json:"generic_name"`
+		Brands        string `json:"brands"`
+		Quantity      string `json:"quantity"`
+	} `json:"product"`
+}
+
+// Lookup fetches the product with barcode. It returns ErrNotFound when the
+// database doesn't know the barcode or has no name for it.
+func (c *Client) Lookup(ctx context.Context, barcode string) (*Product, error) {
+	u := fmt.Sprintf("%s/api/v2/product/%s.json?fields=product_name,product_name_en,generic_name,brands,quantity",
+		c.baseURL, url.PathEscape(barcode))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open food facts: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// A missing product is a 404 whose body still carries status 0.
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("open food facts returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var pr productResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&pr); err != nil {
+		return nil, fmt.Errorf("failed to decode open food facts response: %w", err)
+	}
+	if pr.Status != 1 {
+		return nil, ErrNotFound
+	}
+
+	p := pr.Product
+	name := firstNonBlank(p.ProductName, p.ProductNameEN, p.GenericName)
+	if name == "" {
+		return nil, ErrNotFound
+	}
+	// Brands is a comma-separated list; the first is the one on the label.
+	if brand, _, _ := strings.Cut(p.Brands, ","); strings.TrimSpace(brand) != "" &&
+		!strings.Contains(strings.ToLower(name), strings.ToLower(strings.TrimSpace(brand))) {
+		name = strings.TrimSpace(brand) + " " + name
+	}
+	return &Product{Barcode: barcode, Name: name, Quantity: strings.TrimSpace(p.Quantity)}, nil
+}
+
+func firstNonBlank(ss ...string) string {
+	for _, s := range ss {
+		if s = strings.TrimSpace(s); s != "" {
+			return s
+		}
+	}
+	return ""
+}
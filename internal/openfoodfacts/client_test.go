@@ -0,0 +1,62 @@
+package openfoodfacts
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get("User-Agent"), "kitchinv")
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v2/product/4006381333931.json":
+			_, _ = w.Write([]byte(`{"status":1,"product":{"product_name":"Textmarker","brands":"Stabilo, Schwan","quantity":"1 pc"}}`))
+		case "/api/v2/product/3017620422003.json":
+			_, _ = w.Write([]byte(`{"status":1,"product":{"product_name":"Nutella","brands":"Nutella,Ferrero","quantity":" 400 g "}}`))
+		case "/api/v2/product/5000000000000.json":
+			_, _ = w.Write([]byte(`{"status":1,"product":{"product_name":"","generic_name":""}}`))
+		case "/api/v2/product/5000000000001.json":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("maintenance"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"status":0,"status_verbose":"product not found"}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClientLookup(t *testing.T) {
+	c := NewClient(newTestAPI(t).URL + "/")
+	ctx := context.Background()
+
+	p, err := c.Lookup(ctx, "4006381333931")
+	require.NoError(t, err)
+	assert.Equal(t, &Product{Barcode: "4006381333931", Name: "Stabilo Textmarker", Quantity: "1 pc"}, p)
+
+	p, err = c.Lookup(ctx, "3017620422003")
+	require.NoError(t, err)
+	assert.Equal(t, "Nutella", p.Name, "a brand already in the name isn't repeated")
+	assert.Equal(t, "400 g", p.Quantity)
+}
+
+func TestClientLookupErrors(t *testing.T) {
+	c := NewClient(newTestAPI(t).URL)
+	ctx := context.Background()
+
+	_, err := c.Lookup(ctx, "0000000000000")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = c.Lookup(ctx, "5000000000000")
+	assert.ErrorIs(t, err, ErrNotFound, "a product without a name is no use")
+	_, err = c.Lookup(ctx, "5000000000001")
+	assert.ErrorContains(t, err, "503")
+	assert.NotErrorIs(t, err, ErrNotFound)
+}
@@ -2,15 +2,23 @@ package local
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
-	"time"
+
+	"github.com/vbonduro/kitchinv/internal/photostore"
 )
 
+// hashDir is the subdirectory holding content-addressed photo files.
+const hashDir = "sha256"
+
 type LocalPhotoStore struct {
 	basePath string
 }
@@ -22,30 +30,49 @@ func NewLocalPhotoStore(basePath string) (*LocalPhotoStore, error) {
 	return &LocalPhotoStore{basePath: basePath}, nil
 }
 
-func (s *LocalPhotoStore) Save(ctx context.Context, prefix, mimeType string, r io.Reader) (string, error) {
-	filename := fmt.Sprintf("%s_%d%s", prefix, time.Now().UnixNano(), mimeTypeToExt(mimeType))
-	filePath := filepath.Join(s.basePath, filename)
-
-	f, err := os.Create(filePath)
+// Save writes r under a key derived from its SHA-256, "sha256/<hash>.<ext>".
+// If a file with the same content already exists it is reused and the new
+// copy discarded. Keys from before content addressing remain readable.
+func (s *LocalPhotoStore) Save(ctx context.Context, mimeType string, r io.Reader) (string, error) {
+	f, err := os.CreateTemp(s.basePath, ".upload-*")
 	if err != nil {
 		return "", fmt.Errorf("failed to create file: %w", err)
 	}
-	if _, err := io.Copy(f, r); err != nil {
+	tmpPath := f.Name()
+	removeTmp := func(reason string) {
+		if rerr := os.Remove(tmpPath); rerr != nil && !os.IsNotExist(rerr) {
+			slog.Error("failed to remove temporary file", "reason", reason, "error", rerr)
+		}
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
 		if cerr := f.Close(); cerr != nil {
 			slog.Error("failed to close file after write error", "error", cerr)
 		}
-		if rerr := os.Remove(filePath); rerr != nil {
-			slog.Error("failed to remove file after write error", "error", rerr)
-		}
+		removeTmp("write error")
 		return "", fmt.Errorf("failed to write file: %w", err)
 	}
 	if err := f.Close(); err != nil {
-		if rerr := os.Remove(filePath); rerr != nil {
-			slog.Error("failed to remove file after close error", "error", rerr)
-		}
+		removeTmp("close error")
 		return "", fmt.Errorf("failed to close file: %w", err)
 	}
-	return filename, nil
+
+	key := path.Join(hashDir, hex.EncodeToString(h.Sum(nil))+mimeTypeToExt(mimeType))
+	filePath := filepath.Join(s.basePath, filepath.FromSlash(key))
+	if _, err := os.Stat(filePath); err == nil {
+		removeTmp("duplicate content")
+		return key, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		removeTmp("mkdir error")
+		return "", fmt.Errorf("failed to create photo directory: %w", err)
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		removeTmp("rename error")
+		return "", fmt.Errorf("failed to store file: %w", err)
+	}
+	return key, nil
 }
 
 func (s *LocalPhotoStore) Get(ctx context.Context, storageKey string) (io.ReadCloser, string, error) {
@@ -64,6 +91,15 @@ func (s *LocalPhotoStore) Get(ctx context.Context, storageKey string) (io.ReadCl
 	return f, extToMimeType(filePath), nil
 }
 
+// GetSeeker is Get returning the open file, which can seek.
+func (s *LocalPhotoStore) GetSeeker(ctx context.Context, storageKey string) (io.ReadSeekCloser, string, error) {
+	r, mimeType, err := s.Get(ctx, storageKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return r.(*os.File), mimeType, nil
+}
+
 func (s *LocalPhotoStore) Delete(ctx context.Context, storageKey string) error {
 	filePath, err := s.safeJoin(storageKey)
 	if err != nil {
@@ -79,6 +115,37 @@ func (s *LocalPhotoStore) Delete(ctx context.Context, storageKey string) error {
 	return nil
 }
 
+// Usage totals the photo files under the base path, leaving out uploads
+// still being written.
+func (s *LocalPhotoStore) Usage(ctx context.Context) (photostore.Usage, error) {
+	var u photostore.Usage
+	err := filepath.WalkDir(s.basePath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil // deleted since it was listed
+			}
+			return err
+		}
+		u.Files++
+		u.Bytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return photostore.Usage{}, fmt.Errorf("failed to walk photo directory: %w", err)
+	}
+	return u, nil
+}
+
 // safeJoin resolves storageKey relative to basePath and rejects directory traversal.
 func (s *LocalPhotoStore) safeJoin(storageKey string) (string, error) {
 	absBase, err := filepath.Abs(s.basePath)
@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/photostore"
 )
 
 func TestLocalPhotoStoreSaveAndGet(t *testing.T) {
@@ -19,7 +22,7 @@ func TestLocalPhotoStoreSaveAndGet(t *testing.T) {
 	imageData := []byte("fake jpeg data")
 
 	// Save
-	key, err := store.Save(ctx, "area_1", "image/jpeg", bytes.NewReader(imageData))
+	key, err := store.Save(ctx, "image/jpeg", bytes.NewReader(imageData))
 	require.NoError(t, err)
 	assert.NotEmpty(t, key)
 
@@ -44,7 +47,7 @@ func TestLocalPhotoStoreDelete(t *testing.T) {
 	imageData := []byte("test data")
 
 	// Save
-	key, err := store.Save(ctx, "area_1", "image/jpeg", bytes.NewReader(imageData))
+	key, err := store.Save(ctx, "image/jpeg", bytes.NewReader(imageData))
 	require.NoError(t, err)
 
 	// Delete
@@ -56,6 +59,56 @@ func TestLocalPhotoStoreDelete(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestLocalPhotoStoreSaveDeduplicates(t *testing.T) {
+	tmpdir := t.TempDir()
+	store, err := NewLocalPhotoStore(tmpdir)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	key1, err := store.Save(ctx, "image/png", bytes.NewReader([]byte("same bytes")))
+	require.NoError(t, err)
+	key2, err := store.Save(ctx, "image/png", bytes.NewReader([]byte("same bytes")))
+	require.NoError(t, err)
+	key3, err := store.Save(ctx, "image/png", bytes.NewReader([]byte("other bytes")))
+	require.NoError(t, err)
+
+	assert.Equal(t, key1, key2)
+	assert.NotEqual(t, key1, key3)
+	assert.Regexp(t, `^sha256/[0-9a-f]{64}\.png$`, key1)
+
+	files, err := filepath.Glob(filepath.Join(tmpdir, "sha256", "*"))
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+	leftovers, err := filepath.Glob(filepath.Join(tmpdir, ".upload-*"))
+	require.NoError(t, err)
+	assert.Empty(t, leftovers, "temporary files must be cleaned up")
+}
+
+func TestLocalPhotoStoreLegacyKey(t *testing.T) {
+	tmpdir := t.TempDir()
+	store, err := NewLocalPhotoStore(tmpdir)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	// Photos saved before content addressing used flat, timestamped names.
+	legacy := "area_1_1700000000000000000.jpg"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpdir, legacy), []byte("old photo"), 0o644))
+
+	reader, mimeType, err := store.Get(ctx, legacy)
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	assert.Equal(t, "image/jpeg", mimeType)
+	assert.Equal(t, []byte("old photo"), data)
+
+	require.NoError(t, store.Delete(ctx, legacy))
+	_, _, err = store.Get(ctx, legacy)
+	assert.Error(t, err)
+}
+
 func TestLocalPhotoStoreNotFound(t *testing.T) {
 	tmpdir := t.TempDir()
 	store, err := NewLocalPhotoStore(tmpdir)
@@ -78,3 +131,42 @@ func TestLocalPhotoStorePathTraversal(t *testing.T) {
 	_, _, err = store.Get(ctx, "../../etc/passwd")
 	assert.Error(t, err)
 }
+
+func TestLocalPhotoStoreGetSeeker(t *testing.T) {
+	store, err := NewLocalPhotoStore(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	key, err := store.Save(ctx, "image/png", bytes.NewReader([]byte("0123456789")))
+	require.NoError(t, err)
+
+	f, mimeType, err := store.GetSeeker(ctx, key)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = f.Close() })
+	assert.Equal(t, "image/png", mimeType)
+
+	_, err = f.Seek(6, io.SeekStart)
+	require.NoError(t, err)
+	rest, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "6789", string(rest))
+
+	_, _, err = store.GetSeeker(ctx, "sha256/missing.png")
+	assert.Error(t, err)
+}
+
+func TestLocalPhotoStoreUsage(t *testing.T) {
+	tmpdir := t.TempDir()
+	store, err := NewLocalPhotoStore(tmpdir)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = store.Save(ctx, "image/png", bytes.NewReader([]byte("0123456789")))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(tmpdir, "area_1_1700000000000000000.jpg"), []byte("old"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpdir, ".upload-123"), []byte("partial upload"), 0o644))
+
+	u, err := store.Usage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, photostore.Usage{Files: 2, Bytes: 13}, u)
+}
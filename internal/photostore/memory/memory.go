@@ -0,0 +1,95 @@
+// Package memory provides a PhotoStore that keeps photos in memory. Nothing
+// survives a restart, which suits tests, demos and other throwaway
+// instances.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/vbonduro/kitchinv/internal/photostore"
+)
+
+type photo struct {
+	data     []byte
+	mimeType string
+}
+
+// Store is safe for concurrent use. It implements the optional
+// photostore.SeekerStore and photostore.UsageReporter as well.
+type Store struct {
+	mu     sync.RWMutex
+	photos map[string]photo
+}
+
+// New returns an empty Store.
+func New() *Store {
+	return &Store{photos: make(map[string]photo)}
+}
+
+// Save keeps r under "sha256/<hash>", as the local store names files. Saving
+// identical bytes again returns the same key and records the new MIME type.
+func (s *Store) Save(ctx context.Context, mimeType string, r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read photo: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	key := "sha256/" + hex.EncodeToString(sum[:])
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.photos[key] = photo{data: data, mimeType: mimeType}
+	return key, nil
+}
+
+// Get returns the photo saved under storageKey and its MIME type, or an error
+// if there is none.
+func (s *Store) Get(ctx context.Context, storageKey string) (io.ReadCloser, string, error) {
+	return s.GetSeeker(ctx, storageKey)
+}
+
+// GetSeeker is Get returning a reader that can seek.
+func (s *Store) GetSeeker(ctx context.Context, storageKey string) (io.ReadSeekCloser, string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.photos[storageKey]
+	if !ok {
+		return nil, "", fmt.Errorf("photo not found")
+	}
+	// Stored bytes are never modified, so readers can share them.
+	return nopCloser{bytes.NewReader(p.data)}, p.mimeType, nil
+}
+
+// Delete forgets the photo saved under storageKey. Deleting a key that isn't
+// held is an error, as it is for the local store.
+func (s *Store) Delete(ctx context.Context, storageKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.photos[storageKey]; !ok {
+		return fmt.Errorf("photo not found")
+	}
+	delete(s.photos, storageKey)
+	return nil
+}
+
+// Usage totals the photos held.
+func (s *Store) Usage(ctx context.Context) (photostore.Usage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u := photostore.Usage{Files: int64(len(s.photos))}
+	for _, p := range s.photos {
+		u.Bytes += int64(len(p.data))
+	}
+	return u, nil
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error { return nil }
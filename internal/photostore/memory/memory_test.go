@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/photostore"
+)
+
+var (
+	_ photostore.PhotoStore    = (*Store)(nil)
+	_ photostore.SeekerStore   = (*Store)(nil)
+	_ photostore.UsageReporter = (*Store)(nil)
+)
+
+func TestStoreSaveGetDelete(t *testing.T) {
+	store := New()
+	ctx := context.Background()
+	imageData := []byte("fake jpeg data")
+
+	key, err := store.Save(ctx, "image/jpeg", bytes.NewReader(imageData))
+	require.NoError(t, err)
+	again, err := store.Save(ctx, "image/jpeg", bytes.NewReader(imageData))
+	require.NoError(t, err)
+	assert.Equal(t, key, again, "keys are content-addressed")
+
+	r, mimeType, err := store.Get(ctx, key)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = r.Close() })
+	assert.Equal(t, "image/jpeg", mimeType)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, imageData, data)
+
+	rs, _, err := store.GetSeeker(ctx, key)
+	require.NoError(t, err)
+	_, err = rs.Seek(5, io.SeekStart)
+	require.NoError(t, err)
+	rest, err := io.ReadAll(rs)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg data", string(rest))
+
+	u, err := store.Usage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, photostore.Usage{Files: 1, Bytes: int64(len(imageData))}, u)
+
+	require.NoError(t, store.Delete(ctx, key))
+	_, _, err = store.Get(ctx, key)
+	assert.Error(t, err)
+	assert.Error(t, store.Delete(ctx, key), "deleting a missing photo fails, as in the local store")
+}
+
+func TestStoreConcurrent(t *testing.T) {
+	store := New()
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, err := store.Save(ctx, "image/png", bytes.NewReader([]byte(fmt.Sprint(i))))
+			assert.NoError(t, err)
+			r, _, err := store.Get(ctx, key)
+			if assert.NoError(t, err) {
+				_ = r.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	u, err := store.Usage(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), u.Files)
+}
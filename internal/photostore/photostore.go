@@ -5,8 +5,30 @@ import (
 	"io"
 )
 
+// PhotoStore persists photo files. Keys are derived from the file's content,
+// so saving identical bytes twice returns the same key; callers must only
+// Delete a key once no photo record references it.
 type PhotoStore interface {
-	Save(ctx context.Context, prefix, mimeType string, r io.Reader) (storageKey string, err error)
+	Save(ctx context.Context, mimeType string, r io.Reader) (storageKey string, err error)
 	Get(ctx context.Context, storageKey string) (io.ReadCloser, string, error)
 	Delete(ctx context.Context, storageKey string) error
 }
+
+// SeekerStore is implemented by stores that can return a seekable reader,
+// which lets photos be served with HTTP Range support. Callers detect it
+// with a type assertion and fall back to Get.
+type SeekerStore interface {
+	GetSeeker(ctx context.Context, storageKey string) (io.ReadSeekCloser, string, error)
+}
+
+// Usage is how much space a store's photos take up.
+type Usage struct {
+	Files int64
+	Bytes int64
+}
+
+// UsageReporter is implemented by stores that can report how much space
+// they use, shown on the admin page. Like SeekerStore it is optional.
+type UsageReporter interface {
+	Usage(ctx context.Context) (Usage, error)
+}
@@ -0,0 +1,279 @@
+package qr
+
+// matrix is a symbol being laid out. Coordinates are (x, y) with x the
+// column, both from the top-left corner.
+type matrix struct {
+	size     int
+	modules  [][]bool // [y][x], true is dark
+	function [][]bool // [y][x], true for finder, timing, alignment and format modules
+}
+
+func newMatrix(ver int) *matrix {
+	size := 4*ver + 17
+	m := &matrix{size: size, modules: make([][]bool, size), function: make([][]bool, size)}
+	for y := range size {
+		m.modules[y] = make([]bool, size)
+		m.function[y] = make([]bool, size)
+	}
+	return m
+}
+
+func (m *matrix) setFunction(x, y int, dark bool) {
+	m.modules[y][x] = dark
+	m.function[y][x] = true
+}
+
+// drawFunctionPatterns draws everything but the data: finder, timing and
+// alignment patterns, the version information, and placeholders for the
+// format information, which depends on the mask.
+func (m *matrix) drawFunctionPatterns(ver int) {
+	for i := range m.size {
+		m.setFunction(6, i, i%2 == 0)
+		m.setFunction(i, 6, i%2 == 0)
+	}
+	m.drawFinder(3, 3)
+	m.drawFinder(m.size-4, 3)
+	m.drawFinder(3, m.size-4)
+
+	pos := versions[ver].alignment
+	last := len(pos) - 1
+	for i, y := range pos {
+		for j, x := range pos {
+			// Skip the three that would overlap the finder patterns.
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			m.drawAlignment(x, y)
+		}
+	}
+
+	m.drawFormat(0)
+	if ver >= 7 {
+		bits := versionBits(ver)
+		for i := range 18 {
+			dark := bits>>i&1 == 1
+			a, b := m.size-11+i%3, i/3
+			m.setFunction(a, b, dark)
+			m.setFunction(b, a, dark)
+		}
+	}
+}
+
+// drawFinder draws a finder pattern centred on (cx, cy) with its light
+// separator.
+func (m *matrix) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= m.size || y < 0 || y >= m.size {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			m.setFunction(x, y, d != 2 && d != 4)
+		}
+	}
+}
+
+// drawAlignment draws an alignment pattern centred on (cx, cy).
+func (m *matrix) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			m.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormat draws both copies of the format information for mask at level
+// M, and the dark module beside the bottom-left finder.
+func (m *matrix) drawFormat(mask int) {
+	bits := formatBits(mask)
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+	for i := range 6 {
+		m.setFunction(8, i, bit(i))
+	}
+	m.setFunction(8, 7, bit(6))
+	m.setFunction(8, 8, bit(7))
+	m.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		m.setFunction(14-i, 8, bit(i))
+	}
+	for i := range 8 {
+		m.setFunction(m.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		m.setFunction(8, m.size-15+i, bit(i))
+	}
+	m.setFunction(8, m.size-8, true)
+}
+
+// formatBits returns the 15-bit format information for level M and mask:
+// the five data bits, their BCH(15,5) check bits, XORed with the mask
+// pattern the standard specifies.
+func formatBits(mask int) uint32 {
+	const levelM = 0b00
+	data := uint32(levelM<<3 | mask)
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// versionBits returns the 18-bit version information: the version number
+// and its BCH(18,6) check bits.
+func versionBits(ver int) uint32 {
+	rem := uint32(ver)
+	for range 12 {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	return uint32(ver)<<12 | rem
+}
+
+// drawCodewords places data in the zigzag order the standard specifies:
+// pairs of columns from the right, alternately upwards and downwards,
+// skipping function modules and the vertical timing pattern. Modules left
+// over are remainder bits and stay light.
+func (m *matrix) drawCodewords(data []byte) {
+	i := 0
+	for right := m.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range m.size {
+			y := vert
+			if upward {
+				y = m.size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if m.function[y][x] || i >= len(data)*8 {
+					continue
+				}
+				m.modules[y][x] = data[i/8]>>(7-i%8)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyMask inverts the data modules that mask selects.
+func (m *matrix) applyMask(mask int) {
+	for y := range m.size {
+		for x := range m.size {
+			if !m.function[y][x] && maskAt(mask, x, y) {
+				m.modules[y][x] = !m.modules[y][x]
+			}
+		}
+	}
+}
+
+func maskAt(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// penalty scores the symbol by the standard's four rules; the mask giving
+// the lowest score is the easiest to scan.
+func (m *matrix) penalty() int {
+	p := 0
+	line := make([]bool, m.size)
+	for _, vertical := range []bool{false, true} {
+		for i := range m.size {
+			for j := range m.size {
+				if vertical {
+					line[j] = m.modules[j][i]
+				} else {
+					line[j] = m.modules[i][j]
+				}
+			}
+			p += linePenalty(line)
+		}
+	}
+	dark := 0
+	for y := range m.size {
+		for x := range m.size {
+			if m.modules[y][x] {
+				dark++
+			}
+			if x > 0 && y > 0 {
+				c := m.modules[y][x]
+				if c == m.modules[y-1][x] && c == m.modules[y][x-1] && c == m.modules[y-1][x-1] {
+					p += 3
+				}
+			}
+		}
+	}
+	total := m.size * m.size
+	// 10 points for every 5% the dark share strays from 50%.
+	p += abs(dark*20-total*10) / total * 10
+	return p
+}
+
+// finderLike is the dark-light ratio 1:1:3:1:1 that looks like a finder
+// pattern to a scanner.
+var finderLike = []bool{true, false, true, true, true, false, true}
+
+// linePenalty scores one row or column: runs of five or more modules of one
+// colour, and finder-like patterns with four light modules on either side.
+func linePenalty(line []bool) int {
+	p := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			p += 3 + run - 5
+		}
+		run = 1
+	}
+	for i := 0; i+len(finderLike) <= len(line); i++ {
+		match := true
+		for j, dark := range finderLike {
+			if line[i+j] != dark {
+				match = false
+				break
+			}
+		}
+		if match && (lightRun(line, i-4, i) || lightRun(line, i+len(finderLike), i+len(finderLike)+4)) {
+			p += 40
+		}
+	}
+	return p
+}
+
+// lightRun reports whether line[from:to] is all light, counting modules
+// beyond the ends as the light quiet zone.
+func lightRun(line []bool, from, to int) bool {
+	for i := from; i < to; i++ {
+		if i >= 0 && i < len(line) && line[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
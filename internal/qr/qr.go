@@ -0,0 +1,188 @@
+// Package qr encodes short byte strings, such as URLs, as QR codes (ISO/IEC
+// 18004) and renders them as images. It supports byte mode at error
+// correction level M in versions 1 to 10, which holds up to 213 bytes.
+package qr
+
+import (
+	"errors"
+	"image"
+	"image/color"
+)
+
+// ErrTooLong is returned for data that doesn't fit in a version 10 code.
+var ErrTooLong = errors.New("data too long for a QR code")
+
+// quietZone is the light border, in modules, scanners need around a code.
+const quietZone = 4
+
+// version describes a QR version's error-correction blocks at level M.
+type version struct {
+	ecPerBlock int
+	groups     [][2]int // {block count, data codewords per block}
+	alignment  []int    // alignment pattern centre coordinates
+}
+
+// versions is indexed by version number; index 0 is unused.
+var versions = [...]version{
+	{},
+	{10, [][2]int{{1, 16}}, nil},
+	{16, [][2]int{{1, 28}}, []int{6, 18}},
+	{26, [][2]int{{1, 44}}, []int{6, 22}},
+	{18, [][2]int{{2, 32}}, []int{6, 26}},
+	{24, [][2]int{{2, 43}}, []int{6, 30}},
+	{16, [][2]int{{4, 27}}, []int{6, 34}},
+	{18, [][2]int{{4, 31}}, []int{6, 22, 38}},
+	{22, [][2]int{{2, 38}, {2, 39}}, []int{6, 24, 42}},
+	{22, [][2]int{{3, 36}, {2, 37}}, []int{6, 26, 46}},
+	{26, [][2]int{{4, 43}, {1, 44}}, []int{6, 28, 50}},
+}
+
+func (v version) dataCodewords() int {
+	n := 0
+	for _, g := range v.groups {
+		n += g[0] * g[1]
+	}
+	return n
+}
+
+// Code is an encoded QR symbol.
+type Code struct {
+	// Size is the number of modules along each side, without the quiet
+	// zone.
+	Size    int
+	modules [][]bool // [y][x], true is dark
+}
+
+// Dark reports whether the module in column x of row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// Encode returns the smallest code holding data.
+func Encode(data []byte) (*Code, error) {
+	for ver := 1; ver < len(versions); ver++ {
+		countBits := 8
+		if ver >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= 8*versions[ver].dataCodewords() {
+			return build(ver, countBits, data), nil
+		}
+	}
+	return nil, ErrTooLong
+}
+
+// build lays out data as a version ver symbol with the mask that scores
+// best.
+func build(ver, countBits int, data []byte) *Code {
+	m := newMatrix(ver)
+	m.drawFunctionPatterns(ver)
+	m.drawCodewords(interleave(versions[ver], encodeData(versions[ver], countBits, data)))
+
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		m.applyMask(mask)
+		m.drawFormat(mask)
+		if p := m.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		m.applyMask(mask) // masking twice undoes it
+	}
+	m.applyMask(best)
+	m.drawFormat(best)
+	return &Code{Size: m.size, modules: m.modules}
+}
+
+// encodeData returns the data codewords: the byte-mode segment for data,
+// terminated and padded to the version's capacity.
+func encodeData(v version, countBits int, data []byte) []byte {
+	var bb bitBuffer
+	bb.append(0b0100, 4) // byte mode
+	bb.append(uint32(len(data)), countBits)
+	for _, b := range data {
+		bb.append(uint32(b), 8)
+	}
+	capacity := 8 * v.dataCodewords()
+	bb.append(0, min(4, capacity-len(bb)))
+	bb.append(0, (8-len(bb)%8)%8)
+	for pad := uint32(0xEC); len(bb) < capacity; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+	return bb.bytes()
+}
+
+// interleave splits data into the version's blocks, adds each block's
+// error correction codewords, and interleaves them in symbol order.
+func interleave(v version, data []byte) []byte {
+	var blocks, ecc [][]byte
+	for _, g := range v.groups {
+		for range g[0] {
+			blocks = append(blocks, data[:g[1]])
+			ecc = append(ecc, reedSolomon(data[:g[1]], v.ecPerBlock))
+			data = data[g[1]:]
+		}
+	}
+	var out []byte
+	for i := 0; ; i++ {
+		added := false
+		for _, b := range blocks {
+			if i < len(b) {
+				out = append(out, b[i])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	for i := range v.ecPerBlock {
+		for _, e := range ecc {
+			out = append(out, e[i])
+		}
+	}
+	return out
+}
+
+// bitBuffer is a sequence of bits, most significant first.
+type bitBuffer []bool
+
+func (b *bitBuffer) append(v uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, v>>i&1 == 1)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	out := make([]byte, (len(b)+7)/8)
+	for i, bit := range b {
+		if bit {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// Image renders the code, with its quiet zone, as a black-on-white image
+// px pixels square. Modules are whole pixels, so a px that isn't a multiple
+// of the symbol's width leaves a slightly wider border.
+func (c *Code) Image(px int) image.Image {
+	width := c.Size + 2*quietZone
+	scale := max(1, px/width)
+	px = max(px, width)
+	offset := (px - scale*c.Size) / 2
+	img := image.NewPaletted(image.Rect(0, 0, px, px), color.Palette{color.White, color.Black})
+	for y := range c.Size {
+		for x := range c.Size {
+			if !c.modules[y][x] {
+				continue
+			}
+			for dy := range scale {
+				row := img.Pix[(offset+y*scale+dy)*img.Stride:]
+				for dx := range scale {
+					row[offset+x*scale+dx] = 1
+				}
+			}
+		}
+	}
+	return img
+}
@@ -0,0 +1,199 @@
+package qr
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// The widely used worked example: "HELLO WORLD" at 1-M.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	assert.Equal(t, []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}, reedSolomon(data, 10))
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	assert.Equal(t, uint32(0b101010000010010), formatBits(0))
+	assert.Equal(t, uint32(0b100000011001110), formatBits(5))
+	assert.Equal(t, uint32(0b100101010100000), formatBits(7))
+	assert.Equal(t, uint32(0b000111110010010100), versionBits(7))
+	assert.Equal(t, uint32(0b001010010011010011), versionBits(10))
+}
+
+func TestEncodeData(t *testing.T) {
+	got := encodeData(versions[1], 8, []byte("ab"))
+	assert.Equal(t, []byte{0x40, 0x26, 0x16, 0x20, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}, got)
+}
+
+// decode reads c back: the format information, the unmasked data modules
+// in placement order, each block's error correction, and the byte-mode
+// segment. It is the inverse of build, written from the standard rather
+// than by reusing build's helpers where that could hide a shared mistake.
+func decode(t *testing.T, c *Code) []byte {
+	t.Helper()
+	ver := (c.Size - 17) / 4
+	require.Equal(t, 4*ver+17, c.Size)
+
+	var format uint32
+	for i := 0; i < 6; i++ {
+		format |= b2u(c.Dark(8, i)) << i
+	}
+	format |= b2u(c.Dark(8, 7))<<6 | b2u(c.Dark(8, 8))<<7 | b2u(c.Dark(7, 8))<<8
+	for i := 9; i < 15; i++ {
+		format |= b2u(c.Dark(14-i, 8)) << i
+	}
+	mask := -1
+	for m := 0; m < 8; m++ {
+		if formatBits(m) == format {
+			mask = m
+		}
+	}
+	require.NotEqual(t, -1, mask, "format information is valid for level M")
+
+	var second uint32
+	for i := 0; i < 8; i++ {
+		second |= b2u(c.Dark(c.Size-1-i, 8)) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= b2u(c.Dark(8, c.Size-15+i)) << i
+	}
+	assert.Equal(t, format, second, "both format copies agree")
+	assert.True(t, c.Dark(8, c.Size-8), "dark module")
+
+	fn := newMatrix(ver)
+	fn.drawFunctionPatterns(ver)
+	var bits []bool
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.Size - 1 - vert
+				}
+				if !fn.function[y][x] {
+					bits = append(bits, c.Dark(x, y) != maskAt(mask, x, y))
+				}
+			}
+		}
+	}
+	codewords := bitBuffer(bits[:len(bits)/8*8]).bytes()
+
+	v := versions[ver]
+	var blocks [][]byte
+	for _, g := range v.groups {
+		for n := 0; n < g[0]; n++ {
+			blocks = append(blocks, make([]byte, 0, g[1]+v.ecPerBlock))
+		}
+	}
+	lengths := []int{}
+	for _, g := range v.groups {
+		for n := 0; n < g[0]; n++ {
+			lengths = append(lengths, g[1])
+		}
+	}
+	k := 0
+	for i := 0; i < lengths[len(lengths)-1]; i++ {
+		for b := range blocks {
+			if i < lengths[b] {
+				blocks[b] = append(blocks[b], codewords[k])
+				k++
+			}
+		}
+	}
+	for i := 0; i < v.ecPerBlock; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], codewords[k])
+			k++
+		}
+	}
+	var data []byte
+	for b, blk := range blocks {
+		assert.Equal(t, blk[lengths[b]:], reedSolomon(blk[:lengths[b]], v.ecPerBlock), "block %d error correction", b)
+		data = append(data, blk[:lengths[b]]...)
+	}
+
+	require.Equal(t, byte(0x4), data[0]>>4, "byte mode")
+	var bb bitBuffer
+	for _, d := range data {
+		bb.append(uint32(d), 8)
+	}
+	countBits := 8
+	if ver >= 10 {
+		countBits = 16
+	}
+	n := 0
+	for _, bit := range bb[4 : 4+countBits] {
+		n = n<<1 | int(b2u(bit))
+	}
+	out := bitBuffer(bb[4+countBits : 4+countBits+8*n]).bytes()
+	return out
+}
+
+func b2u(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"https://kitchinv.example/areas/1",
+		"http://192.168.1.20:8080/areas/42",
+		strings.Repeat("https://a.example/", 6),
+		strings.Repeat("x", 150),
+		strings.Repeat("y", 213),
+	} {
+		c, err := Encode([]byte(s))
+		require.NoError(t, err, s)
+		assert.Equal(t, s, string(decode(t, c)), "version %d", (c.Size-17)/4)
+	}
+
+	c, err := Encode([]byte("https://kitchinv.example/areas/1"))
+	require.NoError(t, err)
+	assert.Equal(t, 29, c.Size, "32 bytes need version 3")
+
+	_, err = Encode(bytes.Repeat([]byte("z"), 214))
+	assert.ErrorIs(t, err, ErrTooLong)
+}
+
+func TestFinderPatterns(t *testing.T) {
+	c, err := Encode([]byte("hello"))
+	require.NoError(t, err)
+	for _, corner := range [][2]int{{0, 0}, {c.Size - 7, 0}, {0, c.Size - 7}} {
+		for i := 0; i < 7; i++ {
+			assert.True(t, c.Dark(corner[0]+i, corner[1]), "top edge")
+			assert.True(t, c.Dark(corner[0], corner[1]+i), "left edge")
+		}
+		assert.False(t, c.Dark(corner[0]+1, corner[1]+1))
+		assert.True(t, c.Dark(corner[0]+3, corner[1]+3), "centre")
+	}
+}
+
+func TestImage(t *testing.T) {
+	c, err := Encode([]byte("hello")) // version 1, 21 modules
+	require.NoError(t, err)
+	img := c.Image(300)
+	assert.Equal(t, image.Rect(0, 0, 300, 300), img.Bounds())
+	// 29 modules with the quiet zone at 10 pixels each, centred.
+	offset := (300 - 10*21) / 2
+	assert.Equal(t, uint32(0xffff), gray(img, offset-1, offset-1), "quiet zone")
+	assert.Equal(t, uint32(0), gray(img, offset, offset), "finder corner")
+	assert.Equal(t, uint32(0), gray(img, offset+9, offset+9))
+
+	assert.Equal(t, image.Rect(0, 0, 29, 29), c.Image(1).Bounds(), "never smaller than a pixel a module")
+}
+
+func gray(img image.Image, x, y int) uint32 {
+	r, _, _, _ := img.At(x, y).RGBA()
+	return r
+}
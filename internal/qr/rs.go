@@ -0,0 +1,60 @@
+package qr
+
+// gfExp and gfLog are exponent and logarithm tables for GF(256) with the
+// QR code polynomial x^8 + x^4 + x^3 + x^2 + 1. gfExp is doubled so that
+// products of two logarithms index it directly.
+var gfExp, gfLog = func() (exp [512]byte, log [256]byte) {
+	x := 1
+	for i := range 255 {
+		exp[i], exp[i+255] = byte(x), byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	return exp, log
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// rsGenerator returns the coefficients, highest power first without the
+// leading 1, of the Reed-Solomon generator polynomial of the given degree:
+// (x - α^0)(x - α^1)…(x - α^(degree-1)).
+func rsGenerator(degree int) []byte {
+	g := make([]byte, degree)
+	g[degree-1] = 1 // start from the constant polynomial 1
+	root := byte(1)
+	for range degree {
+		// Multiply by (x - root), i.e. (x + root) in GF(256).
+		for j := range degree {
+			g[j] = gfMul(g[j], root)
+			if j+1 < degree {
+				g[j] ^= g[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+	return g
+}
+
+// reedSolomon returns the n error correction codewords for data: the
+// remainder of data·x^n divided by the generator polynomial.
+func reedSolomon(data []byte, n int) []byte {
+	g := rsGenerator(n)
+	rem := make([]byte, n)
+	for _, b := range data {
+		factor := b ^ rem[0]
+		copy(rem, rem[1:])
+		rem[n-1] = 0
+		for i := range rem {
+			rem[i] ^= gfMul(g[i], factor)
+		}
+	}
+	return rem
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// ErrUnknownAreaKind is returned when an area kind is not one of
+// domain.AreaKinds.
+var ErrUnknownAreaKind = errors.New("unknown area kind")
+
+// kindPrompts replace the backend's default user prompt for areas of a kind
+// that has no custom prompt. Kinds without an entry use the default.
+var kindPrompts = map[domain.AreaKind]string{
+	domain.AreaKindFridge: `List every food item visible in this fridge, including the door shelves and drawers. ` +
+		`Be as specific as possible — include brand names where visible. List every individual item, do not group or summarise.`,
+	domain.AreaKindFreezer: `List every item in this freezer. Describe the contents, not the packaging (e.g. "Frozen Peas", "Chicken Thighs"). ` +
+		`Read any handwritten labels or dates. If an item is covered in frost or freezer burn, or shows an expiry date, ` +
+		`mention it in the item's notes. List every individual item, do not group or summarise.`,
+	domain.AreaKindPantry: `List every item on these pantry shelves: cans, jars, boxes, bags and bottles. ` +
+		`Be as specific as possible — include brand names and varieties where visible. List every individual item, do not group or summarise.`,
+	domain.AreaKindSpiceRack: `This is a spice rack. Read the label on every jar, bottle and packet and list each by the spice or ` +
+		`seasoning it contains (e.g. "Smoked Paprika", "Ground Cumin"), not by the brand alone. List every container separately.`,
+}
+
+// analysisPrompt picks the prompt for an area's uploads: its custom prompt if
+// set, otherwise its kind's prompt, otherwise "" for the backend default.
+func analysisPrompt(area *domain.Area) string {
+	if p := strings.TrimSpace(area.Prompt); p != "" {
+		return p
+	}
+	return kindPrompts[area.Kind]
+}
+
+// CreateAreaWithKind is CreateArea for an area of the given kind.
+func (s *AreaService) CreateAreaWithKind(ctx context.Context, name string, kind domain.AreaKind) (*domain.Area, error) {
+	if _, ok := domain.ParseAreaKind(string(kind)); !ok {
+		return nil, ErrUnknownAreaKind
+	}
+	area, err := s.areaStore.CreateWithKind(ctx, name, kind)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrNameTaken
+		}
+		return nil, err
+	}
+	s.publishArea(ctx, area.ID)
+	return area, nil
+}
+
+// SetAreaKind changes what kind of area this is, which changes the prompt
+// used for its future uploads unless it has a custom one. It returns
+// ErrAreaNotFound for a missing area.
+func (s *AreaService) SetAreaKind(ctx context.Context, areaID int64, kind domain.AreaKind) (*domain.Area, error) {
+	if _, ok := domain.ParseAreaKind(string(kind)); !ok {
+		return nil, ErrUnknownAreaKind
+	}
+	if err := s.areaStore.UpdateKind(ctx, areaID, kind); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAreaNotFound
+		}
+		return nil, fmt.Errorf("failed to update area kind: %w", err)
+	}
+	return s.areaStore.GetByID(ctx, areaID)
+}
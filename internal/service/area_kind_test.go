@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/db"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/store"
+)
+
+func TestAreaServiceCreateAreaWithKind(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	area, err := svc.CreateAreaWithKind(ctx, "Chest Freezer", domain.AreaKindFreezer)
+	require.NoError(t, err)
+	assert.Equal(t, domain.AreaKindFreezer, area.Kind)
+
+	plain, err := svc.CreateArea(ctx, "Shelf")
+	require.NoError(t, err)
+	assert.Equal(t, domain.AreaKindOther, plain.Kind)
+
+	_, err = svc.CreateAreaWithKind(ctx, "Chest Freezer", domain.AreaKindFreezer)
+	assert.ErrorIs(t, err, ErrNameTaken)
+
+	_, err = svc.CreateAreaWithKind(ctx, "Cellar", domain.AreaKind("cellar"))
+	assert.ErrorIs(t, err, ErrUnknownAreaKind)
+}
+
+func TestAreaServiceSetAreaKind(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Rack")
+	require.NoError(t, err)
+
+	updated, err := svc.SetAreaKind(ctx, area.ID, domain.AreaKindSpiceRack)
+	require.NoError(t, err)
+	assert.Equal(t, domain.AreaKindSpiceRack, updated.Kind)
+
+	_, err = svc.SetAreaKind(ctx, area.ID, domain.AreaKind(""))
+	assert.ErrorIs(t, err, ErrUnknownAreaKind)
+
+	_, err = svc.SetAreaKind(ctx, 99999, domain.AreaKindSpiceRack)
+	assert.ErrorIs(t, err, ErrAreaNotFound)
+}
+
+func TestAreaServiceUploadPhoto_UsesKindPrompt(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	vis := &promptVision{}
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		vis,
+		newStubPhotoStore(),
+		slog.Default(),
+	).WithDB(d)
+	ctx := context.Background()
+
+	area, err := svc.CreateAreaWithKind(ctx, "Freezer", domain.AreaKindFreezer)
+	require.NoError(t, err)
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
+	require.NoError(t, err)
+
+	// A custom prompt wins over the kind's.
+	_, err = svc.SetAreaPrompt(ctx, area.ID, "Only count the ice cream.")
+	require.NoError(t, err)
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x02}, "image/jpeg")
+	require.NoError(t, err)
+
+	// "other" has no prompt of its own.
+	_, err = svc.SetAreaPrompt(ctx, area.ID, "")
+	require.NoError(t, err)
+	_, err = svc.SetAreaKind(ctx, area.ID, domain.AreaKindOther)
+	require.NoError(t, err)
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x03}, "image/jpeg")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{kindPrompts[domain.AreaKindFreezer], "Only count the ice cream.", ""}, vis.prompts)
+	assert.Contains(t, kindPrompts[domain.AreaKindFreezer], "frost")
+}
@@ -3,16 +3,25 @@ package service
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"slices"
 	"strings"
 	"sync"
+	"text/template"
+	"time"
 
+	"github.com/vbonduro/kitchinv/internal/audit"
 	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/logging"
 	"github.com/vbonduro/kitchinv/internal/photostore"
+	"github.com/vbonduro/kitchinv/internal/tracing"
 	"github.com/vbonduro/kitchinv/internal/vision"
 )
 
@@ -29,6 +38,43 @@ func encodeBBoxesJSON(bboxes [][]float64) interface{} {
 	return string(b)
 }
 
+// ErrTooManyAnalyses is returned by UploadPhoto when the configured number of
+// concurrent vision analyses is already running.
+var ErrTooManyAnalyses = errors.New("too many analyses in progress")
+
+// ErrAnalysisInProgress is returned by UploadPhoto when the area already has
+// an upload being analysed.
+var ErrAnalysisInProgress = errors.New("an analysis is already running for this area")
+
+// ErrAnalysisTimeout is returned by UploadPhoto when the vision backend
+// doesn't answer within the analysis timeout.
+var ErrAnalysisTimeout = errors.New("vision analysis timed out")
+
+// interruptedReason is recorded on photos whose analysis was still running
+// when the process stopped.
+const interruptedReason = "Analysis was interrupted by a restart. Upload the photo again."
+
+// ErrAreaNotFound is returned when the requested area does not exist or has
+// been deleted.
+var ErrAreaNotFound = errors.New("area not found")
+
+// ErrNoPhoto is returned when an area has no photo to act on.
+var ErrNoPhoto = errors.New("area has no photo")
+
+// ErrNoKeptItems is returned by ResolveKeptItems when the area's latest
+// photo didn't keep the previous items.
+var ErrNoKeptItems = errors.New("the area's latest photo is not awaiting a decision on its kept items")
+
+// BulkItemError is returned by BulkEditItems when one or more entries were
+// rejected. Nothing from the batch is applied.
+type BulkItemError struct {
+	Failures []domain.ItemOpFailure
+}
+
+func (e *BulkItemError) Error() string {
+	return fmt.Sprintf("%d bulk item operation(s) rejected", len(e.Failures))
+}
+
 // ErrNameTaken is returned by UpdateArea when the requested name is already
 // used by another area.
 var ErrNameTaken = errors.New("an area with this name already exists")
@@ -36,17 +82,36 @@ var ErrNameTaken = errors.New("an area with this name already exists")
 // areaRepository is the subset of store.AreaStore that AreaService requires.
 type areaRepository interface {
 	Create(ctx context.Context, name string) (*domain.Area, error)
+	CreateWithKind(ctx context.Context, name string, kind domain.AreaKind) (*domain.Area, error)
+	CreateChild(ctx context.Context, parentID int64, name string, kind domain.AreaKind) (*domain.Area, error)
+	HasChildren(ctx context.Context, id int64) (bool, error)
 	GetByID(ctx context.Context, id int64) (*domain.Area, error)
 	List(ctx context.Context) ([]*domain.Area, error)
 	Update(ctx context.Context, id int64, name string) error
+	UpdatePrompt(ctx context.Context, id int64, prompt string) error
+	UpdateKind(ctx context.Context, id int64, kind domain.AreaKind) error
+	Touch(ctx context.Context, id int64) error
 	Delete(ctx context.Context, id int64) error
 	UpdateSortOrder(ctx context.Context, ids []int64) error
+	Restore(ctx context.Context, id int64, deletedAfter time.Time) (bool, error)
+	ListDeletedBefore(ctx context.Context, cutoff time.Time) ([]*domain.Area, error)
+	Purge(ctx context.Context, id int64) error
 }
 
 // photoRepository is the subset of store.PhotoStore that AreaService requires.
 type photoRepository interface {
-	Create(ctx context.Context, areaID int64, storageKey, mimeType string) (*domain.Photo, error)
+	Create(ctx context.Context, areaID int64, storageKey, mimeType, contentHash string) (*domain.Photo, error)
 	GetLatestByAreaID(ctx context.Context, areaID int64) (*domain.Photo, error)
+	ListByAreaID(ctx context.Context, areaID int64) ([]*domain.Photo, error)
+	SetAnalysisStatus(ctx context.Context, id int64, status domain.PhotoAnalysisStatus, errMsg string) error
+	SetRawResponse(ctx context.Context, id int64, raw string) error
+	GetRawResponse(ctx context.Context, id int64) (string, error)
+	SetUsage(ctx context.Context, id int64, u domain.AnalysisUsage) error
+	SetStopReason(ctx context.Context, id int64, reason string, truncated bool) error
+	SetItemsKept(ctx context.Context, id int64, kept bool) error
+	MonthlyUsage(ctx context.Context, since time.Time) ([]domain.MonthlyUsage, error)
+	FailRunningBefore(ctx context.Context, cutoff time.Time, reason string) (int64, error)
+	CountByStorageKey(ctx context.Context, storageKey string) (int, error)
 	Delete(ctx context.Context, id int64) error
 	DeleteByArea(ctx context.Context, areaID int64) (*domain.Photo, error)
 }
@@ -54,12 +119,27 @@ type photoRepository interface {
 // itemRepository is the subset of store.ItemStore that AreaService requires.
 type itemRepository interface {
 	Create(ctx context.Context, areaID int64, photoID *int64, name, quantity, source string, bboxes [][]float64) (*domain.Item, error)
+	CreateBatch(ctx context.Context, areaID, photoID int64, detected []domain.DetectedItem) ([]*domain.Item, error)
+	CreateBatchTx(ctx context.Context, tx *sql.Tx, areaID, photoID int64, detected []domain.DetectedItem) ([]*domain.Item, error)
 	GetByID(ctx context.Context, id int64) (*domain.Item, error)
 	ListByAreaID(ctx context.Context, areaID int64) ([]*domain.Item, error)
-	Update(ctx context.Context, id int64, name, quantity string) error
+	ListByAreaIDPage(ctx context.Context, areaID int64, page domain.ItemPage) ([]*domain.Item, error)
+	ListByAreaIDTx(ctx context.Context, tx *sql.Tx, areaID int64) ([]*domain.Item, error)
+	ListPreviews(ctx context.Context, limit int) (map[int64]*domain.ItemPreview, error)
+	Update(ctx context.Context, id, version int64, name, quantity string) error
 	Delete(ctx context.Context, id int64) error
 	DeleteByAreaID(ctx context.Context, areaID int64) error
+	DeleteUneditedByAreaID(ctx context.Context, areaID int64) error
 	Search(ctx context.Context, query string) ([]*domain.Item, error)
+	SearchPage(ctx context.Context, query string, filter domain.SearchFilter, page domain.ItemPage) ([]*domain.Item, error)
+	SearchWordPrefix(ctx context.Context, prefix string, filter domain.SearchFilter) ([]*domain.Item, error)
+	SuggestNames(ctx context.Context, query string, limit int) ([]string, error)
+	ListRecent(ctx context.Context, since time.Time, limit, offset int) ([]*domain.RecentItem, error)
+	ApplyOps(ctx context.Context, areaID int64, ops []domain.ItemOp) ([]domain.ItemOpFailure, error)
+	SetCropKey(ctx context.Context, id int64, key string) error
+	CountByCropKey(ctx context.Context, key string) (int, error)
+	SetStatus(ctx context.Context, id int64, status domain.ItemStatus) (bool, error)
+	MonthlyWaste(ctx context.Context, since time.Time) ([]domain.MonthlyWaste, error)
 }
 
 // itemEditRepository is the subset of store.ItemEditStore that AreaService requires.
@@ -70,8 +150,10 @@ type itemEditRepository interface {
 
 // snapshotRepository persists area inventory snapshots.
 type snapshotRepository interface {
-	Create(ctx context.Context, areaID int64, items []domain.SnapshotItem) (*domain.Snapshot, error)
+	Create(ctx context.Context, areaID int64, photoID *int64, items []domain.SnapshotItem) (*domain.Snapshot, error)
+	CreateTx(ctx context.Context, tx *sql.Tx, areaID int64, photoID *int64, items []domain.SnapshotItem) (*domain.Snapshot, error)
 	ListByAreaID(ctx context.Context, areaID int64) ([]*domain.Snapshot, error)
+	GetByPhotoID(ctx context.Context, photoID int64) (*domain.Snapshot, error)
 }
 
 // overrideRepository manages item name override rules.
@@ -88,19 +170,62 @@ type overrideRepository interface {
 }
 
 type AreaService struct {
-	areaStore      areaRepository
-	photoStore     photoRepository
-	itemStore      itemRepository
-	itemEditStore  itemEditRepository
-	snapshotStore  snapshotRepository
-	overrideStore  overrideRepository
-	visionAPI      vision.VisionAnalyzer
-	photoStg       photostore.PhotoStore
-	logger         *slog.Logger
-	db             *sql.DB
-	uploadLocks    sync.Map // key: int64 areaID → *sync.Mutex
+	areaStore       areaRepository
+	photoStore      photoRepository
+	itemStore       itemRepository
+	itemEditStore   itemEditRepository
+	snapshotStore   snapshotRepository
+	overrideStore   overrideRepository
+	visionAPI       vision.VisionAnalyzer
+	photoStg        photostore.PhotoStore
+	logger          *slog.Logger
+	db              *sql.DB
+	photoFilesMu    sync.Mutex // orders saving a shared photo file against releasing it
+	inflightMu      sync.Mutex
+	inflight        map[int64]*inflightAnalysis // keyed by area ID
+	attention       AttentionWeights
+	areaRetention   time.Duration
+	analysisSlots   chan struct{} // nil means unlimited
+	analysisTimeout time.Duration // 0 means no limit
+	staleAfter      time.Duration // 0 never flags an area stale
+	keepOnEmpty     bool          // keep the items when an analysis detects none
+	ignoreEnabled   bool
+	ignoreBuiltin   []string
+	ignoreStore     ignoreRepository
+	auditLog        auditRepository   // nil disables the audit log
+	webhookStore    webhookRepository // nil disables webhooks
+	webhookClient   *http.Client
+	webhookBackoff  []time.Duration
+	webhookWG       sync.WaitGroup  // tracks in-flight deliveries
+	webhookMu       sync.Mutex      // orders webhookWG.Add against Close
+	webhookCtx      context.Context // cancelled by Close to abandon retries
+	stopWebhooks    context.CancelFunc
+	publisher       inventoryPublisher // nil disables publishing
+	publishMu       sync.Mutex
+	shelfLife       []ShelfLife
+	expiryEvents    expiryEventRepository // nil leaves every event at sequence 0
+	suggestPrompt   string
+	suggestMaxItems int
+	itemLimits      domain.ItemLimits
+	askPrompt       *template.Template
+	barcodeLookup   productLookup     // nil names scanned items after their barcode
+	barcodeCache    barcodeRepository // nil looks up every scan
+	historyStore    historyRepository // nil keeps no inventory history
+	historyKeep     int               // points kept per area; 0 keeps all
+	stapleStore     stapleRepository  // nil means there are no staples
+	tokenPrices     TokenPrices
+	visionStatusMu  sync.RWMutex
+	visionStatus    VisionStatus
 }
 
+// DefaultAreaRetention is how long a deleted area can be restored before the
+// purge job removes it for good.
+const DefaultAreaRetention = 30 * 24 * time.Hour
+
+// DefaultAnalysisTimeout bounds a single vision call. Local models on slow
+// hardware can take minutes, so it is generous.
+const DefaultAnalysisTimeout = 5 * time.Minute
+
 func NewAreaService(
 	areaStore areaRepository,
 	photoStore photoRepository,
@@ -113,15 +238,25 @@ func NewAreaService(
 	logger *slog.Logger,
 ) *AreaService {
 	return &AreaService{
-		areaStore:     areaStore,
-		photoStore:    photoStore,
-		itemStore:     itemStore,
-		itemEditStore: itemEditStore,
-		snapshotStore: snapshotStore,
-		overrideStore: overrideStore,
-		visionAPI:     visionAPI,
-		photoStg:      photoStg,
-		logger:        logger,
+		areaStore:       areaStore,
+		photoStore:      photoStore,
+		itemStore:       itemStore,
+		itemEditStore:   itemEditStore,
+		snapshotStore:   snapshotStore,
+		overrideStore:   overrideStore,
+		visionAPI:       visionAPI,
+		photoStg:        photoStg,
+		logger:          logger,
+		inflight:        make(map[int64]*inflightAnalysis),
+		attention:       DefaultAttentionWeights,
+		areaRetention:   DefaultAreaRetention,
+		analysisTimeout: DefaultAnalysisTimeout,
+		staleAfter:      DefaultStaleAfter,
+		keepOnEmpty:     true,
+		suggestPrompt:   DefaultSuggestPrompt,
+		suggestMaxItems: DefaultSuggestMaxItems,
+		itemLimits:      domain.DefaultItemLimits,
+		askPrompt:       defaultAskPrompt,
 	}
 }
 
@@ -132,52 +267,188 @@ func (s *AreaService) WithDB(db *sql.DB) *AreaService {
 	return s
 }
 
-func (s *AreaService) lockForArea(areaID int64) func() {
-	v, _ := s.uploadLocks.LoadOrStore(areaID, &sync.Mutex{})
-	mu := v.(*sync.Mutex)
-	mu.Lock()
-	return mu.Unlock
+// WithAttentionWeights overrides the weights used to compute each area's
+// needs-attention score.
+func (s *AreaService) WithAttentionWeights(w AttentionWeights) *AreaService {
+	s.attention = w
+	return s
 }
 
-func (s *AreaService) CreateArea(ctx context.Context, name string) (*domain.Area, error) {
-	area, err := s.areaStore.Create(ctx, name)
+// WithAreaRetention sets how long deleted areas remain restorable.
+func (s *AreaService) WithAreaRetention(d time.Duration) *AreaService {
+	s.areaRetention = d
+	return s
+}
+
+// WithMaxConcurrentAnalyses caps how many vision analyses may run at once;
+// uploads beyond the cap fail fast with ErrTooManyAnalyses. n <= 0 removes
+// the cap.
+func (s *AreaService) WithMaxConcurrentAnalyses(n int) *AreaService {
+	if n > 0 {
+		s.analysisSlots = make(chan struct{}, n)
+	} else {
+		s.analysisSlots = nil
+	}
+	return s
+}
+
+// WithAnalysisTimeout sets how long a single vision call may run before it
+// is cancelled and the upload fails with ErrAnalysisTimeout. d <= 0 removes
+// the limit.
+func (s *AreaService) WithAnalysisTimeout(d time.Duration) *AreaService {
+	s.analysisTimeout = max(d, 0)
+	return s
+}
+
+// WithKeepItemsOnEmpty sets whether an upload whose analysis detects no
+// items keeps the area's previous items, pending ResolveKeptItems, instead
+// of replacing them with nothing. It is on by default.
+func (s *AreaService) WithKeepItemsOnEmpty(keep bool) *AreaService {
+	s.keepOnEmpty = keep
+	return s
+}
+
+// acquireAnalysisSlot reserves one of the concurrent analysis slots. The
+// returned release func must be deferred so the slot is freed on every exit
+// path, including a panic.
+func (s *AreaService) acquireAnalysisSlot() (func(), error) {
+	if s.analysisSlots == nil {
+		return func() {}, nil
+	}
+	select {
+	case s.analysisSlots <- struct{}{}:
+		return func() { <-s.analysisSlots }, nil
+	default:
+		return nil, ErrTooManyAnalyses
+	}
+}
+
+// SweepInterruptedAnalyses marks photos still flagged as analysing and
+// uploaded at or before cutoff as failed. It is meant to run once at startup,
+// before any new uploads are accepted, so every running row it finds was
+// orphaned by the previous process.
+func (s *AreaService) SweepInterruptedAnalyses(ctx context.Context, cutoff time.Time) (int64, error) {
+	n, err := s.photoStore.FailRunningBefore(ctx, cutoff, interruptedReason)
 	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-			return nil, ErrNameTaken
+		return 0, err
+	}
+	if n > 0 {
+		s.log(ctx).Warn("marked interrupted analyses as failed", "count", n)
+	}
+	return n, nil
+}
+
+// log returns the request-scoped logger from ctx when there is one, so
+// service log lines carry the same request ID as the handler that called in.
+func (s *AreaService) log(ctx context.Context) *slog.Logger {
+	return logging.FromContext(ctx, s.logger)
+}
+
+// inflightAnalysis tracks the upload currently writing to an area.
+type inflightAnalysis struct {
+	client context.Context // the uploader's request context
+	cancel context.CancelFunc
+	done   chan struct{} // closed once the upload has finished its cleanup
+}
+
+// beginAnalysis claims areaID for an upload and returns the context the
+// upload must run under, detached from the client so a dropped connection
+// doesn't abandon a half-finished write. If another upload for the area is
+// still running and its client is connected, it fails with
+// ErrAnalysisInProgress. If that client has gone away, its analysis can only
+// produce results nobody is waiting for, so it is cancelled and this call
+// waits for it to clean up before taking over.
+func (s *AreaService) beginAnalysis(ctx context.Context, areaID int64) (context.Context, func(), error) {
+	for {
+		s.inflightMu.Lock()
+		prev := s.inflight[areaID]
+		if prev == nil {
+			actx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+			a := &inflightAnalysis{client: ctx, cancel: cancel, done: make(chan struct{})}
+			s.inflight[areaID] = a
+			s.inflightMu.Unlock()
+			return actx, func() {
+				s.inflightMu.Lock()
+				delete(s.inflight, areaID)
+				s.inflightMu.Unlock()
+				cancel()
+				close(a.done)
+			}, nil
+		}
+		s.inflightMu.Unlock()
+
+		if prev.client.Err() == nil {
+			return nil, nil, ErrAnalysisInProgress
+		}
+		s.log(ctx).Info("superseding abandoned analysis", "area_id", areaID)
+		prev.cancel()
+		select {
+		case <-prev.done:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
 		}
-		return nil, err
 	}
-	return area, nil
+}
+
+func (s *AreaService) CreateArea(ctx context.Context, name string) (*domain.Area, error) {
+	return s.CreateAreaWithKind(ctx, name, domain.AreaKindOther)
 }
 
 func (s *AreaService) ListAreas(ctx context.Context) ([]*domain.Area, error) {
 	return s.areaStore.List(ctx)
 }
 
+// ItemPreviewLimit is how many of an area's items its card on the area list
+// shows; the detail page lists them all.
+const ItemPreviewLimit = 5
+
 // AreaSummary bundles an area with its latest photo and items for list rendering.
 type AreaSummary struct {
 	*domain.Area
 	Photo *domain.Photo
-	Items []*domain.Item
+	// ItemCount is how many active items the area has, and Preview the
+	// first ItemPreviewLimit of them by name. Items lists them all, but only
+	// GetAreaSummary loads it; ListAreaSummaries leaves it nil.
+	ItemCount int
+	Preview   []*domain.Item
+	Items     []*domain.Item
+	// OutOfStock counts the items whose quantity reads as zero.
+	OutOfStock int
+	// Attention is the needs-attention score; higher means the area is more
+	// in need of a fresh photo or a restock. See AttentionWeights.
+	Attention float64
+	// PhotoAge is how long ago the latest photo was uploaded; zero without one.
+	PhotoAge time.Duration
+	// Stale is set once PhotoAge passes the threshold set by WithStaleAfter.
+	Stale bool
+	// Children are the area's sub-areas. TotalItems counts the area's own
+	// items plus theirs.
+	Children   []*AreaSummary
+	TotalItems int
 }
 
-func (s *AreaService) ListAreasWithItems(ctx context.Context) ([]*AreaSummary, error) {
+// ListAreaSummaries returns every area's summary for the area list. Each
+// area's items are counted and previewed rather than loaded, so the number
+// of queries doesn't grow with the inventory.
+func (s *AreaService) ListAreaSummaries(ctx context.Context) ([]*AreaSummary, error) {
 	areas, err := s.areaStore.List(ctx)
 	if err != nil {
 		return nil, err
 	}
+	previews, err := s.itemStore.ListPreviews(ctx, ItemPreviewLimit)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
 	summaries := make([]*AreaSummary, 0, len(areas))
 	for _, area := range areas {
-		items, err := s.itemStore.ListByAreaID(ctx, area.ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to list items for area %d: %w", area.ID, err)
-		}
 		photo, err := s.photoStore.GetLatestByAreaID(ctx, area.ID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get photo for area %d: %w", area.ID, err)
 		}
-		summaries = append(summaries, &AreaSummary{Area: area, Photo: photo, Items: items})
+		summaries = append(summaries, s.summarize(area, photo, previews[area.ID], now))
 	}
+	rollUpChildren(summaries)
 	return summaries, nil
 }
 
@@ -185,115 +456,644 @@ func (s *AreaService) GetArea(ctx context.Context, areaID int64) (*domain.Area,
 	return s.areaStore.GetByID(ctx, areaID)
 }
 
+// DeleteArea moves an area to the trash. It disappears from listings and
+// search immediately but can be brought back with RestoreArea until the
+// retention window passes and PurgeDeletedAreas removes it. An area with
+// sub-areas is not deleted; ErrHasChildren is returned instead.
 func (s *AreaService) DeleteArea(ctx context.Context, areaID int64) error {
+	hasChildren, err := s.areaStore.HasChildren(ctx, areaID)
+	if err != nil {
+		return err
+	}
+	if hasChildren {
+		return ErrHasChildren
+	}
+	area, err := s.areaStore.GetByID(ctx, areaID)
+	if err != nil {
+		return fmt.Errorf("failed to get area: %w", err)
+	}
 	if err := s.areaStore.Delete(ctx, areaID); err != nil {
 		return err
 	}
+	if area != nil {
+		s.audit(ctx, audit.ActionAreaDelete, audit.EntityArea, areaID, areaID, map[string]any{"name": area.Name})
+		s.emitWebhook(ctx, WebhookPayload{Event: EventAreaDeleted, Area: &WebhookArea{ID: areaID, Name: area.Name}})
+	}
+	s.publishArea(ctx, areaID)
+	return nil
+}
+
+// RestoreArea undoes DeleteArea. It returns (nil, nil) if the area is not in
+// the trash or has aged out of the retention window, and ErrNameTaken if
+// another area has claimed the name in the meantime.
+func (s *AreaService) RestoreArea(ctx context.Context, areaID int64) (*domain.Area, error) {
+	restored, err := s.areaStore.Restore(ctx, areaID, time.Now().Add(-s.areaRetention))
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrNameTaken
+		}
+		return nil, err
+	}
+	if !restored {
+		return nil, nil
+	}
+	area, err := s.areaStore.GetByID(ctx, areaID)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, audit.ActionAreaRestore, audit.EntityArea, areaID, areaID, map[string]any{"name": area.Name})
+	s.publishArea(ctx, areaID)
+	return area, nil
+}
+
+// PurgeDeletedAreas permanently removes areas deleted at or before cutoff,
+// along with their photo files, and returns how many were purged. Failures on
+// individual areas are logged and skipped so one bad row doesn't block the rest.
+func (s *AreaService) PurgeDeletedAreas(ctx context.Context, cutoff time.Time) (int, error) {
+	areas, err := s.areaStore.ListDeletedBefore(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, area := range areas {
+		photos, err := s.photoStore.ListByAreaID(ctx, area.ID)
+		if err != nil {
+			s.log(ctx).Error("failed to list photos for purge", "area_id", area.ID, "error", err)
+			continue
+		}
+		// Consumed and discarded items keep their thumbnails too.
+		items, err := s.itemStore.ListByAreaIDPage(ctx, area.ID, domain.ItemPage{Status: domain.ItemStatusAll})
+		if err != nil {
+			s.log(ctx).Error("failed to list items for purge", "area_id", area.ID, "error", err)
+			continue
+		}
+		if err := s.areaStore.Purge(ctx, area.ID); err != nil {
+			s.log(ctx).Error("failed to purge area", "area_id", area.ID, "error", err)
+			continue
+		}
+		for _, p := range photos {
+			s.releasePhotoFile(ctx, p.StorageKey)
+		}
+		s.releaseCrops(ctx, items)
+		purged++
+	}
+
 	// The ON DELETE CASCADE on override_rule_areas removes the area association;
 	// now clean up any area-scoped rules that have no remaining areas.
-	if s.overrideStore != nil {
+	if purged > 0 && s.overrideStore != nil {
 		if err := s.overrideStore.DeleteOrphanedAreaRules(ctx); err != nil {
-			s.logger.Error("failed to delete orphaned area override rules", "area_id", areaID, "error", err)
+			s.log(ctx).Error("failed to delete orphaned area override rules", "error", err)
 		}
 	}
-	return nil
+	return purged, nil
+}
+
+// RunPurgeLoop calls PurgeDeletedAreas immediately and then every interval
+// until ctx is cancelled.
+func (s *AreaService) RunPurgeLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := s.PurgeDeletedAreas(ctx, time.Now().Add(-s.areaRetention))
+		if err != nil {
+			s.log(ctx).Error("purge deleted areas failed", "error", err)
+		} else if n > 0 {
+			s.log(ctx).Info("purged deleted areas", "count", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// UploadOptions adjusts how UploadPhotoWithOptions handles an upload.
+type UploadOptions struct {
+	// Force analyses the photo even when it is byte-for-byte identical to the
+	// area's latest photo.
+	Force bool
+	// ReplaceEdited discards items the user created or corrected along with
+	// the machine-generated ones, instead of keeping them.
+	ReplaceEdited bool
+	// ReplaceEmpty replaces the area's items even when the analysis detects
+	// none, rather than keeping them (see WithKeepItemsOnEmpty).
+	ReplaceEmpty bool
 }
 
-// UploadPhoto saves the photo to storage and commits the DB record before running
-// vision analysis, so a page refresh during analysis sees Photo&&!Items (analysing
-// state) and resumes polling. Concurrent calls for the same areaID are serialised;
-// concurrent calls for different areas run in parallel.
+// PhotoUpload is one image of an upload.
+type PhotoUpload struct {
+	Data     []byte
+	MIMEType string
+}
+
+// UploadPhoto is UploadPhotoWithOptions with the default options.
 func (s *AreaService) UploadPhoto(ctx context.Context, areaID int64, imageData []byte, mimeType string) (*domain.Photo, []*domain.Item, error) {
-	s.logger.Info("upload photo started", "area_id", areaID, "mime_type", mimeType, "bytes", len(imageData))
+	return s.UploadPhotoWithOptions(ctx, areaID, imageData, mimeType, UploadOptions{})
+}
+
+// UploadPhotoWithOptions is UploadPhotos for a single photo.
+func (s *AreaService) UploadPhotoWithOptions(ctx context.Context, areaID int64, imageData []byte, mimeType string, opts UploadOptions) (*domain.Photo, []*domain.Item, error) {
+	photos, items, err := s.UploadPhotos(ctx, areaID, []PhotoUpload{{Data: imageData, MIMEType: mimeType}}, opts)
+	if len(photos) == 0 {
+		return nil, items, err
+	}
+	return photos[0], items, err
+}
+
+// UploadPhotos saves the photos to storage and commits their DB records
+// before running vision analysis, so a page refresh during analysis sees a
+// photo whose analysis is still running and resumes polling. A second upload
+// for an area whose analysis is still running fails fast with
+// ErrAnalysisInProgress; uploads for different areas run in parallel, up to
+// the WithMaxConcurrentAnalyses cap.
+//
+// An area too big for one photo can be uploaded as several: each is analysed
+// in turn, and their items are merged (see mergePhotoDetections) and replace
+// the area's items once. If any analysis fails, none of the photos are kept.
+//
+// Re-uploading the area's latest photo unchanged, on its own, returns the
+// existing photo and items without calling the vision backend, unless
+// opts.Force is set.
+//
+// If the analysis detects no items at all, an unreadable reply can't be told
+// from an empty shelf, so the area's items are kept rather than wiped and
+// the latest photo is flagged ItemsKept until ResolveKeptItems settles it.
+func (s *AreaService) UploadPhotos(ctx context.Context, areaID int64, uploads []PhotoUpload, opts UploadOptions) ([]*domain.Photo, []*domain.Item, error) {
+	ctx, span := tracing.Start(ctx, "AreaService.UploadPhotos", tracing.KindInternal)
+	defer span.End()
+	span.SetInt("area_id", areaID)
+	span.SetInt("photo_count", int64(len(uploads)))
+	photos, items, err := s.uploadPhotos(ctx, areaID, uploads, opts)
+	span.SetError(err)
+	return photos, items, err
+}
+
+func (s *AreaService) uploadPhotos(ctx context.Context, areaID int64, uploads []PhotoUpload, opts UploadOptions) ([]*domain.Photo, []*domain.Item, error) {
+	if len(uploads) == 0 {
+		return nil, nil, errors.New("no photos to upload")
+	}
+	for i, u := range uploads {
+		s.log(ctx).Info("upload photo started", "area_id", areaID, "photo_index", i, "mime_type", u.MIMEType, "bytes", len(u.Data))
+	}
 
 	area, err := s.areaStore.GetByID(ctx, areaID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get area: %w", err)
 	}
 	if area == nil {
-		return nil, nil, fmt.Errorf("area not found")
+		return nil, nil, ErrAreaNotFound
+	}
+
+	// Hold the area for the whole write sequence: create photo records, delete
+	// old items, insert new items. Rejecting rather than queueing means a
+	// double-tapped upload button can't trigger a second paid analysis.
+	ctx, finish, err := s.beginAnalysis(ctx, areaID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer finish()
+	// Rollback and status writes must land even if this upload is superseded.
+	cleanupCtx := context.WithoutCancel(ctx)
+
+	if len(uploads) == 1 && !opts.Force {
+		photo, items, err := s.unchangedPhoto(ctx, areaID, contentHash(uploads[0].Data))
+		if err != nil {
+			return nil, nil, err
+		}
+		if photo != nil {
+			s.log(ctx).Info("photo unchanged, skipping analysis", "area_id", areaID, "photo_id", photo.ID, "items", len(items))
+			return []*domain.Photo{photo}, items, nil
+		}
+	}
+
+	// The photos are analysed one after another, so one slot covers them.
+	release, err := s.acquireAnalysisSlot()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+
+	// Save the photos and commit the DB records before calling the vision API
+	// so that a client disconnect/refresh sees Photo&&!Items and polls for
+	// results.
+	photos := make([]*domain.Photo, 0, len(uploads))
+	for _, u := range uploads {
+		photo, err := s.storePhoto(ctx, areaID, u.MIMEType, u.Data, contentHash(u.Data))
+		if err != nil {
+			s.discardPhotos(cleanupCtx, areaID, photos)
+			return nil, nil, err
+		}
+		s.log(ctx).Debug("photo saved", "area_id", areaID, "storage_key", photo.StorageKey)
+		photos = append(photos, photo)
+	}
+	latest := photos[len(photos)-1]
+
+	detected := make([]photoDetections, 0, len(photos))
+	found, ignored := 0, 0
+	for i, photo := range photos {
+		s.log(ctx).Info("vision analysis started", "area_id", areaID, "photo_index", i)
+		result, err := s.analyze(ctx, uploads[i].Data, uploads[i].MIMEType, analysisPrompt(area))
+		if err == nil {
+			// A vision call that ignores cancellation may still return after
+			// being superseded; its results are stale.
+			err = ctx.Err()
+		}
+		if err != nil {
+			// Roll back the photo records and storage files so the area
+			// reverts to the upload zone rather than being stuck in the
+			// analysing state.
+			s.discardPhotos(cleanupCtx, areaID, photos)
+			s.emitWebhook(cleanupCtx, WebhookPayload{
+				Event: EventAnalysisFailed, Area: &WebhookArea{ID: areaID, Name: area.Name}, Error: err.Error(),
+			})
+			return nil, nil, fmt.Errorf("failed to analyze image: %w", err)
+		}
+		s.log(ctx).Info("vision analysis complete", "area_id", areaID, "photo_index", i, "status", result.Status, "items_detected", len(result.Items))
+		s.recordRawResponse(cleanupCtx, areaID, photo.ID, result)
+		s.recordUsage(cleanupCtx, photo, result.Usage)
+		s.recordStopReason(cleanupCtx, photo, result)
+		found += len(result.Items)
+		items, n := s.filterIgnored(ctx, areaID, s.clampDetected(result.Items))
+		ignored += n
+		detected = append(detected, photoDetections{photoID: photo.ID, items: items})
+		if result.Status != vision.StatusOK && result.Status != "" {
+			s.log(ctx).Info("vision analysis non-ok result", "area_id", areaID, "status", result.Status)
+		}
+	}
+
+	// The previous items describe the change and hold the thumbnails that
+	// can be released once they are replaced.
+	existing, err := s.itemStore.ListByAreaID(ctx, areaID)
+	if err != nil {
+		s.log(ctx).Error("failed to list items before replacing them", "area_id", areaID, "error", err)
+	}
+	if found == 0 && s.keepOnEmpty && !opts.ReplaceEmpty && len(replacedItems(existing, opts.ReplaceEdited)) > 0 {
+		return s.keepItems(cleanupCtx, photos, existing)
+	}
+	rctx, span := tracing.Start(ctx, "db.items.replace", tracing.KindInternal)
+	items, err := s.replaceItems(rctx, areaID, detected, opts.ReplaceEdited)
+	span.SetInt("items", int64(len(items)))
+	span.SetError(err)
+	span.End()
+	if err != nil {
+		for _, photo := range photos {
+			s.setAnalysisStatus(cleanupCtx, photo, domain.PhotoAnalysisFailed, "Saving the detected items failed. Upload the photo again.")
+		}
+		s.emitWebhook(cleanupCtx, WebhookPayload{
+			Event: EventAnalysisFailed, Area: &WebhookArea{ID: areaID, Name: area.Name}, PhotoID: latest.ID, Error: err.Error(),
+		})
+		return photos, nil, err
+	}
+	for i, photo := range photos {
+		s.cropItems(ctx, photo, uploads[i].Data, items)
+	}
+	s.releaseCrops(cleanupCtx, existing)
+	if replaced := replacedItems(existing, opts.ReplaceEdited); len(replaced) > 0 {
+		s.audit(cleanupCtx, audit.ActionItemsReplace, audit.EntityPhoto, latest.ID, areaID, map[string]any{
+			"removed": itemNames(replaced), "added": len(items),
+		})
+	}
+	for _, photo := range photos {
+		s.setAnalysisStatus(cleanupCtx, photo, domain.PhotoAnalysisComplete, "")
+	}
+	s.touchArea(cleanupCtx, areaID)
+	s.recordHistory(cleanupCtx, areaID, items)
+	s.checkStaples(cleanupCtx)
+	added, removed := diffItemsByName(existing, items)
+	s.emitWebhook(cleanupCtx, WebhookPayload{
+		Event: EventAnalysisCompleted, Area: &WebhookArea{ID: areaID, Name: area.Name}, PhotoID: latest.ID,
+		ItemsAdded: webhookItems(added), ItemsRemoved: webhookItems(removed),
+	})
+
+	s.log(ctx).Info("upload photo complete", "area_id", areaID, "photos", len(photos), "items_stored", len(items), "items_ignored", ignored)
+	return photos, items, nil
+}
+
+// keepItems finishes an upload whose analysis detected nothing, leaving the
+// area's items in place. The photos are kept, and the latest is flagged so
+// the user can be asked whether the list should be emptied.
+func (s *AreaService) keepItems(ctx context.Context, photos []*domain.Photo, existing []*domain.Item) ([]*domain.Photo, []*domain.Item, error) {
+	latest := photos[len(photos)-1]
+	if err := s.photoStore.SetItemsKept(ctx, latest.ID, true); err != nil {
+		s.log(ctx).Error("failed to flag photo items kept", "photo_id", latest.ID, "error", err)
+	} else {
+		latest.ItemsKept = true
+	}
+	for _, photo := range photos {
+		s.setAnalysisStatus(ctx, photo, domain.PhotoAnalysisComplete, "")
+	}
+	s.touchArea(ctx, latest.AreaID)
+	s.log(ctx).Warn("vision analysis detected no items, keeping previous items", "area_id", latest.AreaID, "photo_id", latest.ID, "items_kept", len(existing))
+	return photos, existing, nil
+}
+
+// ResolveKeptItems settles an upload that kept the area's items because its
+// analysis detected none. With replace, the items the upload would have
+// replaced are removed, as though it had; otherwise they stay. Either way
+// the photo is no longer flagged. It returns ErrAreaNotFound for a missing
+// area and ErrNoKeptItems when the latest photo isn't flagged.
+func (s *AreaService) ResolveKeptItems(ctx context.Context, areaID int64, replace bool) ([]*domain.Item, error) {
+	area, err := s.areaStore.GetByID(ctx, areaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get area: %w", err)
+	}
+	if area == nil {
+		return nil, ErrAreaNotFound
+	}
+	// An upload running now would replace the items itself.
+	ctx, finish, err := s.beginAnalysis(ctx, areaID)
+	if err != nil {
+		return nil, err
+	}
+	defer finish()
+
+	photo, err := s.photoStore.GetLatestByAreaID(ctx, areaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest photo: %w", err)
+	}
+	if photo == nil || !photo.ItemsKept {
+		return nil, ErrNoKeptItems
+	}
+	existing, err := s.itemStore.ListByAreaID(ctx, areaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
 	}
+	items := existing
+	if replace {
+		items, err = s.replaceItems(ctx, areaID, []photoDetections{{photoID: photo.ID}}, false)
+		if err != nil {
+			return nil, err
+		}
+		s.releaseCrops(ctx, existing)
+		if replaced := replacedItems(existing, false); len(replaced) > 0 {
+			s.audit(ctx, audit.ActionItemsReplace, audit.EntityPhoto, photo.ID, areaID, map[string]any{
+				"removed": itemNames(replaced), "added": 0,
+			})
+		}
+		s.touchArea(ctx, areaID)
+		s.recordHistory(ctx, areaID, items)
+		s.checkStaples(ctx)
+		added, removed := diffItemsByName(existing, items)
+		s.emitWebhook(ctx, WebhookPayload{
+			Event: EventAnalysisCompleted, Area: &WebhookArea{ID: areaID, Name: area.Name}, PhotoID: photo.ID,
+			ItemsAdded: webhookItems(added), ItemsRemoved: webhookItems(removed),
+		})
+	}
+	if err := s.photoStore.SetItemsKept(ctx, photo.ID, false); err != nil {
+		return nil, err
+	}
+	s.log(ctx).Info("kept items resolved", "area_id", areaID, "photo_id", photo.ID, "replaced", replace, "items", len(items))
+	return items, nil
+}
+
+// discardPhotos deletes the records of photos whose upload failed, and their
+// files once nothing else refers to them. Failures are logged.
+func (s *AreaService) discardPhotos(ctx context.Context, areaID int64, photos []*domain.Photo) {
+	for _, photo := range photos {
+		if err := s.photoStore.Delete(ctx, photo.ID); err != nil {
+			s.log(ctx).Error("failed to delete photo record after analysis failure", "area_id", areaID, "error", err)
+		}
+		s.releasePhotoFile(ctx, photo.StorageKey)
+	}
+}
 
-	// Save photo and commit the DB record before calling the vision API so
-	// that a client disconnect/refresh sees Photo&&!Items and polls for results.
-	storageKey, err := s.photoStg.Save(ctx, fmt.Sprintf("area_%d", areaID), mimeType, bytes.NewReader(imageData))
+// contentHash returns the hex SHA-256 of an image.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// unchangedPhoto returns the area's latest photo and its current items when
+// that photo has the given content hash and was analysed successfully, or
+// nil if the upload needs analysing.
+func (s *AreaService) unchangedPhoto(ctx context.Context, areaID int64, hash string) (*domain.Photo, []*domain.Item, error) {
+	latest, err := s.photoStore.GetLatestByAreaID(ctx, areaID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get latest photo: %w", err)
+	}
+	// A photo that kept the previous items is worth another try.
+	if latest == nil || latest.ContentHash != hash || latest.AnalysisStatus != domain.PhotoAnalysisComplete || latest.ItemsKept {
+		return nil, nil, nil
+	}
+	items, err := s.itemStore.ListByAreaID(ctx, areaID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to save photo: %w", err)
+		return nil, nil, fmt.Errorf("failed to list items: %w", err)
 	}
-	s.logger.Debug("photo saved", "area_id", areaID, "storage_key", storageKey)
+	return latest, items, nil
+}
 
-	// Serialise the write sequence per area: create photo record, delete old
-	// items, insert new items. This prevents concurrent uploads to the same
-	// area from interleaving their delete+insert sequences and corrupting data.
-	unlock := s.lockForArea(areaID)
-	defer unlock()
+// storePhoto saves the image file and records it against areaID. Files are
+// shared by content, so saving and recording happen under photoFilesMu: a
+// concurrent release can't remove the file between the two steps.
+func (s *AreaService) storePhoto(ctx context.Context, areaID int64, mimeType string, data []byte, hash string) (*domain.Photo, error) {
+	s.photoFilesMu.Lock()
+	defer s.photoFilesMu.Unlock()
 
-	photo, err := s.photoStore.Create(ctx, areaID, storageKey, mimeType)
+	sctx, span := tracing.Start(ctx, "photo.save", tracing.KindInternal)
+	span.SetInt("bytes", int64(len(data)))
+	storageKey, err := s.photoStg.Save(sctx, mimeType, bytes.NewReader(data))
+	span.SetError(err)
+	span.End()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save photo: %w", err)
+	}
+	cctx, span := tracing.Start(ctx, "db.photo.create", tracing.KindInternal)
+	photo, err := s.photoStore.Create(cctx, areaID, storageKey, mimeType, hash)
+	span.SetError(err)
+	span.End()
 	if err != nil {
-		_ = s.photoStg.Delete(ctx, storageKey)
-		return nil, nil, fmt.Errorf("failed to create photo record: %w", err)
+		s.deletePhotoFileIfUnused(context.WithoutCancel(ctx), storageKey)
+		return nil, fmt.Errorf("failed to create photo record: %w", err)
 	}
+	return photo, nil
+}
+
+// releasePhotoFile removes the file behind storageKey once no photo record
+// refers to it any more. Call it after deleting the record.
+func (s *AreaService) releasePhotoFile(ctx context.Context, storageKey string) {
+	s.photoFilesMu.Lock()
+	defer s.photoFilesMu.Unlock()
+	s.deletePhotoFileIfUnused(ctx, storageKey)
+}
 
-	s.logger.Info("vision analysis started", "area_id", areaID)
-	result, err := s.visionAPI.Analyze(ctx, bytes.NewReader(imageData), mimeType)
+// deletePhotoFileIfUnused is releasePhotoFile without the lock. Failures are
+// logged: the record is already gone, so at worst the file is orphaned.
+func (s *AreaService) deletePhotoFileIfUnused(ctx context.Context, storageKey string) {
+	n, err := s.photoStore.CountByStorageKey(ctx, storageKey)
 	if err != nil {
-		// Roll back the photo record and storage file so the area reverts to
-		// the upload zone rather than being stuck in the analysing state.
-		if delErr := s.photoStore.Delete(ctx, photo.ID); delErr != nil {
-			s.logger.Error("failed to delete photo record after analysis failure", "area_id", areaID, "error", delErr)
+		s.log(ctx).Error("failed to count photo references", "storage_key", storageKey, "error", err)
+		return
+	}
+	if n > 0 {
+		s.log(ctx).Debug("photo file still referenced", "storage_key", storageKey, "references", n)
+		return
+	}
+	if err := s.photoStg.Delete(ctx, storageKey); err != nil {
+		s.log(ctx).Error("failed to delete photo file", "storage_key", storageKey, "error", err)
+	}
+}
+
+// analyze calls the vision backend, converting a panic into an error so the
+// caller's cleanup runs and the photo isn't left in the analysing state. A
+// non-blank prompt is passed to backends that support one; otherwise the
+// backend's default prompt is used. The call is cancelled after the analysis
+// timeout; upload contexts are detached from the client, so nothing else
+// would stop a hung backend.
+func (s *AreaService) analyze(ctx context.Context, imageData []byte, mimeType, prompt string) (result *vision.AnalysisResult, err error) {
+	ctx, span := tracing.Start(ctx, "vision.analyze", tracing.KindInternal)
+	defer func() {
+		if result != nil {
+			span.SetInt("items", int64(len(result.Items)))
+		}
+		span.SetError(err)
+		span.End()
+	}()
+	if s.analysisTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.analysisTimeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("vision analyzer panicked: %v", r)
 		}
-		_ = s.photoStg.Delete(ctx, storageKey)
-		return nil, nil, fmt.Errorf("failed to analyze image: %w", err)
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%w after %s: %w", ErrAnalysisTimeout, s.analysisTimeout, err)
+		}
+	}()
+	if pa, ok := s.visionAPI.(vision.PromptAnalyzer); ok && strings.TrimSpace(prompt) != "" {
+		return pa.AnalyzeWithPrompt(ctx, bytes.NewReader(imageData), mimeType, prompt)
+	}
+	return s.visionAPI.Analyze(ctx, bytes.NewReader(imageData), mimeType)
+}
+
+// maxRawResponseLen caps how much of a vision reply is kept per photo.
+const maxRawResponseLen = 64 << 10
+
+// recordRawResponse keeps the backend's unparsed reply on the photo so an
+// unexpected result can be inspected later. A reply that parsed to no items
+// is also logged at debug level. Failures are logged, not returned.
+func (s *AreaService) recordRawResponse(ctx context.Context, areaID, photoID int64, result *vision.AnalysisResult) {
+	raw := result.RawResponse
+	if len(result.Items) == 0 && strings.TrimSpace(raw) != "" {
+		s.log(ctx).Debug("vision response parsed to no items", "area_id", areaID, "photo_id", photoID, "raw_response", raw)
 	}
-	s.logger.Info("vision analysis complete", "area_id", areaID, "status", result.Status, "items_detected", len(result.Items))
-	if result.Status != vision.StatusOK && result.Status != "" {
-		s.logger.Info("vision analysis non-ok result", "area_id", areaID, "status", result.Status)
+	if len(raw) > maxRawResponseLen {
+		raw = strings.ToValidUTF8(raw[:maxRawResponseLen], "")
 	}
+	if err := s.photoStore.SetRawResponse(ctx, photoID, raw); err != nil {
+		s.log(ctx).Error("failed to record raw vision response", "photo_id", photoID, "error", err)
+	}
+}
 
-	items, err := s.replaceItems(ctx, areaID, photo.ID, result.Items)
+// recordStopReason stores why the analysis stopped on the photo, and on the
+// in-memory copy so the caller sees it. A truncated reply is logged as a
+// warning. Failures are logged, not returned.
+func (s *AreaService) recordStopReason(ctx context.Context, photo *domain.Photo, result *vision.AnalysisResult) {
+	if result.StopReason == "" && !result.Truncated {
+		return
+	}
+	photo.StopReason, photo.Truncated = result.StopReason, result.Truncated
+	if result.Truncated {
+		s.log(ctx).Warn("vision response truncated, item list may be incomplete", "area_id", photo.AreaID, "photo_id", photo.ID,
+			"stop_reason", result.StopReason, "items_detected", len(result.Items))
+	}
+	if err := s.photoStore.SetStopReason(ctx, photo.ID, result.StopReason, result.Truncated); err != nil {
+		s.log(ctx).Error("failed to record analysis stop reason", "photo_id", photo.ID, "error", err)
+	}
+}
+
+// LatestRawResponse returns the stored vision reply for the area's latest
+// photo. It returns ErrAreaNotFound for a missing area and ErrNoPhoto when
+// the area has no photo.
+func (s *AreaService) LatestRawResponse(ctx context.Context, areaID int64) (string, error) {
+	area, err := s.areaStore.GetByID(ctx, areaID)
 	if err != nil {
-		return photo, nil, err
+		return "", fmt.Errorf("failed to get area: %w", err)
 	}
+	if area == nil {
+		return "", ErrAreaNotFound
+	}
+	photo, err := s.photoStore.GetLatestByAreaID(ctx, areaID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get latest photo: %w", err)
+	}
+	if photo == nil {
+		return "", ErrNoPhoto
+	}
+	return s.photoStore.GetRawResponse(ctx, photo.ID)
+}
 
-	s.logger.Info("upload photo complete", "area_id", areaID, "items_stored", len(items))
-	return photo, items, nil
+// setAnalysisStatus persists the analysis outcome and mirrors it onto photo.
+// A failure here is logged rather than returned: the items are already saved.
+func (s *AreaService) setAnalysisStatus(ctx context.Context, photo *domain.Photo, status domain.PhotoAnalysisStatus, errMsg string) {
+	if err := s.photoStore.SetAnalysisStatus(ctx, photo.ID, status, errMsg); err != nil {
+		s.log(ctx).Error("failed to record analysis status", "photo_id", photo.ID, "status", status, "error", err)
+		return
+	}
+	photo.AnalysisStatus = status
+	photo.AnalysisError = errMsg
 }
 
-// replaceItems atomically deletes all existing items for an area and inserts the
-// newly detected ones. If a *sql.DB is available it uses a transaction; otherwise
-// it falls back to non-transactional execution (test environments without WithDB).
-func (s *AreaService) replaceItems(ctx context.Context, areaID, photoID int64, detected []vision.DetectedItem) ([]*domain.Item, error) {
+// replaceItems atomically swaps an area's machine-generated items for the
+// items newly detected in the upload's photos. Items the user created or corrected are kept, and
+// detections matching one of them by name are dropped; replaceEdited replaces
+// everything instead. If a *sql.DB is available it uses a transaction;
+// otherwise it falls back to non-transactional execution (test environments
+// without WithDB).
+func (s *AreaService) replaceItems(ctx context.Context, areaID int64, detected []photoDetections, replaceEdited bool) ([]*domain.Item, error) {
 	if s.db != nil {
-		return s.replaceItemsTx(ctx, areaID, photoID, detected)
+		return s.replaceItemsTx(ctx, areaID, detected, replaceEdited)
 	}
 	// Fallback (tests without a DB reference): non-transactional but still
 	// protected by the per-area lock acquired in UploadPhoto.
-	if err := s.itemStore.DeleteByAreaID(ctx, areaID); err != nil {
+	existing, err := s.itemStore.ListByAreaID(ctx, areaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list existing items: %w", err)
+	}
+	kept := keptItems(existing, replaceEdited)
+	if replaceEdited {
+		err = s.itemStore.DeleteByAreaID(ctx, areaID)
+	} else {
+		err = s.itemStore.DeleteUneditedByAreaID(ctx, areaID)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to delete old items: %w", err)
 	}
-	merged := mergeDetectedItems(detected)
+	merged := mergePhotoDetections(detected)
 	merged = s.applyOverridesToMerged(ctx, areaID, merged)
-	items := make([]*domain.Item, 0, len(merged))
-	for _, m := range merged {
-		item, err := s.itemStore.Create(ctx, areaID, &photoID, m.name, m.quantity, string(domain.ItemSourceAI), m.bboxes)
+	merged = withoutKeptNames(merged, kept)
+	items := make([]*domain.Item, 0, len(kept)+len(merged))
+	items = append(items, kept...)
+	photoIDs, batches := detectedByPhoto(merged)
+	for i, photoID := range photoIDs {
+		created, err := s.itemStore.CreateBatch(ctx, areaID, photoID, batches[i])
 		if err != nil {
-			s.logger.Error("failed to create item", "name", m.name, "error", err)
-			continue
+			return nil, fmt.Errorf("failed to create items: %w", err)
 		}
-		items = append(items, item)
+		items = append(items, created...)
 	}
 	return items, nil
 }
 
-func (s *AreaService) replaceItemsTx(ctx context.Context, areaID, photoID int64, detected []vision.DetectedItem) ([]*domain.Item, error) {
+func (s *AreaService) replaceItemsTx(ctx context.Context, areaID int64, detected []photoDetections, replaceEdited bool) ([]*domain.Item, error) {
+	// Overrides are read before the transaction: it holds the write lock
+	// and a connection from the pool, so everything inside it must go
+	// through tx, or it waits on itself.
+	merged := mergePhotoDetections(detected)
+	merged = s.applyOverridesToMerged(ctx, areaID, merged)
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	// Snapshot the existing inventory before replacing it.
-	existing, err := s.itemStore.ListByAreaID(ctx, areaID)
+	// Snapshot the existing inventory before replacing it, against the
+	// upload's latest photo so the change can be shown later.
+	existing, err := s.itemStore.ListByAreaIDTx(ctx, tx, areaID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list existing items: %w", err)
 	}
@@ -302,42 +1102,33 @@ func (s *AreaService) replaceItemsTx(ctx context.Context, areaID, photoID int64,
 		for i, it := range existing {
 			snapItems[i] = domain.SnapshotItem{Name: it.Name, Quantity: it.Quantity}
 		}
-		if _, err := s.snapshotStore.Create(ctx, areaID, snapItems); err != nil {
-			s.logger.Error("failed to create inventory snapshot", "area_id", areaID, "error", err)
+		photoID := detected[len(detected)-1].photoID
+		if _, err := s.snapshotStore.CreateTx(ctx, tx, areaID, &photoID, snapItems); err != nil {
+			s.log(ctx).Error("failed to create inventory snapshot", "area_id", areaID, "error", err)
 			// Non-fatal: continue with the replacement even if snapshotting fails.
 		}
 	}
 
-	if _, err := tx.ExecContext(ctx, `DELETE FROM items WHERE area_id = ?`, areaID); err != nil {
+	kept := keptItems(existing, replaceEdited)
+	// Only active items are replaced; consumed and discarded ones are history.
+	deleteSQL := `DELETE FROM items WHERE area_id = ? AND edited = 0 AND status = 'active'`
+	if replaceEdited {
+		deleteSQL = `DELETE FROM items WHERE area_id = ? AND status = 'active'`
+	}
+	if _, err := tx.ExecContext(ctx, deleteSQL, areaID); err != nil {
 		return nil, fmt.Errorf("failed to delete old items: %w", err)
 	}
 
-	merged := mergeDetectedItems(detected)
-	merged = s.applyOverridesToMerged(ctx, areaID, merged)
-	items := make([]*domain.Item, 0, len(merged))
-	for _, m := range merged {
-		bboxesJSON := encodeBBoxesJSON(m.bboxes)
-		result, err := tx.ExecContext(ctx,
-			`INSERT INTO items (area_id, photo_id, name, quantity, source, bboxes) VALUES (?, ?, ?, ?, ?, ?)`,
-			areaID, photoID, m.name, m.quantity, string(domain.ItemSourceAI), bboxesJSON)
+	merged = withoutKeptNames(merged, kept)
+	items := make([]*domain.Item, 0, len(kept)+len(merged))
+	items = append(items, kept...)
+	photoIDs, batches := detectedByPhoto(merged)
+	for i, photoID := range photoIDs {
+		created, err := s.itemStore.CreateBatchTx(ctx, tx, areaID, photoID, batches[i])
 		if err != nil {
-			s.logger.Error("failed to create item", "name", m.name, "error", err)
-			continue
+			return nil, fmt.Errorf("failed to create items: %w", err)
 		}
-		id, err := result.LastInsertId()
-		if err != nil {
-			s.logger.Error("failed to get item id", "name", m.name, "error", err)
-			continue
-		}
-		items = append(items, &domain.Item{
-			ID:       id,
-			AreaID:   areaID,
-			PhotoID:  &photoID,
-			Name:     m.name,
-			Quantity: m.quantity,
-			Source:   domain.ItemSourceAI,
-			BBoxes:   m.bboxes,
-		})
+		items = append(items, created...)
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -346,14 +1137,13 @@ func (s *AreaService) replaceItemsTx(ctx context.Context, areaID, photoID int64,
 	return items, nil
 }
 
-
 func (s *AreaService) GetAreaWithItems(ctx context.Context, areaID int64) (*domain.Area, []*domain.Item, *domain.Photo, error) {
 	area, err := s.areaStore.GetByID(ctx, areaID)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("failed to get area: %w", err)
 	}
 	if area == nil {
-		return nil, nil, nil, fmt.Errorf("area not found")
+		return nil, nil, nil, ErrAreaNotFound
 	}
 
 	items, err := s.itemStore.ListByAreaID(ctx, areaID)
@@ -376,10 +1166,30 @@ func (s *AreaService) UpdateArea(ctx context.Context, areaID int64, name string)
 		}
 		return nil, fmt.Errorf("failed to update area: %w", err)
 	}
+	s.publishArea(ctx, areaID)
+	return s.areaStore.GetByID(ctx, areaID)
+}
+
+// SetAreaPrompt sets the custom analysis prompt used for the area's future
+// uploads. A blank prompt clears it, restoring the backend's default. It
+// returns ErrAreaNotFound for a missing area.
+func (s *AreaService) SetAreaPrompt(ctx context.Context, areaID int64, prompt string) (*domain.Area, error) {
+	if err := s.areaStore.UpdatePrompt(ctx, areaID, strings.TrimSpace(prompt)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAreaNotFound
+		}
+		return nil, fmt.Errorf("failed to update area prompt: %w", err)
+	}
 	return s.areaStore.GetByID(ctx, areaID)
 }
 
 func (s *AreaService) DeletePhoto(ctx context.Context, areaID int64) error {
+	// DeleteByArea removes every photo record for the area; list them first
+	// so each file can be released, not just the latest one.
+	photos, err := s.photoStore.ListByAreaID(ctx, areaID)
+	if err != nil {
+		return fmt.Errorf("failed to list photos: %w", err)
+	}
 	photo, err := s.photoStore.DeleteByArea(ctx, areaID)
 	if err != nil {
 		return fmt.Errorf("failed to delete photo record: %w", err)
@@ -388,23 +1198,59 @@ func (s *AreaService) DeletePhoto(ctx context.Context, areaID int64) error {
 		return nil
 	}
 
+	removed, err := s.itemStore.ListByAreaID(ctx, areaID)
+	if err != nil {
+		s.log(ctx).Error("failed to list items before deleting them", "area_id", areaID, "error", err)
+	}
 	if err := s.itemStore.DeleteByAreaID(ctx, areaID); err != nil {
 		return fmt.Errorf("failed to delete items: %w", err)
 	}
+	s.releaseCrops(ctx, removed)
+	s.touchArea(ctx, areaID)
+	s.audit(ctx, audit.ActionPhotoDelete, audit.EntityPhoto, photo.ID, areaID, map[string]any{
+		"photos": len(photos), "removed": itemNames(removed),
+	})
 
-	if err := s.photoStg.Delete(ctx, photo.StorageKey); err != nil {
-		s.logger.Error("failed to delete photo file", "storage_key", photo.StorageKey, "error", err)
+	released := make(map[string]bool, len(photos))
+	for _, p := range photos {
+		if !released[p.StorageKey] {
+			released[p.StorageKey] = true
+			s.releasePhotoFile(ctx, p.StorageKey)
+		}
 	}
-
 	return nil
 }
 
 func (s *AreaService) CreateItem(ctx context.Context, areaID int64, name, quantity string) (*domain.Item, error) {
-	return s.itemStore.Create(ctx, areaID, nil, name, quantity, string(domain.ItemSourceUser), nil)
+	item, err := s.itemStore.Create(ctx, areaID, nil, name, quantity, string(domain.ItemSourceUser), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.touchArea(ctx, areaID)
+	return item, nil
 }
 
+// GetItem returns an item of an area, or nil if the area has no such item.
+func (s *AreaService) GetItem(ctx context.Context, areaID, itemID int64) (*domain.Item, error) {
+	item, err := s.itemStore.GetByID(ctx, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+	if item == nil || item.AreaID != areaID {
+		return nil, nil
+	}
+	return item, nil
+}
 
 func (s *AreaService) UpdateItem(ctx context.Context, itemID int64, name, quantity string) (*domain.Item, error) {
+	return s.UpdateItemIfCurrent(ctx, itemID, 0, name, quantity)
+}
+
+// UpdateItemIfCurrent is UpdateItem guarded by the version the caller last
+// saw. If the item has been edited since, nothing is written and it returns
+// the item as it now stands with an error wrapping domain.ErrItemConflict. A
+// zero version skips the check.
+func (s *AreaService) UpdateItemIfCurrent(ctx context.Context, itemID, version int64, name, quantity string) (*domain.Item, error) {
 	old, err := s.itemStore.GetByID(ctx, itemID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get item: %w", err)
@@ -413,18 +1259,34 @@ func (s *AreaService) UpdateItem(ctx context.Context, itemID int64, name, quanti
 		return nil, fmt.Errorf("item not found")
 	}
 
-	if err := s.itemStore.Update(ctx, itemID, name, quantity); err != nil {
+	if err := s.itemStore.Update(ctx, itemID, version, name, quantity); err != nil {
+		if errors.Is(err, domain.ErrItemConflict) {
+			current, getErr := s.itemStore.GetByID(ctx, itemID)
+			if getErr != nil {
+				return nil, fmt.Errorf("failed to get item: %w", getErr)
+			}
+			return current, fmt.Errorf("failed to update item: %w", err)
+		}
 		return nil, fmt.Errorf("failed to update item: %w", err)
 	}
+	s.recordItemEdit(ctx, old, name, quantity)
+	s.touchArea(ctx, old.AreaID)
+
+	return s.itemStore.GetByID(ctx, itemID)
+}
 
+// recordItemEdit writes the edit history for an item that changed from old to
+// name/quantity, and auto-creates an area-scoped override rule when the name
+// changed. Failures are logged; the edit itself has already been saved.
+func (s *AreaService) recordItemEdit(ctx context.Context, old *domain.Item, name, quantity string) {
 	// Record a diff entry for each changed field.
 	for _, change := range []struct{ field, oldVal, newVal string }{
 		{"name", old.Name, name},
 		{"quantity", old.Quantity, quantity},
 	} {
 		if change.oldVal != change.newVal {
-			if _, err := s.itemEditStore.Create(ctx, itemID, change.field, change.oldVal, change.newVal); err != nil {
-				s.logger.Error("failed to record item edit", "item_id", itemID, "field", change.field, "error", err)
+			if _, err := s.itemEditStore.Create(ctx, old.ID, change.field, change.oldVal, change.newVal); err != nil {
+				s.log(ctx).Error("failed to record item edit", "item_id", old.ID, "field", change.field, "error", err)
 			}
 		}
 	}
@@ -432,15 +1294,128 @@ func (s *AreaService) UpdateItem(ctx context.Context, itemID int64, name, quanti
 	// Auto-create an area-scoped override rule when the name changes.
 	if old.Name != name && s.overrideStore != nil {
 		if err := s.overrideStore.CreateFromEdit(ctx, old.AreaID, old.Name, name); err != nil {
-			s.logger.Error("failed to auto-create override rule from edit", "item_id", itemID, "error", err)
+			s.log(ctx).Error("failed to auto-create override rule from edit", "item_id", old.ID, "error", err)
 		}
 	}
+}
 
-	return s.itemStore.GetByID(ctx, itemID)
+// BulkEditItems applies a batch of item creates, updates and deletes to an
+// area atomically and returns the area's refreshed item list. Every entry is
+// validated before anything is written; if any entry is invalid or refers to
+// an item outside the area, nothing is applied and a *BulkItemError lists the
+// rejected entries.
+func (s *AreaService) BulkEditItems(ctx context.Context, areaID int64, ops []domain.ItemOp) ([]*domain.Item, error) {
+	area, err := s.areaStore.GetByID(ctx, areaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get area: %w", err)
+	}
+	if area == nil {
+		return nil, ErrAreaNotFound
+	}
+
+	ops = slices.Clone(ops)
+	var failures []domain.ItemOpFailure
+	for i := range ops {
+		op := &ops[i]
+		op.Name = domain.CleanItemText(op.Name)
+		op.Quantity = domain.CleanItemText(op.Quantity)
+		if msg := validateItemOp(*op, s.itemLimits); msg != "" {
+			failures = append(failures, domain.ItemOpFailure{Index: i, Error: msg})
+		}
+	}
+	if len(failures) > 0 {
+		return nil, &BulkItemError{Failures: failures}
+	}
+
+	// Capture the pre-edit state so edit history can be recorded once the
+	// batch commits.
+	existing, err := s.itemStore.ListByAreaID(ctx, areaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list existing items: %w", err)
+	}
+	before := make(map[int64]*domain.Item, len(existing))
+	for _, it := range existing {
+		before[it.ID] = it
+	}
+
+	failures, err = s.itemStore.ApplyOps(ctx, areaID, ops)
+	if err != nil {
+		return nil, err
+	}
+	if len(failures) > 0 {
+		return nil, &BulkItemError{Failures: failures}
+	}
+
+	for _, op := range ops {
+		if op.Op != domain.ItemOpUpdate {
+			continue
+		}
+		if old, ok := before[op.ID]; ok {
+			s.recordItemEdit(ctx, old, op.Name, op.Quantity)
+			// A later entry may update the same item again; diff against this one.
+			before[op.ID] = &domain.Item{ID: old.ID, AreaID: old.AreaID, Name: op.Name, Quantity: op.Quantity}
+		}
+	}
+
+	s.releaseCrops(ctx, existing)
+	s.touchArea(ctx, areaID)
+	s.auditBulkEdit(ctx, areaID, ops, before)
+
+	s.log(ctx).Info("bulk item edit applied", "area_id", areaID, "ops", len(ops))
+	return s.itemStore.ListByAreaID(ctx, areaID)
+}
+
+// validateItemOp returns a user-facing reason op is malformed, or "" if it is
+// well-formed. Whether the referenced item exists is checked by the store.
+func validateItemOp(op domain.ItemOp, limits domain.ItemLimits) string {
+	switch op.Op {
+	case domain.ItemOpCreate:
+		if errs := limits.Check(op.Name, op.Quantity); len(errs) > 0 {
+			return errs[0].Message
+		}
+	case domain.ItemOpUpdate:
+		if op.ID <= 0 {
+			return "item id required"
+		}
+		if errs := limits.Check(op.Name, op.Quantity); len(errs) > 0 {
+			return errs[0].Message
+		}
+	case domain.ItemOpDelete:
+		if op.ID <= 0 {
+			return "item id required"
+		}
+	default:
+		return fmt.Sprintf("unknown op %q", op.Op)
+	}
+	return ""
 }
 
 func (s *AreaService) DeleteItem(ctx context.Context, itemID int64) error {
-	return s.itemStore.Delete(ctx, itemID)
+	item, err := s.itemStore.GetByID(ctx, itemID)
+	if err != nil {
+		return fmt.Errorf("failed to get item: %w", err)
+	}
+	if err := s.itemStore.Delete(ctx, itemID); err != nil {
+		return err
+	}
+	if item != nil {
+		s.releaseCrops(ctx, []*domain.Item{item})
+		s.touchArea(ctx, item.AreaID)
+		s.audit(ctx, audit.ActionItemDelete, audit.EntityItem, itemID, item.AreaID, map[string]any{
+			"name": item.Name, "quantity": item.Quantity,
+		})
+	}
+	return nil
+}
+
+// touchArea records that an area's inventory changed and publishes the new
+// inventory. A failure is logged rather than returned: the change itself has
+// already been saved.
+func (s *AreaService) touchArea(ctx context.Context, areaID int64) {
+	if err := s.areaStore.Touch(ctx, areaID); err != nil {
+		s.log(ctx).Error("failed to touch area", "area_id", areaID, "error", err)
+	}
+	s.publishArea(ctx, areaID)
 }
 
 func (s *AreaService) ReorderAreas(ctx context.Context, ids []int64) error {
@@ -451,6 +1426,43 @@ func (s *AreaService) SearchItems(ctx context.Context, query string) ([]*domain.
 	return s.itemStore.Search(ctx, query)
 }
 
+// maxNameSuggestions is how many names SuggestItemNames offers.
+const maxNameSuggestions = 5
+
+// SuggestItemNames returns the names to offer while query is being typed
+// into search: up to five distinct in-stock names containing it.
+func (s *AreaService) SuggestItemNames(ctx context.Context, query string) ([]string, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+	return s.itemStore.SuggestNames(ctx, strings.TrimSpace(query), maxNameSuggestions)
+}
+
+// SearchItemsPage is SearchItems for one page of the matches, narrowed by
+// filter.
+func (s *AreaService) SearchItemsPage(ctx context.Context, query string, filter domain.SearchFilter, page domain.ItemPage) ([]*domain.Item, error) {
+	return s.itemStore.SearchPage(ctx, query, filter, page)
+}
+
+// ListItemsPage returns one page of an area's items. It returns
+// ErrAreaNotFound for an unknown area.
+func (s *AreaService) ListItemsPage(ctx context.Context, areaID int64, page domain.ItemPage) ([]*domain.Item, error) {
+	area, err := s.areaStore.GetByID(ctx, areaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get area: %w", err)
+	}
+	if area == nil {
+		return nil, ErrAreaNotFound
+	}
+	return s.itemStore.ListByAreaIDPage(ctx, areaID, page)
+}
+
+// RecentItems returns items added at or after since across all areas,
+// newest first.
+func (s *AreaService) RecentItems(ctx context.Context, since time.Time, limit, offset int) ([]*domain.RecentItem, error) {
+	return s.itemStore.ListRecent(ctx, since, limit, offset)
+}
+
 func (s *AreaService) ListSnapshots(ctx context.Context, areaID int64) ([]*domain.Snapshot, error) {
 	return s.snapshotStore.ListByAreaID(ctx, areaID)
 }
@@ -463,7 +1475,7 @@ func (s *AreaService) applyOverridesToMerged(ctx context.Context, areaID int64,
 	}
 	rules, err := s.overrideStore.ListForArea(ctx, areaID)
 	if err != nil {
-		s.logger.Error("failed to load override rules", "area_id", areaID, "error", err)
+		s.log(ctx).Error("failed to load override rules", "area_id", areaID, "error", err)
 		return merged // non-fatal: proceed with original names
 	}
 	filtered := merged[:0]
@@ -505,4 +1517,3 @@ func (s *AreaService) DeleteOverrideRule(ctx context.Context, id int64) error {
 func (s *AreaService) ReorderOverrideRules(ctx context.Context, ids []int64) error {
 	return s.overrideStore.ReorderSortOrder(ctx, ids)
 }
-
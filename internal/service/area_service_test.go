@@ -3,18 +3,28 @@ package service
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vbonduro/kitchinv/internal/db"
 	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/logging"
 	"github.com/vbonduro/kitchinv/internal/store"
 	"github.com/vbonduro/kitchinv/internal/vision"
+	"modernc.org/sqlite"
 )
 
 // noopOverrideStore satisfies overrideRepository with no-ops for tests.
@@ -73,12 +83,12 @@ func newStubPhotoStore() *stubPhotoStore {
 	return &stubPhotoStore{saved: make(map[string][]byte)}
 }
 
-func (s *stubPhotoStore) Save(_ context.Context, prefix, _ string, r io.Reader) (string, error) {
+func (s *stubPhotoStore) Save(_ context.Context, _ string, r io.Reader) (string, error) {
 	if s.saveErr != nil {
 		return "", s.saveErr
 	}
 	data, _ := io.ReadAll(r)
-	key := prefix + "/photo.jpg"
+	key := fmt.Sprintf("sha256/%x.jpg", sha256.Sum256(data))
 	s.mu.Lock()
 	s.saved[key] = data
 	s.mu.Unlock()
@@ -163,6 +173,105 @@ func TestAreaServiceDeleteArea(t *testing.T) {
 	assert.Nil(t, retrieved)
 }
 
+func TestAreaServiceRestoreArea(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, area.ID, "Rice", "1")
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteArea(ctx, area.ID))
+
+	restored, err := svc.RestoreArea(ctx, area.ID)
+	require.NoError(t, err)
+	require.NotNil(t, restored)
+	assert.Equal(t, "Pantry", restored.Name)
+
+	_, items, _, err := svc.GetAreaWithItems(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Len(t, items, 1, "items should come back with the area")
+
+	// Restoring a live area is a no-op.
+	again, err := svc.RestoreArea(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Nil(t, again)
+}
+
+func TestAreaServiceRestoreArea_NameTaken(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteArea(ctx, area.ID))
+	_, err = svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+
+	_, err = svc.RestoreArea(ctx, area.ID)
+	assert.ErrorIs(t, err, ErrNameTaken)
+}
+
+func TestAreaServiceRestoreArea_Expired(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	svc.WithAreaRetention(-time.Hour)
+
+	area, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteArea(ctx, area.ID))
+
+	restored, err := svc.RestoreArea(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Nil(t, restored)
+}
+
+func TestAreaServicePurgeDeletedAreas_RemovesPhotoFiles(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	stg := newStubPhotoStore()
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		&stubVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}}}},
+		stg,
+		slog.Default(),
+	).WithDB(d)
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte("img"), "image/jpeg")
+	require.NoError(t, err)
+	require.Len(t, stg.saved, 1)
+
+	require.NoError(t, svc.DeleteArea(ctx, area.ID))
+
+	// Not yet due: nothing purged and the file is kept for undo.
+	n, err := svc.PurgeDeletedAreas(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.Len(t, stg.saved, 1)
+
+	n, err = svc.PurgeDeletedAreas(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Empty(t, stg.saved)
+
+	restored, err := svc.RestoreArea(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Nil(t, restored, "purged area cannot be restored")
+}
+
 func TestAreaServiceUploadPhoto_StoresItemsFromVision(t *testing.T) {
 	d, err := db.OpenForTesting()
 	require.NoError(t, err)
@@ -199,6 +308,26 @@ func TestAreaServiceUploadPhoto_StoresItemsFromVision(t *testing.T) {
 	assert.Equal(t, "Butter", items[1].Name)
 }
 
+func TestAreaServiceUploadPhoto_LogsRequestIDFromContext(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+
+	area, err := svc.CreateArea(context.Background(), "Fridge")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	scoped := slog.New(slog.NewJSONHandler(&buf, nil)).With("request_id", "req-1")
+	ctx := logging.NewContext(context.Background(), scoped)
+
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+	require.NoError(t, err)
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		assert.Contains(t, line, `"request_id":"req-1"`)
+	}
+	assert.Contains(t, buf.String(), "vision analysis started")
+}
+
 func TestAreaServiceUploadPhoto_ReplacesExistingItems(t *testing.T) {
 	d, err := db.OpenForTesting()
 	require.NoError(t, err)
@@ -221,17 +350,194 @@ func TestAreaServiceUploadPhoto_ReplacesExistingItems(t *testing.T) {
 	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
 	require.NoError(t, err)
 
-	// Upload again with different items
+	// Upload a new photo with different items
 	svc.visionAPI = &stubVision{result: &vision.AnalysisResult{
 		Items: []vision.DetectedItem{{Name: "New Item", Quantity: "2", Notes: ""}},
 	}}
-	_, items, err := svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+	_, items, err := svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
 	require.NoError(t, err)
 
 	assert.Len(t, items, 1)
 	assert.Equal(t, "New Item", items[0].Name)
 }
 
+// TestAreaServiceUploadPhoto_ReplacesWithinOneConnection re-uploads against a
+// file database limited to one connection. Replacing the items holds the
+// write lock, so reading them and snapshotting them must go through the same
+// transaction rather than wait on it. The in-memory test database shares its
+// cache between connections and doesn't show this.
+func TestAreaServiceUploadPhoto_ReplacesWithinOneConnection(t *testing.T) {
+	d, err := db.Open(filepath.Join(t.TempDir(), "kitchinv.db"), db.Options{MaxOpenConns: 1, BusyTimeout: time.Second})
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, db.Close(d)) })
+
+	snapshots := store.NewSnapshotStore(d)
+	vis := &stubVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}}}}
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		snapshots,
+		store.NewOverrideStore(d),
+		vis,
+		newStubPhotoStore(),
+		slog.Default(),
+	).WithDB(d)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte("first"), "image/jpeg")
+	require.NoError(t, err)
+
+	vis.result = &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Eggs", Quantity: "6"}}}
+	start := time.Now()
+	var items []*domain.Item
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, items, err = svc.UploadPhoto(ctx, area.ID, []byte("second"), "image/jpeg")
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("re-upload deadlocked waiting for a second connection")
+	}
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second, "the re-upload must not wait out the busy timeout")
+	require.Len(t, items, 1)
+	assert.Equal(t, "Eggs", items[0].Name)
+
+	taken, err := snapshots.ListByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	require.Len(t, taken, 1, "the replaced items should be snapshotted")
+	assert.Equal(t, []domain.SnapshotItem{{Name: "Milk", Quantity: "1"}}, taken[0].Items)
+}
+
+func TestAreaServiceUploadPhoto_NothingDetectedKeepsItems(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	vis := &countingVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}}}}
+	svc.visionAPI = vis
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
+	require.NoError(t, err)
+
+	// A reply that parsed to nothing must not read as an empty fridge.
+	vis.result = &vision.AnalysisResult{Status: vision.StatusOK, RawResponse: "Sorry, I can't tell."}
+	photo, items, err := svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x02}, "image/jpeg")
+	require.NoError(t, err)
+	assert.True(t, photo.ItemsKept)
+	assert.Equal(t, domain.PhotoAnalysisComplete, photo.AnalysisStatus)
+	require.Len(t, items, 1)
+	assert.Equal(t, "Milk", items[0].Name)
+
+	_, items, latest, err := svc.GetAreaWithItems(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Equal(t, photo.ID, latest.ID, "the new photo is kept")
+	assert.True(t, latest.ItemsKept)
+	require.Len(t, items, 1)
+
+	// The same image is analysed again rather than treated as unchanged.
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x02}, "image/jpeg")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), vis.calls.Load())
+
+	// ReplaceEmpty asks for the old behaviour.
+	photo, items, err = svc.UploadPhotoWithOptions(ctx, area.ID, []byte{0xFF, 0xD8, 0x03}, "image/jpeg", UploadOptions{ReplaceEmpty: true})
+	require.NoError(t, err)
+	assert.False(t, photo.ItemsKept)
+	assert.Empty(t, items)
+}
+
+func TestAreaServiceUploadPhoto_NothingDetected(t *testing.T) {
+	tests := []struct {
+		name        string
+		keepOnEmpty bool
+		editItem    bool
+		wantItems   int
+		wantKept    bool
+	}{
+		{name: "keeps replaceable items", keepOnEmpty: true, wantItems: 1, wantKept: true},
+		{name: "disabled replaces them", keepOnEmpty: false, wantItems: 0},
+		{name: "nothing to replace", keepOnEmpty: true, editItem: true, wantItems: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, cleanup := newTestService(t)
+			defer cleanup()
+			svc.WithKeepItemsOnEmpty(tt.keepOnEmpty)
+			ctx := context.Background()
+
+			vis := &stubVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}}}}
+			svc.visionAPI = vis
+			area, err := svc.CreateArea(ctx, "Fridge")
+			require.NoError(t, err)
+			_, items, err := svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
+			require.NoError(t, err)
+			if tt.editItem {
+				_, err := svc.UpdateItem(ctx, items[0].ID, "Milk", "2")
+				require.NoError(t, err)
+			}
+
+			vis.result = &vision.AnalysisResult{Status: vision.StatusNoItems}
+			photo, items, err := svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x02}, "image/jpeg")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantKept, photo.ItemsKept)
+			assert.Len(t, items, tt.wantItems)
+		})
+	}
+}
+
+func TestAreaServiceResolveKeptItems(t *testing.T) {
+	for _, replace := range []bool{false, true} {
+		t.Run(fmt.Sprintf("replace=%t", replace), func(t *testing.T) {
+			svc, cleanup := newTestService(t)
+			defer cleanup()
+			ctx := context.Background()
+
+			vis := &stubVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}}}}
+			svc.visionAPI = vis
+			area, err := svc.CreateArea(ctx, "Fridge")
+			require.NoError(t, err)
+			_, err = svc.ResolveKeptItems(ctx, area.ID, replace)
+			assert.ErrorIs(t, err, ErrNoKeptItems, "no photo yet")
+
+			_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
+			require.NoError(t, err)
+			_, err = svc.CreateItem(ctx, area.ID, "Butter", "1")
+			require.NoError(t, err)
+			vis.result = &vision.AnalysisResult{}
+			_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x02}, "image/jpeg")
+			require.NoError(t, err)
+
+			items, err := svc.ResolveKeptItems(ctx, area.ID, replace)
+			require.NoError(t, err)
+			names := make([]string, len(items))
+			for i, it := range items {
+				names[i] = it.Name
+			}
+			if replace {
+				assert.Equal(t, []string{"Butter"}, names, "the item the user added stays")
+			} else {
+				assert.ElementsMatch(t, []string{"Milk", "Butter"}, names)
+			}
+
+			_, stored, latest, err := svc.GetAreaWithItems(ctx, area.ID)
+			require.NoError(t, err)
+			assert.Len(t, stored, len(items))
+			assert.False(t, latest.ItemsKept)
+			_, err = svc.ResolveKeptItems(ctx, area.ID, replace)
+			assert.ErrorIs(t, err, ErrNoKeptItems, "already resolved")
+		})
+	}
+}
+
 func TestAreaServiceUploadPhoto_AreaNotFound(t *testing.T) {
 	svc, cleanup := newTestService(t)
 	defer cleanup()
@@ -300,6 +606,304 @@ func TestAreaServiceUploadPhoto_VisionError_RollsBackPhoto(t *testing.T) {
 	assert.Empty(t, photoStg.saved, "photo storage file should be deleted after vision failure")
 }
 
+// panicVision is a VisionAnalyzer whose Analyze always panics.
+type panicVision struct{}
+
+func (panicVision) Analyze(_ context.Context, _ io.Reader, _ string) (*vision.AnalysisResult, error) {
+	panic("analyzer exploded")
+}
+
+func TestAreaServiceUploadPhoto_VisionPanic_RollsBackAndReleasesSlot(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		panicVision{},
+		newStubPhotoStore(),
+		slog.Default(),
+	).WithMaxConcurrentAnalyses(1)
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+
+	for range 2 {
+		_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrTooManyAnalyses, "slot must be released after a panic")
+	}
+
+	_, _, photo, err := svc.GetAreaWithItems(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Nil(t, photo)
+}
+
+// hangingVision is a VisionAnalyzer that blocks until its context ends.
+type hangingVision struct{}
+
+func (hangingVision) Analyze(ctx context.Context, _ io.Reader, _ string) (*vision.AnalysisResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestAreaServiceUploadPhoto_AnalysisTimeout(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	photoStg := newStubPhotoStore()
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		hangingVision{},
+		photoStg,
+		slog.Default(),
+	).WithDB(d).WithAnalysisTimeout(20 * time.Millisecond)
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, area.ID, "Milk", "1")
+	require.NoError(t, err)
+
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+	require.ErrorIs(t, err, ErrAnalysisTimeout)
+
+	// The upload is rolled back and the existing items are untouched.
+	_, items, photo, err := svc.GetAreaWithItems(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Nil(t, photo)
+	require.Len(t, items, 1)
+	assert.Equal(t, "Milk", items[0].Name)
+	assert.Empty(t, photoStg.saved)
+
+	// A second upload isn't blocked by the first.
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+	assert.ErrorIs(t, err, ErrAnalysisTimeout)
+}
+
+func TestAreaServiceUploadPhoto_MaxConcurrentAnalyses(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	cv := &chanVision{ch: make(chan *vision.AnalysisResult)}
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		cv,
+		newStubPhotoStore(),
+		slog.Default(),
+	).WithMaxConcurrentAnalyses(1)
+	ctx := context.Background()
+
+	fridge, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	pantry, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := svc.UploadPhoto(ctx, fridge.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+		done <- err
+	}()
+
+	// Wait until the first upload holds the only slot.
+	require.Eventually(t, func() bool { return len(svc.analysisSlots) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	_, _, err = svc.UploadPhoto(ctx, pantry.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+	assert.ErrorIs(t, err, ErrTooManyAnalyses)
+
+	cv.ch <- &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Milk"}}}
+	require.NoError(t, <-done)
+
+	_, _, photo, err := svc.GetAreaWithItems(ctx, fridge.ID)
+	require.NoError(t, err)
+	require.NotNil(t, photo)
+	assert.Equal(t, domain.PhotoAnalysisComplete, photo.AnalysisStatus)
+
+	cv.ch = make(chan *vision.AnalysisResult, 1)
+	cv.ch <- &vision.AnalysisResult{}
+	_, _, err = svc.UploadPhoto(ctx, pantry.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+	assert.NoError(t, err, "slot should be free once the first analysis finished")
+}
+
+func TestAreaServiceUploadPhoto_SameAreaInProgress_Rejected(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	cv := &chanVision{ch: make(chan *vision.AnalysisResult)}
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		cv,
+		newStubPhotoStore(),
+		slog.Default(),
+	)
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+		done <- err
+	}()
+
+	// Wait until the first upload's photo record exists, i.e. it holds the lock.
+	require.Eventually(t, func() bool {
+		_, _, photo, err := svc.GetAreaWithItems(ctx, area.ID)
+		return err == nil && photo != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+	assert.ErrorIs(t, err, ErrAnalysisInProgress)
+
+	cv.ch <- &vision.AnalysisResult{}
+	require.NoError(t, <-done)
+}
+
+// gatedVision blocks its first call until released, like visiontest.Blocking,
+// but ignores cancellation to model a backend that keeps going after the
+// caller has given up. Later calls return next at once.
+type gatedVision struct {
+	ready    chan struct{}
+	release  chan struct{}
+	first    *vision.AnalysisResult
+	next     *vision.AnalysisResult
+	calls    atomic.Int32
+	firstCtx context.Context // set before ready is closed
+}
+
+func (g *gatedVision) Analyze(ctx context.Context, _ io.Reader, _ string) (*vision.AnalysisResult, error) {
+	if g.calls.Add(1) == 1 {
+		g.firstCtx = ctx
+		close(g.ready)
+		<-g.release
+		return g.first, nil
+	}
+	return g.next, nil
+}
+
+func TestAreaServiceUploadPhoto_SupersedesAbandonedAnalysis(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	gv := &gatedVision{
+		ready:   make(chan struct{}),
+		release: make(chan struct{}),
+		first:   &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Stale Milk"}, {Name: "Stale Eggs"}}},
+		next:    &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Fresh Butter"}}},
+	}
+	photos := store.NewPhotoStore(d)
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		photos,
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		gv,
+		newStubPhotoStore(),
+		slog.Default(),
+	).WithDB(d)
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+
+	clientCtx, clientGone := context.WithCancel(ctx)
+	firstDone := make(chan error, 1)
+	go func() {
+		_, _, err := svc.UploadPhoto(clientCtx, area.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
+		firstDone <- err
+	}()
+	<-gv.ready
+	clientGone() // the first uploader navigates away mid-analysis
+
+	secondDone := make(chan error, 1)
+	go func() {
+		_, _, err := svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x02}, "image/jpeg")
+		secondDone <- err
+	}()
+	// Only let the abandoned analysis finish once the new upload has
+	// cancelled it, so the late result is guaranteed to be stale.
+	require.Eventually(t, func() bool { return gv.firstCtx.Err() != nil }, 5*time.Second, 10*time.Millisecond)
+	close(gv.release)
+
+	assert.Error(t, <-firstDone, "superseded upload must not report success")
+	require.NoError(t, <-secondDone)
+
+	_, items, _, err := svc.GetAreaWithItems(ctx, area.ID)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "Fresh Butter", items[0].Name)
+
+	all, err := photos.ListByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Len(t, all, 1, "superseded photo must be rolled back")
+}
+
+func TestAreaServiceSweepInterruptedAnalyses(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	areas := store.NewAreaStore(d)
+	photos := store.NewPhotoStore(d)
+	ctx := context.Background()
+
+	// Simulate a photo left mid-analysis by a previous process.
+	area, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	_, err = photos.Create(ctx, area.ID, "area_1/photo.jpg", "image/jpeg", "")
+	require.NoError(t, err)
+
+	svc := NewAreaService(
+		areas,
+		photos,
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		&stubVision{result: &vision.AnalysisResult{}},
+		newStubPhotoStore(),
+		slog.Default(),
+	)
+
+	n, err := svc.SweepInterruptedAnalyses(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	_, _, photo, err := svc.GetAreaWithItems(ctx, area.ID)
+	require.NoError(t, err)
+	require.NotNil(t, photo)
+	assert.Equal(t, domain.PhotoAnalysisFailed, photo.AnalysisStatus)
+	assert.Equal(t, interruptedReason, photo.AnalysisError)
+}
+
 func TestAreaServiceUploadPhoto_PhotoStorageError(t *testing.T) {
 	d, err := db.OpenForTesting()
 	require.NoError(t, err)
@@ -374,38 +978,184 @@ func TestAreaServiceUpdateArea(t *testing.T) {
 	area, err := svc.CreateArea(ctx, "Old Name")
 	require.NoError(t, err)
 
-	updated, err := svc.UpdateArea(ctx, area.ID, "New Name")
+	updated, err := svc.UpdateArea(ctx, area.ID, "New Name")
+	require.NoError(t, err)
+	assert.Equal(t, "New Name", updated.Name)
+}
+
+func TestAreaServiceUpdateArea_DuplicateName_ReturnsErrNameTaken(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := svc.CreateArea(ctx, "Area One")
+	require.NoError(t, err)
+
+	areaTwo, err := svc.CreateArea(ctx, "Area Two")
+	require.NoError(t, err)
+
+	// Renaming Area Two to "Area One" should return ErrNameTaken.
+	_, err = svc.UpdateArea(ctx, areaTwo.ID, "Area One")
+	require.ErrorIs(t, err, ErrNameTaken)
+}
+
+func TestAreaServiceDeletePhoto(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	visionResult := &vision.AnalysisResult{
+		Items: []vision.DetectedItem{
+			{Name: "Milk", Quantity: "1 liter", Notes: ""},
+		},
+	}
+
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		&stubVision{result: visionResult},
+		newStubPhotoStore(),
+		slog.Default(),
+	)
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+	require.NoError(t, err)
+
+	err = svc.DeletePhoto(ctx, area.ID)
+	require.NoError(t, err)
+
+	// Area still exists but has no items or photo.
+	_, items, photo, err := svc.GetAreaWithItems(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Nil(t, photo)
+	assert.Empty(t, items)
+}
+
+func TestAreaServiceDeletePhoto_SharedFileKeptUntilUnreferenced(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	stg := newStubPhotoStore()
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		&stubVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}}}},
+		stg,
+		slog.Default(),
+	).WithDB(d)
+	ctx := context.Background()
+
+	fridge, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	freezer, err := svc.CreateArea(ctx, "Freezer")
+	require.NoError(t, err)
+
+	// The same bytes uploaded twice to one area and once to another share a file.
+	img := []byte{0xFF, 0xD8, 0x01}
+	p1, _, err := svc.UploadPhoto(ctx, fridge.ID, img, "image/jpeg")
+	require.NoError(t, err)
+	p2, _, err := svc.UploadPhoto(ctx, fridge.ID, img, "image/jpeg")
+	require.NoError(t, err)
+	p3, _, err := svc.UploadPhoto(ctx, freezer.ID, img, "image/jpeg")
+	require.NoError(t, err)
+	assert.Equal(t, p1.StorageKey, p2.StorageKey)
+	assert.Equal(t, p1.StorageKey, p3.StorageKey)
+	require.Len(t, stg.saved, 1)
+
+	require.NoError(t, svc.DeletePhoto(ctx, fridge.ID))
+	assert.Len(t, stg.saved, 1, "file is still referenced by the freezer's photo")
+
+	require.NoError(t, svc.DeletePhoto(ctx, freezer.ID))
+	assert.Empty(t, stg.saved, "file is removed once nothing references it")
+}
+
+// countingVision returns result and counts how often it was called.
+type countingVision struct {
+	result *vision.AnalysisResult
+	calls  atomic.Int32
+}
+
+func (c *countingVision) Analyze(_ context.Context, _ io.Reader, _ string) (*vision.AnalysisResult, error) {
+	c.calls.Add(1)
+	return c.result, nil
+}
+
+func TestAreaServiceUploadPhoto_IdenticalImageSkipsAnalysis(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	vis := &countingVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}}}}
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		vis,
+		newStubPhotoStore(),
+		slog.Default(),
+	).WithDB(d)
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	img := []byte{0xFF, 0xD8, 0x02}
+
+	first, items, err := svc.UploadPhoto(ctx, area.ID, img, "image/jpeg")
 	require.NoError(t, err)
-	assert.Equal(t, "New Name", updated.Name)
-}
+	require.Len(t, items, 1)
+	assert.Equal(t, contentHash(img), first.ContentHash)
+	require.Equal(t, int32(1), vis.calls.Load())
 
-func TestAreaServiceUpdateArea_DuplicateName_ReturnsErrNameTaken(t *testing.T) {
-	svc, cleanup := newTestService(t)
-	defer cleanup()
-	ctx := context.Background()
+	// A manual edit must survive re-uploading the same photo.
+	_, err = svc.UpdateItem(ctx, items[0].ID, "Oat Milk", "2")
+	require.NoError(t, err)
 
-	_, err := svc.CreateArea(ctx, "Area One")
+	again, items, err := svc.UploadPhoto(ctx, area.ID, img, "image/jpeg")
 	require.NoError(t, err)
+	assert.Equal(t, int32(1), vis.calls.Load(), "identical upload must not be analysed")
+	assert.Equal(t, first.ID, again.ID)
+	require.Len(t, items, 1)
+	assert.Equal(t, "Oat Milk", items[0].Name)
 
-	areaTwo, err := svc.CreateArea(ctx, "Area Two")
+	_, items, err = svc.UploadPhotoWithOptions(ctx, area.ID, img, "image/jpeg", UploadOptions{Force: true})
 	require.NoError(t, err)
+	assert.Equal(t, int32(2), vis.calls.Load(), "force must bypass the cache")
+	names := make([]string, len(items))
+	for i, it := range items {
+		names[i] = it.Name
+	}
+	assert.ElementsMatch(t, []string{"Oat Milk", "Milk"}, names, "the edited item is kept alongside the fresh detection")
 
-	// Renaming Area Two to "Area One" should return ErrNameTaken.
-	_, err = svc.UpdateArea(ctx, areaTwo.ID, "Area One")
-	require.ErrorIs(t, err, ErrNameTaken)
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x03}, "image/jpeg")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), vis.calls.Load(), "different bytes are analysed")
 }
 
-func TestAreaServiceDeletePhoto(t *testing.T) {
+func TestAreaServiceUploadPhoto_PreservesEditedItems(t *testing.T) {
 	d, err := db.OpenForTesting()
 	require.NoError(t, err)
 	t.Cleanup(func() { assert.NoError(t, d.Close()) })
 
-	visionResult := &vision.AnalysisResult{
-		Items: []vision.DetectedItem{
-			{Name: "Milk", Quantity: "1 liter", Notes: ""},
-		},
-	}
-
+	vis := &stubVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{
+		{Name: "Milk", Quantity: "1"},
+		{Name: "Eggs", Quantity: "6"},
+	}}}
 	svc := NewAreaService(
 		store.NewAreaStore(d),
 		store.NewPhotoStore(d),
@@ -413,26 +1163,62 @@ func TestAreaServiceDeletePhoto(t *testing.T) {
 		store.NewItemEditStore(d),
 		store.NewSnapshotStore(d),
 		&noopOverrideStore{},
-		&stubVision{result: visionResult},
+		vis,
 		newStubPhotoStore(),
 		slog.Default(),
-	)
+	).WithDB(d)
 	ctx := context.Background()
 
 	area, err := svc.CreateArea(ctx, "Fridge")
 	require.NoError(t, err)
+	_, items, err := svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x10}, "image/jpeg")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
 
-	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+	var eggs *domain.Item
+	for _, it := range items {
+		if it.Name == "Eggs" {
+			eggs = it
+		}
+	}
+	require.NotNil(t, eggs)
+	edited, err := svc.UpdateItem(ctx, eggs.ID, "Eggs", "4")
+	require.NoError(t, err)
+	assert.True(t, edited.Edited)
+	added, err := svc.CreateItem(ctx, area.ID, "Butter", "1")
 	require.NoError(t, err)
 
-	err = svc.DeletePhoto(ctx, area.ID)
+	// The next photo sees "EGGS" again and a new item; the edited row and the
+	// manually added one must survive untouched, without a duplicate Eggs.
+	vis.result = &vision.AnalysisResult{Items: []vision.DetectedItem{
+		{Name: "EGGS", Quantity: "12"},
+		{Name: "Cheese", Quantity: "1"},
+	}}
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x11}, "image/jpeg")
 	require.NoError(t, err)
 
-	// Area still exists but has no items or photo.
-	_, items, photo, err := svc.GetAreaWithItems(ctx, area.ID)
+	_, items, _, err = svc.GetAreaWithItems(ctx, area.ID)
 	require.NoError(t, err)
-	assert.Nil(t, photo)
-	assert.Empty(t, items)
+	got := make(map[string]*domain.Item, len(items))
+	for _, it := range items {
+		got[it.Name] = it
+	}
+	assert.Len(t, items, 3)
+	require.Contains(t, got, "Eggs")
+	assert.Equal(t, eggs.ID, got["Eggs"].ID)
+	assert.Equal(t, "4", got["Eggs"].Quantity)
+	require.Contains(t, got, "Butter")
+	assert.Equal(t, added.ID, got["Butter"].ID)
+	assert.Contains(t, got, "Cheese")
+	assert.NotContains(t, got, "Milk", "unedited items are replaced")
+
+	// ReplaceEdited discards everything for a clean slate.
+	_, items, err = svc.UploadPhotoWithOptions(ctx, area.ID, []byte{0xFF, 0xD8, 0x12}, "image/jpeg", UploadOptions{ReplaceEdited: true})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	for _, it := range items {
+		assert.False(t, it.Edited, it.Name)
+	}
 }
 
 func TestAreaServiceCreateItem(t *testing.T) {
@@ -522,6 +1308,85 @@ func TestAreaServiceUpdateItem_RecordsEdits(t *testing.T) {
 	assert.Equal(t, "2 liters", edits[1].NewValue)
 }
 
+func TestAreaServiceBulkEditItems(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	editStore := store.NewItemEditStore(d)
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		editStore,
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		&stubVision{result: &vision.AnalysisResult{}},
+		newStubPhotoStore(),
+		slog.Default(),
+	)
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	milk, err := svc.CreateItem(ctx, area.ID, "Milk", "1")
+	require.NoError(t, err)
+	eggs, err := svc.CreateItem(ctx, area.ID, "Eggs", "6")
+	require.NoError(t, err)
+
+	items, err := svc.BulkEditItems(ctx, area.ID, []domain.ItemOp{
+		{Op: domain.ItemOpUpdate, ID: milk.ID, Name: "  Whole Milk ", Quantity: "1"},
+		{Op: domain.ItemOpDelete, ID: eggs.ID},
+		{Op: domain.ItemOpCreate, Name: "Butter"},
+	})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "Butter", items[0].Name)
+	assert.Equal(t, "Whole Milk", items[1].Name)
+
+	edits, err := editStore.ListByItemID(ctx, milk.ID)
+	require.NoError(t, err)
+	require.Len(t, edits, 1)
+	assert.Equal(t, "name", edits[0].Field)
+}
+
+func TestAreaServiceBulkEditItems_ValidationRejectsWholeBatch(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	milk, err := svc.CreateItem(ctx, area.ID, "Milk", "1")
+	require.NoError(t, err)
+
+	_, err = svc.BulkEditItems(ctx, area.ID, []domain.ItemOp{
+		{Op: domain.ItemOpDelete, ID: milk.ID},
+		{Op: domain.ItemOpCreate, Name: "   "},
+		{Op: domain.ItemOpUpdate, Name: "Eggs"},
+		{Op: "rename", ID: milk.ID},
+	})
+	var bulkErr *BulkItemError
+	require.ErrorAs(t, err, &bulkErr)
+	assert.Equal(t, []domain.ItemOpFailure{
+		{Index: 1, Error: "item name required"},
+		{Index: 2, Error: "item id required"},
+		{Index: 3, Error: `unknown op "rename"`},
+	}, bulkErr.Failures)
+
+	_, items, _, err := svc.GetAreaWithItems(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Len(t, items, 1, "delete must not be applied when another entry is invalid")
+}
+
+func TestAreaServiceBulkEditItems_AreaNotFound(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+
+	_, err := svc.BulkEditItems(context.Background(), 99999, nil)
+	assert.ErrorIs(t, err, ErrAreaNotFound)
+}
+
 func TestAreaServiceUpdateItem_NoEditsWhenUnchanged(t *testing.T) {
 	d, err := db.OpenForTesting()
 	require.NoError(t, err)
@@ -586,6 +1451,54 @@ func TestAreaServiceDeleteItem(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestAreaServiceInventoryChangesTouchArea(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+
+	// updated_at has second precision, so start from well in the past.
+	backdate := func() time.Time {
+		t.Helper()
+		_, err := svc.db.ExecContext(ctx, `UPDATE areas SET updated_at = '2020-01-01 00:00:00' WHERE id = ?`, area.ID)
+		require.NoError(t, err)
+		a, err := svc.GetArea(ctx, area.ID)
+		require.NoError(t, err)
+		return a.UpdatedAt
+	}
+	updatedAfter := func(before time.Time) bool {
+		t.Helper()
+		a, err := svc.GetArea(ctx, area.ID)
+		require.NoError(t, err)
+		return a.UpdatedAt.After(before)
+	}
+
+	before := backdate()
+	item, err := svc.CreateItem(ctx, area.ID, "Milk", "1")
+	require.NoError(t, err)
+	assert.True(t, updatedAfter(before), "CreateItem")
+
+	before = backdate()
+	_, err = svc.UpdateItem(ctx, item.ID, "Oat milk", "1")
+	require.NoError(t, err)
+	assert.True(t, updatedAfter(before), "UpdateItem")
+
+	before = backdate()
+	require.NoError(t, svc.DeleteItem(ctx, item.ID))
+	assert.True(t, updatedAfter(before), "DeleteItem")
+
+	before = backdate()
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+	require.NoError(t, err)
+	assert.True(t, updatedAfter(before), "UploadPhoto")
+
+	before = backdate()
+	require.NoError(t, svc.DeletePhoto(ctx, area.ID))
+	assert.True(t, updatedAfter(before), "DeletePhoto")
+}
+
 func TestAreaServiceUploadPhoto_ConcurrentSameArea_DoesNotCorruptItems(t *testing.T) {
 	d, err := db.OpenForTesting()
 	require.NoError(t, err)
@@ -617,16 +1530,23 @@ func TestAreaServiceUploadPhoto_ConcurrentSameArea_DoesNotCorruptItems(t *testin
 	}
 
 	var wg sync.WaitGroup
+	var succeeded atomic.Int32
 	for i := range sets {
 		wg.Add(1)
 		cv.ch <- &vision.AnalysisResult{Items: sets[i]}
 		go func() {
 			defer wg.Done()
 			_, _, err := svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
-			assert.NoError(t, err)
+			// Overlapping uploads to the same area are rejected, not queued.
+			if err == nil {
+				succeeded.Add(1)
+			} else {
+				assert.ErrorIs(t, err, ErrAnalysisInProgress)
+			}
 		}()
 	}
 	wg.Wait()
+	assert.Positive(t, succeeded.Load())
 
 	// After all uploads, items must belong to exactly one upload's result set —
 	// no mixing of items from different uploads.
@@ -685,7 +1605,7 @@ func TestAreaServiceUploadPhoto_ConcurrentDifferentAreas_DoNotInterfere(t *testi
 	}
 }
 
-func TestAreaServiceListAreasWithItems(t *testing.T) {
+func TestAreaServiceListAreaSummaries(t *testing.T) {
 	d, err := db.OpenForTesting()
 	require.NoError(t, err)
 	t.Cleanup(func() { assert.NoError(t, d.Close()) })
@@ -713,14 +1633,109 @@ func TestAreaServiceListAreasWithItems(t *testing.T) {
 	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
 	require.NoError(t, err)
 
-	summaries, err := svc.ListAreasWithItems(ctx)
+	summaries, err := svc.ListAreaSummaries(ctx)
 	require.NoError(t, err)
 	require.Len(t, summaries, 1)
 	assert.Equal(t, "Fridge", summaries[0].Name)
-	assert.Len(t, summaries[0].Items, 1)
+	assert.Equal(t, 1, summaries[0].ItemCount)
+	assert.Len(t, summaries[0].Preview, 1)
+	assert.Nil(t, summaries[0].Items, "the area list only previews items")
 	assert.NotNil(t, summaries[0].Photo)
 }
 
+// countingConnector opens sqlite connections that count the statements run
+// on them.
+type countingConnector struct {
+	dsn     string
+	queries atomic.Int64
+}
+
+func (c *countingConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.Driver().Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{Conn: conn, queries: &c.queries}, nil
+}
+
+func (c *countingConnector) Driver() driver.Driver { return &sqlite.Driver{} }
+
+type countingConn struct {
+	driver.Conn
+	queries *atomic.Int64
+}
+
+func (c *countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.queries.Add(1)
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c *countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.queries.Add(1)
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *countingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func TestAreaServiceListAreaSummaries_QueriesDontGrowWithItems(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kitchinv.db")
+	migrated, err := db.Open(path, db.Options{})
+	require.NoError(t, err)
+	require.NoError(t, migrated.Close())
+	counter := &countingConnector{dsn: "file:" + path + "?_pragma=foreign_keys(1)"}
+	d := sql.OpenDB(counter)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		&stubVision{result: &vision.AnalysisResult{}},
+		newStubPhotoStore(),
+		slog.Default(),
+	).WithDB(d)
+	ctx := context.Background()
+
+	var areas []*domain.Area
+	for _, name := range []string{"Fridge", "Pantry"} {
+		area, err := svc.CreateArea(ctx, name)
+		require.NoError(t, err)
+		areas = append(areas, area)
+	}
+	addItems := func(n int) {
+		for _, area := range areas {
+			for i := 0; i < n; i++ {
+				_, err := svc.CreateItem(ctx, area.ID, fmt.Sprintf("Item %03d", i), "1")
+				require.NoError(t, err)
+			}
+		}
+	}
+	queries := func() int64 {
+		before := counter.queries.Load()
+		summaries, err := svc.ListAreaSummaries(ctx)
+		require.NoError(t, err)
+		require.Len(t, summaries, len(areas))
+		return counter.queries.Load() - before
+	}
+
+	addItems(2)
+	few := queries()
+	addItems(100)
+	many := queries()
+
+	assert.Equal(t, few, many, "listing areas must not query per item")
+	summaries, err := svc.ListAreaSummaries(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 102, summaries[0].ItemCount)
+	assert.Len(t, summaries[0].Preview, ItemPreviewLimit)
+	assert.Equal(t, "Item 000", summaries[0].Preview[0].Name)
+}
+
 // TestAreaService_SnapshotCreatedOnReupload verifies that uploading a second
 // photo to an area that already has items creates a snapshot of the previous
 // inventory before replacing it.
@@ -771,8 +1786,8 @@ func TestAreaService_SnapshotCreatedOnReupload(t *testing.T) {
 	require.NoError(t, err)
 	assert.Empty(t, snapshots, "no snapshot expected on first upload")
 
-	// Second upload — should snapshot the previous inventory (Milk + Eggs).
-	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+	// Second upload of a new photo — should snapshot the previous inventory (Milk + Eggs).
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
 	require.NoError(t, err)
 
 	snapshots, err = snapshotStore.ListByAreaID(ctx, area.ID)
@@ -1050,7 +2065,15 @@ func TestDeleteArea_CleansUpOrphanedOverrideRules(t *testing.T) {
 
 	rules, err = overrideStore.List(ctx)
 	require.NoError(t, err)
-	assert.Empty(t, rules, "orphaned rule should be deleted with the area")
+	require.Len(t, rules, 1, "rule should survive a soft delete so undo restores it")
+
+	n, err := svc.PurgeDeletedAreas(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	rules, err = overrideStore.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, rules, "orphaned rule should be deleted when the area is purged")
 }
 
 func TestUpdateItem_AutoRuleSortsFirst(t *testing.T) {
@@ -1078,3 +2101,182 @@ func TestUpdateItem_AutoRuleSortsFirst(t *testing.T) {
 	require.Len(t, rules, 2)
 	assert.Equal(t, "OJ", rules[0].MatchPattern, "auto-created rule should sort before existing rule")
 }
+
+// promptVision records the prompt of each call; an empty entry means the
+// default Analyze was used.
+type promptVision struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (p *promptVision) Analyze(_ context.Context, _ io.Reader, _ string) (*vision.AnalysisResult, error) {
+	return p.AnalyzeWithPrompt(context.Background(), nil, "", "")
+}
+
+func (p *promptVision) AnalyzeWithPrompt(_ context.Context, _ io.Reader, _, prompt string) (*vision.AnalysisResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prompts = append(p.prompts, prompt)
+	return &vision.AnalysisResult{}, nil
+}
+
+func TestAreaServiceUploadPhoto_UsesAreaPrompt(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	vis := &promptVision{}
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		vis,
+		newStubPhotoStore(),
+		slog.Default(),
+	).WithDB(d)
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Spice Rack")
+	require.NoError(t, err)
+
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
+	require.NoError(t, err)
+
+	updated, err := svc.SetAreaPrompt(ctx, area.ID, "  Read every jar label.\n")
+	require.NoError(t, err)
+	assert.Equal(t, "Read every jar label.", updated.Prompt)
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x02}, "image/jpeg")
+	require.NoError(t, err)
+
+	// Whitespace-only clears the prompt.
+	updated, err = svc.SetAreaPrompt(ctx, area.ID, " \t ")
+	require.NoError(t, err)
+	assert.Empty(t, updated.Prompt)
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x03}, "image/jpeg")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"", "Read every jar label.", ""}, vis.prompts)
+}
+
+func TestAreaServiceLatestRawResponse(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	long := strings.Repeat("x", maxRawResponseLen+100)
+	vis := &stubVision{result: &vision.AnalysisResult{RawResponse: long}}
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		vis,
+		newStubPhotoStore(),
+		slog.Default(),
+	).WithDB(d)
+	ctx := context.Background()
+
+	_, err = svc.LatestRawResponse(ctx, 99)
+	assert.ErrorIs(t, err, ErrAreaNotFound)
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	_, err = svc.LatestRawResponse(ctx, area.ID)
+	assert.ErrorIs(t, err, ErrNoPhoto)
+
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
+	require.NoError(t, err)
+	raw, err := svc.LatestRawResponse(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Equal(t, long[:maxRawResponseLen], raw, "stored reply is truncated")
+}
+
+// sequenceVision returns results in turn, one per call, and errs once they
+// run out.
+type sequenceVision struct {
+	results []*vision.AnalysisResult
+	calls   int
+}
+
+func (v *sequenceVision) Analyze(_ context.Context, _ io.Reader, _ string) (*vision.AnalysisResult, error) {
+	if v.calls >= len(v.results) {
+		return nil, errors.New("vision unavailable")
+	}
+	v.calls++
+	return v.results[v.calls-1], nil
+}
+
+func TestAreaServiceUploadPhotos_MergesItems(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	svc.visionAPI = &sequenceVision{results: []*vision.AnalysisResult{
+		{Items: []vision.DetectedItem{{Name: "Flour", Quantity: "1"}, {Name: "Rice", Quantity: "2"}}},
+		{Items: []vision.DetectedItem{{Name: "rice", Quantity: "3"}, {Name: "Beans", Quantity: "4"}}},
+	}}
+	area, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, area.ID, "Old jam", "1")
+	require.NoError(t, err)
+
+	photos, items, err := svc.UploadPhotos(ctx, area.ID, []PhotoUpload{
+		{Data: []byte{0xFF, 0xD8, 0x01}, MIMEType: "image/jpeg"},
+		{Data: []byte{0xFF, 0xD8, 0x02}, MIMEType: "image/jpeg"},
+	}, UploadOptions{ReplaceEdited: true})
+	require.NoError(t, err)
+	require.Len(t, photos, 2)
+	for _, p := range photos {
+		assert.Equal(t, domain.PhotoAnalysisComplete, p.AnalysisStatus)
+	}
+
+	got := make(map[string]*domain.Item)
+	for _, it := range items {
+		got[it.Name] = it
+	}
+	require.Len(t, got, 3, "the rice seen in both photos is kept once")
+	assert.Equal(t, "2", got["Rice"].Quantity, "from the first photo it appears in")
+	assert.Equal(t, photos[0].ID, *got["Rice"].PhotoID)
+	assert.Equal(t, photos[1].ID, *got["Beans"].PhotoID)
+
+	stored, err := svc.itemStore.ListByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Len(t, stored, 3, "the area's items are replaced once, by all the photos")
+	all, err := svc.photoStore.ListByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func TestAreaServiceUploadPhotos_FailureRollsBackAll(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	photoStg := newStubPhotoStore()
+	svc.photoStg = photoStg
+	svc.visionAPI = &sequenceVision{results: []*vision.AnalysisResult{
+		{Items: []vision.DetectedItem{{Name: "Flour", Quantity: "1"}}},
+	}}
+	area, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, area.ID, "Rice", "1")
+	require.NoError(t, err)
+
+	_, _, err = svc.UploadPhotos(ctx, area.ID, []PhotoUpload{
+		{Data: []byte{0xFF, 0xD8, 0x01}, MIMEType: "image/jpeg"},
+		{Data: []byte{0xFF, 0xD8, 0x02}, MIMEType: "image/jpeg"},
+	}, UploadOptions{})
+	require.Error(t, err)
+
+	photos, err := svc.photoStore.ListByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Empty(t, photos, "a failed analysis discards every photo of the upload")
+	assert.Empty(t, photoStg.saved)
+	items, err := svc.itemStore.ListByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	require.Len(t, items, 1, "the items aren't touched")
+	assert.Equal(t, "Rice", items[0].Name)
+}
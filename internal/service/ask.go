@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+// DefaultAskPrompt is the text/template the question and inventory are
+// rendered into. It receives .Question, .Matches (items whose names match
+// words of the question), .Inventory (every item in stock, newest first) and
+// .More (how many items were left out of .Inventory). Items are formatted as
+// "Milk (1 carton) in Fridge".
+const DefaultAskPrompt = `Answer a question about my kitchen inventory using only the items listed below. Reply in one or two short sentences of plain text, and say where things are when that helps. If the list doesn't answer the question, say so.
+
+Question: {{.Question}}
+{{if .Matches}}
+Items matching the question:
+{{range .Matches}}- {{.}}
+{{end}}{{end}}
+Everything in stock:
+{{range .Inventory}}- {{.}}
+{{else}}(nothing)
+{{end}}{{if .More}}(and {{.More}} more not listed)
+{{end}}`
+
+var defaultAskPrompt = template.Must(ParseAskPrompt(""))
+
+// maxAskMatches caps the search matches returned with an answer.
+const maxAskMatches = 20
+
+// askStopWords are question words too common to be worth searching for.
+var askStopWords = map[string]bool{
+	"the": true, "and": true, "any": true, "are": true, "can": true, "did": true,
+	"does": true, "for": true, "have": true, "has": true, "how": true, "many": true,
+	"much": true, "our": true, "left": true, "some": true, "there": true, "what": true,
+	"where": true, "which": true, "who": true, "with": true, "you": true, "got": true,
+	"need": true, "still": true, "enough": true, "kitchen": true, "anything": true,
+	"make": true, "into": true, "from": true, "out": true, "run": true, "running": true,
+	"buy": true, "should": true, "could": true, "would": true, "all": true, "else": true,
+	"this": true, "that": true, "them": true, "they": true, "its": true,
+}
+
+// AskAnswer is the reply to a question about the inventory.
+type AskAnswer struct {
+	Question string
+	// Answer is the model's reply; empty when Fallback is set.
+	Answer string
+	// Fallback is set when the model couldn't be asked or failed, leaving
+	// only the search matches.
+	Fallback bool
+	// Items are the items whose names match words of the question.
+	Items []*domain.RecentItem
+}
+
+// askPromptData is what the ask prompt template is executed with.
+type askPromptData struct {
+	Question  string
+	Matches   []string
+	Inventory []string
+	More      int
+}
+
+// ParseAskPrompt parses an ask prompt template; see DefaultAskPrompt for the
+// fields it can use. A blank text parses the default.
+func ParseAskPrompt(text string) (*template.Template, error) {
+	t, err := template.New("ask").Option("missingkey=error").Parse(vision.PromptOrDefault(text, DefaultAskPrompt))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ask prompt: %w", err)
+	}
+	if err := t.Execute(&strings.Builder{}, askPromptData{Question: "test", Inventory: []string{"test"}}); err != nil {
+		return nil, fmt.Errorf("invalid ask prompt: %w", err)
+	}
+	return t, nil
+}
+
+// WithAskPrompt replaces the DefaultAskPrompt template. nil keeps the
+// default.
+func (s *AreaService) WithAskPrompt(t *template.Template) *AreaService {
+	if t != nil {
+		s.askPrompt = t
+	}
+	return s
+}
+
+// Ask answers a natural-language question about the inventory, such as "do
+// we have eggs?". The items matching words of the question are searched for
+// and returned with the answer. The model is asked with those matches and
+// the in-stock inventory, up to the recipe item cap; when the backend can't
+// generate text, is busy or fails, the answer falls back to the matches
+// alone. Like SuggestRecipes, the request takes an analysis slot and is bound
+// by the analysis timeout.
+func (s *AreaService) Ask(ctx context.Context, question string) (*AskAnswer, error) {
+	question = strings.TrimSpace(question)
+	matches, err := s.askMatches(ctx, question)
+	if err != nil {
+		return nil, err
+	}
+	ans := &AskAnswer{Question: question, Items: matches}
+
+	gen, ok := s.visionAPI.(vision.TextGenerator)
+	if !ok {
+		ans.Fallback = true
+		return ans, nil
+	}
+	prompt, err := s.askPromptFor(ctx, question, matches)
+	if err != nil {
+		return nil, err
+	}
+	var answer strings.Builder
+	if err := s.generateText(ctx, gen, prompt, func(delta string) { answer.WriteString(delta) }); err != nil {
+		s.log(ctx).Warn("ask fell back to search results", "error", err)
+		ans.Fallback = true
+		return ans, nil
+	}
+	ans.Answer = strings.TrimSpace(answer.String())
+	ans.Fallback = ans.Answer == ""
+	return ans, nil
+}
+
+// askMatches searches for each word of question worth searching for and
+// returns the matching items in the order found.
+func (s *AreaService) askMatches(ctx context.Context, question string) ([]*domain.RecentItem, error) {
+	areas, err := s.areaStore.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list areas: %w", err)
+	}
+	paths := make(map[int64]string, len(areas))
+	for _, a := range areas {
+		paths[a.ID] = a.Path()
+	}
+
+	seen := make(map[int64]bool)
+	var matches []*domain.RecentItem
+	for _, kw := range askKeywords(question) {
+		items, err := s.itemStore.Search(ctx, kw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search items: %w", err)
+		}
+		for _, it := range items {
+			if seen[it.ID] {
+				continue
+			}
+			seen[it.ID] = true
+			matches = append(matches, &domain.RecentItem{Item: *it, AreaName: paths[it.AreaID]})
+		}
+	}
+	return matches[:min(len(matches), maxAskMatches)], nil
+}
+
+// askPromptFor renders the ask prompt for question.
+func (s *AreaService) askPromptFor(ctx context.Context, question string, matches []*domain.RecentItem) (string, error) {
+	items, err := s.itemStore.ListRecent(ctx, time.Time{}, 0, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to list items: %w", err)
+	}
+	data := askPromptData{Question: question}
+	for _, it := range matches {
+		data.Matches = append(data.Matches, askItemLine(it))
+	}
+	for _, it := range items {
+		if isOutOfStock(&it.Item) {
+			continue
+		}
+		if len(data.Inventory) == s.suggestMaxItems {
+			data.More++
+			continue
+		}
+		data.Inventory = append(data.Inventory, askItemLine(it))
+	}
+	var b strings.Builder
+	if err := s.askPrompt.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render ask prompt: %w", err)
+	}
+	return b.String(), nil
+}
+
+func askItemLine(it *domain.RecentItem) string {
+	line := it.Name
+	if q := strings.TrimSpace(it.Quantity); q != "" {
+		line += " (" + q + ")"
+	}
+	if it.AreaName != "" {
+		line += " in " + it.AreaName
+	}
+	return line
+}
+
+// askKeywords returns the words of question worth searching for, singular
+// so that "eggs" also matches "Egg".
+func askKeywords(question string) []string {
+	var kws []string
+	seen := make(map[string]bool)
+	for _, w := range nameWords(question) {
+		if len([]rune(w)) < 3 || askStopWords[w] {
+			continue
+		}
+		w = singular(w)
+		if !seen[w] {
+			seen[w] = true
+			kws = append(kws, w)
+		}
+	}
+	return kws
+}
+
+// singular undoes the regular English plurals isPlural recognises. It errs
+// towards keeping letters, since the result is used as a substring search.
+func singular(w string) string {
+	switch {
+	case strings.HasSuffix(w, "ies") && len(w) > 4:
+		return strings.TrimSuffix(w, "ies") + "y"
+	case strings.HasSuffix(w, "oes"), strings.HasSuffix(w, "ches"), strings.HasSuffix(w, "shes"), strings.HasSuffix(w, "xes"):
+		return strings.TrimSuffix(w, "es")
+	case strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") && len(w) > 3:
+		return strings.TrimSuffix(w, "s")
+	}
+	return w
+}
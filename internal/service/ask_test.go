@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAsk(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	fridge, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	pantry, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, fridge.ID, "Eggs", "6")
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, pantry.ID, "Egg noodles", "1 bag")
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, pantry.ID, "Flour", "0")
+	require.NoError(t, err)
+
+	// Without a text generator the search matches are the answer.
+	ans, err := svc.Ask(ctx, " Do we have any eggs? ")
+	require.NoError(t, err)
+	assert.Equal(t, "Do we have any eggs?", ans.Question)
+	assert.True(t, ans.Fallback)
+	assert.Empty(t, ans.Answer)
+	require.Len(t, ans.Items, 2)
+	assert.Equal(t, "Pantry", ans.Items[0].AreaName)
+	assert.Equal(t, "Fridge", ans.Items[1].AreaName)
+
+	gen := &textVision{deltas: []string{"Yes, 6 eggs ", "in the Fridge."}}
+	svc.visionAPI = gen
+	ans, err = svc.Ask(ctx, "Do we have any eggs?")
+	require.NoError(t, err)
+	assert.False(t, ans.Fallback)
+	assert.Equal(t, "Yes, 6 eggs in the Fridge.", ans.Answer)
+	assert.Len(t, ans.Items, 2)
+	assert.Contains(t, gen.prompt, "Question: Do we have any eggs?")
+	assert.Contains(t, gen.prompt, "- Eggs (6) in Fridge")
+	assert.NotContains(t, gen.prompt, "Flour", "out-of-stock items are left out")
+
+	gen.err = errors.New("connection refused")
+	ans, err = svc.Ask(ctx, "Do we have any eggs?")
+	require.NoError(t, err)
+	assert.True(t, ans.Fallback, "a failing backend falls back to the search results")
+	assert.Empty(t, ans.Answer)
+	assert.Len(t, ans.Items, 2)
+}
+
+func TestAskPromptTemplate(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	for _, name := range []string{"Milk", "Butter", "Jam"} {
+		_, err := svc.CreateItem(ctx, area.ID, name, "1")
+		require.NoError(t, err)
+	}
+
+	tmpl, err := ParseAskPrompt("Q={{.Question}} N={{len .Inventory}} More={{.More}}")
+	require.NoError(t, err)
+	gen := &textVision{deltas: []string{"ok"}}
+	svc.visionAPI = gen
+	svc.WithAskPrompt(tmpl).WithSuggestMaxItems(2)
+	_, err = svc.Ask(ctx, "what jam?")
+	require.NoError(t, err)
+	assert.Equal(t, "Q=what jam? N=2 More=1", gen.prompt)
+
+	_, err = ParseAskPrompt("{{.Question")
+	assert.Error(t, err)
+	_, err = ParseAskPrompt("{{.Nope}}")
+	assert.Error(t, err)
+}
+
+func TestAskKeywords(t *testing.T) {
+	assert.Equal(t, []string{"egg"}, askKeywords("Do we have any eggs?"))
+	assert.Equal(t, []string{"cherry", "tomato"}, askKeywords("cherries or tomatoes?"))
+	assert.Equal(t, []string{"peach", "glass"}, askKeywords("Where are the peaches, and the glass?"))
+	assert.Empty(t, askKeywords("is it?"))
+}
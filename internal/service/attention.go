@@ -0,0 +1,76 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// AttentionWeights controls how much each signal contributes to an area's
+// "needs attention" score. Higher scores sort first on GET /areas?sort=attention.
+//
+// The defaults are tuned so that an area that has never been photographed ranks
+// alongside one whose photo is a month old, an empty area (photographed but
+// nothing stored) ranks above both, and each out-of-stock item nudges an area
+// up without dominating staleness.
+type AttentionWeights struct {
+	// StalePerDay is added for every whole day since the latest photo.
+	StalePerDay float64
+	// StaleMaxDays caps the staleness contribution so ancient photos don't
+	// drown out every other signal.
+	StaleMaxDays int
+	// NoPhoto is added when the area has never been photographed.
+	NoPhoto float64
+	// Empty is added when the area has a photo but no stored items.
+	Empty float64
+	// OutOfStock is added for each item whose quantity parses as zero.
+	OutOfStock float64
+}
+
+// DefaultAttentionWeights are used unless overridden via WithAttentionWeights.
+var DefaultAttentionWeights = AttentionWeights{
+	StalePerDay:  1,
+	StaleMaxDays: 60,
+	NoPhoto:      30,
+	Empty:        40,
+	OutOfStock:   5,
+}
+
+// attentionScore computes the needs-attention score for a summary using only
+// the data already loaded by ListAreaSummaries. It is pure so the ranking can
+// be pinned by unit tests.
+func attentionScore(w AttentionWeights, sum *AreaSummary, now time.Time) float64 {
+	if sum.Photo == nil {
+		return w.NoPhoto
+	}
+
+	var score float64
+	days := int(now.Sub(sum.Photo.UploadedAt).Hours() / 24)
+	if days > w.StaleMaxDays {
+		days = w.StaleMaxDays
+	}
+	if days > 0 {
+		score += float64(days) * w.StalePerDay
+	}
+
+	if sum.ItemCount == 0 {
+		score += w.Empty
+	}
+	score += float64(sum.OutOfStock) * w.OutOfStock
+	return score
+}
+
+// isOutOfStock reports whether an item's quantity reads as zero remaining,
+// as ItemStore.ListPreviews counts it.
+func isOutOfStock(it *domain.Item) bool {
+	return it.QuantityValue != nil && *it.QuantityValue <= 0
+}
+
+// SortByAttention orders summaries by descending attention score. The sort is
+// stable so areas with equal scores keep their user-defined order.
+func SortByAttention(summaries []*AreaSummary) {
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Attention > summaries[j].Attention
+	})
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/units"
+)
+
+func TestAttentionScore(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	w := DefaultAttentionWeights
+	photoAt := func(daysAgo int) *domain.Photo {
+		return &domain.Photo{UploadedAt: now.Add(-time.Duration(daysAgo) * 24 * time.Hour)}
+	}
+
+	t.Run("no photo", func(t *testing.T) {
+		got := attentionScore(w, &AreaSummary{Area: &domain.Area{}}, now)
+		assert.Equal(t, w.NoPhoto, got)
+	})
+
+	t.Run("fresh photo with stocked items scores zero", func(t *testing.T) {
+		sum := &AreaSummary{Photo: photoAt(0), ItemCount: 1}
+		assert.Equal(t, 0.0, attentionScore(w, sum, now))
+	})
+
+	t.Run("staleness is capped", func(t *testing.T) {
+		sum := &AreaSummary{Photo: photoAt(365), ItemCount: 1}
+		assert.Equal(t, float64(w.StaleMaxDays)*w.StalePerDay, attentionScore(w, sum, now))
+	})
+
+	t.Run("empty area", func(t *testing.T) {
+		sum := &AreaSummary{Photo: photoAt(2)}
+		assert.Equal(t, 2*w.StalePerDay+w.Empty, attentionScore(w, sum, now))
+	})
+
+	t.Run("out of stock items add per item", func(t *testing.T) {
+		var items []*domain.Item
+		for _, q := range []string{"0", " 0 ", "0 cans", "half", "some"} {
+			value, unit := units.Nullable(q)
+			items = append(items, &domain.Item{Quantity: q, QuantityValue: value, QuantityUnit: string(unit)})
+		}
+		p := previewOf(items)
+		sum := &AreaSummary{Photo: photoAt(0), ItemCount: p.Count, OutOfStock: p.OutOfStock}
+		assert.Equal(t, 3*w.OutOfStock, attentionScore(w, sum, now))
+	})
+}
+
+func TestSortByAttention(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	w := DefaultAttentionWeights
+	fresh := &AreaSummary{Area: &domain.Area{Name: "fresh"}, Photo: &domain.Photo{UploadedAt: now}, ItemCount: 1}
+	stale := &AreaSummary{Area: &domain.Area{Name: "stale"}, Photo: &domain.Photo{UploadedAt: now.Add(-10 * 24 * time.Hour)}, ItemCount: 1}
+	noPhoto := &AreaSummary{Area: &domain.Area{Name: "no photo"}}
+	empty := &AreaSummary{Area: &domain.Area{Name: "empty"}, Photo: &domain.Photo{UploadedAt: now}}
+	alsoFresh := &AreaSummary{Area: &domain.Area{Name: "also fresh"}, Photo: &domain.Photo{UploadedAt: now}, ItemCount: 1}
+
+	summaries := []*AreaSummary{fresh, stale, noPhoto, empty, alsoFresh}
+	for _, s := range summaries {
+		s.Attention = attentionScore(w, s, now)
+	}
+	SortByAttention(summaries)
+
+	var names []string
+	for _, s := range summaries {
+		names = append(names, s.Area.Name)
+	}
+	assert.Equal(t, []string{"empty", "no photo", "stale", "fresh", "also fresh"}, names)
+}
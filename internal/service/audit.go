@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+
+	"github.com/vbonduro/kitchinv/internal/audit"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/logging"
+)
+
+// auditRepository is the subset of audit.Store that AreaService requires.
+type auditRepository interface {
+	Record(ctx context.Context, e audit.Entry) error
+	List(ctx context.Context, f audit.Filter) ([]*audit.Entry, error)
+}
+
+// WithAuditLog records destructive actions (deletes, restores and item
+// replacement) to st. Without it nothing is recorded.
+func (s *AreaService) WithAuditLog(st auditRepository) *AreaService {
+	s.auditLog = st
+	return s
+}
+
+// ListAuditEntries returns audit log entries matching f, newest first.
+func (s *AreaService) ListAuditEntries(ctx context.Context, f audit.Filter) ([]*audit.Entry, error) {
+	if s.auditLog == nil {
+		return nil, nil
+	}
+	return s.auditLog.List(ctx, f)
+}
+
+// audit records an action against an entity in areaID, tagged with the
+// request that caused it. Failures are logged: the action itself has already
+// happened and must not be reported as failed.
+func (s *AreaService) audit(ctx context.Context, action, entityType string, entityID, areaID int64, detail map[string]any) {
+	if s.auditLog == nil {
+		return
+	}
+	e := audit.Entry{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		AreaID:     &areaID,
+		Detail:     detail,
+		RequestID:  logging.RequestID(ctx),
+	}
+	if err := s.auditLog.Record(context.WithoutCancel(ctx), e); err != nil {
+		s.log(ctx).Error("failed to record audit entry", "action", action, "entity_id", entityID, "error", err)
+	}
+}
+
+// auditBulkEdit records a committed bulk edit. before maps item IDs to their
+// pre-edit state so deleted items can be named.
+func (s *AreaService) auditBulkEdit(ctx context.Context, areaID int64, ops []domain.ItemOp, before map[int64]*domain.Item) {
+	counts := map[domain.ItemOpKind]int{}
+	var deleted []*domain.Item
+	for _, op := range ops {
+		counts[op.Op]++
+		if op.Op == domain.ItemOpDelete {
+			if it, ok := before[op.ID]; ok {
+				deleted = append(deleted, it)
+			}
+		}
+	}
+	s.audit(ctx, audit.ActionItemsBulk, audit.EntityArea, areaID, areaID, map[string]any{
+		"created": counts[domain.ItemOpCreate], "updated": counts[domain.ItemOpUpdate], "removed": itemNames(deleted),
+	})
+}
+
+// itemNames lists items as "name (quantity)" for audit details, so a log
+// entry shows what was removed.
+func itemNames(items []*domain.Item) []string {
+	names := make([]string, 0, len(items))
+	for _, it := range items {
+		if it.Quantity != "" {
+			names = append(names, it.Name+" ("+it.Quantity+")")
+		} else {
+			names = append(names, it.Name)
+		}
+	}
+	return names
+}
+
+// replacedItems is the part of existing that a re-analysis removes: every
+// item with replaceEdited, otherwise those the user hasn't touched.
+func replacedItems(existing []*domain.Item, replaceEdited bool) []*domain.Item {
+	var out []*domain.Item
+	for _, it := range existing {
+		if replaceEdited || !it.Edited {
+			out = append(out, it)
+		}
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/audit"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/logging"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+func TestAreaServiceAuditLog(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	log := audit.NewStore(svc.db)
+	svc.WithAuditLog(log)
+	svc.visionAPI = &stubVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}}}}
+	ctx := logging.WithRequestID(context.Background(), "req-42")
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+	require.NoError(t, err)
+
+	// Re-analysis replaces the first photo's items.
+	_, _, err = svc.UploadPhotoWithOptions(ctx, area.ID, []byte{0xFF, 0xD9}, "image/jpeg", UploadOptions{})
+	require.NoError(t, err)
+
+	butter, err := svc.CreateItem(ctx, area.ID, "Butter", "")
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteItem(ctx, butter.ID))
+	_, err = svc.BulkEditItems(ctx, area.ID, []domain.ItemOp{{Op: domain.ItemOpCreate, Name: "Eggs"}})
+	require.NoError(t, err)
+	require.NoError(t, svc.DeletePhoto(ctx, area.ID))
+	require.NoError(t, svc.DeleteArea(ctx, area.ID))
+	_, err = svc.RestoreArea(ctx, area.ID)
+	require.NoError(t, err)
+
+	entries, err := svc.ListAuditEntries(ctx, audit.Filter{AreaID: area.ID})
+	require.NoError(t, err)
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+		assert.Equal(t, "req-42", e.RequestID)
+	}
+	assert.Equal(t, []string{
+		audit.ActionAreaRestore, audit.ActionAreaDelete, audit.ActionPhotoDelete,
+		audit.ActionItemsBulk, audit.ActionItemDelete, audit.ActionItemsReplace,
+	}, actions, "newest first; the first upload replaced nothing")
+
+	assert.Equal(t, []any{"Milk (1)"}, entries[5].Detail["removed"])
+	assert.Equal(t, "Butter", entries[4].Detail["name"])
+	assert.Equal(t, []any{"Eggs", "Milk (1)"}, entries[2].Detail["removed"])
+}
+
+func TestAreaServiceAuditLogDisabled(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteArea(ctx, area.ID))
+
+	entries, err := svc.ListAuditEntries(ctx, audit.Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/openfoodfacts"
+)
+
+// How long a barcode lookup is cached. Products rarely change, but a barcode
+// the database didn't know may be added to it later.
+const (
+	barcodeFoundTTL    = 30 * 24 * time.Hour
+	barcodeNotFoundTTL = 24 * time.Hour
+)
+
+// ErrInvalidBarcode is returned by CreateItemFromBarcode for anything but an
+// 8 to 14 digit product code (EAN-8, UPC-A, EAN-13 or GTIN-14).
+var ErrInvalidBarcode = errors.New("invalid barcode")
+
+// productLookup is the subset of openfoodfacts.Client that AreaService
+// requires.
+type productLookup interface {
+	Lookup(ctx context.Context, barcode string) (*openfoodfacts.Product, error)
+}
+
+// barcodeRepository is the subset of store.BarcodeStore that AreaService
+// requires.
+type barcodeRepository interface {
+	Get(ctx context.Context, barcode string) (*domain.BarcodeProduct, error)
+	Put(ctx context.Context, p domain.BarcodeProduct) error
+}
+
+// WithBarcodeLookup sets where barcodes are looked up and where the results
+// are cached. Either may be nil: without a lookup every scanned item is named
+// after its barcode, and without a cache every scan queries the lookup.
+func (s *AreaService) WithBarcodeLookup(lookup productLookup, cache barcodeRepository) *AreaService {
+	s.barcodeLookup = lookup
+	s.barcodeCache = cache
+	return s
+}
+
+// CreateItemFromBarcode adds an item to an area from a scanned barcode,
+// named and sized from the product database. When the product can't be
+// found, or the lookup fails, the item is named after the barcode so it can
+// be renamed later. It returns ErrInvalidBarcode for a malformed barcode and
+// ErrAreaNotFound for an unknown area.
+func (s *AreaService) CreateItemFromBarcode(ctx context.Context, areaID int64, barcode string) (*domain.Item, error) {
+	barcode = strings.TrimSpace(barcode)
+	if !validBarcode(barcode) {
+		return nil, ErrInvalidBarcode
+	}
+	area, err := s.areaStore.GetByID(ctx, areaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get area: %w", err)
+	}
+	if area == nil {
+		return nil, ErrAreaNotFound
+	}
+
+	name, quantity := barcode, ""
+	if p := s.lookupBarcode(ctx, barcode); p != nil && p.Found {
+		name, quantity = s.itemLimits.Clamp(p.Name, p.Quantity)
+		if name == "" {
+			name = barcode
+		}
+	}
+	return s.CreateItem(ctx, areaID, name, quantity)
+}
+
+// lookupBarcode returns the cached lookup of barcode if it is fresh, or asks
+// the product database and caches its answer. It returns nil when neither
+// can say, logging why.
+func (s *AreaService) lookupBarcode(ctx context.Context, barcode string) *domain.BarcodeProduct {
+	log := s.log(ctx).With("barcode", barcode)
+	var cached *domain.BarcodeProduct
+	if s.barcodeCache != nil {
+		var err error
+		if cached, err = s.barcodeCache.Get(ctx, barcode); err != nil {
+			log.Warn("failed to read barcode cache", "error", err)
+		}
+		ttl := barcodeNotFoundTTL
+		if cached != nil && cached.Found {
+			ttl = barcodeFoundTTL
+		}
+		if cached != nil && time.Since(cached.FetchedAt) < ttl {
+			return cached
+		}
+	}
+	if s.barcodeLookup == nil {
+		return cached
+	}
+
+	p := domain.BarcodeProduct{Barcode: barcode, FetchedAt: time.Now()}
+	product, err := s.barcodeLookup.Lookup(ctx, barcode)
+	switch {
+	case errors.Is(err, openfoodfacts.ErrNotFound):
+		log.Info("barcode not in product database")
+	case err != nil:
+		// Keep a stale answer rather than none while the API is unreachable.
+		log.Warn("barcode lookup failed", "error", err)
+		return cached
+	default:
+		p.Found, p.Name, p.Quantity = true, product.Name, product.Quantity
+	}
+	if s.barcodeCache != nil {
+		if err := s.barcodeCache.Put(ctx, p); err != nil {
+			log.Warn("failed to cache barcode lookup", "error", err)
+		}
+	}
+	return &p
+}
+
+// validBarcode reports whether code is an 8 to 14 digit product code.
+func validBarcode(code string) bool {
+	if len(code) < 8 || len(code) > 14 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
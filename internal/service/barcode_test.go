@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/openfoodfacts"
+	"github.com/vbonduro/kitchinv/internal/store"
+)
+
+// newFakeOFF serves Open Food Facts product lookups, knowing only
+// 3017620422003, and counts the requests it gets.
+func newFakeOFF(t *testing.T, status *atomic.Int32, calls *atomic.Int32) *openfoodfacts.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if code := status.Load(); code != 0 {
+			w.WriteHeader(int(code))
+			return
+		}
+		if r.URL.Path == "/api/v2/product/3017620422003.json" {
+			_, _ = w.Write([]byte(`{"status":1,"product":{"product_name":"Nutella","quantity":"400 g"}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"status":0}`))
+	}))
+	t.Cleanup(srv.Close)
+	return openfoodfacts.NewClient(srv.URL)
+}
+
+func TestCreateItemFromBarcode(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	var status, calls atomic.Int32
+	cache := store.NewBarcodeStore(svc.db)
+	svc.WithBarcodeLookup(newFakeOFF(t, &status, &calls), cache)
+	area, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+
+	item, err := svc.CreateItemFromBarcode(ctx, area.ID, " 3017620422003 ")
+	require.NoError(t, err)
+	assert.Equal(t, "Nutella", item.Name)
+	assert.Equal(t, "400 g", item.Quantity)
+	assert.Equal(t, area.ID, item.AreaID)
+
+	// A second scan is answered from the cache, even with the API down.
+	status.Store(http.StatusBadGateway)
+	item, err = svc.CreateItemFromBarcode(ctx, area.ID, "3017620422003")
+	require.NoError(t, err)
+	assert.Equal(t, "Nutella", item.Name)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// Unreachable and unknown products are named after the barcode.
+	item, err = svc.CreateItemFromBarcode(ctx, area.ID, "4006381333931")
+	require.NoError(t, err)
+	assert.Equal(t, "4006381333931", item.Name)
+	assert.Empty(t, item.Quantity)
+	p, err := cache.Get(ctx, "4006381333931")
+	require.NoError(t, err)
+	assert.Nil(t, p, "failed lookups aren't cached")
+
+	status.Store(0)
+	_, err = svc.CreateItemFromBarcode(ctx, area.ID, "4006381333931")
+	require.NoError(t, err)
+	p, err = cache.Get(ctx, "4006381333931")
+	require.NoError(t, err)
+	require.NotNil(t, p)
+	assert.False(t, p.Found, "unknown barcodes are cached as not found")
+}
+
+func TestCreateItemFromBarcode_StaleCache(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	var status, calls atomic.Int32
+	cache := store.NewBarcodeStore(svc.db)
+	svc.WithBarcodeLookup(newFakeOFF(t, &status, &calls), cache)
+	area, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	old := time.Now().Add(-2 * barcodeFoundTTL)
+	require.NoError(t, cache.Put(ctx, domain.BarcodeProduct{Barcode: "3017620422003", Name: "Old name", Found: true, FetchedAt: old}))
+
+	status.Store(http.StatusServiceUnavailable)
+	item, err := svc.CreateItemFromBarcode(ctx, area.ID, "3017620422003")
+	require.NoError(t, err)
+	assert.Equal(t, "Old name", item.Name, "a stale answer beats none")
+
+	status.Store(0)
+	item, err = svc.CreateItemFromBarcode(ctx, area.ID, "3017620422003")
+	require.NoError(t, err)
+	assert.Equal(t, "Nutella", item.Name, "a stale entry is refreshed")
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestCreateItemFromBarcode_Invalid(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	area, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+
+	for _, code := range []string{"", "1234567", "123456789012345", "40063813x3931"} {
+		_, err := svc.CreateItemFromBarcode(ctx, area.ID, code)
+		assert.ErrorIs(t, err, ErrInvalidBarcode, code)
+	}
+	_, err = svc.CreateItemFromBarcode(ctx, area.ID+1, "4006381333931")
+	assert.ErrorIs(t, err, ErrAreaNotFound)
+
+	item, err := svc.CreateItemFromBarcode(ctx, area.ID, "4006381333931")
+	require.NoError(t, err)
+	assert.Equal(t, "4006381333931", item.Name, "without a lookup the barcode is the name")
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Counts are the totals shown on the admin page: live areas, items in
+// stock, and photos of every area including deleted ones.
+type Counts struct {
+	Areas  int
+	Items  int
+	Photos int
+}
+
+// Counts totals the inventory. It needs WithDB.
+func (s *AreaService) Counts(ctx context.Context) (Counts, error) {
+	if s.db == nil {
+		return Counts{}, errors.New("counts need a database")
+	}
+	var c Counts
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM areas WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM items i JOIN areas a ON a.id = i.area_id
+			  WHERE i.status = 'active' AND a.deleted_at IS NULL),
+			(SELECT COUNT(*) FROM photos)`).Scan(&c.Areas, &c.Items, &c.Photos)
+	if err != nil {
+		return Counts{}, fmt.Errorf("failed to count inventory: %w", err)
+	}
+	return c, nil
+}
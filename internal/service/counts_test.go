@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounts(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	fridge, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	pantry, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, fridge.ID, "Milk", "1")
+	require.NoError(t, err)
+	eggs, err := svc.CreateItem(ctx, fridge.ID, "Eggs", "6")
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, pantry.ID, "Rice", "1")
+	require.NoError(t, err)
+	_, _, err = svc.UploadPhoto(ctx, fridge.ID, twoTonePNG(t), "image/png")
+	require.NoError(t, err)
+
+	_, err = svc.ConsumeItem(ctx, fridge.ID, eggs.ID)
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteArea(ctx, pantry.ID))
+
+	c, err := svc.Counts(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Counts{Areas: 1, Items: 1, Photos: 1}, c)
+}
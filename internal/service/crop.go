@@ -0,0 +1,189 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // decoders for image.Decode
+	"image/jpeg"
+	_ "image/png"
+	"math"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// Item thumbnails are cut from the photo around each item's first bounding
+// box, padded a little so the item isn't clipped, and scaled down.
+const (
+	cropMaxSide = 160  // longest side of a thumbnail, in pixels
+	cropPadding = 0.08 // margin added on each side, as a fraction of the box
+	cropQuality = 80   // JPEG quality
+
+	// cropMaxPixels bounds the photos thumbnails are cut from, since the
+	// whole image is decoded into memory first. It allows a 48-megapixel
+	// phone photo.
+	cropMaxPixels = 50_000_000
+)
+
+// cropItems saves a thumbnail for every item detected in photo that has a
+// bounding box, cut from imageData, and sets its CropKey. Backends that give
+// no boxes, formats the standard library can't decode (WebP, HEIC) and
+// photos over cropMaxPixels get no thumbnails. Failures are logged and leave
+// the item without one.
+func (s *AreaService) cropItems(ctx context.Context, photo *domain.Photo, imageData []byte, items []*domain.Item) {
+	var boxed []*domain.Item
+	for _, it := range items {
+		if len(it.BBoxes) > 0 && it.PhotoID != nil && *it.PhotoID == photo.ID {
+			boxed = append(boxed, it)
+		}
+	}
+	if len(boxed) == 0 {
+		return
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(imageData))
+	if err != nil {
+		s.log(ctx).Debug("photo not decodable, skipping item thumbnails", "photo_id", photo.ID, "mime_type", photo.MimeType, "error", err)
+		return
+	}
+	if cfg.Width*cfg.Height > cropMaxPixels {
+		s.log(ctx).Warn("photo too large to cut item thumbnails from", "photo_id", photo.ID, "width", cfg.Width, "height", cfg.Height)
+		return
+	}
+	img, _, err := image.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		s.log(ctx).Debug("photo not decodable, skipping item thumbnails", "photo_id", photo.ID, "mime_type", photo.MimeType, "error", err)
+		return
+	}
+
+	s.photoFilesMu.Lock()
+	defer s.photoFilesMu.Unlock()
+	for _, it := range boxed {
+		data, err := cropThumbnail(img, it.BBoxes[0])
+		if err != nil {
+			s.log(ctx).Debug("skipping item thumbnail", "item_id", it.ID, "error", err)
+			continue
+		}
+		key, err := s.photoStg.Save(ctx, "image/jpeg", bytes.NewReader(data))
+		if err != nil {
+			s.log(ctx).Error("failed to save item thumbnail", "item_id", it.ID, "error", err)
+			continue
+		}
+		if err := s.itemStore.SetCropKey(ctx, it.ID, key); err != nil {
+			s.log(ctx).Error("failed to record item thumbnail", "item_id", it.ID, "error", err)
+			s.deleteCropFileIfUnused(context.WithoutCancel(ctx), key)
+			continue
+		}
+		it.CropKey = key
+	}
+}
+
+// releaseCrops removes the thumbnails of items that have been deleted once
+// nothing refers to them any more. Thumbnails are stored by content, so
+// passing items that still exist, or that share a thumbnail, is harmless.
+func (s *AreaService) releaseCrops(ctx context.Context, items []*domain.Item) {
+	s.photoFilesMu.Lock()
+	defer s.photoFilesMu.Unlock()
+	released := make(map[string]bool)
+	for _, it := range items {
+		if it.CropKey != "" && !released[it.CropKey] {
+			released[it.CropKey] = true
+			s.deleteCropFileIfUnused(ctx, it.CropKey)
+		}
+	}
+}
+
+// deleteCropFileIfUnused deletes the thumbnail file behind key unless an item
+// or photo still refers to it. The caller holds photoFilesMu.
+func (s *AreaService) deleteCropFileIfUnused(ctx context.Context, key string) {
+	items, err := s.itemStore.CountByCropKey(ctx, key)
+	if err != nil {
+		s.log(ctx).Error("failed to count thumbnail references", "storage_key", key, "error", err)
+		return
+	}
+	photos, err := s.photoStore.CountByStorageKey(ctx, key)
+	if err != nil {
+		s.log(ctx).Error("failed to count photo references", "storage_key", key, "error", err)
+		return
+	}
+	if items+photos > 0 {
+		return
+	}
+	if err := s.photoStg.Delete(ctx, key); err != nil {
+		s.log(ctx).Error("failed to delete item thumbnail", "storage_key", key, "error", err)
+	}
+}
+
+// cropThumbnail cuts the normalized [x1, y1, x2, y2] box out of img, padded
+// by cropPadding, and returns it as a JPEG no larger than cropMaxSide.
+func cropThumbnail(img image.Image, box []float64) ([]byte, error) {
+	if len(box) != 4 {
+		return nil, fmt.Errorf("bounding box has %d values, want 4", len(box))
+	}
+	x1, x2 := min(box[0], box[2]), max(box[0], box[2])
+	y1, y2 := min(box[1], box[3]), max(box[1], box[3])
+	padX, padY := (x2-x1)*cropPadding, (y2-y1)*cropPadding
+	x1, x2 = clamp01(x1-padX), clamp01(x2+padX)
+	y1, y2 = clamp01(y1-padY), clamp01(y2+padY)
+
+	b := img.Bounds()
+	w, h := float64(b.Dx()), float64(b.Dy())
+	src := image.Rect(
+		b.Min.X+int(x1*w), b.Min.Y+int(y1*h),
+		b.Min.X+int(math.Ceil(x2*w)), b.Min.Y+int(math.Ceil(y2*h)),
+	).Intersect(b)
+	if src.Dx() < 2 || src.Dy() < 2 {
+		return nil, errors.New("bounding box is too small")
+	}
+	return scaleToJPEG(img, src, cropMaxSide)
+}
+
+// scaleToJPEG scales the src region of img down so its longest side is at
+// most maxSide and returns it as a JPEG.
+func scaleToJPEG(img image.Image, src image.Rectangle, maxSide int) ([]byte, error) {
+	scale := min(1, float64(maxSide)/float64(max(src.Dx(), src.Dy())))
+	dw := max(1, int(math.Round(float64(src.Dx())*scale)))
+	dh := max(1, int(math.Round(float64(src.Dy())*scale)))
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	resample(dst, img, src)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: cropQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// resample scales the src region of img into dst, averaging a grid of up to
+// 4×4 samples per destination pixel. That is plenty for thumbnails and keeps
+// the cost independent of the photo's resolution.
+func resample(dst *image.RGBA, img image.Image, src image.Rectangle) {
+	const grid = 4
+	sx := float64(src.Dx()) / float64(dst.Rect.Dx())
+	sy := float64(src.Dy()) / float64(dst.Rect.Dy())
+	nx, ny := min(grid, max(1, int(math.Ceil(sx)))), min(grid, max(1, int(math.Ceil(sy))))
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			var r, g, b, a uint32
+			for j := 0; j < ny; j++ {
+				py := src.Min.Y + int((float64(y)+(float64(j)+0.5)/float64(ny))*sy)
+				for i := 0; i < nx; i++ {
+					px := src.Min.X + int((float64(x)+(float64(i)+0.5)/float64(nx))*sx)
+					cr, cg, cb, ca := img.At(min(px, src.Max.X-1), min(py, src.Max.Y-1)).RGBA()
+					r, g, b, a = r+cr, g+cg, b+cb, a+ca
+				}
+			}
+			n := uint32(nx * ny)
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(b / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+}
+
+func clamp01(v float64) float64 {
+	return min(1, max(0, v))
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+// twoTonePNG is a 400×200 image, red on the left half and blue on the right.
+func twoTonePNG(t *testing.T) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 200 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestUploadPhotoCropsItems(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	photos := newStubPhotoStore()
+	svc.photoStg = photos
+	svc.visionAPI = &stubVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{
+		{Name: "Tomato", Quantity: "1", BBox: &[4]float64{0.1, 0.1, 0.4, 0.9}},
+		{Name: "Blueberries", Quantity: "1", BBox: &[4]float64{0.6, 0.2, 0.9, 0.8}},
+		{Name: "Salt", Quantity: "1"},
+	}}}
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+
+	_, items, err := svc.UploadPhoto(ctx, area.ID, twoTonePNG(t), "image/png")
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	byName := make(map[string]*domain.Item)
+	for _, it := range items {
+		byName[it.Name] = it
+	}
+	assert.Empty(t, byName["Salt"].CropKey, "no box, no thumbnail")
+	require.NotEmpty(t, byName["Tomato"].CropKey)
+
+	stored, err := svc.GetItem(ctx, area.ID, byName["Tomato"].ID)
+	require.NoError(t, err)
+	assert.Equal(t, byName["Tomato"].CropKey, stored.CropKey)
+
+	thumb, err := jpeg.Decode(bytes.NewReader(photos.saved[stored.CropKey]))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, max(thumb.Bounds().Dx(), thumb.Bounds().Dy()), cropMaxSide)
+	r, _, b, _ := thumb.At(thumb.Bounds().Dx()/2, thumb.Bounds().Dy()/2).RGBA()
+	assert.Greater(t, r, b, "the tomato's box is on the red half")
+	thumb, err = jpeg.Decode(bytes.NewReader(photos.saved[byName["Blueberries"].CropKey]))
+	require.NoError(t, err)
+	r, _, b, _ = thumb.At(thumb.Bounds().Dx()/2, thumb.Bounds().Dy()/2).RGBA()
+	assert.Greater(t, b, r, "the blueberries' box is on the blue half")
+
+	other, err := svc.GetItem(ctx, area.ID+1, byName["Tomato"].ID)
+	require.NoError(t, err)
+	assert.Nil(t, other, "items are looked up within their area")
+
+	require.NoError(t, svc.DeleteItem(ctx, byName["Tomato"].ID))
+	assert.NotContains(t, photos.saved, byName["Tomato"].CropKey, "a deleted item's thumbnail is removed")
+
+	require.NoError(t, svc.DeletePhoto(ctx, area.ID))
+	assert.Empty(t, photos.saved, "deleting the photo removes its items' thumbnails")
+}
+
+func TestUploadPhotoReplacesCrops(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	photos := newStubPhotoStore()
+	svc.photoStg = photos
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	img := twoTonePNG(t)
+
+	svc.visionAPI = &stubVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{
+		{Name: "Tomato", Quantity: "1", BBox: &[4]float64{0.1, 0.1, 0.4, 0.9}},
+	}}}
+	_, first, err := svc.UploadPhoto(ctx, area.ID, img, "image/png")
+	require.NoError(t, err)
+
+	// Re-analysing gives the same box, so the same thumbnail file, which
+	// must survive the old item being replaced.
+	_, second, err := svc.UploadPhotoWithOptions(ctx, area.ID, img, "image/png", UploadOptions{Force: true})
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	assert.NotEqual(t, first[0].ID, second[0].ID)
+	assert.Equal(t, first[0].CropKey, second[0].CropKey)
+	assert.Contains(t, photos.saved, second[0].CropKey)
+
+	svc.visionAPI = &stubVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{
+		{Name: "Blueberries", Quantity: "1", BBox: &[4]float64{0.6, 0.2, 0.9, 0.8}},
+	}}}
+	_, third, err := svc.UploadPhotoWithOptions(ctx, area.ID, img, "image/png", UploadOptions{Force: true})
+	require.NoError(t, err)
+	assert.NotContains(t, photos.saved, first[0].CropKey, "the replaced item's thumbnail is removed")
+	assert.Contains(t, photos.saved, third[0].CropKey)
+}
+
+func TestUploadPhotoSkipsCropsForHugePhotos(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	photos := newStubPhotoStore()
+	svc.photoStg = photos
+	svc.visionAPI = &stubVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{
+		{Name: "Tomato", Quantity: "1", BBox: &[4]float64{0.1, 0.1, 0.4, 0.9}},
+	}}}
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+
+	// Only the header claims the size: decoding would allocate the whole
+	// 20000×20000 image before finding the pixel data short.
+	img := twoTonePNG(t)
+	ihdr := img[12:29] // chunk type and data
+	binary.BigEndian.PutUint32(ihdr[4:], 20000)
+	binary.BigEndian.PutUint32(ihdr[8:], 20000)
+	binary.BigEndian.PutUint32(img[29:], crc32.ChecksumIEEE(ihdr))
+	cfg, err := png.DecodeConfig(bytes.NewReader(img))
+	require.NoError(t, err)
+	require.Greater(t, cfg.Width*cfg.Height, cropMaxPixels)
+
+	_, items, err := svc.UploadPhoto(ctx, area.ID, img, "image/png")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Empty(t, items[0].CropKey)
+	assert.Len(t, photos.saved, 1, "only the photo itself is stored")
+}
+
+func TestCropThumbnail(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1000, 500))
+
+	data, err := cropThumbnail(img, []float64{0, 0, 1, 1})
+	require.NoError(t, err)
+	thumb, err := jpeg.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, cropMaxSide, cropMaxSide/2), thumb.Bounds())
+
+	data, err = cropThumbnail(img, []float64{0.52, 0.6, 0.5, 0.5})
+	require.NoError(t, err, "corners in either order")
+	thumb, err = jpeg.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 24, 58), thumb.Bounds(), "small boxes aren't enlarged, only padded")
+
+	_, err = cropThumbnail(img, []float64{0.5, 0.5, 0.5, 0.5})
+	assert.Error(t, err)
+	_, err = cropThumbnail(img, []float64{0.5, 0.5})
+	assert.Error(t, err)
+}
+
+func TestPurgeDeletedAreasRemovesCrops(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	photos := newStubPhotoStore()
+	svc.photoStg = photos
+	svc.visionAPI = &stubVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{
+		{Name: "Tomato", Quantity: "1", BBox: &[4]float64{0.1, 0.1, 0.4, 0.9}},
+		{Name: "Blueberries", Quantity: "1", BBox: &[4]float64{0.6, 0.2, 0.9, 0.8}},
+	}}}
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	_, items, err := svc.UploadPhoto(ctx, area.ID, twoTonePNG(t), "image/png")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	_, err = svc.ConsumeItem(ctx, area.ID, items[0].ID)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.DeleteArea(ctx, area.ID))
+	n, err := svc.PurgeDeletedAreas(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	assert.Empty(t, photos.saved, "the consumed item's thumbnail is removed with the rest")
+}
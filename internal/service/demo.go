@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+//go:embed demodata
+var demoFS embed.FS
+
+// demoDataset is the shape of demodata/dataset.json.
+type demoDataset struct {
+	Areas []struct {
+		Name  string `json:"name"`
+		Photo string `json:"photo"`
+		Items []struct {
+			Name     string    `json:"name"`
+			Quantity string    `json:"quantity"`
+			BBox     []float64 `json:"bbox"`
+		} `json:"items"`
+	} `json:"areas"`
+}
+
+func loadDemoDataset() (*demoDataset, error) {
+	data, err := demoFS.ReadFile("demodata/dataset.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read demo dataset: %w", err)
+	}
+	var ds demoDataset
+	if err := json.Unmarshal(data, &ds); err != nil {
+		return nil, fmt.Errorf("failed to parse demo dataset: %w", err)
+	}
+	return &ds, nil
+}
+
+// SeedDemo loads the embedded demo dataset, registering its sample photos in
+// the photo store. Areas that already exist by name are left untouched, so
+// calling it repeatedly is safe. It returns how many areas were created.
+func (s *AreaService) SeedDemo(ctx context.Context) (int, error) {
+	ds, err := loadDemoDataset()
+	if err != nil {
+		return 0, err
+	}
+
+	existing, err := s.areaStore.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list areas: %w", err)
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, a := range existing {
+		seen[a.Name] = true
+	}
+
+	created := 0
+	for _, da := range ds.Areas {
+		if seen[da.Name] {
+			continue
+		}
+		area, err := s.areaStore.Create(ctx, da.Name)
+		if err != nil {
+			return created, fmt.Errorf("failed to create demo area %q: %w", da.Name, err)
+		}
+		created++
+
+		var photoID *int64
+		if da.Photo != "" {
+			photo, err := s.seedDemoPhoto(ctx, area.ID, da.Photo)
+			if err != nil {
+				return created, err
+			}
+			photoID = &photo.ID
+		}
+
+		for _, it := range da.Items {
+			var bboxes [][]float64
+			if len(it.BBox) == 4 {
+				bboxes = [][]float64{it.BBox}
+			}
+			if _, err := s.itemStore.Create(ctx, area.ID, photoID, it.Name, it.Quantity, string(domain.ItemSourceAI), bboxes); err != nil {
+				return created, fmt.Errorf("failed to create demo item %q: %w", it.Name, err)
+			}
+		}
+	}
+	return created, nil
+}
+
+// seedDemoPhoto copies an embedded sample photo into the photo store and
+// records it as an already-analysed upload for areaID.
+func (s *AreaService) seedDemoPhoto(ctx context.Context, areaID int64, name string) (*domain.Photo, error) {
+	data, err := demoFS.ReadFile(path.Join("demodata", name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read demo photo %q: %w", name, err)
+	}
+	photo, err := s.storePhoto(ctx, areaID, http.DetectContentType(data), data, contentHash(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to store demo photo %q: %w", name, err)
+	}
+	s.setAnalysisStatus(ctx, photo, domain.PhotoAnalysisComplete, "")
+	return photo, nil
+}
+
+// ResetDemo discards every area, including ones in the trash, along with their
+// photos, and reseeds the demo dataset.
+func (s *AreaService) ResetDemo(ctx context.Context) error {
+	areas, err := s.areaStore.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list areas: %w", err)
+	}
+	for _, a := range areas {
+		if err := s.areaStore.Delete(ctx, a.ID); err != nil {
+			return fmt.Errorf("failed to delete area %d: %w", a.ID, err)
+		}
+	}
+	// deleted_at has one-second resolution; look slightly ahead so the rows
+	// just deleted above are included.
+	if _, err := s.PurgeDeletedAreas(ctx, time.Now().Add(time.Minute)); err != nil {
+		return err
+	}
+	_, err = s.SeedDemo(ctx)
+	return err
+}
+
+// RunDemoResetLoop resets the demo data every interval until ctx is cancelled.
+// Unlike RunPurgeLoop it does not run immediately; the caller seeds at startup.
+// A reset under way when ctx is cancelled still finishes, as one stopped
+// halfway would leave the demo without its areas.
+func (s *AreaService) RunDemoResetLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.ResetDemo(context.WithoutCancel(ctx)); err != nil {
+			s.log(ctx).Error("demo reset failed", "error", err)
+		} else {
+			s.log(ctx).Info("demo data reset")
+		}
+	}
+}
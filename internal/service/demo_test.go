@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/db"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/store"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+func newDemoTestService(t *testing.T) (*AreaService, *stubPhotoStore) {
+	t.Helper()
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	photoStg := newStubPhotoStore()
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		&stubVision{result: &vision.AnalysisResult{}},
+		photoStg,
+		slog.Default(),
+	).WithDB(d)
+	return svc, photoStg
+}
+
+// demoInventory flattens the current areas and items into sorted
+// "area/item=qty" strings so two states can be compared regardless of IDs.
+func demoInventory(t *testing.T, svc *AreaService) []string {
+	t.Helper()
+	summaries, err := svc.ListAreaSummaries(context.Background())
+	require.NoError(t, err)
+	var out []string
+	for _, sum := range summaries {
+		out = append(out, sum.Area.Name+"/")
+		_, items, _, err := svc.GetAreaWithItems(context.Background(), sum.Area.ID)
+		require.NoError(t, err)
+		for _, it := range items {
+			out = append(out, sum.Area.Name+"/"+it.Name+"="+it.Quantity)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestAreaServiceSeedDemo_Idempotent(t *testing.T) {
+	svc, photoStg := newDemoTestService(t)
+	ctx := context.Background()
+
+	ds, err := loadDemoDataset()
+	require.NoError(t, err)
+
+	created, err := svc.SeedDemo(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(ds.Areas), created)
+	seeded := demoInventory(t, svc)
+	assert.Contains(t, seeded, "Fridge/Whole Milk=2")
+	photos := len(photoStg.saved)
+	assert.NotZero(t, photos)
+
+	created, err = svc.SeedDemo(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, created)
+	assert.Equal(t, seeded, demoInventory(t, svc))
+	assert.Len(t, photoStg.saved, photos, "reseeding must not register photos again")
+
+	// Seeded photos are complete, not left in the analysing state.
+	summaries, err := svc.ListAreaSummaries(ctx)
+	require.NoError(t, err)
+	for _, sum := range summaries {
+		if sum.Photo != nil {
+			assert.Equal(t, domain.PhotoAnalysisComplete, sum.Photo.AnalysisStatus, sum.Area.Name)
+		}
+	}
+}
+
+func TestAreaServiceResetDemo_RestoresModifiedData(t *testing.T) {
+	svc, photoStg := newDemoTestService(t)
+	ctx := context.Background()
+
+	_, err := svc.SeedDemo(ctx)
+	require.NoError(t, err)
+	seeded := demoInventory(t, svc)
+	photos := len(photoStg.saved)
+
+	summaries, err := svc.ListAreaSummaries(ctx)
+	require.NoError(t, err)
+	_, err = svc.UpdateArea(ctx, summaries[0].Area.ID, "Renamed")
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteItem(ctx, summaries[1].Preview[0].ID))
+	require.NoError(t, svc.DeleteArea(ctx, summaries[2].Area.ID))
+	_, err = svc.CreateArea(ctx, "Visitor Area")
+	require.NoError(t, err)
+	require.NotEqual(t, seeded, demoInventory(t, svc))
+
+	require.NoError(t, svc.ResetDemo(ctx))
+	assert.Equal(t, seeded, demoInventory(t, svc))
+	assert.Len(t, photoStg.saved, photos, "old demo photos must be purged")
+}
+
+func TestAreaServiceRunDemoResetLoop(t *testing.T) {
+	svc, _ := newDemoTestService(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	_, err := svc.SeedDemo(ctx)
+	require.NoError(t, err)
+	seeded := demoInventory(t, svc)
+
+	_, err = svc.CreateArea(ctx, "Visitor Area")
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		svc.RunDemoResetLoop(ctx, 20*time.Millisecond)
+		close(done)
+	}()
+
+	// The inventory can only be compared between resets, so wait for the
+	// visitor's area to go and then stop the loop.
+	assert.Eventually(t, func() bool {
+		areas, err := svc.ListAreas(ctx)
+		if err != nil {
+			return false
+		}
+		for _, a := range areas {
+			if a.Name == "Visitor Area" {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 20*time.Millisecond)
+
+	cancel()
+	<-done
+	assert.Equal(t, seeded, demoInventory(t, svc))
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// QuantityChange is an item whose quantity differs between two inventories.
+type QuantityChange struct {
+	Name   string
+	Before string
+	After  string
+}
+
+// AreaDiff is how an area's inventory changed with its latest analysis.
+type AreaDiff struct {
+	AreaID  int64
+	PhotoID int64
+	// AnalysedAt is when the analysed photo was uploaded.
+	AnalysedAt time.Time
+	Added      []domain.SnapshotItem
+	Removed    []domain.SnapshotItem
+	Changed    []QuantityChange
+}
+
+// Empty reports whether nothing changed.
+func (d *AreaDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffLatestAnalysis compares the items an area held before its latest
+// photo was analysed with the items it holds now. Items are matched by name,
+// ignoring case and spacing, so corrections made since the analysis are part
+// of the diff. It returns ErrAreaNotFound for a missing area and ErrNoPhoto
+// when the area has no analysed photo.
+func (s *AreaService) DiffLatestAnalysis(ctx context.Context, areaID int64) (*AreaDiff, error) {
+	area, err := s.areaStore.GetByID(ctx, areaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get area: %w", err)
+	}
+	if area == nil {
+		return nil, ErrAreaNotFound
+	}
+	photo, err := s.photoStore.GetLatestByAreaID(ctx, areaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest photo: %w", err)
+	}
+	if photo == nil || photo.AnalysisStatus != domain.PhotoAnalysisComplete {
+		return nil, ErrNoPhoto
+	}
+
+	// No snapshot means the area was empty before the analysis.
+	snap, err := s.snapshotStore.GetByPhotoID(ctx, photo.ID)
+	if err != nil {
+		return nil, err
+	}
+	var before []domain.SnapshotItem
+	if snap != nil {
+		before = snap.Items
+	}
+	items, err := s.itemStore.ListByAreaID(ctx, areaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+	after := make([]domain.SnapshotItem, len(items))
+	for i, it := range items {
+		after[i] = domain.SnapshotItem{Name: it.Name, Quantity: it.Quantity}
+	}
+
+	d := diffInventories(before, after)
+	d.AreaID, d.PhotoID, d.AnalysedAt = areaID, photo.ID, photo.UploadedAt
+	return d, nil
+}
+
+// diffInventories lists the items only in after, those only in before, and
+// those in both whose quantity changed. The lists are never nil, so they
+// encode as empty JSON arrays.
+func diffInventories(before, after []domain.SnapshotItem) *AreaDiff {
+	d := &AreaDiff{Added: []domain.SnapshotItem{}, Removed: []domain.SnapshotItem{}, Changed: []QuantityChange{}}
+	prev := make(map[string]domain.SnapshotItem, len(before))
+	for _, it := range before {
+		if _, ok := prev[diffKey(it.Name)]; !ok {
+			prev[diffKey(it.Name)] = it
+		}
+	}
+	seen := make(map[string]bool, len(after))
+	for _, it := range after {
+		key := diffKey(it.Name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		old, ok := prev[key]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, it)
+		case !strings.EqualFold(strings.TrimSpace(old.Quantity), strings.TrimSpace(it.Quantity)):
+			d.Changed = append(d.Changed, QuantityChange{Name: it.Name, Before: old.Quantity, After: it.Quantity})
+		}
+	}
+	for _, it := range before {
+		key := diffKey(it.Name)
+		if !seen[key] {
+			seen[key] = true
+			d.Removed = append(d.Removed, it)
+		}
+	}
+	return d
+}
+
+// diffKey normalises an item name for matching: lower case, with runs of
+// whitespace collapsed.
+func diffKey(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
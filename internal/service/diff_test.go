@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+func TestDiffLatestAnalysis(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	svc.visionAPI = &sequenceVision{results: []*vision.AnalysisResult{
+		{Items: []vision.DetectedItem{{Name: "Milk", Quantity: "2"}, {Name: "Eggs", Quantity: "12"}, {Name: "Jam", Quantity: "1"}}},
+		{Items: []vision.DetectedItem{{Name: "milk ", Quantity: "3"}, {Name: "Eggs", Quantity: "12"}, {Name: "Butter", Quantity: "1"}}},
+	}}
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+
+	_, err = svc.DiffLatestAnalysis(ctx, area.ID)
+	assert.ErrorIs(t, err, ErrNoPhoto)
+	_, err = svc.DiffLatestAnalysis(ctx, area.ID+1)
+	assert.ErrorIs(t, err, ErrAreaNotFound)
+
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
+	require.NoError(t, err)
+	d, err := svc.DiffLatestAnalysis(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Len(t, d.Added, 3, "everything is new on the first analysis")
+	assert.Empty(t, d.Removed)
+
+	photo, _, err := svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x02}, "image/jpeg")
+	require.NoError(t, err)
+	d, err = svc.DiffLatestAnalysis(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Equal(t, photo.ID, d.PhotoID)
+	assert.Equal(t, []domain.SnapshotItem{{Name: "Butter", Quantity: "1"}}, d.Added)
+	assert.Equal(t, []domain.SnapshotItem{{Name: "Jam", Quantity: "1"}}, d.Removed)
+	assert.Equal(t, []QuantityChange{{Name: "milk", Before: "2", After: "3"}}, d.Changed)
+	assert.False(t, d.Empty())
+}
+
+func TestDiffInventories(t *testing.T) {
+	d := diffInventories(
+		[]domain.SnapshotItem{{Name: "Oat  Milk", Quantity: "1 carton"}, {Name: "Eggs", Quantity: "6"}},
+		[]domain.SnapshotItem{{Name: "oat milk", Quantity: "1 Carton"}, {Name: "Eggs", Quantity: "6"}},
+	)
+	assert.True(t, d.Empty(), "names match ignoring case and spacing, quantities ignoring case")
+	assert.NotNil(t, d.Added)
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// ShelfLife estimates how long items whose name contains Keyword keep once
+// added. Items have no recorded expiry dates, so the calendar feed uses these
+// estimates.
+type ShelfLife struct {
+	Keyword string
+	Days    int
+}
+
+// ExpiringItem is an item with its estimated expiry date.
+type ExpiringItem struct {
+	*domain.RecentItem
+	ShelfLife ShelfLife
+	// ExpiresOn is midnight of the expiry day in the requested location.
+	ExpiresOn time.Time
+	// Sequence goes up each time ExpiresOn changes, for calendar clients.
+	Sequence int
+}
+
+// expiryEventRepository is the subset of store.ExpiryEventStore that
+// AreaService requires.
+type expiryEventRepository interface {
+	Sync(ctx context.Context, dates map[int64]string) (map[int64]int, error)
+}
+
+// ParseShelfLife parses "keyword=days" entries such as "milk=7d" or
+// "cream cheese=14". The "d" suffix is optional.
+func ParseShelfLife(entries []string) ([]ShelfLife, error) {
+	rules := make([]ShelfLife, 0, len(entries))
+	for _, e := range entries {
+		kw, days, ok := strings.Cut(e, "=")
+		kw = strings.Join(nameWords(kw), " ")
+		if !ok || kw == "" {
+			return nil, fmt.Errorf("invalid shelf life %q: want keyword=days", e)
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(days), "d"))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid shelf life %q: days must be a whole number", e)
+		}
+		rules = append(rules, ShelfLife{Keyword: kw, Days: n})
+	}
+	return rules, nil
+}
+
+// WithShelfLife sets the rules used to estimate expiry dates. Without any,
+// no item is listed as expiring.
+func (s *AreaService) WithShelfLife(rules []ShelfLife) *AreaService {
+	s.shelfLife = rules
+	return s
+}
+
+// WithExpiryEvents sets where the calendar event sequence of each item's
+// expiry date is kept. Without it every event has sequence 0.
+func (s *AreaService) WithExpiryEvents(st expiryEventRepository) *AreaService {
+	s.expiryEvents = st
+	return s
+}
+
+// ListExpiringItems returns the items a shelf-life rule applies to, soonest
+// expiry first, with dates counted from the day each item was added in loc.
+// Items with no matching rule, or a quantity of zero, are left out.
+func (s *AreaService) ListExpiringItems(ctx context.Context, loc *time.Location) ([]*ExpiringItem, error) {
+	if len(s.shelfLife) == 0 {
+		return nil, nil
+	}
+	items, err := s.itemStore.ListRecent(ctx, time.Time{}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiring []*ExpiringItem
+	dates := make(map[int64]string)
+	for _, it := range items {
+		if isOutOfStock(&it.Item) {
+			continue
+		}
+		rule, ok := matchShelfLife(s.shelfLife, it.Name)
+		if !ok {
+			continue
+		}
+		y, m, d := it.CreatedAt.In(loc).Date()
+		e := &ExpiringItem{RecentItem: it, ShelfLife: rule, ExpiresOn: time.Date(y, m, d+rule.Days, 0, 0, 0, 0, loc)}
+		expiring = append(expiring, e)
+		dates[it.ID] = e.ExpiresOn.Format(time.DateOnly)
+	}
+
+	if s.expiryEvents != nil && len(dates) > 0 {
+		seqs, err := s.expiryEvents.Sync(ctx, dates)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range expiring {
+			e.Sequence = seqs[e.ID]
+		}
+	}
+
+	sort.SliceStable(expiring, func(i, j int) bool {
+		if !expiring[i].ExpiresOn.Equal(expiring[j].ExpiresOn) {
+			return expiring[i].ExpiresOn.Before(expiring[j].ExpiresOn)
+		}
+		return expiring[i].ID < expiring[j].ID
+	})
+	return expiring, nil
+}
+
+// matchShelfLife returns the rule for name: the one whose keyword has the
+// most words among those found in name, the earliest on ties. Keywords match
+// whole words, and their last word also matches its plural: "berry" matches
+// "Mixed berries" and "egg" matches "Free-range eggs" but not "Eggplant".
+func matchShelfLife(rules []ShelfLife, name string) (ShelfLife, bool) {
+	words := nameWords(name)
+	var best ShelfLife
+	bestLen := 0
+	for _, r := range rules {
+		kw := strings.Fields(r.Keyword)
+		if len(kw) > bestLen && containsWords(words, kw) {
+			best, bestLen = r, len(kw)
+		}
+	}
+	return best, bestLen > 0
+}
+
+// nameWords lower-cases s and splits it into words of letters and digits.
+func nameWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// containsWords reports whether kw appears as consecutive words in words.
+func containsWords(words, kw []string) bool {
+	for i := 0; i+len(kw) <= len(words); i++ {
+		match := true
+		for j, k := range kw {
+			w := words[i+j]
+			if w != k && (j < len(kw)-1 || !isPlural(w, k)) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// isPlural reports whether w is a regular English plural of singular.
+func isPlural(w, singular string) bool {
+	if w == singular+"s" || w == singular+"es" {
+		return true
+	}
+	stem, ok := strings.CutSuffix(singular, "y")
+	return ok && w == stem+"ies"
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/store"
+)
+
+func TestParseShelfLife(t *testing.T) {
+	rules, err := ParseShelfLife([]string{"milk=7d", " Cream  Cheese = 14 "})
+	require.NoError(t, err)
+	assert.Equal(t, []ShelfLife{{Keyword: "milk", Days: 7}, {Keyword: "cream cheese", Days: 14}}, rules)
+
+	for _, bad := range []string{"milk", "=7d", "milk=week", "milk=-1d"} {
+		_, err := ParseShelfLife([]string{bad})
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestMatchShelfLife(t *testing.T) {
+	rules := []ShelfLife{{"cheese", 28}, {"cream", 7}, {"cream cheese", 14}, {"egg", 21}, {"berry", 5}}
+	for name, want := range map[string]int{
+		"Cheddar cheese":     28,
+		"Sour cream":         7,
+		"Cream cheese":       14,
+		"Free-range EGGS":    21,
+		"Mixed berries":      5,
+		"Philadelphia cream": 7,
+	} {
+		rule, ok := matchShelfLife(rules, name)
+		assert.True(t, ok, name)
+		assert.Equal(t, want, rule.Days, name)
+	}
+	for _, name := range []string{"Eggplant", "Ice-creamery", "Cheesecake"} {
+		_, ok := matchShelfLife(rules, name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestListExpiringItems(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	svc.WithShelfLife([]ShelfLife{{"milk", 7}, {"egg", 28}}).WithExpiryEvents(store.NewExpiryEventStore(svc.db))
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	milk, err := svc.CreateItem(ctx, area.ID, "Milk", "1")
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, area.ID, "Eggs", "0") // none left
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, area.ID, "Ketchup", "1") // no rule
+	require.NoError(t, err)
+
+	loc := time.FixedZone("UTC+10", 10*3600)
+	items, err := svc.ListExpiringItems(ctx, loc)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	got := items[0]
+	assert.Equal(t, milk.ID, got.ID)
+	assert.Equal(t, "Fridge", got.AreaName)
+	y, m, d := milk.CreatedAt.In(loc).Date()
+	assert.Equal(t, time.Date(y, m, d+7, 0, 0, 0, 0, loc), got.ExpiresOn)
+	assert.Zero(t, got.Sequence)
+
+	items, err = svc.ListExpiringItems(ctx, loc)
+	require.NoError(t, err)
+	assert.Zero(t, items[0].Sequence, "an unchanged date keeps its sequence")
+
+	svc.WithShelfLife([]ShelfLife{{"milk", 10}})
+	items, err = svc.ListExpiringItems(ctx, loc)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(y, m, d+10, 0, 0, 0, 0, loc), items[0].ExpiresOn)
+	assert.Equal(t, 1, items[0].Sequence, "a changed date bumps the sequence")
+
+	svc.WithShelfLife(nil)
+	items, err = svc.ListExpiringItems(ctx, loc)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
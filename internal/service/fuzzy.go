@@ -0,0 +1,152 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/textfold"
+)
+
+const (
+	// fuzzyThreshold is the similarity a name needs to count as a close
+	// match. One typo in a six-letter word scores about 0.83, two score 0.67.
+	fuzzyThreshold = 0.75
+	// maxFuzzyResults caps the close matches returned.
+	maxFuzzyResults = 10
+)
+
+// FuzzyMatch is an item whose name is close to, but doesn't contain, what
+// was searched for.
+type FuzzyMatch struct {
+	Item       *domain.Item
+	Similarity float64 // 1 is identical
+}
+
+// SearchItemsFuzzy returns active items whose names are within a typo or
+// two of query, best first, for when the exact search finds little:
+// "yogurt" finds "Yoghurt" and "tomatos" finds "Tomatoes". Candidates are
+// the names with a word starting with the query's first letter, so a typo
+// in the first letter isn't forgiven; that keeps the list short enough to
+// score every name in Go.
+func (s *AreaService) SearchItemsFuzzy(ctx context.Context, query string, filter domain.SearchFilter) ([]FuzzyMatch, error) {
+	query = textfold.Fold(strings.Join(strings.Fields(query), " "))
+	first, _ := utf8.DecodeRuneInString(query)
+	if !unicode.IsLetter(first) && !unicode.IsDigit(first) {
+		return nil, nil
+	}
+	candidates, err := s.itemStore.SearchWordPrefix(ctx, string(first), filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fuzzy candidates: %w", err)
+	}
+	return rankFuzzy(query, candidates), nil
+}
+
+// rankFuzzy scores each item's name against query, which must be folded
+// with textfold.Fold, and keeps the best maxFuzzyResults at or above fuzzyThreshold.
+func rankFuzzy(query string, items []*domain.Item) []FuzzyMatch {
+	q := []rune(query)
+	qWords := len(strings.Fields(query))
+	var d distance
+	var matches []FuzzyMatch
+	for _, it := range items {
+		if sim := d.nameSimilarity(q, qWords, it.Name); sim >= fuzzyThreshold {
+			matches = append(matches, FuzzyMatch{Item: it, Similarity: sim})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b FuzzyMatch) int {
+		return cmp.Compare(b.Similarity, a.Similarity)
+	})
+	if len(matches) > maxFuzzyResults {
+		matches = matches[:maxFuzzyResults]
+	}
+	return matches
+}
+
+// distance computes edit distances, reusing its buffers between names so
+// scoring thousands of them allocates next to nothing.
+type distance struct {
+	prev, cur []int
+	name      []rune
+	starts    []int // where each word of name starts
+	ends      []int // and ends
+}
+
+// nameSimilarity is the best similarity of q against the whole name or any
+// run of as many consecutive words as q has, so "yogurt" matches "Greek
+// yoghurt" as well as "Yoghurt". Case and diacritics are ignored.
+func (d *distance) nameSimilarity(q []rune, qWords int, name string) float64 {
+	d.name, d.starts, d.ends = d.name[:0], d.starts[:0], d.ends[:0]
+	inWord := false
+	for _, r := range textfold.Fold(name) {
+		if unicode.IsSpace(r) {
+			if inWord {
+				d.ends = append(d.ends, len(d.name))
+			}
+			inWord = false
+		} else if !inWord {
+			d.starts = append(d.starts, len(d.name))
+			inWord = true
+		}
+		d.name = append(d.name, r)
+	}
+	if inWord {
+		d.ends = append(d.ends, len(d.name))
+	}
+	if len(d.starts) == 0 {
+		return 0
+	}
+	best := d.similarity(q, d.name[d.starts[0]:d.ends[len(d.ends)-1]])
+	for i := 0; i+qWords <= len(d.starts) && qWords < len(d.starts); i++ {
+		best = max(best, d.similarity(q, d.name[d.starts[i]:d.ends[i+qWords-1]]))
+	}
+	return best
+}
+
+// similarity is 1 minus the Levenshtein distance between a and b over the
+// longer one's length. Strings whose lengths alone put them below
+// fuzzyThreshold score 0 without computing the distance.
+func (d *distance) similarity(a, b []rune) float64 {
+	n := max(len(a), len(b))
+	if n == 0 {
+		return 1
+	}
+	if float64(abs(len(a)-len(b))) > (1-fuzzyThreshold)*float64(n) {
+		return 0
+	}
+	return 1 - float64(d.levenshtein(a, b))/float64(n)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (d *distance) levenshtein(a, b []rune) int {
+	if cap(d.prev) < len(b)+1 {
+		d.prev, d.cur = make([]int, len(b)+1), make([]int, len(b)+1)
+	}
+	prev, cur := d.prev[:len(b)+1], d.cur[:len(b)+1]
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
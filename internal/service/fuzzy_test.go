@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+func TestRankFuzzy(t *testing.T) {
+	names := []string{"Yoghurt", "Greek yoghurt", "Tomatoes", "Cherry tomatoes", "Tofu", "Milk", "Mild salsa", "Eggs"}
+	var items []*domain.Item
+	for i, n := range names {
+		items = append(items, &domain.Item{ID: int64(i + 1), Name: n})
+	}
+	matched := func(query string) []string {
+		var out []string
+		for _, m := range rankFuzzy(query, items) {
+			out = append(out, m.Item.Name)
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"Yoghurt", "Greek yoghurt"}, matched("yogurt"))
+	assert.Equal(t, []string{"Tomatoes", "Cherry tomatoes"}, matched("tomatos"))
+	assert.Equal(t, []string{"Greek yoghurt"}, matched("greek yogurt"))
+	assert.Equal(t, []string{"Eggs"}, matched("egs"))
+	assert.Empty(t, matched("tuna"), "two edits in four letters is too far")
+
+	ranked := rankFuzzy("tomatoe", items)
+	require.NotEmpty(t, ranked)
+	assert.Equal(t, "Tomatoes", ranked[0].Item.Name)
+	assert.InDelta(t, 0.875, ranked[0].Similarity, 0.001)
+}
+
+func TestSearchItemsFuzzy(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	fridge, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	pantry, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	for _, it := range []struct {
+		area int64
+		name string
+	}{{fridge.ID, "Yoghurt"}, {fridge.ID, "Greek yoghurt"}, {pantry.ID, "Yeast"}, {pantry.ID, "Oat yoghurt"}} {
+		_, err := svc.CreateItem(ctx, it.area, it.name, "1")
+		require.NoError(t, err)
+	}
+
+	found, err := svc.SearchItemsFuzzy(ctx, "  Yogurt ", domain.SearchFilter{})
+	require.NoError(t, err)
+	var names []string
+	for _, m := range found {
+		names = append(names, m.Item.Name)
+	}
+	assert.ElementsMatch(t, []string{"Yoghurt", "Greek yoghurt", "Oat yoghurt"}, names)
+
+	found, err = svc.SearchItemsFuzzy(ctx, "yogurt", domain.SearchFilter{AreaIDs: []int64{pantry.ID}})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "Oat yoghurt", found[0].Item.Name)
+
+	found, err = svc.SearchItemsFuzzy(ctx, "%", domain.SearchFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, found)
+}
+
+// BenchmarkRankFuzzy scores a few thousand candidate names, about the most
+// a household's inventory sharing a first letter would hold.
+func BenchmarkRankFuzzy(b *testing.B) {
+	words := []string{"yoghurt", "greek", "strawberry", "tomatoes", "cherry", "oat", "milk", "frozen", "peas", "chicken", "thighs", "salsa"}
+	items := make([]*domain.Item, 5000)
+	for i := range items {
+		items[i] = &domain.Item{Name: fmt.Sprintf("%s %s %d", words[i%len(words)], words[(i/len(words))%len(words)], i)}
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		rankFuzzy("yogurt", items)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// DefaultHistoryKeep is how many history points are kept per area.
+const DefaultHistoryKeep = 365
+
+// historyRepository is the subset of store.HistoryStore that AreaService
+// requires.
+type historyRepository interface {
+	Record(ctx context.Context, areaID int64, items []domain.SnapshotItem, keep int) error
+	ListSince(ctx context.Context, areaID int64, since time.Time) ([]*domain.HistoryPoint, error)
+}
+
+// WithHistory records each area's inventory after every successful analysis
+// in store, keeping the newest keep points per area; keep <= 0 keeps them
+// all. A nil store records nothing.
+func (s *AreaService) WithHistory(store historyRepository, keep int) *AreaService {
+	s.historyStore = store
+	s.historyKeep = max(keep, 0)
+	return s
+}
+
+// recordHistory appends items to the area's history. It runs on the upload
+// path after the items are stored, so failures are logged, not returned.
+func (s *AreaService) recordHistory(ctx context.Context, areaID int64, items []*domain.Item) {
+	if s.historyStore == nil {
+		return
+	}
+	points := make([]domain.SnapshotItem, len(items))
+	for i, it := range items {
+		points[i] = domain.SnapshotItem{Name: it.Name, Quantity: it.Quantity}
+	}
+	if err := s.historyStore.Record(ctx, areaID, points, s.historyKeep); err != nil {
+		s.log(ctx).Error("failed to record inventory history", "area_id", areaID, "error", err)
+	}
+}
+
+// AreaHistory returns the area's inventory after each analysis since the
+// given time, oldest first. It returns ErrAreaNotFound for a missing area.
+func (s *AreaService) AreaHistory(ctx context.Context, areaID int64, since time.Time) ([]*domain.HistoryPoint, error) {
+	area, err := s.areaStore.GetByID(ctx, areaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get area: %w", err)
+	}
+	if area == nil {
+		return nil, ErrAreaNotFound
+	}
+	if s.historyStore == nil {
+		return []*domain.HistoryPoint{}, nil
+	}
+	return s.historyStore.ListSince(ctx, areaID, since)
+}
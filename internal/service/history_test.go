@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/store"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+func TestAreaHistory(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	svc.visionAPI = &sequenceVision{results: []*vision.AnalysisResult{
+		{Items: []vision.DetectedItem{{Name: "Peas", Quantity: "2"}}},
+		{Items: []vision.DetectedItem{{Name: "Peas", Quantity: "1"}, {Name: "Ice cream", Quantity: "1"}}},
+		{Items: []vision.DetectedItem{{Name: "Ice cream", Quantity: "1"}}},
+	}}
+	area, err := svc.CreateArea(ctx, "Freezer")
+	require.NoError(t, err)
+
+	// Without a history store nothing is recorded.
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
+	require.NoError(t, err)
+	points, err := svc.AreaHistory(ctx, area.ID, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, points)
+
+	svc.WithHistory(store.NewHistoryStore(svc.db), 1)
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x02}, "image/jpeg")
+	require.NoError(t, err)
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x03}, "image/jpeg")
+	require.NoError(t, err)
+
+	points, err = svc.AreaHistory(ctx, area.ID, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, points, 1, "only the newest point is kept")
+	assert.Equal(t, 1, points[0].ItemCount)
+	assert.Equal(t, "Ice cream", points[0].Items[0].Name)
+
+	_, err = svc.AreaHistory(ctx, area.ID+1, time.Time{})
+	assert.ErrorIs(t, err, ErrAreaNotFound)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+// ErrIgnorePatternInvalid is returned by AddIgnoredItem for a pattern with
+// nothing to match once whitespace and wildcards are removed.
+var ErrIgnorePatternInvalid = errors.New("ignore pattern is empty")
+
+// ErrIgnorePatternExists is returned by AddIgnoredItem when the pattern is
+// already on the list, ignoring case.
+var ErrIgnorePatternExists = errors.New("ignore pattern already exists")
+
+// ignoreRepository is the subset of store.IgnoreStore that AreaService requires.
+type ignoreRepository interface {
+	Create(ctx context.Context, pattern string) (*domain.IgnoredItem, error)
+	List(ctx context.Context) ([]*domain.IgnoredItem, error)
+	Delete(ctx context.Context, id int64) (bool, error)
+}
+
+// WithIgnoreList turns on ignore-list filtering of vision results, using
+// patterns as built-in entries alongside those added through AddIgnoredItem.
+// Without it every detection is kept.
+func (s *AreaService) WithIgnoreList(patterns []string) *AreaService {
+	s.ignoreEnabled = true
+	s.ignoreBuiltin = patterns
+	return s
+}
+
+// WithIgnoreStore sets where user-added ignore-list entries are kept.
+func (s *AreaService) WithIgnoreStore(st ignoreRepository) *AreaService {
+	s.ignoreStore = st
+	return s
+}
+
+// ListIgnoredItems returns the user-added ignore-list entries.
+func (s *AreaService) ListIgnoredItems(ctx context.Context) ([]*domain.IgnoredItem, error) {
+	if s.ignoreStore == nil {
+		return nil, nil
+	}
+	return s.ignoreStore.List(ctx)
+}
+
+// AddIgnoredItem adds pattern to the ignore list; see ignoreMatches for the
+// pattern syntax.
+func (s *AreaService) AddIgnoredItem(ctx context.Context, pattern string) (*domain.IgnoredItem, error) {
+	pattern = strings.TrimSpace(pattern)
+	if strings.Trim(pattern, "* ") == "" {
+		return nil, ErrIgnorePatternInvalid
+	}
+	if s.ignoreStore == nil {
+		return nil, errors.New("ignore list store not configured")
+	}
+	it, err := s.ignoreStore.Create(ctx, pattern)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrIgnorePatternExists
+		}
+		return nil, err
+	}
+	return it, nil
+}
+
+// DeleteIgnoredItem removes a user-added ignore-list entry. It reports
+// whether the entry existed.
+func (s *AreaService) DeleteIgnoredItem(ctx context.Context, id int64) (bool, error) {
+	if s.ignoreStore == nil {
+		return false, nil
+	}
+	return s.ignoreStore.Delete(ctx, id)
+}
+
+// filterIgnored drops detections whose name matches the ignore list and
+// returns the rest with the number dropped. A failure to load the stored
+// entries is logged and only the built-ins are applied.
+func (s *AreaService) filterIgnored(ctx context.Context, areaID int64, detected []vision.DetectedItem) ([]vision.DetectedItem, int) {
+	if !s.ignoreEnabled {
+		return detected, 0
+	}
+	patterns := s.ignoreBuiltin
+	if s.ignoreStore != nil {
+		stored, err := s.ignoreStore.List(ctx)
+		if err != nil {
+			s.log(ctx).Error("failed to load ignore list", "area_id", areaID, "error", err)
+		}
+		patterns = append(append([]string(nil), s.ignoreBuiltin...), ignorePatterns(stored)...)
+	}
+	if len(patterns) == 0 {
+		return detected, 0
+	}
+
+	kept := make([]vision.DetectedItem, 0, len(detected))
+	for _, d := range detected {
+		if p, ok := matchIgnored(patterns, d.Name); ok {
+			s.log(ctx).Debug("ignoring detected item", "area_id", areaID, "name", d.Name, "pattern", p)
+			continue
+		}
+		kept = append(kept, d)
+	}
+	return kept, len(detected) - len(kept)
+}
+
+func ignorePatterns(items []*domain.IgnoredItem) []string {
+	out := make([]string, len(items))
+	for i, it := range items {
+		out[i] = it.Pattern
+	}
+	return out
+}
+
+// matchIgnored returns the first pattern that matches name.
+func matchIgnored(patterns []string, name string) (string, bool) {
+	for _, p := range patterns {
+		if ignoreMatches(p, name) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// ignoreMatches reports whether name matches pattern, ignoring case and
+// surrounding whitespace. A plain pattern must equal the whole name; a
+// leading '*' matches names ending with the rest, a trailing '*' names
+// starting with it, and both any name containing it.
+func ignoreMatches(pattern, name string) bool {
+	p := strings.ToLower(strings.TrimSpace(pattern))
+	n := strings.ToLower(strings.TrimSpace(name))
+	anyPrefix := strings.HasPrefix(p, "*")
+	anySuffix := strings.HasSuffix(p, "*")
+	core := strings.TrimSpace(strings.Trim(p, "*"))
+	if core == "" {
+		return false
+	}
+	switch {
+	case anyPrefix && anySuffix:
+		return strings.Contains(n, core)
+	case anyPrefix:
+		return strings.HasSuffix(n, core)
+	case anySuffix:
+		return strings.HasPrefix(n, core)
+	default:
+		return n == core
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/db"
+	"github.com/vbonduro/kitchinv/internal/store"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+func TestIgnoreMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		want    bool
+	}{
+		{"shelf", "Shelf", true},
+		{"shelf", " shelf ", true},
+		{"shelf", "Shelf-stable Milk", false},
+		{"*container", "Plastic Container", true},
+		{"*container", "Container of Soup", false},
+		{"container*", "Container of Soup", true},
+		{"*drawer*", "Crisper Drawer Liner", true},
+		{"*drawer*", "Milk", false},
+		{"*", "Milk", false},
+		{"  ", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, ignoreMatches(tt.pattern, tt.input))
+		})
+	}
+}
+
+func TestAreaServiceUploadPhoto_DropsIgnoredItems(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	vis := &stubVision{result: &vision.AnalysisResult{Status: vision.StatusOK, Items: []vision.DetectedItem{
+		{Name: "Milk", Quantity: "1"},
+		{Name: "Shelf", Quantity: "3"},
+		{Name: "Plastic Container", Quantity: "2"},
+		{Name: "Door Bin", Quantity: "1"},
+	}}}
+	newSvc := func() *AreaService {
+		return NewAreaService(
+			store.NewAreaStore(d),
+			store.NewPhotoStore(d),
+			store.NewItemStore(d),
+			store.NewItemEditStore(d),
+			store.NewSnapshotStore(d),
+			&noopOverrideStore{},
+			vis,
+			newStubPhotoStore(),
+			slog.Default(),
+		).WithDB(d).WithIgnoreStore(store.NewIgnoreStore(d))
+	}
+	ctx := context.Background()
+
+	svc := newSvc().WithIgnoreList([]string{"shelf", "*container"})
+	_, err = svc.AddIgnoredItem(ctx, "door bin")
+	require.NoError(t, err)
+	_, err = svc.AddIgnoredItem(ctx, "Door Bin")
+	assert.ErrorIs(t, err, ErrIgnorePatternExists)
+	_, err = svc.AddIgnoredItem(ctx, " * ")
+	assert.ErrorIs(t, err, ErrIgnorePatternInvalid)
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	_, items, err := svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "Milk", items[0].Name)
+
+	// Without WithIgnoreList nothing is filtered, stored entries included.
+	_, items, err = newSvc().UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x02}, "image/jpeg")
+	require.NoError(t, err)
+	assert.Len(t, items, 4)
+}
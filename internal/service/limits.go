@@ -0,0 +1,26 @@
+package service
+
+import (
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+// WithItemLimits sets how long an item's name and quantity may be. Bulk
+// edits over the limits are rejected; names from the vision model and the
+// product database are cut to fit.
+func (s *AreaService) WithItemLimits(l domain.ItemLimits) *AreaService {
+	s.itemLimits = l
+	return s
+}
+
+// clampDetected cleans and cuts each detected item's name and quantity to
+// the item limits, so a rambling model answer is stored as a short item
+// rather than failing the analysis.
+func (s *AreaService) clampDetected(detected []vision.DetectedItem) []vision.DetectedItem {
+	out := make([]vision.DetectedItem, len(detected))
+	for i, d := range detected {
+		d.Name, d.Quantity = s.itemLimits.Clamp(d.Name, d.Quantity)
+		out[i] = d
+	}
+	return out
+}
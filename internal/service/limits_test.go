@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/db"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/store"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+func TestAreaServiceUploadPhoto_ClampsDetectedItems(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	vis := &stubVision{result: &vision.AnalysisResult{Status: vision.StatusOK, Items: []vision.DetectedItem{
+		{Name: strings.Repeat("Very ", 500) + "long cheese", Quantity: strings.Repeat("9", 300)},
+		{Name: "Milk\n\x1b[31m", Quantity: "2\tcartons"},
+		{Name: "\x00\x07", Quantity: "1"},
+	}}}
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		vis,
+		newStubPhotoStore(),
+		slog.Default(),
+	).WithDB(d)
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	_, items, err := svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
+	require.NoError(t, err)
+	require.Len(t, items, 2, "a name of only control characters is dropped")
+
+	assert.Len(t, []rune(items[0].Name), 199, "cut to 200 characters, trailing space dropped")
+	assert.True(t, strings.HasPrefix(items[0].Name, "Very Very"))
+	assert.Len(t, items[0].Quantity, domain.DefaultItemLimits.Quantity)
+	assert.Equal(t, "Milk[31m", items[1].Name)
+	assert.Equal(t, "2cartons", items[1].Quantity)
+}
+
+func TestAreaServiceBulkEditItems_RejectsOverlongText(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	svc.WithItemLimits(domain.ItemLimits{Name: 10, Quantity: 5})
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+
+	_, err = svc.BulkEditItems(ctx, area.ID, []domain.ItemOp{
+		{Op: domain.ItemOpCreate, Name: "Milk\n", Quantity: "1"},
+		{Op: domain.ItemOpCreate, Name: "Strawberry jam"},
+		{Op: domain.ItemOpCreate, Name: "Eggs", Quantity: "a dozen"},
+		{Op: domain.ItemOpCreate, Name: "Crème brûlée"},
+	})
+	var bulkErr *BulkItemError
+	require.ErrorAs(t, err, &bulkErr)
+	assert.Equal(t, []domain.ItemOpFailure{
+		{Index: 1, Error: "item name must be at most 10 characters"},
+		{Index: 2, Error: "quantity must be at most 5 characters"},
+		{Index: 3, Error: "item name must be at most 10 characters"},
+	}, bulkErr.Failures)
+
+	items, err := svc.BulkEditItems(ctx, area.ID, []domain.ItemOp{
+		{Op: domain.ItemOpCreate, Name: "Milk\n", Quantity: "1"},
+		{Op: domain.ItemOpCreate, Name: "Crème"},
+	})
+	require.NoError(t, err)
+	var names []string
+	for _, it := range items {
+		names = append(names, it.Name)
+	}
+	assert.ElementsMatch(t, []string{"Milk", "Crème"}, names, "control characters are dropped and length counts characters, not bytes")
+}
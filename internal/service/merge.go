@@ -4,6 +4,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/vbonduro/kitchinv/internal/domain"
 	"github.com/vbonduro/kitchinv/internal/vision"
 )
 
@@ -11,6 +12,51 @@ type mergedItem struct {
 	name     string
 	quantity string
 	bboxes   [][]float64
+	photoID  int64 // the photo the item was detected in
+}
+
+// photoDetections are the items detected in one photo of an upload.
+type photoDetections struct {
+	photoID int64
+	items   []vision.DetectedItem
+}
+
+// mergePhotoDetections merges each photo's detections with
+// mergeDetectedItems, then drops items already found in an earlier photo:
+// photos of one area overlap, so the same item seen twice is most likely the
+// same item. An item keeps the quantity and bounding boxes of the first photo
+// it appears in, since boxes are only meaningful against their own photo.
+func mergePhotoDetections(photos []photoDetections) []mergedItem {
+	seen := make(map[string]bool)
+	var result []mergedItem
+	for _, p := range photos {
+		for _, m := range mergeDetectedItems(p.items) {
+			key := strings.ToLower(m.name)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			m.photoID = p.photoID
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// detectedByPhoto splits merged items into runs found in the same photo,
+// in order, each ready for ItemStore.CreateBatch.
+func detectedByPhoto(merged []mergedItem) ([]int64, [][]domain.DetectedItem) {
+	var photoIDs []int64
+	var batches [][]domain.DetectedItem
+	for _, m := range merged {
+		if n := len(photoIDs); n == 0 || photoIDs[n-1] != m.photoID {
+			photoIDs = append(photoIDs, m.photoID)
+			batches = append(batches, nil)
+		}
+		last := len(batches) - 1
+		batches[last] = append(batches[last], domain.DetectedItem{Name: m.name, Quantity: m.quantity, BBoxes: m.bboxes})
+	}
+	return photoIDs, batches
 }
 
 // mergeDetectedItems groups detected items by name (case-insensitive, trimmed),
@@ -53,3 +99,37 @@ func mergeDetectedItems(detected []vision.DetectedItem) []mergedItem {
 
 	return result
 }
+
+// keptItems returns the existing items that survive re-analysis: those the
+// user created or corrected. replaceEdited discards them too.
+func keptItems(existing []*domain.Item, replaceEdited bool) []*domain.Item {
+	if replaceEdited {
+		return nil
+	}
+	var kept []*domain.Item
+	for _, it := range existing {
+		if it.Edited {
+			kept = append(kept, it)
+		}
+	}
+	return kept
+}
+
+// withoutKeptNames drops detections whose name matches a kept item's,
+// ignoring case, so re-analysis doesn't duplicate an item the user corrected.
+func withoutKeptNames(merged []mergedItem, kept []*domain.Item) []mergedItem {
+	if len(kept) == 0 {
+		return merged
+	}
+	names := make(map[string]bool, len(kept))
+	for _, it := range kept {
+		names[strings.ToLower(strings.TrimSpace(it.Name))] = true
+	}
+	result := merged[:0:0]
+	for _, m := range merged {
+		if !names[strings.ToLower(m.name)] {
+			result = append(result, m)
+		}
+	}
+	return result
+}
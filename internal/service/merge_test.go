@@ -153,3 +153,46 @@ func TestMergeDetectedItems(t *testing.T) {
 		}
 	})
 }
+
+func TestMergePhotoDetections(t *testing.T) {
+	got := mergePhotoDetections([]photoDetections{
+		{photoID: 1, items: []vision.DetectedItem{
+			{Name: "Milk", Quantity: "1", BBox: ptr4(0.1, 0.1, 0.3, 0.3)},
+			{Name: "Milk", Quantity: "1", BBox: ptr4(0.5, 0.1, 0.7, 0.3)},
+		}},
+		{photoID: 2, items: []vision.DetectedItem{
+			{Name: "milk", Quantity: "5", BBox: ptr4(0.2, 0.2, 0.4, 0.4)},
+			{Name: "Eggs", Quantity: "6"},
+		}},
+	})
+	if len(got) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(got))
+	}
+	if got[0].name != "Milk" || got[0].quantity != "2" || got[0].photoID != 1 || len(got[0].bboxes) != 2 {
+		t.Errorf("milk should be merged within the first photo only, got %+v", got[0])
+	}
+	if got[1].name != "Eggs" || got[1].photoID != 2 {
+		t.Errorf("eggs should come from the second photo, got %+v", got[1])
+	}
+}
+
+func TestDetectedByPhoto(t *testing.T) {
+	photoIDs, batches := detectedByPhoto([]mergedItem{
+		{name: "Milk", quantity: "2", photoID: 1, bboxes: [][]float64{{0.1, 0.1, 0.3, 0.3}}},
+		{name: "Butter", photoID: 1},
+		{name: "Eggs", quantity: "6", photoID: 2},
+	})
+	if len(photoIDs) != 2 || photoIDs[0] != 1 || photoIDs[1] != 2 {
+		t.Fatalf("expected photos [1 2], got %v", photoIDs)
+	}
+	if len(batches[0]) != 2 || batches[0][0].Name != "Milk" || batches[0][0].Quantity != "2" || len(batches[0][0].BBoxes) != 1 || batches[0][1].Name != "Butter" {
+		t.Errorf("first photo's batch should be Milk then Butter, got %+v", batches[0])
+	}
+	if len(batches[1]) != 1 || batches[1][0].Name != "Eggs" {
+		t.Errorf("second photo's batch should be Eggs, got %+v", batches[1])
+	}
+
+	if photoIDs, _ := detectedByPhoto(nil); len(photoIDs) != 0 {
+		t.Errorf("nothing detected should give no batches, got %v", photoIDs)
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+var (
+	// ErrInvalidParent is returned when a sub-area's parent does not exist or
+	// is itself a sub-area. Areas nest one level deep.
+	ErrInvalidParent = errors.New("invalid parent area")
+	// ErrHasChildren is returned when deleting an area that still has
+	// sub-areas.
+	ErrHasChildren = errors.New("area has sub-areas")
+)
+
+// CreateChildArea creates a sub-area, such as a basket in a freezer, under a
+// top-level area.
+func (s *AreaService) CreateChildArea(ctx context.Context, parentID int64, name string, kind domain.AreaKind) (*domain.Area, error) {
+	if _, ok := domain.ParseAreaKind(string(kind)); !ok {
+		return nil, ErrUnknownAreaKind
+	}
+	parent, err := s.areaStore.GetByID(ctx, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parent area: %w", err)
+	}
+	if parent == nil || parent.ParentID != nil {
+		return nil, ErrInvalidParent
+	}
+	area, err := s.areaStore.CreateChild(ctx, parentID, name, kind)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrNameTaken
+		}
+		return nil, err
+	}
+	s.publishArea(ctx, area.ID)
+	return area, nil
+}
+
+// rollUpChildren attaches each sub-area's summary to its parent's and adds
+// its items to the parent's TotalItems. Sub-areas whose parent is not in
+// summaries are left alone.
+func rollUpChildren(summaries []*AreaSummary) {
+	byID := make(map[int64]*AreaSummary, len(summaries))
+	for _, sum := range summaries {
+		byID[sum.ID] = sum
+	}
+	for _, sum := range summaries {
+		if sum.ParentID == nil {
+			continue
+		}
+		if parent := byID[*sum.ParentID]; parent != nil {
+			parent.Children = append(parent.Children, sum)
+			parent.TotalItems += sum.ItemCount
+		}
+	}
+}
+
+// summarizeChildren fills in sum.Children and rolls their items into
+// sum.TotalItems, as ListAreaSummaries does for every area.
+func (s *AreaService) summarizeChildren(ctx context.Context, sum *AreaSummary, now time.Time) error {
+	if sum.ParentID != nil {
+		return nil
+	}
+	areas, err := s.areaStore.List(ctx)
+	if err != nil {
+		return err
+	}
+	var previews map[int64]*domain.ItemPreview
+	for _, area := range areas {
+		if area.ParentID == nil || *area.ParentID != sum.ID {
+			continue
+		}
+		if previews == nil {
+			if previews, err = s.itemStore.ListPreviews(ctx, ItemPreviewLimit); err != nil {
+				return err
+			}
+		}
+		photo, err := s.photoStore.GetLatestByAreaID(ctx, area.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get photo for area %d: %w", area.ID, err)
+		}
+		child := s.summarize(area, photo, previews[area.ID], now)
+		sum.Children = append(sum.Children, child)
+		sum.TotalItems += child.ItemCount
+	}
+	return nil
+}
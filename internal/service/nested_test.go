@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+func TestAreaServiceCreateChildArea(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	freezer, err := svc.CreateAreaWithKind(ctx, "Freezer", domain.AreaKindFreezer)
+	require.NoError(t, err)
+	basket, err := svc.CreateChildArea(ctx, freezer.ID, "Top basket", domain.AreaKindFreezer)
+	require.NoError(t, err)
+	assert.Equal(t, "Freezer › Top basket", basket.Path())
+
+	// One level deep only.
+	_, err = svc.CreateChildArea(ctx, basket.ID, "Ice trays", domain.AreaKindFreezer)
+	assert.ErrorIs(t, err, ErrInvalidParent)
+	_, err = svc.CreateChildArea(ctx, 9999, "Orphan", domain.AreaKindOther)
+	assert.ErrorIs(t, err, ErrInvalidParent)
+	_, err = svc.CreateChildArea(ctx, freezer.ID, "Top basket", domain.AreaKindFreezer)
+	assert.ErrorIs(t, err, ErrNameTaken)
+}
+
+func TestAreaServiceChildItemsRollUp(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	freezer, err := svc.CreateArea(ctx, "Freezer")
+	require.NoError(t, err)
+	basket, err := svc.CreateChildArea(ctx, freezer.ID, "Top basket", domain.AreaKindFreezer)
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, freezer.ID, "Ice", "1")
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, basket.ID, "Peas", "2")
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, basket.ID, "Chips", "1")
+	require.NoError(t, err)
+
+	summaries, err := svc.ListAreaSummaries(ctx)
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, 3, summaries[0].TotalItems)
+	require.Len(t, summaries[0].Children, 1)
+	assert.Equal(t, basket.ID, summaries[0].Children[0].ID)
+	assert.Equal(t, 2, summaries[1].TotalItems)
+
+	sum, err := svc.GetAreaSummary(ctx, freezer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, sum.TotalItems)
+	require.Len(t, sum.Children, 1)
+	assert.Equal(t, 2, sum.Children[0].ItemCount)
+}
+
+func TestAreaServiceDeleteArea_BlockedByChildren(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	freezer, err := svc.CreateArea(ctx, "Freezer")
+	require.NoError(t, err)
+	basket, err := svc.CreateChildArea(ctx, freezer.ID, "Top basket", domain.AreaKindFreezer)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, svc.DeleteArea(ctx, freezer.ID), ErrHasChildren)
+
+	require.NoError(t, svc.DeleteArea(ctx, basket.ID))
+	require.NoError(t, svc.DeleteArea(ctx, freezer.ID))
+}
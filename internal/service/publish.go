@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// inventoryPublisher mirrors area inventories to an outside system, such as
+// MQTT. Both methods must return without blocking on the network.
+type inventoryPublisher interface {
+	PublishArea(area *domain.Area, items []*domain.Item)
+	RemoveArea(areaID int64)
+}
+
+// WithInventoryPublisher sends each area's inventory to p whenever it
+// changes. Without it nothing is published.
+func (s *AreaService) WithInventoryPublisher(p inventoryPublisher) *AreaService {
+	s.publisher = p
+	return s
+}
+
+// PublishInventory publishes every area's current inventory, for a publisher
+// that has just (re)connected.
+func (s *AreaService) PublishInventory(ctx context.Context) error {
+	if s.publisher == nil {
+		return nil
+	}
+	areas, err := s.areaStore.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list areas: %w", err)
+	}
+	for _, area := range areas {
+		items, err := s.itemStore.ListByAreaID(ctx, area.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list items: %w", err)
+		}
+		s.publisher.PublishArea(area, items)
+	}
+	return nil
+}
+
+// publishArea publishes an area's inventory in the background, or removes it
+// if the area has been deleted. Runs are serialised so the state read last is
+// the state published last.
+func (s *AreaService) publishArea(ctx context.Context, areaID int64) {
+	if s.publisher == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		s.publishMu.Lock()
+		defer s.publishMu.Unlock()
+		area, err := s.areaStore.GetByID(ctx, areaID)
+		if err != nil {
+			s.log(ctx).Error("failed to get area to publish", "area_id", areaID, "error", err)
+			return
+		}
+		if area == nil {
+			s.publisher.RemoveArea(areaID)
+			return
+		}
+		items, err := s.itemStore.ListByAreaID(ctx, areaID)
+		if err != nil {
+			s.log(ctx).Error("failed to list items to publish", "area_id", areaID, "error", err)
+			return
+		}
+		s.publisher.PublishArea(area, items)
+	}()
+}
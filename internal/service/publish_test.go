@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// publishEvent is one call to recordingPublisher: an area's item names, or
+// removed set for RemoveArea.
+type publishEvent struct {
+	areaID  int64
+	items   []string
+	removed bool
+}
+
+type recordingPublisher struct {
+	events chan publishEvent
+}
+
+func (p *recordingPublisher) PublishArea(area *domain.Area, items []*domain.Item) {
+	names := []string{}
+	for _, it := range items {
+		names = append(names, it.Name)
+	}
+	p.events <- publishEvent{areaID: area.ID, items: names}
+}
+
+func (p *recordingPublisher) RemoveArea(areaID int64) {
+	p.events <- publishEvent{areaID: areaID, removed: true}
+}
+
+func (p *recordingPublisher) next(t *testing.T) publishEvent {
+	t.Helper()
+	select {
+	case e := <-p.events:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("nothing was published")
+		return publishEvent{}
+	}
+}
+
+func TestAreaServicePublishesInventoryChanges(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	pub := &recordingPublisher{events: make(chan publishEvent, 10)}
+	svc.WithInventoryPublisher(pub)
+	ctx := context.Background()
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	assert.Equal(t, publishEvent{areaID: area.ID, items: []string{}}, pub.next(t))
+
+	_, err = svc.CreateItem(ctx, area.ID, "Milk", "1")
+	require.NoError(t, err)
+	assert.Equal(t, publishEvent{areaID: area.ID, items: []string{"Milk"}}, pub.next(t))
+
+	require.NoError(t, svc.DeleteArea(ctx, area.ID))
+	assert.Equal(t, publishEvent{areaID: area.ID, removed: true}, pub.next(t))
+
+	_, err = svc.RestoreArea(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Equal(t, publishEvent{areaID: area.ID, items: []string{"Milk"}}, pub.next(t))
+}
+
+func TestAreaServicePublishInventory(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	fridge, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	pantry, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, pantry.ID, "Rice", "1 bag")
+	require.NoError(t, err)
+
+	require.NoError(t, svc.PublishInventory(ctx), "no publisher is a no-op")
+
+	pub := &recordingPublisher{events: make(chan publishEvent, 10)}
+	svc.WithInventoryPublisher(pub)
+	require.NoError(t, svc.PublishInventory(ctx))
+	got := map[int64][]string{}
+	for range 2 {
+		e := pub.next(t)
+		got[e.areaID] = e.items
+	}
+	assert.Equal(t, map[int64][]string{fridge.ID: {}, pantry.ID: {"Rice"}}, got)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// ReportArea is one area's section of an inventory report.
+type ReportArea struct {
+	ID    int64
+	Path  string
+	Items []*domain.Item
+}
+
+// EachReportArea calls fn with every area and its items, in the areas page
+// order with each area's sub-areas right after it. Areas without items are
+// skipped unless includeEmpty is set. Items are loaded one area at a time,
+// so a report can be written out as it is built; an error from fn stops the
+// walk and is returned.
+func (s *AreaService) EachReportArea(ctx context.Context, includeEmpty bool, fn func(*ReportArea) error) error {
+	areas, err := s.areaStore.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list areas: %w", err)
+	}
+	for _, area := range reportOrder(areas) {
+		items, err := s.itemStore.ListByAreaID(ctx, area.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list items for area %d: %w", area.ID, err)
+		}
+		if len(items) == 0 && !includeEmpty {
+			continue
+		}
+		if err := fn(&ReportArea{ID: area.ID, Path: area.Path(), Items: items}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AreaThumbnail returns the area's latest photo scaled down, through the
+// same pipeline as item thumbnails, so its longest side is at most maxSide
+// pixels, as a JPEG. It returns nil without an error when the area has no
+// photo or the photo is in a format the standard library can't decode.
+func (s *AreaService) AreaThumbnail(ctx context.Context, areaID int64, maxSide int) ([]byte, error) {
+	photo, err := s.photoStore.GetLatestByAreaID(ctx, areaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest photo: %w", err)
+	}
+	if photo == nil || photo.StorageKey == "" {
+		return nil, nil
+	}
+	rc, _, err := s.photoStg.Get(ctx, photo.StorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read photo: %w", err)
+	}
+	defer func() { _ = rc.Close() }()
+	img, _, err := image.Decode(rc)
+	if err != nil {
+		s.log(ctx).Debug("photo not decodable, skipping area thumbnail", "photo_id", photo.ID, "mime_type", photo.MimeType, "error", err)
+		return nil, nil
+	}
+	return scaleToJPEG(img, img.Bounds(), maxSide)
+}
+
+// reportOrder puts each area's sub-areas right after it, keeping the order
+// List returned them in otherwise. Sub-areas whose parent isn't listed come
+// last.
+func reportOrder(areas []*domain.Area) []*domain.Area {
+	listed := make(map[int64]bool, len(areas))
+	children := make(map[int64][]*domain.Area)
+	for _, a := range areas {
+		listed[a.ID] = true
+		if a.ParentID != nil {
+			children[*a.ParentID] = append(children[*a.ParentID], a)
+		}
+	}
+	ordered := make([]*domain.Area, 0, len(areas))
+	for _, a := range areas {
+		if a.ParentID == nil {
+			ordered = append(ordered, a)
+			ordered = append(ordered, children[a.ID]...)
+		}
+	}
+	for _, a := range areas {
+		if a.ParentID != nil && !listed[*a.ParentID] {
+			ordered = append(ordered, a)
+		}
+	}
+	return ordered
+}
+
+// WriteMarkdownReport writes the inventory to w as a Markdown document: a
+// heading with the generation time, then a table of item names and
+// quantities under a heading for each area. The time is shown in
+// generatedAt's location. Each area is written as soon as its items are
+// loaded.
+func (s *AreaService) WriteMarkdownReport(ctx context.Context, w io.Writer, generatedAt time.Time, includeEmpty bool) error {
+	if _, err := fmt.Fprintf(w, "# Kitchen inventory\n\nGenerated %s\n", generatedAt.Format("2006-01-02 15:04 MST")); err != nil {
+		return err
+	}
+	written := false
+	err := s.EachReportArea(ctx, includeEmpty, func(a *ReportArea) error {
+		written = true
+		return writeMarkdownArea(w, a)
+	})
+	if err != nil {
+		return err
+	}
+	if !written {
+		_, err = io.WriteString(w, "\nNo items.\n")
+	}
+	return err
+}
+
+// writeMarkdownArea writes one area's heading and item table.
+func writeMarkdownArea(w io.Writer, a *ReportArea) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n## %s\n\n", escapeMarkdown(a.Path))
+	if len(a.Items) == 0 {
+		b.WriteString("No items.\n")
+	} else {
+		b.WriteString("| Item | Quantity |\n| --- | --- |\n")
+		for _, it := range a.Items {
+			fmt.Fprintf(&b, "| %s | %s |\n", escapeMarkdown(it.Name), escapeMarkdown(it.Quantity))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownEscaper backslash-escapes the characters that would start
+// emphasis, code, links or HTML, or end a table cell, and flattens line
+// breaks so a value stays in its cell.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`",
+	"[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`,
+	"\r\n", " ", "\n", " ", "\r", " ",
+)
+
+// escapeMarkdown makes s safe to use as literal text in a heading or table
+// cell.
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(strings.TrimSpace(s))
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+func TestWriteMarkdownReport(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	freezer, err := svc.CreateArea(ctx, "Freezer")
+	require.NoError(t, err)
+	_, err = svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	basket, err := svc.CreateChildArea(ctx, freezer.ID, "Top basket", domain.AreaKindOther)
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, freezer.ID, "Peas", "1 bag")
+	require.NoError(t, err)
+	_, err = svc.CreateItem(ctx, basket.ID, "Ice cream | vanilla", "")
+	require.NoError(t, err)
+
+	at := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+	var b strings.Builder
+	require.NoError(t, svc.WriteMarkdownReport(ctx, &b, at, false))
+	assert.Equal(t, "# Kitchen inventory\n\nGenerated 2026-03-14 09:30 UTC\n"+
+		"\n## Freezer\n\n| Item | Quantity |\n| --- | --- |\n| Peas | 1 bag |\n"+
+		"\n## Freezer › Top basket\n\n| Item | Quantity |\n| --- | --- |\n| Ice cream \\| vanilla |  |\n", b.String())
+
+	b.Reset()
+	require.NoError(t, svc.WriteMarkdownReport(ctx, &b, at, true))
+	assert.Contains(t, b.String(), "\n## Pantry\n\nNo items.\n", "empty areas are listed on request")
+}
+
+func TestWriteMarkdownReport_NoItems(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+
+	var b strings.Builder
+	require.NoError(t, svc.WriteMarkdownReport(context.Background(), &b, time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC), false))
+	assert.Equal(t, "# Kitchen inventory\n\nGenerated 2026-03-14 09:30 UTC\n\nNo items.\n", b.String())
+}
+
+func TestAreaThumbnail(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+
+	data, err := svc.AreaThumbnail(ctx, area.ID, 100)
+	require.NoError(t, err)
+	assert.Nil(t, data, "no photo yet")
+
+	_, _, err = svc.UploadPhoto(ctx, area.ID, twoTonePNG(t), "image/png")
+	require.NoError(t, err)
+	data, err = svc.AreaThumbnail(ctx, area.ID, 100)
+	require.NoError(t, err)
+	img, format, err := image.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), img.Bounds())
+
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
+	require.NoError(t, err)
+	data, err = svc.AreaThumbnail(ctx, area.ID, 100)
+	require.NoError(t, err)
+	assert.Nil(t, data, "an undecodable photo is skipped")
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	for in, want := range map[string]string{
+		"Milk":               "Milk",
+		"Salt | pepper":      `Salt \| pepper`,
+		"*Organic* eggs":     `\*Organic\* eggs`,
+		"**bold**":           `\*\*bold\*\*`,
+		`C:\pantry`:          `C:\\pantry`,
+		"snake_case":         `snake\_case`,
+		"[link](x)":          `\[link\](x)`,
+		"<b>jam</b>":         `\<b\>jam\</b\>`,
+		"# 1 tin":            `\# 1 tin`,
+		"two\nlines\r\nhere": "two lines here",
+		"  padded  ":         "padded",
+	} {
+		assert.Equal(t, want, escapeMarkdown(in), in)
+	}
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"strconv"
+	"strings"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// SeedData is a dataset for Seed: areas, optionally nested one level, each
+// with items and optionally a placeholder photo.
+type SeedData struct {
+	Areas []SeedArea `json:"areas"`
+}
+
+// SeedArea is one area to create. Parent names an area earlier in the same
+// dataset. PhotoColor, a "#rrggbb" colour, gives the area a solid-colour
+// placeholder photo.
+type SeedArea struct {
+	Name       string          `json:"name"`
+	Kind       domain.AreaKind `json:"kind,omitempty"`
+	Parent     string          `json:"parent,omitempty"`
+	PhotoColor string          `json:"photo_color,omitempty"`
+	Items      []SeedItem      `json:"items,omitempty"`
+}
+
+// SeedItem is an item to add to a SeedArea.
+type SeedItem struct {
+	Name     string `json:"name"`
+	Quantity string `json:"quantity"`
+}
+
+// SeedResult lists what Seed created, in dataset order, so a test can refer
+// to it by ID.
+type SeedResult struct {
+	Areas []SeededArea `json:"areas"`
+}
+
+// SeededArea is a created area. PhotoID is zero when it has no photo.
+type SeededArea struct {
+	ID      int64        `json:"id"`
+	Name    string       `json:"name"`
+	PhotoID int64        `json:"photo_id,omitempty"`
+	Items   []SeededItem `json:"items"`
+}
+
+// SeededItem is a created item.
+type SeededItem struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// DefaultSeedData is the dataset Seed loads when given none: one area of
+// each common kind, a shelf inside the fridge, and a placeholder photo for
+// each top-level area.
+var DefaultSeedData = SeedData{Areas: []SeedArea{
+	{Name: "Fridge", Kind: domain.AreaKindFridge, PhotoColor: "#dbe9f0", Items: []SeedItem{
+		{Name: "Milk", Quantity: "1"},
+		{Name: "Eggs", Quantity: "12"},
+		{Name: "Cheddar Cheese", Quantity: "1"},
+	}},
+	{Name: "Door", Kind: domain.AreaKindFridge, Parent: "Fridge", Items: []SeedItem{
+		{Name: "Ketchup", Quantity: "1"},
+		{Name: "Mustard", Quantity: "1"},
+	}},
+	{Name: "Freezer", Kind: domain.AreaKindFreezer, PhotoColor: "#c9d8ff", Items: []SeedItem{
+		{Name: "Frozen Peas", Quantity: "2"},
+		{Name: "Ice Cream", Quantity: "1"},
+	}},
+	{Name: "Pantry", Kind: domain.AreaKindPantry, PhotoColor: "#e8d5b5", Items: []SeedItem{
+		{Name: "Pasta", Quantity: "3"},
+		{Name: "Rice", Quantity: "1"},
+		{Name: "Canned Tomatoes", Quantity: "4"},
+	}},
+}}
+
+// seedPhotoWidth and seedPhotoHeight are the size of placeholder photos, in
+// pixels.
+const seedPhotoWidth, seedPhotoHeight = 320, 240
+
+// Seed creates data's areas, photos and items through the same methods as
+// the web UI, so IDs, photo files and audit entries are consistent with real
+// use. Placeholder photos are recorded as analysed without calling the
+// vision backend. Nothing is matched against existing data, so seed an
+// empty database for predictable IDs.
+func (s *AreaService) Seed(ctx context.Context, data *SeedData) (*SeedResult, error) {
+	// Check every colour first so a bad one doesn't leave half a dataset.
+	colors := make([]color.Color, len(data.Areas))
+	for i, sa := range data.Areas {
+		if sa.Name == "" {
+			return nil, fmt.Errorf("area %d has no name", i+1)
+		}
+		if sa.PhotoColor == "" {
+			continue
+		}
+		c, err := parseHexColor(sa.PhotoColor)
+		if err != nil {
+			return nil, fmt.Errorf("area %q: %w", sa.Name, err)
+		}
+		colors[i] = c
+	}
+
+	res := &SeedResult{Areas: make([]SeededArea, 0, len(data.Areas))}
+	ids := make(map[string]int64, len(data.Areas))
+	for i, sa := range data.Areas {
+		kind := sa.Kind
+		if kind == "" {
+			kind = domain.AreaKindOther
+		}
+		var area *domain.Area
+		var err error
+		if sa.Parent == "" {
+			area, err = s.CreateAreaWithKind(ctx, sa.Name, kind)
+		} else {
+			parentID, ok := ids[sa.Parent]
+			if !ok {
+				return res, fmt.Errorf("area %q is inside %q, which is not earlier in the dataset", sa.Name, sa.Parent)
+			}
+			area, err = s.CreateChildArea(ctx, parentID, sa.Name, kind)
+		}
+		if err != nil {
+			return res, fmt.Errorf("failed to create area %q: %w", sa.Name, err)
+		}
+		ids[sa.Name] = area.ID
+		seeded := SeededArea{ID: area.ID, Name: area.Name, Items: make([]SeededItem, 0, len(sa.Items))}
+
+		if colors[i] != nil {
+			img, err := placeholderJPEG(colors[i])
+			if err != nil {
+				return res, err
+			}
+			photo, err := s.storePhoto(ctx, area.ID, "image/jpeg", img, contentHash(img))
+			if err != nil {
+				return res, fmt.Errorf("failed to store photo of area %q: %w", sa.Name, err)
+			}
+			s.setAnalysisStatus(ctx, photo, domain.PhotoAnalysisComplete, "")
+			seeded.PhotoID = photo.ID
+		}
+
+		for _, si := range sa.Items {
+			item, err := s.CreateItem(ctx, area.ID, si.Name, si.Quantity)
+			if err != nil {
+				return res, fmt.Errorf("failed to add %q to area %q: %w", si.Name, sa.Name, err)
+			}
+			seeded.Items = append(seeded.Items, SeededItem{ID: item.ID, Name: item.Name})
+		}
+		res.Areas = append(res.Areas, seeded)
+	}
+	s.log(ctx).Info("seeded data", "areas", len(res.Areas))
+	return res, nil
+}
+
+// parseHexColor parses a "#rrggbb" colour.
+func parseHexColor(s string) (color.Color, error) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok || len(hex) != 6 {
+		return nil, fmt.Errorf("photo colour %q is not #rrggbb", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("photo colour %q is not #rrggbb", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+// placeholderJPEG encodes a solid-colour JPEG.
+func placeholderJPEG(c color.Color) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, seedPhotoWidth, seedPhotoHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		return nil, fmt.Errorf("failed to encode placeholder photo: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+func TestSeedDefault(t *testing.T) {
+	svc, photos := newDemoTestService(t)
+	ctx := context.Background()
+
+	res, err := svc.Seed(ctx, &DefaultSeedData)
+	require.NoError(t, err)
+	require.Len(t, res.Areas, len(DefaultSeedData.Areas))
+
+	fridge, door := res.Areas[0], res.Areas[1]
+	assert.Equal(t, "Fridge", fridge.Name)
+	require.NotZero(t, fridge.PhotoID)
+	assert.Zero(t, door.PhotoID, "no colour, no photo")
+
+	area, items, photo, err := svc.GetAreaWithItems(ctx, fridge.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.AreaKindFridge, area.Kind)
+	assert.Len(t, items, 3)
+	require.NotNil(t, photo)
+	assert.Equal(t, fridge.PhotoID, photo.ID)
+	assert.Equal(t, domain.PhotoAnalysisComplete, photo.AnalysisStatus)
+	data := photos.saved[photo.StorageKey]
+	require.NotEmpty(t, data)
+	assert.Equal(t, "image/jpeg", http.DetectContentType(data))
+
+	child, err := svc.GetArea(ctx, door.ID)
+	require.NoError(t, err)
+	require.NotNil(t, child.ParentID)
+	assert.Equal(t, fridge.ID, *child.ParentID)
+	assert.Equal(t, "Ketchup", door.Items[0].Name)
+	assert.NotZero(t, door.Items[0].ID)
+}
+
+func TestSeedRejectsBadData(t *testing.T) {
+	svc, _ := newDemoTestService(t)
+	ctx := context.Background()
+
+	_, err := svc.Seed(ctx, &SeedData{Areas: []SeedArea{{Name: "Fridge", PhotoColor: "blue"}}})
+	assert.ErrorContains(t, err, "#rrggbb")
+	_, err = svc.Seed(ctx, &SeedData{Areas: []SeedArea{{Name: "Shelf", Parent: "Nowhere"}}})
+	assert.ErrorContains(t, err, "not earlier in the dataset")
+
+	areas, err := svc.ListAreas(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, areas, "a bad colour is caught before anything is created")
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// DefaultStaleAfter is how old an area's latest photo may get before the area
+// is flagged for re-photographing. Inventories drift within a few weeks.
+const DefaultStaleAfter = 14 * 24 * time.Hour
+
+// WithStaleAfter sets the photo age past which an area is flagged stale.
+// d <= 0 never flags an area.
+func (s *AreaService) WithStaleAfter(d time.Duration) *AreaService {
+	s.staleAfter = max(d, 0)
+	return s
+}
+
+// summarize builds the AreaSummary for an area from its item preview, nil
+// for an area without items, scoring it and ageing its latest photo as of now.
+func (s *AreaService) summarize(area *domain.Area, photo *domain.Photo, preview *domain.ItemPreview, now time.Time) *AreaSummary {
+	sum := &AreaSummary{Area: area, Photo: photo}
+	if preview != nil {
+		sum.ItemCount, sum.OutOfStock, sum.Preview = preview.Count, preview.OutOfStock, preview.Items
+	}
+	sum.TotalItems = sum.ItemCount
+	sum.Attention = attentionScore(s.attention, sum, now)
+	if photo != nil {
+		sum.PhotoAge = max(now.Sub(photo.UploadedAt), 0)
+		sum.Stale = s.staleAfter > 0 && sum.PhotoAge > s.staleAfter
+	}
+	return sum
+}
+
+// GetAreaSummary returns a single area's summary, as ListAreaSummaries would
+// list it, with all its items. It returns ErrAreaNotFound for an unknown area.
+func (s *AreaService) GetAreaSummary(ctx context.Context, areaID int64) (*AreaSummary, error) {
+	area, items, photo, err := s.GetAreaWithItems(ctx, areaID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	sum := s.summarize(area, photo, previewOf(items), now)
+	sum.Items = items
+	if err := s.summarizeChildren(ctx, sum, now); err != nil {
+		return nil, err
+	}
+	return sum, nil
+}
+
+// previewOf is the preview ListPreviews would give for an area with items,
+// which are in name order.
+func previewOf(items []*domain.Item) *domain.ItemPreview {
+	p := &domain.ItemPreview{Count: len(items), Items: items[:min(len(items), ItemPreviewLimit)]}
+	for _, it := range items {
+		if isOutOfStock(it) {
+			p.OutOfStock++
+		}
+	}
+	return p
+}
+
+// ListStaleAreas returns the areas whose latest photo is older than the stale
+// threshold, in the user's order. Areas never photographed are not included.
+func (s *AreaService) ListStaleAreas(ctx context.Context) ([]*AreaSummary, error) {
+	summaries, err := s.ListAreaSummaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stale := make([]*AreaSummary, 0, len(summaries))
+	for _, sum := range summaries {
+		if sum.Stale {
+			stale = append(stale, sum)
+		}
+	}
+	return stale, nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+func TestSummarizeStale(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	svc := &AreaService{attention: DefaultAttentionWeights, staleAfter: DefaultStaleAfter}
+	photoAt := func(age time.Duration) *domain.Photo {
+		return &domain.Photo{UploadedAt: now.Add(-age)}
+	}
+
+	t.Run("no photo", func(t *testing.T) {
+		sum := svc.summarize(&domain.Area{}, nil, nil, now)
+		assert.Zero(t, sum.PhotoAge)
+		assert.False(t, sum.Stale)
+	})
+
+	t.Run("fresh", func(t *testing.T) {
+		sum := svc.summarize(&domain.Area{}, photoAt(3*24*time.Hour), nil, now)
+		assert.Equal(t, 3*24*time.Hour, sum.PhotoAge)
+		assert.False(t, sum.Stale)
+	})
+
+	t.Run("past threshold", func(t *testing.T) {
+		sum := svc.summarize(&domain.Area{}, photoAt(DefaultStaleAfter+time.Hour), nil, now)
+		assert.True(t, sum.Stale)
+		assert.NotZero(t, sum.Attention)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		off := &AreaService{attention: DefaultAttentionWeights}
+		off.WithStaleAfter(0)
+		assert.False(t, off.summarize(&domain.Area{}, photoAt(365*24*time.Hour), nil, now).Stale)
+	})
+}
+
+func TestListStaleAreas(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	fridge, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	pantry, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	_, err = svc.CreateArea(ctx, "Freezer") // never photographed
+	require.NoError(t, err)
+
+	for _, id := range []int64{fridge.ID, pantry.ID} {
+		_, err := svc.photoStore.Create(ctx, id, "key", "image/jpeg", "hash")
+		require.NoError(t, err)
+	}
+	_, err = svc.db.ExecContext(ctx, `UPDATE photos SET uploaded_at = ? WHERE area_id = ?`,
+		time.Now().Add(-30*24*time.Hour).UTC(), pantry.ID)
+	require.NoError(t, err)
+
+	stale, err := svc.ListStaleAreas(ctx)
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	assert.Equal(t, "Pantry", stale[0].Name)
+	assert.InDelta(t, 30, stale[0].PhotoAge.Hours()/24, 0.1)
+
+	sum, err := svc.GetAreaSummary(ctx, pantry.ID)
+	require.NoError(t, err)
+	assert.True(t, sum.Stale)
+
+	sum, err = svc.GetAreaSummary(ctx, fridge.ID)
+	require.NoError(t, err)
+	assert.False(t, sum.Stale)
+}
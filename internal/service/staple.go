@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/units"
+)
+
+// ErrStapleInvalid is returned by AddStaple for a pattern with no words, a
+// minimum that isn't positive or an unknown unit.
+var ErrStapleInvalid = errors.New("invalid staple")
+
+// ErrStapleExists is returned by AddStaple when the pattern is already a
+// staple for the same area, ignoring case.
+var ErrStapleExists = errors.New("staple already exists")
+
+// stapleRepository is the subset of store.StapleStore that AreaService requires.
+type stapleRepository interface {
+	Create(ctx context.Context, st domain.Staple) (*domain.Staple, error)
+	List(ctx context.Context) ([]*domain.Staple, error)
+	Delete(ctx context.Context, id int64) (bool, error)
+}
+
+// StapleLevel is a staple with how much of it is in stock.
+type StapleLevel struct {
+	domain.Staple
+	// Have adds up the matching items in the staple's MinUnit; see
+	// stapleAmount.
+	Have float64
+	// Low is set when Have is below the staple's MinQuantity.
+	Low bool
+}
+
+// WithStapleStore sets where staples are kept. Without it there are none.
+func (s *AreaService) WithStapleStore(st stapleRepository) *AreaService {
+	s.stapleStore = st
+	return s
+}
+
+// ListStaples returns every staple, alphabetically.
+func (s *AreaService) ListStaples(ctx context.Context) ([]*domain.Staple, error) {
+	if s.stapleStore == nil {
+		return nil, nil
+	}
+	return s.stapleStore.List(ctx)
+}
+
+// AddStaple adds st to the staples. A minimum without a unit is a count. It
+// returns ErrStapleInvalid for a blank pattern, non-positive minimum or
+// unknown unit, ErrAreaNotFound for an unknown area and ErrStapleExists for
+// a duplicate.
+func (s *AreaService) AddStaple(ctx context.Context, st domain.Staple) (*domain.Staple, error) {
+	st.Pattern = strings.Join(strings.Fields(st.Pattern), " ")
+	if st.MinUnit == "" {
+		st.MinUnit = string(units.Count)
+	}
+	switch units.Unit(st.MinUnit) {
+	case units.Count, units.Gram, units.Millilitre:
+	default:
+		return nil, ErrStapleInvalid
+	}
+	if len(nameWords(st.Pattern)) == 0 || st.MinQuantity <= 0 {
+		return nil, ErrStapleInvalid
+	}
+	if s.stapleStore == nil {
+		return nil, errors.New("staple store not configured")
+	}
+	if st.AreaID != nil {
+		area, err := s.areaStore.GetByID(ctx, *st.AreaID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get area: %w", err)
+		}
+		if area == nil {
+			return nil, ErrAreaNotFound
+		}
+	}
+	created, err := s.stapleStore.Create(ctx, st)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, ErrStapleExists
+		}
+		return nil, err
+	}
+	return created, nil
+}
+
+// DeleteStaple removes a staple. It reports whether the staple existed.
+func (s *AreaService) DeleteStaple(ctx context.Context, id int64) (bool, error) {
+	if s.stapleStore == nil {
+		return false, nil
+	}
+	return s.stapleStore.Delete(ctx, id)
+}
+
+// StapleLevels works out how much of each staple is in stock from the items
+// currently in the inventory. A staple tied to an area counts the items in
+// it and its sub-areas; the others count every area.
+func (s *AreaService) StapleLevels(ctx context.Context) ([]*StapleLevel, error) {
+	staples, err := s.ListStaples(ctx)
+	if err != nil {
+		return nil, err
+	}
+	levels := make([]*StapleLevel, 0, len(staples))
+	if len(staples) == 0 {
+		return levels, nil
+	}
+
+	areas, err := s.areaStore.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list areas: %w", err)
+	}
+	parents := make(map[int64]int64, len(areas))
+	for _, a := range areas {
+		if a.ParentID != nil {
+			parents[a.ID] = *a.ParentID
+		}
+	}
+	items, err := s.itemStore.ListRecent(ctx, time.Time{}, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+
+	for _, st := range staples {
+		level := &StapleLevel{Staple: *st}
+		for _, it := range items {
+			if st.AreaID != nil && it.AreaID != *st.AreaID && parents[it.AreaID] != *st.AreaID {
+				continue
+			}
+			if stapleMatches(st.Pattern, it.Name) {
+				level.Have += stapleAmount(&it.Item, units.Unit(st.MinUnit))
+			}
+		}
+		level.Low = level.Have < st.MinQuantity
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// LowStaples returns the staples that are missing or below their minimum.
+func (s *AreaService) LowStaples(ctx context.Context) ([]*StapleLevel, error) {
+	levels, err := s.StapleLevels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	low := levels[:0]
+	for _, l := range levels {
+		if l.Low {
+			low = append(low, l)
+		}
+	}
+	return low, nil
+}
+
+// checkStaples logs the staples running low once an analysis has replaced
+// an area's items. Failures are logged, not returned.
+func (s *AreaService) checkStaples(ctx context.Context) {
+	if s.stapleStore == nil {
+		return
+	}
+	low, err := s.LowStaples(ctx)
+	if err != nil {
+		s.log(ctx).Error("failed to check staples", "error", err)
+		return
+	}
+	if len(low) == 0 {
+		return
+	}
+	names := make([]string, len(low))
+	for i, l := range low {
+		names[i] = l.Pattern
+	}
+	s.log(ctx).Info("staples running low", "staples", names)
+}
+
+// stapleMatches reports whether an item called name is the staple pattern.
+// The pattern's words must appear in order in the name, ignoring case,
+// punctuation and regular plurals: "egg" matches "Free-range eggs" but not
+// "Eggplant". A pattern word of four letters or more also matches the end of
+// a longer word, so "berry" matches "Blueberries" and "milk" "Buttermilk".
+func stapleMatches(pattern, name string) bool {
+	kw := nameWords(pattern)
+	words := nameWords(name)
+	if len(kw) == 0 {
+		return false
+	}
+	for i := 0; i+len(kw) <= len(words); i++ {
+		match := true
+		for j, k := range kw {
+			if !stapleWordMatches(words[i+j], k) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// stapleWordMatches compares a name word with a pattern word, each in any of
+// its singular forms.
+func stapleWordMatches(w, k string) bool {
+	for _, fw := range singularForms(w) {
+		for _, fk := range singularForms(k) {
+			if fw == fk || (len(fk) >= 4 && strings.HasSuffix(fw, fk)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// singularForms returns w and the words it could be a regular plural of.
+// Guessing "cookies" from "cookie" and "cherries" from "cherry" needs both
+// "-s" and "-ies" readings, so every plausible one is returned.
+func singularForms(w string) []string {
+	forms := []string{w}
+	if len(w) <= 3 || !strings.HasSuffix(w, "s") || strings.HasSuffix(w, "ss") {
+		return forms
+	}
+	forms = append(forms, strings.TrimSuffix(w, "s"))
+	if stem, ok := strings.CutSuffix(w, "ies"); ok {
+		forms = append(forms, stem+"y")
+	} else if stem, ok := strings.CutSuffix(w, "es"); ok {
+		forms = append(forms, stem)
+	}
+	return forms
+}
+
+// stapleAmount is how much of a staple measured in unit the item is. A
+// counted staple counts the item's number, or 1 for an item without one or
+// measured by weight or volume: a 500 g bag of rice is one bag. A staple
+// measured by weight or volume only counts items measured the same way.
+func stapleAmount(it *domain.Item, unit units.Unit) float64 {
+	if unit == units.Count {
+		if it.QuantityValue == nil || units.Unit(it.QuantityUnit) != units.Count {
+			return 1
+		}
+		return *it.QuantityValue
+	}
+	if it.QuantityValue == nil || units.Unit(it.QuantityUnit) != unit {
+		return 0
+	}
+	return *it.QuantityValue
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/store"
+	"github.com/vbonduro/kitchinv/internal/units"
+)
+
+func TestStapleMatches(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"eggs", "Egg", true},
+		{"egg", "Free-range eggs", true},
+		{"egg", "Eggplant", false},
+		{"tomato", "Cherry tomatoes", true},
+		{"tomatoes", "Tomato paste", true},
+		{"cherry", "Cherries", true},
+		{"cherries", "Cherry", true},
+		{"cookie", "Choc chip cookies", true},
+		{"cookies", "Cookie dough", true},
+		{"glass", "Glasses", true},
+		{"glasses", "Glass jar", true},
+		{"berry", "Mixed blueberries", true},
+		{"milk", "Buttermilk", true},
+		{"ham", "Graham crackers", false},
+		{"peanut butter", "Crunchy Peanut Butter", true},
+		{"peanut butter", "Butter, peanut", false},
+		{"MILK", "  oat milk ", true},
+		{"bus", "Buses", true},
+		{"  ", "Milk", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, stapleMatches(tt.pattern, tt.name), "%q vs %q", tt.pattern, tt.name)
+	}
+}
+
+func TestStapleAmount(t *testing.T) {
+	tests := []struct {
+		quantity string
+		unit     units.Unit
+		want     float64
+	}{
+		{"", units.Count, 1},
+		{"a bunch", units.Count, 1},
+		{"0", units.Count, 0},
+		{"2", units.Count, 2},
+		{"2 cartons", units.Count, 2},
+		{"1/2 bag", units.Count, 0.5},
+		{"3. ", units.Count, 3},
+		{"1.5 L", units.Count, 1},
+		{"2 x 500g", units.Count, 1},
+		{"1.5 L", units.Millilitre, 1500},
+		{"1,5 l", units.Millilitre, 1500},
+		{"2 x 500g", units.Gram, 1000},
+		{"2 x 500g", units.Millilitre, 0},
+		{"2 cartons", units.Millilitre, 0},
+		{"some", units.Gram, 0},
+	}
+	for _, tt := range tests {
+		value, unit := units.Nullable(tt.quantity)
+		it := &domain.Item{Quantity: tt.quantity, QuantityValue: value, QuantityUnit: string(unit)}
+		assert.InDelta(t, tt.want, stapleAmount(it, tt.unit), 1e-9, "%q in %s", tt.quantity, tt.unit)
+	}
+}
+
+func TestStapleLevels(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	levels, err := svc.StapleLevels(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, levels, "no staples without a store")
+	_, err = svc.AddStaple(ctx, domain.Staple{Pattern: "milk", MinQuantity: 1})
+	assert.Error(t, err)
+
+	svc.WithStapleStore(store.NewStapleStore(svc.db))
+	fridge, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	pantry, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	door, err := svc.CreateChildArea(ctx, fridge.ID, "Door", domain.AreaKindFridge)
+	require.NoError(t, err)
+	for _, it := range []struct {
+		areaID         int64
+		name, quantity string
+	}{
+		{fridge.ID, "Whole milk", "1 carton"},
+		{door.ID, "Oat milk", "1"},
+		{pantry.ID, "Eggs", "4"},
+		{pantry.ID, "Rice", "0"},
+		{pantry.ID, "Flour", "1.5 kg"},
+		{pantry.ID, "Flour", "2 x 250g"},
+	} {
+		_, err := svc.CreateItem(ctx, it.areaID, it.name, it.quantity)
+		require.NoError(t, err)
+	}
+
+	_, err = svc.AddStaple(ctx, domain.Staple{Pattern: " ", MinQuantity: 1})
+	assert.ErrorIs(t, err, ErrStapleInvalid)
+	_, err = svc.AddStaple(ctx, domain.Staple{Pattern: "milk", MinQuantity: 0})
+	assert.ErrorIs(t, err, ErrStapleInvalid)
+	_, err = svc.AddStaple(ctx, domain.Staple{Pattern: "milk", MinQuantity: 1, MinUnit: "cups"})
+	assert.ErrorIs(t, err, ErrStapleInvalid)
+	missing := int64(999)
+	_, err = svc.AddStaple(ctx, domain.Staple{Pattern: "milk", MinQuantity: 1, AreaID: &missing})
+	assert.ErrorIs(t, err, ErrAreaNotFound)
+
+	for _, st := range []domain.Staple{
+		{Pattern: "milk", MinQuantity: 2, AreaID: &fridge.ID},
+		{Pattern: "egg", MinQuantity: 6},
+		{Pattern: "rice", MinQuantity: 1},
+		{Pattern: "butter", MinQuantity: 1},
+		{Pattern: "eggs", MinQuantity: 2, AreaID: &pantry.ID},
+		{Pattern: "flour", MinQuantity: 2000, MinUnit: "g"},
+	} {
+		created, err := svc.AddStaple(ctx, st)
+		require.NoError(t, err)
+		if st.MinUnit == "" {
+			assert.Equal(t, "count", created.MinUnit)
+		}
+	}
+	_, err = svc.AddStaple(ctx, domain.Staple{Pattern: "Milk", MinQuantity: 3, AreaID: &fridge.ID})
+	assert.ErrorIs(t, err, ErrStapleExists)
+
+	levels, err = svc.StapleLevels(ctx)
+	require.NoError(t, err)
+	have := make(map[string]float64)
+	for _, l := range levels {
+		have[l.Pattern] = l.Have
+	}
+	assert.Equal(t, map[string]float64{"butter": 0, "egg": 4, "eggs": 4, "flour": 2000, "milk": 2, "rice": 0}, have,
+		"the fridge's milk includes its door and both bags of flour are added up in grams")
+
+	low, err := svc.LowStaples(ctx)
+	require.NoError(t, err)
+	var names []string
+	for _, l := range low {
+		names = append(names, l.Pattern)
+	}
+	assert.Equal(t, []string{"butter", "egg", "rice"}, names)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+// DefaultSuggestPrompt asks the model for recipe ideas; the inventory is
+// appended to it.
+const DefaultSuggestPrompt = `Suggest 3 recipes I could cook using mostly the ingredients I have on hand, listed below. For each recipe give its name, which of my ingredients it uses, anything else I would need, and brief steps. Keep the whole answer short and use plain text.`
+
+// DefaultSuggestMaxItems caps how many items are sent with a recipe request,
+// keeping the prompt small enough for local models' context windows.
+const DefaultSuggestMaxItems = 100
+
+// ErrSuggestUnsupported is returned by SuggestRecipes when the configured
+// backend can't generate text.
+var ErrSuggestUnsupported = errors.New("the configured model backend can't suggest recipes")
+
+// ErrNothingToCook is returned by SuggestRecipes when the chosen areas have
+// no items in stock.
+var ErrNothingToCook = errors.New("no items to suggest recipes from")
+
+// WithSuggestPrompt replaces DefaultSuggestPrompt. A blank prompt keeps the
+// default.
+func (s *AreaService) WithSuggestPrompt(prompt string) *AreaService {
+	s.suggestPrompt = vision.PromptOrDefault(prompt, DefaultSuggestPrompt)
+	return s
+}
+
+// WithSuggestMaxItems sets how many items at most are sent with a recipe
+// request. n <= 0 keeps DefaultSuggestMaxItems.
+func (s *AreaService) WithSuggestMaxItems(n int) *AreaService {
+	if n > 0 {
+		s.suggestMaxItems = n
+	}
+	return s
+}
+
+// CanSuggestRecipes reports whether the vision backend can also generate
+// text, which SuggestRecipes needs.
+func (s *AreaService) CanSuggestRecipes() bool {
+	_, ok := s.visionAPI.(vision.TextGenerator)
+	return ok
+}
+
+// SuggestRecipes asks the model for recipe ideas using the items in stock in
+// areaIDs and their sub-areas, or in every area when areaIDs is empty, and
+// streams the reply to onDelta. Only the newest items up to the configured
+// cap are sent. The request takes one of the analysis slots and is bound by
+// the analysis timeout.
+func (s *AreaService) SuggestRecipes(ctx context.Context, areaIDs []int64, onDelta func(string)) error {
+	gen, ok := s.visionAPI.(vision.TextGenerator)
+	if !ok {
+		return ErrSuggestUnsupported
+	}
+	prompt, err := s.suggestPromptFor(ctx, areaIDs)
+	if err != nil {
+		return err
+	}
+	return s.generateText(ctx, gen, prompt, onDelta)
+}
+
+// generateText streams gen's reply to prompt to onDelta, holding an analysis
+// slot and applying the analysis timeout. A panicking generator is reported
+// as an error.
+func (s *AreaService) generateText(ctx context.Context, gen vision.TextGenerator, prompt string, onDelta func(string)) (err error) {
+	release, err := s.acquireAnalysisSlot()
+	if err != nil {
+		return err
+	}
+	defer release()
+	if s.analysisTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.analysisTimeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("text generator panicked: %v", r)
+		}
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%w after %s: %w", ErrAnalysisTimeout, s.analysisTimeout, err)
+		}
+	}()
+	return gen.GenerateText(ctx, prompt, onDelta)
+}
+
+// suggestPromptFor builds the recipe prompt listing the in-stock items of
+// areaIDs (all areas if empty). Items with the same name are listed once.
+func (s *AreaService) suggestPromptFor(ctx context.Context, areaIDs []int64) (string, error) {
+	var include map[int64]bool
+	if len(areaIDs) > 0 {
+		areas, err := s.areaStore.List(ctx)
+		if err != nil {
+			return "", err
+		}
+		selected := make(map[int64]bool, len(areaIDs))
+		for _, id := range areaIDs {
+			selected[id] = true
+		}
+		include = make(map[int64]bool)
+		for _, a := range areas {
+			if selected[a.ID] || (a.ParentID != nil && selected[*a.ParentID]) {
+				include[a.ID] = true
+			}
+		}
+		for _, id := range areaIDs {
+			if !include[id] {
+				return "", ErrAreaNotFound
+			}
+		}
+	}
+
+	items, err := s.itemStore.ListRecent(ctx, time.Time{}, 0, 0)
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	seen := make(map[string]bool)
+	for _, it := range items {
+		key := strings.ToLower(strings.TrimSpace(it.Name))
+		if (include != nil && !include[it.AreaID]) || isOutOfStock(&it.Item) || seen[key] {
+			continue
+		}
+		seen[key] = true
+		line := "- " + it.Name
+		if q := strings.TrimSpace(it.Quantity); q != "" {
+			line += " (" + q + ")"
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "", ErrNothingToCook
+	}
+
+	var b strings.Builder
+	b.WriteString(s.suggestPrompt)
+	b.WriteString("\n\nIngredients on hand:\n")
+	b.WriteString(strings.Join(lines[:min(len(lines), s.suggestMaxItems)], "\n"))
+	if extra := len(lines) - s.suggestMaxItems; extra > 0 {
+		fmt.Fprintf(&b, "\n(and %d more not listed)", extra)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// textVision is a stubVision that can also generate text, recording the
+// prompt and replying with deltas.
+type textVision struct {
+	stubVision
+	prompt string
+	deltas []string
+}
+
+func (v *textVision) GenerateText(_ context.Context, prompt string, onDelta func(string)) error {
+	v.prompt = prompt
+	for _, d := range v.deltas {
+		onDelta(d)
+	}
+	return v.err
+}
+
+func TestSuggestRecipes(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	assert.False(t, svc.CanSuggestRecipes())
+	assert.ErrorIs(t, svc.SuggestRecipes(context.Background(), nil, func(string) {}), ErrSuggestUnsupported)
+
+	gen := &textVision{deltas: []string{"1. Omelette", "\n2. Pancakes"}}
+	svc.visionAPI = gen
+	svc.WithSuggestPrompt("Ideas please.")
+	ctx := context.Background()
+	assert.True(t, svc.CanSuggestRecipes())
+
+	fridge, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	door, err := svc.CreateChildArea(ctx, fridge.ID, "Door", domain.AreaKindOther)
+	require.NoError(t, err)
+	pantry, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	for _, it := range []struct {
+		area     int64
+		name     string
+		quantity string
+	}{
+		{fridge.ID, "Eggs", "6"},
+		{door.ID, "Milk", "1"},
+		{door.ID, "Butter", "0"},
+		{pantry.ID, "Flour", "1"},
+		{pantry.ID, "eggs", "12"},
+	} {
+		_, err := svc.CreateItem(ctx, it.area, it.name, it.quantity)
+		require.NoError(t, err)
+	}
+
+	var reply strings.Builder
+	require.NoError(t, svc.SuggestRecipes(ctx, []int64{fridge.ID}, func(s string) { reply.WriteString(s) }))
+	assert.Equal(t, "1. Omelette\n2. Pancakes", reply.String())
+	assert.Equal(t, "Ideas please.\n\nIngredients on hand:\n- Milk (1)\n- Eggs (6)", gen.prompt, "sub-areas are included, empty items are not")
+
+	require.NoError(t, svc.SuggestRecipes(ctx, nil, func(string) {}))
+	assert.Equal(t, "Ideas please.\n\nIngredients on hand:\n- eggs (12)\n- Flour (1)\n- Milk (1)", gen.prompt, "a name is listed once")
+
+	svc.WithSuggestMaxItems(1)
+	require.NoError(t, svc.SuggestRecipes(ctx, nil, func(string) {}))
+	assert.Equal(t, "Ideas please.\n\nIngredients on hand:\n- eggs (12)\n(and 2 more not listed)", gen.prompt)
+
+	assert.ErrorIs(t, svc.SuggestRecipes(ctx, []int64{pantry.ID + 100}, func(string) {}), ErrAreaNotFound)
+	empty, err := svc.CreateArea(ctx, "Garage")
+	require.NoError(t, err)
+	assert.ErrorIs(t, svc.SuggestRecipes(ctx, []int64{empty.ID}, func(string) {}), ErrNothingToCook)
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/tracing"
+	"github.com/vbonduro/kitchinv/internal/tracing/tracingtest"
+)
+
+func TestUploadPhotoSpans(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	c := tracingtest.Start(t)
+
+	ctx, req := tracing.Start(context.Background(), "POST /areas/{id}/photos", tracing.KindServer)
+	area, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte("img"), "image/jpeg")
+	require.NoError(t, err)
+	req.End()
+
+	spans := c.Spans()
+	root := tracingtest.Named(spans, "POST /areas/{id}/photos")
+	upload := tracingtest.Named(spans, "AreaService.UploadPhotos")
+	require.NotNil(t, root)
+	require.NotNil(t, upload)
+	assert.Equal(t, root.SpanID, upload.ParentID)
+	// The upload runs detached from the request's cancellation, but its
+	// steps still belong to the request's trace.
+	for _, name := range []string{"photo.save", "db.photo.create", "vision.analyze", "db.items.replace"} {
+		s := tracingtest.Named(spans, name)
+		if assert.NotNil(t, s, name) {
+			assert.Equal(t, root.TraceID, s.TraceID, name)
+			assert.Equal(t, upload.SpanID, s.ParentID, name)
+		}
+	}
+}
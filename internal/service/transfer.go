@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// InventoryExportVersion is the InventoryExport format version written and
+// accepted.
+const InventoryExportVersion = 1
+
+// InventoryExport is the inventory as the `kitchinv export` and `kitchinv
+// import` commands write and read it: every area, parents before their
+// sub-areas, with its items. Photos are not included.
+type InventoryExport struct {
+	Version    int
+	ExportedAt time.Time
+	Areas      []ExportedArea
+}
+
+// ExportedArea is an area in an InventoryExport. Parent names the area it
+// is inside, which comes earlier in the export.
+type ExportedArea struct {
+	Name   string
+	Parent string `json:",omitempty"`
+	Kind   domain.AreaKind
+	Prompt string `json:",omitempty"`
+	Items  []ExportedItem
+}
+
+// ExportedItem is an item in an ExportedArea.
+type ExportedItem struct {
+	Name     string
+	Quantity string
+}
+
+// ImportResult counts what ImportInventory added.
+type ImportResult struct {
+	AreasCreated int
+	ItemsCreated int
+}
+
+// ExportInventory returns every area and its items.
+func (s *AreaService) ExportInventory(ctx context.Context, exportedAt time.Time) (*InventoryExport, error) {
+	areas, err := s.areaStore.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list areas: %w", err)
+	}
+	out := &InventoryExport{Version: InventoryExportVersion, ExportedAt: exportedAt, Areas: []ExportedArea{}}
+	for _, area := range reportOrder(areas) {
+		items, err := s.itemStore.ListByAreaID(ctx, area.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list items for area %d: %w", area.ID, err)
+		}
+		ea := ExportedArea{Name: area.Name, Parent: area.ParentName, Kind: area.Kind, Prompt: area.Prompt, Items: make([]ExportedItem, len(items))}
+		for i, item := range items {
+			ea.Items[i] = ExportedItem{Name: item.Name, Quantity: item.Quantity}
+		}
+		out.Areas = append(out.Areas, ea)
+	}
+	return out, nil
+}
+
+// ImportInventory adds an export's areas and items. An area matching an
+// existing one by name and parent is reused rather than duplicated, and a
+// prompt in the export replaces its prompt; items are always added, as
+// hand-entered items.
+func (s *AreaService) ImportInventory(ctx context.Context, in *InventoryExport) (ImportResult, error) {
+	var res ImportResult
+	if in.Version != InventoryExportVersion {
+		return res, fmt.Errorf("unsupported export version %d; expected %d", in.Version, InventoryExportVersion)
+	}
+	existing, err := s.areaStore.List(ctx)
+	if err != nil {
+		return res, fmt.Errorf("failed to list areas: %w", err)
+	}
+	type areaKey struct{ parent, name string }
+	ids := make(map[areaKey]int64, len(existing))
+	for _, a := range existing {
+		ids[areaKey{a.ParentName, a.Name}] = a.ID
+	}
+
+	for _, ea := range in.Areas {
+		kind := ea.Kind
+		if kind == "" {
+			kind = domain.AreaKindOther
+		}
+		key := areaKey{ea.Parent, ea.Name}
+		id, ok := ids[key]
+		if !ok {
+			var area *domain.Area
+			if ea.Parent == "" {
+				area, err = s.CreateAreaWithKind(ctx, ea.Name, kind)
+			} else {
+				parentID, found := ids[areaKey{"", ea.Parent}]
+				if !found {
+					return res, fmt.Errorf("area %q is inside %q, which is not a top-level area", ea.Name, ea.Parent)
+				}
+				area, err = s.CreateChildArea(ctx, parentID, ea.Name, kind)
+			}
+			if err != nil {
+				return res, fmt.Errorf("failed to create area %q: %w", ea.Name, err)
+			}
+			id = area.ID
+			ids[key] = id
+			res.AreasCreated++
+		}
+		if ea.Prompt != "" {
+			if _, err := s.SetAreaPrompt(ctx, id, ea.Prompt); err != nil {
+				return res, fmt.Errorf("failed to set prompt of area %q: %w", ea.Name, err)
+			}
+		}
+		for _, item := range ea.Items {
+			if _, err := s.CreateItem(ctx, id, item.Name, item.Quantity); err != nil {
+				return res, fmt.Errorf("failed to add %q to area %q: %w", item.Name, ea.Name, err)
+			}
+			res.ItemsCreated++
+		}
+	}
+	s.log(ctx).Info("inventory imported", "areas_created", res.AreasCreated, "items_created", res.ItemsCreated)
+	return res, nil
+}
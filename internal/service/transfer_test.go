@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+func TestExportImportInventory(t *testing.T) {
+	src, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	freezer, err := src.CreateAreaWithKind(ctx, "Freezer", domain.AreaKindFreezer)
+	require.NoError(t, err)
+	basket, err := src.CreateChildArea(ctx, freezer.ID, "Top basket", domain.AreaKindOther)
+	require.NoError(t, err)
+	_, err = src.SetAreaPrompt(ctx, freezer.ID, "Count ice trays too.")
+	require.NoError(t, err)
+	_, err = src.CreateItem(ctx, basket.ID, "Peas", "1 bag")
+	require.NoError(t, err)
+
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	exp, err := src.ExportInventory(ctx, at)
+	require.NoError(t, err)
+	assert.Equal(t, &InventoryExport{Version: 1, ExportedAt: at, Areas: []ExportedArea{
+		{Name: "Freezer", Kind: domain.AreaKindFreezer, Prompt: "Count ice trays too.", Items: []ExportedItem{}},
+		{Name: "Top basket", Parent: "Freezer", Kind: domain.AreaKindOther, Items: []ExportedItem{{Name: "Peas", Quantity: "1 bag"}}},
+	}}, exp)
+
+	// Import into the same inventory with the basket gone: the freezer is
+	// reused and the basket recreated.
+	require.NoError(t, src.DeleteArea(ctx, basket.ID))
+	res, err := src.ImportInventory(ctx, exp)
+	require.NoError(t, err)
+	assert.Equal(t, ImportResult{AreasCreated: 1, ItemsCreated: 1}, res)
+
+	again, err := src.ExportInventory(ctx, at)
+	require.NoError(t, err)
+	assert.Equal(t, exp, again)
+}
+
+func TestImportInventoryErrors(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := svc.ImportInventory(ctx, &InventoryExport{Version: 2})
+	assert.ErrorContains(t, err, "unsupported export version 2")
+
+	_, err = svc.ImportInventory(ctx, &InventoryExport{Version: 1, Areas: []ExportedArea{{Name: "Basket", Parent: "Freezer"}}})
+	assert.ErrorContains(t, err, `inside "Freezer"`)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+// TokenPrices are the vision backend's prices in US dollars per million
+// tokens, used to estimate what each analysis cost. Zero prices record no
+// cost, which is right for local backends.
+type TokenPrices struct {
+	InputPerMTok  float64
+	OutputPerMTok float64
+}
+
+// Prompt-cache writes cost a quarter more than plain input tokens and reads
+// a tenth as much, as Anthropic prices them.
+const (
+	cacheWritePriceFactor = 1.25
+	cacheReadPriceFactor  = 0.1
+)
+
+// cost estimates the price of u.
+func (p TokenPrices) cost(u vision.Usage) float64 {
+	input := float64(u.InputTokens) + float64(u.CacheWriteTokens)*cacheWritePriceFactor + float64(u.CacheReadTokens)*cacheReadPriceFactor
+	return (input*p.InputPerMTok + float64(u.OutputTokens)*p.OutputPerMTok) / 1e6
+}
+
+// WithTokenPrices sets the prices used to estimate each analysis's cost.
+func (s *AreaService) WithTokenPrices(p TokenPrices) *AreaService {
+	s.tokenPrices = p
+	return s
+}
+
+// recordUsage stores what the analysis consumed on the photo, and on the
+// in-memory copy so the caller sees it. Failures are logged, not returned.
+func (s *AreaService) recordUsage(ctx context.Context, photo *domain.Photo, u vision.Usage) {
+	if u == (vision.Usage{}) {
+		return
+	}
+	photo.AnalysisUsage = domain.AnalysisUsage{
+		InputTokens:    u.InputTokens,
+		OutputTokens:   u.OutputTokens,
+		CostUSD:        s.tokenPrices.cost(u),
+		EvalDurationMs: u.Duration.Milliseconds(),
+
+		CacheWriteTokens: u.CacheWriteTokens,
+		CacheReadTokens:  u.CacheReadTokens,
+	}
+	s.log(ctx).Info("vision analysis usage", "photo_id", photo.ID,
+		"input_tokens", u.InputTokens, "output_tokens", u.OutputTokens,
+		"cache_write_tokens", u.CacheWriteTokens, "cache_read_tokens", u.CacheReadTokens,
+		"cost_usd", photo.CostUSD, "eval_duration", u.Duration)
+	if err := s.photoStore.SetUsage(ctx, photo.ID, photo.AnalysisUsage); err != nil {
+		s.log(ctx).Error("failed to record analysis usage", "photo_id", photo.ID, "error", err)
+	}
+}
+
+// MonthlyUsage totals analysis usage for the current month and the months
+// before it, newest first. Months with no analyses are left out. Usage is
+// kept on photo records, so photos that have since been deleted no longer
+// count.
+func (s *AreaService) MonthlyUsage(ctx context.Context, months int) ([]domain.MonthlyUsage, error) {
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -(months - 1), 0)
+	usage, err := s.photoStore.MonthlyUsage(ctx, start)
+	if err != nil {
+		return nil, err
+	}
+	if usage == nil {
+		usage = []domain.MonthlyUsage{}
+	}
+	return usage, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/db"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/store"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+func TestTokenPricesCost(t *testing.T) {
+	p := TokenPrices{InputPerMTok: 5, OutputPerMTok: 25}
+	assert.InDelta(t, 0.0125, p.cost(vision.Usage{InputTokens: 1000, OutputTokens: 300}), 1e-9)
+	assert.Zero(t, TokenPrices{}.cost(vision.Usage{InputTokens: 1000, OutputTokens: 300}))
+
+	// 100 plain input tokens, 2000 written to the cache at 1.25x and 10000
+	// read from it at 0.1x make 3600 tokens' worth of input.
+	cached := vision.Usage{InputTokens: 100, OutputTokens: 300, CacheWriteTokens: 2000, CacheReadTokens: 10000}
+	assert.InDelta(t, 0.0255, p.cost(cached), 1e-9)
+}
+
+func TestAreaServiceUploadPhoto_RecordsUsage(t *testing.T) {
+	d, err := db.OpenForTesting()
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, d.Close()) })
+
+	vis := &stubVision{result: &vision.AnalysisResult{
+		Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}},
+		Usage: vision.Usage{InputTokens: 2000, OutputTokens: 400, Duration: 1500 * time.Millisecond},
+	}}
+	svc := NewAreaService(
+		store.NewAreaStore(d),
+		store.NewPhotoStore(d),
+		store.NewItemStore(d),
+		store.NewItemEditStore(d),
+		store.NewSnapshotStore(d),
+		&noopOverrideStore{},
+		vis,
+		newStubPhotoStore(),
+		slog.Default(),
+	).WithDB(d).WithTokenPrices(TokenPrices{InputPerMTok: 5, OutputPerMTok: 25})
+	ctx := context.Background()
+
+	usage, err := svc.MonthlyUsage(ctx, 12)
+	require.NoError(t, err)
+	assert.Empty(t, usage)
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	photo, _, err := svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
+	require.NoError(t, err)
+
+	want := domain.AnalysisUsage{InputTokens: 2000, OutputTokens: 400, CostUSD: 0.02, EvalDurationMs: 1500}
+	assert.Equal(t, want.InputTokens, photo.InputTokens)
+	assert.InDelta(t, want.CostUSD, photo.CostUSD, 1e-9)
+	_, _, stored, err := svc.GetAreaWithItems(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Equal(t, want.TotalTokens(), stored.TotalTokens())
+	assert.Equal(t, want.EvalDurationMs, stored.EvalDurationMs)
+
+	usage, err = svc.MonthlyUsage(ctx, 12)
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	assert.Equal(t, 1, usage[0].Analyses)
+	assert.Equal(t, 2400, usage[0].TotalTokens())
+}
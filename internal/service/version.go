@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// AreaItemsVersion fingerprints an area's items without loading them. It
+// changes whenever an item is added, edited, retired, given a thumbnail or
+// removed, so a client holding the same version already has the current
+// list. It needs WithDB.
+func (s *AreaService) AreaItemsVersion(ctx context.Context, areaID int64) (string, error) {
+	if s.db == nil {
+		return "", errors.New("versions need a database")
+	}
+	// Item IDs are never reused, so an added item raises the newest ID and a
+	// removed one lowers the count; edits and retirements bump a version.
+	// Unlike updated_at, which only has second precision, these can't miss
+	// two changes made in the same second.
+	var v string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) || ':' || COALESCE(MAX(id), 0) || ':' || COALESCE(SUM(version), 0) || ':' || COUNT(crop_key)
+		FROM items WHERE area_id = ?`, areaID).Scan(&v)
+	if err != nil {
+		return "", fmt.Errorf("failed to get area items version: %w", err)
+	}
+	return v, nil
+}
+
+// AreaListVersion fingerprints everything the area list shows: the areas'
+// names, kinds, nesting and order, every item, the photos' analysis
+// and the staples. It needs WithDB.
+func (s *AreaService) AreaListVersion(ctx context.Context) (string, error) {
+	if s.db == nil {
+		return "", errors.New("versions need a database")
+	}
+	var v string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COALESCE(group_concat(id || '.' || sort_order || '.' || COALESCE(parent_id, '') || '.' || kind || '.' || quote(name), ','), '')
+			 FROM (SELECT * FROM areas WHERE deleted_at IS NULL ORDER BY id)) || '/' ||
+			(SELECT COUNT(*) || ':' || COALESCE(MAX(id), 0) || ':' || COALESCE(SUM(version), 0) || ':' || COUNT(crop_key)
+			 FROM items) || '/' ||
+			(SELECT COUNT(*) || ':' || COALESCE(MAX(id), 0) || ':' || COALESCE(SUM(analysis_status = 'complete'), 0) || ':' ||
+			        COALESCE(SUM(analysis_status = 'failed'), 0) || ':' || COALESCE(SUM(items_kept), 0) FROM photos) || '/' ||
+			(SELECT COUNT(*) || ':' || COALESCE(MAX(id), 0) FROM staples)`).Scan(&v)
+	if err != nil {
+		return "", fmt.Errorf("failed to get area list version: %w", err)
+	}
+	return v, nil
+}
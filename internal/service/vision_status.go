@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+// VisionStatus is the outcome of the most recent vision backend check.
+// CheckedAt is zero until CheckVision has run, in which case OK is true:
+// an unchecked backend isn't reported as broken.
+type VisionStatus struct {
+	OK        bool      `json:"ok"`
+	Message   string    `json:"message,omitempty"`
+	CheckedAt time.Time `json:"checked_at,omitzero"`
+}
+
+// CheckVision verifies the vision backend is usable, e.g. that the Ollama
+// model is installed or the Claude API key is accepted, and records the
+// result for VisionStatus. Backends with nothing to check always pass.
+func (s *AreaService) CheckVision(ctx context.Context) error {
+	var err error
+	if c, ok := s.visionAPI.(vision.Checker); ok {
+		err = c.Check(ctx)
+	}
+
+	st := VisionStatus{OK: err == nil, CheckedAt: time.Now().UTC()}
+	if err != nil {
+		st.Message = err.Error()
+	}
+	s.visionStatusMu.Lock()
+	s.visionStatus = st
+	s.visionStatusMu.Unlock()
+	return err
+}
+
+// VisionStatus returns the result of the last CheckVision call.
+func (s *AreaService) VisionStatus() VisionStatus {
+	s.visionStatusMu.RLock()
+	defer s.visionStatusMu.RUnlock()
+	if s.visionStatus.CheckedAt.IsZero() {
+		return VisionStatus{OK: true}
+	}
+	return s.visionStatus
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// checkVision is a stubVision that also implements vision.Checker.
+type checkVision struct {
+	stubVision
+	err error
+}
+
+func (c *checkVision) Check(context.Context) error { return c.err }
+
+func TestAreaServiceCheckVision(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	assert.True(t, svc.VisionStatus().OK, "unchecked backend should report OK")
+
+	// stubVision has nothing to check.
+	require.NoError(t, svc.CheckVision(ctx))
+	st := svc.VisionStatus()
+	assert.True(t, st.OK)
+	assert.False(t, st.CheckedAt.IsZero())
+
+	vis := &checkVision{err: errors.New("model llava not installed")}
+	svc.visionAPI = vis
+	require.Error(t, svc.CheckVision(ctx))
+	st = svc.VisionStatus()
+	assert.False(t, st.OK)
+	assert.Equal(t, "model llava not installed", st.Message)
+
+	vis.err = nil
+	require.NoError(t, svc.CheckVision(ctx))
+	st = svc.VisionStatus()
+	assert.True(t, st.OK)
+	assert.Empty(t, st.Message)
+}
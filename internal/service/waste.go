@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// ErrItemNotFound is returned when an item doesn't exist in the given area.
+var ErrItemNotFound = errors.New("item not found")
+
+// ErrItemNotActive is returned when an item has already been consumed or
+// discarded.
+var ErrItemNotActive = errors.New("item has already been consumed or discarded")
+
+// ConsumeItem marks an item of areaID as used up. It leaves the inventory but
+// is kept, with when it was consumed, and re-analysis leaves it alone.
+func (s *AreaService) ConsumeItem(ctx context.Context, areaID, itemID int64) (*domain.Item, error) {
+	return s.retireItem(ctx, areaID, itemID, domain.ItemStatusConsumed)
+}
+
+// DiscardItem marks an item of areaID as thrown away, counting it as waste.
+func (s *AreaService) DiscardItem(ctx context.Context, areaID, itemID int64) (*domain.Item, error) {
+	return s.retireItem(ctx, areaID, itemID, domain.ItemStatusDiscarded)
+}
+
+// retireItem moves an active item to status. It returns ErrItemNotFound for
+// an item outside areaID and ErrItemNotActive for one already retired.
+func (s *AreaService) retireItem(ctx context.Context, areaID, itemID int64, status domain.ItemStatus) (*domain.Item, error) {
+	item, err := s.itemStore.GetByID(ctx, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item: %w", err)
+	}
+	if item == nil || item.AreaID != areaID {
+		return nil, ErrItemNotFound
+	}
+	ok, err := s.itemStore.SetStatus(ctx, itemID, status)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrItemNotActive
+	}
+	s.touchArea(ctx, areaID)
+	s.log(ctx).Info("item retired", "item_id", itemID, "area_id", areaID, "status", status)
+	return s.itemStore.GetByID(ctx, itemID)
+}
+
+// MonthlyWaste counts the items used up and thrown away in the current month
+// and the months before it, newest first. Months with neither are left out.
+func (s *AreaService) MonthlyWaste(ctx context.Context, months int) ([]domain.MonthlyWaste, error) {
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -(months - 1), 0)
+	waste, err := s.itemStore.MonthlyWaste(ctx, start)
+	if err != nil {
+		return nil, err
+	}
+	if waste == nil {
+		waste = []domain.MonthlyWaste{}
+	}
+	return waste, nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+func TestRetireItem(t *testing.T) {
+	svc, cleanup := newTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	svc.visionAPI = &sequenceVision{results: []*vision.AnalysisResult{
+		{Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}, {Name: "Eggs", Quantity: "6"}}},
+		{Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}}},
+	}}
+	fridge, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	pantry, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+
+	_, items, err := svc.UploadPhoto(ctx, fridge.ID, []byte{0xFF, 0xD8, 0x01}, "image/jpeg")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	milk, eggs := items[0], items[1]
+
+	_, err = svc.ConsumeItem(ctx, pantry.ID, milk.ID)
+	assert.ErrorIs(t, err, ErrItemNotFound, "the item must be in the area")
+	got, err := svc.ConsumeItem(ctx, fridge.ID, milk.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ItemStatusConsumed, got.Status)
+	assert.NotNil(t, got.ConsumedAt)
+	_, err = svc.DiscardItem(ctx, fridge.ID, milk.ID)
+	assert.ErrorIs(t, err, ErrItemNotActive)
+	_, err = svc.DiscardItem(ctx, fridge.ID, eggs.ID)
+	require.NoError(t, err)
+
+	// Re-analysis replaces active items only.
+	_, items, err = svc.UploadPhoto(ctx, fridge.ID, []byte{0xFF, 0xD8, 0x02}, "image/jpeg")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.NotEqual(t, milk.ID, items[0].ID)
+	all, err := svc.ListItemsPage(ctx, fridge.ID, domain.ItemPage{Status: domain.ItemStatusAll})
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	waste, err := svc.MonthlyWaste(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, waste, 1)
+	assert.Equal(t, 1, waste[0].Consumed)
+	assert.Equal(t, 1, waste[0].Discarded)
+}
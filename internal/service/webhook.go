@@ -0,0 +1,379 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// Webhook event types.
+const (
+	EventAnalysisCompleted = "analysis.completed"
+	EventAnalysisFailed    = "analysis.failed"
+	EventAreaDeleted       = "area.deleted"
+	// EventWebhookTest is sent only by SendTestWebhook; hooks need not
+	// subscribe to it.
+	EventWebhookTest = "webhook.test"
+)
+
+// WebhookEvents lists the event types a webhook can subscribe to.
+var WebhookEvents = []string{EventAnalysisCompleted, EventAnalysisFailed, EventAreaDeleted}
+
+// Webhook request headers. The signature is "sha256=" followed by the hex
+// HMAC-SHA256 of the request body keyed with the webhook's secret.
+const (
+	WebhookEventHeader     = "X-Kitchinv-Event"
+	WebhookSignatureHeader = "X-Kitchinv-Signature"
+)
+
+// ErrWebhookInvalid is returned when a webhook's URL or event list is
+// unusable; the wrapped message says which.
+var ErrWebhookInvalid = errors.New("invalid webhook")
+
+// DefaultWebhookBackoff is the wait before each retry of a failed delivery.
+var DefaultWebhookBackoff = []time.Duration{5 * time.Second, 30 * time.Second, 2 * time.Minute}
+
+// DefaultWebhookTimeout bounds a single delivery attempt.
+const DefaultWebhookTimeout = 10 * time.Second
+
+// maxWebhookDeliveries caps ListWebhookDeliveries.
+const maxWebhookDeliveries = 100
+
+// webhookRepository is the subset of store.WebhookStore that AreaService requires.
+type webhookRepository interface {
+	Create(ctx context.Context, h domain.Webhook) (*domain.Webhook, error)
+	GetByID(ctx context.Context, id int64) (*domain.Webhook, error)
+	List(ctx context.Context) ([]*domain.Webhook, error)
+	Update(ctx context.Context, h domain.Webhook) (*domain.Webhook, error)
+	Delete(ctx context.Context, id int64) (bool, error)
+	RecordDelivery(ctx context.Context, d domain.WebhookDelivery) (*domain.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]*domain.WebhookDelivery, error)
+}
+
+// WebhookPayload is the JSON body POSTed to a webhook.
+type WebhookPayload struct {
+	Event        string        `json:"event"`
+	Timestamp    time.Time     `json:"timestamp"`
+	Area         *WebhookArea  `json:"area,omitempty"`
+	PhotoID      int64         `json:"photo_id,omitempty"`
+	ItemsAdded   []WebhookItem `json:"items_added,omitempty"`
+	ItemsRemoved []WebhookItem `json:"items_removed,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// WebhookArea identifies the area an event is about.
+type WebhookArea struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// WebhookItem is an item named in a payload.
+type WebhookItem struct {
+	Name     string `json:"name"`
+	Quantity string `json:"quantity"`
+}
+
+// WithWebhooks turns on outbound webhooks, kept in st. Without it no events
+// are sent.
+func (s *AreaService) WithWebhooks(st webhookRepository) *AreaService {
+	s.webhookStore = st
+	if s.webhookClient == nil {
+		s.webhookClient = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+	if s.webhookBackoff == nil {
+		s.webhookBackoff = DefaultWebhookBackoff
+	}
+	if s.webhookCtx == nil {
+		s.webhookCtx, s.stopWebhooks = context.WithCancel(context.Background())
+	}
+	return s
+}
+
+// Close abandons pending webhook retries and waits for attempts in flight,
+// each bounded by the client's timeout, to finish and be recorded. Close the
+// service before the stores it uses; events after it are not sent.
+func (s *AreaService) Close() {
+	s.webhookMu.Lock()
+	if s.stopWebhooks != nil {
+		s.stopWebhooks()
+	}
+	s.webhookMu.Unlock()
+	s.webhookWG.Wait()
+}
+
+// ListWebhooks returns every webhook in the order they were added.
+func (s *AreaService) ListWebhooks(ctx context.Context) ([]*domain.Webhook, error) {
+	if s.webhookStore == nil {
+		return nil, nil
+	}
+	return s.webhookStore.List(ctx)
+}
+
+// CreateWebhook adds a webhook. A random secret is generated when h.Secret
+// is empty.
+func (s *AreaService) CreateWebhook(ctx context.Context, h domain.Webhook) (*domain.Webhook, error) {
+	if err := normalizeWebhook(&h); err != nil {
+		return nil, err
+	}
+	if s.webhookStore == nil {
+		return nil, errors.New("webhook store not configured")
+	}
+	if h.Secret == "" {
+		var b [32]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		h.Secret = hex.EncodeToString(b[:])
+	}
+	return s.webhookStore.Create(ctx, h)
+}
+
+// UpdateWebhook replaces a webhook's settings, keeping its secret when
+// h.Secret is empty. It returns nil if the webhook does not exist.
+func (s *AreaService) UpdateWebhook(ctx context.Context, h domain.Webhook) (*domain.Webhook, error) {
+	if err := normalizeWebhook(&h); err != nil {
+		return nil, err
+	}
+	if s.webhookStore == nil {
+		return nil, nil
+	}
+	if h.Secret == "" {
+		existing, err := s.webhookStore.GetByID(ctx, h.ID)
+		if err != nil || existing == nil {
+			return nil, err
+		}
+		h.Secret = existing.Secret
+	}
+	return s.webhookStore.Update(ctx, h)
+}
+
+// DeleteWebhook removes a webhook and its delivery log. It reports whether
+// the webhook existed.
+func (s *AreaService) DeleteWebhook(ctx context.Context, id int64) (bool, error) {
+	if s.webhookStore == nil {
+		return false, nil
+	}
+	return s.webhookStore.Delete(ctx, id)
+}
+
+// ListWebhookDeliveries returns the webhook's most recent delivery attempts,
+// newest first, or nil if the webhook does not exist.
+func (s *AreaService) ListWebhookDeliveries(ctx context.Context, id int64) ([]*domain.WebhookDelivery, error) {
+	if s.webhookStore == nil {
+		return nil, nil
+	}
+	h, err := s.webhookStore.GetByID(ctx, id)
+	if err != nil || h == nil {
+		return nil, err
+	}
+	deliveries, err := s.webhookStore.ListDeliveries(ctx, id, maxWebhookDeliveries)
+	if err != nil {
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []*domain.WebhookDelivery{}
+	}
+	return deliveries, nil
+}
+
+// SendTestWebhook delivers a webhook.test event to the webhook once, without
+// retrying, and returns the logged attempt. It returns nil if the webhook
+// does not exist. Disabled webhooks are sent to as well, so a hook can be
+// checked before it is turned on.
+func (s *AreaService) SendTestWebhook(ctx context.Context, id int64) (*domain.WebhookDelivery, error) {
+	if s.webhookStore == nil {
+		return nil, nil
+	}
+	h, err := s.webhookStore.GetByID(ctx, id)
+	if err != nil || h == nil {
+		return nil, err
+	}
+	body, err := json.Marshal(WebhookPayload{Event: EventWebhookTest, Timestamp: time.Now().UTC()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	d := s.deliverWebhook(ctx, h, EventWebhookTest, body, 1)
+	return s.webhookStore.RecordDelivery(ctx, d)
+}
+
+// normalizeWebhook trims and validates h's URL and events in place.
+func normalizeWebhook(h *domain.Webhook) error {
+	h.URL = strings.TrimSpace(h.URL)
+	u, err := url.Parse(h.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrWebhookInvalid)
+	}
+	var events []string
+	for _, e := range h.Events {
+		e = strings.TrimSpace(e)
+		if !slices.Contains(WebhookEvents, e) {
+			return fmt.Errorf("%w: unknown event %q", ErrWebhookInvalid, e)
+		}
+		if !slices.Contains(events, e) {
+			events = append(events, e)
+		}
+	}
+	if len(events) == 0 {
+		return fmt.Errorf("%w: at least one event is required", ErrWebhookInvalid)
+	}
+	h.Events = events
+	return nil
+}
+
+// emitWebhook sends p to every enabled webhook subscribed to its event. It
+// returns immediately: delivery, including retries, happens in the
+// background, and failures are only logged.
+func (s *AreaService) emitWebhook(ctx context.Context, p WebhookPayload) {
+	if s.webhookStore == nil {
+		return
+	}
+	p.Timestamp = time.Now().UTC()
+	s.webhookMu.Lock()
+	defer s.webhookMu.Unlock()
+	if s.webhookCtx.Err() != nil {
+		s.log(ctx).Warn("webhook event dropped at shutdown", "event", p.Event)
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	s.webhookWG.Add(1)
+	go func() {
+		defer s.webhookWG.Done()
+		hooks, err := s.webhookStore.List(ctx)
+		if err != nil {
+			s.log(ctx).Error("failed to list webhooks", "event", p.Event, "error", err)
+			return
+		}
+		body, err := json.Marshal(p)
+		if err != nil {
+			s.log(ctx).Error("failed to encode webhook payload", "event", p.Event, "error", err)
+			return
+		}
+		var wg sync.WaitGroup
+		for _, h := range hooks {
+			if !h.Enabled || !slices.Contains(h.Events, p.Event) {
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.deliverWithRetry(ctx, h, p.Event, body)
+			}()
+		}
+		wg.Wait()
+	}()
+}
+
+// deliverWithRetry POSTs body to h, retrying after each webhookBackoff delay
+// until it is accepted, the retries run out or Close abandons them. Every
+// attempt is logged.
+func (s *AreaService) deliverWithRetry(ctx context.Context, h *domain.Webhook, event string, body []byte) {
+	for attempt := 1; ; attempt++ {
+		d := s.deliverWebhook(ctx, h, event, body, attempt)
+		if _, err := s.webhookStore.RecordDelivery(ctx, d); err != nil {
+			s.log(ctx).Error("failed to record webhook delivery", "webhook_id", h.ID, "error", err)
+		}
+		if d.Error == "" || !retryableDelivery(d) || attempt > len(s.webhookBackoff) {
+			if d.Error != "" {
+				s.log(ctx).Warn("webhook delivery failed", "webhook_id", h.ID, "event", event, "attempts", attempt, "error", d.Error)
+			}
+			return
+		}
+		timer := time.NewTimer(s.webhookBackoff[attempt-1])
+		select {
+		case <-timer.C:
+		case <-s.webhookCtx.Done():
+			timer.Stop()
+			s.log(ctx).Warn("webhook retries abandoned at shutdown", "webhook_id", h.ID, "event", event, "attempts", attempt, "error", d.Error)
+			return
+		}
+	}
+}
+
+// retryableDelivery reports whether a failed attempt may succeed if repeated:
+// no response, a server error, or rate limiting.
+func retryableDelivery(d domain.WebhookDelivery) bool {
+	return d.StatusCode == 0 || d.StatusCode >= 500 || d.StatusCode == http.StatusTooManyRequests
+}
+
+// deliverWebhook makes one signed POST of body to h. A non-2xx response is
+// recorded as an error.
+func (s *AreaService) deliverWebhook(ctx context.Context, h *domain.Webhook, event string, body []byte, attempt int) (d domain.WebhookDelivery) {
+	d = domain.WebhookDelivery{WebhookID: h.ID, Event: event, Attempt: attempt}
+	start := time.Now()
+	defer func() { d.DurationMS = time.Since(start).Milliseconds() }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	mac := hmac.New(sha256.New, []byte(h.Secret))
+	mac.Write(body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "kitchinv-webhook")
+	req.Header.Set(WebhookEventHeader, event)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	client := s.webhookClient
+	if client == nil {
+		client = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	_ = resp.Body.Close()
+	d.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		d.Error = resp.Status
+	}
+	return d
+}
+
+// webhookItems converts items for a payload.
+func webhookItems(items []*domain.Item) []WebhookItem {
+	out := make([]WebhookItem, 0, len(items))
+	for _, it := range items {
+		out = append(out, WebhookItem{Name: it.Name, Quantity: it.Quantity})
+	}
+	return out
+}
+
+// diffItemsByName returns the items in after whose name is not in before,
+// and those in before whose name is not in after, ignoring case.
+func diffItemsByName(before, after []*domain.Item) (added, removed []*domain.Item) {
+	names := func(items []*domain.Item) map[string]bool {
+		m := make(map[string]bool, len(items))
+		for _, it := range items {
+			m[strings.ToLower(it.Name)] = true
+		}
+		return m
+	}
+	beforeNames, afterNames := names(before), names(after)
+	for _, it := range after {
+		if !beforeNames[strings.ToLower(it.Name)] {
+			added = append(added, it)
+		}
+	}
+	for _, it := range before {
+		if !afterNames[strings.ToLower(it.Name)] {
+			removed = append(removed, it)
+		}
+	}
+	return added, removed
+}
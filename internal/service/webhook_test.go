@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/store"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+// webhookReceiver records the requests a test webhook endpoint receives and
+// answers each with the next status in statuses (200 once they run out).
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	events   []string
+	payloads []WebhookPayload
+	sigs     []string
+	bodies   [][]byte
+}
+
+func (rc *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var p WebhookPayload
+	_ = json.Unmarshal(body, &p)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.events = append(rc.events, r.Header.Get(WebhookEventHeader))
+	rc.payloads = append(rc.payloads, p)
+	rc.sigs = append(rc.sigs, r.Header.Get(WebhookSignatureHeader))
+	rc.bodies = append(rc.bodies, body)
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newWebhookTestService(t *testing.T) (*AreaService, *webhookReceiver, string) {
+	t.Helper()
+	svc, cleanup := newTestService(t)
+	t.Cleanup(cleanup)
+	svc.WithWebhooks(store.NewWebhookStore(svc.db))
+	svc.webhookBackoff = []time.Duration{time.Millisecond, time.Millisecond}
+	rc := &webhookReceiver{}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+	return svc, rc, srv.URL
+}
+
+func TestAreaServiceWebhooks_AnalysisEvents(t *testing.T) {
+	svc, rc, url := newWebhookTestService(t)
+	ctx := context.Background()
+	hook, err := svc.CreateWebhook(ctx, domain.Webhook{
+		URL: url, Events: []string{EventAnalysisCompleted, EventAnalysisFailed}, Enabled: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, hook.Secret, 64, "a secret is generated when none is given")
+
+	area, err := svc.CreateArea(ctx, "Fridge")
+	require.NoError(t, err)
+	svc.visionAPI = &stubVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}, {Name: "Eggs", Quantity: "6"}}}}
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD8}, "image/jpeg")
+	require.NoError(t, err)
+	svc.webhookWG.Wait()
+
+	svc.visionAPI = &stubVision{result: &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}, {Name: "Butter", Quantity: "1"}}}}
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xD9}, "image/jpeg")
+	require.NoError(t, err)
+	svc.webhookWG.Wait()
+
+	svc.visionAPI = &stubVision{err: errors.New("model offline")}
+	_, _, err = svc.UploadPhoto(ctx, area.ID, []byte{0xFF, 0xDA}, "image/jpeg")
+	require.Error(t, err)
+	svc.webhookWG.Wait()
+
+	require.Len(t, rc.payloads, 3)
+	assert.Equal(t, []string{EventAnalysisCompleted, EventAnalysisCompleted, EventAnalysisFailed}, rc.events)
+
+	first := rc.payloads[0]
+	assert.Equal(t, &WebhookArea{ID: area.ID, Name: "Fridge"}, first.Area)
+	assert.Len(t, first.ItemsAdded, 2)
+	assert.Empty(t, first.ItemsRemoved)
+	assert.False(t, first.Timestamp.IsZero())
+
+	second := rc.payloads[1]
+	assert.Equal(t, []WebhookItem{{Name: "Butter", Quantity: "1"}}, second.ItemsAdded)
+	assert.Equal(t, []WebhookItem{{Name: "Eggs", Quantity: "6"}}, second.ItemsRemoved)
+
+	assert.Contains(t, rc.payloads[2].Error, "model offline")
+
+	mac := hmac.New(sha256.New, []byte(hook.Secret))
+	mac.Write(rc.bodies[0])
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), rc.sigs[0])
+}
+
+func TestAreaServiceWebhooks_SubscriptionAndEnabled(t *testing.T) {
+	svc, rc, url := newWebhookTestService(t)
+	ctx := context.Background()
+	_, err := svc.CreateWebhook(ctx, domain.Webhook{URL: url, Events: []string{EventAnalysisCompleted}, Enabled: true})
+	require.NoError(t, err)
+	_, err = svc.CreateWebhook(ctx, domain.Webhook{URL: url, Events: []string{EventAreaDeleted}, Enabled: false})
+	require.NoError(t, err)
+	_, err = svc.CreateWebhook(ctx, domain.Webhook{URL: url, Events: []string{EventAreaDeleted}, Enabled: true})
+	require.NoError(t, err)
+
+	area, err := svc.CreateArea(ctx, "Pantry")
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteArea(ctx, area.ID))
+	svc.webhookWG.Wait()
+
+	assert.Equal(t, []string{EventAreaDeleted}, rc.events, "only the enabled, subscribed hook is called")
+	assert.Equal(t, "Pantry", rc.payloads[0].Area.Name)
+}
+
+func TestAreaServiceWebhooks_RetriesAndLogsDeliveries(t *testing.T) {
+	svc, rc, url := newWebhookTestService(t)
+	ctx := context.Background()
+	rc.statuses = []int{http.StatusServiceUnavailable, http.StatusBadGateway}
+	hook, err := svc.CreateWebhook(ctx, domain.Webhook{URL: url, Events: []string{EventAreaDeleted}, Enabled: true})
+	require.NoError(t, err)
+
+	area, err := svc.CreateArea(ctx, "Garage")
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteArea(ctx, area.ID))
+	svc.webhookWG.Wait()
+
+	assert.Len(t, rc.events, 3)
+	deliveries, err := svc.ListWebhookDeliveries(ctx, hook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 3)
+	assert.Equal(t, 3, deliveries[0].Attempt)
+	assert.Equal(t, http.StatusOK, deliveries[0].StatusCode)
+	assert.Empty(t, deliveries[0].Error)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[2].StatusCode)
+	assert.NotEmpty(t, deliveries[2].Error)
+}
+
+func TestAreaServiceWebhooks_CloseAbandonsRetries(t *testing.T) {
+	svc, rc, url := newWebhookTestService(t)
+	ctx := context.Background()
+	svc.webhookBackoff = []time.Duration{time.Hour}
+	rc.statuses = []int{http.StatusServiceUnavailable}
+	hook, err := svc.CreateWebhook(ctx, domain.Webhook{URL: url, Events: []string{EventAreaDeleted}, Enabled: true})
+	require.NoError(t, err)
+
+	area, err := svc.CreateArea(ctx, "Garage")
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteArea(ctx, area.ID))
+	start := time.Now()
+	svc.Close()
+	assert.Less(t, time.Since(start), 5*time.Second, "Close doesn't wait out the retry delay")
+
+	deliveries, err := svc.ListWebhookDeliveries(ctx, hook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1, "the failed attempt is recorded, the retry dropped")
+	assert.NotEmpty(t, deliveries[0].Error)
+
+	other, err := svc.CreateArea(ctx, "Shed")
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteArea(ctx, other.ID))
+	svc.webhookWG.Wait()
+	deliveries, err = svc.ListWebhookDeliveries(ctx, hook.ID)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 1, "events after Close aren't sent")
+}
+
+func TestAreaServiceWebhooks_NoRetryOnClientError(t *testing.T) {
+	svc, rc, url := newWebhookTestService(t)
+	ctx := context.Background()
+	rc.statuses = []int{http.StatusNotFound}
+	_, err := svc.CreateWebhook(ctx, domain.Webhook{URL: url, Events: []string{EventAreaDeleted}, Enabled: true})
+	require.NoError(t, err)
+
+	area, err := svc.CreateArea(ctx, "Shed")
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteArea(ctx, area.ID))
+	svc.webhookWG.Wait()
+
+	assert.Len(t, rc.events, 1)
+}
+
+func TestAreaServiceWebhooks_RecordsDuration(t *testing.T) {
+	svc, _, _ := newWebhookTestService(t)
+	ctx := context.Background()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	t.Cleanup(slow.Close)
+	hook, err := svc.CreateWebhook(ctx, domain.Webhook{URL: slow.URL, Events: []string{EventAreaDeleted}, Enabled: true})
+	require.NoError(t, err)
+
+	d, err := svc.SendTestWebhook(ctx, hook.ID)
+	require.NoError(t, err)
+	require.NotNil(t, d)
+	assert.GreaterOrEqual(t, d.DurationMS, int64(20))
+
+	deliveries, err := svc.ListWebhookDeliveries(ctx, hook.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.GreaterOrEqual(t, deliveries[0].DurationMS, int64(20), "the recorded delivery keeps its duration")
+}
+
+func TestAreaServiceSendTestWebhook(t *testing.T) {
+	svc, rc, url := newWebhookTestService(t)
+	ctx := context.Background()
+	hook, err := svc.CreateWebhook(ctx, domain.Webhook{URL: url, Secret: "s3cret", Events: []string{EventAreaDeleted}})
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", hook.Secret)
+
+	d, err := svc.SendTestWebhook(ctx, hook.ID)
+	require.NoError(t, err)
+	require.NotNil(t, d)
+	assert.Equal(t, http.StatusOK, d.StatusCode)
+	assert.Equal(t, EventWebhookTest, d.Event)
+	assert.Equal(t, []string{EventWebhookTest}, rc.events, "test events reach disabled hooks")
+
+	d, err = svc.SendTestWebhook(ctx, hook.ID+1)
+	require.NoError(t, err)
+	assert.Nil(t, d)
+}
+
+func TestAreaServiceWebhookValidation(t *testing.T) {
+	svc, _, url := newWebhookTestService(t)
+	ctx := context.Background()
+	for _, h := range []domain.Webhook{
+		{URL: "ftp://example.com", Events: []string{EventAreaDeleted}},
+		{URL: "/relative", Events: []string{EventAreaDeleted}},
+		{URL: url},
+		{URL: url, Events: []string{"item.eaten"}},
+	} {
+		_, err := svc.CreateWebhook(ctx, h)
+		assert.ErrorIs(t, err, ErrWebhookInvalid, "%+v", h)
+	}
+
+	hook, err := svc.CreateWebhook(ctx, domain.Webhook{URL: url, Secret: "keep", Events: []string{EventAreaDeleted, EventAreaDeleted}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{EventAreaDeleted}, hook.Events, "duplicates are dropped")
+
+	updated, err := svc.UpdateWebhook(ctx, domain.Webhook{ID: hook.ID, URL: url, Events: []string{EventAnalysisFailed}, Enabled: true})
+	require.NoError(t, err)
+	assert.Equal(t, "keep", updated.Secret, "an empty secret keeps the old one")
+	assert.True(t, updated.Enabled)
+
+	missing, err := svc.UpdateWebhook(ctx, domain.Webhook{ID: hook.ID + 1, URL: url, Events: []string{EventAnalysisFailed}})
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
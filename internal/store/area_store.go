@@ -5,23 +5,47 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"time"
 
+	"github.com/vbonduro/kitchinv/internal/db"
 	"github.com/vbonduro/kitchinv/internal/domain"
 )
 
 type AreaStore struct {
-	db *sql.DB
+	db    *sql.DB
+	stmts *stmtCache // the hot queries, prepared once
 }
 
 func NewAreaStore(db *sql.DB) *AreaStore {
-	return &AreaStore{db: db}
+	return &AreaStore{db: db, stmts: newStmtCache(db)}
+}
+
+// Close closes the store's prepared statements. Close stores before their
+// database.
+func (s *AreaStore) Close() error {
+	return s.stmts.Close()
 }
 
 func (s *AreaStore) Create(ctx context.Context, name string) (*domain.Area, error) {
+	return s.CreateWithKind(ctx, name, domain.AreaKindOther)
+}
+
+// CreateWithKind is Create for an area of the given kind.
+func (s *AreaStore) CreateWithKind(ctx context.Context, name string, kind domain.AreaKind) (*domain.Area, error) {
+	return s.create(ctx, name, kind, nil)
+}
+
+// CreateChild creates a sub-area of parentID. It does not check that the
+// parent exists or is itself top-level; the service does.
+func (s *AreaStore) CreateChild(ctx context.Context, parentID int64, name string, kind domain.AreaKind) (*domain.Area, error) {
+	return s.create(ctx, name, kind, &parentID)
+}
+
+func (s *AreaStore) create(ctx context.Context, name string, kind domain.AreaKind, parentID *int64) (*domain.Area, error) {
 	result, err := s.db.ExecContext(ctx, `
-		INSERT INTO areas (name, sort_order)
-		VALUES (?, (SELECT COALESCE(MAX(sort_order), 0) + 1 FROM areas))
-	`, name)
+		INSERT INTO areas (name, kind, parent_id, sort_order)
+		VALUES (?, ?, ?, (SELECT COALESCE(MAX(sort_order), 0) + 1 FROM areas))
+	`, name, kind, parentID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create area: %w", err)
 	}
@@ -36,9 +60,11 @@ func (s *AreaStore) Create(ctx context.Context, name string) (*domain.Area, erro
 
 func (s *AreaStore) GetByID(ctx context.Context, id int64) (*domain.Area, error) {
 	area := &domain.Area{}
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, name, created_at, updated_at FROM areas WHERE id = ?
-	`, id).Scan(&area.ID, &area.Name, &area.CreatedAt, &area.UpdatedAt)
+	err := s.stmts.queryRow(ctx, `
+		SELECT a.id, a.name, COALESCE(a.prompt, ''), a.kind, p.id, COALESCE(p.name, ''), a.created_at, a.updated_at
+		FROM areas a LEFT JOIN areas p ON p.id = a.parent_id AND p.deleted_at IS NULL
+		WHERE a.id = ? AND a.deleted_at IS NULL
+	`, id).Scan(&area.ID, &area.Name, &area.Prompt, &area.Kind, &area.ParentID, &area.ParentName, &area.CreatedAt, &area.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -50,9 +76,15 @@ func (s *AreaStore) GetByID(ctx context.Context, id int64) (*domain.Area, error)
 	return area, nil
 }
 
+// List returns the live areas in display order, each sub-area directly after
+// its parent. A sub-area whose parent is in the trash is listed as top-level.
 func (s *AreaStore) List(ctx context.Context) ([]*domain.Area, error) {
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, name, created_at, updated_at FROM areas ORDER BY sort_order ASC, name ASC
+	rows, err := s.stmts.query(ctx, `
+		SELECT a.id, a.name, COALESCE(a.prompt, ''), a.kind, p.id, COALESCE(p.name, ''), a.created_at, a.updated_at
+		FROM areas a LEFT JOIN areas p ON p.id = a.parent_id AND p.deleted_at IS NULL
+		WHERE a.deleted_at IS NULL
+		ORDER BY COALESCE(p.sort_order, a.sort_order) ASC, COALESCE(p.name, a.name) ASC,
+		         p.id IS NOT NULL, a.sort_order ASC, a.name ASC
 	`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list areas: %w", err)
@@ -66,7 +98,7 @@ func (s *AreaStore) List(ctx context.Context) ([]*domain.Area, error) {
 	var areas []*domain.Area
 	for rows.Next() {
 		area := &domain.Area{}
-		if err := rows.Scan(&area.ID, &area.Name, &area.CreatedAt, &area.UpdatedAt); err != nil {
+		if err := rows.Scan(&area.ID, &area.Name, &area.Prompt, &area.Kind, &area.ParentID, &area.ParentName, &area.CreatedAt, &area.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan area: %w", err)
 		}
 		areas = append(areas, area)
@@ -81,7 +113,7 @@ func (s *AreaStore) List(ctx context.Context) ([]*domain.Area, error) {
 
 func (s *AreaStore) Update(ctx context.Context, id int64, name string) error {
 	result, err := s.db.ExecContext(ctx, `
-		UPDATE areas SET name = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+		UPDATE areas SET name = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL
 	`, name, id)
 	if err != nil {
 		return fmt.Errorf("failed to update area: %w", err)
@@ -99,6 +131,51 @@ func (s *AreaStore) Update(ctx context.Context, id int64, name string) error {
 	return nil
 }
 
+// UpdateKind sets the area's kind. It returns an error wrapping sql.ErrNoRows
+// when the area does not exist or is in the trash.
+func (s *AreaStore) UpdateKind(ctx context.Context, id int64, kind domain.AreaKind) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE areas SET kind = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL
+	`, kind, id)
+	if err != nil {
+		return fmt.Errorf("failed to update area kind: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("area not found: %w", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// UpdatePrompt sets the area's custom analysis prompt. An empty prompt is
+// stored as NULL. It returns an error wrapping sql.ErrNoRows when the area
+// does not exist or is in the trash.
+func (s *AreaStore) UpdatePrompt(ctx context.Context, id int64, prompt string) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE areas SET prompt = NULLIF(?, ''), updated_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL
+	`, prompt, id)
+	if err != nil {
+		return fmt.Errorf("failed to update area prompt: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("area not found: %w", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
 // UpdateSortOrder sets each area's sort_order to its position in ids (1-based).
 // ids must contain all area IDs being reordered; any area not listed is unaffected.
 func (s *AreaStore) UpdateSortOrder(ctx context.Context, ids []int64) error {
@@ -122,9 +199,34 @@ func (s *AreaStore) UpdateSortOrder(ctx context.Context, ids []int64) error {
 	return nil
 }
 
+// HasChildren reports whether the area has any sub-areas that are not in the
+// trash.
+func (s *AreaStore) HasChildren(ctx context.Context, id int64) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM areas WHERE parent_id = ? AND deleted_at IS NULL)
+	`, id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for sub-areas: %w", err)
+	}
+	return exists, nil
+}
+
+// Touch sets an area's updated_at to now, marking its inventory as changed.
+func (s *AreaStore) Touch(ctx context.Context, id int64) error {
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE areas SET updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, id); err != nil {
+		return fmt.Errorf("failed to touch area: %w", err)
+	}
+	return nil
+}
+
+// Delete soft-deletes an area by stamping deleted_at. The row and everything
+// that references it stay in place until Purge removes them.
 func (s *AreaStore) Delete(ctx context.Context, id int64) error {
 	result, err := s.db.ExecContext(ctx, `
-		DELETE FROM areas WHERE id = ?
+		UPDATE areas SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL
 	`, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete area: %w", err)
@@ -141,3 +243,69 @@ func (s *AreaStore) Delete(ctx context.Context, id int64) error {
 
 	return nil
 }
+
+// Restore clears deleted_at on an area that was soft-deleted after
+// deletedAfter. It reports false if there is no such area, either because it
+// was never deleted or because it has aged out of the restore window.
+func (s *AreaStore) Restore(ctx context.Context, id int64, deletedAfter time.Time) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE areas SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND deleted_at IS NOT NULL AND deleted_at > ?
+	`, id, db.FormatTime(deletedAfter))
+	if err != nil {
+		return false, fmt.Errorf("failed to restore area: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// ListDeletedBefore returns soft-deleted areas whose deleted_at is at or
+// before cutoff, i.e. those eligible for purging.
+func (s *AreaStore) ListDeletedBefore(ctx context.Context, cutoff time.Time) ([]*domain.Area, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT a.id, a.name, COALESCE(a.prompt, ''), a.kind, p.id, COALESCE(p.name, ''), a.created_at, a.updated_at
+		FROM areas a LEFT JOIN areas p ON p.id = a.parent_id AND p.deleted_at IS NULL
+		WHERE a.deleted_at IS NOT NULL AND a.deleted_at <= ?
+		ORDER BY a.deleted_at ASC
+	`, db.FormatTime(cutoff))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted areas: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("failed to close rows", "error", err)
+		}
+	}()
+
+	var areas []*domain.Area
+	for rows.Next() {
+		area := &domain.Area{}
+		if err := rows.Scan(&area.ID, &area.Name, &area.Prompt, &area.Kind, &area.ParentID, &area.ParentName, &area.CreatedAt, &area.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan area: %w", err)
+		}
+		areas = append(areas, area)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deleted areas: %w", err)
+	}
+
+	return areas, nil
+}
+
+// Purge permanently removes a soft-deleted area. Photos, items, snapshots and
+// override rule associations are removed by ON DELETE CASCADE.
+func (s *AreaStore) Purge(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM areas WHERE id = ? AND deleted_at IS NOT NULL
+	`, id)
+	if err != nil {
+		return fmt.Errorf("failed to purge area: %w", err)
+	}
+	return nil
+}
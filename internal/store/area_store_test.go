@@ -4,10 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vbonduro/kitchinv/internal/db"
+	"github.com/vbonduro/kitchinv/internal/domain"
 )
 
 func openTestDB(t *testing.T) *sql.DB {
@@ -118,6 +120,93 @@ func TestAreaStoreUpdate_NotFound(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestAreaStoreUpdatePrompt(t *testing.T) {
+	d := openTestDB(t)
+	store := NewAreaStore(d)
+	ctx := context.Background()
+
+	created, err := store.Create(ctx, "Spice Rack")
+	require.NoError(t, err)
+	assert.Empty(t, created.Prompt)
+
+	require.NoError(t, store.UpdatePrompt(ctx, created.ID, "Read every jar label."))
+	retrieved, err := store.GetByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Read every jar label.", retrieved.Prompt)
+
+	// Clearing stores NULL, which reads back as empty.
+	require.NoError(t, store.UpdatePrompt(ctx, created.ID, ""))
+	var isNull bool
+	require.NoError(t, d.QueryRow(`SELECT prompt IS NULL FROM areas WHERE id = ?`, created.ID).Scan(&isNull))
+	assert.True(t, isNull)
+	retrieved, err = store.GetByID(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Empty(t, retrieved.Prompt)
+
+	assert.ErrorIs(t, store.UpdatePrompt(ctx, 99999, "x"), sql.ErrNoRows)
+}
+
+func TestAreaStoreKind(t *testing.T) {
+	d := openTestDB(t)
+	store := NewAreaStore(d)
+	ctx := context.Background()
+
+	plain, err := store.Create(ctx, "Shelf")
+	require.NoError(t, err)
+	assert.Equal(t, domain.AreaKindOther, plain.Kind)
+
+	freezer, err := store.CreateWithKind(ctx, "Chest Freezer", domain.AreaKindFreezer)
+	require.NoError(t, err)
+	assert.Equal(t, domain.AreaKindFreezer, freezer.Kind)
+
+	require.NoError(t, store.UpdateKind(ctx, plain.ID, domain.AreaKindPantry))
+	areas, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, areas, 2)
+	assert.Equal(t, domain.AreaKindPantry, areas[0].Kind)
+
+	assert.ErrorIs(t, store.UpdateKind(ctx, 99999, domain.AreaKindFridge), sql.ErrNoRows)
+}
+
+func TestAreaStoreChildren(t *testing.T) {
+	d := openTestDB(t)
+	store := NewAreaStore(d)
+	ctx := context.Background()
+
+	freezer, err := store.Create(ctx, "Freezer")
+	require.NoError(t, err)
+	pantry, err := store.Create(ctx, "Pantry")
+	require.NoError(t, err)
+	basket, err := store.CreateChild(ctx, freezer.ID, "Top basket", domain.AreaKindFreezer)
+	require.NoError(t, err)
+	require.NotNil(t, basket.ParentID)
+	assert.Equal(t, freezer.ID, *basket.ParentID)
+	assert.Equal(t, "Freezer › Top basket", basket.Path())
+
+	// Names are unique among siblings only.
+	_, err = store.CreateChild(ctx, pantry.ID, "Top basket", domain.AreaKindOther)
+	require.NoError(t, err)
+	_, err = store.CreateChild(ctx, freezer.ID, "Top basket", domain.AreaKindOther)
+	assert.Error(t, err)
+
+	// Sub-areas are listed directly after their parent.
+	areas, err := store.List(ctx)
+	require.NoError(t, err)
+	var paths []string
+	for _, a := range areas {
+		paths = append(paths, a.Path())
+	}
+	assert.Equal(t, []string{"Freezer", "Freezer › Top basket", "Pantry", "Pantry › Top basket"}, paths)
+
+	has, err := store.HasChildren(ctx, freezer.ID)
+	require.NoError(t, err)
+	assert.True(t, has)
+	require.NoError(t, store.Delete(ctx, basket.ID))
+	has, err = store.HasChildren(ctx, freezer.ID)
+	require.NoError(t, err)
+	assert.False(t, has)
+}
+
 func TestAreaStoreDelete(t *testing.T) {
 	d := openTestDB(t)
 	store := NewAreaStore(d)
@@ -175,3 +264,101 @@ func TestAreaStoreUpdateSortOrder_Empty(t *testing.T) {
 	err := s.UpdateSortOrder(ctx, []int64{})
 	require.NoError(t, err)
 }
+
+func TestAreaStoreDelete_IsSoft(t *testing.T) {
+	d := openTestDB(t)
+	s := NewAreaStore(d)
+	ctx := context.Background()
+
+	area, err := s.Create(ctx, "Pantry")
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(ctx, area.ID))
+
+	// Row survives but is hidden from List.
+	var count int
+	require.NoError(t, d.QueryRow(`SELECT COUNT(*) FROM areas WHERE id = ?`, area.ID).Scan(&count))
+	assert.Equal(t, 1, count)
+
+	areas, err := s.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, areas)
+
+	// Deleting again reports not found.
+	assert.Error(t, s.Delete(ctx, area.ID))
+}
+
+func TestAreaStoreDelete_NameReusable(t *testing.T) {
+	d := openTestDB(t)
+	s := NewAreaStore(d)
+	ctx := context.Background()
+
+	area, err := s.Create(ctx, "Pantry")
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(ctx, area.ID))
+
+	_, err = s.Create(ctx, "Pantry")
+	require.NoError(t, err)
+
+	// Restoring the original now collides with the live area.
+	_, err = s.Restore(ctx, area.ID, time.Now().Add(-time.Hour))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "UNIQUE constraint failed")
+}
+
+func TestAreaStoreRestore(t *testing.T) {
+	d := openTestDB(t)
+	s := NewAreaStore(d)
+	ctx := context.Background()
+
+	area, err := s.Create(ctx, "Pantry")
+	require.NoError(t, err)
+
+	restored, err := s.Restore(ctx, area.ID, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.False(t, restored, "live area should not be restorable")
+
+	require.NoError(t, s.Delete(ctx, area.ID))
+
+	// Outside the window: deleted_at is not after a cutoff in the future.
+	restored, err = s.Restore(ctx, area.ID, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, restored)
+
+	restored, err = s.Restore(ctx, area.ID, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.True(t, restored)
+
+	got, err := s.GetByID(ctx, area.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "Pantry", got.Name)
+}
+
+func TestAreaStoreListDeletedBeforeAndPurge(t *testing.T) {
+	d := openTestDB(t)
+	s := NewAreaStore(d)
+	ctx := context.Background()
+
+	live, err := s.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	trashed, err := s.Create(ctx, "Pantry")
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(ctx, trashed.ID))
+
+	due, err := s.ListDeletedBefore(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, due, "recently deleted area is not yet due")
+
+	due, err = s.ListDeletedBefore(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, trashed.ID, due[0].ID)
+
+	// Purge ignores live areas.
+	require.NoError(t, s.Purge(ctx, live.ID))
+	require.NoError(t, s.Purge(ctx, trashed.ID))
+
+	var count int
+	require.NoError(t, d.QueryRow(`SELECT COUNT(*) FROM areas`).Scan(&count))
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// BarcodeStore caches barcode lookups.
+type BarcodeStore struct {
+	db *sql.DB
+}
+
+// NewBarcodeStore creates a new BarcodeStore backed by db.
+func NewBarcodeStore(db *sql.DB) *BarcodeStore {
+	return &BarcodeStore{db: db}
+}
+
+// Get returns the cached lookup for barcode, or nil if there is none.
+func (s *BarcodeStore) Get(ctx context.Context, barcode string) (*domain.BarcodeProduct, error) {
+	var p domain.BarcodeProduct
+	err := s.db.QueryRowContext(ctx, `
+		SELECT barcode, name, quantity, found, fetched_at FROM barcode_products WHERE barcode = ?
+	`, barcode).Scan(&p.Barcode, &p.Name, &p.Quantity, &p.Found, &p.FetchedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get barcode product: %w", err)
+	}
+	return &p, nil
+}
+
+// Put caches p, replacing any earlier lookup of the same barcode.
+func (s *BarcodeStore) Put(ctx context.Context, p domain.BarcodeProduct) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO barcode_products (barcode, name, quantity, found, fetched_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (barcode) DO UPDATE SET
+			name = excluded.name, quantity = excluded.quantity,
+			found = excluded.found, fetched_at = excluded.fetched_at
+	`, p.Barcode, p.Name, p.Quantity, p.Found, p.FetchedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to cache barcode product: %w", err)
+	}
+	return nil
+}
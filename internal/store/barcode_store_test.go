@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+func TestBarcodeStore(t *testing.T) {
+	store := NewBarcodeStore(openTestDB(t))
+	ctx := context.Background()
+
+	p, err := store.Get(ctx, "4006381333931")
+	require.NoError(t, err)
+	assert.Nil(t, p)
+
+	fetched := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	require.NoError(t, store.Put(ctx, domain.BarcodeProduct{Barcode: "4006381333931", Found: false, FetchedAt: fetched}))
+	require.NoError(t, store.Put(ctx, domain.BarcodeProduct{Barcode: "4006381333931", Name: "Oat milk", Quantity: "1 l", Found: true, FetchedAt: fetched.Add(time.Hour)}))
+
+	p, err = store.Get(ctx, "4006381333931")
+	require.NoError(t, err)
+	require.NotNil(t, p)
+	assert.Equal(t, "Oat milk", p.Name)
+	assert.Equal(t, "1 l", p.Quantity)
+	assert.True(t, p.Found)
+	assert.True(t, fetched.Add(time.Hour).Equal(p.FetchedAt), "got %v", p.FetchedAt)
+}
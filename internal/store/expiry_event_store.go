@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ExpiryEventStore remembers the expiry date last published in the calendar
+// feed for each item, so a changed date can be sent as an update.
+type ExpiryEventStore struct {
+	db *sql.DB
+}
+
+// NewExpiryEventStore creates a new ExpiryEventStore backed by db.
+func NewExpiryEventStore(db *sql.DB) *ExpiryEventStore {
+	return &ExpiryEventStore{db: db}
+}
+
+// Sync records dates, keyed by item ID, as each item's current expiry date and
+// returns each item's event sequence number. An item starts at 0; its number
+// goes up by one every time Sync sees a different date for it.
+func (s *ExpiryEventStore) Sync(ctx context.Context, dates map[int64]string) (map[int64]int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	seqs := make(map[int64]int, len(dates))
+	for id, date := range dates {
+		var stored string
+		var seq int
+		err := tx.QueryRowContext(ctx, `
+			SELECT due_date, sequence FROM expiry_events WHERE item_id = ?
+		`, id).Scan(&stored, &seq)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO expiry_events (item_id, due_date) VALUES (?, ?)
+			`, id, date)
+		case err != nil:
+			return nil, fmt.Errorf("failed to get expiry event: %w", err)
+		case stored != date:
+			seq++
+			_, err = tx.ExecContext(ctx, `
+				UPDATE expiry_events SET due_date = ?, sequence = ? WHERE item_id = ?
+			`, date, seq, id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to save expiry event: %w", err)
+		}
+		seqs[id] = seq
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit expiry events: %w", err)
+	}
+	return seqs, nil
+}
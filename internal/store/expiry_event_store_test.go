@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiryEventStoreSync(t *testing.T) {
+	d := openTestDB(t)
+	area, err := NewAreaStore(d).Create(context.Background(), "Fridge")
+	require.NoError(t, err)
+	items := NewItemStore(d)
+	ctx := context.Background()
+	milk, err := items.Create(ctx, area.ID, nil, "Milk", "1", "user", nil)
+	require.NoError(t, err)
+	eggs, err := items.Create(ctx, area.ID, nil, "Eggs", "6", "user", nil)
+	require.NoError(t, err)
+	store := NewExpiryEventStore(d)
+
+	seqs, err := store.Sync(ctx, map[int64]string{milk.ID: "2026-03-20", eggs.ID: "2026-04-10"})
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int{milk.ID: 0, eggs.ID: 0}, seqs)
+
+	seqs, err = store.Sync(ctx, map[int64]string{milk.ID: "2026-03-20", eggs.ID: "2026-04-12"})
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int{milk.ID: 0, eggs.ID: 1}, seqs, "only a changed date bumps the sequence")
+
+	seqs, err = store.Sync(ctx, map[int64]string{eggs.ID: "2026-04-12"})
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int{eggs.ID: 1}, seqs)
+
+	require.NoError(t, items.Delete(ctx, eggs.ID))
+	var n int
+	require.NoError(t, d.QueryRow(`SELECT COUNT(*) FROM expiry_events`).Scan(&n))
+	assert.Equal(t, 1, n, "events go with their item")
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/db"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// HistoryStore keeps the inventory each area was left with after each
+// analysis.
+type HistoryStore struct {
+	db *sql.DB
+}
+
+func NewHistoryStore(db *sql.DB) *HistoryStore {
+	return &HistoryStore{db: db}
+}
+
+// Record appends an area's current items to its history, then drops its
+// oldest points beyond keep. keep <= 0 keeps everything.
+func (s *HistoryStore) Record(ctx context.Context, areaID int64, items []domain.SnapshotItem, keep int) error {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return fmt.Errorf("failed to encode history items: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO area_history (area_id, item_count, items) VALUES (?, ?, ?)`,
+		areaID, len(items), string(data),
+	); err != nil {
+		return fmt.Errorf("failed to insert history point: %w", err)
+	}
+	if keep <= 0 {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM area_history
+		WHERE area_id = ? AND id NOT IN (
+			SELECT id FROM area_history WHERE area_id = ? ORDER BY id DESC LIMIT ?
+		)`, areaID, areaID, keep,
+	); err != nil {
+		return fmt.Errorf("failed to prune history: %w", err)
+	}
+	return nil
+}
+
+// ListSince returns an area's history points recorded at or after since,
+// oldest first.
+func (s *HistoryStore) ListSince(ctx context.Context, areaID int64, since time.Time) ([]*domain.HistoryPoint, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT area_id, recorded_at, item_count, items FROM area_history
+		 WHERE area_id = ? AND recorded_at >= ? ORDER BY id`,
+		areaID, db.FormatTime(since),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	points := make([]*domain.HistoryPoint, 0)
+	for rows.Next() {
+		var p domain.HistoryPoint
+		var itemsJSON string
+		if err := rows.Scan(&p.AreaID, &p.RecordedAt, &p.ItemCount, &itemsJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan history point: %w", err)
+		}
+		if err := json.Unmarshal([]byte(itemsJSON), &p.Items); err != nil {
+			return nil, fmt.Errorf("failed to decode history items: %w", err)
+		}
+		points = append(points, &p)
+	}
+	return points, rows.Err()
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+func TestHistoryStore(t *testing.T) {
+	d := openTestDB(t)
+	ctx := context.Background()
+	areas := NewAreaStore(d)
+	fridge, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	pantry, err := areas.Create(ctx, "Pantry")
+	require.NoError(t, err)
+	store := NewHistoryStore(d)
+
+	for n := 1; n <= 4; n++ {
+		items := make([]domain.SnapshotItem, n)
+		for i := range items {
+			items[i] = domain.SnapshotItem{Name: "Peas", Quantity: "1"}
+		}
+		require.NoError(t, store.Record(ctx, fridge.ID, items, 3))
+	}
+	require.NoError(t, store.Record(ctx, pantry.ID, nil, 3))
+
+	points, err := store.ListSince(ctx, fridge.ID, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, points, 3, "pruned to the newest three")
+	assert.Equal(t, []int{2, 3, 4}, []int{points[0].ItemCount, points[1].ItemCount, points[2].ItemCount})
+	assert.Len(t, points[2].Items, 4)
+	assert.False(t, points[0].RecordedAt.IsZero())
+
+	points, err = store.ListSince(ctx, fridge.ID, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, points)
+
+	points, err = store.ListSince(ctx, pantry.ID, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.Equal(t, 0, points[0].ItemCount)
+}
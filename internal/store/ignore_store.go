@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// IgnoreStore persists the user-managed ignore list.
+type IgnoreStore struct {
+	db *sql.DB
+}
+
+// NewIgnoreStore creates a new IgnoreStore backed by db.
+func NewIgnoreStore(db *sql.DB) *IgnoreStore {
+	return &IgnoreStore{db: db}
+}
+
+// Create adds pattern to the ignore list. Patterns are unique regardless of
+// case; adding a duplicate fails with a UNIQUE constraint error.
+func (s *IgnoreStore) Create(ctx context.Context, pattern string) (*domain.IgnoredItem, error) {
+	result, err := s.db.ExecContext(ctx, `INSERT INTO ignored_items (pattern) VALUES (?)`, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ignored item: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	it := &domain.IgnoredItem{}
+	err = s.db.QueryRowContext(ctx, `
+		SELECT id, pattern, created_at FROM ignored_items WHERE id = ?
+	`, id).Scan(&it.ID, &it.Pattern, &it.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ignored item: %w", err)
+	}
+	return it, nil
+}
+
+// List returns every ignore-list entry in the order they were added.
+func (s *IgnoreStore) List(ctx context.Context) ([]*domain.IgnoredItem, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, pattern, created_at FROM ignored_items ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ignored items: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("failed to close rows", "error", err)
+		}
+	}()
+
+	var items []*domain.IgnoredItem
+	for rows.Next() {
+		it := &domain.IgnoredItem{}
+		if err := rows.Scan(&it.ID, &it.Pattern, &it.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ignored item: %w", err)
+		}
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ignored items: %w", err)
+	}
+	return items, nil
+}
+
+// Delete removes an ignore-list entry. It reports whether the entry existed.
+func (s *IgnoreStore) Delete(ctx context.Context, id int64) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM ignored_items WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete ignored item: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreStore(t *testing.T) {
+	d := openTestDB(t)
+	store := NewIgnoreStore(d)
+	ctx := context.Background()
+
+	items, err := store.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	shelf, err := store.Create(ctx, "shelf")
+	require.NoError(t, err)
+	assert.Equal(t, "shelf", shelf.Pattern)
+	assert.False(t, shelf.CreatedAt.IsZero())
+	_, err = store.Create(ctx, "*container")
+	require.NoError(t, err)
+
+	_, err = store.Create(ctx, "Shelf")
+	assert.Error(t, err, "patterns are unique regardless of case")
+
+	items, err = store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "shelf", items[0].Pattern)
+	assert.Equal(t, "*container", items[1].Pattern)
+
+	ok, err := store.Delete(ctx, shelf.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = store.Delete(ctx, shelf.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	items, err = store.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+}
@@ -1,22 +1,35 @@
 package store
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/vbonduro/kitchinv/internal/db"
 	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/textfold"
+	"github.com/vbonduro/kitchinv/internal/units"
 )
 
 type ItemStore struct {
-	db *sql.DB
+	db    *sql.DB
+	stmts *stmtCache // the hot queries, prepared once
 }
 
 func NewItemStore(db *sql.DB) *ItemStore {
-	return &ItemStore{db: db}
+	return &ItemStore{db: db, stmts: newStmtCache(db)}
+}
+
+// Close closes the store's prepared statements. Close stores before their
+// database.
+func (s *ItemStore) Close() error {
+	return s.stmts.Close()
 }
 
 func encodeBBoxes(bboxes [][]float64) sql.NullString {
@@ -41,11 +54,22 @@ func decodeBBoxes(ns sql.NullString) [][]float64 {
 	return bboxes
 }
 
+// nullTime is t as a pointer, nil when it is NULL.
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// Create inserts an item. Items the user adds (source "user") are marked
+// edited so re-analysis leaves them alone.
 func (s *ItemStore) Create(ctx context.Context, areaID int64, photoID *int64, name, quantity, source string, bboxes [][]float64) (*domain.Item, error) {
-	result, err := s.db.ExecContext(ctx, `
-		INSERT INTO items (area_id, photo_id, name, quantity, source, bboxes)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, areaID, photoID, name, quantity, source, encodeBBoxes(bboxes))
+	value, unit := units.Nullable(quantity)
+	result, err := s.stmts.exec(ctx, `
+		INSERT INTO items (area_id, photo_id, name, name_folded, quantity, quantity_value, quantity_unit, source, bboxes, edited)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, areaID, photoID, name, textfold.Fold(name), quantity, value, unit, source, encodeBBoxes(bboxes), source == string(domain.ItemSourceUser))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create item: %w", err)
 	}
@@ -58,16 +82,121 @@ func (s *ItemStore) Create(ctx context.Context, areaID int64, photoID *int64, na
 	return s.GetByID(ctx, id)
 }
 
+// batchRows is how many rows CreateBatchTx inserts per statement, keeping
+// the bound parameters well under SQLite's limit.
+const batchRows = 100
+
+// CreateBatch inserts items the analysis of photoID detected in one
+// transaction and returns them in the order given.
+func (s *ItemStore) CreateBatch(ctx context.Context, areaID, photoID int64, detected []domain.DetectedItem) ([]*domain.Item, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	items, err := s.CreateBatchTx(ctx, tx, areaID, photoID, detected)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit items: %w", err)
+	}
+	return items, nil
+}
+
+// CreateBatchTx is CreateBatch within tx, so the items can replace others
+// atomically. It inserts up to batchRows items per statement instead of one
+// at a time.
+func (s *ItemStore) CreateBatchTx(ctx context.Context, tx *sql.Tx, areaID, photoID int64, detected []domain.DetectedItem) ([]*domain.Item, error) {
+	items := make([]*domain.Item, 0, len(detected))
+	for start := 0; start < len(detected); start += batchRows {
+		chunk := detected[start:min(start+batchRows, len(detected))]
+		var query strings.Builder
+		query.WriteString(`INSERT INTO items (area_id, photo_id, name, name_folded, quantity, quantity_value, quantity_unit, source, bboxes) VALUES `)
+		args := make([]any, 0, len(chunk)*9)
+		for i, d := range chunk {
+			if i > 0 {
+				query.WriteString(", ")
+			}
+			query.WriteString("(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			value, unit := units.Nullable(d.Quantity)
+			args = append(args, areaID, photoID, d.Name, textfold.Fold(d.Name), d.Quantity, value, unit,
+				string(domain.ItemSourceAI), encodeBBoxes(d.BBoxes))
+		}
+		// RETURNING gives the stored rows without reading them back, though
+		// in no particular order; IDs ascend in insertion order.
+		query.WriteString(` RETURNING id, area_id, photo_id, name, quantity, quantity_value, quantity_unit, source, bboxes, crop_key, edited, version, status, consumed_at, created_at, updated_at`)
+
+		rows, err := tx.QueryContext(ctx, query.String(), args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create items: %w", err)
+		}
+		created, err := scanItems(rows)
+		if err != nil {
+			return nil, err
+		}
+		slices.SortFunc(created, func(a, b *domain.Item) int { return cmp.Compare(a.ID, b.ID) })
+		items = append(items, created...)
+	}
+	return items, nil
+}
+
+// scanItems reads and closes rows of the columns GetByID selects.
+func scanItems(rows *sql.Rows) ([]*domain.Item, error) {
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("failed to close rows", "error", err)
+		}
+	}()
+
+	var items []*domain.Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating items: %w", err)
+	}
+	return items, nil
+}
+
+// scanItem reads one row of the columns GetByID selects, followed by any
+// extra columns the query adds, into extra.
+func scanItem(row rowScanner, extra ...any) (*domain.Item, error) {
+	item := &domain.Item{}
+	var bboxesRaw, cropKey sql.NullString
+	var consumedAt sql.NullTime
+	dest := []any{
+		&item.ID, &item.AreaID, &item.PhotoID,
+		&item.Name, &item.Quantity, &item.QuantityValue, &item.QuantityUnit, &item.Source,
+		&bboxesRaw, &cropKey, &item.Edited, &item.Version, &item.Status, &consumedAt,
+		&item.CreatedAt, &item.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, fmt.Errorf("failed to scan item: %w", err)
+	}
+	item.BBoxes = decodeBBoxes(bboxesRaw)
+	item.CropKey = cropKey.String
+	item.ConsumedAt = nullTime(consumedAt)
+	return item, nil
+}
+
 func (s *ItemStore) GetByID(ctx context.Context, id int64) (*domain.Item, error) {
 	item := &domain.Item{}
-	var bboxesRaw sql.NullString
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, area_id, photo_id, name, quantity, source, bboxes, created_at, updated_at
+	var bboxesRaw, cropKey sql.NullString
+	var consumedAt sql.NullTime
+	err := s.stmts.queryRow(ctx, `
+		SELECT id, area_id, photo_id, name, quantity, quantity_value, quantity_unit, source, bboxes, crop_key, edited, version, status, consumed_at, created_at, updated_at
 		FROM items WHERE id = ?
 	`, id).Scan(
 		&item.ID, &item.AreaID, &item.PhotoID,
-		&item.Name, &item.Quantity, &item.Source,
-		&bboxesRaw,
+		&item.Name, &item.Quantity, &item.QuantityValue, &item.QuantityUnit, &item.Source,
+		&bboxesRaw, &cropKey, &item.Edited, &item.Version, &item.Status, &consumedAt,
 		&item.CreatedAt, &item.UpdatedAt,
 	)
 
@@ -79,79 +208,262 @@ func (s *ItemStore) GetByID(ctx context.Context, id int64) (*domain.Item, error)
 	}
 
 	item.BBoxes = decodeBBoxes(bboxesRaw)
+	item.CropKey = cropKey.String
+	item.ConsumedAt = nullTime(consumedAt)
 	return item, nil
 }
 
 func (s *ItemStore) ListByAreaID(ctx context.Context, areaID int64) ([]*domain.Item, error) {
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, area_id, photo_id, name, quantity, source, bboxes, created_at, updated_at
-		FROM items WHERE area_id = ? ORDER BY name ASC
-	`, areaID)
+	return s.ListByAreaIDPage(ctx, areaID, domain.ItemPage{})
+}
+
+// ListByAreaIDPage is ListByAreaID for one page of the area's items.
+func (s *ItemStore) ListByAreaIDPage(ctx context.Context, areaID int64, page domain.ItemPage) ([]*domain.Item, error) {
+	query, args := listByAreaID(areaID, page)
+	rows, err := s.stmts.query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+	return scanItems(rows)
+}
+
+// ListByAreaIDTx is ListByAreaID within tx, for a caller about to replace
+// the items it reads.
+func (s *ItemStore) ListByAreaIDTx(ctx context.Context, tx *sql.Tx, areaID int64) ([]*domain.Item, error) {
+	query, args := listByAreaID(areaID, domain.ItemPage{})
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list items: %w", err)
 	}
+	return scanItems(rows)
+}
+
+// listByAreaID is the query and arguments for a page of an area's items.
+func listByAreaID(areaID int64, page domain.ItemPage) (string, []any) {
+	return `
+		SELECT i.id, i.area_id, i.photo_id, i.name, i.quantity, i.quantity_value, i.quantity_unit, i.source, i.bboxes, i.crop_key, i.edited, i.version, i.status, i.consumed_at, i.created_at, i.updated_at
+		FROM items i WHERE i.area_id = ? AND (? = 'all' OR i.status = ?)
+		ORDER BY ` + itemOrder(page.Sort) + `
+		LIMIT ? OFFSET ?
+	`, []any{areaID, pageStatus(page), pageStatus(page), pageLimit(page), page.Offset}
+}
+
+// ListPreviews returns every area's active item count with its first limit
+// items in name order, keyed by area ID, in one query however many items
+// there are. Areas without active items are left out.
+func (s *ItemStore) ListPreviews(ctx context.Context, limit int) (map[int64]*domain.ItemPreview, error) {
+	// The window functions count each area's items before the outer WHERE
+	// trims them to the preview, so rn = 1 keeps every area in the result
+	// even when limit is 0.
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, area_id, photo_id, name, quantity, quantity_value, quantity_unit, source, bboxes, crop_key, edited, version, status, consumed_at, created_at, updated_at,
+		       rn, n, out_of_stock
+		FROM (
+			SELECT i.*,
+			       ROW_NUMBER() OVER (PARTITION BY i.area_id ORDER BY i.name ASC, i.id ASC) AS rn,
+			       COUNT(*) OVER (PARTITION BY i.area_id) AS n,
+			       SUM(i.quantity_value IS NOT NULL AND i.quantity_value <= 0) OVER (PARTITION BY i.area_id) AS out_of_stock
+			FROM items i WHERE i.status = ?
+		)
+		WHERE rn = 1 OR rn <= ?
+		ORDER BY area_id, rn
+	`, string(domain.ItemStatusActive), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list item previews: %w", err)
+	}
 	defer func() {
 		if err := rows.Close(); err != nil {
 			slog.Error("failed to close rows", "error", err)
 		}
 	}()
 
-	var items []*domain.Item
+	previews := make(map[int64]*domain.ItemPreview)
 	for rows.Next() {
-		item := &domain.Item{}
-		var bboxesRaw sql.NullString
-		if err := rows.Scan(
-			&item.ID, &item.AreaID, &item.PhotoID,
-			&item.Name, &item.Quantity, &item.Source,
-			&bboxesRaw,
-			&item.CreatedAt, &item.UpdatedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan item: %w", err)
+		var rn int
+		var p domain.ItemPreview
+		item, err := scanItem(rows, &rn, &p.Count, &p.OutOfStock)
+		if err != nil {
+			return nil, err
+		}
+		preview := previews[item.AreaID]
+		if preview == nil {
+			preview = &p
+			previews[item.AreaID] = preview
+		}
+		if rn <= limit {
+			preview.Items = append(preview.Items, item)
 		}
-		item.BBoxes = decodeBBoxes(bboxesRaw)
-		items = append(items, item)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating items: %w", err)
+		return nil, fmt.Errorf("error iterating item previews: %w", err)
 	}
 
-	return items, nil
+	return previews, nil
 }
 
 func (s *ItemStore) Search(ctx context.Context, query string) ([]*domain.Item, error) {
+	return s.SearchPage(ctx, query, domain.SearchFilter{}, domain.ItemPage{})
+}
+
+// SearchPage is Search for one page of the matches, narrowed by filter.
+// Names are matched ignoring case and diacritics, so "creme" finds "Crème".
+func (s *ItemStore) SearchPage(ctx context.Context, query string, filter domain.SearchFilter, page domain.ItemPage) ([]*domain.Item, error) {
+	folded := "%" + textfold.Fold(query) + "%"
 	pattern := "%" + strings.ToLower(query) + "%"
 
+	fields := filter.Fields
+	if len(fields) == 0 {
+		fields = []domain.SearchField{domain.SearchName}
+	}
+	var match []string
+	var args []any
+	for _, f := range fields {
+		// Only known columns reach the SQL; ParseSearchField guards callers.
+		switch f {
+		case domain.SearchName:
+			match = append(match, "i.name_folded LIKE ?")
+			args = append(args, folded)
+		case domain.SearchQuantity:
+			match = append(match, "LOWER(i.quantity) LIKE ?")
+			args = append(args, pattern)
+		default:
+			return nil, fmt.Errorf("unknown search field %q", f)
+		}
+	}
+	where := "(" + strings.Join(match, " OR ") + ")"
+	if len(filter.AreaIDs) > 0 {
+		where += " AND i.area_id IN (" + strings.TrimSuffix(strings.Repeat("?,", len(filter.AreaIDs)), ",") + ")"
+		for _, id := range filter.AreaIDs {
+			args = append(args, id)
+		}
+	}
+	args = append(args, pageStatus(page), pageStatus(page), pageLimit(page), page.Offset)
+
+	// An area filter's IN list varies in length, so only unfiltered searches
+	// are worth preparing.
+	queryContext := s.stmts.query
+	if len(filter.AreaIDs) > 0 {
+		queryContext = s.db.QueryContext
+	}
+	rows, err := queryContext(ctx, `
+		SELECT i.id, i.area_id, i.photo_id, i.name, i.quantity, i.quantity_value, i.quantity_unit, i.source,
+		       i.bboxes, i.crop_key, i.edited, i.version, i.status, i.consumed_at, i.created_at, i.updated_at
+		FROM items i
+		INNER JOIN areas a ON i.area_id = a.id
+		WHERE `+where+` AND a.deleted_at IS NULL AND (? = 'all' OR i.status = ?)
+		ORDER BY `+itemOrder(page.Sort)+`
+		LIMIT ? OFFSET ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search items: %w", err)
+	}
+	return scanItems(rows)
+}
+
+// SearchWordPrefix returns the active items in live areas, narrowed to
+// filter.AreaIDs, with a word of their name starting with prefix. It is the
+// broad candidate list for fuzzy matching, which can't be done in SQL.
+func (s *ItemStore) SearchWordPrefix(ctx context.Context, prefix string, filter domain.SearchFilter) ([]*domain.Item, error) {
+	prefix = textfold.Fold(prefix)
+	where := "(i.name_folded LIKE ? OR i.name_folded LIKE ?)"
+	args := []any{prefix + "%", "% " + prefix + "%"}
+	if len(filter.AreaIDs) > 0 {
+		where += " AND i.area_id IN (" + strings.TrimSuffix(strings.Repeat("?,", len(filter.AreaIDs)), ",") + ")"
+		for _, id := range filter.AreaIDs {
+			args = append(args, id)
+		}
+	}
+
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT i.id, i.area_id, i.photo_id, i.name, i.quantity, i.source,
-		       i.bboxes, i.created_at, i.updated_at
+		SELECT i.id, i.area_id, i.photo_id, i.name, i.quantity, i.quantity_value, i.quantity_unit, i.source,
+		       i.bboxes, i.crop_key, i.edited, i.version, i.status, i.consumed_at, i.created_at, i.updated_at
 		FROM items i
 		INNER JOIN areas a ON i.area_id = a.id
-		WHERE LOWER(i.name) LIKE ?
-		ORDER BY i.name ASC
-	`, pattern)
+		WHERE `+where+` AND a.deleted_at IS NULL AND i.status = 'active'
+		ORDER BY i.name ASC, i.id ASC
+	`, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search items: %w", err)
 	}
+	return scanItems(rows)
+}
+
+// SuggestNames returns up to limit distinct names of active items in live
+// areas that contain query, ignoring case and diacritics. Names starting
+// with it come first, then the most common.
+func (s *ItemStore) SuggestNames(ctx context.Context, query string, limit int) ([]string, error) {
+	folded := textfold.Fold(query)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT MIN(i.name)
+		FROM items i
+		INNER JOIN areas a ON i.area_id = a.id
+		WHERE i.name_folded LIKE ? AND a.deleted_at IS NULL AND i.status = 'active'
+		GROUP BY i.name_folded
+		ORDER BY i.name_folded LIKE ? DESC, COUNT(*) DESC, i.name_folded ASC
+		LIMIT ?
+	`, "%"+folded+"%", folded+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest item names: %w", err)
+	}
 	defer func() {
 		if err := rows.Close(); err != nil {
 			slog.Error("failed to close rows", "error", err)
 		}
 	}()
 
-	var items []*domain.Item
+	var names []string
 	for rows.Next() {
-		item := &domain.Item{}
-		var bboxesRaw sql.NullString
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan item name: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating item names: %w", err)
+	}
+	return names, nil
+}
+
+// ListRecent returns active items created at or after since across all live areas,
+// newest first, with their area names. A zero since lists everything; a
+// non-positive limit means no limit.
+func (s *ItemStore) ListRecent(ctx context.Context, since time.Time, limit, offset int) ([]*domain.RecentItem, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT i.id, i.area_id, i.photo_id, i.name, i.quantity, i.quantity_value, i.quantity_unit, i.source,
+		       i.bboxes, i.crop_key, i.edited, i.version, i.status, i.consumed_at, i.created_at, i.updated_at, a.name
+		FROM items i
+		INNER JOIN areas a ON i.area_id = a.id
+		WHERE a.deleted_at IS NULL AND i.status = 'active' AND i.created_at >= ?
+		ORDER BY i.created_at DESC, i.id DESC
+		LIMIT ? OFFSET ?
+	`, db.FormatTime(since), pageLimit(domain.ItemPage{Limit: limit}), offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recent items: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("failed to close rows", "error", err)
+		}
+	}()
+
+	var items []*domain.RecentItem
+	for rows.Next() {
+		item := &domain.RecentItem{}
+		var bboxesRaw, cropKey sql.NullString
+		var consumedAt sql.NullTime
 		if err := rows.Scan(
 			&item.ID, &item.AreaID, &item.PhotoID,
-			&item.Name, &item.Quantity, &item.Source,
-			&bboxesRaw,
-			&item.CreatedAt, &item.UpdatedAt,
+			&item.Name, &item.Quantity, &item.QuantityValue, &item.QuantityUnit, &item.Source,
+			&bboxesRaw, &cropKey, &item.Edited, &item.Version, &item.Status, &consumedAt,
+			&item.CreatedAt, &item.UpdatedAt, &item.AreaName,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan item: %w", err)
 		}
 		item.BBoxes = decodeBBoxes(bboxesRaw)
+		item.CropKey = cropKey.String
+		item.ConsumedAt = nullTime(consumedAt)
 		items = append(items, item)
 	}
 
@@ -162,10 +474,47 @@ func (s *ItemStore) Search(ctx context.Context, query string) ([]*domain.Item, e
 	return items, nil
 }
 
-func (s *ItemStore) Update(ctx context.Context, id int64, name, quantity string) error {
+// itemOrder is the ORDER BY clause for sort over items aliased as i. Every
+// order ends on the id so that pages don't overlap or skip rows.
+func itemOrder(sort domain.ItemSort) string {
+	switch sort {
+	case domain.ItemSortCreatedAt:
+		return "i.created_at DESC, i.id DESC"
+	case domain.ItemSortQuantity:
+		// Largest first within each unit, so grams aren't compared with
+		// counts; quantities without an amount sort last.
+		return "i.quantity_value IS NULL, i.quantity_unit ASC, i.quantity_value DESC, i.name ASC, i.id ASC"
+	default:
+		return "i.name ASC, i.id ASC"
+	}
+}
+
+// pageStatus is the status page lists; the zero value lists active items.
+func pageStatus(page domain.ItemPage) domain.ItemStatus {
+	if page.Status == "" {
+		return domain.ItemStatusActive
+	}
+	return page.Status
+}
+
+// pageLimit is the LIMIT for page; -1 tells SQLite there is none.
+func pageLimit(page domain.ItemPage) int {
+	if page.Limit <= 0 {
+		return -1
+	}
+	return page.Limit
+}
+
+// Update changes an item's name and quantity, marks it edited and bumps its
+// version. A non-zero version must match the stored one or the update is
+// refused with domain.ErrItemConflict; zero updates unconditionally.
+func (s *ItemStore) Update(ctx context.Context, id, version int64, name, quantity string) error {
+	value, unit := units.Nullable(quantity)
 	result, err := s.db.ExecContext(ctx, `
-		UPDATE items SET name = ?, quantity = ?, updated_at = datetime('now') WHERE id = ?
-	`, name, quantity, id)
+		UPDATE items SET name = ?, name_folded = ?, quantity = ?, quantity_value = ?, quantity_unit = ?,
+			edited = 1, version = version + 1, updated_at = datetime('now')
+		WHERE id = ? AND (? = 0 OR version = ?)
+	`, name, textfold.Fold(name), quantity, value, unit, id, version, version)
 	if err != nil {
 		return fmt.Errorf("failed to update item: %w", err)
 	}
@@ -176,12 +525,88 @@ func (s *ItemStore) Update(ctx context.Context, id int64, name, quantity string)
 	}
 
 	if rowsAffected == 0 {
+		if version != 0 {
+			var exists bool
+			if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM items WHERE id = ?)`, id).Scan(&exists); err != nil {
+				return fmt.Errorf("failed to check item: %w", err)
+			}
+			if exists {
+				return domain.ErrItemConflict
+			}
+		}
 		return fmt.Errorf("item not found")
 	}
 
 	return nil
 }
 
+// SetStatus marks an active item consumed or discarded, stamping when, and
+// bumps its version. It returns false when no active item has the id.
+func (s *ItemStore) SetStatus(ctx context.Context, id int64, status domain.ItemStatus) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE items SET status = ?, consumed_at = CURRENT_TIMESTAMP, version = version + 1, updated_at = datetime('now')
+		WHERE id = ? AND status = 'active'
+	`, string(status), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to set item status: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// MonthlyWaste counts the items consumed and discarded at or after since by
+// month, newest month first.
+func (s *ItemStore) MonthlyWaste(ctx context.Context, since time.Time) ([]domain.MonthlyWaste, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT strftime('%Y-%m', consumed_at) AS month,
+			SUM(status = 'consumed'), SUM(status = 'discarded')
+		FROM items
+		WHERE status IN ('consumed', 'discarded') AND consumed_at >= ?
+		GROUP BY month ORDER BY month DESC
+	`, db.FormatTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to total item waste: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("failed to close rows", "error", err)
+		}
+	}()
+
+	var months []domain.MonthlyWaste
+	for rows.Next() {
+		var m domain.MonthlyWaste
+		if err := rows.Scan(&m.Month, &m.Consumed, &m.Discarded); err != nil {
+			return nil, fmt.Errorf("failed to scan item waste: %w", err)
+		}
+		months = append(months, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating item waste: %w", err)
+	}
+	return months, nil
+}
+
+// SetCropKey records the photo-store key of the item's thumbnail.
+func (s *ItemStore) SetCropKey(ctx context.Context, id int64, key string) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE items SET crop_key = ? WHERE id = ?`, key, id); err != nil {
+		return fmt.Errorf("failed to set item crop: %w", err)
+	}
+	return nil
+}
+
+// CountByCropKey returns how many items use the thumbnail stored under key.
+func (s *ItemStore) CountByCropKey(ctx context.Context, key string) (int, error) {
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM items WHERE crop_key = ?`, key).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count item crops: %w", err)
+	}
+	return n, nil
+}
+
 func (s *ItemStore) Delete(ctx context.Context, id int64) error {
 	result, err := s.db.ExecContext(ctx, `
 		DELETE FROM items WHERE id = ?
@@ -202,9 +627,92 @@ func (s *ItemStore) Delete(ctx context.Context, id int64) error {
 	return nil
 }
 
+// ApplyOps runs a batch of creates, updates and deletes against one area in a
+// single transaction. Updates and deletes only match items in areaID, and
+// those naming a version only match while it is current. If any entry cannot
+// be applied the whole batch is rolled back and the failures are returned;
+// the error is reserved for database faults.
+func (s *ItemStore) ApplyOps(ctx context.Context, areaID int64, ops []domain.ItemOp) ([]domain.ItemOpFailure, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var failures []domain.ItemOpFailure
+	for i, op := range ops {
+		var result sql.Result
+		value, unit := units.Nullable(op.Quantity)
+		switch op.Op {
+		case domain.ItemOpCreate:
+			result, err = tx.ExecContext(ctx, `
+				INSERT INTO items (area_id, name, name_folded, quantity, quantity_value, quantity_unit, source, edited) VALUES (?, ?, ?, ?, ?, ?, ?, 1)
+			`, areaID, op.Name, textfold.Fold(op.Name), op.Quantity, value, unit, string(domain.ItemSourceUser))
+		case domain.ItemOpUpdate:
+			result, err = tx.ExecContext(ctx, `
+				UPDATE items SET name = ?, name_folded = ?, quantity = ?, quantity_value = ?, quantity_unit = ?,
+					edited = 1, version = version + 1, updated_at = datetime('now')
+				WHERE id = ? AND area_id = ? AND (? = 0 OR version = ?)
+			`, op.Name, textfold.Fold(op.Name), op.Quantity, value, unit, op.ID, areaID, op.Version, op.Version)
+		case domain.ItemOpDelete:
+			result, err = tx.ExecContext(ctx, `
+				DELETE FROM items WHERE id = ? AND area_id = ? AND (? = 0 OR version = ?)
+			`, op.ID, areaID, op.Version, op.Version)
+		default:
+			failures = append(failures, domain.ItemOpFailure{Index: i, Error: fmt.Sprintf("unknown op %q", op.Op)})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to %s item: %w", op.Op, err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			msg := "item not found"
+			if op.Version != 0 {
+				var exists bool
+				if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM items WHERE id = ? AND area_id = ?)`, op.ID, areaID).Scan(&exists); err != nil {
+					return nil, fmt.Errorf("failed to check item: %w", err)
+				}
+				if exists {
+					msg = domain.ErrItemConflict.Error()
+				}
+			}
+			failures = append(failures, domain.ItemOpFailure{Index: i, Error: msg})
+		}
+	}
+
+	if len(failures) > 0 {
+		return failures, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit item ops: %w", err)
+	}
+	return nil, nil
+}
+
+// DeleteUneditedByAreaID removes an area's active machine-generated items,
+// keeping those the user created or corrected and those already consumed or
+// discarded.
+func (s *ItemStore) DeleteUneditedByAreaID(ctx context.Context, areaID int64) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM items WHERE area_id = ? AND edited = 0 AND status = 'active'
+	`, areaID)
+	if err != nil {
+		return fmt.Errorf("failed to delete unedited items: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteByAreaID removes an area's active items. Consumed and discarded ones
+// are kept for the waste summary.
 func (s *ItemStore) DeleteByAreaID(ctx context.Context, areaID int64) error {
 	_, err := s.db.ExecContext(ctx, `
-		DELETE FROM items WHERE area_id = ?
+		DELETE FROM items WHERE area_id = ? AND status = 'active'
 	`, areaID)
 	if err != nil {
 		return fmt.Errorf("failed to delete items: %w", err)
@@ -2,10 +2,16 @@ package store
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/db"
 	"github.com/vbonduro/kitchinv/internal/domain"
 )
 
@@ -72,7 +78,7 @@ func TestItemStoreUpdate_UpdatesUpdatedAt(t *testing.T) {
 
 	before := item.UpdatedAt
 
-	err = items.Update(ctx, item.ID, "Whole Milk", "2 liters")
+	err = items.Update(ctx, item.ID, 0, "Whole Milk", "2 liters")
 	require.NoError(t, err)
 
 	updated, err := items.GetByID(ctx, item.ID)
@@ -80,6 +86,39 @@ func TestItemStoreUpdate_UpdatesUpdatedAt(t *testing.T) {
 	assert.True(t, !updated.UpdatedAt.Before(before), "updated_at should not go backwards")
 }
 
+func TestItemStoreEditedFlag(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	items := NewItemStore(d)
+	ctx := context.Background()
+
+	area, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+
+	ai, err := items.Create(ctx, area.ID, nil, "Milk", "1", "ai", nil)
+	require.NoError(t, err)
+	assert.False(t, ai.Edited)
+	corrected, err := items.Create(ctx, area.ID, nil, "Eggs", "6", "ai", nil)
+	require.NoError(t, err)
+	user, err := items.Create(ctx, area.ID, nil, "Butter", "1", "user", nil)
+	require.NoError(t, err)
+	assert.True(t, user.Edited, "user-created items count as edited")
+
+	require.NoError(t, items.Update(ctx, corrected.ID, 0, "Eggs", "4"))
+	got, err := items.GetByID(ctx, corrected.ID)
+	require.NoError(t, err)
+	assert.True(t, got.Edited)
+
+	require.NoError(t, items.DeleteUneditedByAreaID(ctx, area.ID))
+	remaining, err := items.ListByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	var names []string
+	for _, it := range remaining {
+		names = append(names, it.Name)
+	}
+	assert.Equal(t, []string{"Butter", "Eggs"}, names)
+}
+
 func TestItemStoreListByAreaID(t *testing.T) {
 	d := openTestDB(t)
 	areas := NewAreaStore(d)
@@ -116,6 +155,87 @@ func TestItemStoreListByAreaID_Empty(t *testing.T) {
 	assert.Empty(t, list)
 }
 
+func TestItemStoreListByAreaIDPage(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	items := NewItemStore(d)
+	ctx := context.Background()
+
+	area, err := areas.Create(ctx, "Pantry")
+	require.NoError(t, err)
+	for _, it := range []struct{ name, qty string }{
+		{"Beans", "3 cans"}, {"Apples", "6"}, {"Rice", ""}, {"Pasta", "12"},
+	} {
+		_, err := items.Create(ctx, area.ID, nil, it.name, it.qty, "user", nil)
+		require.NoError(t, err)
+	}
+	names := func(list []*domain.Item) []string {
+		var out []string
+		for _, it := range list {
+			out = append(out, it.Name)
+		}
+		return out
+	}
+
+	page, err := items.ListByAreaIDPage(ctx, area.ID, domain.ItemPage{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Apples", "Beans"}, names(page))
+	page, err = items.ListByAreaIDPage(ctx, area.ID, domain.ItemPage{Limit: 2, Offset: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Pasta", "Rice"}, names(page))
+
+	page, err = items.ListByAreaIDPage(ctx, area.ID, domain.ItemPage{Sort: domain.ItemSortQuantity})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Pasta", "Apples", "Beans", "Rice"}, names(page))
+
+	page, err = items.ListByAreaIDPage(ctx, area.ID, domain.ItemPage{Sort: domain.ItemSortCreatedAt, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Pasta"}, names(page))
+
+	found, err := items.SearchPage(ctx, "a", domain.SearchFilter{}, domain.ItemPage{Limit: 2, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Beans", "Pasta"}, names(found))
+}
+
+func TestItemStoreListRecent(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	items := NewItemStore(d)
+	ctx := context.Background()
+
+	fridge, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	pantry, err := areas.Create(ctx, "Pantry")
+	require.NoError(t, err)
+	old, err := items.Create(ctx, fridge.ID, nil, "Milk", "1", "user", nil)
+	require.NoError(t, err)
+	_, err = items.Create(ctx, pantry.ID, nil, "Rice", "", "user", nil)
+	require.NoError(t, err)
+	_, err = items.Create(ctx, fridge.ID, nil, "Eggs", "12", "user", nil)
+	require.NoError(t, err)
+	_, err = d.ExecContext(ctx, `UPDATE items SET created_at = '2020-01-01 00:00:00' WHERE id = ?`, old.ID)
+	require.NoError(t, err)
+
+	recent, err := items.ListRecent(ctx, time.Time{}, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, recent, 3)
+	assert.Equal(t, "Eggs", recent[0].Name)
+	assert.Equal(t, "Fridge", recent[0].AreaName)
+	assert.Equal(t, "Pantry", recent[1].AreaName)
+	assert.Equal(t, "Milk", recent[2].Name)
+
+	recent, err = items.ListRecent(ctx, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), 1, 1)
+	require.NoError(t, err)
+	require.Len(t, recent, 1)
+	assert.Equal(t, "Rice", recent[0].Name)
+
+	// Items in trashed areas drop out of the feed.
+	require.NoError(t, areas.Delete(ctx, pantry.ID))
+	recent, err = items.ListRecent(ctx, time.Time{}, 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, recent, 2)
+}
+
 func TestItemStoreSearch(t *testing.T) {
 	d := openTestDB(t)
 	areas := NewAreaStore(d)
@@ -170,6 +290,57 @@ func TestItemStoreSearch_NoMatch(t *testing.T) {
 	assert.Empty(t, results)
 }
 
+func TestItemStoreSearch_Diacritics(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	items := NewItemStore(d)
+	ctx := context.Background()
+
+	area, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	for _, name := range []string{"Jalapeño peppers", "Crème fraîche", "Gruye\u0300re", "Weißwurst", "Smørrebrød"} {
+		_, err := items.Create(ctx, area.ID, nil, name, "1", "ai", nil)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"jalapeno", []string{"Jalapeño peppers"}},
+		{"JALAPEÑO", []string{"Jalapeño peppers"}},
+		{"jalapen\u0303o", []string{"Jalapeño peppers"}},
+		{"creme fraiche", []string{"Crème fraîche"}},
+		{"CRÈME", []string{"Crème fraîche"}},
+		{"gruyère", []string{"Gruye\u0300re"}},
+		{"gruyere", []string{"Gruye\u0300re"}},
+		{"weisswurst", []string{"Weißwurst"}},
+		{"smorrebrod", []string{"Smørrebrød"}},
+		{"fraiches", nil},
+	}
+	for _, tt := range tests {
+		found, err := items.Search(ctx, tt.query)
+		require.NoError(t, err)
+		var names []string
+		for _, it := range found {
+			names = append(names, it.Name)
+		}
+		assert.Equal(t, tt.want, names, "query %q", tt.query)
+	}
+
+	// Renaming refolds the name, through Update and bulk edits alike.
+	found, err := items.Search(ctx, "jalapeno")
+	require.NoError(t, err)
+	require.NoError(t, items.Update(ctx, found[0].ID, 0, "Piñon nuts", "1"))
+	_, err = items.ApplyOps(ctx, area.ID, []domain.ItemOp{{Op: domain.ItemOpCreate, Name: "Açaí", Quantity: "1"}})
+	require.NoError(t, err)
+	for query, want := range map[string]int{"jalapeno": 0, "pinon": 1, "acai": 1} {
+		found, err := items.Search(ctx, query)
+		require.NoError(t, err)
+		assert.Len(t, found, want, "query %q", query)
+	}
+}
+
 // Regression test for kitchinv-foy: search must not return items whose area
 // has been deleted.
 func TestItemStoreSearch_DeletedArea(t *testing.T) {
@@ -191,6 +362,97 @@ func TestItemStoreSearch_DeletedArea(t *testing.T) {
 	assert.Empty(t, results, "search must not return items from deleted areas")
 }
 
+func TestItemStoreSearchFilter(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	items := NewItemStore(d)
+	ctx := context.Background()
+
+	fridge, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	pantry, err := areas.Create(ctx, "Pantry")
+	require.NoError(t, err)
+	gone, err := areas.Create(ctx, "Garage")
+	require.NoError(t, err)
+	for _, it := range []struct {
+		area           int64
+		name, quantity string
+	}{
+		{fridge.ID, "Milk", "2 cartons"},
+		{fridge.ID, "Yogurt", "4 cups"},
+		{pantry.ID, "Powdered milk", "1 bag"},
+		{pantry.ID, "Rice", "2 bags"},
+		{gone.ID, "Milk", "1 carton"},
+		{gone.ID, "Paper cups", "50"},
+	} {
+		_, err := items.Create(ctx, it.area, nil, it.name, it.quantity, "ai", nil)
+		require.NoError(t, err)
+	}
+	require.NoError(t, areas.Delete(ctx, gone.ID))
+
+	tests := []struct {
+		name   string
+		query  string
+		filter domain.SearchFilter
+		want   []string
+	}{
+		{"zero filter matches names everywhere", "milk", domain.SearchFilter{}, []string{"Milk", "Powdered milk"}},
+		{"one area", "milk", domain.SearchFilter{AreaIDs: []int64{pantry.ID}}, []string{"Powdered milk"}},
+		{"several areas", "i", domain.SearchFilter{AreaIDs: []int64{fridge.ID, pantry.ID}}, []string{"Milk", "Powdered milk", "Rice"}},
+		{"deleted area stays hidden when named", "milk", domain.SearchFilter{AreaIDs: []int64{gone.ID}}, nil},
+		{"deleted area alongside a live one", "milk", domain.SearchFilter{AreaIDs: []int64{fridge.ID, gone.ID}}, []string{"Milk"}},
+		{"quantity only", "cup", domain.SearchFilter{Fields: []domain.SearchField{domain.SearchQuantity}}, []string{"Yogurt"}},
+		{"name and quantity", "ba", domain.SearchFilter{Fields: []domain.SearchField{domain.SearchName, domain.SearchQuantity}}, []string{"Powdered milk", "Rice"}},
+		{"quantity in one area", "2", domain.SearchFilter{AreaIDs: []int64{fridge.ID}, Fields: []domain.SearchField{domain.SearchQuantity}}, []string{"Milk"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := items.SearchPage(ctx, tt.query, tt.filter, domain.ItemPage{})
+			require.NoError(t, err)
+			var names []string
+			for _, it := range found {
+				names = append(names, it.Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+
+	_, err = items.SearchPage(ctx, "milk", domain.SearchFilter{Fields: []domain.SearchField{"notes"}}, domain.ItemPage{})
+	assert.Error(t, err)
+}
+
+func TestItemStoreSuggestNames(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	items := NewItemStore(d)
+	ctx := context.Background()
+
+	fridge, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	gone, err := areas.Create(ctx, "Garage")
+	require.NoError(t, err)
+	for _, it := range []struct {
+		area int64
+		name string
+	}{
+		{fridge.ID, "Oat milk"}, {fridge.ID, "oat milk"}, {fridge.ID, "Milk"}, {fridge.ID, "Buttermilk"},
+		{fridge.ID, "Oat milk"}, {fridge.ID, "Milk chocolate"}, {fridge.ID, "Mîlk powder"}, {gone.ID, "Milkshake"},
+	} {
+		_, err := items.Create(ctx, it.area, nil, it.name, "1", "ai", nil)
+		require.NoError(t, err)
+	}
+	require.NoError(t, areas.Delete(ctx, gone.ID))
+
+	names, err := items.SuggestNames(ctx, "MILK", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Milk", "Milk chocolate", "Mîlk powder", "Oat milk", "Buttermilk"}, names,
+		"prefix matches first, then by count; one spelling per name")
+
+	names, err = items.SuggestNames(ctx, "milk", 2)
+	require.NoError(t, err)
+	assert.Len(t, names, 2)
+}
+
 func TestItemStoreUpdate(t *testing.T) {
 	d := openTestDB(t)
 	areas := NewAreaStore(d)
@@ -203,7 +465,7 @@ func TestItemStoreUpdate(t *testing.T) {
 	item, err := items.Create(ctx, area.ID, nil, "Milk", "1 liter", "ai", nil)
 	require.NoError(t, err)
 
-	err = items.Update(ctx, item.ID, "Whole Milk", "2 liters")
+	err = items.Update(ctx, item.ID, 0, "Whole Milk", "2 liters")
 	require.NoError(t, err)
 
 	updated, err := items.GetByID(ctx, item.ID)
@@ -212,12 +474,41 @@ func TestItemStoreUpdate(t *testing.T) {
 	assert.Equal(t, "2 liters", updated.Quantity)
 }
 
+func TestItemStoreUpdate_Version(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	items := NewItemStore(d)
+	ctx := context.Background()
+
+	area, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	item, err := items.Create(ctx, area.ID, nil, "Milk", "1", "user", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), item.Version)
+
+	require.NoError(t, items.Update(ctx, item.ID, 1, "Whole Milk", "1"))
+
+	// A second editor still holding version 1 is refused.
+	err = items.Update(ctx, item.ID, 1, "Oat Milk", "1")
+	assert.ErrorIs(t, err, domain.ErrItemConflict)
+
+	current, err := items.GetByID(ctx, item.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Whole Milk", current.Name)
+	assert.Equal(t, int64(2), current.Version)
+
+	// A stale version for a missing item is not found, not a conflict.
+	err = items.Update(ctx, 99999, 1, "Name", "1")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, domain.ErrItemConflict)
+}
+
 func TestItemStoreUpdate_NotFound(t *testing.T) {
 	d := openTestDB(t)
 	items := NewItemStore(d)
 	ctx := context.Background()
 
-	err := items.Update(ctx, 99999, "Name", "1")
+	err := items.Update(ctx, 99999, 0, "Name", "1")
 	assert.Error(t, err)
 }
 
@@ -327,3 +618,372 @@ func TestItemStoreDeleteByAreaID(t *testing.T) {
 	require.NoError(t, err)
 	assert.Empty(t, list)
 }
+
+func TestItemStoreApplyOps(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	items := NewItemStore(d)
+	ctx := context.Background()
+
+	area, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	milk, err := items.Create(ctx, area.ID, nil, "Milk", "1", "ai", nil)
+	require.NoError(t, err)
+	eggs, err := items.Create(ctx, area.ID, nil, "Eggs", "6", "ai", nil)
+	require.NoError(t, err)
+
+	failures, err := items.ApplyOps(ctx, area.ID, []domain.ItemOp{
+		{Op: domain.ItemOpUpdate, ID: milk.ID, Name: "Oat milk", Quantity: "2"},
+		{Op: domain.ItemOpDelete, ID: eggs.ID},
+		{Op: domain.ItemOpCreate, Name: "Butter", Quantity: "250 g"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+
+	list, err := items.ListByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "Butter", list[0].Name)
+	assert.Equal(t, domain.ItemSourceUser, list[0].Source)
+	assert.Equal(t, "Oat milk", list[1].Name)
+	assert.Equal(t, "2", list[1].Quantity)
+}
+
+func TestItemStoreApplyOps_RollsBackOnFailure(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	items := NewItemStore(d)
+	ctx := context.Background()
+
+	fridge, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	pantry, err := areas.Create(ctx, "Pantry")
+	require.NoError(t, err)
+	milk, err := items.Create(ctx, fridge.ID, nil, "Milk", "1", "ai", nil)
+	require.NoError(t, err)
+	rice, err := items.Create(ctx, pantry.ID, nil, "Rice", "1 kg", "ai", nil)
+	require.NoError(t, err)
+
+	failures, err := items.ApplyOps(ctx, fridge.ID, []domain.ItemOp{
+		{Op: domain.ItemOpDelete, ID: milk.ID},
+		{Op: domain.ItemOpCreate, Name: "Butter"},
+		{Op: domain.ItemOpUpdate, ID: rice.ID, Name: "Basmati"}, // belongs to another area
+		{Op: domain.ItemOpDelete, ID: 99999},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ItemOpFailure{
+		{Index: 2, Error: "item not found"},
+		{Index: 3, Error: "item not found"},
+	}, failures)
+
+	list, err := items.ListByAreaID(ctx, fridge.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1, "batch must be rolled back")
+	assert.Equal(t, "Milk", list[0].Name)
+
+	got, err := items.GetByID(ctx, rice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Rice", got.Name)
+}
+
+func TestItemStoreApplyOps_Version(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	items := NewItemStore(d)
+	ctx := context.Background()
+
+	area, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	milk, err := items.Create(ctx, area.ID, nil, "Milk", "1", "ai", nil)
+	require.NoError(t, err)
+	eggs, err := items.Create(ctx, area.ID, nil, "Eggs", "6", "ai", nil)
+	require.NoError(t, err)
+	require.NoError(t, items.Update(ctx, milk.ID, 0, "Oat milk", "1"))
+
+	failures, err := items.ApplyOps(ctx, area.ID, []domain.ItemOp{
+		{Op: domain.ItemOpUpdate, ID: milk.ID, Version: milk.Version, Name: "Skim milk"},
+		{Op: domain.ItemOpDelete, ID: eggs.ID, Version: eggs.Version},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.ItemOpFailure{{Index: 0, Error: domain.ErrItemConflict.Error()}}, failures)
+
+	got, err := items.GetByID(ctx, milk.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Oat milk", got.Name, "a stale version must not overwrite the edit")
+
+	failures, err = items.ApplyOps(ctx, area.ID, []domain.ItemOp{
+		{Op: domain.ItemOpUpdate, ID: milk.ID, Version: got.Version, Name: "Skim milk"},
+		{Op: domain.ItemOpDelete, ID: eggs.ID, Version: eggs.Version},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+}
+
+func TestItemStoreCreateBatch(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	photos := NewPhotoStore(d)
+	items := NewItemStore(d)
+	ctx := context.Background()
+
+	area, err := areas.Create(ctx, "Pantry")
+	require.NoError(t, err)
+	photo, err := photos.Create(ctx, area.ID, "k", "image/jpeg", "")
+	require.NoError(t, err)
+
+	// More than one statement's worth, to cover the split.
+	detected := make([]domain.DetectedItem, batchRows+5)
+	for i := range detected {
+		detected[i] = domain.DetectedItem{Name: fmt.Sprintf("item-%03d", i), Quantity: "2 kg"}
+	}
+	detected[0].BBoxes = [][]float64{{0.1, 0.2, 0.3, 0.4}}
+
+	created, err := items.CreateBatch(ctx, area.ID, photo.ID, detected)
+	require.NoError(t, err)
+	require.Len(t, created, len(detected))
+	for i, item := range created {
+		assert.Equal(t, detected[i].Name, item.Name, "created items keep the order given")
+		if i > 0 {
+			assert.Greater(t, item.ID, created[i-1].ID)
+		}
+	}
+	first := created[0]
+	assert.Equal(t, area.ID, first.AreaID)
+	require.NotNil(t, first.PhotoID)
+	assert.Equal(t, photo.ID, *first.PhotoID)
+	assert.Equal(t, domain.ItemSourceAI, first.Source)
+	assert.Equal(t, domain.ItemStatusActive, first.Status)
+	assert.False(t, first.Edited)
+	assert.Equal(t, [][]float64{{0.1, 0.2, 0.3, 0.4}}, first.BBoxes)
+	require.NotNil(t, first.QuantityValue)
+	assert.Equal(t, 2000.0, *first.QuantityValue)
+	assert.False(t, first.CreatedAt.IsZero())
+
+	stored, err := items.GetByID(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first, stored)
+
+	none, err := items.CreateBatch(ctx, area.ID, photo.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+// TestItemStoreCreateBatch_OneConnection runs a batch on a pool of one
+// connection, which the transaction holds: nothing in it may need another.
+func TestItemStoreCreateBatch_OneConnection(t *testing.T) {
+	d, err := db.Open(filepath.Join(t.TempDir(), "one.db"), db.Options{MaxOpenConns: 1})
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, db.Close(d)) })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	area, err := NewAreaStore(d).Create(ctx, "Pantry")
+	require.NoError(t, err)
+	photo, err := NewPhotoStore(d).Create(ctx, area.ID, "k", "image/jpeg", "")
+	require.NoError(t, err)
+	created, err := NewItemStore(d).CreateBatch(ctx, area.ID, photo.ID, []domain.DetectedItem{{Name: "Rice"}, {Name: "Oats"}})
+	require.NoError(t, err)
+	assert.Len(t, created, 2)
+}
+
+func TestItemStoreCreateBatchTx_RollsBack(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	items := NewItemStore(d)
+	ctx := context.Background()
+
+	area, err := areas.Create(ctx, "Pantry")
+	require.NoError(t, err)
+
+	tx, err := d.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = items.CreateBatchTx(ctx, tx, area.ID, 0, []domain.DetectedItem{{Name: "Rice"}})
+	require.Error(t, err, "photo 0 doesn't exist")
+	require.NoError(t, tx.Rollback())
+
+	list, err := items.ListByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+// TestItemStoreCreate_Concurrent hammers Create from many goroutines against a
+// file-backed database opened with production settings and asserts that no
+// "database is locked" errors escape the busy timeout.
+func TestItemStoreCreate_Concurrent(t *testing.T) {
+	d, err := db.Open(filepath.Join(t.TempDir(), "concurrent.db"), db.Options{})
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, db.Close(d)) })
+
+	areas := NewAreaStore(d)
+	items := NewItemStore(d)
+	ctx := context.Background()
+
+	area, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+
+	const workers, perWorker = 8, 25
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if _, err := items.Create(ctx, area.ID, nil, fmt.Sprintf("item-%d-%d", w, i), "1", "user", nil); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent create: %v", err)
+	}
+
+	list, err := items.ListByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Len(t, list, workers*perWorker)
+}
+
+func TestItemStoreSetStatus(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	items := NewItemStore(d)
+	ctx := context.Background()
+
+	area, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	milk, err := items.Create(ctx, area.ID, nil, "Milk", "1", "ai", nil)
+	require.NoError(t, err)
+	eggs, err := items.Create(ctx, area.ID, nil, "Eggs", "12", "ai", nil)
+	require.NoError(t, err)
+	jam, err := items.Create(ctx, area.ID, nil, "Jam", "1", "ai", nil)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ItemStatusActive, milk.Status)
+	assert.Nil(t, milk.ConsumedAt)
+
+	ok, err := items.SetStatus(ctx, milk.ID, domain.ItemStatusConsumed)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = items.SetStatus(ctx, milk.ID, domain.ItemStatusDiscarded)
+	require.NoError(t, err)
+	assert.False(t, ok, "only active items change status")
+	ok, err = items.SetStatus(ctx, eggs.ID, domain.ItemStatusDiscarded)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	got, err := items.GetByID(ctx, milk.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ItemStatusConsumed, got.Status)
+	require.NotNil(t, got.ConsumedAt)
+	assert.Equal(t, milk.Version+1, got.Version)
+
+	active, err := items.ListByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, jam.ID, active[0].ID)
+	consumed, err := items.ListByAreaIDPage(ctx, area.ID, domain.ItemPage{Status: domain.ItemStatusConsumed})
+	require.NoError(t, err)
+	require.Len(t, consumed, 1)
+	assert.Equal(t, milk.ID, consumed[0].ID)
+	all, err := items.SearchPage(ctx, "", domain.SearchFilter{}, domain.ItemPage{Status: domain.ItemStatusAll})
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+	recent, err := items.ListRecent(ctx, time.Time{}, 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, recent, 1, "recent items are active ones")
+
+	// Clearing the area for re-analysis keeps what was used up or thrown away.
+	require.NoError(t, items.DeleteByAreaID(ctx, area.ID))
+	all, err = items.SearchPage(ctx, "", domain.SearchFilter{}, domain.ItemPage{Status: domain.ItemStatusAll})
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	waste, err := items.MonthlyWaste(ctx, time.Now().AddDate(0, -1, 0))
+	require.NoError(t, err)
+	require.Len(t, waste, 1)
+	assert.Equal(t, time.Now().UTC().Format("2006-01"), waste[0].Month)
+	assert.Equal(t, 1, waste[0].Consumed)
+	assert.Equal(t, 1, waste[0].Discarded)
+	waste, err = items.MonthlyWaste(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, waste)
+}
+
+// benchmarkPantry is how many items a well-stocked pantry photo yields.
+const benchmarkPantry = 60
+
+func openBenchmarkDB(b *testing.B) (*sql.DB, int64) {
+	b.Helper()
+	d, err := db.Open(filepath.Join(b.TempDir(), "bench.db"), db.Options{})
+	require.NoError(b, err)
+	b.Cleanup(func() { _ = db.Close(d) })
+	area, err := NewAreaStore(d).Create(context.Background(), "Pantry")
+	require.NoError(b, err)
+	return d, area.ID
+}
+
+func benchmarkDetected() []domain.DetectedItem {
+	detected := make([]domain.DetectedItem, benchmarkPantry)
+	for i := range detected {
+		detected[i] = domain.DetectedItem{Name: fmt.Sprintf("item-%d", i), Quantity: "500 g", BBoxes: [][]float64{{0.1, 0.2, 0.3, 0.4}}}
+	}
+	return detected
+}
+
+// BenchmarkItemStoreCreate_PerItem stores a pantry's worth of detections
+// one Create at a time, as uploads did before CreateBatch.
+func BenchmarkItemStoreCreate_PerItem(b *testing.B) {
+	d, areaID := openBenchmarkDB(b)
+	items := NewItemStore(d)
+	ctx := context.Background()
+	detected := benchmarkDetected()
+	for b.Loop() {
+		for _, it := range detected {
+			if _, err := items.Create(ctx, areaID, nil, it.Name, it.Quantity, string(domain.ItemSourceAI), it.BBoxes); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkItemStoreCreateBatch stores the same detections with CreateBatch.
+func BenchmarkItemStoreCreateBatch(b *testing.B) {
+	d, areaID := openBenchmarkDB(b)
+	photo, err := NewPhotoStore(d).Create(context.Background(), areaID, "k", "image/jpeg", "")
+	require.NoError(b, err)
+	items := NewItemStore(d)
+	ctx := context.Background()
+	detected := benchmarkDetected()
+	for b.Loop() {
+		if _, err := items.CreateBatch(ctx, areaID, photo.ID, detected); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkItemStoreCreate compares Create with its statements prepared once
+// against preparing them on every call, as a closed store does.
+func BenchmarkItemStoreCreate(b *testing.B) {
+	for _, prepared := range []bool{false, true} {
+		name := "unprepared"
+		if prepared {
+			name = "prepared"
+		}
+		b.Run(name, func(b *testing.B) {
+			d, areaID := openBenchmarkDB(b)
+			items := NewItemStore(d)
+			if !prepared {
+				require.NoError(b, items.Close())
+			}
+			ctx := context.Background()
+			for b.Loop() {
+				if _, err := items.Create(ctx, areaID, nil, "Rice", "500 g", string(domain.ItemSourceAI), nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -255,9 +255,11 @@ func TestOverrideStore_DeleteOrphanedAreaRules(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, rules, 1)
 
-	// Delete the area (cascades the override_rule_areas row).
+	// Delete and purge the area (cascades the override_rule_areas row).
 	err = areaStore.Delete(ctx, area.ID)
 	require.NoError(t, err)
+	err = areaStore.Purge(ctx, area.ID)
+	require.NoError(t, err)
 
 	// Rule is now orphaned — clean it up.
 	err = s.DeleteOrphanedAreaRules(ctx)
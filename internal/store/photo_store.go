@@ -4,22 +4,34 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/vbonduro/kitchinv/internal/db"
 	"github.com/vbonduro/kitchinv/internal/domain"
 )
 
 type PhotoStore struct {
-	db *sql.DB
+	db    *sql.DB
+	stmts *stmtCache // the hot queries, prepared once
 }
 
 func NewPhotoStore(db *sql.DB) *PhotoStore {
-	return &PhotoStore{db: db}
+	return &PhotoStore{db: db, stmts: newStmtCache(db)}
 }
 
-func (s *PhotoStore) Create(ctx context.Context, areaID int64, storageKey, mimeType string) (*domain.Photo, error) {
+// Close closes the store's prepared statements. Close stores before their
+// database.
+func (s *PhotoStore) Close() error {
+	return s.stmts.Close()
+}
+
+// Create records a new photo for an area with its analysis running.
+// contentHash is the hex SHA-256 of the image bytes, or "" if unknown.
+func (s *PhotoStore) Create(ctx context.Context, areaID int64, storageKey, mimeType, contentHash string) (*domain.Photo, error) {
 	result, err := s.db.ExecContext(ctx, `
-		INSERT INTO photos (area_id, storage_key, mime_type) VALUES (?, ?, ?)
-	`, areaID, storageKey, mimeType)
+		INSERT INTO photos (area_id, storage_key, mime_type, content_hash, analysis_status) VALUES (?, ?, ?, ?, 'running')
+	`, areaID, storageKey, mimeType, contentHash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create photo: %w", err)
 	}
@@ -34,9 +46,11 @@ func (s *PhotoStore) Create(ctx context.Context, areaID int64, storageKey, mimeT
 
 func (s *PhotoStore) GetByID(ctx context.Context, id int64) (*domain.Photo, error) {
 	photo := &domain.Photo{}
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, area_id, storage_key, mime_type, uploaded_at FROM photos WHERE id = ?
-	`, id).Scan(&photo.ID, &photo.AreaID, &photo.StorageKey, &photo.MimeType, &photo.UploadedAt)
+	err := s.stmts.queryRow(ctx, `
+		SELECT id, area_id, storage_key, mime_type, content_hash, uploaded_at, analysis_status, analysis_error,
+			input_tokens, output_tokens, cost_usd, eval_duration_ms, cache_write_tokens, cache_read_tokens, stop_reason, truncated, items_kept FROM photos WHERE id = ?
+	`, id).Scan(&photo.ID, &photo.AreaID, &photo.StorageKey, &photo.MimeType, &photo.ContentHash, &photo.UploadedAt, &photo.AnalysisStatus, &photo.AnalysisError,
+		&photo.InputTokens, &photo.OutputTokens, &photo.CostUSD, &photo.EvalDurationMs, &photo.CacheWriteTokens, &photo.CacheReadTokens, &photo.StopReason, &photo.Truncated, &photo.ItemsKept)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -50,10 +64,12 @@ func (s *PhotoStore) GetByID(ctx context.Context, id int64) (*domain.Photo, erro
 
 func (s *PhotoStore) GetLatestByAreaID(ctx context.Context, areaID int64) (*domain.Photo, error) {
 	photo := &domain.Photo{}
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, area_id, storage_key, mime_type, uploaded_at FROM photos
+	err := s.stmts.queryRow(ctx, `
+		SELECT id, area_id, storage_key, mime_type, content_hash, uploaded_at, analysis_status, analysis_error,
+			input_tokens, output_tokens, cost_usd, eval_duration_ms, cache_write_tokens, cache_read_tokens, stop_reason, truncated, items_kept FROM photos
 		WHERE area_id = ? ORDER BY uploaded_at DESC, id DESC LIMIT 1
-	`, areaID).Scan(&photo.ID, &photo.AreaID, &photo.StorageKey, &photo.MimeType, &photo.UploadedAt)
+	`, areaID).Scan(&photo.ID, &photo.AreaID, &photo.StorageKey, &photo.MimeType, &photo.ContentHash, &photo.UploadedAt, &photo.AnalysisStatus, &photo.AnalysisError,
+		&photo.InputTokens, &photo.OutputTokens, &photo.CostUSD, &photo.EvalDurationMs, &photo.CacheWriteTokens, &photo.CacheReadTokens, &photo.StopReason, &photo.Truncated, &photo.ItemsKept)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -65,6 +81,173 @@ func (s *PhotoStore) GetLatestByAreaID(ctx context.Context, areaID int64) (*doma
 	return photo, nil
 }
 
+// ListByAreaID returns every photo recorded for an area, oldest first.
+func (s *PhotoStore) ListByAreaID(ctx context.Context, areaID int64) ([]*domain.Photo, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, area_id, storage_key, mime_type, content_hash, uploaded_at, analysis_status, analysis_error,
+			input_tokens, output_tokens, cost_usd, eval_duration_ms, cache_write_tokens, cache_read_tokens, stop_reason, truncated, items_kept FROM photos
+		WHERE area_id = ? ORDER BY uploaded_at ASC, id ASC
+	`, areaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list photos: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("failed to close rows", "error", err)
+		}
+	}()
+
+	var photos []*domain.Photo
+	for rows.Next() {
+		photo := &domain.Photo{}
+		if err := rows.Scan(&photo.ID, &photo.AreaID, &photo.StorageKey, &photo.MimeType, &photo.ContentHash, &photo.UploadedAt, &photo.AnalysisStatus, &photo.AnalysisError,
+			&photo.InputTokens, &photo.OutputTokens, &photo.CostUSD, &photo.EvalDurationMs, &photo.CacheWriteTokens, &photo.CacheReadTokens, &photo.StopReason, &photo.Truncated, &photo.ItemsKept); err != nil {
+			return nil, fmt.Errorf("failed to scan photo: %w", err)
+		}
+		photos = append(photos, photo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating photos: %w", err)
+	}
+
+	return photos, nil
+}
+
+// SetAnalysisStatus records the outcome of a photo's vision analysis. errMsg
+// is shown to the user when status is failed and should be empty otherwise.
+func (s *PhotoStore) SetAnalysisStatus(ctx context.Context, id int64, status domain.PhotoAnalysisStatus, errMsg string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE photos SET analysis_status = ?, analysis_error = ? WHERE id = ?
+	`, string(status), errMsg, id)
+	if err != nil {
+		return fmt.Errorf("failed to set photo analysis status: %w", err)
+	}
+	return nil
+}
+
+// SetRawResponse stores the vision backend's unparsed reply for a photo.
+func (s *PhotoStore) SetRawResponse(ctx context.Context, id int64, raw string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE photos SET raw_response = ? WHERE id = ?`, raw, id)
+	if err != nil {
+		return fmt.Errorf("failed to set photo raw response: %w", err)
+	}
+	return nil
+}
+
+// SetUsage records what a photo's vision analysis consumed.
+func (s *PhotoStore) SetUsage(ctx context.Context, id int64, u domain.AnalysisUsage) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE photos SET input_tokens = ?, output_tokens = ?, cost_usd = ?, eval_duration_ms = ?,
+			cache_write_tokens = ?, cache_read_tokens = ?
+		WHERE id = ?
+	`, u.InputTokens, u.OutputTokens, u.CostUSD, u.EvalDurationMs, u.CacheWriteTokens, u.CacheReadTokens, id)
+	if err != nil {
+		return fmt.Errorf("failed to set photo usage: %w", err)
+	}
+	return nil
+}
+
+// SetStopReason records why a photo's vision analysis stopped and whether
+// that was the backend's output limit.
+func (s *PhotoStore) SetStopReason(ctx context.Context, id int64, reason string, truncated bool) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE photos SET stop_reason = ?, truncated = ? WHERE id = ?`, reason, truncated, id)
+	if err != nil {
+		return fmt.Errorf("failed to set photo stop reason: %w", err)
+	}
+	return nil
+}
+
+// SetItemsKept records whether the area's previous items were kept because
+// the photo's analysis detected none.
+func (s *PhotoStore) SetItemsKept(ctx context.Context, id int64, kept bool) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE photos SET items_kept = ? WHERE id = ?`, kept, id)
+	if err != nil {
+		return fmt.Errorf("failed to set photo items kept: %w", err)
+	}
+	return nil
+}
+
+// MonthlyUsage totals analysis usage by upload month for photos uploaded at
+// or after since, newest month first. Only photos that reported usage are
+// counted.
+func (s *PhotoStore) MonthlyUsage(ctx context.Context, since time.Time) ([]domain.MonthlyUsage, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT strftime('%Y-%m', uploaded_at) AS month, COUNT(*),
+			SUM(input_tokens), SUM(output_tokens), SUM(cost_usd), SUM(eval_duration_ms),
+			SUM(cache_write_tokens), SUM(cache_read_tokens)
+		FROM photos
+		WHERE uploaded_at >= ? AND input_tokens + output_tokens > 0
+		GROUP BY month ORDER BY month DESC
+	`, db.FormatTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to total photo usage: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("failed to close rows", "error", err)
+		}
+	}()
+
+	var months []domain.MonthlyUsage
+	for rows.Next() {
+		var m domain.MonthlyUsage
+		if err := rows.Scan(&m.Month, &m.Analyses, &m.InputTokens, &m.OutputTokens, &m.CostUSD, &m.EvalDurationMs, &m.CacheWriteTokens, &m.CacheReadTokens); err != nil {
+			return nil, fmt.Errorf("failed to scan photo usage: %w", err)
+		}
+		months = append(months, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating photo usage: %w", err)
+	}
+	return months, nil
+}
+
+// GetRawResponse returns the stored vision reply for a photo, or "" when
+// none was recorded. It is kept out of the Photo scan since it can be large.
+func (s *PhotoStore) GetRawResponse(ctx context.Context, id int64) (string, error) {
+	var raw string
+	err := s.db.QueryRowContext(ctx, `SELECT raw_response FROM photos WHERE id = ?`, id).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get photo raw response: %w", err)
+	}
+	return raw, nil
+}
+
+// FailRunningBefore marks every photo still analysing that was uploaded at or
+// before cutoff as failed with reason, returning how many rows changed.
+func (s *PhotoStore) FailRunningBefore(ctx context.Context, cutoff time.Time, reason string) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE photos SET analysis_status = 'failed', analysis_error = ?
+		WHERE analysis_status = 'running' AND uploaded_at <= ?
+	`, reason, db.FormatTime(cutoff))
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark interrupted analyses: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return n, nil
+}
+
+// CountByStorageKey returns how many photo records, across all areas, refer
+// to storageKey. Content-addressed files are shared, so a file may only be
+// removed once this reaches zero.
+func (s *PhotoStore) CountByStorageKey(ctx context.Context, storageKey string) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM photos WHERE storage_key = ?
+	`, storageKey).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count photos by storage key: %w", err)
+	}
+	return n, nil
+}
+
 func (s *PhotoStore) DeleteByArea(ctx context.Context, areaID int64) (*domain.Photo, error) {
 	// Get the latest photo first so we can return it for file cleanup.
 	photo, err := s.GetLatestByAreaID(ctx, areaID)
@@ -3,9 +3,11 @@ package store
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
 )
 
 func TestPhotoStoreCreate(t *testing.T) {
@@ -17,7 +19,7 @@ func TestPhotoStoreCreate(t *testing.T) {
 	area, err := areas.Create(ctx, "Fridge")
 	require.NoError(t, err)
 
-	photo, err := photos.Create(ctx, area.ID, "area_1/abc123.jpg", "image/jpeg")
+	photo, err := photos.Create(ctx, area.ID, "area_1/abc123.jpg", "image/jpeg", "")
 	require.NoError(t, err)
 	assert.NotZero(t, photo.ID)
 	assert.Equal(t, area.ID, photo.AreaID)
@@ -34,9 +36,9 @@ func TestPhotoStoreGetLatestByAreaID(t *testing.T) {
 	area, err := areas.Create(ctx, "Fridge")
 	require.NoError(t, err)
 
-	first, err := photos.Create(ctx, area.ID, "key1.jpg", "image/jpeg")
+	first, err := photos.Create(ctx, area.ID, "key1.jpg", "image/jpeg", "")
 	require.NoError(t, err)
-	second, err := photos.Create(ctx, area.ID, "key2.jpg", "image/jpeg")
+	second, err := photos.Create(ctx, area.ID, "key2.jpg", "image/jpeg", "")
 	require.NoError(t, err)
 
 	latest, err := photos.GetLatestByAreaID(ctx, area.ID)
@@ -69,7 +71,7 @@ func TestPhotoStoreDeleteByArea(t *testing.T) {
 	area, err := areas.Create(ctx, "Fridge")
 	require.NoError(t, err)
 
-	_, err = photos.Create(ctx, area.ID, "key1.jpg", "image/jpeg")
+	_, err = photos.Create(ctx, area.ID, "key1.jpg", "image/jpeg", "")
 	require.NoError(t, err)
 
 	deleted, err := photos.DeleteByArea(ctx, area.ID)
@@ -97,6 +99,36 @@ func TestPhotoStoreDeleteByArea_NoPhotos(t *testing.T) {
 	assert.Nil(t, deleted)
 }
 
+func TestPhotoStoreCountByStorageKey(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	photos := NewPhotoStore(d)
+	ctx := context.Background()
+
+	fridge, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	pantry, err := areas.Create(ctx, "Pantry")
+	require.NoError(t, err)
+
+	p1, err := photos.Create(ctx, fridge.ID, "sha256/abc.jpg", "image/jpeg", "")
+	require.NoError(t, err)
+	_, err = photos.Create(ctx, pantry.ID, "sha256/abc.jpg", "image/jpeg", "")
+	require.NoError(t, err)
+
+	n, err := photos.CountByStorageKey(ctx, "sha256/abc.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	require.NoError(t, photos.Delete(ctx, p1.ID))
+	n, err = photos.CountByStorageKey(ctx, "sha256/abc.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	n, err = photos.CountByStorageKey(ctx, "sha256/missing.jpg")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
 func TestPhotoStoreDelete(t *testing.T) {
 	d := openTestDB(t)
 	areas := NewAreaStore(d)
@@ -106,7 +138,7 @@ func TestPhotoStoreDelete(t *testing.T) {
 	area, err := areas.Create(ctx, "Fridge")
 	require.NoError(t, err)
 
-	photo, err := photos.Create(ctx, area.ID, "key.jpg", "image/jpeg")
+	photo, err := photos.Create(ctx, area.ID, "key.jpg", "image/jpeg", "")
 	require.NoError(t, err)
 
 	err = photos.Delete(ctx, photo.ID)
@@ -125,3 +157,196 @@ func TestPhotoStoreDelete_NotFound(t *testing.T) {
 	err := photos.Delete(ctx, 99999)
 	assert.Error(t, err)
 }
+
+func TestPhotoStoreListByAreaID(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	photos := NewPhotoStore(d)
+	ctx := context.Background()
+
+	area, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	other, err := areas.Create(ctx, "Pantry")
+	require.NoError(t, err)
+
+	_, err = photos.Create(ctx, area.ID, "key1.jpg", "image/jpeg", "")
+	require.NoError(t, err)
+	_, err = photos.Create(ctx, area.ID, "key2.jpg", "image/jpeg", "")
+	require.NoError(t, err)
+	_, err = photos.Create(ctx, other.ID, "key3.jpg", "image/jpeg", "")
+	require.NoError(t, err)
+
+	list, err := photos.ListByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "key1.jpg", list[0].StorageKey)
+	assert.Equal(t, "key2.jpg", list[1].StorageKey)
+}
+
+func TestPhotoStoreAnalysisStatus(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	photos := NewPhotoStore(d)
+	ctx := context.Background()
+
+	area, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+
+	photo, err := photos.Create(ctx, area.ID, "key.jpg", "image/jpeg", "")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PhotoAnalysisRunning, photo.AnalysisStatus)
+
+	require.NoError(t, photos.SetAnalysisStatus(ctx, photo.ID, domain.PhotoAnalysisFailed, "boom"))
+
+	latest, err := photos.GetLatestByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	require.NotNil(t, latest)
+	assert.Equal(t, domain.PhotoAnalysisFailed, latest.AnalysisStatus)
+	assert.Equal(t, "boom", latest.AnalysisError)
+}
+
+func TestPhotoStoreFailRunningBefore(t *testing.T) {
+	d := openTestDB(t)
+	areas := NewAreaStore(d)
+	photos := NewPhotoStore(d)
+	ctx := context.Background()
+
+	fridge, err := areas.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	pantry, err := areas.Create(ctx, "Pantry")
+	require.NoError(t, err)
+
+	running, err := photos.Create(ctx, fridge.ID, "a.jpg", "image/jpeg", "")
+	require.NoError(t, err)
+	done, err := photos.Create(ctx, pantry.ID, "b.jpg", "image/jpeg", "")
+	require.NoError(t, err)
+	require.NoError(t, photos.SetAnalysisStatus(ctx, done.ID, domain.PhotoAnalysisComplete, ""))
+
+	// A cutoff in the past leaves fresh uploads alone.
+	n, err := photos.FailRunningBefore(ctx, time.Now().Add(-time.Hour), "interrupted")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	n, err = photos.FailRunningBefore(ctx, time.Now().Add(time.Minute), "interrupted")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	got, err := photos.GetLatestByAreaID(ctx, fridge.ID)
+	require.NoError(t, err)
+	assert.Equal(t, running.ID, got.ID)
+	assert.Equal(t, domain.PhotoAnalysisFailed, got.AnalysisStatus)
+	assert.Equal(t, "interrupted", got.AnalysisError)
+
+	got, err = photos.GetLatestByAreaID(ctx, pantry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.PhotoAnalysisComplete, got.AnalysisStatus)
+}
+
+func TestPhotoStoreRawResponse(t *testing.T) {
+	d := openTestDB(t)
+	areaStore := NewAreaStore(d)
+	photoStore := NewPhotoStore(d)
+	ctx := context.Background()
+
+	area, err := areaStore.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	photo, err := photoStore.Create(ctx, area.ID, "k", "image/jpeg", "")
+	require.NoError(t, err)
+
+	raw, err := photoStore.GetRawResponse(ctx, photo.ID)
+	require.NoError(t, err)
+	assert.Empty(t, raw)
+
+	require.NoError(t, photoStore.SetRawResponse(ctx, photo.ID, `{"status":"ok"}`))
+	raw, err = photoStore.GetRawResponse(ctx, photo.ID)
+	require.NoError(t, err)
+	assert.Equal(t, `{"status":"ok"}`, raw)
+
+	raw, err = photoStore.GetRawResponse(ctx, 99999)
+	require.NoError(t, err)
+	assert.Empty(t, raw)
+}
+
+func TestPhotoStoreUsage(t *testing.T) {
+	d := openTestDB(t)
+	areaStore := NewAreaStore(d)
+	photoStore := NewPhotoStore(d)
+	ctx := context.Background()
+
+	area, err := areaStore.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	first, err := photoStore.Create(ctx, area.ID, "a", "image/jpeg", "")
+	require.NoError(t, err)
+	second, err := photoStore.Create(ctx, area.ID, "b", "image/jpeg", "")
+	require.NoError(t, err)
+	_, err = photoStore.Create(ctx, area.ID, "c", "image/jpeg", "")
+	require.NoError(t, err)
+
+	u := domain.AnalysisUsage{InputTokens: 1500, OutputTokens: 300, CostUSD: 0.015, EvalDurationMs: 0, CacheWriteTokens: 1200, CacheReadTokens: 40}
+	require.NoError(t, photoStore.SetUsage(ctx, first.ID, u))
+	require.NoError(t, photoStore.SetUsage(ctx, second.ID, domain.AnalysisUsage{InputTokens: 500, OutputTokens: 100, EvalDurationMs: 2500}))
+
+	got, err := photoStore.GetByID(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, u, got.AnalysisUsage)
+
+	months, err := photoStore.MonthlyUsage(ctx, time.Now().AddDate(0, -1, 0))
+	require.NoError(t, err)
+	require.Len(t, months, 1)
+	m := months[0]
+	assert.Equal(t, time.Now().UTC().Format("2006-01"), m.Month)
+	assert.Equal(t, 2, m.Analyses, "photos without usage are not counted")
+	assert.Equal(t, 2000, m.InputTokens)
+	assert.Equal(t, 400, m.OutputTokens)
+	assert.InDelta(t, 0.015, m.CostUSD, 1e-9)
+	assert.Equal(t, int64(2500), m.EvalDurationMs)
+	assert.Equal(t, 1200, m.CacheWriteTokens)
+	assert.Equal(t, 40, m.CacheReadTokens)
+
+	months, err = photoStore.MonthlyUsage(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, months)
+}
+
+func TestPhotoStoreSetStopReason(t *testing.T) {
+	d := openTestDB(t)
+	areaStore := NewAreaStore(d)
+	photoStore := NewPhotoStore(d)
+	ctx := context.Background()
+
+	area, err := areaStore.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	photo, err := photoStore.Create(ctx, area.ID, "key", "image/jpeg", "")
+	require.NoError(t, err)
+	assert.Empty(t, photo.StopReason)
+	assert.False(t, photo.Truncated)
+
+	require.NoError(t, photoStore.SetStopReason(ctx, photo.ID, "max_tokens", true))
+	got, err := photoStore.GetLatestByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "max_tokens", got.StopReason)
+	assert.True(t, got.Truncated)
+}
+
+func TestPhotoStoreSetItemsKept(t *testing.T) {
+	d := openTestDB(t)
+	areaStore := NewAreaStore(d)
+	photoStore := NewPhotoStore(d)
+	ctx := context.Background()
+
+	area, err := areaStore.Create(ctx, "Fridge")
+	require.NoError(t, err)
+	photo, err := photoStore.Create(ctx, area.ID, "key", "image/jpeg", "")
+	require.NoError(t, err)
+	assert.False(t, photo.ItemsKept)
+
+	require.NoError(t, photoStore.SetItemsKept(ctx, photo.ID, true))
+	got, err := photoStore.GetByID(ctx, photo.ID)
+	require.NoError(t, err)
+	assert.True(t, got.ItemsKept)
+
+	require.NoError(t, photoStore.SetItemsKept(ctx, photo.ID, false))
+	got, err = photoStore.GetLatestByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	assert.False(t, got.ItemsKept)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -18,15 +19,33 @@ func NewSnapshotStore(db *sql.DB) *SnapshotStore {
 	return &SnapshotStore{db: db}
 }
 
-func (s *SnapshotStore) Create(ctx context.Context, areaID int64, items []domain.SnapshotItem) (*domain.Snapshot, error) {
+// Create records the items an area held before photoID's analysis replaced
+// them. photoID may be nil.
+func (s *SnapshotStore) Create(ctx context.Context, areaID int64, photoID *int64, items []domain.SnapshotItem) (*domain.Snapshot, error) {
+	return createSnapshot(ctx, s.db, areaID, photoID, items)
+}
+
+// CreateTx is Create within tx, so the snapshot is taken by the same
+// transaction that replaces the items.
+func (s *SnapshotStore) CreateTx(ctx context.Context, tx *sql.Tx, areaID int64, photoID *int64, items []domain.SnapshotItem) (*domain.Snapshot, error) {
+	return createSnapshot(ctx, tx, areaID, photoID, items)
+}
+
+// execQuerier is satisfied by *sql.DB and *sql.Tx.
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func createSnapshot(ctx context.Context, q execQuerier, areaID int64, photoID *int64, items []domain.SnapshotItem) (*domain.Snapshot, error) {
 	data, err := json.Marshal(items)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode snapshot items: %w", err)
 	}
 
-	res, err := s.db.ExecContext(ctx,
-		`INSERT INTO area_snapshots (area_id, items) VALUES (?, ?)`,
-		areaID, string(data),
+	res, err := q.ExecContext(ctx,
+		`INSERT INTO area_snapshots (area_id, photo_id, items) VALUES (?, ?, ?)`,
+		areaID, photoID, string(data),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert snapshot: %w", err)
@@ -38,13 +57,14 @@ func (s *SnapshotStore) Create(ctx context.Context, areaID int64, items []domain
 	}
 
 	var takenAt time.Time
-	if err := s.db.QueryRowContext(ctx, `SELECT taken_at FROM area_snapshots WHERE id = ?`, id).Scan(&takenAt); err != nil {
+	if err := q.QueryRowContext(ctx, `SELECT taken_at FROM area_snapshots WHERE id = ?`, id).Scan(&takenAt); err != nil {
 		return nil, fmt.Errorf("failed to read snapshot taken_at: %w", err)
 	}
 
 	return &domain.Snapshot{
 		ID:      id,
 		AreaID:  areaID,
+		PhotoID: photoID,
 		TakenAt: takenAt,
 		Items:   items,
 	}, nil
@@ -52,7 +72,7 @@ func (s *SnapshotStore) Create(ctx context.Context, areaID int64, items []domain
 
 func (s *SnapshotStore) ListByAreaID(ctx context.Context, areaID int64) ([]*domain.Snapshot, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, area_id, taken_at, items FROM area_snapshots WHERE area_id = ? ORDER BY taken_at DESC`,
+		`SELECT id, area_id, photo_id, taken_at, items FROM area_snapshots WHERE area_id = ? ORDER BY taken_at DESC`,
 		areaID,
 	)
 	if err != nil {
@@ -62,15 +82,44 @@ func (s *SnapshotStore) ListByAreaID(ctx context.Context, areaID int64) ([]*doma
 
 	snapshots := make([]*domain.Snapshot, 0)
 	for rows.Next() {
-		var snap domain.Snapshot
-		var itemsJSON string
-		if err := rows.Scan(&snap.ID, &snap.AreaID, &snap.TakenAt, &itemsJSON); err != nil {
+		snap, err := scanSnapshot(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
 		}
-		if err := json.Unmarshal([]byte(itemsJSON), &snap.Items); err != nil {
-			return nil, fmt.Errorf("failed to decode snapshot items: %w", err)
-		}
-		snapshots = append(snapshots, &snap)
+		snapshots = append(snapshots, snap)
 	}
 	return snapshots, rows.Err()
 }
+
+// GetByPhotoID returns the snapshot taken when photoID's analysis replaced
+// the area's items, or nil if there is none: the area was empty, or the
+// photo was never analysed.
+func (s *SnapshotStore) GetByPhotoID(ctx context.Context, photoID int64) (*domain.Snapshot, error) {
+	snap, err := scanSnapshot(s.db.QueryRowContext(ctx,
+		`SELECT id, area_id, photo_id, taken_at, items FROM area_snapshots WHERE photo_id = ? ORDER BY id DESC LIMIT 1`,
+		photoID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+func scanSnapshot(row rowScanner) (*domain.Snapshot, error) {
+	var snap domain.Snapshot
+	var photoID sql.NullInt64
+	var itemsJSON string
+	if err := row.Scan(&snap.ID, &snap.AreaID, &photoID, &snap.TakenAt, &itemsJSON); err != nil {
+		return nil, err
+	}
+	if photoID.Valid {
+		snap.PhotoID = &photoID.Int64
+	}
+	if err := json.Unmarshal([]byte(itemsJSON), &snap.Items); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot items: %w", err)
+	}
+	return &snap, nil
+}
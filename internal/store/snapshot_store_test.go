@@ -0,0 +1,40 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+func TestSnapshotStoreGetByPhotoID(t *testing.T) {
+	d := openTestDB(t)
+	ctx := context.Background()
+	area, err := NewAreaStore(d).Create(ctx, "Fridge")
+	require.NoError(t, err)
+	photo, err := NewPhotoStore(d).Create(ctx, area.ID, "k1", "image/jpeg", "")
+	require.NoError(t, err)
+	store := NewSnapshotStore(d)
+
+	snap, err := store.GetByPhotoID(ctx, photo.ID)
+	require.NoError(t, err)
+	assert.Nil(t, snap)
+
+	_, err = store.Create(ctx, area.ID, nil, []domain.SnapshotItem{{Name: "Jam"}})
+	require.NoError(t, err)
+	_, err = store.Create(ctx, area.ID, &photo.ID, []domain.SnapshotItem{{Name: "Milk", Quantity: "1"}})
+	require.NoError(t, err)
+
+	snap, err = store.GetByPhotoID(ctx, photo.ID)
+	require.NoError(t, err)
+	require.NotNil(t, snap)
+	assert.Equal(t, []domain.SnapshotItem{{Name: "Milk", Quantity: "1"}}, snap.Items)
+	require.NotNil(t, snap.PhotoID)
+	assert.Equal(t, photo.ID, *snap.PhotoID)
+
+	all, err := store.ListByAreaID(ctx, area.ID)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// StapleStore persists the staples the household wants kept in stock.
+type StapleStore struct {
+	db *sql.DB
+}
+
+// NewStapleStore creates a new StapleStore backed by db.
+func NewStapleStore(db *sql.DB) *StapleStore {
+	return &StapleStore{db: db}
+}
+
+// Create adds a staple. A pattern may appear once per area, regardless of
+// case; a duplicate fails with a UNIQUE constraint error.
+func (s *StapleStore) Create(ctx context.Context, st domain.Staple) (*domain.Staple, error) {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO staples (pattern, min_quantity, min_unit, area_id) VALUES (?, ?, ?, ?)
+	`, st.Pattern, st.MinQuantity, st.MinUnit, st.AreaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staple: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+
+	created := &domain.Staple{}
+	err = s.db.QueryRowContext(ctx, `
+		SELECT id, pattern, min_quantity, min_unit, area_id, created_at FROM staples WHERE id = ?
+	`, id).Scan(&created.ID, &created.Pattern, &created.MinQuantity, &created.MinUnit, &created.AreaID, &created.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staple: %w", err)
+	}
+	return created, nil
+}
+
+// List returns every staple, alphabetically by pattern.
+func (s *StapleStore) List(ctx context.Context) ([]*domain.Staple, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, pattern, min_quantity, min_unit, area_id, created_at
+		FROM staples ORDER BY pattern COLLATE NOCASE ASC, id ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list staples: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("failed to close rows", "error", err)
+		}
+	}()
+
+	var staples []*domain.Staple
+	for rows.Next() {
+		st := &domain.Staple{}
+		if err := rows.Scan(&st.ID, &st.Pattern, &st.MinQuantity, &st.MinUnit, &st.AreaID, &st.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan staple: %w", err)
+		}
+		staples = append(staples, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating staples: %w", err)
+	}
+	return staples, nil
+}
+
+// Delete removes a staple. It reports whether the staple existed.
+func (s *StapleStore) Delete(ctx context.Context, id int64) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM staples WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete staple: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return n > 0, nil
+}
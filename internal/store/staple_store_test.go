@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+func TestStapleStore(t *testing.T) {
+	d := openTestDB(t)
+	store := NewStapleStore(d)
+	ctx := context.Background()
+	fridge, err := NewAreaStore(d).Create(ctx, "Fridge")
+	require.NoError(t, err)
+
+	staples, err := store.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, staples)
+
+	milk, err := store.Create(ctx, domain.Staple{Pattern: "milk", MinQuantity: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, milk.MinQuantity)
+	assert.Nil(t, milk.AreaID)
+	assert.False(t, milk.CreatedAt.IsZero())
+	eggs, err := store.Create(ctx, domain.Staple{Pattern: "Eggs", MinQuantity: 6, AreaID: &fridge.ID})
+	require.NoError(t, err)
+	require.NotNil(t, eggs.AreaID)
+	assert.Equal(t, fridge.ID, *eggs.AreaID)
+
+	_, err = store.Create(ctx, domain.Staple{Pattern: "Milk", MinQuantity: 1})
+	assert.Error(t, err, "a pattern is unique regardless of case")
+	_, err = store.Create(ctx, domain.Staple{Pattern: "Milk", MinQuantity: 1, AreaID: &fridge.ID})
+	assert.NoError(t, err, "but may be repeated for an area")
+
+	staples, err = store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, staples, 3)
+	assert.Equal(t, "Eggs", staples[0].Pattern)
+
+	ok, err := store.Delete(ctx, milk.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = store.Delete(ctx, milk.ID)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
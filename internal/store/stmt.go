@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// stmtCache prepares a store's hot queries the first time they run and
+// reuses the statements after that, so SQLite parses and plans them once.
+// It is safe for concurrent use, as is each *sql.Stmt. A query that fails to
+// prepare, or runs after Close, runs unprepared; either way a bad query
+// reports its own error when it runs. Statements belong to the pool, so
+// queries inside a transaction don't go through the cache: preparing one
+// would need a second connection while the transaction holds its own.
+type stmtCache struct {
+	db     *sql.DB
+	mu     sync.RWMutex
+	stmts  map[string]*sql.Stmt
+	closed bool
+}
+
+func newStmtCache(db *sql.DB) *stmtCache {
+	return &stmtCache{db: db, stmts: make(map[string]*sql.Stmt)}
+}
+
+// prepared returns the statement for query, preparing it on first use, or
+// nil if it can't be.
+func (c *stmtCache) prepared(ctx context.Context, query string) *sql.Stmt {
+	c.mu.RLock()
+	stmt, ok := c.stmts[query]
+	closed := c.closed
+	c.mu.RUnlock()
+	if ok || closed {
+		return stmt
+	}
+
+	// Prepare without the lock so lookups of other statements don't queue
+	// behind it; if two callers race, the first to store its statement wins.
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		slog.Warn("failed to prepare statement, running it unprepared", "error", err)
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.stmts[query]; ok || c.closed {
+		_ = stmt.Close()
+		return cached
+	}
+	c.stmts[query] = stmt
+	return stmt
+}
+
+func (c *stmtCache) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if stmt := c.prepared(ctx, query); stmt != nil {
+		return stmt.ExecContext(ctx, args...)
+	}
+	return c.db.ExecContext(ctx, query, args...)
+}
+
+func (c *stmtCache) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if stmt := c.prepared(ctx, query); stmt != nil {
+		return stmt.QueryContext(ctx, args...)
+	}
+	return c.db.QueryContext(ctx, query, args...)
+}
+
+func (c *stmtCache) queryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	if stmt := c.prepared(ctx, query); stmt != nil {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return c.db.QueryRowContext(ctx, query, args...)
+}
+
+// Close closes the prepared statements. Queries after it run unprepared.
+func (c *stmtCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	var errs []error
+	for _, stmt := range c.stmts {
+		errs = append(errs, stmt.Close())
+	}
+	c.stmts = nil
+	return errors.Join(errs...)
+}
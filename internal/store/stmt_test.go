@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/db"
+)
+
+func TestStmtCache_PreparesOnce(t *testing.T) {
+	d := openTestDB(t)
+	c := newStmtCache(d)
+	t.Cleanup(func() { assert.NoError(t, c.Close()) })
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		var n int
+		require.NoError(t, c.queryRow(ctx, `SELECT ? + 1`, i).Scan(&n))
+		assert.Equal(t, i+1, n)
+	}
+	_, err := c.exec(ctx, `INSERT INTO areas (name) VALUES (?)`, "Fridge")
+	require.NoError(t, err)
+	assert.Len(t, c.stmts, 2)
+}
+
+func TestStmtCache_BadQueryReportsItsError(t *testing.T) {
+	d := openTestDB(t)
+	c := newStmtCache(d)
+	t.Cleanup(func() { assert.NoError(t, c.Close()) })
+
+	_, err := c.query(context.Background(), `SELECT nope FROM nowhere`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nowhere")
+}
+
+func TestStmtCache_Close(t *testing.T) {
+	d := openTestDB(t)
+	c := newStmtCache(d)
+	ctx := context.Background()
+
+	var n int
+	require.NoError(t, c.queryRow(ctx, `SELECT 1`).Scan(&n))
+	require.NoError(t, c.Close())
+	assert.Nil(t, c.stmts)
+
+	// The database outlives the cache, so queries still run, unprepared.
+	require.NoError(t, c.queryRow(ctx, `SELECT 2`).Scan(&n))
+	assert.Equal(t, 2, n)
+	assert.NoError(t, c.Close())
+}
+
+// TestStmtCache_Concurrent shares one cache across goroutines preparing and
+// running the same statements; run it with -race.
+func TestStmtCache_Concurrent(t *testing.T) {
+	d, err := db.Open(filepath.Join(t.TempDir(), "stmts.db"), db.Options{})
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, db.Close(d)) })
+	c := newStmtCache(d)
+	t.Cleanup(func() { assert.NoError(t, c.Close()) })
+	ctx := context.Background()
+
+	const workers, perWorker = 8, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if _, err := c.exec(ctx, `INSERT INTO areas (name) VALUES (?)`, fmt.Sprintf("area-%d-%d", w, i)); err != nil {
+					errs <- err
+					continue
+				}
+				rows, err := c.query(ctx, `SELECT COUNT(*) FROM areas`)
+				if err == nil {
+					err = rows.Close()
+				}
+				if err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent statement: %v", err)
+	}
+	var n int
+	require.NoError(t, c.queryRow(ctx, `SELECT COUNT(*) FROM areas`).Scan(&n))
+	assert.Equal(t, workers*perWorker, n)
+	assert.Len(t, c.stmts, 2)
+}
@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// maxDeliveriesPerWebhook bounds the delivery log kept for each webhook.
+const maxDeliveriesPerWebhook = 100
+
+// WebhookStore persists outbound webhooks and their delivery log.
+type WebhookStore struct {
+	db *sql.DB
+}
+
+// NewWebhookStore creates a new WebhookStore backed by db.
+func NewWebhookStore(db *sql.DB) *WebhookStore {
+	return &WebhookStore{db: db}
+}
+
+// Create adds a webhook.
+func (s *WebhookStore) Create(ctx context.Context, h domain.Webhook) (*domain.Webhook, error) {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhooks (url, secret, events, enabled) VALUES (?, ?, ?, ?)
+	`, h.URL, h.Secret, strings.Join(h.Events, ","), h.Enabled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	return s.GetByID(ctx, id)
+}
+
+// GetByID returns the webhook, or nil if it does not exist.
+func (s *WebhookStore) GetByID(ctx context.Context, id int64) (*domain.Webhook, error) {
+	h, err := scanWebhook(s.db.QueryRowContext(ctx, `
+		SELECT id, url, secret, events, enabled, created_at FROM webhooks WHERE id = ?
+	`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return h, nil
+}
+
+// List returns every webhook in the order they were added.
+func (s *WebhookStore) List(ctx context.Context) ([]*domain.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, url, secret, events, enabled, created_at FROM webhooks ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("failed to close rows", "error", err)
+		}
+	}()
+
+	var hooks []*domain.Webhook
+	for rows.Next() {
+		h, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		hooks = append(hooks, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhooks: %w", err)
+	}
+	return hooks, nil
+}
+
+// Update overwrites the webhook's URL, secret, events and enabled flag. It
+// returns nil if the webhook does not exist.
+func (s *WebhookStore) Update(ctx context.Context, h domain.Webhook) (*domain.Webhook, error) {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE webhooks SET url = ?, secret = ?, events = ?, enabled = ? WHERE id = ?
+	`, h.URL, h.Secret, strings.Join(h.Events, ","), h.Enabled, h.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	return s.GetByID(ctx, h.ID)
+}
+
+// Delete removes a webhook and its delivery log. It reports whether the
+// webhook existed.
+func (s *WebhookStore) Delete(ctx context.Context, id int64) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RecordDelivery logs a delivery attempt and trims the webhook's log to its
+// most recent maxDeliveriesPerWebhook entries.
+func (s *WebhookStore) RecordDelivery(ctx context.Context, d domain.WebhookDelivery) (*domain.WebhookDelivery, error) {
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, attempt, status_code, error, duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, d.WebhookID, d.Event, d.Attempt, d.StatusCode, d.Error, d.DurationMS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM webhook_deliveries
+		WHERE webhook_id = ? AND id <= (
+			SELECT id FROM webhook_deliveries WHERE webhook_id = ?
+			ORDER BY id DESC LIMIT 1 OFFSET ?
+		)
+	`, d.WebhookID, d.WebhookID, maxDeliveriesPerWebhook); err != nil {
+		return nil, fmt.Errorf("failed to trim webhook deliveries: %w", err)
+	}
+
+	out := &domain.WebhookDelivery{}
+	err = s.db.QueryRowContext(ctx, `
+		SELECT id, webhook_id, event, attempt, status_code, error, duration_ms, created_at
+		FROM webhook_deliveries WHERE id = ?
+	`, id).Scan(&out.ID, &out.WebhookID, &out.Event, &out.Attempt, &out.StatusCode, &out.Error, &out.DurationMS, &out.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	return out, nil
+}
+
+// ListDeliveries returns up to limit of the webhook's delivery attempts,
+// newest first.
+func (s *WebhookStore) ListDeliveries(ctx context.Context, webhookID int64, limit int) ([]*domain.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, webhook_id, event, attempt, status_code, error, duration_ms, created_at
+		FROM webhook_deliveries WHERE webhook_id = ?
+		ORDER BY id DESC LIMIT ?
+	`, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			slog.Error("failed to close rows", "error", err)
+		}
+	}()
+
+	var out []*domain.WebhookDelivery
+	for rows.Next() {
+		d := &domain.WebhookDelivery{}
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Attempt, &d.StatusCode, &d.Error, &d.DurationMS, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+	return out, nil
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row rowScanner) (*domain.Webhook, error) {
+	h := &domain.Webhook{}
+	var events string
+	if err := row.Scan(&h.ID, &h.URL, &h.Secret, &events, &h.Enabled, &h.CreatedAt); err != nil {
+		return nil, err
+	}
+	if events != "" {
+		h.Events = strings.Split(events, ",")
+	}
+	return h, nil
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+func TestWebhookStore(t *testing.T) {
+	d := openTestDB(t)
+	store := NewWebhookStore(d)
+	ctx := context.Background()
+
+	h, err := store.Create(ctx, domain.Webhook{
+		URL: "http://ha.local/hook", Secret: "s3cret",
+		Events: []string{"analysis.completed", "area.deleted"}, Enabled: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "http://ha.local/hook", h.URL)
+	assert.Equal(t, "s3cret", h.Secret)
+	assert.Equal(t, []string{"analysis.completed", "area.deleted"}, h.Events)
+	assert.True(t, h.Enabled)
+	assert.False(t, h.CreatedAt.IsZero())
+
+	h.Enabled = false
+	h.Events = []string{"analysis.failed"}
+	updated, err := store.Update(ctx, *h)
+	require.NoError(t, err)
+	require.NotNil(t, updated)
+	assert.False(t, updated.Enabled)
+	assert.Equal(t, []string{"analysis.failed"}, updated.Events)
+
+	missing, err := store.Update(ctx, domain.Webhook{ID: 999, URL: "http://x", Events: []string{"a"}})
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	hooks, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, hooks, 1)
+
+	for i := 1; i <= maxDeliveriesPerWebhook+5; i++ {
+		_, err := store.RecordDelivery(ctx, domain.WebhookDelivery{WebhookID: h.ID, Event: "test", Attempt: i, StatusCode: 200})
+		require.NoError(t, err)
+	}
+	deliveries, err := store.ListDeliveries(ctx, h.ID, 1000)
+	require.NoError(t, err)
+	require.Len(t, deliveries, maxDeliveriesPerWebhook, "the log is trimmed to the newest entries")
+	assert.Equal(t, maxDeliveriesPerWebhook+5, deliveries[0].Attempt, "newest first")
+
+	ok, err := store.Delete(ctx, h.ID)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	deliveries, err = store.ListDeliveries(ctx, h.ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries, "deliveries are removed with their webhook")
+	got, err := store.GetByID(ctx, h.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
@@ -0,0 +1,142 @@
+// Package textfold normalizes text for matching, so that "creme fraiche"
+// finds "Crème Fraîche" and "jalapeno" finds "jalapeño" however the accent
+// was typed.
+//
+// Fold approximates NFD decomposition, dropping the diacritics, followed by
+// case folding. Precomposed Latin letters map to their base letter, combining
+// marks are removed, a few letters with no decomposition (ß, æ, ø, ł, …) are
+// spelled out in ASCII, and everything else is lower-cased. Precomposed
+// letters of other scripts keep their marks, since there a mark often makes a
+// different letter rather than an accented one.
+package textfold
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// spelled are letters without a canonical decomposition that are commonly
+// written in ASCII as these.
+var spelled = map[rune]string{
+	'ß': "ss", 'ẞ': "ss",
+	'æ': "ae", 'Æ': "ae",
+	'œ': "oe", 'Œ': "oe",
+	'ø': "o", 'Ø': "o",
+	'đ': "d", 'Đ': "d",
+	'ð': "d", 'Ð': "d",
+	'ħ': "h", 'Ħ': "h",
+	'ł': "l", 'Ł': "l",
+	'ŀ': "l", 'Ŀ': "l",
+	'ŧ': "t", 'Ŧ': "t",
+	'þ': "th", 'Þ': "th",
+	'ı': "i",
+	'ſ': "s",
+}
+
+// Fold returns s without Latin diacritics and in lower case.
+func Fold(s string) string {
+	if isASCII(s) {
+		return strings.ToLower(s)
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		b.WriteString(foldRune(r))
+	}
+	return b.String()
+}
+
+// Find returns the byte ranges of s, as [start, end) pairs, where query
+// occurs once both are folded, left to right and not overlapping. A match
+// takes in any combining marks after its last letter, so "cafe" covers all
+// of "cafe\u0301".
+func Find(s, query string) [][2]int {
+	q := Fold(query)
+	if q == "" {
+		return nil
+	}
+	// folded is s folded rune by rune; at[i] is the byte offset in s of
+	// the rune that folded[i] came from.
+	var folded strings.Builder
+	var at []int
+	for i, r := range s {
+		f := foldRune(r)
+		folded.WriteString(f)
+		for range len(f) {
+			at = append(at, i)
+		}
+	}
+	hay := folded.String()
+
+	var ranges [][2]int
+	for off := 0; off+len(q) <= len(hay); {
+		n := strings.Index(hay[off:], q)
+		if n < 0 {
+			break
+		}
+		start, last := off+n, off+n+len(q)-1
+		end := at[last] + runeLen(s, at[last])
+		for end < len(s) {
+			r, size := utf8.DecodeRuneInString(s[end:])
+			if foldRune(r) != "" {
+				break
+			}
+			end += size
+		}
+		ranges = append(ranges, [2]int{at[start], end})
+		// Carry on after the original rune the match ended in, so one
+		// "ß" can't end one match and start the next.
+		off = start + len(q)
+		for off < len(hay) && at[off] < end {
+			off++
+		}
+	}
+	return ranges
+}
+
+func runeLen(s string, i int) int {
+	_, size := utf8.DecodeRuneInString(s[i:])
+	return size
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// foldRune is Fold for a single rune; combining marks fold to "".
+func foldRune(r rune) string {
+	if r < utf8.RuneSelf {
+		return asciiLower[r]
+	}
+	if base, ok := latinBase[r]; ok {
+		return asciiLower[base]
+	}
+	if sp, ok := spelled[r]; ok {
+		return sp
+	}
+	if isCombiningLatinMark(r) {
+		return ""
+	}
+	return string(unicode.ToLower(r))
+}
+
+// asciiLower holds each ASCII character lower-cased as a string, so folding
+// one doesn't allocate.
+var asciiLower = func() (t [utf8.RuneSelf]string) {
+	for i := range t {
+		t[i] = strings.ToLower(string(rune(i)))
+	}
+	return t
+}()
+
+// isCombiningLatinMark reports whether r is one of the combining diacritics
+// used with Latin letters, as in "jalapeño".
+func isCombiningLatinMark(r rune) bool {
+	return r >= 0x0300 && r <= 0x036F || r >= 0x1AB0 && r <= 0x1AFF || r >= 0x1DC0 && r <= 0x1DFF
+}
@@ -0,0 +1,69 @@
+package textfold
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Milk", "milk"},
+		{"jalapeño", "jalapeno"},
+		{"jalapen\u0303o", "jalapeno"}, // n + combining tilde
+		{"Crème Fraîche", "creme fraiche"},
+		{"cre\u0300me frai\u0302che", "creme fraiche"}, // combining grave and circumflex
+		{"CRÈME BRÛLÉE", "creme brulee"},
+		{"Häagen-Dazs", "haagen-dazs"},
+		{"Gruyère", "gruyere"},
+		{"Øl", "ol"},
+		{"Weißbier", "weissbier"},
+		{"Smørrebrød", "smorrebrod"},
+		{"Œufs", "oeufs"},
+		{"Łosoś", "losos"},
+		{"phở", "pho"},
+		{"Açaí", "acai"},
+		{"Пельмени", "пельмени"},
+		{"й", "й"}, // a different letter in Cyrillic, not an accented и
+		{"味噌", "味噌"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Fold(tt.in), "Fold(%q)", tt.in)
+	}
+}
+
+func TestFoldIsIdempotent(t *testing.T) {
+	for _, s := range []string{"Crème Fraîche", "Weißbier", "jalapeño"} {
+		once := Fold(s)
+		assert.Equal(t, once, Fold(once))
+	}
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		s, query string
+		want     []string
+	}{
+		{"Greek yoghurt", "YOG", []string{"yog"}},
+		{"Jalapeño peppers", "jalapeno", []string{"Jalapeño"}},
+		{"jalapen\u0303o", "jalapen", []string{"jalapen\u0303"}},
+		{"cafe\u0301 au lait", "cafe", []string{"cafe\u0301"}},
+		{"Crème fraîche", "FRAICHE", []string{"fraîche"}},
+		{"Weißwurst", "ss", []string{"ß"}},
+		{"Weißwurst", "eiss", []string{"eiß"}},
+		{"Banana", "ana", []string{"ana"}}, // not overlapping
+		{"Milk and milk", "milk", []string{"Milk", "milk"}},
+		{"Milk", "", nil},
+		{"Milk", "oat", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range Find(tt.s, tt.query) {
+			got = append(got, tt.s[r[0]:r[1]])
+		}
+		assert.Equal(t, tt.want, got, "Find(%q, %q)", tt.s, tt.query)
+	}
+}
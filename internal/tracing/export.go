@@ -0,0 +1,289 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultBatchSize     = 256
+	defaultFlushInterval = 5 * time.Second
+	// maxQueue bounds the spans held while the collector is unreachable;
+	// beyond it new spans are dropped.
+	maxQueue      = 4096
+	exportTimeout = 10 * time.Second
+)
+
+// active is the exporter spans are recorded for, nil while tracing is off.
+var active atomic.Pointer[exporter]
+
+// Options configure Setup.
+type Options struct {
+	// Endpoint is the URL spans are POSTed to, e.g.
+	// http://collector:4318/v1/traces. Empty leaves tracing off.
+	Endpoint string
+	// Headers are added to every export request, typically for
+	// authentication.
+	Headers map[string]string
+	// ServiceName is reported as the service.name resource attribute.
+	ServiceName string
+	// BatchSize and FlushInterval control how often spans are sent: when
+	// BatchSize are waiting, or every FlushInterval. Zero uses the defaults.
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// Setup starts recording spans and exporting them to opts.Endpoint. The
+// returned function stops recording and sends the spans still queued,
+// giving up when ctx is done. With no endpoint tracing stays off and the
+// function does nothing.
+func Setup(opts Options) func(context.Context) error {
+	if opts.Endpoint == "" {
+		return func(context.Context) error { return nil }
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = defaultFlushInterval
+	}
+	e := &exporter{
+		opts:    opts,
+		client:  &http.Client{Timeout: exportTimeout},
+		flush:   make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	active.Store(e)
+	go e.run()
+	return e.shutdown
+}
+
+// ParseHeaders reads OTEL_EXPORTER_OTLP_HEADERS: comma-separated key=value
+// pairs whose values may be URL-encoded.
+func ParseHeaders(s string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("header %q is not key=value", strings.TrimSpace(pair))
+		}
+		val, err := url.QueryUnescape(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("header %q: %w", k, err)
+		}
+		out[k] = val
+	}
+	return out, nil
+}
+
+// exporter batches finished spans and sends them in the background.
+type exporter struct {
+	opts   Options
+	client *http.Client
+
+	mu      sync.Mutex
+	queue   []*Span
+	dropped int
+
+	flush   chan struct{} // signalled when a batch is ready
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func (e *exporter) enqueue(s *Span) {
+	e.mu.Lock()
+	if len(e.queue) >= maxQueue {
+		e.dropped++
+		e.mu.Unlock()
+		return
+	}
+	e.queue = append(e.queue, s)
+	full := len(e.queue) >= e.opts.BatchSize
+	e.mu.Unlock()
+	if full {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *exporter) run() {
+	defer close(e.stopped)
+	t := time.NewTicker(e.opts.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-t.C:
+		case <-e.flush:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		_ = e.send(ctx)
+		cancel()
+	}
+}
+
+func (e *exporter) shutdown(ctx context.Context) error {
+	if !active.CompareAndSwap(e, nil) {
+		return nil
+	}
+	close(e.stop)
+	<-e.stopped
+	return e.send(ctx)
+}
+
+// send exports everything queued, a batch at a time. A failed batch is
+// dropped rather than retried, so a collector that is down can't make
+// spans pile up.
+func (e *exporter) send(ctx context.Context) error {
+	for {
+		e.mu.Lock()
+		n := min(len(e.queue), e.opts.BatchSize)
+		batch := e.queue[:n:n]
+		e.queue = e.queue[n:]
+		dropped := e.dropped
+		e.dropped = 0
+		e.mu.Unlock()
+		if dropped > 0 {
+			slog.Warn("trace queue full, spans dropped", "count", dropped)
+		}
+		if n == 0 {
+			return nil
+		}
+		if err := e.post(ctx, batch); err != nil {
+			slog.Warn("failed to export spans", "endpoint", e.opts.Endpoint, "spans", n, "error", err)
+			return err
+		}
+	}
+}
+
+func (e *exporter) post(ctx context.Context, batch []*Span) error {
+	payload, err := json.Marshal(e.encode(batch))
+	if err != nil {
+		return fmt.Errorf("failed to marshal spans: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.opts.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return errors.New("collector returned " + resp.Status)
+	}
+	return nil
+}
+
+// The OTLP/HTTP JSON encoding of an ExportTraceServiceRequest. IDs are hex
+// and 64-bit integers are decimal strings, as the spec requires.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              Kind           `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            *otlpStatus    `json:"status,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"` // 2 is error
+		Message string `json:"message,omitempty"`
+	}
+)
+
+func (e *exporter) encode(batch []*Span) otlpRequest {
+	service := e.opts.ServiceName
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		s.mu.Lock()
+		out := otlpSpan{
+			TraceID:           hex.EncodeToString(s.sc.trace[:]),
+			SpanID:            hex.EncodeToString(s.sc.span[:]),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+		if s.parent != (spanID{}) {
+			out.ParentSpanID = hex.EncodeToString(s.parent[:])
+		}
+		for _, a := range s.attrs {
+			kv := otlpKeyValue{Key: a.key}
+			if a.isNum {
+				n := strconv.FormatInt(a.num, 10)
+				kv.Value.IntValue = &n
+			} else {
+				str := a.str
+				kv.Value.StringValue = &str
+			}
+			out.Attributes = append(out.Attributes, kv)
+		}
+		if s.failed {
+			out.Status = &otlpStatus{Code: 2, Message: s.errMsg}
+		}
+		s.mu.Unlock()
+		spans = append(spans, out)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{
+			{Key: "service.name", Value: otlpValue{StringValue: &service}},
+		}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "kitchinv"}, Spans: spans}},
+	}}}
+}
@@ -0,0 +1,251 @@
+// Package tracing records spans of work — an HTTP request, a photo save, a
+// vision call — and sends them to an OpenTelemetry collector over OTLP/HTTP
+// as JSON.
+//
+// Tracing is off until Setup is given an endpoint. While it is off, Start
+// returns the context unchanged and a nil *Span, whose methods do nothing,
+// so instrumented code costs one atomic load.
+//
+// Spans are kept in context values rather than tied to cancellation, so work
+// detached with context.WithoutCancel still reports to the span that started
+// it.
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind says what side of a call a span is on, with OTLP's numbering.
+type Kind int
+
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+	KindClient   Kind = 3
+)
+
+type (
+	traceID [16]byte
+	spanID  [8]byte
+)
+
+// spanContext identifies a span, local or received from a caller.
+type spanContext struct {
+	trace traceID
+	span  spanID
+}
+
+// Span is one timed operation. A nil *Span is valid and ignores every call,
+// which is what Start returns when tracing is off.
+type Span struct {
+	exp    *exporter
+	sc     spanContext
+	parent spanID
+	kind   Kind
+	start  time.Time
+
+	mu     sync.Mutex
+	name   string
+	end    time.Time
+	attrs  []attribute
+	errMsg string
+	failed bool
+	ended  bool
+}
+
+type attribute struct {
+	key   string
+	str   string
+	num   int64
+	isNum bool
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// Enabled reports whether spans are being recorded.
+func Enabled() bool {
+	return active.Load() != nil
+}
+
+// Start begins a span named name as a child of the span in ctx, or of the
+// remote caller recorded by FromTraceparent, or as the root of a new trace.
+// The returned context carries the span. End must be called on it.
+func Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	exp := active.Load()
+	if exp == nil {
+		return ctx, nil
+	}
+	s := &Span{exp: exp, name: name, kind: kind, start: time.Now()}
+	switch parent := spanFrom(ctx); {
+	case parent != nil:
+		s.sc.trace, s.parent = parent.sc.trace, parent.sc.span
+	default:
+		if remote, ok := ctx.Value(remoteKey{}).(spanContext); ok {
+			s.sc.trace, s.parent = remote.trace, remote.span
+		} else {
+			s.sc.trace = newTraceID()
+		}
+	}
+	s.sc.span = newSpanID()
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func spanFrom(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// SetName renames the span, for when a better name is only known once the
+// work has started, such as the route an HTTP request matched.
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+// SetString records a string attribute.
+func (s *Span) SetString(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attribute{key: key, str: value})
+	s.mu.Unlock()
+}
+
+// SetInt records an integer attribute.
+func (s *Span) SetInt(key string, value int64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attribute{key: key, num: value, isNum: true})
+	s.mu.Unlock()
+}
+
+// SetError marks the span as failed with err's message. A nil err is
+// ignored, so it can be called with whatever the work returned.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.failed, s.errMsg = true, err.Error()
+	s.mu.Unlock()
+}
+
+// Fail marks the span as failed without an error value, e.g. for a 5xx
+// response.
+func (s *Span) Fail(msg string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.failed, s.errMsg = true, msg
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export. Later calls do nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended, s.end = true, time.Now()
+	s.mu.Unlock()
+	s.exp.enqueue(s)
+}
+
+// TraceID returns the span's trace ID in hex, or "" for a nil span, so log
+// lines can be matched to traces.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.sc.trace[:])
+}
+
+// FromTraceparent returns ctx carrying the caller's span from a W3C
+// traceparent header, so spans started from it join the caller's trace. A
+// missing or malformed header, or tracing being off, leaves ctx unchanged.
+func FromTraceparent(ctx context.Context, header string) context.Context {
+	if header == "" || !Enabled() {
+		return ctx
+	}
+	sc, ok := parseTraceparent(header)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// Inject sets the traceparent header for an outbound request made under the
+// span in ctx. It does nothing without one.
+func Inject(ctx context.Context, h http.Header) {
+	s := spanFrom(ctx)
+	if s == nil {
+		return
+	}
+	h.Set("traceparent", "00-"+hex.EncodeToString(s.sc.trace[:])+"-"+hex.EncodeToString(s.sc.span[:])+"-01")
+}
+
+// parseTraceparent reads version 00 of the header:
+// 00-<32 hex trace ID>-<16 hex parent ID>-<2 hex flags>. All-zero IDs are
+// invalid.
+func parseTraceparent(h string) (spanContext, bool) {
+	var sc spanContext
+	parts := strings.Split(strings.TrimSpace(h), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := strconv.ParseUint(parts[3], 16, 8); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.trace[:], []byte(parts[1])); err != nil || sc.trace == (traceID{}) {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.span[:], []byte(parts[2])); err != nil || sc.span == (spanID{}) {
+		return sc, false
+	}
+	return sc, true
+}
+
+func newTraceID() traceID {
+	var id traceID
+	for id == (traceID{}) {
+		for i := range 2 {
+			v := rand.Uint64()
+			for j := range 8 {
+				id[i*8+j] = byte(v >> (8 * j))
+			}
+		}
+	}
+	return id
+}
+
+func newSpanID() spanID {
+	var id spanID
+	for id == (spanID{}) {
+		v := rand.Uint64()
+		for j := range 8 {
+			id[j] = byte(v >> (8 * j))
+		}
+	}
+	return id
+}
@@ -0,0 +1,95 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/tracing"
+	"github.com/vbonduro/kitchinv/internal/tracing/tracingtest"
+)
+
+func TestDisabledIsNoOp(t *testing.T) {
+	require.False(t, tracing.Enabled())
+	ctx := context.Background()
+	got, span := tracing.Start(ctx, "work", tracing.KindInternal)
+	assert.Nil(t, span)
+	assert.Equal(t, ctx, got, "the context is returned unchanged")
+
+	errIgnored := errors.New("ignored")
+	allocs := testing.AllocsPerRun(100, func() {
+		_, s := tracing.Start(ctx, "work", tracing.KindInternal)
+		s.SetString("k", "v")
+		s.SetInt("n", 1)
+		s.SetError(errIgnored)
+		s.End()
+	})
+	assert.Zero(t, allocs)
+
+	h := http.Header{}
+	tracing.Inject(ctx, h)
+	assert.Empty(t, h)
+}
+
+func TestSpansNestAcrossWithoutCancel(t *testing.T) {
+	c := tracingtest.Start(t)
+
+	ctx, parent := tracing.Start(context.Background(), "upload", tracing.KindServer)
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	detached := context.WithoutCancel(ctx)
+	_, child := tracing.Start(detached, "vision.analyze", tracing.KindClient)
+	child.SetString("gen_ai.request.model", "llava")
+	child.SetInt("gen_ai.usage.input_tokens", 1200)
+	child.SetError(errors.New("model overloaded"))
+	child.End()
+	parent.End()
+	parent.End() // a second End is ignored
+
+	spans := c.Spans()
+	require.Len(t, spans, 2)
+	up, va := tracingtest.Named(spans, "upload"), tracingtest.Named(spans, "vision.analyze")
+	require.NotNil(t, up)
+	require.NotNil(t, va)
+	assert.Equal(t, up.TraceID, va.TraceID)
+	assert.Equal(t, up.SpanID, va.ParentID)
+	assert.Empty(t, up.ParentID)
+	assert.Equal(t, int(tracing.KindClient), va.Kind)
+	assert.Equal(t, "llava", va.Attrs["gen_ai.request.model"])
+	assert.Equal(t, "1200", va.Attrs["gen_ai.usage.input_tokens"])
+	assert.True(t, va.Failed)
+	assert.Equal(t, "model overloaded", va.Message)
+	assert.False(t, tracing.Enabled(), "collecting the spans turns tracing off")
+}
+
+func TestTraceparent(t *testing.T) {
+	c := tracingtest.Start(t)
+
+	ctx := tracing.FromTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, span := tracing.Start(ctx, "GET /areas", tracing.KindServer)
+	h := http.Header{}
+	tracing.Inject(ctx, h)
+	span.End()
+
+	for _, bad := range []string{"", "garbage", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"} {
+		assert.Equal(t, context.Background(), tracing.FromTraceparent(context.Background(), bad), bad)
+	}
+
+	spans := c.Spans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].ParentID)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+spans[0].SpanID+"-01", h.Get("traceparent"))
+}
+
+func TestParseHeaders(t *testing.T) {
+	h, err := tracing.ParseHeaders("Authorization=Bearer%20abc, x-team = kitchen,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer abc", "x-team": "kitchen"}, h)
+
+	_, err = tracing.ParseHeaders("novalue")
+	assert.ErrorContains(t, err, "novalue")
+}
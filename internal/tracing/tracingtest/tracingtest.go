@@ -0,0 +1,121 @@
+// Package tracingtest turns tracing on for a test and collects the spans it
+// exports, decoded from the OTLP JSON a collector would receive.
+package tracingtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/vbonduro/kitchinv/internal/tracing"
+)
+
+// Span is an exported span as the collector saw it.
+type Span struct {
+	TraceID  string
+	SpanID   string
+	ParentID string
+	Name     string
+	Kind     int
+	Attrs    map[string]string // integers in decimal
+	Failed   bool
+	Message  string
+}
+
+// Collector is a fake OTLP/HTTP endpoint tracing exports to while a test
+// runs.
+type Collector struct {
+	shutdown func(context.Context) error
+
+	mu    sync.Mutex
+	spans []Span
+}
+
+// Start turns tracing on, exporting to a new Collector, and turns it off
+// when the test ends. Tests using it must not run in parallel with other
+// tracing tests, as tracing is process-wide.
+func Start(t testing.TB) *Collector {
+	t.Helper()
+	c := &Collector{}
+	srv := httptest.NewServer(http.HandlerFunc(c.receive))
+	t.Cleanup(srv.Close)
+	c.shutdown = tracing.Setup(tracing.Options{Endpoint: srv.URL + "/v1/traces", ServiceName: "kitchinv-test"})
+	t.Cleanup(func() { _ = c.shutdown(context.Background()) })
+	return c
+}
+
+// Spans stops tracing, so every finished span is exported, and returns them
+// in the order they ended.
+func (c *Collector) Spans() []Span {
+	_ = c.shutdown(context.Background())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Span(nil), c.spans...)
+}
+
+// Named returns the first span called name, or nil.
+func Named(spans []Span, name string) *Span {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+type value struct {
+	StringValue *string `json:"stringValue"`
+	IntValue    *string `json:"intValue"`
+}
+
+func (c *Collector) receive(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceID      string `json:"traceId"`
+					SpanID       string `json:"spanId"`
+					ParentSpanID string `json:"parentSpanId"`
+					Name         string `json:"name"`
+					Kind         int    `json:"kind"`
+					Attributes   []struct {
+						Key   string `json:"key"`
+						Value value  `json:"value"`
+					} `json:"attributes"`
+					Status *struct {
+						Code    int    `json:"code"`
+						Message string `json:"message"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			for _, s := range ss.Spans {
+				span := Span{TraceID: s.TraceID, SpanID: s.SpanID, ParentID: s.ParentSpanID, Name: s.Name, Kind: s.Kind, Attrs: map[string]string{}}
+				for _, a := range s.Attributes {
+					switch {
+					case a.Value.StringValue != nil:
+						span.Attrs[a.Key] = *a.Value.StringValue
+					case a.Value.IntValue != nil:
+						span.Attrs[a.Key] = *a.Value.IntValue
+					}
+				}
+				if s.Status != nil && s.Status.Code == 2 {
+					span.Failed, span.Message = true, s.Status.Message
+				}
+				c.spans = append(c.spans, span)
+			}
+		}
+	}
+}
@@ -0,0 +1,295 @@
+// Package units reads the free-text quantities vision models and people
+// write, such as "2 x 500g", "1,5 l" or "half a dozen", into a number and a
+// normalized unit, so quantities can be sorted, added up and compared.
+//
+// Weights are normalized to grams and volumes to millilitres; anything else
+// ("3 cans", "a bunch") is a count. Parse reports false for text with no
+// amount it can read, such as "some" or "a few"; callers keep the raw text.
+package units
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Unit is the normalized unit of a parsed quantity.
+type Unit string
+
+const (
+	Count      Unit = "count"
+	Gram       Unit = "g"
+	Millilitre Unit = "ml"
+)
+
+// Quantity is an amount in a normalized unit.
+type Quantity struct {
+	Value float64
+	Unit  Unit
+}
+
+// String formats q for display: counts as a bare number, and weights and
+// volumes of a thousand or more in kilograms and litres, e.g. "1.5 kg".
+func (q Quantity) String() string {
+	switch q.Unit {
+	case Gram:
+		if q.Value >= 1000 {
+			return formatNumber(q.Value/1000) + " kg"
+		}
+		return formatNumber(q.Value) + " g"
+	case Millilitre:
+		if q.Value >= 1000 {
+			return formatNumber(q.Value/1000) + " l"
+		}
+		return formatNumber(q.Value) + " ml"
+	default:
+		return formatNumber(q.Value)
+	}
+}
+
+// formatNumber writes v with at most two decimals and no trailing zeros.
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// measures maps unit spellings to their normalized unit and how many of it
+// one of them is.
+var measures = map[string]struct {
+	unit  Unit
+	scale float64
+}{
+	"g": {Gram, 1}, "gr": {Gram, 1}, "grs": {Gram, 1}, "gram": {Gram, 1}, "grams": {Gram, 1}, "gramme": {Gram, 1}, "grammes": {Gram, 1},
+	"kg": {Gram, 1000}, "kgs": {Gram, 1000}, "kilo": {Gram, 1000}, "kilos": {Gram, 1000}, "kilogram": {Gram, 1000}, "kilograms": {Gram, 1000},
+	"mg": {Gram, 0.001},
+	"lb": {Gram, 453.59237}, "lbs": {Gram, 453.59237}, "pound": {Gram, 453.59237}, "pounds": {Gram, 453.59237},
+	"oz": {Gram, 28.349523125}, "ounce": {Gram, 28.349523125}, "ounces": {Gram, 28.349523125},
+	"ml": {Millilitre, 1}, "millilitre": {Millilitre, 1}, "millilitres": {Millilitre, 1}, "milliliter": {Millilitre, 1}, "milliliters": {Millilitre, 1},
+	"cl": {Millilitre, 10}, "dl": {Millilitre, 100},
+	"l": {Millilitre, 1000}, "lt": {Millilitre, 1000}, "ltr": {Millilitre, 1000}, "litre": {Millilitre, 1000}, "litres": {Millilitre, 1000}, "liter": {Millilitre, 1000}, "liters": {Millilitre, 1000},
+	"floz": {Millilitre, 29.5735295625},
+}
+
+// numberWords are the amounts written as words. "a" and "an" are handled
+// separately, since they also introduce "a dozen" or "a half".
+var numberWords = map[string]float64{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	"half": 0.5, "quarter": 0.25, "couple": 2, "pair": 2, "dozen": 12,
+}
+
+// vague are words after "a" that make it no amount at all: "a few" isn't 1.
+var vague = map[string]bool{"few": true, "little": true, "bit": true, "lot": true, "lots": true, "handful": true, "some": true}
+
+// approximations are dropped from the front of a quantity.
+var approximations = []string{"approximately", "approx.", "approx", "about", "around", "roughly", "circa", "ca.", "~", "≈"}
+
+// fractions are the vulgar fraction characters models sometimes write.
+var fractions = map[rune]float64{'½': 0.5, '¼': 0.25, '¾': 0.75, '⅓': 1.0 / 3, '⅔': 2.0 / 3}
+
+// Parse reads a quantity. A multiplied pack size such as "2 x 500g" or
+// "500 g x 2" is the total, 1000 g. The number is read from the start, so
+// "3 cans of beans" is 3 and "12 (approx.)" is 12.
+func Parse(s string) (Quantity, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, a := range approximations {
+		if rest, ok := strings.CutPrefix(s, a); ok {
+			s = strings.TrimSpace(rest)
+			break
+		}
+	}
+	// "x2" and "×2" are how some models write a count.
+	if rest, ok := cutTimes(s); ok {
+		s = rest
+	}
+
+	n, rest, ok := amount(s)
+	if !ok {
+		return Quantity{}, false
+	}
+	q, rest := withUnit(n, rest)
+	if after, ok := cutTimes(rest); ok {
+		if m, rest, ok := amount(after); ok {
+			q2, _ := withUnit(m, rest)
+			switch {
+			case q.Unit == Count:
+				return Quantity{Value: q.Value * q2.Value, Unit: q2.Unit}, true
+			case q2.Unit == Count:
+				return Quantity{Value: q.Value * q2.Value, Unit: q.Unit}, true
+			default:
+				// "1 kg x 500 g" multiplies two measures.
+				return Quantity{}, false
+			}
+		}
+	}
+	return q, true
+}
+
+// cutTimes removes a leading multiplication sign, "x", "×" or "*", that is
+// followed by a number or a space.
+func cutTimes(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	for _, sign := range []string{"x", "×", "*"} {
+		rest, ok := strings.CutPrefix(s, sign)
+		if !ok || rest == "" {
+			continue
+		}
+		if r := []rune(rest)[0]; unicode.IsDigit(r) || unicode.IsSpace(r) || fractions[r] != 0 {
+			return strings.TrimSpace(rest), true
+		}
+	}
+	return s, false
+}
+
+// amount reads the number a quantity starts with, in digits or words, and
+// returns the text after it.
+func amount(s string) (float64, string, bool) {
+	n, rest, ok := number(s)
+	if !ok {
+		n, rest, ok = wordAmount(s)
+	}
+	if !ok {
+		return 0, s, false
+	}
+	// "2 dozen", "half a dozen"
+	if w, after := firstWord(rest); w == "dozen" {
+		n, rest = n*12, after
+	}
+	if w, after := firstWord(rest); w == "of" {
+		rest = after
+	}
+	return n, rest, true
+}
+
+// number reads a number in digits: "2", "1.5", "1,5", "1/2", "1 1/2" or "½".
+func number(s string) (float64, string, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, s, false
+	}
+	r := []rune(s)
+	if f, ok := fractions[r[0]]; ok {
+		return f, string(r[1:]), true
+	}
+	end := 0
+	for end < len(s) && (s[end] >= '0' && s[end] <= '9' || (end > 0 && (s[end] == '.' || s[end] == ','))) {
+		end++
+	}
+	if end == 0 {
+		return 0, s, false
+	}
+	digits := strings.TrimRight(s[:end], ".,")
+	rest := s[len(digits):]
+	n, err := strconv.ParseFloat(strings.ReplaceAll(digits, ",", "."), 64)
+	if err != nil {
+		return 0, s, false
+	}
+	// A fraction, "1/2", or the fractional part of "1 1/2" or "1½".
+	if den, after, ok := cutDenominator(rest); ok && !strings.ContainsAny(digits, ".,") {
+		if den == 0 {
+			return 0, s, false
+		}
+		return n / den, after, true
+	}
+	trimmed := strings.TrimLeft(rest, " ")
+	if tr := []rune(trimmed); len(tr) > 0 {
+		if f, ok := fractions[tr[0]]; ok {
+			return n + f, string(tr[1:]), true
+		}
+	}
+	if trimmed != rest {
+		if num, after, ok := number(trimmed); ok && num < 1 && strings.Contains(trimmed[:len(trimmed)-len(after)], "/") {
+			return n + num, after, true
+		}
+	}
+	return n, rest, true
+}
+
+// cutDenominator reads "/4" from the front of s.
+func cutDenominator(s string) (float64, string, bool) {
+	rest, ok := strings.CutPrefix(s, "/")
+	if !ok {
+		return 0, s, false
+	}
+	end := 0
+	for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+		end++
+	}
+	if end == 0 {
+		return 0, s, false
+	}
+	d, err := strconv.ParseFloat(rest[:end], 64)
+	if err != nil {
+		return 0, s, false
+	}
+	return d, rest[end:], true
+}
+
+// wordAmount reads an amount written in words: "one", "a", "a couple of",
+// "half a", "a dozen".
+func wordAmount(s string) (float64, string, bool) {
+	w, rest := firstWord(s)
+	if w == "a" || w == "an" {
+		next, after := firstWord(rest)
+		if vague[next] {
+			return 0, s, false
+		}
+		if n, ok := numberWords[next]; ok && n != 1 {
+			return n, after, true
+		}
+		return 1, rest, true
+	}
+	n, ok := numberWords[w]
+	if !ok {
+		return 0, s, false
+	}
+	// "half a bottle" is half of one bottle.
+	if next, after := firstWord(rest); (next == "a" || next == "an") && n < 1 {
+		rest = after
+	}
+	return n, rest, true
+}
+
+// withUnit reads the unit after an amount. Text that isn't a weight or
+// volume, such as "cans", leaves the amount a count.
+func withUnit(n float64, s string) (Quantity, string) {
+	s = strings.TrimSpace(s)
+	// "fl oz" and "fl. oz" are two words.
+	for _, p := range []string{"fl. oz", "fl oz"} {
+		if rest, ok := strings.CutPrefix(s, p); ok && !startsWithLetter(rest) {
+			return Quantity{Value: n * measures["floz"].scale, Unit: Millilitre}, rest
+		}
+	}
+	end := 0
+	for end < len(s) && s[end] >= 'a' && s[end] <= 'z' {
+		end++
+	}
+	if m, ok := measures[s[:end]]; ok {
+		return Quantity{Value: n * m.scale, Unit: m.unit}, strings.TrimPrefix(s[end:], ".")
+	}
+	return Quantity{Value: n, Unit: Count}, s
+}
+
+// firstWord splits off the first space-separated word of s.
+func firstWord(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	w, rest, _ := strings.Cut(s, " ")
+	return w, rest
+}
+
+func startsWithLetter(s string) bool {
+	for _, r := range s {
+		return unicode.IsLetter(r)
+	}
+	return false
+}
+
+// Nullable is Parse in the form items store it: a nil value and an empty
+// unit when s has no amount.
+func Nullable(s string) (*float64, Unit) {
+	q, ok := Parse(s)
+	if !ok {
+		return nil, ""
+	}
+	return &q.Value, q.Unit
+}
@@ -0,0 +1,154 @@
+package units
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in    string
+		value float64
+		unit  Unit
+	}{
+		// Counts
+		{"1", 1, Count},
+		{"12", 12, Count},
+		{" 3 ", 3, Count},
+		{"3 cans", 3, Count},
+		{"3 cans of beans", 3, Count},
+		{"2 bottles", 2, Count},
+		{"6-pack", 6, Count},
+		{"12 (approx.)", 12, Count},
+		{"x2", 2, Count},
+		{"×3", 3, Count},
+		{"0", 0, Count},
+		{"2.", 2, Count},
+
+		// Words
+		{"one", 1, Count},
+		{"Two cartons", 2, Count},
+		{"a bunch", 1, Count},
+		{"an onion", 1, Count},
+		{"a", 1, Count},
+		{"dozen", 12, Count},
+		{"a dozen", 12, Count},
+		{"A dozen eggs", 12, Count},
+		{"2 dozen", 24, Count},
+		{"half a dozen", 6, Count},
+		{"half dozen", 6, Count},
+		{"half a bottle", 0.5, Count},
+		{"half", 0.5, Count},
+		{"a half", 0.5, Count},
+		{"quarter of a wheel", 0.25, Count},
+		{"a couple of apples", 2, Count},
+		{"a pair", 2, Count},
+
+		// Fractions and decimals
+		{"1/2 bag", 0.5, Count},
+		{"3/4", 0.75, Count},
+		{"1 1/2 loaves", 1.5, Count},
+		{"½", 0.5, Count},
+		{"1½", 1.5, Count},
+		{"2 ½ kg", 2500, Gram},
+		{"1.5", 1.5, Count},
+		{"1,5 l", 1500, Millilitre},
+
+		// Weights
+		{"250 g", 250, Gram},
+		{"250g", 250, Gram},
+		{"250 G", 250, Gram},
+		{"250 grams", 250, Gram},
+		{"250gr", 250, Gram},
+		{"1kg", 1000, Gram},
+		{"1.2 kg", 1200, Gram},
+		{"2 kilos", 2000, Gram},
+		{"500 mg", 0.5, Gram},
+		{"1 lb", 453.59237, Gram},
+		{"2 lbs", 907.18474, Gram},
+		{"16 oz", 453.59237, Gram},
+		{"approx. 400g", 400, Gram},
+		{"about 1 kg", 1000, Gram},
+		{"~300 g", 300, Gram},
+
+		// Volumes
+		{"500 ml", 500, Millilitre},
+		{"500ml", 500, Millilitre},
+		{"500 mL", 500, Millilitre},
+		{"1 l", 1000, Millilitre},
+		{"1L", 1000, Millilitre},
+		{"2 litres", 2000, Millilitre},
+		{"2 liters", 2000, Millilitre},
+		{"1.75 ltr", 1750, Millilitre},
+		{"33 cl", 330, Millilitre},
+		{"5 dl", 500, Millilitre},
+		{"12 fl oz", 354.882354750, Millilitre},
+		{"1 l. bottle", 1000, Millilitre},
+
+		// Pack sizes
+		{"2 x 500g", 1000, Gram},
+		{"2x500g", 1000, Gram},
+		{"2 × 500 g", 1000, Gram},
+		{"4 * 330ml", 1320, Millilitre},
+		{"500g x 2", 1000, Gram},
+		{"6 x 1 l", 6000, Millilitre},
+		{"3 x 2", 6, Count},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := Parse(tt.in)
+			if !assert.True(t, ok) {
+				return
+			}
+			assert.Equal(t, tt.unit, got.Unit)
+			assert.InDelta(t, tt.value, got.Value, 1e-6)
+		})
+	}
+}
+
+func TestParseUnreadable(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"   ",
+		"some",
+		"a few",
+		"a little",
+		"a lot",
+		"lots",
+		"several",
+		"unknown",
+		"N/A",
+		"?",
+		"kg",
+		"large",
+		"x large",
+		"1/0",
+		"1 kg x 500 g",
+		".5",
+	} {
+		t.Run(in, func(t *testing.T) {
+			q, ok := Parse(in)
+			assert.False(t, ok, "parsed as %+v", q)
+		})
+	}
+}
+
+func TestQuantityString(t *testing.T) {
+	tests := []struct {
+		q    Quantity
+		want string
+	}{
+		{Quantity{3, Count}, "3"},
+		{Quantity{0.5, Count}, "0.5"},
+		{Quantity{250, Gram}, "250 g"},
+		{Quantity{1000, Gram}, "1 kg"},
+		{Quantity{1500, Gram}, "1.5 kg"},
+		{Quantity{453.59237, Gram}, "453.59 g"},
+		{Quantity{330, Millilitre}, "330 ml"},
+		{Quantity{1750, Millilitre}, "1.75 l"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.q.String())
+	}
+}
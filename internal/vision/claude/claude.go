@@ -9,7 +9,10 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 
+	"github.com/vbonduro/kitchinv/internal/tracing"
 	"github.com/vbonduro/kitchinv/internal/vision"
 )
 
@@ -20,10 +23,15 @@ const anthropicVersion = "2023-06-01"
 
 // request types mirror the Anthropic Messages API structure.
 type request struct {
-	Model     string    `json:"model"`
-	MaxTokens int       `json:"max_tokens"`
-	System    string    `json:"system,omitempty"`
-	Messages  []message `json:"messages"`
+	Model     string `json:"model"`
+	MaxTokens int    `json:"max_tokens"`
+	// System is a plain string, or a []block when parts of it are marked
+	// for prompt caching.
+	System     any         `json:"system,omitempty"`
+	Messages   []message   `json:"messages"`
+	Tools      []tool      `json:"tools,omitempty"`
+	ToolChoice *toolChoice `json:"tool_choice,omitempty"`
+	Stream     bool        `json:"stream,omitempty"`
 }
 
 type message struct {
@@ -32,11 +40,21 @@ type message struct {
 }
 
 type block struct {
-	Type   string  `json:"type"`
-	Text   string  `json:"text,omitempty"`
-	Source *source `json:"source,omitempty"`
+	Type         string        `json:"type"`
+	Text         string        `json:"text,omitempty"`
+	Source       *source       `json:"source,omitempty"`
+	CacheControl *cacheControl `json:"cache_control,omitempty"`
 }
 
+// cacheControl marks the end of a prompt prefix Anthropic may cache.
+type cacheControl struct {
+	Type string `json:"type"`
+}
+
+// ephemeral is the only cache type the API offers, kept for five minutes
+// after its last use.
+var ephemeral = &cacheControl{Type: "ephemeral"}
+
 type source struct {
 	Type      string `json:"type"`
 	MediaType string `json:"media_type"`
@@ -44,44 +62,140 @@ type source struct {
 }
 
 type response struct {
-	Content []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-	} `json:"content"`
+	Content    []contentBlock `json:"content"`
+	StopReason string         `json:"stop_reason"`
+	Usage      usage          `json:"usage"`
 }
 
+// contentBlock is one block of Claude's reply: text, or a tool call with
+// the tool's name and input.
+type contentBlock struct {
+	Type  string          `json:"type"`
+	Text  string          `json:"text"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+type usage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// stopMaxTokens is the stop_reason Claude reports when the response was cut
+// off at max_tokens.
+const stopMaxTokens = "max_tokens"
+
+// DefaultMaxTokens covers the largest fixtures (51 items × ~30 tokens each ≈
+// 1500 tokens), with headroom for verbose Claude output and JSON structure
+// overhead.
+const DefaultMaxTokens = 4096
+
 type ClaudeAnalyzer struct {
-	apiKey  string
-	model   string
-	client  *http.Client
-	baseURL string
+	apiKey      string
+	model       string
+	prompt      string
+	maxTokens   int
+	promptCache bool
+	extraction  string
+	stream      bool
+	client      *http.Client
+	baseURL     string
 }
 
 func NewClaudeAnalyzer(apiKey, model string) *ClaudeAnalyzer {
 	return &ClaudeAnalyzer{
-		apiKey:  apiKey,
-		model:   model,
-		client:  &http.Client{},
-		baseURL: defaultAPIURL,
-	}
-}
-
-// buildMessages constructs the Anthropic API message payload for a vision request.
-func buildMessages(imageData []byte, mimeType string) []message {
-	return []message{{
-		Role: "user",
-		Content: []block{
-			{
-				Type: "image",
-				Source: &source{
-					Type:      "base64",
-					MediaType: normaliseMIME(mimeType),
-					Data:      base64.StdEncoding.EncodeToString(imageData),
-				},
-			},
-			{Type: "text", Text: vision.ClaudeUserPrompt},
+		apiKey:     apiKey,
+		model:      model,
+		prompt:     vision.ClaudeUserPrompt,
+		maxTokens:  DefaultMaxTokens,
+		extraction: ExtractionText,
+		client:     &http.Client{},
+		baseURL:    defaultAPIURL,
+	}
+}
+
+// WithPrompt replaces ClaudeUserPrompt as the default user-turn prompt. A
+// blank prompt keeps the built-in one.
+func (a *ClaudeAnalyzer) WithPrompt(prompt string) *ClaudeAnalyzer {
+	a.prompt = vision.PromptOrDefault(prompt, vision.ClaudeUserPrompt)
+	return a
+}
+
+// WithMaxTokens caps the length of Claude's response. Values below 1 keep
+// DefaultMaxTokens.
+func (a *ClaudeAnalyzer) WithMaxTokens(n int) *ClaudeAnalyzer {
+	if n > 0 {
+		a.maxTokens = n
+	}
+	return a
+}
+
+// WithPromptCaching moves the instructions into the system prompt and marks
+// it for Anthropic's prompt caching, so repeated uploads within a few minutes
+// reuse it at a tenth of the input price. Models that don't support caching
+// reject the request, so it is off by default. Prompts shorter than the
+// model's minimum cacheable length (1024 tokens for most) are sent uncached
+// without error.
+func (a *ClaudeAnalyzer) WithPromptCaching(enabled bool) *ClaudeAnalyzer {
+	a.promptCache = enabled
+	return a
+}
+
+// WithExtraction sets how Claude returns the items it finds: ExtractionText
+// for a JSON reply, or ExtractionTools for one add_item tool call per item.
+// Any other value keeps ExtractionText.
+func (a *ClaudeAnalyzer) WithExtraction(mode string) *ClaudeAnalyzer {
+	if mode == ExtractionTools {
+		a.extraction = ExtractionTools
+	} else {
+		a.extraction = ExtractionText
+	}
+	return a
+}
+
+// WithStream requests a streamed (server-sent events) reply. In tools mode
+// each item is decoded as soon as its tool call is complete, rather than
+// once the whole reply has arrived.
+func (a *ClaudeAnalyzer) WithStream(stream bool) *ClaudeAnalyzer {
+	a.stream = stream
+	return a
+}
+
+// buildRequest constructs the Anthropic API payload for a vision request.
+// The response schema always goes in the system prompt. The instructions,
+// prompt, follow the image in the user turn, or with prompt caching join
+// the system prompt as a cached block, leaving the image alone in the user
+// turn. In tools mode the system prompt asks for tool calls instead of
+// JSON, and Claude must call at least one of extractionTools.
+func (a *ClaudeAnalyzer) buildRequest(imageData []byte, mimeType, prompt string) request {
+	image := block{
+		Type: "image",
+		Source: &source{
+			Type:      "base64",
+			MediaType: normaliseMIME(mimeType),
+			Data:      base64.StdEncoding.EncodeToString(imageData),
 		},
-	}}
+	}
+	system := vision.ClaudeSystemPrompt
+	body := request{Model: a.model, MaxTokens: a.maxTokens, Stream: a.stream}
+	if a.extraction == ExtractionTools {
+		system = vision.ClaudeToolSystemPrompt
+		body.Tools = extractionTools
+		body.ToolChoice = choiceAny
+	}
+	if a.promptCache {
+		body.System = []block{
+			{Type: "text", Text: system},
+			{Type: "text", Text: prompt, CacheControl: ephemeral},
+		}
+		body.Messages = []message{{Role: "user", Content: []block{image}}}
+		return body
+	}
+	body.System = system
+	body.Messages = []message{{Role: "user", Content: []block{image, {Type: "text", Text: prompt}}}}
+	return body
 }
 
 // newHTTPRequest creates an authenticated POST request to the Claude API.
@@ -97,24 +211,94 @@ func (a *ClaudeAnalyzer) newHTTPRequest(ctx context.Context, payload []byte) (*h
 }
 
 func (a *ClaudeAnalyzer) Analyze(ctx context.Context, r io.Reader, mimeType string) (*vision.AnalysisResult, error) {
+	return a.AnalyzeWithPrompt(ctx, r, mimeType, "")
+}
+
+// AnalyzeWithPrompt is Analyze with prompt replacing the default user-turn
+// prompt. The system prompt, which carries the response schema, is unchanged.
+// An empty prompt uses the default.
+func (a *ClaudeAnalyzer) AnalyzeWithPrompt(ctx context.Context, r io.Reader, mimeType, prompt string) (*vision.AnalysisResult, error) {
 	imageData, err := io.ReadAll(r)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read image: %w", err)
 	}
 
-	body := request{
-		Model: a.model,
-		// 4096 tokens covers the largest fixtures (51 items × ~30 tokens each ≈ 1500
-		// tokens), with headroom for verbose Claude output and JSON structure overhead.
-		MaxTokens: 4096,
-		System:    vision.ClaudeSystemPrompt,
-		Messages:  buildMessages(imageData, mimeType),
+	payload, err := json.Marshal(a.buildRequest(imageData, mimeType, vision.PromptOrDefault(prompt, a.prompt)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// In tools mode each item is taken from its tool call as the call
+	// completes, which with streaming is before the reply has ended.
+	var calls *toolCalls
+	var onBlock func(contentBlock)
+	if a.extraction == ExtractionTools {
+		calls = &toolCalls{}
+		onBlock = calls.add
+	}
+	respBody, err := a.send(ctx, payload, onBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	truncated := respBody.StopReason == stopMaxTokens
+	if truncated {
+		slog.Warn("claude response hit max_tokens, item list may be incomplete", "max_tokens", a.maxTokens)
 	}
 
-	payload, err := json.Marshal(body)
+	var result *vision.AnalysisResult
+	if calls != nil {
+		result, err = calls.result()
+	} else {
+		result, err = vision.ParseJSONResponse(responseText(respBody))
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		if truncated {
+			return nil, fmt.Errorf("failed to parse vision response cut off at %d tokens (raise CLAUDE_MAX_TOKENS): %w", a.maxTokens, err)
+		}
+		return nil, fmt.Errorf("failed to parse vision response: %w", err)
+	}
+	result.Truncated = truncated
+	result.StopReason = respBody.StopReason
+	result.Usage = vision.Usage{
+		InputTokens:      respBody.Usage.InputTokens,
+		OutputTokens:     respBody.Usage.OutputTokens,
+		CacheWriteTokens: respBody.Usage.CacheCreationInputTokens,
+		CacheReadTokens:  respBody.Usage.CacheReadInputTokens,
 	}
+	if a.promptCache {
+		slog.Debug("claude prompt cache", "hit", respBody.Usage.CacheReadInputTokens > 0,
+			"cache_read_tokens", respBody.Usage.CacheReadInputTokens, "cache_write_tokens", respBody.Usage.CacheCreationInputTokens)
+	}
+
+	if result.Status == vision.StatusUnclear {
+		return nil, fmt.Errorf("image is unclear: please retake the photo")
+	}
+
+	return result, nil
+}
+
+// responseText returns the first text block of resp.
+func responseText(resp *response) string {
+	for _, blk := range resp.Content {
+		if blk.Type == "text" {
+			return blk.Text
+		}
+	}
+	return ""
+}
+
+// send posts a messages request and decodes the reply, streamed or not,
+// passing each content block to onBlock, if not nil, once it is complete.
+// The call is recorded as a client span with the model and token counts.
+func (a *ClaudeAnalyzer) send(ctx context.Context, payload []byte, onBlock func(contentBlock)) (_ *response, err error) {
+	ctx, span := tracing.Start(ctx, "claude.messages", tracing.KindClient)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	span.SetString("gen_ai.system", "anthropic")
+	span.SetString("gen_ai.request.model", a.model)
 
 	req, err := a.newHTTPRequest(ctx, payload)
 	if err != nil {
@@ -130,35 +314,69 @@ func (a *ClaudeAnalyzer) Analyze(ctx context.Context, r io.Reader, mimeType stri
 			slog.Error("failed to close claude response body", "error", err)
 		}
 	}()
+	span.SetInt("http.response.status_code", int64(resp.StatusCode))
 
 	if resp.StatusCode != http.StatusOK {
 		errBody, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("claude returned status %d: %s", resp.StatusCode, errBody)
 	}
 
-	var respBody response
-	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	var responseText string
-	for _, blk := range respBody.Content {
-		if blk.Type == "text" {
-			responseText = blk.Text
-			break
+	var respBody *response
+	if a.stream {
+		if respBody, err = readStream(resp.Body, onBlock); err != nil {
+			return nil, err
+		}
+	} else {
+		respBody = &response{}
+		if err := json.NewDecoder(resp.Body).Decode(respBody); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		if onBlock != nil {
+			for _, blk := range respBody.Content {
+				onBlock(blk)
+			}
 		}
 	}
+	span.SetInt("gen_ai.usage.input_tokens", int64(respBody.Usage.InputTokens))
+	span.SetInt("gen_ai.usage.output_tokens", int64(respBody.Usage.OutputTokens))
+	if a.promptCache {
+		span.SetInt("gen_ai.usage.cache_creation_input_tokens", int64(respBody.Usage.CacheCreationInputTokens))
+		span.SetInt("gen_ai.usage.cache_read_input_tokens", int64(respBody.Usage.CacheReadInputTokens))
+	}
+	return respBody, nil
+}
 
-	result, err := vision.ParseJSONResponse(responseText)
+// Check validates the API key and model with a models lookup, which is free
+// and doesn't run the model.
+func (a *ClaudeAnalyzer) Check(ctx context.Context) error {
+	modelsURL := strings.TrimSuffix(a.baseURL, "/messages") + "/models/" + url.PathEscape(a.model)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, modelsURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse vision response: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Header.Set("x-api-key", a.apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
 
-	if result.Status == vision.StatusUnclear {
-		return nil, fmt.Errorf("image is unclear: please retake the photo")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach claude: %w", err)
 	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			slog.Error("failed to close claude response body", "error", err)
+		}
+	}()
 
-	return result, nil
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("claude rejected the API key (status %d): check CLAUDE_API_KEY", resp.StatusCode)
+	case http.StatusNotFound:
+		return fmt.Errorf("claude model %q not found: check CLAUDE_MODEL", a.model)
+	default:
+		return fmt.Errorf("claude returned status %d checking model %q", resp.StatusCode, a.model)
+	}
 }
 
 // normaliseMIME maps browser MIME types to the values the Anthropic API accepts.
@@ -11,6 +11,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/tracing"
+	"github.com/vbonduro/kitchinv/internal/tracing/tracingtest"
 	"github.com/vbonduro/kitchinv/internal/vision"
 )
 
@@ -113,3 +115,207 @@ type errReader struct{}
 func (e *errReader) Read(_ []byte) (int, error) {
 	return 0, io.ErrUnexpectedEOF
 }
+
+func TestClaudeAnalyzeWithPrompt(t *testing.T) {
+	var captured request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&captured)
+		resp := map[string]interface{}{
+			"content": []map[string]interface{}{
+				{"type": "text", "text": `{"status":"ok","items":[]}`},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	analyzer := NewClaudeAnalyzer("sk-test", "claude-opus-4-6")
+	analyzer.baseURL = server.URL
+
+	userText := func() string {
+		require.Len(t, captured.Messages, 1)
+		require.Len(t, captured.Messages[0].Content, 2)
+		return captured.Messages[0].Content[1].Text
+	}
+
+	_, err := analyzer.AnalyzeWithPrompt(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg", "Read every jar label.")
+	require.NoError(t, err)
+	assert.Equal(t, "Read every jar label.", userText())
+	assert.Equal(t, vision.ClaudeSystemPrompt, captured.System, "schema prompt must not change")
+
+	_, err = analyzer.AnalyzeWithPrompt(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg", "  \n")
+	require.NoError(t, err)
+	assert.Equal(t, vision.ClaudeUserPrompt, userText())
+}
+
+func TestClaudeAnalyzeMaxTokens(t *testing.T) {
+	var captured request
+	text := `{"status":"ok","items":[{"name":"Milk","quantity":1}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&captured)
+		resp := map[string]interface{}{
+			"content":     []map[string]interface{}{{"type": "text", "text": text}},
+			"stop_reason": "max_tokens",
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	analyzer := NewClaudeAnalyzer("sk-test", "claude-opus-4-6").WithMaxTokens(512).WithPrompt("Pantry only.")
+	analyzer.baseURL = server.URL
+
+	result, err := analyzer.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+	require.NoError(t, err)
+	assert.Equal(t, 512, captured.MaxTokens)
+	assert.Equal(t, "Pantry only.", captured.Messages[0].Content[1].Text)
+	assert.True(t, result.Truncated)
+	assert.Equal(t, "max_tokens", result.StopReason)
+
+	// A response cut off mid-JSON fails with a hint to raise the limit.
+	text = `{"status":"ok","items":[{"name":"Mi`
+	_, err = analyzer.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "CLAUDE_MAX_TOKENS")
+}
+
+func TestClaudeCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{"ok", http.StatusOK, ""},
+		{"bad key", http.StatusUnauthorized, "CLAUDE_API_KEY"},
+		{"unknown model", http.StatusNotFound, "CLAUDE_MODEL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodGet, r.Method)
+				assert.Equal(t, "/v1/models/claude-opus-4-6", r.URL.Path)
+				assert.Equal(t, "sk-test", r.Header.Get("x-api-key"))
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			analyzer := NewClaudeAnalyzer("sk-test", "claude-opus-4-6")
+			analyzer.baseURL = server.URL + "/v1/messages"
+
+			err := analyzer.Check(context.Background())
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestClaudeAnalyzeUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"{\"status\":\"ok\",\"items\":[{\"name\":\"Milk\",\"quantity\":1}]}"}],` +
+			`"stop_reason":"end_turn","usage":{"input_tokens":1534,"output_tokens":87}}`))
+	}))
+	defer server.Close()
+
+	analyzer := NewClaudeAnalyzer("sk-test", "claude-opus-4-6")
+	analyzer.baseURL = server.URL
+
+	result, err := analyzer.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+	require.NoError(t, err)
+	assert.Equal(t, vision.Usage{InputTokens: 1534, OutputTokens: 87}, result.Usage)
+}
+
+func TestClaudeAnalyzeSpan(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"{\"status\":\"ok\",\"items\":[]}"}],` +
+			`"stop_reason":"end_turn","usage":{"input_tokens":1534,"output_tokens":87}}`))
+	}))
+	defer server.Close()
+	c := tracingtest.Start(t)
+
+	analyzer := NewClaudeAnalyzer("sk-test", "claude-opus-4-6")
+	analyzer.baseURL = server.URL
+	_, err := analyzer.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+	require.NoError(t, err)
+
+	span := tracingtest.Named(c.Spans(), "claude.messages")
+	require.NotNil(t, span)
+	assert.Equal(t, int(tracing.KindClient), span.Kind)
+	assert.Equal(t, "claude-opus-4-6", span.Attrs["gen_ai.request.model"])
+	assert.Equal(t, "1534", span.Attrs["gen_ai.usage.input_tokens"])
+	assert.Equal(t, "87", span.Attrs["gen_ai.usage.output_tokens"])
+	assert.Equal(t, "200", span.Attrs["http.response.status_code"])
+}
+
+func TestClaudeAnalyzePromptCaching(t *testing.T) {
+	var raw []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"{\"status\":\"ok\",\"items\":[]}"}],` +
+			`"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":40,` +
+			`"cache_creation_input_tokens":0,"cache_read_input_tokens":1650}}`))
+	}))
+	defer server.Close()
+
+	// The payload as the API sees it, with the image data left out.
+	type wireBlock struct {
+		Type         string            `json:"type"`
+		Text         string            `json:"text"`
+		Source       map[string]string `json:"source"`
+		CacheControl map[string]string `json:"cache_control"`
+	}
+	var wire struct {
+		System   json.RawMessage `json:"system"`
+		Messages []struct {
+			Role    string      `json:"role"`
+			Content []wireBlock `json:"content"`
+		} `json:"messages"`
+	}
+	analyze := func(a *ClaudeAnalyzer) *vision.AnalysisResult {
+		t.Helper()
+		a.baseURL = server.URL
+		result, err := a.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(raw, &wire))
+		require.Len(t, wire.Messages, 1)
+		assert.Equal(t, "user", wire.Messages[0].Role)
+		return result
+	}
+
+	t.Run("off", func(t *testing.T) {
+		analyze(NewClaudeAnalyzer("sk-test", "claude-opus-4-6").WithPrompt("Count the eggs."))
+		var system string
+		require.NoError(t, json.Unmarshal(wire.System, &system), "system stays a plain string")
+		assert.Equal(t, vision.ClaudeSystemPrompt, system)
+		content := wire.Messages[0].Content
+		require.Len(t, content, 2)
+		assert.Equal(t, "image", content[0].Type)
+		assert.Equal(t, "Count the eggs.", content[1].Text)
+		assert.NotContains(t, string(raw), "cache_control")
+	})
+
+	t.Run("on", func(t *testing.T) {
+		result := analyze(NewClaudeAnalyzer("sk-test", "claude-opus-4-6").WithPrompt("Count the eggs.").WithPromptCaching(true))
+		var system []wireBlock
+		require.NoError(t, json.Unmarshal(wire.System, &system))
+		require.Len(t, system, 2)
+		assert.Equal(t, wireBlock{Type: "text", Text: vision.ClaudeSystemPrompt}, system[0])
+		assert.Equal(t, wireBlock{Type: "text", Text: "Count the eggs.", CacheControl: map[string]string{"type": "ephemeral"}}, system[1])
+
+		content := wire.Messages[0].Content
+		require.Len(t, content, 1, "the image is alone in the user turn")
+		assert.Equal(t, "image", content[0].Type)
+		assert.Equal(t, "base64", content[0].Source["type"])
+		assert.Equal(t, "image/jpeg", content[0].Source["media_type"])
+		assert.Nil(t, content[0].CacheControl)
+
+		assert.Equal(t, vision.Usage{InputTokens: 12, OutputTokens: 40, CacheReadTokens: 1650}, result.Usage)
+	})
+}
@@ -0,0 +1,144 @@
+package claude
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vbonduro/kitchinv/internal/vision/sse"
+)
+
+// streamEvent is the part of a Messages API stream event that GenerateText
+// and readStream use: message_start carries the input usage, content block
+// events the reply in pieces, message_delta the stop reason and output
+// usage, and error events a message.
+type streamEvent struct {
+	Type         string       `json:"type"`
+	Index        int          `json:"index"`
+	ContentBlock contentBlock `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Message struct {
+		Usage usage `json:"usage"`
+	} `json:"message"`
+	Usage usage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// nextEvent reads and decodes the next event of a stream, returning io.EOF
+// at its end. The event: field, when there is one, names the event's type.
+// An error event, such as overloaded_error part way through a reply, is
+// returned as an error so the reply isn't mistaken for a complete one.
+func nextEvent(dec *sse.Decoder) (streamEvent, error) {
+	e, err := dec.Next()
+	if err == io.EOF {
+		return streamEvent{}, err
+	}
+	if err != nil {
+		return streamEvent{}, fmt.Errorf("failed to read response stream: %w", err)
+	}
+	var ev streamEvent
+	if err := json.Unmarshal([]byte(e.Data), &ev); err != nil {
+		if e.Type == "error" {
+			return streamEvent{}, fmt.Errorf("claude returned error: %s", e.Data)
+		}
+		return streamEvent{}, fmt.Errorf("failed to decode stream event: %w", err)
+	}
+	if e.Type != "message" {
+		ev.Type = e.Type
+	}
+	if ev.Type == "error" {
+		msg := ev.Error.Message
+		if ev.Error.Type != "" {
+			msg = ev.Error.Type + ": " + msg
+		}
+		return streamEvent{}, fmt.Errorf("claude returned error: %s", msg)
+	}
+	return ev, nil
+}
+
+// openBlock is a content block still arriving in a stream.
+type openBlock struct {
+	block contentBlock
+	text  strings.Builder
+	input strings.Builder // tool input JSON, sent in pieces
+}
+
+// readStream rebuilds a messages response from its server-sent events,
+// passing each content block to onBlock, if not nil, as soon as the block
+// is complete. A tool call's input arrives as input_json_delta fragments
+// that are only valid JSON once joined, so it is decoded at
+// content_block_stop. Blocks left open at message_stop are dropped. A stream
+// that ends before message_stop, such as a connection cut mid-reply, is an
+// error wrapping io.ErrUnexpectedEOF, though blocks already passed to
+// onBlock stay passed.
+func readStream(body io.Reader, onBlock func(contentBlock)) (*response, error) {
+	var resp response
+	open := map[int]*openBlock{}
+
+	dec := sse.NewDecoder(body)
+	for {
+		ev, err := nextEvent(dec)
+		if err == io.EOF {
+			return nil, fmt.Errorf("claude stream ended before message_stop: %w", io.ErrUnexpectedEOF)
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch ev.Type {
+		case "message_start":
+			resp.Usage = ev.Message.Usage
+		case "content_block_start":
+			blk := ev.ContentBlock
+			blk.Input = nil
+			open[ev.Index] = &openBlock{block: blk}
+		case "content_block_delta":
+			ob := open[ev.Index]
+			if ob == nil {
+				continue
+			}
+			switch ev.Delta.Type {
+			case "text_delta":
+				ob.text.WriteString(ev.Delta.Text)
+			case "input_json_delta":
+				ob.input.WriteString(ev.Delta.PartialJSON)
+			}
+		case "content_block_stop":
+			ob := open[ev.Index]
+			if ob == nil {
+				continue
+			}
+			delete(open, ev.Index)
+			blk := ob.block
+			blk.Text += ob.text.String()
+			if blk.Type == "tool_use" {
+				// A tool called without arguments sends no fragments.
+				blk.Input = json.RawMessage("{}")
+				if ob.input.Len() > 0 {
+					blk.Input = json.RawMessage(ob.input.String())
+				}
+			}
+			resp.Content = append(resp.Content, blk)
+			if onBlock != nil {
+				onBlock(blk)
+			}
+		case "message_delta":
+			if ev.Delta.StopReason != "" {
+				resp.StopReason = ev.Delta.StopReason
+			}
+			if ev.Usage.OutputTokens > 0 {
+				resp.Usage.OutputTokens = ev.Usage.OutputTokens
+			}
+		case "message_stop":
+			return &resp, nil
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package claude
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+// writeEvents writes each event as the API does, an event: line naming its
+// type followed by a data: line.
+func writeEvents(w io.Writer, events ...string) {
+	for _, ev := range events {
+		var typ struct{ Type string }
+		_ = json.Unmarshal([]byte(ev), &typ)
+		_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ.Type, ev)
+	}
+}
+
+func TestClaudeAnalyzeToolsStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeEvents(w,
+			`{"type":"message_start","message":{"usage":{"input_tokens":1600,"output_tokens":1,"cache_read_input_tokens":900}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Recording items."}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"add_item","input":{}}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":""}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"name\": \"Hot | Sw"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"eet sauce\", \"quant"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"ity\": 2}"}}`,
+			`{"type":"content_block_stop","index":1}`,
+			`{"type":"ping"}`,
+			`{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_2","name":"add_item","input":{}}}`,
+			`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"name\": \"Eggs\"}"}}`,
+			`{"type":"content_block_stop","index":2}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":75}}`,
+			`{"type":"message_stop"}`,
+		)
+	}))
+	defer server.Close()
+
+	a := NewClaudeAnalyzer("sk-test", "claude-opus-4-6").WithExtraction(ExtractionTools).WithStream(true)
+	a.baseURL = server.URL
+	result, err := a.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+	require.NoError(t, err)
+
+	assert.Equal(t, vision.StatusOK, result.Status)
+	assert.Equal(t, []vision.DetectedItem{{Name: "Hot | Sweet sauce", Quantity: "2"}, {Name: "Eggs"}}, result.Items)
+	assert.Equal(t, vision.Usage{InputTokens: 1600, OutputTokens: 75, CacheReadTokens: 900}, result.Usage)
+}
+
+func TestClaudeAnalyzeTextStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeEvents(w,
+			`{"type":"message_start","message":{"usage":{"input_tokens":1500,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"{\"status\":\"ok\",\"items\":[{\"na"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"me\":\"Milk\",\"quantity\":1}]}"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"max_tokens"},"usage":{"output_tokens":30}}`,
+			`{"type":"message_stop"}`,
+		)
+	}))
+	defer server.Close()
+
+	a := NewClaudeAnalyzer("sk-test", "claude-opus-4-6").WithStream(true)
+	a.baseURL = server.URL
+	result, err := a.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+	require.NoError(t, err)
+
+	assert.Equal(t, []vision.DetectedItem{{Name: "Milk", Quantity: "1"}}, result.Items)
+	assert.True(t, result.Truncated)
+	assert.Equal(t, vision.Usage{InputTokens: 1500, OutputTokens: 30}, result.Usage)
+}
+
+// TestClaudeAnalyzeStreamLongLine feeds a 200 KB event on a single line,
+// beyond bufio.Scanner's default limit, through a streamed analysis.
+func TestClaudeAnalyzeStreamLongLine(t *testing.T) {
+	notes := strings.Repeat("n", 200<<10)
+	partial, err := json.Marshal(`{"name":"Milk","notes":"` + notes + `"}`)
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeEvents(w,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","name":"add_item","input":{}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":`+string(partial)+`}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_stop"}`,
+		)
+	}))
+	defer server.Close()
+
+	a := NewClaudeAnalyzer("sk-test", "claude-opus-4-6").WithExtraction(ExtractionTools).WithStream(true)
+	a.baseURL = server.URL
+	result, err := a.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+	require.NoError(t, err)
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, notes, result.Items[0].Notes)
+}
+
+func TestReadStreamFraming(t *testing.T) {
+	tests := []struct {
+		name    string
+		stream  string
+		wantErr string
+	}{
+		{
+			name: "CRLF and multi-line data",
+			stream: "event: content_block_start\r\ndata: {\"type\":\"content_block_start\",\"index\":0,\r\ndata: \"content_block\":{\"type\":\"text\",\"text\":\"\"}}\r\n\r\n" +
+				"event: content_block_delta\r\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Milk\"}}\r\n\r\n" +
+				"event: content_block_stop\r\ndata: {\"type\":\"content_block_stop\",\"index\":0}\r\n\r\n" +
+				"event: message_stop\r\ndata: {\"type\":\"message_stop\"}\r\n\r\n",
+		},
+		{
+			name: "overloaded mid-stream",
+			stream: "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n" +
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Milk\"}}\n\n" +
+				"event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n",
+			wantErr: "overloaded_error: Overloaded",
+		},
+		{
+			name:    "error event without JSON",
+			stream:  "event: error\ndata: upstream connect error\n\n",
+			wantErr: "upstream connect error",
+		},
+		{
+			name: "cut off before message_stop",
+			stream: "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n" +
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Milk\"}}\n\n",
+			wantErr: io.ErrUnexpectedEOF.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := readStream(strings.NewReader(tt.stream), nil)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "Milk", responseText(resp))
+		})
+	}
+}
+
+func TestReadStreamEmitsEachBlockWhenComplete(t *testing.T) {
+	pr, pw := io.Pipe()
+	emitted := make(chan contentBlock)
+	go func() {
+		writeEvents(pw,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","name":"add_item","input":{}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"name\":\"Milk\"}"}}`,
+			`{"type":"content_block_stop","index":0}`,
+		)
+		// The first call must be handed over before the rest is sent.
+		select {
+		case <-emitted:
+		case <-time.After(5 * time.Second):
+			_ = pw.CloseWithError(fmt.Errorf("first tool call was not emitted"))
+			return
+		}
+		writeEvents(pw,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","name":"report_status","input":{}}}`,
+			`{"type":"content_block_stop","index":1}`,
+			`{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","name":"add_item","input":{}}}`,
+			`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"name\":"}}`,
+			`{"type":"message_stop"}`,
+		)
+		_ = pw.Close()
+	}()
+
+	var names []string
+	resp, err := readStream(pr, func(blk contentBlock) {
+		names = append(names, blk.Name)
+		if len(names) == 1 {
+			assert.JSONEq(t, `{"name":"Milk"}`, string(blk.Input))
+			emitted <- blk
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"add_item", "report_status"}, names, "the unfinished call is dropped")
+	require.Len(t, resp.Content, 2)
+	assert.JSONEq(t, `{}`, string(resp.Content[1].Input), "a call without arguments has empty input")
+}
@@ -0,0 +1,64 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/vbonduro/kitchinv/internal/vision/sse"
+)
+
+// GenerateText sends prompt as a text-only user turn and streams the reply to
+// onDelta. The vision system prompt is not sent. As for an analysis, a
+// stream that ends before message_stop is an error wrapping
+// io.ErrUnexpectedEOF.
+func (a *ClaudeAnalyzer) GenerateText(ctx context.Context, prompt string, onDelta func(string)) error {
+	payload, err := json.Marshal(request{
+		Model:     a.model,
+		MaxTokens: a.maxTokens,
+		Messages:  []message{{Role: "user", Content: []block{{Type: "text", Text: prompt}}}},
+		Stream:    true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := a.newHTTPRequest(ctx, payload)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call claude: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			slog.Error("failed to close claude response body", "error", err)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("claude returned status %d: %s", resp.StatusCode, errBody)
+	}
+
+	dec := sse.NewDecoder(resp.Body)
+	for {
+		ev, err := nextEvent(dec)
+		if err == io.EOF {
+			return fmt.Errorf("claude stream ended before message_stop: %w", io.ErrUnexpectedEOF)
+		}
+		if err != nil {
+			return err
+		}
+		switch ev.Type {
+		case "content_block_delta":
+			if ev.Delta.Type == "text_delta" && ev.Delta.Text != "" {
+				onDelta(ev.Delta.Text)
+			}
+		case "message_stop":
+			return nil
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaudeGenerateText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+		assert.Empty(t, req.System)
+		require.Len(t, req.Messages, 1)
+		assert.Equal(t, []block{{Type: "text", Text: "What can I cook?"}}, req.Messages[0].Content)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range []string{
+			`{"type":"message_start","message":{}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"ping"}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"1. Omelette"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"\n2. Pancakes"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_stop"}`,
+		} {
+			var typ struct{ Type string }
+			_ = json.Unmarshal([]byte(ev), &typ)
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ.Type, ev)
+		}
+	}))
+	defer server.Close()
+
+	a := NewClaudeAnalyzer("sk-test", "claude-opus-4-6")
+	a.baseURL = server.URL
+	var got strings.Builder
+	require.NoError(t, a.GenerateText(context.Background(), "What can I cook?", func(s string) { got.WriteString(s) }))
+	assert.Equal(t, "1. Omelette\n2. Pancakes", got.String())
+}
+
+// TestClaudeGenerateTextLongLine is a regression test for a 64 KB line limit
+// that ended the stream on any event larger than that.
+func TestClaudeGenerateTextLongLine(t *testing.T) {
+	long := strings.Repeat("a", 200<<10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprintf(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":%q}}\n\n", long)
+		_, _ = fmt.Fprint(w, "data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"!\"}}\n\n")
+		// The last event has no trailing newline.
+		_, _ = fmt.Fprint(w, "data: {\"type\":\"message_stop\"}")
+	}))
+	defer server.Close()
+
+	a := NewClaudeAnalyzer("sk-test", "claude-opus-4-6")
+	a.baseURL = server.URL
+	var got strings.Builder
+	require.NoError(t, a.GenerateText(context.Background(), "hi", func(s string) { got.WriteString(s) }))
+	assert.Equal(t, long+"!", got.String())
+}
+
+func TestClaudeGenerateTextStreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	}))
+	defer server.Close()
+
+	a := NewClaudeAnalyzer("sk-test", "claude-opus-4-6")
+	a.baseURL = server.URL
+	err := a.GenerateText(context.Background(), "hi", func(string) {})
+	assert.ErrorContains(t, err, "Overloaded")
+}
+
+func TestClaudeGenerateTextCutOff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"1. Omel\"}}\n\n")
+	}))
+	defer server.Close()
+
+	a := NewClaudeAnalyzer("sk-test", "claude-opus-4-6")
+	a.baseURL = server.URL
+	var got strings.Builder
+	err := a.GenerateText(context.Background(), "hi", func(s string) { got.WriteString(s) })
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Equal(t, "1. Omel", got.String(), "what arrived was still streamed")
+}
@@ -0,0 +1,154 @@
+package claude
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+// Extraction modes, the accepted values of WithExtraction.
+const (
+	// ExtractionText asks for the items as a JSON document in a text reply.
+	ExtractionText = "text"
+	// ExtractionTools has Claude call an add_item tool once per item, so
+	// each item arrives as its own structured input and a name containing
+	// punctuation, such as "Hot | Sweet sauce", can't be mis-split.
+	ExtractionTools = "tools"
+)
+
+const (
+	toolAddItem      = "add_item"
+	toolReportStatus = "report_status"
+)
+
+type tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type toolChoice struct {
+	Type string `json:"type"`
+}
+
+// choiceAny makes Claude call at least one tool rather than answer in text.
+var choiceAny = &toolChoice{Type: "any"}
+
+// extractionTools are offered to Claude in tools mode: add_item for each
+// item found, and report_status when there are none.
+var extractionTools = []tool{
+	{
+		Name:        toolAddItem,
+		Description: "Record one food item visible in the photo. Call once per distinct item.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "required": ["name"],
+  "properties": {
+    "name":     { "type": "string", "description": "Product name, including the brand where visible" },
+    "quantity": { "type": "integer", "minimum": 1, "description": "How many are visible" },
+    "notes":    { "type": "string", "description": "Anything worth knowing, such as where it is or that it is opened" },
+    "bbox":     { "type": "array", "items": { "type": "number", "minimum": 0, "maximum": 1 }, "minItems": 4, "maxItems": 4, "description": "Normalized [x1, y1, x2, y2]" }
+  }
+}`),
+	},
+	{
+		Name:        toolReportStatus,
+		Description: "Report why no food items could be recorded. Call only if add_item is never called.",
+		InputSchema: json.RawMessage(`{
+  "type": "object",
+  "required": ["status"],
+  "properties": {
+    "status": { "enum": ["no_items", "not_food", "unclear"] }
+  }
+}`),
+	},
+}
+
+// itemInput is the input of an add_item call.
+type itemInput struct {
+	Name     string    `json:"name"`
+	Quantity *int      `json:"quantity"`
+	Notes    string    `json:"notes"`
+	BBox     []float64 `json:"bbox"`
+}
+
+// toolCalls collects the result of an analysis from Claude's tool calls as
+// each one completes. A call whose input can't be used is logged and
+// skipped, so one bad item doesn't lose the rest.
+type toolCalls struct {
+	items  []vision.DetectedItem
+	status vision.AnalysisStatus
+	calls  int
+	raw    strings.Builder
+}
+
+// add records one completed content block; blocks other than tool calls
+// are ignored.
+func (t *toolCalls) add(blk contentBlock) {
+	if blk.Type != "tool_use" {
+		return
+	}
+	t.calls++
+	fmt.Fprintf(&t.raw, "%s %s\n", blk.Name, blk.Input)
+
+	switch blk.Name {
+	case toolAddItem:
+		var in itemInput
+		if err := json.Unmarshal(blk.Input, &in); err != nil {
+			slog.Warn("skipping claude add_item call with invalid input", "error", err)
+			return
+		}
+		if strings.TrimSpace(in.Name) == "" {
+			slog.Warn("skipping claude add_item call without a name")
+			return
+		}
+		item := vision.DetectedItem{Name: in.Name, Notes: in.Notes}
+		if in.Quantity != nil {
+			item.Quantity = fmt.Sprint(*in.Quantity)
+		}
+		if len(in.BBox) == 4 {
+			bbox := [4]float64{in.BBox[0], in.BBox[1], in.BBox[2], in.BBox[3]}
+			item.BBox = &bbox
+		}
+		slog.Debug("claude tool call complete", "tool", blk.Name, "item", item.Name)
+		t.items = append(t.items, item)
+	case toolReportStatus:
+		var in struct {
+			Status vision.AnalysisStatus `json:"status"`
+		}
+		if err := json.Unmarshal(blk.Input, &in); err != nil {
+			slog.Warn("skipping claude report_status call with invalid input", "error", err)
+			return
+		}
+		switch in.Status {
+		case vision.StatusNoItems, vision.StatusNotFood, vision.StatusUnclear:
+			t.status = in.Status
+		default:
+			slog.Warn("skipping claude report_status call with unknown status", "status", in.Status)
+		}
+	default:
+		slog.Warn("skipping call to unknown claude tool", "tool", blk.Name)
+	}
+}
+
+// result builds the analysis from the calls recorded. Any item found makes
+// the status ok, whatever report_status said.
+func (t *toolCalls) result() (*vision.AnalysisResult, error) {
+	if t.calls == 0 {
+		return nil, fmt.Errorf("no tool calls in response")
+	}
+	status := t.status
+	if len(t.items) > 0 {
+		status = vision.StatusOK
+	} else if status == "" {
+		status = vision.StatusNoItems
+	}
+	return &vision.AnalysisResult{
+		Status:      status,
+		Items:       t.items,
+		RawResponse: t.raw.String(),
+	}, nil
+}
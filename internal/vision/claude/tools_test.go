@@ -0,0 +1,104 @@
+package claude
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+// toolServer answers every request with content, a list of content blocks,
+// and stores the request it was sent in captured.
+func toolServer(t *testing.T, captured *request, content string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if captured != nil {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(captured))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"content":` + content + `,"stop_reason":"tool_use","usage":{"input_tokens":1600,"output_tokens":120}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClaudeAnalyzeTools(t *testing.T) {
+	var captured request
+	server := toolServer(t, &captured, `[
+		{"type":"text","text":"I'll record each item."},
+		{"type":"tool_use","id":"toolu_1","name":"add_item","input":{"name":"Hot | Sweet sauce","quantity":2,"bbox":[0.1,0.2,0.3,0.4]}},
+		{"type":"tool_use","id":"toolu_2","name":"add_item","input":{"name":"Milk","notes":"opened"}},
+		{"type":"tool_use","id":"toolu_3","name":"add_item","input":{"quantity":1}}
+	]`)
+
+	a := NewClaudeAnalyzer("sk-test", "claude-opus-4-6").WithExtraction(ExtractionTools)
+	a.baseURL = server.URL
+	result, err := a.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+	require.NoError(t, err)
+
+	assert.Equal(t, vision.ClaudeToolSystemPrompt, captured.System)
+	require.Len(t, captured.Tools, 2)
+	assert.Equal(t, toolAddItem, captured.Tools[0].Name)
+	assert.Equal(t, toolReportStatus, captured.Tools[1].Name)
+	assert.Equal(t, &toolChoice{Type: "any"}, captured.ToolChoice)
+
+	assert.Equal(t, vision.StatusOK, result.Status)
+	require.Len(t, result.Items, 2, "the call without a name is skipped")
+	assert.Equal(t, vision.DetectedItem{Name: "Hot | Sweet sauce", Quantity: "2", BBox: &[4]float64{0.1, 0.2, 0.3, 0.4}}, result.Items[0])
+	assert.Equal(t, vision.DetectedItem{Name: "Milk", Notes: "opened"}, result.Items[1])
+	assert.Contains(t, result.RawResponse, `add_item {"name":"Hot | Sweet sauce"`)
+	assert.Equal(t, vision.Usage{InputTokens: 1600, OutputTokens: 120}, result.Usage)
+}
+
+func TestClaudeAnalyzeToolsStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    vision.AnalysisStatus
+		wantErr string
+	}{
+		{"not food", `[{"type":"tool_use","name":"report_status","input":{"status":"not_food"}}]`, vision.StatusNotFood, ""},
+		{"no items", `[{"type":"tool_use","name":"report_status","input":{"status":"no_items"}}]`, vision.StatusNoItems, ""},
+		{"unknown status", `[{"type":"tool_use","name":"report_status","input":{"status":"maybe"}}]`, vision.StatusNoItems, ""},
+		{"items win", `[{"type":"tool_use","name":"report_status","input":{"status":"no_items"}},{"type":"tool_use","name":"add_item","input":{"name":"Eggs"}}]`, vision.StatusOK, ""},
+		{"unclear", `[{"type":"tool_use","name":"report_status","input":{"status":"unclear"}}]`, "", "unclear"},
+		{"no tool calls", `[{"type":"text","text":"{\"status\":\"ok\",\"items\":[]}"}]`, "", "no tool calls"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := toolServer(t, nil, tt.content)
+			a := NewClaudeAnalyzer("sk-test", "claude-opus-4-6").WithExtraction(ExtractionTools)
+			a.baseURL = server.URL
+
+			result, err := a.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, result.Status)
+		})
+	}
+}
+
+func TestClaudeAnalyzeTextModeSendsNoTools(t *testing.T) {
+	var captured request
+	server := toolServer(t, &captured, `[{"type":"text","text":"{\"status\":\"ok\",\"items\":[{\"name\":\"Milk\"}]}"}]`)
+
+	a := NewClaudeAnalyzer("sk-test", "claude-opus-4-6").WithExtraction("bogus")
+	a.baseURL = server.URL
+	result, err := a.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+	require.NoError(t, err)
+
+	assert.Equal(t, vision.ClaudeSystemPrompt, captured.System)
+	assert.Empty(t, captured.Tools)
+	assert.Nil(t, captured.ToolChoice)
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, "Milk", result.Items[0].Name)
+}
@@ -0,0 +1,58 @@
+// Package fake provides a VisionAnalyzer that returns canned results without
+// calling any model. It backs the "try an upload" path in demo mode.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+// cannedResults are the item sets the analyzer chooses between. Each set
+// looks like a plausible shelf so demo uploads feel realistic.
+var cannedResults = [][]vision.DetectedItem{
+	{
+		{Name: "Sparkling Water", Quantity: "6", BBox: &[4]float64{0.05, 0.10, 0.30, 0.45}},
+		{Name: "Cheddar Cheese", Quantity: "1", BBox: &[4]float64{0.35, 0.15, 0.55, 0.40}},
+		{Name: "Blueberries", Quantity: "2", BBox: &[4]float64{0.60, 0.50, 0.85, 0.80}},
+	},
+	{
+		{Name: "Black Beans", Quantity: "3", BBox: &[4]float64{0.10, 0.05, 0.35, 0.40}},
+		{Name: "Honey", Quantity: "1", BBox: &[4]float64{0.45, 0.10, 0.60, 0.45}},
+		{Name: "Crackers", Quantity: "2", BBox: &[4]float64{0.20, 0.55, 0.70, 0.90}},
+		{Name: "Maple Syrup", Quantity: "1", BBox: &[4]float64{0.75, 0.50, 0.95, 0.90}},
+	},
+	{
+		{Name: "Frozen Berries", Quantity: "2", BBox: &[4]float64{0.05, 0.05, 0.45, 0.45}},
+		{Name: "Veggie Burgers", Quantity: "1", BBox: &[4]float64{0.50, 0.10, 0.90, 0.40}},
+	},
+}
+
+// FakeAnalyzer returns one of a few canned item sets, chosen
+// deterministically from the image bytes so the same photo always yields the
+// same items.
+type FakeAnalyzer struct{}
+
+func NewFakeAnalyzer() *FakeAnalyzer {
+	return &FakeAnalyzer{}
+}
+
+func (a *FakeAnalyzer) Analyze(ctx context.Context, r io.Reader, mimeType string) (*vision.AnalysisResult, error) {
+	h := fnv.New32a()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	items := cannedResults[h.Sum32()%uint32(len(cannedResults))]
+	return &vision.AnalysisResult{
+		Status: vision.StatusOK,
+		Items:  append([]vision.DetectedItem(nil), items...),
+	}, nil
+}
+
+// AnalyzeWithPrompt ignores prompt: the canned items depend only on the image.
+func (a *FakeAnalyzer) AnalyzeWithPrompt(ctx context.Context, r io.Reader, mimeType, prompt string) (*vision.AnalysisResult, error) {
+	return a.Analyze(ctx, r, mimeType)
+}
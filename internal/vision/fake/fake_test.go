@@ -0,0 +1,25 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+func TestFakeAnalyzer_Deterministic(t *testing.T) {
+	a := NewFakeAnalyzer()
+	img := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x01}
+
+	first, err := a.Analyze(context.Background(), bytes.NewReader(img), "image/jpeg")
+	require.NoError(t, err)
+	second, err := a.Analyze(context.Background(), bytes.NewReader(img), "image/jpeg")
+	require.NoError(t, err)
+
+	assert.Equal(t, vision.StatusOK, first.Status)
+	assert.NotEmpty(t, first.Items)
+	assert.Equal(t, first.Items, second.Items)
+}
@@ -53,12 +53,18 @@ type response struct {
 				Text string `json:"text"`
 			} `json:"parts"`
 		} `json:"content"`
+		FinishReason string `json:"finishReason"`
 	} `json:"candidates"`
 }
 
+// finishMaxTokens is the finishReason Gemini reports when the reply was cut
+// off at its output token limit.
+const finishMaxTokens = "MAX_TOKENS"
+
 type GeminiAnalyzer struct {
 	apiKey  string
 	model   string
+	prompt  string
 	client  *http.Client
 	baseURL string
 }
@@ -67,12 +73,26 @@ func NewGeminiAnalyzer(apiKey, model string) *GeminiAnalyzer {
 	return &GeminiAnalyzer{
 		apiKey:  apiKey,
 		model:   model,
+		prompt:  vision.GeminiUserPrompt,
 		client:  &http.Client{},
 		baseURL: defaultBaseURL,
 	}
 }
 
+// WithPrompt replaces GeminiUserPrompt as the default user-turn prompt. A
+// blank prompt keeps the built-in one.
+func (a *GeminiAnalyzer) WithPrompt(prompt string) *GeminiAnalyzer {
+	a.prompt = vision.PromptOrDefault(prompt, vision.GeminiUserPrompt)
+	return a
+}
+
 func (a *GeminiAnalyzer) Analyze(ctx context.Context, r io.Reader, mimeType string) (*vision.AnalysisResult, error) {
+	return a.AnalyzeWithPrompt(ctx, r, mimeType, "")
+}
+
+// AnalyzeWithPrompt is Analyze with prompt replacing the default user-turn
+// prompt. An empty prompt uses the default.
+func (a *GeminiAnalyzer) AnalyzeWithPrompt(ctx context.Context, r io.Reader, mimeType, prompt string) (*vision.AnalysisResult, error) {
 	imageData, err := io.ReadAll(r)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read image: %w", err)
@@ -90,7 +110,7 @@ func (a *GeminiAnalyzer) Analyze(ctx context.Context, r io.Reader, mimeType stri
 						Data:     base64.StdEncoding.EncodeToString(imageData),
 					},
 				},
-				{Text: vision.GeminiUserPrompt},
+				{Text: vision.PromptOrDefault(prompt, a.prompt)},
 			},
 		}},
 		GenerationConfig: genConfig{
@@ -140,6 +160,8 @@ func (a *GeminiAnalyzer) Analyze(ctx context.Context, r io.Reader, mimeType stri
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse vision response: %w", err)
 	}
+	result.StopReason = respBody.Candidates[0].FinishReason
+	result.Truncated = result.StopReason == finishMaxTokens
 
 	if result.Status == vision.StatusUnclear {
 		return nil, fmt.Errorf("image is unclear: please retake the photo")
@@ -164,7 +186,7 @@ func (a *GeminiAnalyzer) AnalyzeWithFileURI(ctx context.Context, fileURI, mimeTy
 						FileURI:  fileURI,
 					},
 				},
-				{Text: vision.GeminiUserPrompt},
+				{Text: a.prompt},
 			},
 		}},
 		GenerationConfig: genConfig{
@@ -214,6 +236,8 @@ func (a *GeminiAnalyzer) AnalyzeWithFileURI(ctx context.Context, fileURI, mimeTy
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse vision response: %w", err)
 	}
+	result.StopReason = respBody.Candidates[0].FinishReason
+	result.Truncated = result.StopReason == finishMaxTokens
 
 	if result.Status == vision.StatusUnclear {
 		return nil, fmt.Errorf("image is unclear: please retake the photo")
@@ -195,3 +195,30 @@ type errReader struct{}
 func (e *errReader) Read(_ []byte) (int, error) {
 	return 0, io.ErrUnexpectedEOF
 }
+
+func TestGeminiAnalyzeWithPrompt(t *testing.T) {
+	var captured request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&captured)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(geminiResp(`{"status":"ok","items":[]}`))
+	}))
+	defer server.Close()
+
+	analyzer := NewGeminiAnalyzer("test-key", "gemini-2.0-flash")
+	analyzer.baseURL = server.URL
+
+	userText := func() string {
+		require.Len(t, captured.Contents, 1)
+		require.Len(t, captured.Contents[0].Parts, 2)
+		return captured.Contents[0].Parts[1].Text
+	}
+
+	_, err := analyzer.AnalyzeWithPrompt(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg", "Read every jar label.")
+	require.NoError(t, err)
+	assert.Equal(t, "Read every jar label.", userText())
+
+	_, err = analyzer.AnalyzeWithPrompt(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg", "")
+	require.NoError(t, err)
+	assert.Equal(t, vision.GeminiUserPrompt, userText())
+}
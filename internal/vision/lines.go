@@ -0,0 +1,24 @@
+package vision
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// ReadLine returns the next line of a streamed reply without its line ending.
+// Unlike bufio.Scanner it has no length limit, since a backend may send one
+// event as a single line of hundreds of kilobytes. A last line without a
+// newline is returned as a line; io.EOF is only returned once nothing is
+// left.
+func ReadLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), nil
+}
@@ -0,0 +1,26 @@
+package vision
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLine(t *testing.T) {
+	long := strings.Repeat("x", 200<<10)
+	br := bufio.NewReader(strings.NewReader("data: one\r\n\n" + long + "\nlast"))
+	var lines []string
+	for {
+		line, err := ReadLine(br)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		lines = append(lines, line)
+	}
+	assert.Equal(t, []string{"data: one", "", long, "last"}, lines, "no length limit, and the unterminated last line is kept")
+}
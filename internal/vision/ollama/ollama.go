@@ -5,29 +5,103 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/vbonduro/kitchinv/internal/tracing"
 	"github.com/vbonduro/kitchinv/internal/vision"
 )
 
+// Ollama endpoints the analyzer can talk to; see WithAPI.
+const (
+	APIGenerate = "generate"
+	APIChat     = "chat"
+)
+
+// ErrModelNotFound is returned by Check when the configured model has not
+// been pulled into the Ollama instance.
+var ErrModelNotFound = errors.New("ollama model not found")
+
+// Options are generation settings sent with every request. Zero values are
+// left out so the model's own defaults apply.
+type Options struct {
+	Temperature *float64 // sampling temperature; lower reduces invented quantities
+	NumPredict  int      // cap on generated tokens
+	NumCtx      int      // context window size
+	KeepAlive   string   // how long Ollama keeps the model loaded, e.g. "10m"
+}
+
+// requestOptions returns the "options" block for o, or nil if nothing is set.
+func (o Options) requestOptions() map[string]interface{} {
+	opts := map[string]interface{}{}
+	if o.Temperature != nil {
+		opts["temperature"] = *o.Temperature
+	}
+	if o.NumPredict > 0 {
+		opts["num_predict"] = o.NumPredict
+	}
+	if o.NumCtx > 0 {
+		opts["num_ctx"] = o.NumCtx
+	}
+	if len(opts) == 0 {
+		return nil
+	}
+	return opts
+}
+
 type OllamaAnalyzer struct {
-	host   string
-	model  string
-	client *http.Client
+	host    string
+	model   string
+	prompt  string
+	api     string
+	options Options
+	client  *http.Client
 }
 
 func NewOllamaAnalyzer(host, model string) *OllamaAnalyzer {
 	return &OllamaAnalyzer{
 		host:   host,
 		model:  model,
+		api:    APIGenerate,
 		client: &http.Client{},
 	}
 }
 
+// WithAPI selects the Ollama endpoint: APIGenerate (the default) sends a
+// single prompt to /api/generate, APIChat sends a messages array to
+// /api/chat, which some newer multimodal models handle better.
+func (a *OllamaAnalyzer) WithAPI(api string) *OllamaAnalyzer {
+	a.api = api
+	return a
+}
+
+// WithOptions sets the generation options sent with each request.
+func (a *OllamaAnalyzer) WithOptions(o Options) *OllamaAnalyzer {
+	a.options = o
+	return a
+}
+
+// WithPrompt sets default instructions appended to OllamaAnalysisPrompt,
+// which stays in place because it describes the response shape.
+func (a *OllamaAnalyzer) WithPrompt(prompt string) *OllamaAnalyzer {
+	a.prompt = vision.PromptOrDefault(prompt, "")
+	return a
+}
+
 func (a *OllamaAnalyzer) Analyze(ctx context.Context, r io.Reader, mimeType string) (*vision.AnalysisResult, error) {
+	return a.AnalyzeWithPrompt(ctx, r, mimeType, "")
+}
+
+// AnalyzeWithPrompt is Analyze with custom instructions in place of those set
+// by WithPrompt. Ollama takes a single prompt that also describes the response
+// shape, so the instructions are appended to OllamaAnalysisPrompt rather than
+// replacing it.
+func (a *OllamaAnalyzer) AnalyzeWithPrompt(ctx context.Context, r io.Reader, mimeType, prompt string) (*vision.AnalysisResult, error) {
 	imageData, err := io.ReadAll(r)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read image: %w", err)
@@ -35,19 +109,175 @@ func (a *OllamaAnalyzer) Analyze(ctx context.Context, r io.Reader, mimeType stri
 
 	encoded := base64.StdEncoding.EncodeToString(imageData)
 
-	reqBody := map[string]interface{}{
+	fullPrompt := vision.OllamaAnalysisPrompt
+	if p := vision.PromptOrDefault(prompt, a.prompt); p != "" {
+		fullPrompt += "\n\n" + p
+	}
+
+	text, stats, err := a.send(ctx, fullPrompt, encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	truncated := stats.DoneReason == doneLength
+	if truncated {
+		slog.Warn("ollama response hit its length limit, item list may be incomplete", "num_predict", a.options.NumPredict)
+	}
+
+	result, err := vision.ParseJSONResponse(text)
+	if err != nil {
+		if truncated {
+			return nil, fmt.Errorf("failed to parse vision response cut off at its length limit (raise OLLAMA_NUM_PREDICT or OLLAMA_NUM_CTX): %w", err)
+		}
+		return nil, fmt.Errorf("failed to parse vision response: %w", err)
+	}
+	result.Truncated = truncated
+	result.StopReason = stats.DoneReason
+	result.Usage = stats.usage()
+
+	if result.Status == vision.StatusUnclear {
+		return nil, fmt.Errorf("image is unclear: please retake the photo")
+	}
+
+	return result, nil
+}
+
+// doneLength is the done_reason Ollama reports when generation stopped at
+// num_predict or the context window.
+const doneLength = "length"
+
+// evalStats are the counters Ollama reports on its final response object,
+// with the reason generation stopped.
+type evalStats struct {
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	EvalDuration    int64  `json:"eval_duration"` // nanoseconds
+	DoneReason      string `json:"done_reason"`
+}
+
+func (s evalStats) usage() vision.Usage {
+	return vision.Usage{
+		InputTokens:  s.PromptEvalCount,
+		OutputTokens: s.EvalCount,
+		Duration:     time.Duration(s.EvalDuration),
+	}
+}
+
+// send runs the prompt through the configured endpoint, recording the call
+// as a client span with the model and token counts.
+func (a *OllamaAnalyzer) send(ctx context.Context, prompt, image string) (text string, stats evalStats, err error) {
+	ctx, span := tracing.Start(ctx, "ollama."+a.api, tracing.KindClient)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+	span.SetString("gen_ai.system", "ollama")
+	span.SetString("gen_ai.request.model", a.model)
+
+	if a.api == APIChat {
+		text, stats, err = a.chat(ctx, prompt, image)
+	} else {
+		text, stats, err = a.generate(ctx, prompt, image)
+	}
+	span.SetInt("gen_ai.usage.input_tokens", int64(stats.PromptEvalCount))
+	span.SetInt("gen_ai.usage.output_tokens", int64(stats.EvalCount))
+	return text, stats, err
+}
+
+// generate sends the prompt through the legacy /api/generate endpoint and
+// returns the model's reply.
+func (a *OllamaAnalyzer) generate(ctx context.Context, prompt, image string) (string, evalStats, error) {
+	resp, err := a.post(ctx, "/api/generate", map[string]interface{}{
 		"model":  a.model,
-		"prompt": vision.OllamaAnalysisPrompt,
-		"images": []string{encoded},
-		"stream": false,
+		"prompt": prompt,
+		"images": []string{image},
+	})
+	if err != nil {
+		return "", evalStats{}, err
+	}
+	defer closeBody(resp)
+
+	var respBody struct {
+		Response string `json:"response"`
+		evalStats
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
+		return "", evalStats{}, fmt.Errorf("failed to decode response: %w", err)
 	}
+	return respBody.Response, respBody.evalStats, nil
+}
+
+// chatMessage is one entry of an /api/chat messages array.
+type chatMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"`
+}
 
-	payload, err := json.Marshal(reqBody)
+// chat sends the prompt through /api/chat with the image attached to the
+// user message. The reply is read as a sequence of JSON objects whose
+// message.content deltas are concatenated, which covers both a single
+// non-streamed object and a streamed NDJSON body. The eval counters come
+// from the final (done) object.
+func (a *OllamaAnalyzer) chat(ctx context.Context, prompt, image string) (string, evalStats, error) {
+	resp, err := a.post(ctx, "/api/chat", map[string]interface{}{
+		"model":    a.model,
+		"messages": []chatMessage{{Role: "user", Content: prompt, Images: []string{image}}},
+	})
+	if err != nil {
+		return "", evalStats{}, err
+	}
+	defer closeBody(resp)
+
+	var (
+		text  strings.Builder
+		stats evalStats
+	)
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var chunk struct {
+			Message chatMessage `json:"message"`
+			Done    bool        `json:"done"`
+			Error   string      `json:"error"`
+			evalStats
+		}
+		if err := dec.Decode(&chunk); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", evalStats{}, fmt.Errorf("failed to decode response: %w", err)
+		}
+		if chunk.Error != "" {
+			return "", evalStats{}, fmt.Errorf("ollama returned error: %s", chunk.Error)
+		}
+		text.WriteString(chunk.Message.Content)
+		if chunk.Done {
+			stats = chunk.evalStats
+			break
+		}
+	}
+	return text.String(), stats, nil
+}
+
+// post sends body as JSON to path on the Ollama host, adding the settings
+// shared by every endpoint, and returns the response if it succeeded. The
+// caller must close it. Replies are not streamed unless body sets "stream".
+func (a *OllamaAnalyzer) post(ctx context.Context, path string, body map[string]interface{}) (*http.Response, error) {
+	if _, ok := body["stream"]; !ok {
+		body["stream"] = false
+	}
+	if opts := a.options.requestOptions(); opts != nil {
+		body["options"] = opts
+	}
+	if a.options.KeepAlive != "" {
+		body["keep_alive"] = a.options.KeepAlive
+	}
+
+	payload, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", a.host+"/api/generate", bytes.NewReader(payload))
+	req, err := http.NewRequestWithContext(ctx, "POST", a.host+path, bytes.NewReader(payload))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -58,32 +288,105 @@ func (a *OllamaAnalyzer) Analyze(ctx context.Context, r io.Reader, mimeType stri
 	if err != nil {
 		return nil, fmt.Errorf("failed to call ollama: %w", err)
 	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			slog.Error("failed to close ollama response body", "error", err)
-		}
-	}()
 
 	if resp.StatusCode != http.StatusOK {
+		closeBody(resp)
 		return nil, fmt.Errorf("ollama returned status %d", resp.StatusCode)
 	}
+	return resp, nil
+}
 
-	var respBody struct {
-		Response string `json:"response"`
+func closeBody(resp *http.Response) {
+	if err := resp.Body.Close(); err != nil {
+		slog.Error("failed to close ollama response body", "error", err)
 	}
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&respBody); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+// Check verifies Ollama is reachable and has the configured model, returning
+// an error wrapping ErrModelNotFound if it needs pulling.
+func (a *OllamaAnalyzer) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.host+"/api/tags", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach ollama at %s: %w", a.host, err)
 	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ollama returned status %d listing models", resp.StatusCode)
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return fmt.Errorf("failed to decode model list: %w", err)
+	}
+	for _, m := range tags.Models {
+		if sameModel(m.Name, a.model) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q is not installed, run `ollama pull %s`", ErrModelNotFound, a.model, a.model)
+}
 
-	result, err := vision.ParseJSONResponse(respBody.Response)
+// sameModel compares Ollama model names, treating a missing tag as
+// ":latest" the way Ollama does.
+func sameModel(a, b string) bool {
+	withTag := func(s string) string {
+		if strings.Contains(s, ":") {
+			return s
+		}
+		return s + ":latest"
+	}
+	return withTag(a) == withTag(b)
+}
+
+// Pull downloads the configured model, calling progress with each status
+// update Ollama streams back (e.g. "pulling manifest", byte counts while
+// downloading layers).
+func (a *OllamaAnalyzer) Pull(ctx context.Context, progress func(status string, completed, total int64)) error {
+	payload, err := json.Marshal(map[string]string{"model": a.model})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse vision response: %w", err)
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.host+"/api/pull", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 
-	if result.Status == vision.StatusUnclear {
-		return nil, fmt.Errorf("image is unclear: please retake the photo")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call ollama: %w", err)
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ollama returned status %d pulling %s", resp.StatusCode, a.model)
 	}
 
-	return result, nil
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var update struct {
+			Status    string `json:"status"`
+			Completed int64  `json:"completed"`
+			Total     int64  `json:"total"`
+			Error     string `json:"error"`
+		}
+		if err := dec.Decode(&update); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to decode pull progress: %w", err)
+		}
+		if update.Error != "" {
+			return fmt.Errorf("ollama failed to pull %s: %s", a.model, update.Error)
+		}
+		if progress != nil {
+			progress(update.Status, update.Completed, update.Total)
+		}
+	}
 }
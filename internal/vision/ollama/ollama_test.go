@@ -8,9 +8,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/tracing/tracingtest"
 	"github.com/vbonduro/kitchinv/internal/vision"
 )
 
@@ -114,3 +116,266 @@ func TestOllamaAnalyzeReadError(t *testing.T) {
 
 	assert.Error(t, err)
 }
+
+func TestOllamaAnalyzeWithPrompt(t *testing.T) {
+	var captured struct {
+		Prompt string `json:"prompt"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&captured)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"response": `{"status":"ok","items":[]}`})
+	}))
+	defer server.Close()
+
+	analyzer := NewOllamaAnalyzer(server.URL, "moondream")
+
+	// The custom prompt is appended; the format instructions stay.
+	_, err := analyzer.AnalyzeWithPrompt(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg", " Read every jar label. ")
+	require.NoError(t, err)
+	assert.Equal(t, vision.OllamaAnalysisPrompt+"\n\nRead every jar label.", captured.Prompt)
+
+	_, err = analyzer.AnalyzeWithPrompt(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg", "   ")
+	require.NoError(t, err)
+	assert.Equal(t, vision.OllamaAnalysisPrompt, captured.Prompt)
+}
+
+func TestOllamaChatAPI(t *testing.T) {
+	const reply = `{"status":"ok","items":[{"name":"Milk","quantity":2}]}`
+	tests := []struct {
+		name string
+		body func(w http.ResponseWriter)
+	}{
+		{
+			name: "single response",
+			body: func(w http.ResponseWriter) {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"message": map[string]string{"role": "assistant", "content": reply},
+					"done":    true,
+				})
+			},
+		},
+		{
+			name: "streamed deltas",
+			body: func(w http.ResponseWriter) {
+				enc := json.NewEncoder(w)
+				for _, delta := range []string{reply[:10], reply[10:30], reply[30:]} {
+					_ = enc.Encode(map[string]interface{}{
+						"message": map[string]string{"role": "assistant", "content": delta},
+						"done":    false,
+					})
+				}
+				_ = enc.Encode(map[string]interface{}{"message": map[string]string{"role": "assistant"}, "done": true})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var captured struct {
+				Model    string `json:"model"`
+				Prompt   string `json:"prompt"`
+				Messages []struct {
+					Role    string   `json:"role"`
+					Content string   `json:"content"`
+					Images  []string `json:"images"`
+				} `json:"messages"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/api/chat", r.URL.Path)
+				_ = json.NewDecoder(r.Body).Decode(&captured)
+				w.Header().Set("Content-Type", "application/x-ndjson")
+				tt.body(w)
+			}))
+			defer server.Close()
+
+			analyzer := NewOllamaAnalyzer(server.URL, "llava").WithAPI(APIChat)
+			result, err := analyzer.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+			require.NoError(t, err)
+
+			assert.Equal(t, "llava", captured.Model)
+			assert.Empty(t, captured.Prompt, "chat requests carry the prompt in messages")
+			require.Len(t, captured.Messages, 1)
+			assert.Equal(t, "user", captured.Messages[0].Role)
+			assert.Equal(t, vision.OllamaAnalysisPrompt, captured.Messages[0].Content)
+			assert.Equal(t, []string{"/9g="}, captured.Messages[0].Images)
+
+			require.Len(t, result.Items, 1)
+			assert.Equal(t, "Milk", result.Items[0].Name)
+			assert.Equal(t, "2", result.Items[0].Quantity)
+		})
+	}
+}
+
+func TestOllamaChatAPI_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "model does not support images"})
+	}))
+	defer server.Close()
+
+	analyzer := NewOllamaAnalyzer(server.URL, "llama3").WithAPI(APIChat)
+	_, err := analyzer.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not support images")
+}
+
+func TestOllamaRequestOptions(t *testing.T) {
+	var captured map[string]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = nil
+		_ = json.NewDecoder(r.Body).Decode(&captured)
+		// Valid for both endpoints: generate reads response, chat message.
+		reply := `{"status":"ok","items":[]}`
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"response": reply,
+			"message":  map[string]string{"role": "assistant", "content": reply},
+			"done":     true,
+		})
+	}))
+	defer server.Close()
+
+	// Unconfigured: only the model's defaults apply.
+	_, err := NewOllamaAnalyzer(server.URL, "moondream").
+		Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+	require.NoError(t, err)
+	assert.NotContains(t, captured, "options")
+	assert.NotContains(t, captured, "keep_alive")
+
+	temp := 0.0
+	for _, api := range []string{APIGenerate, APIChat} {
+		analyzer := NewOllamaAnalyzer(server.URL, "moondream").WithAPI(api).WithOptions(Options{
+			Temperature: &temp,
+			NumPredict:  512,
+			NumCtx:      4096,
+			KeepAlive:   "10m",
+		})
+		_, err := analyzer.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+		require.NoError(t, err, api)
+		assert.JSONEq(t, `{"temperature":0,"num_predict":512,"num_ctx":4096}`, string(captured["options"]), api)
+		assert.JSONEq(t, `"10m"`, string(captured["keep_alive"]), api)
+		assert.JSONEq(t, `false`, string(captured["stream"]), api)
+	}
+}
+
+func TestOllamaCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/tags", r.URL.Path)
+		_, _ = w.Write([]byte(`{"models":[{"name":"moondream:latest"},{"name":"llava:13b"}]}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	assert.NoError(t, NewOllamaAnalyzer(server.URL, "moondream").Check(ctx))
+	assert.NoError(t, NewOllamaAnalyzer(server.URL, "llava:13b").Check(ctx))
+
+	err := NewOllamaAnalyzer(server.URL, "llava").Check(ctx)
+	require.ErrorIs(t, err, ErrModelNotFound)
+	assert.Contains(t, err.Error(), "ollama pull llava")
+
+	err = NewOllamaAnalyzer("http://localhost:99999", "moondream").Check(ctx)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrModelNotFound)
+}
+
+func TestOllamaPull(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/pull", r.URL.Path)
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "llava", req["model"])
+		_, _ = w.Write([]byte(`{"status":"pulling manifest"}
+{"status":"pulling abc123","completed":50,"total":100}
+{"status":"success"}
+`))
+	}))
+	defer server.Close()
+
+	var statuses []string
+	err := NewOllamaAnalyzer(server.URL, "llava").Pull(context.Background(), func(status string, completed, total int64) {
+		statuses = append(statuses, status)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pulling manifest", "pulling abc123", "success"}, statuses)
+}
+
+func TestOllamaPull_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":"pulling manifest"}
+{"error":"pull model manifest: file does not exist"}
+`))
+	}))
+	defer server.Close()
+
+	err := NewOllamaAnalyzer(server.URL, "nope").Pull(context.Background(), nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "file does not exist")
+}
+
+func TestOllamaAnalyzeUsage(t *testing.T) {
+	const okText = `{\"status\":\"ok\",\"items\":[{\"name\":\"Milk\",\"quantity\":1}]}`
+	tests := []struct {
+		api  string
+		body string
+	}{
+		{APIGenerate, `{"response":"` + okText + `","done":true,"prompt_eval_count":700,"eval_count":42,"eval_duration":3500000000}`},
+		{APIChat, `{"message":{"role":"assistant","content":"` + okText + `"},"done":false}
+{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":700,"eval_count":42,"eval_duration":3500000000}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.api, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			result, err := NewOllamaAnalyzer(server.URL, "llava").WithAPI(tt.api).
+				Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+			require.NoError(t, err)
+			assert.Equal(t, vision.Usage{InputTokens: 700, OutputTokens: 42, Duration: 3500 * time.Millisecond}, result.Usage)
+		})
+	}
+}
+
+func TestOllamaAnalyzeDoneReason(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+	a := NewOllamaAnalyzer(server.URL, "llava").WithAPI(APIChat)
+
+	body = `{"message":{"role":"assistant","content":"{\"status\":\"ok\",\"items\":[{\"name\":\"Milk\"}]}"},"done":true,"done_reason":"stop"}`
+	result, err := a.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+	require.NoError(t, err)
+	assert.Equal(t, "stop", result.StopReason)
+	assert.False(t, result.Truncated)
+
+	body = `{"message":{"role":"assistant","content":"{\"status\":\"ok\",\"items\":[{\"name\":\"Milk\"}]}"},"done":true,"done_reason":"length"}`
+	result, err = a.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+	require.NoError(t, err)
+	assert.Equal(t, "length", result.StopReason)
+	assert.True(t, result.Truncated)
+
+	// A reply cut off mid-JSON fails with a hint to raise the limit.
+	body = `{"message":{"role":"assistant","content":"{\"status\":\"ok\",\"items\":[{\"na"},"done":true,"done_reason":"length"}`
+	_, err = a.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+	assert.ErrorContains(t, err, "OLLAMA_NUM_PREDICT")
+}
+
+func TestOllamaAnalyzeSpan(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "model does not support images"})
+	}))
+	defer server.Close()
+	c := tracingtest.Start(t)
+
+	_, err := NewOllamaAnalyzer(server.URL, "llama3").WithAPI(APIChat).
+		Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+	require.Error(t, err)
+
+	span := tracingtest.Named(c.Spans(), "ollama.chat")
+	require.NotNil(t, span)
+	assert.Equal(t, "llama3", span.Attrs["gen_ai.request.model"])
+	assert.True(t, span.Failed)
+	assert.Contains(t, span.Message, "does not support images")
+}
@@ -0,0 +1,45 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// GenerateText sends prompt to /api/generate without an image and streams
+// the reply to onDelta as Ollama produces it.
+func (a *OllamaAnalyzer) GenerateText(ctx context.Context, prompt string, onDelta func(string)) error {
+	resp, err := a.post(ctx, "/api/generate", map[string]interface{}{
+		"model":  a.model,
+		"prompt": prompt,
+		"stream": true,
+	})
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var chunk struct {
+			Response string `json:"response"`
+			Done     bool   `json:"done"`
+			Error    string `json:"error"`
+		}
+		if err := dec.Decode(&chunk); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		if chunk.Error != "" {
+			return fmt.Errorf("ollama returned error: %s", chunk.Error)
+		}
+		if chunk.Response != "" {
+			onDelta(chunk.Response)
+		}
+		if chunk.Done {
+			return nil
+		}
+	}
+}
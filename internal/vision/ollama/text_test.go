@@ -0,0 +1,65 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOllamaGenerateText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/generate", r.URL.Path)
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, true, req["stream"])
+		assert.Equal(t, "What can I cook?", req["prompt"])
+		assert.NotContains(t, req, "images")
+
+		for _, chunk := range []string{
+			`{"response":"1. Omelette","done":false}`,
+			`{"response":"\n2. Pancakes","done":false}`,
+			`{"response":"","done":true,"eval_count":12}`,
+		} {
+			_, _ = fmt.Fprintln(w, chunk)
+		}
+	}))
+	defer server.Close()
+
+	var got strings.Builder
+	err := NewOllamaAnalyzer(server.URL, "llama3").GenerateText(context.Background(), "What can I cook?", func(s string) { got.WriteString(s) })
+	require.NoError(t, err)
+	assert.Equal(t, "1. Omelette\n2. Pancakes", got.String())
+}
+
+// TestOllamaGenerateTextLongLine feeds a 200 KB NDJSON chunk, with no
+// newline after the last chunk, through a streamed reply.
+func TestOllamaGenerateTextLongLine(t *testing.T) {
+	long := strings.Repeat("o", 200<<10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintf(w, "{\"response\":%q,\"done\":false}\n", long)
+		_, _ = fmt.Fprint(w, `{"response":"!","done":true}`)
+	}))
+	defer server.Close()
+
+	var got strings.Builder
+	err := NewOllamaAnalyzer(server.URL, "llama3").GenerateText(context.Background(), "hi", func(s string) { got.WriteString(s) })
+	require.NoError(t, err)
+	assert.Equal(t, long+"!", got.String())
+}
+
+func TestOllamaGenerateTextError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintln(w, `{"error":"model runner crashed"}`)
+	}))
+	defer server.Close()
+
+	err := NewOllamaAnalyzer(server.URL, "llama3").GenerateText(context.Background(), "hi", func(string) {})
+	assert.ErrorContains(t, err, "model runner crashed")
+}
@@ -0,0 +1,299 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+// request mirrors the OpenAI chat-completions request body.
+type request struct {
+	Model    string    `json:"model"`
+	Messages []message `json:"messages"`
+	Stream   bool      `json:"stream"`
+	// StreamOptions asks for a final usage chunk; servers that don't know
+	// it ignore it.
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content []part `json:"content"`
+}
+
+type part struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+// completion is both a full response and a streamed chunk: chunks carry
+// delta, full responses message, and some servers send message in their one
+// and only chunk.
+type completion struct {
+	Choices []struct {
+		Message      *delta `json:"message"`
+		Delta        *delta `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type delta struct {
+	Content string `json:"content"`
+}
+
+// finishLength is the finish_reason for a reply cut off by the token limit.
+const finishLength = "length"
+
+// OpenAIAnalyzer talks to any server that implements the OpenAI
+// chat-completions API with image inputs, such as LM Studio, llama.cpp's
+// server or vLLM.
+type OpenAIAnalyzer struct {
+	baseURL string
+	model   string
+	apiKey  string
+	prompt  string
+	stream  bool
+	client  *http.Client
+}
+
+// NewOpenAIAnalyzer returns an analyzer for the server at baseURL, which
+// includes the API version path (e.g. http://localhost:1234/v1). apiKey may
+// be empty for servers that don't check it.
+func NewOpenAIAnalyzer(baseURL, model, apiKey string) *OpenAIAnalyzer {
+	return &OpenAIAnalyzer{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   model,
+		apiKey:  apiKey,
+		client:  &http.Client{},
+	}
+}
+
+// WithPrompt sets default instructions appended to OllamaAnalysisPrompt.
+// Servers of this kind mostly run the same small local models as Ollama, so
+// the same compact prompt describes the response shape.
+func (a *OpenAIAnalyzer) WithPrompt(prompt string) *OpenAIAnalyzer {
+	a.prompt = vision.PromptOrDefault(prompt, "")
+	return a
+}
+
+// WithStream requests a streamed (server-sent events) reply. Some servers
+// only send usage, or time out long generations, unless streaming.
+func (a *OpenAIAnalyzer) WithStream(stream bool) *OpenAIAnalyzer {
+	a.stream = stream
+	return a
+}
+
+func (a *OpenAIAnalyzer) Analyze(ctx context.Context, r io.Reader, mimeType string) (*vision.AnalysisResult, error) {
+	return a.AnalyzeWithPrompt(ctx, r, mimeType, "")
+}
+
+// AnalyzeWithPrompt is Analyze with custom instructions in place of those set
+// by WithPrompt, appended to OllamaAnalysisPrompt.
+func (a *OpenAIAnalyzer) AnalyzeWithPrompt(ctx context.Context, r io.Reader, mimeType, prompt string) (*vision.AnalysisResult, error) {
+	imageData, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	text := vision.OllamaAnalysisPrompt
+	if p := vision.PromptOrDefault(prompt, a.prompt); p != "" {
+		text += "\n\n" + p
+	}
+	body := request{
+		Model: a.model,
+		Messages: []message{{
+			Role: "user",
+			Content: []part{
+				{Type: "text", Text: text},
+				{Type: "image_url", ImageURL: &imageURL{
+					URL: "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(imageData),
+				}},
+			},
+		}},
+		Stream: a.stream,
+	}
+	if a.stream {
+		body.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+
+	reply, err := a.complete(ctx, body, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := vision.ParseJSONResponse(reply.text)
+	if err != nil {
+		if reply.truncated {
+			return nil, fmt.Errorf("failed to parse vision response cut off by the server's token limit: %w", err)
+		}
+		return nil, fmt.Errorf("failed to parse vision response: %w", err)
+	}
+	result.Truncated = reply.truncated
+	result.StopReason = reply.stopReason
+	result.Usage = reply.usage
+
+	if result.Status == vision.StatusUnclear {
+		return nil, fmt.Errorf("image is unclear: please retake the photo")
+	}
+
+	return result, nil
+}
+
+// complete sends body to the chat-completions endpoint and reads the reply,
+// passing each piece of streamed text to onDelta if it is not nil.
+func (a *OpenAIAnalyzer) complete(ctx context.Context, body request, onDelta func(string)) (reply, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return reply{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return reply{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return reply{}, fmt.Errorf("failed to call openai-compatible server: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			slog.Error("failed to close openai-compatible response body", "error", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return reply{}, fmt.Errorf("openai-compatible server returned status %d: %s", resp.StatusCode, errBody)
+	}
+	return readReply(resp, onDelta)
+}
+
+// GenerateText sends prompt as a text-only user message, asking for a
+// streamed reply, and passes the text to onDelta as it arrives.
+func (a *OpenAIAnalyzer) GenerateText(ctx context.Context, prompt string, onDelta func(string)) error {
+	_, err := a.complete(ctx, request{
+		Model:    a.model,
+		Messages: []message{{Role: "user", Content: []part{{Type: "text", Text: prompt}}}},
+		Stream:   true,
+	}, onDelta)
+	return err
+}
+
+// reply is the assembled model output.
+type reply struct {
+	text       string
+	truncated  bool
+	stopReason string
+	usage      vision.Usage
+}
+
+// readReply reads a completion as server-sent events when the server sent an
+// event stream, or as a single JSON body otherwise; servers don't all honour
+// the stream flag either way. A non-nil onDelta is called with each piece of
+// text as it is read.
+func readReply(resp *http.Response, onDelta func(string)) (reply, error) {
+	var rep reply
+	var text strings.Builder
+	add := func(c completion) error {
+		if c.Error != nil {
+			return fmt.Errorf("openai-compatible server returned error: %s", c.Error.Message)
+		}
+		for _, ch := range c.Choices {
+			var piece string
+			if ch.Delta != nil {
+				piece = ch.Delta.Content
+			} else if ch.Message != nil {
+				piece = ch.Message.Content
+			}
+			text.WriteString(piece)
+			if onDelta != nil && piece != "" {
+				onDelta(piece)
+			}
+			if ch.FinishReason != "" {
+				rep.stopReason = ch.FinishReason
+			}
+			if ch.FinishReason == finishLength {
+				rep.truncated = true
+			}
+		}
+		if c.Usage != nil {
+			rep.usage = vision.Usage{InputTokens: c.Usage.PromptTokens, OutputTokens: c.Usage.CompletionTokens}
+		}
+		return nil
+	}
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		var c completion
+		if err := json.NewDecoder(resp.Body).Decode(&c); err != nil {
+			return reply{}, fmt.Errorf("failed to decode response: %w", err)
+		}
+		if err := add(c); err != nil {
+			return reply{}, err
+		}
+		rep.text = text.String()
+		return rep, nil
+	}
+
+	// Read events until [DONE] or the end of the body, whichever comes
+	// first: not every server sends [DONE]. Events are single data lines
+	// in practice; other fields (event:, id:, comments) are ignored.
+	br := bufio.NewReader(resp.Body)
+	for {
+		line, err := vision.ReadLine(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return reply{}, fmt.Errorf("failed to read response stream: %w", err)
+		}
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		if data == "" {
+			continue
+		}
+		var c completion
+		if err := json.Unmarshal([]byte(data), &c); err != nil {
+			return reply{}, fmt.Errorf("failed to decode stream event: %w", err)
+		}
+		if err := add(c); err != nil {
+			return reply{}, err
+		}
+	}
+	rep.text = text.String()
+	return rep, nil
+}
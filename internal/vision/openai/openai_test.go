@@ -0,0 +1,198 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+const okReply = `{\"status\":\"ok\",\"items\":[{\"name\":\"Milk\",\"quantity\":2}]}`
+
+func analyze(t *testing.T, a *OpenAIAnalyzer) (*vision.AnalysisResult, error) {
+	t.Helper()
+	return a.Analyze(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg")
+}
+
+func TestOpenAIAnalyze(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer sk-local", r.Header.Get("Authorization"))
+
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "qwen2.5-vl-7b", req["model"])
+		assert.Equal(t, false, req["stream"])
+		content := req["messages"].([]any)[0].(map[string]any)["content"].([]any)
+		img := content[1].(map[string]any)["image_url"].(map[string]any)["url"].(string)
+		assert.Equal(t, "data:image/jpeg;base64,/9g=", img)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"` + okReply + `"},"finish_reason":"stop"}],` +
+			`"usage":{"prompt_tokens":800,"completion_tokens":30}}`))
+	}))
+	defer server.Close()
+
+	result, err := analyze(t, NewOpenAIAnalyzer(server.URL+"/v1/", "qwen2.5-vl-7b", "sk-local"))
+	require.NoError(t, err)
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, "Milk", result.Items[0].Name)
+	assert.Equal(t, "2", result.Items[0].Quantity)
+	assert.Equal(t, vision.Usage{InputTokens: 800, OutputTokens: 30}, result.Usage)
+	assert.False(t, result.Truncated)
+}
+
+func TestOpenAIAnalyzeNoAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"` + okReply + `"}}]}`))
+	}))
+	defer server.Close()
+
+	_, err := analyze(t, NewOpenAIAnalyzer(server.URL, "m", ""))
+	require.NoError(t, err)
+}
+
+// TestOpenAIAnalyzeStreamLongLine feeds a 200 KB event on a single line,
+// without a trailing newline, through a streamed analysis.
+func TestOpenAIAnalyzeStreamLongLine(t *testing.T) {
+	name := strings.Repeat("m", 200<<10)
+	content, err := json.Marshal(`{"status":"ok","items":[{"name":"` + name + `"}]}`)
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"choices":[{"delta":{"content":` + string(content) + `},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	result, err := analyze(t, NewOpenAIAnalyzer(server.URL, "m", "").WithStream(true))
+	require.NoError(t, err)
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, name, result.Items[0].Name)
+}
+
+func TestOpenAIAnalyzeStream(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "deltas with done",
+			body: "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"{\\\"status\\\":\\\"ok\\\",\"}}]}\n\n" +
+				": keep-alive\n\n" +
+				"data: {\"choices\":[{\"delta\":{\"content\":\"\\\"items\\\":[{\\\"name\\\":\\\"Milk\\\",\\\"quantity\\\":2}]}\"},\"finish_reason\":\"stop\"}]}\n\n" +
+				"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":800,\"completion_tokens\":30}}\n\n" +
+				"data: [DONE]\n\n",
+		},
+		{
+			name: "no done marker",
+			body: "data: {\"choices\":[{\"delta\":{\"content\":\"" + okReply + "\"},\"finish_reason\":\"eos\"}]}\n\n",
+		},
+		{
+			name: "whole message in one chunk",
+			body: "data:{\"choices\":[{\"message\":{\"content\":\"" + okReply + "\"},\"finish_reason\":null}]}\n" +
+				"data: [DONE]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req map[string]any
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				assert.Equal(t, true, req["stream"])
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			result, err := analyze(t, NewOpenAIAnalyzer(server.URL, "m", "").WithStream(true))
+			require.NoError(t, err)
+			require.Len(t, result.Items, 1)
+			assert.Equal(t, "Milk", result.Items[0].Name)
+		})
+	}
+}
+
+func TestOpenAIGenerateText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+		assert.Equal(t, []message{{Role: "user", Content: []part{{Type: "text", Text: "What can I cook?"}}}}, req.Messages)
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"1. Omelette\"}}]}\n\n" +
+			"data: {\"choices\":[{\"delta\":{\"content\":\"\\n2. Pancakes\"}}]}\n\n" +
+			"data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	var deltas []string
+	err := NewOpenAIAnalyzer(server.URL, "m", "").GenerateText(context.Background(), "What can I cook?", func(s string) { deltas = append(deltas, s) })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1. Omelette", "\n2. Pancakes"}, deltas)
+}
+
+func TestOpenAIAnalyzeTruncated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"status\":\"ok\",\"items\":[{\"name\":\"Mi"},"finish_reason":"length"}]}`))
+	}))
+	defer server.Close()
+
+	_, err := analyze(t, NewOpenAIAnalyzer(server.URL, "m", ""))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "token limit")
+}
+
+func TestOpenAIAnalyzeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		ctype   string
+		body    string
+		wantErr string
+	}{
+		{"http status", http.StatusNotFound, "application/json", `{"error":{"message":"model not loaded"}}`, "status 404"},
+		{"stream error event", http.StatusOK, "text/event-stream", "data: {\"error\":{\"message\":\"out of memory\"}}\n\n", "out of memory"},
+		{"bad json", http.StatusOK, "application/json", `not json`, "failed to decode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.ctype)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := analyze(t, NewOpenAIAnalyzer(server.URL, "m", ""))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestOpenAIAnalyzeWithPrompt(t *testing.T) {
+	var got string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req request
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		got = req.Messages[0].Content[0].Text
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"` + okReply + `"}}]}`))
+	}))
+	defer server.Close()
+
+	a := NewOpenAIAnalyzer(server.URL, "m", "").WithPrompt("Spices only.")
+	_, err := a.AnalyzeWithPrompt(context.Background(), bytes.NewReader([]byte{0xFF, 0xD8}), "image/jpeg", "Freezer items.")
+	require.NoError(t, err)
+	assert.True(t, len(got) > len(vision.OllamaAnalysisPrompt))
+	assert.Contains(t, got, "Freezer items.")
+	assert.NotContains(t, got, "Spices only.")
+}
@@ -0,0 +1,118 @@
+// Package sse decodes server-sent event streams, the framing the Claude
+// Messages API uses for streamed replies.
+//
+// It follows the WHATWG event stream format: lines end in LF, CRLF or CR;
+// an event's data may span several data: lines, joined with newlines; a
+// blank line ends an event; lines starting with a colon are comments.
+package sse
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// defaultType is the type of an event without an event: field.
+const defaultType = "message"
+
+// Event is one dispatched event.
+type Event struct {
+	// Type is the event: field, or "message" if the event had none.
+	Type string
+	// Data is the event's data: lines joined with "\n".
+	Data string
+	// ID is the last id: field seen in the stream, which carries over to
+	// later events.
+	ID string
+}
+
+// Decoder reads events from a stream.
+type Decoder struct {
+	r       *bufio.Reader
+	pending []string // lines read but not yet parsed, after splitting on CR
+	lastID  string
+}
+
+// NewDecoder returns a Decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Next returns the next event, or io.EOF once the stream has ended. Events
+// with no data lines are skipped, as the format requires. Unlike the format,
+// an event the stream ends in the middle of is still returned, since some
+// servers close the connection straight after the last data line.
+func (d *Decoder) Next() (Event, error) {
+	var (
+		typ     string
+		data    strings.Builder
+		hasData bool
+	)
+	dispatch := func() Event {
+		if typ == "" {
+			typ = defaultType
+		}
+		return Event{Type: typ, Data: data.String(), ID: d.lastID}
+	}
+	for {
+		line, err := d.readLine()
+		if err == io.EOF && hasData {
+			return dispatch(), nil
+		}
+		if err != nil {
+			return Event{}, err
+		}
+
+		if line == "" {
+			if hasData {
+				return dispatch(), nil
+			}
+			typ = ""
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			typ = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				d.lastID = value
+			}
+		}
+		// retry: and unknown fields are ignored.
+	}
+}
+
+// readLine returns the next line without its ending. A bare CR ends a line
+// too, so one read may hold several lines; the rest wait in pending.
+func (d *Decoder) readLine() (string, error) {
+	if len(d.pending) > 0 {
+		line := d.pending[0]
+		d.pending = d.pending[1:]
+		return line, nil
+	}
+	line, err := d.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	if strings.Contains(line, "\r") {
+		parts := strings.Split(line, "\r")
+		line, d.pending = parts[0], parts[1:]
+	}
+	return line, nil
+}
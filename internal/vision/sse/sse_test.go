@@ -0,0 +1,103 @@
+package sse
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoder(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []Event
+	}{
+		{
+			name:   "single data line",
+			stream: "data: {\"a\":1}\n\n",
+			want:   []Event{{Type: "message", Data: `{"a":1}`}},
+		},
+		{
+			name:   "event type",
+			stream: "event: content_block_delta\ndata: x\n\n",
+			want:   []Event{{Type: "content_block_delta", Data: "x"}},
+		},
+		{
+			name:   "multi-line data is joined with newlines",
+			stream: "data: {\"a\":\ndata: 1}\n\n",
+			want:   []Event{{Type: "message", Data: "{\"a\":\n1}"}},
+		},
+		{
+			name:   "CRLF line endings",
+			stream: "event: ping\r\ndata: {}\r\n\r\nevent: message_stop\r\ndata: {}\r\n\r\n",
+			want:   []Event{{Type: "ping", Data: "{}"}, {Type: "message_stop", Data: "{}"}},
+		},
+		{
+			name:   "bare CR line endings",
+			stream: "event: ping\rdata: a\rdata: b\r\rdata: c\r\r",
+			want:   []Event{{Type: "ping", Data: "a\nb"}, {Type: "message", Data: "c"}},
+		},
+		{
+			name:   "only one leading space is removed",
+			stream: "data:no space\ndata:  two spaces\n\n",
+			want:   []Event{{Type: "message", Data: "no space\n two spaces"}},
+		},
+		{
+			name:   "comments and unknown fields are ignored",
+			stream: ": keep-alive\nretry: 1000\nfoo: bar\ndata: x\n\n",
+			want:   []Event{{Type: "message", Data: "x"}},
+		},
+		{
+			name:   "field without a colon",
+			stream: "data\n\ndata: x\n\n",
+			want:   []Event{{Type: "message", Data: ""}, {Type: "message", Data: "x"}},
+		},
+		{
+			name:   "event without data is skipped and its type forgotten",
+			stream: "event: ping\n\ndata: x\n\n",
+			want:   []Event{{Type: "message", Data: "x"}},
+		},
+		{
+			name:   "id carries over",
+			stream: "id: 7\ndata: a\n\ndata: b\n\nid\ndata: c\n\n",
+			want:   []Event{{Type: "message", Data: "a", ID: "7"}, {Type: "message", Data: "b", ID: "7"}, {Type: "message", Data: "c"}},
+		},
+		{
+			name:   "unterminated last event",
+			stream: "data: a\n\nevent: error\ndata: b",
+			want:   []Event{{Type: "message", Data: "a"}, {Type: "error", Data: "b"}},
+		},
+		{
+			name:   "blank lines only",
+			stream: "\n\n\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tt.stream))
+			var got []Event
+			for {
+				ev, err := dec.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				got = append(got, ev)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDecoderLongLine(t *testing.T) {
+	long := strings.Repeat("x", 200<<10)
+	dec := NewDecoder(strings.NewReader("data: " + long + "\n\n"))
+	ev, err := dec.Next()
+	require.NoError(t, err)
+	assert.Equal(t, long, ev.Data)
+	_, err = dec.Next()
+	assert.Equal(t, io.EOF, err)
+}
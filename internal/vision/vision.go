@@ -3,6 +3,8 @@ package vision
 import (
 	"context"
 	"io"
+	"strings"
+	"time"
 )
 
 // OllamaAnalysisPrompt is a compact example-based prompt for smaller local models.
@@ -53,6 +55,21 @@ Status meanings:
 // ClaudeUserPrompt is the short user-turn message sent alongside the image.
 const ClaudeUserPrompt = `List every food item visible in this photo. Be as specific as possible — include brand names where visible (e.g. "Natrel Whole Milk" not "Milk", "Kraft Peanut Butter" not "Peanut Butter"). List every individual item you can see, do not group or summarise.`
 
+// ClaudeToolSystemPrompt replaces ClaudeSystemPrompt when Claude reports items
+// through tool calls rather than a JSON reply. The tools' input schemas carry
+// the field definitions, so only the procedure is described here.
+const ClaudeToolSystemPrompt = `You analyse food storage area photos and record what you find with tools.
+
+Call add_item once for each distinct food product you can identify, with:
+- name: the food product name (e.g. "Whole Milk", "Cheddar Cheese", "Orange Juice"), exactly as it should be shown
+- quantity: your best-estimate count of how many of this item are visible (e.g. 1, 2, 6). Must be a whole number.
+- bbox: normalized bounding box [x1, y1, x2, y2] where 0,0 is top-left and 1,1 is bottom-right. Enclose the item as tightly as possible.
+
+If you find no food items, call report_status instead:
+- no_items : valid food storage area but nothing identifiable
+- not_food : image is not a food storage area
+- unclear  : image is too blurry, dark, or otherwise unreadable`
+
 // GeminiSystemPrompt is the system instruction for Gemini API calls.
 // It is tuned for Gemini's known miss patterns: grouping, freezer inference,
 // condiment bottles, and non-food items in food storage areas.
@@ -112,10 +129,62 @@ type VisionAnalyzer interface {
 	Analyze(ctx context.Context, r io.Reader, mimeType string) (*AnalysisResult, error)
 }
 
+// PromptAnalyzer is implemented by analyzers that accept a caller-supplied
+// prompt, such as an area's custom instructions, in place of their default
+// user prompt. The response format is still fixed by the backend's own
+// instructions, so a custom prompt can't break result parsing.
+type PromptAnalyzer interface {
+	AnalyzeWithPrompt(ctx context.Context, r io.Reader, mimeType, prompt string) (*AnalysisResult, error)
+}
+
+// Checker is implemented by analyzers that can verify their backend is
+// usable before the first upload, e.g. that the model is installed or the
+// API key is accepted.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// TextGenerator is implemented by backends that can also answer a text-only
+// prompt, such as asking for recipe ideas. onDelta receives the reply in
+// pieces as the model produces it; GenerateText returns once it is complete.
+type TextGenerator interface {
+	GenerateText(ctx context.Context, prompt string, onDelta func(text string)) error
+}
+
+// PromptOrDefault returns prompt trimmed of surrounding whitespace, or def
+// when nothing is left.
+func PromptOrDefault(prompt, def string) string {
+	if p := strings.TrimSpace(prompt); p != "" {
+		return p
+	}
+	return def
+}
+
 type AnalysisResult struct {
 	Status      AnalysisStatus
 	Items       []DetectedItem
 	RawResponse string
+	// Truncated reports that the backend stopped at its output limit, so
+	// Items may be missing entries.
+	Truncated bool
+	// StopReason is why the backend stopped generating, as it reported it,
+	// such as "end_turn" or "max_tokens" from Claude and "stop" or
+	// "length" from Ollama. Empty if not reported.
+	StopReason string
+	// Usage is what the analysis consumed, as far as the backend reports it.
+	Usage Usage
+}
+
+// Usage is the token count and generation time of one analysis. Fields the
+// backend doesn't report are zero.
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+	Duration     time.Duration
+	// CacheWriteTokens and CacheReadTokens are prompt tokens written to and
+	// read from the backend's prompt cache. InputTokens leaves them out.
+	CacheWriteTokens int
+	CacheReadTokens  int
 }
 
 type DetectedItem struct {
@@ -0,0 +1,189 @@
+// Package visiontest provides vision backends for tests: Recording returns a
+// fixed result and remembers what it was sent, Blocking holds each call until
+// released, and Scripted plays back a sequence of results and errors. Each
+// implements vision.VisionAnalyzer, vision.PromptAnalyzer and
+// vision.TextGenerator and is safe for concurrent use.
+package visiontest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+// ErrScriptExhausted is returned by Scripted once every step has been used.
+var ErrScriptExhausted = errors.New("visiontest: script exhausted")
+
+// Recording returns Result, or Err, from every call and records the last
+// image and prompt it was given. Text is the reply to GenerateText.
+type Recording struct {
+	Result *vision.AnalysisResult
+	Text   string
+	Err    error
+
+	mu         sync.Mutex
+	calls      int
+	lastBytes  []byte
+	lastPrompt string
+}
+
+func (r *Recording) Analyze(ctx context.Context, rd io.Reader, mimeType string) (*vision.AnalysisResult, error) {
+	return r.AnalyzeWithPrompt(ctx, rd, mimeType, "")
+}
+
+func (r *Recording) AnalyzeWithPrompt(_ context.Context, rd io.Reader, _, prompt string) (*vision.AnalysisResult, error) {
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, fmt.Errorf("visiontest: read image: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	r.lastBytes = data
+	r.lastPrompt = prompt
+	return r.Result, r.Err
+}
+
+func (r *Recording) GenerateText(_ context.Context, prompt string, onDelta func(string)) error {
+	r.mu.Lock()
+	r.calls++
+	r.lastPrompt = prompt
+	r.mu.Unlock()
+	if r.Err != nil {
+		return r.Err
+	}
+	onDelta(r.Text)
+	return nil
+}
+
+// LastBytes returns the image of the most recent analysis.
+func (r *Recording) LastBytes() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastBytes
+}
+
+// LastPrompt returns the prompt of the most recent call, empty for Analyze.
+func (r *Recording) LastPrompt() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastPrompt
+}
+
+// Calls returns how many calls have been made.
+func (r *Recording) Calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+// Blocking closes Ready when first called, then holds every call until
+// Release is closed or the call's context is done, so a test can inspect
+// the server mid-analysis. Create it with NewBlocking.
+type Blocking struct {
+	Ready   chan struct{}
+	Release chan struct{}
+	Result  *vision.AnalysisResult
+	Text    string
+
+	once sync.Once
+}
+
+func NewBlocking(result *vision.AnalysisResult) *Blocking {
+	return &Blocking{Ready: make(chan struct{}), Release: make(chan struct{}), Result: result}
+}
+
+func (b *Blocking) wait(ctx context.Context) error {
+	b.once.Do(func() { close(b.Ready) })
+	select {
+	case <-b.Release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Blocking) Analyze(ctx context.Context, _ io.Reader, _ string) (*vision.AnalysisResult, error) {
+	if err := b.wait(ctx); err != nil {
+		return nil, err
+	}
+	return b.Result, nil
+}
+
+func (b *Blocking) AnalyzeWithPrompt(ctx context.Context, r io.Reader, mimeType, _ string) (*vision.AnalysisResult, error) {
+	return b.Analyze(ctx, r, mimeType)
+}
+
+func (b *Blocking) GenerateText(ctx context.Context, _ string, onDelta func(string)) error {
+	if err := b.wait(ctx); err != nil {
+		return err
+	}
+	onDelta(b.Text)
+	return nil
+}
+
+// Step is one reply of a Scripted backend: Err if set, otherwise Result
+// for an analysis or Text for GenerateText.
+type Step struct {
+	Result *vision.AnalysisResult
+	Text   string
+	Err    error
+}
+
+// Scripted answers each call, analysis or text, with the next of its steps
+// and fails with ErrScriptExhausted once they run out.
+type Scripted struct {
+	mu    sync.Mutex
+	steps []Step
+	next  int
+}
+
+func NewScripted(steps ...Step) *Scripted {
+	return &Scripted{steps: steps}
+}
+
+func (s *Scripted) step() (Step, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.next >= len(s.steps) {
+		return Step{}, ErrScriptExhausted
+	}
+	st := s.steps[s.next]
+	s.next++
+	return st, st.Err
+}
+
+func (s *Scripted) Analyze(_ context.Context, rd io.Reader, _ string) (*vision.AnalysisResult, error) {
+	if _, err := io.Copy(io.Discard, rd); err != nil {
+		return nil, fmt.Errorf("visiontest: read image: %w", err)
+	}
+	st, err := s.step()
+	if err != nil {
+		return nil, err
+	}
+	return st.Result, nil
+}
+
+func (s *Scripted) AnalyzeWithPrompt(ctx context.Context, r io.Reader, mimeType, _ string) (*vision.AnalysisResult, error) {
+	return s.Analyze(ctx, r, mimeType)
+}
+
+func (s *Scripted) GenerateText(_ context.Context, _ string, onDelta func(string)) error {
+	st, err := s.step()
+	if err != nil {
+		return err
+	}
+	onDelta(st.Text)
+	return nil
+}
+
+// Remaining returns how many steps are left.
+func (s *Scripted) Remaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.steps) - s.next
+}
@@ -0,0 +1,88 @@
+package visiontest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/vision"
+)
+
+var (
+	_ vision.VisionAnalyzer = (*Recording)(nil)
+	_ vision.PromptAnalyzer = (*Recording)(nil)
+	_ vision.TextGenerator  = (*Recording)(nil)
+	_ vision.VisionAnalyzer = (*Blocking)(nil)
+	_ vision.PromptAnalyzer = (*Blocking)(nil)
+	_ vision.TextGenerator  = (*Blocking)(nil)
+	_ vision.VisionAnalyzer = (*Scripted)(nil)
+	_ vision.PromptAnalyzer = (*Scripted)(nil)
+	_ vision.TextGenerator  = (*Scripted)(nil)
+)
+
+func TestRecording(t *testing.T) {
+	want := &vision.AnalysisResult{Status: vision.StatusOK}
+	r := &Recording{Result: want, Text: "pasta"}
+	ctx := context.Background()
+
+	got, err := r.AnalyzeWithPrompt(ctx, strings.NewReader("img"), "image/jpeg", "door only")
+	require.NoError(t, err)
+	assert.Same(t, want, got)
+	assert.Equal(t, "img", string(r.LastBytes()))
+	assert.Equal(t, "door only", r.LastPrompt())
+
+	var text string
+	require.NoError(t, r.GenerateText(ctx, "ideas?", func(d string) { text += d }))
+	assert.Equal(t, "pasta", text)
+	assert.Equal(t, 2, r.Calls())
+}
+
+func TestBlocking(t *testing.T) {
+	b := NewBlocking(&vision.AnalysisResult{Status: vision.StatusNoItems})
+	done := make(chan *vision.AnalysisResult)
+	go func() {
+		res, _ := b.Analyze(context.Background(), strings.NewReader("img"), "image/jpeg")
+		done <- res
+	}()
+	<-b.Ready
+	select {
+	case <-done:
+		t.Fatal("returned before Release was closed")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(b.Release)
+	assert.Equal(t, vision.StatusNoItems, (<-done).Status)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b2 := NewBlocking(nil)
+	_, err := b2.Analyze(ctx, strings.NewReader(""), "image/jpeg")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestScripted(t *testing.T) {
+	boom := errors.New("model overloaded")
+	s := NewScripted(
+		Step{Err: boom},
+		Step{Result: &vision.AnalysisResult{Status: vision.StatusOK}},
+		Step{Text: "soup"},
+	)
+	ctx := context.Background()
+
+	_, err := s.Analyze(ctx, strings.NewReader("img"), "image/jpeg")
+	assert.ErrorIs(t, err, boom)
+	res, err := s.Analyze(ctx, strings.NewReader("img"), "image/jpeg")
+	require.NoError(t, err)
+	assert.Equal(t, vision.StatusOK, res.Status)
+	var text string
+	require.NoError(t, s.GenerateText(ctx, "", func(d string) { text += d }))
+	assert.Equal(t, "soup", text)
+
+	assert.Zero(t, s.Remaining())
+	_, err = s.Analyze(ctx, strings.NewReader("img"), "image/jpeg")
+	assert.ErrorIs(t, err, ErrScriptExhausted)
+}
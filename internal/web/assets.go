@@ -0,0 +1,75 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// assets maps static files to content-hashed URLs, so a changed file gets a
+// new URL and browsers can cache every version indefinitely.
+type assets struct {
+	fsys  fs.FS
+	urls  map[string]string // file name -> "/static/app.<hash>.css"
+	files map[string]string // hashed name -> file name
+	etags map[string]string // hashed name -> quoted content hash
+}
+
+// loadAssets hashes every file at the top level of fsys.
+func loadAssets(fsys fs.FS) (*assets, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list static assets: %w", err)
+	}
+	a := &assets{
+		fsys:  fsys,
+		urls:  make(map[string]string, len(entries)),
+		files: make(map[string]string, len(entries)),
+		etags: make(map[string]string, len(entries)),
+	}
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) == ".go" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read static asset %q: %w", e.Name(), err)
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:5])
+		ext := path.Ext(e.Name())
+		hashed := strings.TrimSuffix(e.Name(), ext) + "." + hash + ext
+		a.urls[e.Name()] = "/static/" + hashed
+		a.files[hashed] = e.Name()
+		a.etags[hashed] = `"` + hash + `"`
+	}
+	return a, nil
+}
+
+// url returns the hashed URL for a static file. It is the "asset" template
+// func; an unknown name fails the render rather than emitting a dead link.
+func (a *assets) url(name string) (string, error) {
+	u, ok := a.urls[name]
+	if !ok {
+		return "", fmt.Errorf("unknown static asset %q", name)
+	}
+	return u, nil
+}
+
+// handleStatic serves a static file by its hashed name. The content behind a
+// hashed name never changes, so it is cached for a year.
+func (s *Server) handleStatic(w http.ResponseWriter, r *http.Request) {
+	hashed := r.PathValue("file")
+	name, ok := s.assets.files[hashed]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("ETag", s.assets.etags[hashed])
+	http.ServeFileFS(w, r, s.assets.fsys, name)
+}
@@ -0,0 +1,173 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// TrustedProxies are the peers whose X-Forwarded-For, X-Real-IP and
+// X-Forwarded-Proto headers are believed. Build it with
+// ParseTrustedProxies; the zero value trusts nobody.
+type TrustedProxies struct {
+	prefixes []netip.Prefix
+	unix     bool // connections over a Unix socket
+}
+
+// ParseTrustedProxies parses CIDR ranges such as "10.0.0.0/8", single
+// addresses, and "unix" for any peer connected over a Unix socket.
+func ParseTrustedProxies(entries []string) (TrustedProxies, error) {
+	var tp TrustedProxies
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "unix" {
+			tp.unix = true
+			continue
+		}
+		if p, err := netip.ParsePrefix(e); err == nil {
+			tp.prefixes = append(tp.prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(e)
+		if err != nil {
+			return TrustedProxies{}, fmt.Errorf("%q is not a CIDR range, an IP address or \"unix\"", e)
+		}
+		tp.prefixes = append(tp.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return tp, nil
+}
+
+func (tp TrustedProxies) contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range tp.prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithTrustedProxies takes the client address and scheme of requests from
+// proxies from their forwarding headers. Requests from anyone else are
+// attributed to the connection's peer whatever headers they carry.
+func (s *Server) WithTrustedProxies(tp TrustedProxies) *Server {
+	s.trustedProxies = tp
+	return s
+}
+
+// client is who a request came from, after looking through trusted proxies.
+type client struct {
+	ip     string // the peer's address for a Unix socket, usually "@"
+	scheme string // "http" or "https"
+}
+
+type clientCtxKey struct{}
+
+// clientInfo stores the request's client in its context, for
+// requestLogger, rate limiting and links back to the server.
+func clientInfo(tp TrustedProxies, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientCtxKey{}, tp.resolve(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// resolve works out r's client. For a trusted peer it is the nearest
+// untrusted address in X-Forwarded-For, counting back from the peer, so
+// addresses a client put in the header itself are passed over; failing
+// that X-Real-IP, and failing that the peer.
+func (tp TrustedProxies) resolve(r *http.Request) client {
+	c := client{ip: r.RemoteAddr, scheme: "http"}
+	if r.TLS != nil {
+		c.scheme = "https"
+	}
+	peer, err := netip.ParseAddrPort(r.RemoteAddr)
+	switch {
+	case err == nil:
+		c.ip = peer.Addr().Unmap().String()
+		if !tp.contains(peer.Addr()) {
+			return c
+		}
+	case isUnixPeer(r.RemoteAddr):
+		if !tp.unix {
+			return c
+		}
+	default:
+		c.ip = hostOnly(r.RemoteAddr)
+		return c
+	}
+
+	if ip, ok := tp.forwardedFor(r.Header.Values("X-Forwarded-For")); ok {
+		c.ip = ip
+	} else if ip, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		c.ip = ip.Unmap().String()
+	}
+	proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+	if proto = strings.ToLower(strings.TrimSpace(proto)); proto == "http" || proto == "https" {
+		c.scheme = proto
+	}
+	return c
+}
+
+// forwardedFor returns the client address in X-Forwarded-For header
+// values: the last one that isn't a trusted proxy. If every address is
+// trusted it returns the first. It stops at an entry that isn't an
+// address, returning the hop after it, since anything further left can't
+// be relied on.
+func (tp TrustedProxies) forwardedFor(values []string) (string, bool) {
+	var hops []string
+	for _, v := range values {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	found := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		found = addr.Unmap().String()
+		if !tp.contains(addr) {
+			break
+		}
+	}
+	return found, found != ""
+}
+
+// isUnixPeer reports whether remoteAddr is that of a Unix socket
+// connection, which net/http leaves empty or as "@".
+func isUnixPeer(remoteAddr string) bool {
+	return remoteAddr == "" || remoteAddr == "@"
+}
+
+// clientOf returns the client stored by clientInfo. Requests that didn't
+// pass through it, such as in handler tests, are attributed to their peer.
+func clientOf(r *http.Request) client {
+	if c, ok := r.Context().Value(clientCtxKey{}).(client); ok {
+		return c
+	}
+	return TrustedProxies{}.resolve(r)
+}
+
+// clientIP returns the address the request came from, looking through
+// trusted proxies.
+func clientIP(r *http.Request) string {
+	return clientOf(r).ip
+}
+
+// clientScheme returns "https" when the client reached the server, or the
+// trusted proxy in front of it, over TLS.
+func clientScheme(r *http.Request) string {
+	return clientOf(r).scheme
+}
+
+// hostOnly strips the port from a host:port address, leaving anything else
+// unchanged.
+func hostOnly(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
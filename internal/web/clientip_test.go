@@ -0,0 +1,147 @@
+package web
+
+import (
+	"bytes"
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	_, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "::1", "unix"})
+	require.NoError(t, err)
+
+	_, err = ParseTrustedProxies([]string{"traefik"})
+	assert.ErrorContains(t, err, "traefik")
+}
+
+func TestResolveClient(t *testing.T) {
+	tp, err := ParseTrustedProxies([]string{"10.0.0.0/8", "unix"})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		tls        bool
+		wantIP     string
+		wantScheme string
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "192.0.2.7:5555",
+			wantIP:     "192.0.2.7", wantScheme: "http",
+		},
+		{
+			name:       "untrusted peer can't spoof its address",
+			remoteAddr: "192.0.2.7:5555",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.9", "X-Real-IP": "203.0.113.9", "X-Forwarded-Proto": "https"},
+			wantIP:     "192.0.2.7", wantScheme: "http",
+		},
+		{
+			name:       "untrusted peer over TLS",
+			remoteAddr: "192.0.2.7:5555",
+			tls:        true,
+			headers:    map[string]string{"X-Forwarded-Proto": "http"},
+			wantIP:     "192.0.2.7", wantScheme: "https",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.2:40000",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.9", "X-Forwarded-Proto": "https"},
+			wantIP:     "203.0.113.9", wantScheme: "https",
+		},
+		{
+			name:       "client-supplied entries left of the real client are ignored",
+			remoteAddr: "10.0.0.2:40000",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4, 203.0.113.9"},
+			wantIP:     "203.0.113.9", wantScheme: "http",
+		},
+		{
+			name:       "chain of trusted proxies",
+			remoteAddr: "10.0.0.2:40000",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.9, 10.1.1.1, 10.0.0.3"},
+			wantIP:     "203.0.113.9", wantScheme: "http",
+		},
+		{
+			name:       "garbage stops the walk",
+			remoteAddr: "10.0.0.2:40000",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.9, not-an-ip, 10.0.0.3"},
+			wantIP:     "10.0.0.3", wantScheme: "http",
+		},
+		{
+			name:       "X-Real-IP without X-Forwarded-For",
+			remoteAddr: "10.0.0.2:40000",
+			headers:    map[string]string{"X-Real-IP": "203.0.113.9"},
+			wantIP:     "203.0.113.9", wantScheme: "http",
+		},
+		{
+			name:       "trusted proxy without headers",
+			remoteAddr: "10.0.0.2:40000",
+			wantIP:     "10.0.0.2", wantScheme: "http",
+		},
+		{
+			name:       "unknown X-Forwarded-Proto is ignored",
+			remoteAddr: "10.0.0.2:40000",
+			headers:    map[string]string{"X-Forwarded-Proto": "gopher"},
+			wantIP:     "10.0.0.2", wantScheme: "http",
+		},
+		{
+			name:       "IPv4-mapped IPv6 peer",
+			remoteAddr: "[::ffff:10.0.0.2]:40000",
+			headers:    map[string]string{"X-Forwarded-For": "2001:db8::1", "X-Forwarded-Proto": "HTTPS, http"},
+			wantIP:     "2001:db8::1", wantScheme: "https",
+		},
+		{
+			name:       "unix socket proxy",
+			remoteAddr: "@",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.9"},
+			wantIP:     "203.0.113.9", wantScheme: "http",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			c := tp.resolve(req)
+			assert.Equal(t, tc.wantIP, c.ip)
+			assert.Equal(t, tc.wantScheme, c.scheme)
+		})
+	}
+}
+
+func TestUnixPeerUntrustedByDefault(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "@"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	assert.Equal(t, "@", TrustedProxies{}.resolve(req).ip)
+}
+
+func TestRequestLogUsesClientIP(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	tp, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	srv := newOverrideTestServer(&fakeOverrideService{})
+	srv.logger = logger
+	srv.WithTrustedProxies(tp)
+
+	req := httptest.NewRequest(http.MethodGet, "/overrides", nil)
+	req.RemoteAddr = "10.0.0.2:40000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	req.Header.Set("User-Agent", "kitchen-tablet/1.0")
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Contains(t, buf.String(), "remote_addr=203.0.113.9")
+	assert.Contains(t, buf.String(), "user_agent=kitchen-tablet/1.0")
+}
@@ -0,0 +1,49 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// WithItemLimits sets how long an item's name and quantity may be; longer
+// ones are rejected with 422.
+func (s *Server) WithItemLimits(l domain.ItemLimits) *Server {
+	s.itemLimits = l
+	return s
+}
+
+// renderFieldErrors responds 422 with what is wrong with each field: a
+// toast listing them for HTMX, {"error", "errors"} for JSON clients, and the
+// error page or plain text otherwise.
+func (s *Server) renderFieldErrors(w http.ResponseWriter, r *http.Request, errs []domain.FieldError) {
+	const status = http.StatusUnprocessableEntity
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Message
+	}
+	msg := strings.Join(msgs, "; ")
+	switch {
+	case isHTMX(r):
+		out, err := s.renderFragment("partials/field_errors.html", map[string]any{"Status": status, "Errors": errs})
+		if err != nil {
+			s.log(r).Error("render field errors failed", "error", err)
+			s.renderError(w, r, status, msg)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("HX-Retarget", "#toast-container")
+		w.Header().Set("HX-Reswap", "beforeend")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, out)
+	case wantsJSON(r):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]any{"error": msg, "errors": errs})
+	default:
+		s.renderError(w, r, status, msg)
+	}
+}
@@ -0,0 +1,1122 @@
+package web_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/vbonduro/kitchinv/internal/vision"
+	"github.com/vbonduro/kitchinv/internal/vision/visiontest"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden HTTP transcripts in testdata/golden")
+
+// goldenHeaders are the response headers recorded in transcripts. Everything
+// else (Date, Content-Length) is either volatile or implied by the body.
+var goldenHeaders = []string{
+	"Cache-Control",
+	"Content-Type",
+	"ETag",
+	"HX-Trigger",
+	"Link",
+	"Location",
+	"X-Client-Correlation-ID",
+	"X-Content-Type-Options",
+	"X-Frame-Options",
+}
+
+// goldenMasks replace volatile fragments of response bodies before comparison.
+var goldenMasks = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`), "<TIMESTAMP>"},
+	{regexp.MustCompile(`(data-testid="photo-timestamp"[^>]*>)[^<]*`), "${1}<AGO>"},
+	{regexp.MustCompile(`(data-testid="photo-taken"[^>]*>Photo taken )[^<]*`), "${1}<AGO>"},
+	{regexp.MustCompile(`(title="(?:Added|Taken|Updated|Logged) |data-testid="photo-taken" title=")[^".]*`), "${1}<DATE>"},
+	{regexp.MustCompile(`(class="detail-date">Added )[^<]*`), "${1}<DATE>"},
+	{regexp.MustCompile(`([?&]v=)\d+`), "${1}<VERSION>"},
+	{regexp.MustCompile(`(nonce="|"inlineScriptNonce":")[^"]*`), "${1}<NONCE>"},
+	{regexp.MustCompile(`(data-testid="generated-at">Generated |\nGenerated )[^<\n]*`), "${1}<DATE>"},
+}
+
+// goldenRequest describes one HTTP request in a scenario. Exactly one of body
+// or images may be set; each image is sent as an "image" field of a multipart
+// form.
+type goldenRequest struct {
+	method  string
+	path    string
+	body    string
+	ctype   string
+	headers map[string]string
+	images  [][]byte
+}
+
+// goldenScenario runs setup requests against a fresh server, then records the
+// response to req in testdata/golden/<name>.golden.
+type goldenScenario struct {
+	name   string
+	vision vision.VisionAnalyzer
+	setup  []goldenRequest
+	req    goldenRequest
+}
+
+func goldenForm(method, path, body string) goldenRequest {
+	return goldenRequest{method: method, path: path, body: body, ctype: "application/x-www-form-urlencoded"}
+}
+
+func goldenJSON(method, path, body string) goldenRequest {
+	return goldenRequest{method: method, path: path, body: body, ctype: "application/json"}
+}
+
+func goldenGet(path string) goldenRequest {
+	return goldenRequest{method: http.MethodGet, path: path}
+}
+
+func goldenUpload(path string, images ...[]byte) goldenRequest {
+	return goldenRequest{method: http.MethodPost, path: path, images: images}
+}
+
+var goldenScenarios = []goldenScenario{
+	{name: "root_redirect", req: goldenGet("/")},
+	{name: "list_areas_empty", req: goldenGet("/areas")},
+	{name: "list_areas", setup: []goldenRequest{goldenForm("POST", "/areas", "name=Pantry")}, req: goldenGet("/areas")},
+	{name: "create_area", req: goldenForm("POST", "/areas", "name=Fridge")},
+	{name: "create_area_blank_name", req: goldenForm("POST", "/areas", "name=+++")},
+	{name: "create_area_with_kind", req: goldenForm("POST", "/areas", "name=Chest+Freezer&kind=freezer")},
+	{name: "create_area_unknown_kind", req: goldenForm("POST", "/areas", "name=Cellar&kind=cellar")},
+	{
+		name: "list_areas_preview",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Pantry"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Rice","quantity":"1"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Beans","quantity":"3"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Flour","quantity":"0"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Oats","quantity":"1"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Lentils","quantity":"2"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Sugar","quantity":"1"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Tea","quantity":"1"}`),
+		},
+		req: goldenGet("/areas"),
+	},
+	{
+		name: "list_areas_by_kind",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Pantry&kind=pantry"),
+			goldenForm("POST", "/areas", "name=Fridge&kind=fridge"),
+		},
+		req: goldenGet("/areas?kind=fridge"),
+	},
+	{
+		name:  "create_area_duplicate",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenForm("POST", "/areas", "name=Fridge"),
+	},
+	{name: "area_detail", setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")}, req: goldenGet("/areas/1")},
+	{
+		name:  "area_detail_with_photo",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenGet("/areas/1"),
+	},
+	{
+		name:  "area_detail_truncated",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenGet("/areas/1"),
+		vision: analyzeOnly{&visiontest.Recording{Result: &vision.AnalysisResult{
+			Items:      []vision.DetectedItem{{Name: "Milk", Quantity: "1"}},
+			Truncated:  true,
+			StopReason: "max_tokens",
+		}}},
+	},
+	{
+		name:   "area_detail_items_kept",
+		setup:  itemsKeptSetup,
+		req:    goldenGet("/areas/1"),
+		vision: itemsKeptVision(),
+	},
+	{
+		name:   "resolve_kept_items_replace",
+		setup:  itemsKeptSetup,
+		req:    goldenForm("POST", "/areas/1/kept-items", "replace=true"),
+		vision: itemsKeptVision(),
+	},
+	{
+		name:  "resolve_kept_items_none_pending",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenForm("POST", "/areas/1/kept-items", "replace=true"),
+	},
+	{name: "stale_areas_empty", setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")}, req: goldenGet("/areas/stale")},
+	{name: "area_detail_not_found", req: goldenGet("/areas/99")},
+	{name: "area_detail_invalid_id", req: goldenGet("/areas/abc")},
+	{
+		name:  "rename_area",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenJSON("PUT", "/areas/1", `{"name":"Garage Fridge"}`),
+	},
+	{
+		name:  "rename_area_form",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenForm("PUT", "/areas/1", "name=Garage+Fridge"),
+	},
+	{
+		name:  "rename_area_json_response",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req: goldenRequest{
+			method: "PUT", path: "/areas/1", body: `{"name":"Garage Fridge"}`,
+			ctype: "application/json", headers: map[string]string{"Accept": "application/json"},
+		},
+	},
+	{
+		name:  "rename_area_duplicate",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenForm("POST", "/areas", "name=Pantry")},
+		req:   goldenJSON("PUT", "/areas/2", `{"name":"Fridge"}`),
+	},
+	{
+		name:  "set_area_prompt",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Spice Rack")},
+		req:   goldenJSON("PUT", "/areas/1", `{"prompt":"Read every jar label."}`),
+	},
+	{
+		name:  "set_area_kind",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Spice Rack")},
+		req:   goldenJSON("PUT", "/areas/1", `{"kind":"spice_rack"}`),
+	},
+	{
+		name: "area_detail_with_prompt",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Spice Rack"),
+			goldenJSON("PUT", "/areas/1", `{"prompt":"Read every <jar> label."}`),
+		},
+		req: goldenGet("/areas/1"),
+	},
+	{
+		name:  "update_area_empty_body",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenJSON("PUT", "/areas/1", `{}`),
+	},
+	{
+		name: "list_ignored_items",
+		setup: []goldenRequest{
+			goldenJSON("POST", "/ignored-items", `{"pattern":"shelf"}`),
+			goldenJSON("POST", "/ignored-items", `{"pattern":"*container"}`),
+		},
+		req: goldenGet("/ignored-items"),
+	},
+	{name: "create_ignored_item", req: goldenJSON("POST", "/ignored-items", `{"pattern":" Shelf "}`)},
+	{
+		name:  "create_ignored_item_duplicate",
+		setup: []goldenRequest{goldenJSON("POST", "/ignored-items", `{"pattern":"shelf"}`)},
+		req:   goldenJSON("POST", "/ignored-items", `{"pattern":"SHELF"}`),
+	},
+	{name: "create_ignored_item_empty", req: goldenJSON("POST", "/ignored-items", `{"pattern":"*"}`)},
+	{name: "delete_ignored_item_not_found", req: goldenRequest{method: "DELETE", path: "/ignored-items/9"}},
+	{
+		name: "create_webhook",
+		req: goldenJSON("POST", "/webhooks",
+			`{"url":"http://ha.local:8123/api/webhook/kitchen","secret":"s3cret","events":["analysis.completed","area.deleted"]}`),
+	},
+	{name: "create_webhook_unknown_event", req: goldenJSON("POST", "/webhooks", `{"url":"http://ha.local/hook","events":["item.eaten"]}`)},
+	{name: "create_webhook_bad_url", req: goldenJSON("POST", "/webhooks", `{"url":"ha.local/hook","events":["area.deleted"]}`)},
+	{
+		name: "list_webhooks",
+		setup: []goldenRequest{
+			goldenJSON("POST", "/webhooks", `{"url":"http://ha.local/a","events":["analysis.failed"],"enabled":false}`),
+		},
+		req: goldenGet("/webhooks"),
+	},
+	{
+		name:  "update_webhook",
+		setup: []goldenRequest{goldenJSON("POST", "/webhooks", `{"url":"http://ha.local/a","events":["analysis.failed"]}`)},
+		req:   goldenJSON("PUT", "/webhooks/1", `{"url":"http://ha.local/b","events":["area.deleted"],"enabled":false}`),
+	},
+	{name: "update_webhook_not_found", req: goldenJSON("PUT", "/webhooks/9", `{"url":"http://ha.local/b","events":["area.deleted"]}`)},
+	{name: "webhook_deliveries_not_found", req: goldenGet("/webhooks/9/deliveries")},
+	{name: "test_webhook_not_found", req: goldenJSON("POST", "/webhooks/9/test", "")},
+	{
+		name:  "rename_area_invalid_json",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenJSON("PUT", "/areas/1", `{`),
+	},
+	{
+		name:  "delete_area",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenRequest{method: "DELETE", path: "/areas/1"},
+	},
+	{
+		name: "delete_area_with_sub_areas",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Freezer"),
+			goldenForm("POST", "/areas", "name=Top+basket&parent_id=1"),
+		},
+		req: goldenRequest{method: "DELETE", path: "/areas/1"},
+	},
+	{
+		name:  "create_sub_area",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Freezer&kind=freezer")},
+		req:   goldenForm("POST", "/areas", "name=Top+basket&kind=freezer&parent_id=1"),
+	},
+	{
+		name:  "create_sub_area_bad_parent",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Freezer")},
+		req:   goldenForm("POST", "/areas", "name=Top+basket&parent_id=9"),
+	},
+	{
+		name: "list_areas_nested",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Freezer&kind=freezer"),
+			goldenForm("POST", "/areas", "name=Pantry&kind=pantry"),
+			goldenForm("POST", "/areas", "name=Top+basket&parent_id=1"),
+			goldenJSON("POST", "/areas/3/items", `{"name":"Peas","quantity":"2"}`),
+		},
+		req: goldenGet("/areas"),
+	},
+	{
+		name: "area_detail_with_sub_areas",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Freezer&kind=freezer"),
+			goldenForm("POST", "/areas", "name=Top+basket&parent_id=1"),
+			goldenJSON("POST", "/areas/2/items", `{"name":"Peas","quantity":"2"}`),
+		},
+		req: goldenGet("/areas/1"),
+	},
+	{
+		name:  "restore_area",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), {method: "DELETE", path: "/areas/1"}},
+		req:   goldenRequest{method: "POST", path: "/areas/1/restore"},
+	},
+	{
+		name:  "restore_area_not_deleted",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenRequest{method: "POST", path: "/areas/1/restore"},
+	},
+	{
+		name: "restore_area_name_taken",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			{method: "DELETE", path: "/areas/1"},
+			goldenForm("POST", "/areas", "name=Fridge"),
+		},
+		req: goldenRequest{method: "POST", path: "/areas/1/restore"},
+	},
+	{
+		name:  "reorder_areas",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=A"), goldenForm("POST", "/areas", "name=B")},
+		req:   goldenJSON("POST", "/areas/reorder", `{"ids":[2,1]}`),
+	},
+	{name: "reorder_areas_invalid_json", req: goldenJSON("POST", "/areas/reorder", `not json`)},
+	{
+		name:  "set_area_order",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=A"), goldenForm("POST", "/areas", "name=B")},
+		req:   goldenJSON("PUT", "/areas/order", `[2,1]`),
+	},
+	{
+		name:  "set_area_order_form",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=A"), goldenForm("POST", "/areas", "name=B")},
+		req:   goldenForm("PUT", "/areas/order", "id=2&id=1"),
+	},
+	{name: "set_area_order_object", req: goldenJSON("PUT", "/areas/order", `{"ids":[2,1]}`)},
+	{name: "set_area_order_repeated_id", req: goldenJSON("PUT", "/areas/order", `[1,1]`)},
+	{name: "set_area_order_empty", req: goldenJSON("PUT", "/areas/order", `[]`)},
+	{
+		name:  "upload_photo",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenUpload("/areas/1/photos", minimalJPEG),
+	},
+	{
+		name:  "upload_photos_multiple",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Pantry")},
+		req:   goldenUpload("/areas/1/photos", minimalJPEG, minimalJPEG),
+	},
+	{
+		name:  "upload_photos_too_many",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Pantry")},
+		req:   goldenUpload("/areas/1/photos", minimalJPEG, minimalJPEG, minimalJPEG, minimalJPEG, minimalJPEG, minimalJPEG),
+	},
+	{
+		name:  "upload_photo_unsupported_format",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenUpload("/areas/1/photos", []byte("%PDF-1.4 not an image")),
+	},
+	{
+		name:  "upload_photo_missing_image",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenForm("POST", "/areas/1/photos", "foo=bar"),
+	},
+	{
+		name:   "upload_photo_analysis_failure",
+		vision: &failingVision{err: errors.New("model unavailable")},
+		setup:  []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:    goldenUpload("/areas/1/photos", minimalJPEG),
+	},
+	{
+		name:  "api_upload_photo",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenRequest{method: "PUT", path: "/api/v1/areas/1/photo", body: string(minimalJPEG), ctype: "image/jpeg"},
+	},
+	{
+		name:  "api_upload_photo_type_mismatch",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenRequest{method: "PUT", path: "/api/v1/areas/1/photo", body: string(minimalJPEG), ctype: "image/png"},
+	},
+	{
+		name: "api_upload_photo_area_not_found",
+		req:  goldenRequest{method: "PUT", path: "/api/v1/areas/42/photo", body: string(minimalJPEG), ctype: "image/jpeg"},
+	},
+	{
+		name:  "get_photo",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenGet("/areas/1/photo"),
+	},
+	{
+		name:  "get_photo_none",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenGet("/areas/1/photo"),
+	},
+	{
+		name:  "get_item_photo_none",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenGet("/areas/1/items/1/photo"),
+	},
+	{
+		name:  "area_diff",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenGet("/areas/1/diff"),
+	},
+	{
+		name:  "area_diff_json",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenRequest{method: "GET", path: "/areas/1/diff", headers: map[string]string{"Accept": "application/json"}},
+	},
+	{
+		name:  "area_diff_no_photo",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenGet("/areas/1/diff"),
+	},
+	{
+		name: "area_history",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Freezer"),
+			goldenUpload("/areas/1/photos", minimalJPEG),
+			goldenUpload("/areas/1/photos?force=true", minimalJPEG),
+		},
+		req: goldenGet("/areas/1/history?days=30"),
+	},
+	{
+		name:  "area_history_invalid_days",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Freezer")},
+		req:   goldenGet("/areas/1/history?days=0"),
+	},
+	{
+		name: "area_detail_with_history",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Freezer"),
+			goldenUpload("/areas/1/photos", minimalJPEG),
+			goldenUpload("/areas/1/photos?force=true", minimalJPEG),
+		},
+		req: goldenGet("/areas/1"),
+	},
+	{
+		name:  "delete_photo",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenRequest{method: "DELETE", path: "/areas/1/photo"},
+	},
+	{
+		name:  "area_card",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenGet("/areas/1/card"),
+	},
+	{name: "area_card_not_found", req: goldenGet("/areas/42/card")},
+	{
+		name:  "area_items_json",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenRequest{method: "GET", path: "/areas/1/items", headers: map[string]string{"Accept": "application/json"}},
+	},
+	{
+		name:  "area_items_page_json",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenRequest{method: "GET", path: "/areas/1/items?limit=1&sort=name", headers: map[string]string{"Accept": "application/json"}},
+	},
+	{
+		name:  "area_items_page_htmx",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenRequest{method: "GET", path: "/areas/1/items?limit=1&offset=1", headers: map[string]string{"HX-Request": "true"}},
+	},
+	{
+		name: "area_items_sort_quantity",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Pantry"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Salt","quantity":"a little"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Rice","quantity":"2 x 500g"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Beans","quantity":"3 cans"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Flour","quantity":"1500g"}`),
+		},
+		req: goldenRequest{method: "GET", path: "/areas/1/items?sort=quantity", headers: map[string]string{"HX-Request": "true"}},
+	},
+	{
+		name:  "area_items_invalid_sort",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenGet("/areas/1/items?sort=colour"),
+	},
+	{
+		name:  "create_item",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenJSON("POST", "/areas/1/items", `{"name":"Butter","quantity":"1"}`),
+	},
+	{
+		name:  "create_item_form",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenForm("POST", "/areas/1/items", "name=Butter&quantity=1"),
+	},
+	{
+		name:  "create_item_blank_name",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenJSON("POST", "/areas/1/items", `{"name":"  "}`),
+	},
+	{
+		name:  "create_item_too_long",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req: goldenRequest{
+			method: "POST", path: "/areas/1/items", ctype: "application/json", headers: map[string]string{"Accept": "application/json"},
+			body: `{"name":"` + strings.Repeat("x", 201) + `","quantity":"` + strings.Repeat("9", 101) + `"}`,
+		},
+	},
+	{
+		name:  "create_item_too_long_htmx",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req: goldenRequest{
+			method: "POST", path: "/areas/1/items", ctype: "application/x-www-form-urlencoded", headers: map[string]string{"HX-Request": "true"},
+			body: "name=%0A%09&quantity=" + strings.Repeat("9", 101),
+		},
+	},
+	{
+		name: "update_item",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Butter","quantity":"1"}`),
+		},
+		req: goldenJSON("PUT", "/areas/1/items/1", `{"name":"Salted Butter","quantity":"2"}`),
+	},
+	{
+		name: "update_item_htmx_form",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Butter","quantity":"1"}`),
+		},
+		req: goldenRequest{
+			method: "PUT", path: "/areas/1/items/1", body: "name=Salted+Butter&quantity=2",
+			ctype: "application/x-www-form-urlencoded", headers: map[string]string{"HX-Request": "true"},
+		},
+	},
+	{
+		name: "update_item_version_conflict",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Butter","quantity":"1"}`),
+			goldenJSON("PUT", "/areas/1/items/1", `{"name":"Salted Butter","quantity":"1","version":1}`),
+		},
+		req: goldenJSON("PUT", "/areas/1/items/1", `{"name":"Unsalted Butter","quantity":"1","version":1}`),
+	},
+	{
+		name: "update_item_if_match_conflict_htmx",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Butter","quantity":"1"}`),
+			goldenJSON("PUT", "/areas/1/items/1", `{"name":"Salted Butter","quantity":"1"}`),
+		},
+		req: goldenRequest{
+			method: "PUT", path: "/areas/1/items/1", body: "name=Unsalted+Butter&quantity=1",
+			ctype:   "application/x-www-form-urlencoded",
+			headers: map[string]string{"HX-Request": "true", "If-Match": `"1"`},
+		},
+	},
+	{
+		name: "update_item_invalid_version",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Butter","quantity":"1"}`),
+		},
+		req: goldenJSON("PUT", "/areas/1/items/1", `{"name":"Salted Butter","version":"first"}`),
+	},
+	{
+		name: "delete_item",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Butter","quantity":"1"}`),
+		},
+		req: goldenRequest{method: "DELETE", path: "/areas/1/items/1"},
+	},
+	{
+		name: "consume_item",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Butter","quantity":"1"}`),
+		},
+		req: goldenRequest{method: "POST", path: "/areas/1/items/1/consume"},
+	},
+	{
+		name: "discard_item_twice",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Butter","quantity":"1"}`),
+			{method: "POST", path: "/areas/1/items/1/discard"},
+		},
+		req: goldenRequest{method: "POST", path: "/areas/1/items/1/discard", headers: map[string]string{"Accept": "application/json"}},
+	},
+	{
+		name: "consume_item_other_area",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenForm("POST", "/areas", "name=Pantry"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Butter","quantity":"1"}`),
+		},
+		req: goldenRequest{method: "POST", path: "/areas/2/items/1/consume", headers: map[string]string{"Accept": "application/json"}},
+	},
+	{
+		name: "area_items_consumed",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Butter","quantity":"1"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Milk","quantity":"1"}`),
+			{method: "POST", path: "/areas/1/items/1/consume"},
+		},
+		req: goldenRequest{method: "GET", path: "/areas/1/items?status=consumed", headers: map[string]string{"Accept": "application/json"}},
+	},
+	{
+		name:  "area_items_invalid_status",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenRequest{method: "GET", path: "/areas/1/items?status=eaten", headers: map[string]string{"Accept": "application/json"}},
+	},
+
+	{
+		name: "bulk_items",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Butter","quantity":"1"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Eggs","quantity":"6"}`),
+		},
+		req: goldenJSON("POST", "/areas/1/items/bulk", `[{"op":"update","id":1,"name":"Salted Butter","quantity":"2"},{"op":"delete","id":2},{"op":"create","name":"Jam"}]`),
+	},
+	{
+		name: "bulk_items_json",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Butter","quantity":"1"}`),
+		},
+		req: goldenRequest{
+			method:  "POST",
+			path:    "/areas/1/items/bulk",
+			body:    `[{"op":"create","name":"Jam"}]`,
+			ctype:   "application/json",
+			headers: map[string]string{"Accept": "application/json"},
+		},
+	},
+	{
+		name: "bulk_items_rejected",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Butter","quantity":"1"}`),
+		},
+		req: goldenJSON("POST", "/areas/1/items/bulk", `[{"op":"delete","id":1},{"op":"update","id":99,"name":"Ghost"},{"op":"create","name":""}]`),
+	},
+	{name: "bulk_items_area_not_found", req: goldenJSON("POST", "/areas/9/items/bulk", `[]`)},
+	{
+		name:  "bulk_items_invalid_json",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge")},
+		req:   goldenJSON("POST", "/areas/1/items/bulk", `{"op":"create"}`),
+	},
+	{
+		name:  "create_item_from_barcode_htmx",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Pantry")},
+		req: goldenRequest{
+			method:  "POST",
+			path:    "/areas/1/items/barcode",
+			body:    `{"barcode":"4006381333931"}`,
+			ctype:   "application/json",
+			headers: map[string]string{"HX-Request": "true"},
+		},
+	},
+	{
+		name:  "create_item_from_barcode_invalid",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Pantry")},
+		req:   goldenJSON("POST", "/areas/1/items/barcode", `{"barcode":"not-a-code"}`),
+	},
+	{name: "create_item_from_barcode_area_not_found", req: goldenJSON("POST", "/areas/9/items/barcode", `{"barcode":"4006381333931"}`)},
+	{
+		name:  "search_htmx",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenRequest{method: "GET", path: "/search?q=milk", headers: map[string]string{"HX-Request": "true"}},
+	},
+	{
+		name: "search_sub_area",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Freezer"),
+			goldenForm("POST", "/areas", "name=Top+basket&parent_id=1"),
+			goldenJSON("POST", "/areas/2/items", `{"name":"Peas","quantity":"2"}`),
+		},
+		req: goldenRequest{method: "GET", path: "/search?q=peas", headers: map[string]string{"HX-Request": "true"}},
+	},
+	{
+		name:  "search_page",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenGet("/search?q=milk"),
+	},
+	{
+		name: "search_paged",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Milk","quantity":"1"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Oat milk","quantity":"2"}`),
+		},
+		req: goldenRequest{method: "GET", path: "/search?q=milk&limit=1", headers: map[string]string{"HX-Request": "true"}},
+	},
+	{
+		name: "search_filtered",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenForm("POST", "/areas", "name=Pantry"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Milk","quantity":"2 cartons"}`),
+			goldenJSON("POST", "/areas/2/items", `{"name":"Powdered milk","quantity":"1 bag"}`),
+			goldenJSON("POST", "/areas/2/items", `{"name":"Rice","quantity":"2 bags"}`),
+		},
+		req: goldenGet("/search?q=ba&area_id=2&in=name,quantity"),
+	},
+	{
+		name: "search_fuzzy",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Greek yoghurt","quantity":"1 tub"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Yogurt drink","quantity":"2"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Tomatoes","quantity":"6"}`),
+		},
+		req: goldenRequest{method: "GET", path: "/search?q=yogurt", headers: map[string]string{"HX-Request": "true"}},
+	},
+	{
+		name: "search_suggest",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Oat milk","quantity":"1"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Milk \"whole\" <3.25%>","quantity":"1"}`),
+		},
+		req: goldenRequest{method: "GET", path: "/search/suggest?q=milk", headers: map[string]string{"HX-Request": "true"}},
+	},
+	{name: "search_invalid_filter", req: goldenGet("/search?q=milk&in=notes")},
+	{
+		name: "search_empty_recent",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Milk","quantity":"1"}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Eggs","quantity":"6"}`),
+		},
+		req: goldenGet("/search"),
+	},
+	{name: "search_empty_no_items", req: goldenRequest{method: "GET", path: "/search?q=", headers: map[string]string{"HX-Request": "true"}}},
+	{
+		name: "recent_page",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenUpload("/areas/1/photos", minimalJPEG),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Butter","quantity":"1"}`),
+		},
+		req: goldenGet("/recent"),
+	},
+	{
+		name: "recent_json_paged",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenUpload("/areas/1/photos", minimalJPEG),
+		},
+		req: goldenRequest{method: "GET", path: "/recent?limit=1", headers: map[string]string{"Accept": "application/json"}},
+	},
+	{
+		name:  "recent_since_htmx",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenRequest{method: "GET", path: "/recent?since=2999-01-01", headers: map[string]string{"HX-Request": "true"}},
+	},
+	{name: "recent_invalid_since", req: goldenGet("/recent?since=last+tuesday")},
+	{
+		name: "audit_page",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenForm("POST", "/areas/1/items", "name=Milk&quantity=2"),
+			{method: "DELETE", path: "/areas/1/items/1", headers: map[string]string{"X-Request-Id": "golden-delete-item"}},
+		},
+		req: goldenGet("/audit"),
+	},
+	{
+		name: "audit_json_by_area",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenForm("POST", "/areas", "name=Pantry"),
+			{method: "DELETE", path: "/areas/1", headers: map[string]string{"X-Request-Id": "golden-delete-fridge"}},
+			{method: "DELETE", path: "/areas/2", headers: map[string]string{"X-Request-Id": "golden-delete-pantry"}},
+		},
+		req: goldenRequest{method: "GET", path: "/audit?area_id=2", headers: map[string]string{"Accept": "application/json"}},
+	},
+	{name: "audit_invalid_area", req: goldenGet("/audit?area_id=fridge")},
+	{name: "audit_invalid_until", req: goldenGet("/audit?until=tomorrow")},
+	{name: "suggest_unsupported", req: goldenForm("POST", "/suggest", "")},
+	{name: "suggest_invalid_area", req: goldenForm("POST", "/suggest", "area_id=fridge")},
+	{
+		name:  "ask_fallback",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenGet("/ask?q=do+we+have+milk%3F"),
+	},
+	{name: "ask_missing_question", req: goldenGet("/ask?q=+")},
+	{name: "overrides_page", req: goldenGet("/overrides")},
+	{name: "create_override", req: goldenForm("POST", "/overrides", "match_pattern=milk&replacement=Whole+Milk&match_exact=on&scope=global")},
+	{name: "create_override_missing_pattern", req: goldenForm("POST", "/overrides", "match_exact=on")},
+	{
+		name:  "raw_response_debug_disabled",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Fridge"), goldenUpload("/areas/1/photos", minimalJPEG)},
+		req:   goldenGet("/areas/1/photos/latest/raw"),
+	},
+	{name: "staples_empty", req: goldenGet("/staples")},
+	{
+		name: "staples",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Whole milk","quantity":"1 carton"}`),
+			goldenForm("POST", "/staples", "pattern=milk&min_quantity=2&area_id=1"),
+			goldenJSON("POST", "/staples", `{"pattern":"eggs","min_quantity":6}`),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Flour","quantity":"1500g"}`),
+			goldenForm("POST", "/staples", "pattern=flour&min_quantity=2+kg"),
+		},
+		req: goldenGet("/staples"),
+	},
+	{
+		name: "staples_json",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Eggs","quantity":"12"}`),
+			goldenJSON("POST", "/staples", `{"pattern":"egg","min_quantity":6}`),
+		},
+		req: goldenRequest{method: "GET", path: "/staples", headers: map[string]string{"Accept": "application/json"}},
+	},
+	{name: "create_staple", req: goldenJSON("POST", "/staples", `{"pattern":"Peanut butter"}`)},
+	{name: "create_staple_form", req: goldenForm("POST", "/staples", "pattern=eggs&min_quantity=6")},
+	{name: "create_staple_invalid", req: goldenJSON("POST", "/staples", `{"pattern":"eggs","min_quantity":0}`)},
+	{name: "create_staple_unit", req: goldenJSON("POST", "/staples", `{"pattern":"Milk","min_quantity":"2 l"}`)},
+	{name: "create_staple_unreadable", req: goldenJSON("POST", "/staples", `{"pattern":"eggs","min_quantity":"a few"}`)},
+	{
+		name:  "create_staple_duplicate",
+		setup: []goldenRequest{goldenJSON("POST", "/staples", `{"pattern":"eggs"}`)},
+		req:   goldenJSON("POST", "/staples", `{"pattern":"Eggs"}`),
+	},
+	{
+		name:  "delete_staple",
+		setup: []goldenRequest{goldenJSON("POST", "/staples", `{"pattern":"eggs"}`)},
+		req:   goldenRequest{method: "DELETE", path: "/staples/1"},
+	},
+	{
+		name: "list_areas_running_low",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Whole milk","quantity":"1"}`),
+			goldenJSON("POST", "/staples", `{"pattern":"milk","min_quantity":2}`),
+			goldenJSON("POST", "/staples", `{"pattern":"eggs"}`),
+		},
+		req: goldenGet("/areas"),
+	},
+	{
+		name: "export_markdown",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenForm("POST", "/areas", "name=Pantry"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"*Organic* milk","quantity":"1 | 2 cartons"}`),
+		},
+		req: goldenGet("/export.md"),
+	},
+	{
+		name: "export_markdown_include_empty",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenForm("POST", "/areas", "name=Pantry"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Milk","quantity":"1"}`),
+		},
+		req: goldenGet("/export.md?include_empty=1"),
+	},
+	{
+		name: "print",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Fridge"),
+			goldenForm("POST", "/areas", "name=Pantry"),
+			goldenJSON("POST", "/areas/1/items", `{"name":"Milk","quantity":"1 carton"}`),
+		},
+		req: goldenGet("/print"),
+	},
+	{name: "print_empty", req: goldenGet("/print")},
+	{
+		name: "area_labels",
+		setup: []goldenRequest{
+			goldenForm("POST", "/areas", "name=Freezer"),
+			goldenForm("POST", "/areas", "name=Top basket&parent_id=1"),
+		},
+		req: goldenGet("/areas/labels"),
+	},
+	{
+		name:  "area_qr",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Freezer")},
+		req:   goldenGet("/areas/1/qr.png?size=128"),
+	},
+	{
+		name:  "area_qr_invalid_size",
+		setup: []goldenRequest{goldenForm("POST", "/areas", "name=Freezer")},
+		req:   goldenGet("/areas/1/qr.png?size=10"),
+	},
+	{name: "area_qr_missing", req: goldenGet("/areas/42/qr.png")},
+	{name: "readyz", req: goldenGet("/readyz")},
+	{name: "stats_empty", req: goldenGet("/stats")},
+
+	{name: "unknown_route", req: goldenGet("/nope")},
+	{name: "method_not_allowed", req: goldenRequest{method: "PATCH", path: "/areas"}},
+}
+
+// itemsKeptSetup uploads a photo that finds items and then one that finds
+// none, which keeps them; see itemsKeptVision.
+var itemsKeptSetup = []goldenRequest{
+	goldenForm("POST", "/areas", "name=Fridge"),
+	goldenUpload("/areas/1/photos", minimalJPEG),
+	goldenUpload("/areas/1/photos?force=true", minimalJPEG),
+}
+
+// itemsKeptVision answers the two analyses of itemsKeptSetup.
+func itemsKeptVision() vision.VisionAnalyzer {
+	return analyzeOnly{visiontest.NewScripted(
+		visiontest.Step{Result: &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}}}},
+		visiontest.Step{Result: &vision.AnalysisResult{Status: vision.StatusOK, RawResponse: "I can't make out any items."}},
+	)}
+}
+
+// analyzeOnly hides every capability of the backend it wraps but analysis,
+// such as text generation, which adds the recipe panel to pages.
+type analyzeOnly struct {
+	vision.VisionAnalyzer
+}
+
+// TestGolden replays each scenario against a fresh server and compares the
+// normalised transcript with testdata/golden/<name>.golden. Run with
+// -update to regenerate the files after an intentional change.
+func TestGolden(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	for _, sc := range goldenScenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			vis := sc.vision
+			if vis == nil {
+				vis = analyzeOnly{&visiontest.Recording{Result: &vision.AnalysisResult{
+					Items: []vision.DetectedItem{
+						{Name: "Milk", Quantity: "1"},
+						{Name: "Eggs", Quantity: "12"},
+					},
+				}}}
+			}
+			srv, cleanup := newTestServer(t, vis)
+			defer cleanup()
+
+			client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			}}
+
+			for i, step := range sc.setup {
+				resp := doGoldenRequest(t, client, srv.URL, step)
+				_, _ = io.Copy(io.Discard, resp.Body)
+				_ = resp.Body.Close()
+				if resp.StatusCode >= 400 {
+					t.Fatalf("setup step %d (%s %s): status %d", i, step.method, step.path, resp.StatusCode)
+				}
+			}
+
+			resp := doGoldenRequest(t, client, srv.URL, sc.req)
+			defer func() { _ = resp.Body.Close() }()
+			got := goldenTranscript(t, sc.req, resp)
+
+			path := filepath.Join("testdata", "golden", sc.name+".golden")
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatalf("mkdir: %v", err)
+				}
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatalf("write golden: %v", err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read golden (run with -update to create): %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("transcript mismatch for %s (-want +got):\n%s", path, lineDiff(string(want), got))
+			}
+		})
+	}
+}
+
+func doGoldenRequest(t *testing.T, client *http.Client, base string, gr goldenRequest) *http.Response {
+	t.Helper()
+
+	var body io.Reader
+	ctype := gr.ctype
+	switch {
+	case gr.images != nil:
+		buf := &bytes.Buffer{}
+		mw := multipart.NewWriter(buf)
+		// A fixed boundary keeps request bodies reproducible across runs.
+		if err := mw.SetBoundary("kitchinv-golden-boundary"); err != nil {
+			t.Fatalf("set boundary: %v", err)
+		}
+		for _, image := range gr.images {
+			fw, err := mw.CreateFormFile("image", "photo.jpg")
+			if err != nil {
+				t.Fatalf("create form file: %v", err)
+			}
+			if _, err := fw.Write(image); err != nil {
+				t.Fatalf("write image: %v", err)
+			}
+		}
+		if err := mw.Close(); err != nil {
+			t.Fatalf("close multipart writer: %v", err)
+		}
+		body, ctype = buf, mw.FormDataContentType()
+	case gr.body != "":
+		body = strings.NewReader(gr.body)
+	}
+
+	req, err := http.NewRequest(gr.method, base+gr.path, body)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	if ctype != "" {
+		req.Header.Set("Content-Type", ctype)
+	}
+	for k, v := range gr.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", gr.method, gr.path, err)
+	}
+	return resp
+}
+
+// goldenTranscript renders the request line, status, recorded headers and
+// masked body as plain text. Full HTML pages are trimmed to the page content
+// between the shared header and toast container so edits to base.html don't
+// churn every transcript.
+func goldenTranscript(t *testing.T, gr goldenRequest, resp *http.Response) string {
+	t.Helper()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n\n", gr.method, gr.path)
+	fmt.Fprintf(&b, "%d %s\n", resp.StatusCode, http.StatusText(resp.StatusCode))
+
+	names := append([]string(nil), goldenHeaders...)
+	sort.Strings(names)
+	for _, h := range names {
+		if v := resp.Header.Get(h); v != "" {
+			if h == "ETag" && strings.Contains(v, ".") {
+				v = `"<HASH>.<NONCE>"` // a page's tag, which changes with its nonce
+			}
+			fmt.Fprintf(&b, "%s: %s\n", h, v)
+		}
+	}
+	b.WriteString("\n")
+
+	body := string(raw)
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "image/") {
+		body = fmt.Sprintf("<%d bytes of %s>\n", len(raw), resp.Header.Get("Content-Type"))
+	}
+	if start := strings.Index(body, "</header>"); start >= 0 {
+		if end := strings.Index(body, `<div class="toast-container"`); end > start {
+			body = strings.TrimSpace(body[start+len("</header>"):end]) + "\n"
+		}
+	}
+	for _, m := range goldenMasks {
+		body = m.re.ReplaceAllString(body, m.repl)
+	}
+	b.WriteString(body)
+	return b.String()
+}
+
+// lineDiff returns a minimal line-oriented diff of want and got, prefixing
+// removed lines with "-" and added lines with "+". Unchanged lines more than
+// two away from a change are elided.
+func lineDiff(want, got string) string {
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+
+	// Longest common subsequence table.
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, line{'+', b[j]})
+			j++
+		}
+	}
+
+	const context = 2
+	var out strings.Builder
+	lastPrinted := -1
+	for k, l := range lines {
+		near := false
+		for d := max(0, k-context); d <= min(len(lines)-1, k+context); d++ {
+			if lines[d].op != ' ' {
+				near = true
+				break
+			}
+		}
+		if !near {
+			continue
+		}
+		if lastPrinted >= 0 && k > lastPrinted+1 {
+			out.WriteString("...\n")
+		}
+		fmt.Fprintf(&out, "%c %s\n", l.op, l.text)
+		lastPrinted = k
+	}
+	return out.String()
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/vbonduro/kitchinv/internal/config"
+	"github.com/vbonduro/kitchinv/internal/logging"
+	"github.com/vbonduro/kitchinv/internal/photostore"
+)
+
+// AdminInfo is what the admin page shows besides live counts and storage
+// use: the effective configuration, where the database is, and a ring of
+// recent warnings and errors. Errors may be nil.
+type AdminInfo struct {
+	Settings []config.Setting
+	DBPath   string
+	Errors   *logging.Ring
+}
+
+// WithAdmin enables the /admin status page. Without it the route answers 404
+// as if it did not exist.
+func (s *Server) WithAdmin(info AdminInfo) *Server {
+	s.admin = &info
+	return s
+}
+
+// handleAdmin renders the status page: configuration with secrets redacted,
+// database and photo storage size, inventory counts, vision backend health
+// and recent errors. Sizes that can't be read are left blank and logged.
+func (s *Server) handleAdmin(w http.ResponseWriter, r *http.Request) {
+	if s.admin == nil {
+		http.NotFound(w, r)
+		return
+	}
+	counts, err := s.service.Counts(r.Context())
+	if err != nil {
+		s.log(r).Error("count inventory failed", "error", err)
+		s.renderError(w, r, http.StatusInternalServerError, "failed to load status")
+		return
+	}
+
+	data := map[string]any{
+		"Settings": s.admin.Settings,
+		"DBPath":   s.admin.DBPath,
+		"Counts":   counts,
+		"Vision":   s.service.VisionStatus(),
+	}
+	if size, err := fileSize(s.admin.DBPath); err != nil {
+		s.log(r).Warn("failed to stat database", "path", s.admin.DBPath, "error", err)
+	} else {
+		// The write-ahead log holds changes not yet checkpointed.
+		if wal, err := fileSize(s.admin.DBPath + "-wal"); err == nil {
+			size += wal
+		}
+		data["DBSize"] = humanize.IBytes(uint64(size))
+	}
+	if ur, ok := s.photoStore.(photostore.UsageReporter); ok {
+		if u, err := ur.Usage(r.Context()); err != nil {
+			s.log(r).Warn("failed to measure photo storage", "error", err)
+		} else {
+			data["PhotoFiles"] = u.Files
+			data["PhotoSize"] = humanize.IBytes(uint64(u.Bytes))
+		}
+	}
+	if s.admin.Errors != nil {
+		data["Errors"] = s.admin.Errors.Entries()
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if err := s.renderPage(w, r, "admin", data); err != nil {
+		s.log(r).Error("render page failed", "error", err)
+	}
+}
+
+func fileSize(path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
@@ -0,0 +1,72 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/config"
+	"github.com/vbonduro/kitchinv/internal/logging"
+	"github.com/vbonduro/kitchinv/internal/photostore/local"
+	"github.com/vbonduro/kitchinv/internal/service"
+	"github.com/vbonduro/kitchinv/internal/web/templates"
+)
+
+type fakeAdminService struct {
+	fakeOverrideService
+}
+
+func (f *fakeAdminService) Counts(_ context.Context) (service.Counts, error) {
+	return service.Counts{Areas: 3, Items: 42, Photos: 5}, nil
+}
+
+func (f *fakeAdminService) VisionStatus() service.VisionStatus {
+	return service.VisionStatus{OK: false, Message: "model moondream is not installed"}
+}
+
+func TestHandleAdmin(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "kitchinv.db")
+	require.NoError(t, os.WriteFile(dbPath, make([]byte, 2048), 0o644))
+	photos, err := local.NewLocalPhotoStore(filepath.Join(dir, "photos"))
+	require.NoError(t, err)
+	_, err = photos.Save(context.Background(), "image/png", bytes.NewReader([]byte("0123456789")))
+	require.NoError(t, err)
+
+	ring := logging.NewRing(10, slog.LevelWarn)
+	slog.New(ring.Handler(slog.NewTextHandler(&bytes.Buffer{}, nil))).Error("upload failed", "area_id", 7)
+
+	srv := NewServer(&fakeAdminService{}, templates.FS, photos, slog.Default()).
+		WithAdmin(AdminInfo{
+			Settings: []config.Setting{{Name: "ClaudeAPIKey", Value: "(set)"}, {Name: "OllamaModel", Value: "moondream"}},
+			DBPath:   dbPath,
+			Errors:   ring,
+		})
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/admin", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, `data-testid="admin-items">42<`)
+	assert.Contains(t, body, "model moondream is not installed")
+	assert.Contains(t, body, dbPath+" · 2.0 KiB")
+	assert.Contains(t, body, "1 files · 10 B")
+	assert.Contains(t, body, "upload failed")
+	assert.Contains(t, body, "area_id=7")
+	assert.Contains(t, body, "OllamaModel")
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+}
+
+func TestHandleAdmin_Disabled(t *testing.T) {
+	srv := newOverrideTestServer(&fakeAdminService{})
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/admin", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -1,133 +1,393 @@
 package web
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/vbonduro/kitchinv/internal/domain"
 	"github.com/vbonduro/kitchinv/internal/service"
 )
 
 func (s *Server) handleListAreas(w http.ResponseWriter, r *http.Request) {
-	areas, err := s.service.ListAreasWithItems(r.Context())
+	// The page says how long ago things happened, so its version moves on
+	// every minute as well as with the inventory.
+	version, err := s.service.AreaListVersion(r.Context())
+	version += "/" + strconv.FormatInt(time.Now().Truncate(time.Minute).Unix(), 10)
+	if s.notModifiedPage(w, r, version, err) {
+		return
+	}
+
+	areas, err := s.service.ListAreaSummaries(r.Context())
 	if err != nil {
+		w.Header().Del("ETag")
 		http.Error(w, "failed to list areas", http.StatusInternalServerError)
-		s.logger.Error("list areas failed", "error", err)
+		s.log(r).Error("list areas failed", "error", err)
 		return
 	}
 
-	if err := s.renderPage(w,
-		map[string]any{"Areas": areas, "ActiveNav": "areas"},
-		"base.html", "pages/areas.html", "partials/area_card.html",
-	); err != nil {
-		s.logger.Error("render page failed", "error", err)
+	sortMode := r.URL.Query().Get("sort")
+	if sortMode == "attention" {
+		service.SortByAttention(areas)
+	} else {
+		sortMode = ""
+	}
+
+	// Sub-areas can only be created under top-level areas, whichever kind
+	// is being shown.
+	var parents []*domain.Area
+	for _, a := range areas {
+		if a.ParentID == nil {
+			parents = append(parents, a.Area)
+		}
+	}
+
+	// An unknown kind is ignored, like an unknown sort.
+	kind, ok := domain.ParseAreaKind(r.URL.Query().Get("kind"))
+	if ok {
+		areas = filterByKind(areas, kind)
+	}
+
+	itemTotal := 0
+	for _, a := range areas {
+		itemTotal += a.ItemCount
+	}
+
+	// The waste summary and running-low staples are niceties; the areas
+	// are still worth showing without them.
+	waste, err := s.service.MonthlyWaste(r.Context(), wasteMonths)
+	if err != nil {
+		s.log(r).Error("get waste summary failed", "error", err)
+	}
+	low, err := s.service.LowStaples(r.Context())
+	if err != nil {
+		s.log(r).Error("get running-low staples failed", "error", err)
+	}
+
+	if err := s.renderPage(w, r, "areas", map[string]any{
+		"Areas": areas, "Parents": parents, "Sort": sortMode, "Kind": kind, "AreaKinds": domain.AreaKinds,
+		"ItemTotal": itemTotal, "Waste": waste, "LowStaples": low,
+		"CanSuggest": s.service.CanSuggestRecipes() && !s.demoMode,
+		"ActiveNav":  "areas",
+	}); err != nil {
+		s.log(r).Error("render page failed", "error", err)
+	}
+}
+
+// notModifiedVersion sets the ETag for the response to r from the version
+// of its content and reports whether the client already has that version,
+// in which case it has been sent a 304. A version that couldn't be read is
+// logged and the response goes out without an ETag.
+func (s *Server) notModifiedVersion(w http.ResponseWriter, r *http.Request, version string, err error) bool {
+	if err != nil {
+		s.log(r).Error("get content version failed", "path", r.URL.Path, "error", err)
+		return false
+	}
+	etag := versionETag(r, version)
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Vary", "Accept, HX-Request")
+	if inm := r.Header.Get("If-None-Match"); inm != "" && matchesETag(inm, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// notModifiedPage is notModifiedVersion for a full page, which carries its
+// CSP nonce: the nonce is part of the page's tag, and a client revalidating
+// an earlier copy is answered with that copy's nonce in the policy, so the
+// scripts in the copy it keeps still run.
+func (s *Server) notModifiedPage(w http.ResponseWriter, r *http.Request, version string, err error) bool {
+	nonce := cspNonce(r)
+	if nonce == "" || err != nil {
+		return s.notModifiedVersion(w, r, version, err)
+	}
+	h := w.Header()
+	h.Set("ETag", pageETag(r, version, nonce))
+	h.Set("Vary", "Accept, HX-Request")
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		i := strings.LastIndexByte(tag, '.')
+		if i < 0 {
+			continue
+		}
+		cached := strings.TrimSuffix(tag[i+1:], `"`)
+		if !validNonce(cached) || tag != pageETag(r, version, cached) {
+			continue
+		}
+		h.Set("ETag", tag)
+		h.Set("Content-Security-Policy", strings.ReplaceAll(s.headers.ContentSecurityPolicy, noncePlaceholder, cached))
+		w.WriteHeader(http.StatusNotModified)
+		return true
 	}
+	return false
+}
+
+// pageETag is versionETag for a page rendered with nonce, which it ends in.
+func pageETag(r *http.Request, version, nonce string) string {
+	return strings.TrimSuffix(versionETag(r, version+"\x00"+nonce), `"`) + "." + nonce + `"`
+}
+
+// versionETag is a validator for the response to r when its content is at
+// version. The same content is rendered differently for each query, for
+// JSON and HTML, and for HTMX, so those are part of the tag.
+func versionETag(r *http.Request, version string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{version, r.URL.RawQuery, r.Header.Get("Accept"), r.Header.Get("HX-Request")}, "\x00")))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func filterByKind(areas []*service.AreaSummary, kind domain.AreaKind) []*service.AreaSummary {
+	filtered := areas[:0]
+	for _, a := range areas {
+		if a.Kind == kind {
+			filtered = append(filtered, a)
+		}
+	}
+	return filtered
 }
 
 const maxAreaNameLen = 200
 
+// maxAreaPromptLen bounds a custom analysis prompt, which is sent with every
+// upload for the area.
+const maxAreaPromptLen = 2000
+
 func (s *Server) handleCreateArea(w http.ResponseWriter, r *http.Request) {
 	name := strings.TrimSpace(r.FormValue("name"))
 	if name == "" {
-		http.Error(w, "area name required", http.StatusBadRequest)
+		s.renderError(w, r, http.StatusBadRequest, "area name required")
 		return
 	}
 	if len(name) > maxAreaNameLen {
-		http.Error(w, "area name too long", http.StatusBadRequest)
+		s.renderError(w, r, http.StatusBadRequest, "area name too long")
 		return
 	}
+	kind := domain.AreaKindOther
+	if v := r.FormValue("kind"); v != "" {
+		var ok bool
+		if kind, ok = domain.ParseAreaKind(v); !ok {
+			s.renderError(w, r, http.StatusBadRequest, "unknown area kind")
+			return
+		}
+	}
 
-	area, err := s.service.CreateArea(r.Context(), name)
+	var (
+		area *domain.Area
+		err  error
+	)
+	if v := r.FormValue("parent_id"); v != "" {
+		parentID, perr := strconv.ParseInt(v, 10, 64)
+		if perr != nil {
+			s.renderError(w, r, http.StatusBadRequest, "invalid parent area")
+			return
+		}
+		area, err = s.service.CreateChildArea(r.Context(), parentID, name, kind)
+	} else {
+		area, err = s.service.CreateAreaWithKind(r.Context(), name, kind)
+	}
 	if err != nil {
 		if errors.Is(err, service.ErrNameTaken) {
-			http.Error(w, "an area with this name already exists", http.StatusConflict)
+			s.renderError(w, r, http.StatusConflict, "an area with this name already exists")
+			return
+		}
+		if errors.Is(err, service.ErrInvalidParent) {
+			s.renderError(w, r, http.StatusBadRequest, "invalid parent area")
 			return
 		}
-		http.Error(w, "failed to create area", http.StatusInternalServerError)
-		s.logger.Error("create area failed", "error", err)
+		s.renderError(w, r, http.StatusInternalServerError, "failed to create area")
+		s.log(r).Error("create area failed", "error", err)
 		return
 	}
 
 	summary := &service.AreaSummary{Area: area}
-	if err := s.renderPartial(w, "partials/area_card.html", summary); err != nil {
-		s.logger.Error("render partial failed", "error", err)
+	if err := s.renderPartial(w, r, "partials/area_card.html", summary); err != nil {
+		s.log(r).Error("render partial failed", "error", err)
 	}
 }
 
+// staleArea is an entry in the GET /areas/stale response.
+type staleArea struct {
+	ID             int64     `json:"id"`
+	Name           string    `json:"name"`
+	LastPhotoAt    time.Time `json:"last_photo_at"`
+	DaysSincePhoto int       `json:"days_since_photo"`
+}
+
+// handleListStaleAreas lists, as JSON, the areas whose latest photo is past
+// the stale threshold, so a reminder can be scripted against it.
+func (s *Server) handleListStaleAreas(w http.ResponseWriter, r *http.Request) {
+	summaries, err := s.service.ListStaleAreas(r.Context())
+	if err != nil {
+		http.Error(w, "failed to list stale areas", http.StatusInternalServerError)
+		s.log(r).Error("list stale areas failed", "error", err)
+		return
+	}
+	areas := make([]staleArea, 0, len(summaries))
+	for _, sum := range summaries {
+		areas = append(areas, staleArea{
+			ID:             sum.ID,
+			Name:           sum.Name,
+			LastPhotoAt:    sum.Photo.UploadedAt,
+			DaysSincePhoto: int(sum.PhotoAge.Hours() / 24),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(areas)
+}
+
 func (s *Server) handleGetAreaDetail(w http.ResponseWriter, r *http.Request) {
 	areaID, err := parseID(r)
 	if err != nil {
-		http.Error(w, "invalid area id", http.StatusBadRequest)
+		s.renderError(w, r, http.StatusBadRequest, "invalid area id")
 		return
 	}
 
-	area, items, photo, err := s.service.GetAreaWithItems(r.Context(), areaID)
+	sum, err := s.service.GetAreaSummary(r.Context(), areaID)
 	if err != nil {
-		http.Error(w, "failed to get area", http.StatusInternalServerError)
-		s.logger.Error("get area failed", "area_id", areaID, "error", err)
+		s.renderError(w, r, http.StatusInternalServerError, "failed to get area")
+		s.log(r).Error("get area failed", "area_id", areaID, "error", err)
 		return
 	}
-	if area == nil {
-		http.NotFound(w, r)
+	if sum == nil {
+		s.renderError(w, r, http.StatusNotFound, "area not found; it may have been deleted")
 		return
 	}
 
-	if err := s.renderPage(w,
-		map[string]any{"Area": area, "Items": items, "Photo": photo, "ActiveNav": "areas"},
-		"base.html", "pages/area_detail.html", "partials/item_list.html",
-	); err != nil {
-		s.logger.Error("render page failed", "error", err)
+	// A large area shows its first page of items; the rest load on demand.
+	items, next := sum.Items, ""
+	if len(items) > s.itemPageSize {
+		page := domain.ItemPage{Sort: domain.ItemSortName, Limit: s.itemPageSize}
+		items = items[:page.Limit]
+		next = nextPageURL(&url.URL{Path: fmt.Sprintf("/areas/%d/items", areaID)}, page)
+	}
+
+	// updated_at advances on every inventory change, so clients can tell
+	// when the area last changed without diffing the page.
+	w.Header().Set("Last-Modified", sum.Area.UpdatedAt.UTC().Format(http.TimeFormat))
+	if err := s.renderPage(w, r, "area_detail", map[string]any{
+		"Area": sum.Area, "Items": items, "ItemsNext": next, "Photo": sum.Photo, "Stale": sum.Stale, "PhotoAge": sum.PhotoAge,
+		"Children":  sum.Children,
+		"History":   s.areaSparkline(r, areaID),
+		"AreaKinds": domain.AreaKinds, "ActiveNav": "areas", "VisionStatus": s.service.VisionStatus(),
+	}); err != nil {
+		s.log(r).Error("render page failed", "error", err)
 	}
 }
 
 func (s *Server) handleUpdateArea(w http.ResponseWriter, r *http.Request) {
 	areaID, err := parseID(r)
 	if err != nil {
-		http.Error(w, "invalid area id", http.StatusBadRequest)
+		s.renderError(w, r, http.StatusBadRequest, "invalid area id")
 		return
 	}
 
-	var body struct {
-		Name string `json:"name"`
+	// Any field may be omitted: the area list renames without touching the
+	// prompt, and the detail page saves the prompt or kind without a name.
+	fields, err := readFields(r, "name", "prompt", "kind")
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "invalid request body")
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+	namePtr, promptPtr, kindPtr := fields["name"], fields["prompt"], fields["kind"]
+	if namePtr == nil && promptPtr == nil && kindPtr == nil {
+		s.renderError(w, r, http.StatusBadRequest, "area name or prompt required")
 		return
 	}
+	var kind domain.AreaKind
+	if kindPtr != nil {
+		var ok bool
+		if kind, ok = domain.ParseAreaKind(*kindPtr); !ok {
+			s.renderError(w, r, http.StatusBadRequest, "unknown area kind")
+			return
+		}
+	}
 
-	name := strings.TrimSpace(body.Name)
-	if name == "" {
-		http.Error(w, "area name required", http.StatusBadRequest)
-		return
+	// Validate every field before the first write so a bad prompt cannot
+	// leave the area renamed.
+	var name string
+	if namePtr != nil {
+		name = strings.TrimSpace(*namePtr)
+		if name == "" {
+			s.renderError(w, r, http.StatusBadRequest, "area name required")
+			return
+		}
+		if len(name) > maxAreaNameLen {
+			s.renderError(w, r, http.StatusBadRequest, "area name too long")
+			return
+		}
 	}
-	if len(name) > maxAreaNameLen {
-		http.Error(w, "area name too long", http.StatusBadRequest)
+	if promptPtr != nil && len(*promptPtr) > maxAreaPromptLen {
+		s.renderError(w, r, http.StatusBadRequest, "area prompt too long")
 		return
 	}
 
-	area, err := s.service.UpdateArea(r.Context(), areaID, name)
-	if err != nil {
-		if errors.Is(err, service.ErrNameTaken) {
-			http.Error(w, "an area with this name already exists", http.StatusConflict)
+	var area *domain.Area
+	if namePtr != nil {
+		area, err = s.service.UpdateArea(r.Context(), areaID, name)
+		if err != nil {
+			if errors.Is(err, service.ErrNameTaken) {
+				s.renderError(w, r, http.StatusConflict, "an area with this name already exists")
+				return
+			}
+			s.renderError(w, r, http.StatusInternalServerError, "failed to update area")
+			s.log(r).Error("update area failed", "area_id", areaID, "error", err)
+			return
+		}
+	}
+	if promptPtr != nil {
+		area, err = s.service.SetAreaPrompt(r.Context(), areaID, *promptPtr)
+		switch {
+		case errors.Is(err, service.ErrAreaNotFound):
+			s.renderError(w, r, http.StatusNotFound, "area not found")
+			return
+		case err != nil:
+			s.renderError(w, r, http.StatusInternalServerError, "failed to update area")
+			s.log(r).Error("update area prompt failed", "area_id", areaID, "error", err)
+			return
+		}
+	}
+	if kindPtr != nil {
+		area, err = s.service.SetAreaKind(r.Context(), areaID, kind)
+		switch {
+		case errors.Is(err, service.ErrAreaNotFound):
+			s.renderError(w, r, http.StatusNotFound, "area not found")
+			return
+		case err != nil:
+			s.renderError(w, r, http.StatusInternalServerError, "failed to update area")
+			s.log(r).Error("update area kind failed", "area_id", areaID, "error", err)
 			return
 		}
-		http.Error(w, "failed to update area", http.StatusInternalServerError)
-		s.logger.Error("update area failed", "area_id", areaID, "error", err)
-		return
 	}
 
-	_, areaItems, areaPhoto, err := s.service.GetAreaWithItems(r.Context(), areaID)
+	summary, err := s.service.GetAreaSummary(r.Context(), areaID)
 	if err != nil {
-		http.Error(w, "failed to get area details", http.StatusInternalServerError)
-		s.logger.Error("get area failed after update", "area_id", areaID, "error", err)
+		s.renderError(w, r, http.StatusInternalServerError, "failed to get area details")
+		s.log(r).Error("get area failed after update", "area_id", areaID, "error", err)
 		return
 	}
 
-	summary := &service.AreaSummary{Area: area, Photo: areaPhoto, Items: areaItems}
-	if err := s.renderPartial(w, "partials/area_card.html", summary); err != nil {
-		s.logger.Error("render partial failed", "error", err)
+	if fromPlainForm(r) {
+		http.Redirect(w, r, fmt.Sprintf("/areas/%d", areaID), http.StatusSeeOther)
+		return
+	}
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(area)
+		return
+	}
+
+	if err := s.renderPartial(w, r, "partials/area_card.html", summary); err != nil {
+		s.log(r).Error("render partial failed", "error", err)
 	}
 }
 
@@ -140,7 +400,7 @@ func (s *Server) handleDeletePhoto(w http.ResponseWriter, r *http.Request) {
 
 	if err := s.service.DeletePhoto(r.Context(), areaID); err != nil {
 		http.Error(w, "failed to delete photo", http.StatusInternalServerError)
-		s.logger.Error("delete photo failed", "area_id", areaID, "error", err)
+		s.log(r).Error("delete photo failed", "area_id", areaID, "error", err)
 		return
 	}
 
@@ -152,8 +412,8 @@ func (s *Server) handleDeletePhoto(w http.ResponseWriter, r *http.Request) {
 	}
 
 	summary := &service.AreaSummary{Area: area}
-	if err := s.renderPartial(w, "partials/area_card.html", summary); err != nil {
-		s.logger.Error("render partial failed", "error", err)
+	if err := s.renderPartial(w, r, "partials/area_card.html", summary); err != nil {
+		s.log(r).Error("render partial failed", "error", err)
 	}
 }
 
@@ -165,15 +425,62 @@ func (s *Server) handleDeleteArea(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.service.DeleteArea(r.Context(), areaID); err != nil {
+		if errors.Is(err, service.ErrHasChildren) {
+			s.renderError(w, r, http.StatusConflict, "this area has sub-areas; delete them first")
+			return
+		}
 		http.Error(w, "failed to delete area", http.StatusInternalServerError)
-		s.logger.Error("delete area failed", "area_id", areaID, "error", err)
+		s.log(r).Error("delete area failed", "area_id", areaID, "error", err)
 		return
 	}
 
-	// Return empty body — HTMX will remove the card from the DOM.
+	if fromPlainForm(r) {
+		http.Redirect(w, r, "/areas", http.StatusSeeOther)
+		return
+	}
+	// Return empty body — HTMX will remove the card from the DOM. The
+	// areaDeleted event carries the restore URL so the client can offer undo.
+	trigger, _ := json.Marshal(map[string]any{
+		"areaDeleted": map[string]any{"id": areaID, "restoreUrl": fmt.Sprintf("/areas/%d/restore", areaID)},
+	})
+	w.Header().Set("HX-Trigger", string(trigger))
 	w.WriteHeader(http.StatusOK)
 }
 
+func (s *Server) handleRestoreArea(w http.ResponseWriter, r *http.Request) {
+	areaID, err := parseID(r)
+	if err != nil {
+		http.Error(w, "invalid area id", http.StatusBadRequest)
+		return
+	}
+
+	area, err := s.service.RestoreArea(r.Context(), areaID)
+	if err != nil {
+		if errors.Is(err, service.ErrNameTaken) {
+			http.Error(w, "an area with this name already exists", http.StatusConflict)
+			return
+		}
+		http.Error(w, "failed to restore area", http.StatusInternalServerError)
+		s.log(r).Error("restore area failed", "area_id", areaID, "error", err)
+		return
+	}
+	if area == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	summary, err := s.service.GetAreaSummary(r.Context(), areaID)
+	if err != nil {
+		http.Error(w, "failed to get area details", http.StatusInternalServerError)
+		s.log(r).Error("get area failed after restore", "area_id", areaID, "error", err)
+		return
+	}
+
+	if err := s.renderPartial(w, r, "partials/area_card.html", summary); err != nil {
+		s.log(r).Error("render partial failed", "error", err)
+	}
+}
+
 func (s *Server) handleGetAreaCard(w http.ResponseWriter, r *http.Request) {
 	areaID, err := parseID(r)
 	if err != nil {
@@ -181,20 +488,19 @@ func (s *Server) handleGetAreaCard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	area, items, photo, err := s.service.GetAreaWithItems(r.Context(), areaID)
+	summary, err := s.service.GetAreaSummary(r.Context(), areaID)
 	if err != nil {
 		http.Error(w, "failed to get area", http.StatusInternalServerError)
-		s.logger.Error("get area card failed", "area_id", areaID, "error", err)
+		s.log(r).Error("get area card failed", "area_id", areaID, "error", err)
 		return
 	}
-	if area == nil {
+	if summary == nil {
 		http.NotFound(w, r)
 		return
 	}
 
-	summary := &service.AreaSummary{Area: area, Photo: photo, Items: items}
-	if err := s.renderPartial(w, "partials/area_card.html", summary); err != nil {
-		s.logger.Error("render partial failed", "error", err)
+	if err := s.renderPartial(w, r, "partials/area_card.html", summary); err != nil {
+		s.log(r).Error("render partial failed", "error", err)
 	}
 }
 
@@ -205,99 +511,223 @@ func (s *Server) handleGetAreaItems(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, items, _, err := s.service.GetAreaWithItems(r.Context(), areaID)
+	page, paged, err := s.readItemPage(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Polling clients send back the ETag, so an area that hasn't changed
+	// costs one query and no rendering. The version is read before the
+	// items, so a change in between only makes the next poll fetch again.
+	version, err := s.service.AreaItemsVersion(r.Context(), areaID)
+	if s.notModifiedVersion(w, r, version, err) {
+		return
+	}
+
+	var items []*domain.Item
+	var next string
+	if paged {
+		items, next, err = fetchPage(r.URL, page, func(p domain.ItemPage) ([]*domain.Item, error) {
+			return s.service.ListItemsPage(r.Context(), areaID, p)
+		})
+	} else if page.Status != "" {
+		items, err = s.service.ListItemsPage(r.Context(), areaID, page)
+	} else {
+		_, items, _, err = s.service.GetAreaWithItems(r.Context(), areaID)
+	}
 	if err != nil {
+		w.Header().Del("ETag")
 		http.Error(w, "failed to get items", http.StatusInternalServerError)
-		s.logger.Error("get area items failed", "area_id", areaID, "error", err)
+		s.log(r).Error("get area items failed", "area_id", areaID, "error", err)
 		return
 	}
 
 	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		if next != "" {
+			w.Header().Set("Link", "<"+next+`>; rel="next"`)
+		}
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(items)
 		return
 	}
 
-	data := map[string]any{"AreaID": areaID, "Items": items}
-	if err := s.renderPartial(w, "partials/item_list.html", data); err != nil {
-		s.logger.Error("render partial failed", "error", err)
+	// Later pages are appended to the list that asked for them, so they are
+	// just the rows.
+	file := "partials/item_list.html"
+	if page.Offset > 0 {
+		file = "partials/item_page.html"
+	}
+	data := map[string]any{"AreaID": areaID, "Items": items, "Paged": paged, "Next": next}
+	if err := s.renderPartial(w, r, file, data); err != nil {
+		s.log(r).Error("render partial failed", "error", err)
 	}
 }
 
 func (s *Server) handleCreateItem(w http.ResponseWriter, r *http.Request) {
 	areaID, err := parseID(r)
 	if err != nil {
-		http.Error(w, "invalid area id", http.StatusBadRequest)
+		s.renderError(w, r, http.StatusBadRequest, "invalid area id")
 		return
 	}
 
-	var body struct {
-		Name     string `json:"name"`
-		Quantity string `json:"quantity"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+	name, quantity, _, ok := s.readItemFields(w, r)
+	if !ok {
 		return
 	}
 
-	name := strings.TrimSpace(body.Name)
-	if name == "" {
-		http.Error(w, "item name required", http.StatusBadRequest)
+	item, err := s.service.CreateItem(r.Context(), areaID, name, quantity)
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "failed to create item")
+		s.log(r).Error("create item failed", "area_id", areaID, "error", err)
 		return
 	}
 
-	item, err := s.service.CreateItem(r.Context(), areaID, name, strings.TrimSpace(body.Quantity))
+	s.writeItem(w, r, http.StatusOK, item)
+}
+
+// handleCreateItemFromBarcode adds an item from a scanned barcode, taken
+// from the "barcode" JSON or form field. The item is named from the product
+// database, or after the barcode when the product isn't found.
+func (s *Server) handleCreateItemFromBarcode(w http.ResponseWriter, r *http.Request) {
+	areaID, err := parseID(r)
 	if err != nil {
-		http.Error(w, "failed to create item", http.StatusInternalServerError)
-		s.logger.Error("create item failed", "area_id", areaID, "error", err)
+		s.renderError(w, r, http.StatusBadRequest, "invalid area id")
+		return
+	}
+	fields, err := readFields(r, "barcode")
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	var barcode string
+	if v := fields["barcode"]; v != nil {
+		barcode = *v
+	}
+
+	item, err := s.service.CreateItemFromBarcode(r.Context(), areaID, barcode)
+	switch {
+	case errors.Is(err, service.ErrInvalidBarcode):
+		s.renderError(w, r, http.StatusBadRequest, "barcode must be 8 to 14 digits")
+		return
+	case errors.Is(err, service.ErrAreaNotFound):
+		s.renderError(w, r, http.StatusNotFound, "area not found")
+		return
+	case err != nil:
+		s.renderError(w, r, http.StatusInternalServerError, "failed to create item")
+		s.log(r).Error("create item from barcode failed", "area_id", areaID, "error", err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(item)
+	s.writeItem(w, r, http.StatusOK, item)
 }
 
 func (s *Server) handleUpdateItem(w http.ResponseWriter, r *http.Request) {
 	_, err := parseID(r) // areaID — validates the path
 	if err != nil {
-		http.Error(w, "invalid area id", http.StatusBadRequest)
+		s.renderError(w, r, http.StatusBadRequest, "invalid area id")
 		return
 	}
 
 	itemID, err := parseItemID(r)
 	if err != nil {
-		http.Error(w, "invalid item id", http.StatusBadRequest)
+		s.renderError(w, r, http.StatusBadRequest, "invalid item id")
 		return
 	}
 
-	var body struct {
-		Name     string `json:"name"`
-		Quantity string `json:"quantity"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+	name, quantity, version, ok := s.readItemFields(w, r)
+	if !ok {
 		return
 	}
+	if version == 0 {
+		if version, err = parseETagVersion(r.Header.Get("If-Match")); err != nil {
+			s.renderError(w, r, http.StatusBadRequest, "invalid If-Match version")
+			return
+		}
+	}
 
-	name := strings.TrimSpace(body.Name)
-	if name == "" {
-		http.Error(w, "item name required", http.StatusBadRequest)
+	item, err := s.service.UpdateItemIfCurrent(r.Context(), itemID, version, name, quantity)
+	if errors.Is(err, domain.ErrItemConflict) && item != nil {
+		// Hand back what the other editor saved so the client can show it.
+		s.writeItem(w, r, http.StatusConflict, item)
 		return
 	}
-
-	item, err := s.service.UpdateItem(r.Context(), itemID, name, strings.TrimSpace(body.Quantity))
 	if err != nil {
-		http.Error(w, "failed to update item", http.StatusInternalServerError)
-		s.logger.Error("update item failed", "item_id", itemID, "error", err)
+		s.renderError(w, r, http.StatusInternalServerError, "failed to update item")
+		s.log(r).Error("update item failed", "item_id", itemID, "error", err)
 		return
 	}
 
+	s.writeItem(w, r, http.StatusOK, item)
+}
+
+// readItemFields reads and validates the name and quantity of an item create
+// or update, plus the optional version an update expects, writing a 400, or
+// a 422 naming the fields over their limits, and returning ok=false if they
+// are unusable. Control characters are dropped from both fields.
+func (s *Server) readItemFields(w http.ResponseWriter, r *http.Request) (name, quantity string, version int64, ok bool) {
+	fields, err := readFields(r, "name", "quantity", "version")
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "invalid request body")
+		return "", "", 0, false
+	}
+	if v := fields["version"]; v != nil && *v != "" {
+		if version, err = strconv.ParseInt(*v, 10, 64); err != nil || version <= 0 {
+			s.renderError(w, r, http.StatusBadRequest, "version must be a positive integer")
+			return "", "", 0, false
+		}
+	}
+	if fields["name"] != nil {
+		name = domain.CleanItemText(*fields["name"])
+	}
+	if fields["quantity"] != nil {
+		quantity = domain.CleanItemText(*fields["quantity"])
+	}
+	if errs := s.itemLimits.Check(name, quantity); len(errs) > 0 {
+		s.renderFieldErrors(w, r, errs)
+		return "", "", 0, false
+	}
+	return name, quantity, version, true
+}
+
+// parseETagVersion reads an item version from an If-Match value such as
+// "3" or W/"3". An empty value or * is version 0, meaning no check.
+func parseETagVersion(v string) (int64, error) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+	if v == "" || v == "*" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(strings.Trim(v, `"`), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid item version %q", v)
+	}
+	return n, nil
+}
+
+// writeItem responds with status and the item_row partial for HTMX requests,
+// or the item as JSON otherwise. The ETag carries the item's version for
+// If-Match.
+func (s *Server) writeItem(w http.ResponseWriter, r *http.Request, status int, item *domain.Item) {
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, item.Version))
+	if isHTMX(r) {
+		out, err := s.renderFragment("partials/item_row.html", map[string]any{"Item": item})
+		if err != nil {
+			s.renderError(w, r, http.StatusInternalServerError, renderFailedMessage)
+			s.log(r).Error("render partial failed", "error", err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, out)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(item)
 }
 
 func (s *Server) handleDeleteItem(w http.ResponseWriter, r *http.Request) {
-	_, err := parseID(r) // areaID — validates the path
+	areaID, err := parseID(r)
 	if err != nil {
 		http.Error(w, "invalid area id", http.StatusBadRequest)
 		return
@@ -311,13 +741,71 @@ func (s *Server) handleDeleteItem(w http.ResponseWriter, r *http.Request) {
 
 	if err := s.service.DeleteItem(r.Context(), itemID); err != nil {
 		http.Error(w, "failed to delete item", http.StatusInternalServerError)
-		s.logger.Error("delete item failed", "item_id", itemID, "error", err)
+		s.log(r).Error("delete item failed", "item_id", itemID, "error", err)
 		return
 	}
 
+	if fromPlainForm(r) {
+		http.Redirect(w, r, fmt.Sprintf("/areas/%d", areaID), http.StatusSeeOther)
+		return
+	}
 	w.WriteHeader(http.StatusOK)
 }
 
+// maxBulkItemOps bounds a single bulk request; a photo rarely yields more
+// than a few dozen items.
+const maxBulkItemOps = 500
+
+// handleBulkItems applies a JSON array of item operations atomically and
+// responds with the refreshed item list, as JSON or the item_list partial
+// depending on the Accept header. Rejected entries yield 422 with their
+// indices and nothing is applied.
+func (s *Server) handleBulkItems(w http.ResponseWriter, r *http.Request) {
+	areaID, err := parseID(r)
+	if err != nil {
+		http.Error(w, "invalid area id", http.StatusBadRequest)
+		return
+	}
+
+	var ops []domain.ItemOp
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(ops) > maxBulkItemOps {
+		http.Error(w, fmt.Sprintf("too many operations (max %d)", maxBulkItemOps), http.StatusBadRequest)
+		return
+	}
+
+	items, err := s.service.BulkEditItems(r.Context(), areaID, ops)
+	if err != nil {
+		var bulkErr *service.BulkItemError
+		switch {
+		case errors.As(err, &bulkErr):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_ = json.NewEncoder(w).Encode(map[string]any{"errors": bulkErr.Failures})
+		case errors.Is(err, service.ErrAreaNotFound):
+			http.Error(w, "area not found", http.StatusNotFound)
+		default:
+			http.Error(w, "failed to apply item changes", http.StatusInternalServerError)
+			s.log(r).Error("bulk item edit failed", "area_id", areaID, "error", err)
+		}
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(items)
+		return
+	}
+
+	data := map[string]any{"AreaID": areaID, "Items": items}
+	if err := s.renderPartial(w, r, "partials/item_list.html", data); err != nil {
+		s.log(r).Error("render partial failed", "error", err)
+	}
+}
+
 func (s *Server) handleReorderAreas(w http.ResponseWriter, r *http.Request) {
 	var body struct {
 		IDs []int64 `json:"ids"`
@@ -328,12 +816,61 @@ func (s *Server) handleReorderAreas(w http.ResponseWriter, r *http.Request) {
 	}
 	if err := s.service.ReorderAreas(r.Context(), body.IDs); err != nil {
 		http.Error(w, "failed to reorder areas", http.StatusInternalServerError)
-		s.logger.Error("reorder areas failed", "error", err)
+		s.log(r).Error("reorder areas failed", "error", err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
+// handleSetAreaOrder sets the manual area order from the area IDs in the
+// body, first to last: a JSON array, or repeated "id" form fields as an HTMX
+// drag-and-drop form sends them. Areas left out keep their current position.
+func (s *Server) handleSetAreaOrder(w http.ResponseWriter, r *http.Request) {
+	ids, err := readIDList(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.service.ReorderAreas(r.Context(), ids); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "failed to reorder areas")
+		s.log(r).Error("reorder areas failed", "error", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readIDList reads a non-empty list of distinct IDs for handleSetAreaOrder.
+func readIDList(r *http.Request) ([]int64, error) {
+	var ids []int64
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/x-www-form-urlencoded" {
+		if err := r.ParseForm(); err != nil {
+			return nil, errors.New("invalid request body")
+		}
+		for _, v := range r.PostForm["id"] {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid area id %q", v)
+			}
+			ids = append(ids, id)
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&ids); err != nil {
+		return nil, errors.New("expected a JSON array of area ids")
+	}
+
+	if len(ids) == 0 {
+		return nil, errors.New("area ids required")
+	}
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if id <= 0 || seen[id] {
+			return nil, fmt.Errorf("invalid or repeated area id %d", id)
+		}
+		seen[id] = true
+	}
+	return ids, nil
+}
+
 func (s *Server) handleListSnapshots(w http.ResponseWriter, r *http.Request) {
 	areaID, err := parseID(r)
 	if err != nil {
@@ -344,7 +881,7 @@ func (s *Server) handleListSnapshots(w http.ResponseWriter, r *http.Request) {
 	snapshots, err := s.service.ListSnapshots(r.Context(), areaID)
 	if err != nil {
 		http.Error(w, "failed to list snapshots", http.StatusInternalServerError)
-		s.logger.Error("list snapshots failed", "area_id", areaID, "error", err)
+		s.log(r).Error("list snapshots failed", "area_id", areaID, "error", err)
 		return
 	}
 
@@ -352,6 +889,69 @@ func (s *Server) handleListSnapshots(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(snapshots)
 }
 
+// maxFormMemory bounds the multipart form fields held in memory; the
+// update endpoints only take short text fields.
+const maxFormMemory = 1 << 20
+
+// readFields reads the named string fields from the request body: form fields
+// when the Content-Type is URL-encoded or multipart, a JSON object otherwise.
+// A field absent from the body is nil, so callers can tell it from "".
+func readFields(r *http.Request, names ...string) (map[string]*string, error) {
+	fields := make(map[string]*string, len(names))
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-www-form-urlencoded", "multipart/form-data":
+		var err error
+		if mediaType == "multipart/form-data" {
+			err = r.ParseMultipartForm(maxFormMemory)
+		} else {
+			err = r.ParseForm()
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if vs, ok := r.PostForm[name]; ok && len(vs) > 0 {
+				fields[name] = &vs[0]
+			}
+		}
+		return fields, nil
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		raw, ok := body[name]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			// Numbers, such as an item version, are kept as their literal text.
+			var n json.Number
+			if json.Unmarshal(raw, &n) != nil {
+				return nil, fmt.Errorf("field %q: %w", name, err)
+			}
+			v = n.String()
+		}
+		fields[name] = &v
+	}
+	return fields, nil
+}
+
+// isHTMX reports whether the request was issued by HTMX, which expects HTML.
+func isHTMX(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true"
+}
+
+// wantsJSON reports whether an API caller asked for JSON. HTMX requests always
+// get HTML.
+func wantsJSON(r *http.Request) bool {
+	return !isHTMX(r) && strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
 // parseID extracts the {id} path variable and returns it as int64.
 func parseID(r *http.Request) (int64, error) {
 	return strconv.ParseInt(r.PathValue("id"), 10, 64)
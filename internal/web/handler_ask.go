@@ -0,0 +1,35 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const maxAskQuestionLen = 300
+
+// handleAsk answers the question in q about the inventory as JSON: the
+// model's answer plus the items matching the question, each with its
+// AreaID so clients can link to it. When the model can't answer, Fallback
+// is set and only the matches are returned.
+func (s *Server) handleAsk(w http.ResponseWriter, r *http.Request) {
+	question := strings.TrimSpace(r.URL.Query().Get("q"))
+	switch {
+	case question == "":
+		s.renderError(w, r, http.StatusBadRequest, "missing question")
+		return
+	case len(question) > maxAskQuestionLen:
+		s.renderError(w, r, http.StatusBadRequest, "question is too long")
+		return
+	}
+
+	ans, err := s.service.Ask(r.Context(), question)
+	if err != nil {
+		s.log(r).Error("ask failed", "question", question, "error", err)
+		s.renderError(w, r, http.StatusInternalServerError, "failed to answer the question")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(ans)
+}
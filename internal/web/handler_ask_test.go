@@ -0,0 +1,93 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/service"
+)
+
+type fakeAskService struct {
+	fakeOverrideService
+	answer   *service.AskAnswer
+	err      error
+	question string
+}
+
+func (f *fakeAskService) CanSuggestRecipes() bool { return true }
+
+func (f *fakeAskService) Ask(_ context.Context, question string) (*service.AskAnswer, error) {
+	f.question = question
+	return f.answer, f.err
+}
+
+func getAsk(srv *Server, q string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/ask?q="+url.QueryEscape(q), nil)
+	req.RemoteAddr = "192.0.2.9:4444"
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleAsk(t *testing.T) {
+	svc := &fakeAskService{answer: &service.AskAnswer{
+		Question: "do we have eggs?",
+		Answer:   "Yes, in the Fridge.",
+		Items:    []*domain.RecentItem{{Item: domain.Item{ID: 3, AreaID: 7, Name: "Eggs", Quantity: "6"}, AreaName: "Fridge"}},
+	}}
+	rec := getAsk(newOverrideTestServer(svc), "  do we have eggs?  ")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "do we have eggs?", svc.question)
+
+	var got struct {
+		Answer   string
+		Fallback bool
+		Items    []struct {
+			Name     string
+			AreaID   int64
+			AreaName string
+		}
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "Yes, in the Fridge.", got.Answer)
+	assert.False(t, got.Fallback)
+	require.Len(t, got.Items, 1)
+	assert.Equal(t, int64(7), got.Items[0].AreaID)
+	assert.Equal(t, "Fridge", got.Items[0].AreaName)
+}
+
+func TestHandleAsk_Errors(t *testing.T) {
+	srv := newOverrideTestServer(&fakeAskService{err: errors.New("db gone")})
+	assert.Equal(t, http.StatusBadRequest, getAsk(srv, " ").Code)
+	assert.Equal(t, http.StatusBadRequest, getAsk(srv, strings.Repeat("x", maxAskQuestionLen+1)).Code)
+	assert.Equal(t, http.StatusInternalServerError, getAsk(srv, "eggs?").Code)
+}
+
+func TestHandleAsk_RateLimited(t *testing.T) {
+	srv := newOverrideTestServer(&fakeAskService{answer: &service.AskAnswer{}}).WithAskRateLimit(1, 2)
+	assert.Equal(t, http.StatusOK, getAsk(srv, "eggs?").Code)
+	assert.Equal(t, http.StatusOK, getAsk(srv, "milk?").Code)
+	rec := getAsk(srv, "jam?")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+}
+
+func TestSearchPageAskPanel(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newOverrideTestServer(&fakeAskService{}).ServeHTTP(rec, httptest.NewRequest("GET", "/search", nil))
+	assert.Contains(t, rec.Body.String(), `data-testid="ask-panel"`)
+
+	rec = httptest.NewRecorder()
+	newOverrideTestServer(&fakeOverrideService{}).ServeHTTP(rec, httptest.NewRequest("GET", "/search", nil))
+	assert.NotContains(t, rec.Body.String(), `data-testid="ask-panel"`, "hidden when the backend can't generate text")
+}
@@ -0,0 +1,179 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/audit"
+)
+
+// auditPageSize caps how many entries /audit returns.
+const auditPageSize = 200
+
+// auditRow is an audit entry as the page shows it.
+type auditRow struct {
+	*audit.Entry
+	AreaLabel string
+	Summary   string
+}
+
+// handleListAudit shows the audit log, newest first. ?area_id= narrows it to
+// one area; ?since= and ?until= take dates (YYYY-MM-DD, both inclusive) or
+// RFC 3339 times.
+func (s *Server) handleListAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := audit.Filter{Limit: auditPageSize}
+	if v := q.Get("area_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			s.renderError(w, r, http.StatusBadRequest, "invalid area id")
+			return
+		}
+		f.AreaID = id
+	}
+	var err error
+	if f.Since, err = s.parseSince(q.Get("since")); err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "since must be a date (YYYY-MM-DD) or an RFC 3339 time")
+		return
+	}
+	if f.Until, err = s.parseUntil(q.Get("until")); err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "until must be a date (YYYY-MM-DD) or an RFC 3339 time")
+		return
+	}
+
+	entries, err := s.service.ListAuditEntries(r.Context(), f)
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "failed to list audit log")
+		s.log(r).Error("list audit entries failed", "error", err)
+		return
+	}
+
+	if wantsJSON(r) {
+		if entries == nil {
+			entries = []*audit.Entry{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(entries)
+		return
+	}
+
+	areas, err := s.service.ListAreas(r.Context())
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "failed to list audit log")
+		s.log(r).Error("list areas failed", "error", err)
+		return
+	}
+	paths := make(map[int64]string, len(areas))
+	for _, a := range areas {
+		paths[a.ID] = a.Path()
+	}
+	rows := make([]auditRow, len(entries))
+	for i, e := range entries {
+		rows[i] = auditRow{Entry: e, AreaLabel: auditAreaLabel(e, paths), Summary: auditSummary(e)}
+	}
+
+	if err := s.renderPage(w, r, "audit", map[string]any{
+		"Rows": rows, "Areas": areas, "AreaID": f.AreaID,
+		"Since": q.Get("since"), "Until": q.Get("until"), "ActiveNav": "audit",
+	}); err != nil {
+		s.log(r).Error("render page failed", "error", err)
+	}
+}
+
+// parseUntil reads a ?until= value. A bare date includes the whole day, so
+// the bound is the following midnight.
+func (s *Server) parseUntil(v string) (time.Time, error) {
+	t, err := s.parseSince(v)
+	if err != nil || t.IsZero() {
+		return t, err
+	}
+	if _, dateErr := time.ParseInLocation("2006-01-02", v, s.loc); dateErr == nil {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// auditAreaLabel names the entry's area, falling back to the name recorded
+// with an area action, or the bare ID once the area is gone.
+func auditAreaLabel(e *audit.Entry, paths map[int64]string) string {
+	if e.AreaID == nil {
+		return ""
+	}
+	if p, ok := paths[*e.AreaID]; ok {
+		return p
+	}
+	if name, ok := e.Detail["name"].(string); ok && e.EntityType == audit.EntityArea {
+		return name
+	}
+	return fmt.Sprintf("Area #%d", *e.AreaID)
+}
+
+// auditSummary describes an entry in a sentence.
+func auditSummary(e *audit.Entry) string {
+	removed := detailList(e.Detail, "removed")
+	switch e.Action {
+	case audit.ActionAreaDelete:
+		return fmt.Sprintf("Moved area %q to the trash", detailString(e.Detail, "name"))
+	case audit.ActionAreaRestore:
+		return fmt.Sprintf("Restored area %q from the trash", detailString(e.Detail, "name"))
+	case audit.ActionPhotoDelete:
+		return "Removed the photo" + removedSuffix(removed)
+	case audit.ActionItemDelete:
+		return "Deleted " + itemLabel(detailString(e.Detail, "name"), detailString(e.Detail, "quantity"))
+	case audit.ActionItemsReplace:
+		return fmt.Sprintf("Re-upload added %d item(s)", detailInt(e.Detail, "added")) + removedSuffix(removed)
+	case audit.ActionItemsBulk:
+		return fmt.Sprintf("Bulk edit created %d and updated %d item(s)",
+			detailInt(e.Detail, "created"), detailInt(e.Detail, "updated")) + removedSuffix(removed)
+	}
+	return e.Action
+}
+
+func removedSuffix(removed []string) string {
+	if len(removed) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" and removed %d item(s): %s", len(removed), strings.Join(removed, ", "))
+}
+
+func itemLabel(name, quantity string) string {
+	if quantity == "" {
+		return name
+	}
+	return name + " (" + quantity + ")"
+}
+
+func detailString(d map[string]any, key string) string {
+	v, _ := d[key].(string)
+	return v
+}
+
+// detailInt reads a count; details decoded from JSON hold numbers as float64.
+func detailInt(d map[string]any, key string) int {
+	switch v := d[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+func detailList(d map[string]any, key string) []string {
+	var out []string
+	switch v := d[key].(type) {
+	case []any:
+		for _, x := range v {
+			if s, ok := x.(string); ok {
+				out = append(out, s)
+			}
+		}
+	case []string:
+		out = v
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/ical"
+)
+
+// WithCalendarToken serves the expiry calendar at /calendar.ics to requests
+// whose token query parameter equals token. Calendar apps can't log in, so
+// the token in the subscription URL is the only access control. Without a
+// token the feed is off.
+func (s *Server) WithCalendarToken(token string) *Server {
+	s.calendarToken = token
+	return s
+}
+
+// handleCalendar serves an iCalendar feed with an all-day event on the
+// estimated expiry date of each item a shelf-life rule applies to.
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	if s.calendarToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(s.calendarToken)) != 1 {
+		s.renderError(w, r, http.StatusForbidden, "invalid calendar token")
+		return
+	}
+
+	items, err := s.service.ListExpiringItems(r.Context(), s.loc)
+	if err != nil {
+		s.log(r).Error("failed to list expiring items", "error", err)
+		s.renderError(w, r, http.StatusInternalServerError, "failed to build calendar")
+		return
+	}
+
+	now := time.Now()
+	cal := ical.Calendar{ProdID: "-//kitchinv//expiry calendar//EN", Name: "kitchinv expiry dates"}
+	for _, it := range items {
+		var desc strings.Builder
+		if it.Quantity != "" {
+			fmt.Fprintf(&desc, "Quantity: %s\n", it.Quantity)
+		}
+		fmt.Fprintf(&desc, "Added %s. Estimated from a shelf life of %d days for %q.",
+			formatIn(it.CreatedAt, s.loc, dateLayout), it.ShelfLife.Days, it.ShelfLife.Keyword)
+		cal.Events = append(cal.Events, ical.Event{
+			UID:         fmt.Sprintf("item-%d@kitchinv", it.ID),
+			Sequence:    it.Sequence,
+			Date:        it.ExpiresOn,
+			Summary:     fmt.Sprintf("%s expires — %s", it.Name, it.AreaName),
+			Description: desc.String(),
+			Stamp:       now,
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="kitchinv.ics"`)
+	w.Header().Set("Cache-Control", "no-store")
+	if err := ical.Encode(w, cal); err != nil {
+		s.log(r).Error("failed to write calendar", "error", err)
+	}
+}
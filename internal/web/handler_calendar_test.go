@@ -0,0 +1,70 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/service"
+)
+
+type fakeCalendarService struct {
+	fakeOverrideService
+	items []*service.ExpiringItem
+	loc   *time.Location
+}
+
+func (f *fakeCalendarService) ListExpiringItems(_ context.Context, loc *time.Location) ([]*service.ExpiringItem, error) {
+	f.loc = loc
+	return f.items, nil
+}
+
+func TestHandleCalendar(t *testing.T) {
+	loc := time.FixedZone("UTC+10", 10*3600)
+	svc := &fakeCalendarService{items: []*service.ExpiringItem{{
+		RecentItem: &domain.RecentItem{
+			Item:     domain.Item{ID: 12, Name: "Milk, 2%", Quantity: "1", CreatedAt: time.Date(2026, 3, 13, 20, 0, 0, 0, time.UTC)},
+			AreaName: "Fridge",
+		},
+		ShelfLife: service.ShelfLife{Keyword: "milk", Days: 7},
+		ExpiresOn: time.Date(2026, 3, 21, 0, 0, 0, 0, loc),
+		Sequence:  3,
+	}}}
+	srv := newOverrideTestServer(svc).WithLocation(loc).WithCalendarToken("s3cret")
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/calendar.ics?token=s3cret", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/calendar; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, loc, svc.loc)
+	body := rec.Body.String()
+	for _, want := range []string{
+		"UID:item-12@kitchinv\r\n",
+		"SEQUENCE:3\r\n",
+		"DTSTART;VALUE=DATE:20260321\r\n",
+		`SUMMARY:Milk\, 2% expires — Fridge` + "\r\n",
+		`DESCRIPTION:Quantity: 1\nAdded 14 Mar 2026.`,
+	} {
+		assert.Contains(t, body, want)
+	}
+	assert.True(t, strings.HasPrefix(body, "BEGIN:VCALENDAR\r\n"))
+
+	for _, target := range []string{"/calendar.ics", "/calendar.ics?token=wrong"} {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		assert.Equal(t, http.StatusForbidden, rec.Code, target)
+	}
+}
+
+func TestHandleCalendar_Disabled(t *testing.T) {
+	srv := newOverrideTestServer(&fakeCalendarService{})
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/calendar.ics?token=", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
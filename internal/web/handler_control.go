@@ -0,0 +1,95 @@
+package web
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/vbonduro/kitchinv/internal/db"
+	"github.com/vbonduro/kitchinv/internal/service"
+)
+
+// testReset is what POST /control/reset wipes.
+type testReset struct {
+	db        *sql.DB
+	photoPath string
+}
+
+// EnableTestMode serves POST /control/reset, which deletes every row in
+// database and every file under photoPath, if set, so an end-to-end test can
+// start from empty without restarting the server, and POST /control/seed,
+// which loads a dataset. Without it both routes answer 404 as if they did not
+// exist. Never enable it where anyone but the test runner can reach the
+// server.
+func (s *Server) EnableTestMode(database *sql.DB, photoPath string) *Server {
+	s.testReset = &testReset{db: database, photoPath: photoPath}
+	return s
+}
+
+func (s *Server) handleControlReset(w http.ResponseWriter, r *http.Request) {
+	if s.testReset == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := db.Reset(s.testReset.db); err != nil {
+		s.log(r).Error("reset database failed", "error", err)
+		http.Error(w, "failed to reset database", http.StatusInternalServerError)
+		return
+	}
+	if dir := s.testReset.photoPath; dir != "" {
+		if err := clearDir(dir); err != nil {
+			s.log(r).Error("clear photos failed", "path", dir, "error", err)
+			http.Error(w, "failed to clear photos", http.StatusInternalServerError)
+			return
+		}
+	}
+	s.log(r).Warn("test mode reset: deleted all data and photos")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleControlSeed loads the service.SeedData in the request body, or
+// service.DefaultSeedData if the body is empty, and answers with the IDs
+// created.
+func (s *Server) handleControlSeed(w http.ResponseWriter, r *http.Request) {
+	if s.testReset == nil {
+		http.NotFound(w, r)
+		return
+	}
+	var data service.SeedData
+	if err := json.NewDecoder(r.Body).Decode(&data); errors.Is(err, io.EOF) {
+		data = service.DefaultSeedData
+	} else if err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	res, err := s.service.Seed(r.Context(), &data)
+	if err != nil {
+		// Anything that fails here is almost always the dataset's fault.
+		s.log(r).Warn("seed failed", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// clearDir removes everything inside dir but leaves dir itself, which the
+// photo store expects to exist.
+func clearDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", e.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package web
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/db"
+	"github.com/vbonduro/kitchinv/internal/service"
+	"github.com/vbonduro/kitchinv/internal/web/templates"
+)
+
+func TestHandleControlReset(t *testing.T) {
+	dir := t.TempDir()
+	database, err := db.Open(filepath.Join(dir, "kitchinv.db"), db.Options{})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = database.Close() })
+	_, err = database.Exec("INSERT INTO areas (name) VALUES ('Fridge')")
+	require.NoError(t, err)
+	photos := filepath.Join(dir, "photos")
+	require.NoError(t, os.MkdirAll(filepath.Join(photos, "sha256", "ab"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(photos, "sha256", "ab", "abcd"), []byte("jpeg"), 0o644))
+
+	srv := NewServer(&fakeOverrideService{}, templates.FS, nil, slog.Default())
+	reset := func() int {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest("POST", "/control/reset", nil))
+		return rec.Code
+	}
+	require.Equal(t, http.StatusNotFound, reset(), "hidden unless test mode is on")
+
+	srv.EnableTestMode(database, photos)
+	require.Equal(t, http.StatusNoContent, reset())
+
+	var n int
+	require.NoError(t, database.QueryRow("SELECT count(*) FROM areas").Scan(&n))
+	assert.Zero(t, n)
+	entries, err := os.ReadDir(photos)
+	require.NoError(t, err, "the photo directory itself is kept")
+	assert.Empty(t, entries)
+}
+
+type fakeSeedService struct {
+	fakeOverrideService
+	got *service.SeedData
+}
+
+func (f *fakeSeedService) Seed(_ context.Context, data *service.SeedData) (*service.SeedResult, error) {
+	f.got = data
+	return &service.SeedResult{Areas: []service.SeededArea{{ID: 1, Name: data.Areas[0].Name, Items: []service.SeededItem{}}}}, nil
+}
+
+func TestHandleControlSeed(t *testing.T) {
+	svc := &fakeSeedService{}
+	srv := NewServer(svc, templates.FS, nil, slog.Default())
+	seed := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest("POST", "/control/seed", strings.NewReader(body)))
+		return rec
+	}
+	require.Equal(t, http.StatusNotFound, seed("").Code, "hidden unless test mode is on")
+
+	srv.EnableTestMode(nil, t.TempDir())
+	rec := seed("")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, service.DefaultSeedData, *svc.got, "an empty body seeds the default dataset")
+	assert.JSONEq(t, `{"areas":[{"id":1,"name":"Fridge","items":[]}]}`, rec.Body.String())
+
+	rec = seed(`{"areas":[{"name":"Garage","photo_color":"#112233","items":[{"name":"Water","quantity":"24"}]}]}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "Garage", svc.got.Areas[0].Name)
+	assert.Equal(t, "24", svc.got.Areas[0].Items[0].Quantity)
+	assert.Equal(t, "Fridge", service.DefaultSeedData.Areas[0].Name, "the default dataset is never overwritten")
+
+	assert.Equal(t, http.StatusBadRequest, seed(`{"areas":`).Code)
+}
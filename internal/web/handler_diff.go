@@ -0,0 +1,49 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/vbonduro/kitchinv/internal/service"
+)
+
+// handleAreaDiff shows what the area's latest analysis changed: items added,
+// removed, and whose quantity changed. JSON with Accept: application/json.
+func (s *Server) handleAreaDiff(w http.ResponseWriter, r *http.Request) {
+	areaID, err := parseID(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "invalid area id")
+		return
+	}
+
+	diff, err := s.service.DiffLatestAnalysis(r.Context(), areaID)
+	switch {
+	case errors.Is(err, service.ErrAreaNotFound):
+		s.renderError(w, r, http.StatusNotFound, "area not found")
+		return
+	case errors.Is(err, service.ErrNoPhoto):
+		s.renderError(w, r, http.StatusNotFound, "this area has no analysed photo yet")
+		return
+	case err != nil:
+		s.renderError(w, r, http.StatusInternalServerError, "failed to compare the inventory")
+		s.log(r).Error("diff latest analysis failed", "area_id", areaID, "error", err)
+		return
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(diff)
+		return
+	}
+
+	area, err := s.service.GetArea(r.Context(), areaID)
+	if err != nil || area == nil {
+		s.renderError(w, r, http.StatusInternalServerError, "failed to compare the inventory")
+		s.log(r).Error("get area failed", "area_id", areaID, "error", err)
+		return
+	}
+	if err := s.renderPage(w, r, "area_diff", map[string]any{"Area": area, "Diff": diff}); err != nil {
+		s.log(r).Error("render page failed", "error", err)
+	}
+}
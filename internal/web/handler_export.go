@@ -0,0 +1,73 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/export"
+	"github.com/vbonduro/kitchinv/internal/service"
+)
+
+// handleExportMarkdown serves the inventory as a Markdown document, grouped
+// by area, for pasting into a notes app. Areas without items are left out
+// unless include_empty is set. The document is written area by area, so an
+// error part way through can only be logged.
+func (s *Server) handleExportMarkdown(w http.ResponseWriter, r *http.Request) {
+	includeEmpty, _ := strconv.ParseBool(r.URL.Query().Get("include_empty"))
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="kitchinv.md"`)
+	w.Header().Set("Cache-Control", "no-store")
+	if err := s.service.WriteMarkdownReport(r.Context(), w, time.Now().In(s.loc), includeEmpty); err != nil {
+		s.log(r).Error("failed to write markdown export", "error", err)
+	}
+}
+
+// WithPDFPageSize sets the paper size of /export.pdf; the default is A4.
+func (s *Server) WithPDFPageSize(size export.PageSize) *Server {
+	s.pdfPageSize = size
+	return s
+}
+
+// handleExportPDF serves the inventory as a PDF with each area's latest
+// photo above its items. Like the Markdown export it is streamed, so errors
+// after the first page can only be logged.
+func (s *Server) handleExportPDF(w http.ResponseWriter, r *http.Request) {
+	includeEmpty, _ := strconv.ParseBool(r.URL.Query().Get("include_empty"))
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="kitchinv.pdf"`)
+	w.Header().Set("Cache-Control", "no-store")
+	err := export.WritePDF(r.Context(), w, s.service, export.Options{
+		PageSize:     s.pdfPageSize,
+		GeneratedAt:  time.Now().In(s.loc),
+		IncludeEmpty: includeEmpty,
+		Logger:       s.log(r),
+	})
+	if err != nil {
+		s.log(r).Error("failed to write pdf export", "error", err)
+	}
+}
+
+// handlePrint renders the inventory as a plain page meant for printing, with
+// the same areas and items as the Markdown export.
+func (s *Server) handlePrint(w http.ResponseWriter, r *http.Request) {
+	includeEmpty, _ := strconv.ParseBool(r.URL.Query().Get("include_empty"))
+	var areas []*service.ReportArea
+	err := s.service.EachReportArea(r.Context(), includeEmpty, func(a *service.ReportArea) error {
+		areas = append(areas, a)
+		return nil
+	})
+	if err != nil {
+		s.log(r).Error("failed to list inventory for printing", "error", err)
+		s.renderError(w, r, http.StatusInternalServerError, "failed to load inventory")
+		return
+	}
+	data := map[string]any{
+		"Areas":        areas,
+		"GeneratedAt":  time.Now(),
+		"IncludeEmpty": includeEmpty,
+	}
+	if err := s.renderPage(w, r, "print", data); err != nil {
+		s.log(r).Error("failed to render print page", "error", err)
+	}
+}
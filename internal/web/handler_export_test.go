@@ -0,0 +1,40 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/export"
+	"github.com/vbonduro/kitchinv/internal/service"
+)
+
+type fakeExportService struct {
+	fakeOverrideService
+	includeEmpty bool
+}
+
+func (f *fakeExportService) EachReportArea(_ context.Context, includeEmpty bool, fn func(*service.ReportArea) error) error {
+	f.includeEmpty = includeEmpty
+	return fn(&service.ReportArea{ID: 1, Path: "Fridge", Items: []*domain.Item{{Name: "Milk", Quantity: "1"}}})
+}
+
+func TestHandleExportPDF(t *testing.T) {
+	svc := &fakeExportService{}
+	srv := newOverrideTestServer(svc).WithPDFPageSize(export.Letter)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest("GET", "/export.pdf?include_empty=1", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/pdf", rec.Header().Get("Content-Type"))
+	assert.True(t, svc.includeEmpty)
+	body := rec.Body.String()
+	assert.True(t, strings.HasPrefix(body, "%PDF-"))
+	assert.Contains(t, body, "/MediaBox [0 0 612.00 792.00]")
+	assert.True(t, strings.HasSuffix(body, "%%EOF\n"))
+}
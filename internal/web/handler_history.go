@@ -0,0 +1,108 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/service"
+)
+
+// History windows for GET /areas/{id}/history, in days.
+const (
+	defaultHistoryDays = 90
+	maxHistoryDays     = 3650
+)
+
+// Sparkline size in pixels.
+const (
+	sparklineWidth  = 120
+	sparklineHeight = 24
+)
+
+// handleAreaHistory returns the area's item count after each analysis over
+// the last ?days= days (default 90) as JSON, oldest first, for charting.
+// ?items=true includes each point's item list.
+func (s *Server) handleAreaHistory(w http.ResponseWriter, r *http.Request) {
+	areaID, err := parseID(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "invalid area id")
+		return
+	}
+	days := defaultHistoryDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		days, err = strconv.Atoi(v)
+		if err != nil || days < 1 || days > maxHistoryDays {
+			s.renderError(w, r, http.StatusBadRequest, fmt.Sprintf("days must be a whole number from 1 to %d", maxHistoryDays))
+			return
+		}
+	}
+	withItems, _ := strconv.ParseBool(r.URL.Query().Get("items"))
+
+	points, err := s.service.AreaHistory(r.Context(), areaID, time.Now().AddDate(0, 0, -days))
+	switch {
+	case errors.Is(err, service.ErrAreaNotFound):
+		s.renderError(w, r, http.StatusNotFound, "area not found")
+		return
+	case err != nil:
+		s.renderError(w, r, http.StatusInternalServerError, "failed to get history")
+		s.log(r).Error("area history failed", "area_id", areaID, "error", err)
+		return
+	}
+	if !withItems {
+		for _, p := range points {
+			p.Items = nil
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(points)
+}
+
+// sparkline is the detail page's chart of an area's item count over time.
+type sparkline struct {
+	Points           string // SVG polyline points
+	Min, Max, Latest int
+	Count            int
+}
+
+// areaSparkline charts the area's item count over the default history
+// window, or returns nil when there are fewer than two points to draw.
+// Failures are logged: the chart is decoration.
+func (s *Server) areaSparkline(r *http.Request, areaID int64) *sparkline {
+	points, err := s.service.AreaHistory(r.Context(), areaID, time.Now().AddDate(0, 0, -defaultHistoryDays))
+	if err != nil {
+		s.log(r).Error("area history failed", "area_id", areaID, "error", err)
+		return nil
+	}
+	return newSparkline(points)
+}
+
+func newSparkline(points []*domain.HistoryPoint) *sparkline {
+	if len(points) < 2 {
+		return nil
+	}
+	sp := &sparkline{Min: points[0].ItemCount, Max: points[0].ItemCount, Count: len(points)}
+	for _, p := range points {
+		sp.Min, sp.Max = min(sp.Min, p.ItemCount), max(sp.Max, p.ItemCount)
+	}
+	sp.Latest = points[len(points)-1].ItemCount
+
+	coords := make([]string, len(points))
+	step := float64(sparklineWidth) / float64(len(points)-1)
+	for i, p := range points {
+		// A flat history is drawn through the middle; otherwise the range
+		// fills the height, leaving a pixel for the stroke.
+		y := float64(sparklineHeight) / 2
+		if sp.Max > sp.Min {
+			y = 1 + float64(sp.Max-p.ItemCount)/float64(sp.Max-sp.Min)*float64(sparklineHeight-2)
+		}
+		coords[i] = fmt.Sprintf("%.1f,%.1f", float64(i)*step, y)
+	}
+	sp.Points = strings.Join(coords, " ")
+	return sp
+}
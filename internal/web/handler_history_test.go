@@ -0,0 +1,22 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+func TestNewSparkline(t *testing.T) {
+	assert.Nil(t, newSparkline(nil))
+	assert.Nil(t, newSparkline([]*domain.HistoryPoint{{ItemCount: 3}}), "one point is no trend")
+
+	sp := newSparkline([]*domain.HistoryPoint{{ItemCount: 10}, {ItemCount: 0}, {ItemCount: 5}})
+	require.NotNil(t, sp)
+	assert.Equal(t, "0.0,1.0 60.0,23.0 120.0,12.0", sp.Points)
+	assert.Equal(t, 0, sp.Min)
+	assert.Equal(t, 10, sp.Max)
+	assert.Equal(t, 5, sp.Latest)
+	assert.Equal(t, 3, sp.Count)
+}
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/service"
+)
+
+// maxIgnorePatternLen bounds an ignore-list entry; item names are far shorter.
+const maxIgnorePatternLen = 200
+
+func (s *Server) handleListIgnoredItems(w http.ResponseWriter, r *http.Request) {
+	items, err := s.service.ListIgnoredItems(r.Context())
+	if err != nil {
+		http.Error(w, "failed to list ignored items", http.StatusInternalServerError)
+		s.log(r).Error("list ignored items failed", "error", err)
+		return
+	}
+	if items == nil {
+		items = []*domain.IgnoredItem{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(items)
+}
+
+func (s *Server) handleCreateIgnoredItem(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Pattern string `json:"pattern"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(body.Pattern) > maxIgnorePatternLen {
+		http.Error(w, "pattern too long", http.StatusBadRequest)
+		return
+	}
+
+	item, err := s.service.AddIgnoredItem(r.Context(), body.Pattern)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrIgnorePatternInvalid):
+			http.Error(w, "pattern required", http.StatusBadRequest)
+		case errors.Is(err, service.ErrIgnorePatternExists):
+			http.Error(w, "pattern is already ignored", http.StatusConflict)
+		default:
+			http.Error(w, "failed to add ignored item", http.StatusInternalServerError)
+			s.log(r).Error("add ignored item failed", "error", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(item)
+}
+
+func (s *Server) handleDeleteIgnoredItem(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		http.Error(w, "invalid ignored item id", http.StatusBadRequest)
+		return
+	}
+
+	found, err := s.service.DeleteIgnoredItem(r.Context(), id)
+	if err != nil {
+		http.Error(w, "failed to delete ignored item", http.StatusInternalServerError)
+		s.log(r).Error("delete ignored item failed", "id", id, "error", err)
+		return
+	}
+	if !found {
+		http.Error(w, "ignored item not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
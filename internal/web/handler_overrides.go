@@ -15,14 +15,14 @@ func (s *Server) handleListOverrides(w http.ResponseWriter, r *http.Request) {
 	rules, err := s.service.ListOverrideRules(ctx)
 	if err != nil {
 		http.Error(w, "failed to list override rules", http.StatusInternalServerError)
-		s.logger.Error("list override rules failed", "error", err)
+		s.log(r).Error("list override rules failed", "error", err)
 		return
 	}
 
 	areas, err := s.service.ListAreas(ctx)
 	if err != nil {
 		http.Error(w, "failed to list areas", http.StatusInternalServerError)
-		s.logger.Error("list areas failed", "error", err)
+		s.log(r).Error("list areas failed", "error", err)
 		return
 	}
 
@@ -31,13 +31,13 @@ func (s *Server) handleListOverrides(w http.ResponseWriter, r *http.Request) {
 		areaMap[a.ID] = a.Name
 	}
 
-	if err := s.renderPage(w, map[string]any{
+	if err := s.renderPage(w, r, "overrides", map[string]any{
 		"Rules":     rules,
 		"Areas":     areas,
 		"AreaMap":   areaMap,
 		"ActiveNav": "overrides",
-	}, "base.html", "pages/overrides.html"); err != nil {
-		s.logger.Error("render page failed", "error", err)
+	}); err != nil {
+		s.log(r).Error("render page failed", "error", err)
 	}
 }
 
@@ -49,7 +49,7 @@ func (s *Server) handleCreateOverride(w http.ResponseWriter, r *http.Request) {
 
 	if _, err := s.service.CreateOverrideRule(r.Context(), rule); err != nil {
 		http.Error(w, "failed to create override rule", http.StatusInternalServerError)
-		s.logger.Error("create override rule failed", "error", err)
+		s.log(r).Error("create override rule failed", "error", err)
 		return
 	}
 
@@ -71,7 +71,7 @@ func (s *Server) handleUpdateOverride(w http.ResponseWriter, r *http.Request) {
 
 	if _, err := s.service.UpdateOverrideRule(r.Context(), rule); err != nil {
 		http.Error(w, "failed to update override rule", http.StatusInternalServerError)
-		s.logger.Error("update override rule failed", "id", id, "error", err)
+		s.log(r).Error("update override rule failed", "id", id, "error", err)
 		return
 	}
 
@@ -87,7 +87,7 @@ func (s *Server) handleDeleteOverride(w http.ResponseWriter, r *http.Request) {
 
 	if err := s.service.DeleteOverrideRule(r.Context(), id); err != nil {
 		http.Error(w, "failed to delete override rule", http.StatusInternalServerError)
-		s.logger.Error("delete override rule failed", "id", id, "error", err)
+		s.log(r).Error("delete override rule failed", "id", id, "error", err)
 		return
 	}
 
@@ -104,7 +104,7 @@ func (s *Server) handleReorderOverrides(w http.ResponseWriter, r *http.Request)
 	}
 	if err := s.service.ReorderOverrideRules(r.Context(), body.IDs); err != nil {
 		http.Error(w, "failed to reorder", http.StatusInternalServerError)
-		s.logger.Error("reorder override rules failed", "error", err)
+		s.log(r).Error("reorder override rules failed", "error", err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -9,9 +9,11 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/audit"
 	"github.com/vbonduro/kitchinv/internal/domain"
 	"github.com/vbonduro/kitchinv/internal/service"
 	"github.com/vbonduro/kitchinv/internal/web/templates"
@@ -53,15 +55,81 @@ func (f *fakeOverrideService) DeleteOverrideRule(_ context.Context, _ int64) err
 func (f *fakeOverrideService) ReorderOverrideRules(_ context.Context, _ []int64) error {
 	return nil
 }
+func (f *fakeOverrideService) ListIgnoredItems(_ context.Context) ([]*domain.IgnoredItem, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) AddIgnoredItem(_ context.Context, _ string) (*domain.IgnoredItem, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) DeleteIgnoredItem(_ context.Context, _ int64) (bool, error) {
+	return false, nil
+}
+func (f *fakeOverrideService) LatestRawResponse(_ context.Context, _ int64) (string, error) {
+	return "", nil
+}
+func (f *fakeOverrideService) VisionStatus() service.VisionStatus {
+	return service.VisionStatus{OK: true}
+}
+func (f *fakeOverrideService) MonthlyUsage(_ context.Context, _ int) ([]domain.MonthlyUsage, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) MonthlyWaste(_ context.Context, _ int) ([]domain.MonthlyWaste, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) EachReportArea(_ context.Context, _ bool, _ func(*service.ReportArea) error) error {
+	return nil
+}
+func (f *fakeOverrideService) WriteMarkdownReport(_ context.Context, _ io.Writer, _ time.Time, _ bool) error {
+	return nil
+}
+func (f *fakeOverrideService) AreaThumbnail(_ context.Context, _ int64, _ int) ([]byte, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) Counts(_ context.Context) (service.Counts, error) {
+	return service.Counts{}, nil
+}
+func (f *fakeOverrideService) Seed(_ context.Context, _ *service.SeedData) (*service.SeedResult, error) {
+	return &service.SeedResult{}, nil
+}
+func (f *fakeOverrideService) StapleLevels(_ context.Context) ([]*service.StapleLevel, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) LowStaples(_ context.Context) ([]*service.StapleLevel, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) AddStaple(_ context.Context, _ domain.Staple) (*domain.Staple, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) DeleteStaple(_ context.Context, _ int64) (bool, error) {
+	return false, nil
+}
 func (f *fakeOverrideService) ListAreas(_ context.Context) ([]*domain.Area, error) {
 	return f.areas, nil
 }
 
 // Remaining kitchenService stubs.
-func (f *fakeOverrideService) CreateArea(_ context.Context, _ string) (*domain.Area, error) {
+func (f *fakeOverrideService) CreateAreaWithKind(_ context.Context, _ string, _ domain.AreaKind) (*domain.Area, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) CreateChildArea(_ context.Context, _ int64, _ string, _ domain.AreaKind) (*domain.Area, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) RecentItems(_ context.Context, _ time.Time, _, _ int) ([]*domain.RecentItem, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) SearchItemsPage(_ context.Context, _ string, _ domain.SearchFilter, _ domain.ItemPage) ([]*domain.Item, error) {
 	return nil, nil
 }
-func (f *fakeOverrideService) ListAreasWithItems(_ context.Context) ([]*service.AreaSummary, error) {
+func (f *fakeOverrideService) SearchItemsFuzzy(_ context.Context, _ string, _ domain.SearchFilter) ([]service.FuzzyMatch, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) SuggestItemNames(_ context.Context, _ string) ([]string, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) ListItemsPage(_ context.Context, _ int64, _ domain.ItemPage) ([]*domain.Item, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) ListAreaSummaries(_ context.Context) ([]*service.AreaSummary, error) {
 	return nil, nil
 }
 func (f *fakeOverrideService) GetArea(_ context.Context, _ int64) (*domain.Area, error) {
@@ -70,21 +138,63 @@ func (f *fakeOverrideService) GetArea(_ context.Context, _ int64) (*domain.Area,
 func (f *fakeOverrideService) GetAreaWithItems(_ context.Context, _ int64) (*domain.Area, []*domain.Item, *domain.Photo, error) {
 	return nil, nil, nil, nil
 }
+func (f *fakeOverrideService) GetAreaSummary(_ context.Context, _ int64) (*service.AreaSummary, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) AreaItemsVersion(_ context.Context, _ int64) (string, error) {
+	return "", nil
+}
+func (f *fakeOverrideService) AreaListVersion(_ context.Context) (string, error) {
+	return "", nil
+}
+func (f *fakeOverrideService) ListStaleAreas(_ context.Context) ([]*service.AreaSummary, error) {
+	return nil, nil
+}
 func (f *fakeOverrideService) UpdateArea(_ context.Context, _ int64, _ string) (*domain.Area, error) {
 	return nil, nil
 }
+func (f *fakeOverrideService) SetAreaPrompt(_ context.Context, _ int64, _ string) (*domain.Area, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) SetAreaKind(_ context.Context, _ int64, _ domain.AreaKind) (*domain.Area, error) {
+	return nil, nil
+}
 func (f *fakeOverrideService) DeleteArea(_ context.Context, _ int64) error       { return nil }
+func (f *fakeOverrideService) RestoreArea(_ context.Context, _ int64) (*domain.Area, error) {
+	return nil, nil
+}
 func (f *fakeOverrideService) DeletePhoto(_ context.Context, _ int64) error      { return nil }
-func (f *fakeOverrideService) UploadPhoto(_ context.Context, _ int64, _ []byte, _ string) (*domain.Photo, []*domain.Item, error) {
+func (f *fakeOverrideService) UploadPhotoWithOptions(_ context.Context, _ int64, _ []byte, _ string, _ service.UploadOptions) (*domain.Photo, []*domain.Item, error) {
 	return nil, nil, nil
 }
+func (f *fakeOverrideService) UploadPhotos(_ context.Context, _ int64, _ []service.PhotoUpload, _ service.UploadOptions) ([]*domain.Photo, []*domain.Item, error) {
+	return nil, nil, nil
+}
+func (f *fakeOverrideService) ResolveKeptItems(_ context.Context, _ int64, _ bool) ([]*domain.Item, error) {
+	return nil, nil
+}
 func (f *fakeOverrideService) CreateItem(_ context.Context, _ int64, _, _ string) (*domain.Item, error) {
 	return nil, nil
 }
-func (f *fakeOverrideService) UpdateItem(_ context.Context, _ int64, _, _ string) (*domain.Item, error) {
+func (f *fakeOverrideService) CreateItemFromBarcode(_ context.Context, _ int64, _ string) (*domain.Item, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) GetItem(_ context.Context, _, _ int64) (*domain.Item, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) UpdateItemIfCurrent(_ context.Context, _, _ int64, _, _ string) (*domain.Item, error) {
 	return nil, nil
 }
 func (f *fakeOverrideService) DeleteItem(_ context.Context, _ int64) error   { return nil }
+func (f *fakeOverrideService) ConsumeItem(_ context.Context, _, _ int64) (*domain.Item, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) DiscardItem(_ context.Context, _, _ int64) (*domain.Item, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) BulkEditItems(_ context.Context, _ int64, _ []domain.ItemOp) ([]*domain.Item, error) {
+	return nil, nil
+}
 func (f *fakeOverrideService) ReorderAreas(_ context.Context, _ []int64) error { return nil }
 func (f *fakeOverrideService) SearchItems(_ context.Context, _ string) ([]*domain.Item, error) {
 	return nil, nil
@@ -92,6 +202,43 @@ func (f *fakeOverrideService) SearchItems(_ context.Context, _ string) ([]*domai
 func (f *fakeOverrideService) ListSnapshots(_ context.Context, _ int64) ([]*domain.Snapshot, error) {
 	return nil, nil
 }
+func (f *fakeOverrideService) DiffLatestAnalysis(_ context.Context, _ int64) (*service.AreaDiff, error) {
+	return nil, service.ErrNoPhoto
+}
+func (f *fakeOverrideService) AreaHistory(_ context.Context, _ int64, _ time.Time) ([]*domain.HistoryPoint, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) ListAuditEntries(_ context.Context, _ audit.Filter) ([]*audit.Entry, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) ListWebhooks(_ context.Context) ([]*domain.Webhook, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) CreateWebhook(_ context.Context, _ domain.Webhook) (*domain.Webhook, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) UpdateWebhook(_ context.Context, _ domain.Webhook) (*domain.Webhook, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) DeleteWebhook(_ context.Context, _ int64) (bool, error) {
+	return false, nil
+}
+func (f *fakeOverrideService) ListExpiringItems(_ context.Context, _ *time.Location) ([]*service.ExpiringItem, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) CanSuggestRecipes() bool { return false }
+func (f *fakeOverrideService) SuggestRecipes(_ context.Context, _ []int64, _ func(string)) error {
+	return service.ErrSuggestUnsupported
+}
+func (f *fakeOverrideService) Ask(_ context.Context, q string) (*service.AskAnswer, error) {
+	return &service.AskAnswer{Question: q, Fallback: true}, nil
+}
+func (f *fakeOverrideService) ListWebhookDeliveries(_ context.Context, _ int64) ([]*domain.WebhookDelivery, error) {
+	return nil, nil
+}
+func (f *fakeOverrideService) SendTestWebhook(_ context.Context, _ int64) (*domain.WebhookDelivery, error) {
+	return nil, nil
+}
 
 func newOverrideTestServer(svc kitchenService) *Server {
 	return NewServer(svc, templates.FS, nil, slog.Default())
@@ -0,0 +1,148 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vbonduro/kitchinv/internal/qr"
+)
+
+// QR code image sizes, in pixels.
+const (
+	defaultQRSize = 256
+	minQRSize     = 64
+	maxQRSize     = 2048
+	labelQRSize   = 300 // on the printable label sheet
+)
+
+// maxQRCacheEntries bounds the rendered codes kept in memory. An area's URL
+// never changes, so entries don't go stale; the cache is just emptied when
+// it fills up.
+const maxQRCacheEntries = 512
+
+// qrKey identifies a rendered code: the area it links to, the base URL the
+// link was made from, and the image size.
+type qrKey struct {
+	areaID  int64
+	baseURL string
+	size    int
+}
+
+type qrCache struct {
+	mu      sync.Mutex
+	entries map[qrKey][]byte
+}
+
+func (c *qrCache) get(k qrKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	png, ok := c.entries[k]
+	return png, ok
+}
+
+func (c *qrCache) put(k qrKey, png []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil || len(c.entries) >= maxQRCacheEntries {
+		c.entries = make(map[qrKey][]byte)
+	}
+	c.entries[k] = png
+}
+
+// WithBaseURL sets the public URL the server is reached at, such as
+// "http://kitchen.local:8080", which QR code labels link to. Without one,
+// links are made from the host each request was addressed to.
+func (s *Server) WithBaseURL(u string) *Server {
+	s.baseURL = strings.TrimRight(u, "/")
+	return s
+}
+
+// publicBaseURL returns the configured base URL, or one made from r's
+// host when none is configured.
+func (s *Server) publicBaseURL(r *http.Request) string {
+	if s.baseURL != "" {
+		return s.baseURL
+	}
+	return clientScheme(r) + "://" + r.Host
+}
+
+// handleAreaQR serves a PNG QR code linking to the area's page, size
+// pixels square.
+func (s *Server) handleAreaQR(w http.ResponseWriter, r *http.Request) {
+	areaID, err := parseID(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "invalid area id")
+		return
+	}
+	size := defaultQRSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		size, err = strconv.Atoi(v)
+		if err != nil || size < minQRSize || size > maxQRSize {
+			s.renderError(w, r, http.StatusBadRequest, fmt.Sprintf("size must be between %d and %d", minQRSize, maxQRSize))
+			return
+		}
+	}
+	area, err := s.service.GetArea(r.Context(), areaID)
+	if err != nil {
+		s.log(r).Error("get area failed", "area_id", areaID, "error", err)
+		s.renderError(w, r, http.StatusInternalServerError, "failed to get area")
+		return
+	}
+	if area == nil {
+		s.renderError(w, r, http.StatusNotFound, "area not found")
+		return
+	}
+
+	key := qrKey{areaID: areaID, baseURL: s.publicBaseURL(r), size: size}
+	data, ok := s.qrCodes.get(key)
+	if !ok {
+		data, err = renderQR(fmt.Sprintf("%s/areas/%d", key.baseURL, areaID), size)
+		if err != nil {
+			s.log(r).Error("render qr code failed", "area_id", areaID, "error", err)
+			s.renderError(w, r, http.StatusInternalServerError, "failed to make QR code")
+			return
+		}
+		s.qrCodes.put(key, data)
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	_, _ = w.Write(data)
+}
+
+// renderQR encodes link as a QR code PNG size pixels square.
+func renderQR(link string, size int) ([]byte, error) {
+	code, err := qr.Encode([]byte(link))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, code.Image(size)); err != nil {
+		return nil, fmt.Errorf("failed to encode png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// handleAreaLabels renders a printable sheet of every area's QR code and
+// name, to cut out and stick on each shelf or freezer.
+func (s *Server) handleAreaLabels(w http.ResponseWriter, r *http.Request) {
+	areas, err := s.service.ListAreas(r.Context())
+	if err != nil {
+		s.log(r).Error("list areas failed", "error", err)
+		s.renderError(w, r, http.StatusInternalServerError, "failed to list areas")
+		return
+	}
+	data := map[string]any{
+		"Areas":      areas,
+		"Size":       labelQRSize,
+		"BaseURL":    s.publicBaseURL(r),
+		"Configured": s.baseURL != "",
+	}
+	if err := s.renderPage(w, r, "labels", data); err != nil {
+		s.log(r).Error("failed to render labels page", "error", err)
+	}
+}
@@ -0,0 +1,82 @@
+package web
+
+import (
+	"context"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+type fakeQRService struct {
+	fakeOverrideService
+	lookups int
+}
+
+func (f *fakeQRService) GetArea(_ context.Context, id int64) (*domain.Area, error) {
+	f.lookups++
+	if id != 7 {
+		return nil, nil
+	}
+	return &domain.Area{ID: 7, Name: "Freezer"}, nil
+}
+
+func getQR(srv *Server, target, host string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	req.Host = host
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleAreaQR(t *testing.T) {
+	srv := newOverrideTestServer(&fakeQRService{})
+	rec := getQR(srv, "/areas/7/qr.png?size=200", "kitchen.local:8080")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+	img, err := png.Decode(rec.Body)
+	require.NoError(t, err)
+	assert.Equal(t, 200, img.Bounds().Dx())
+
+	// The cache is keyed by the base URL, so another host gets its own code.
+	require.Len(t, srv.qrCodes.entries, 1)
+	assert.Contains(t, srv.qrCodes.entries, qrKey{areaID: 7, baseURL: "http://kitchen.local:8080", size: 200})
+	getQR(srv, "/areas/7/qr.png?size=200", "kitchen.local:8080")
+	assert.Len(t, srv.qrCodes.entries, 1, "served from the cache")
+	getQR(srv, "/areas/7/qr.png?size=200", "192.168.1.5")
+	assert.Len(t, srv.qrCodes.entries, 2)
+
+	srv.WithBaseURL("https://kitchinv.example/")
+	getQR(srv, "/areas/7/qr.png", "ignored")
+	assert.Contains(t, srv.qrCodes.entries, qrKey{areaID: 7, baseURL: "https://kitchinv.example", size: defaultQRSize})
+}
+
+func TestHandleAreaQR_Errors(t *testing.T) {
+	srv := newOverrideTestServer(&fakeQRService{})
+	assert.Equal(t, http.StatusNotFound, getQR(srv, "/areas/8/qr.png", "h").Code)
+	assert.Equal(t, http.StatusBadRequest, getQR(srv, "/areas/7/qr.png?size=big", "h").Code)
+	assert.Equal(t, http.StatusBadRequest, getQR(srv, "/areas/7/qr.png?size=4096", "h").Code)
+}
+
+func TestQRCacheBounded(t *testing.T) {
+	var c qrCache
+	for i := range maxQRCacheEntries + 1 {
+		c.put(qrKey{areaID: int64(i)}, []byte{1})
+	}
+	assert.Len(t, c.entries, 1, "a full cache is emptied")
+}
+
+func TestAreaLabelsBaseURLHint(t *testing.T) {
+	srv := newOverrideTestServer(&fakeOverrideService{areas: []*domain.Area{{ID: 1, Name: "Fridge"}}})
+	rec := getQR(srv, "/areas/labels", "kitchen.local")
+	assert.Contains(t, rec.Body.String(), "The codes link to http://kitchen.local.")
+
+	srv.WithBaseURL("https://kitchinv.example")
+	rec = getQR(srv, "/areas/labels", "kitchen.local")
+	assert.NotContains(t, rec.Body.String(), `data-testid="base-url-hint"`)
+	assert.Contains(t, rec.Body.String(), `src="/areas/1/qr.png?size=300"`)
+}
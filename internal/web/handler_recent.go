@@ -0,0 +1,78 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// handleRecent lists items across all areas, newest first. ?since= takes a
+// date (YYYY-MM-DD, in the display time zone) or an RFC 3339 timestamp and
+// hides anything older; limit and offset page the feed as they do item lists.
+func (s *Server) handleRecent(w http.ResponseWriter, r *http.Request) {
+	since, err := s.parseSince(r.URL.Query().Get("since"))
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "since must be a date (YYYY-MM-DD) or an RFC 3339 time")
+		return
+	}
+	page, _, err := s.readItemPage(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if page.Limit == 0 {
+		page.Limit = s.itemPageSize
+	}
+	// The feed has one order; sort is accepted but has no effect.
+	page.Sort = domain.ItemSortCreatedAt
+
+	items, next, err := fetchPage(r.URL, page, func(p domain.ItemPage) ([]*domain.RecentItem, error) {
+		return s.service.RecentItems(r.Context(), since, p.Limit, p.Offset)
+	})
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "failed to list recent items")
+		s.log(r).Error("list recent items failed", "error", err)
+		return
+	}
+
+	if wantsJSON(r) {
+		if next != "" {
+			w.Header().Set("Link", "<"+next+`>; rel="next"`)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(items)
+		return
+	}
+
+	data := map[string]any{"Items": items, "Next": next, "Offset": page.Offset}
+	if isHTMX(r) {
+		if err := s.renderPartial(w, r, "partials/recent_items.html", data); err != nil {
+			s.log(r).Error("render partial failed", "error", err)
+		}
+		return
+	}
+
+	sinceValue := ""
+	if !since.IsZero() {
+		sinceValue = since.In(s.loc).Format("2006-01-02")
+	}
+	if err := s.renderPage(w, r, "recent", map[string]any{
+		"Results": data, "Since": sinceValue, "ActiveNav": "recent",
+	}); err != nil {
+		s.log(r).Error("render page failed", "error", err)
+	}
+}
+
+// parseSince reads a ?since= value. A bare date is midnight in the display
+// time zone; an empty value is the zero time, which filters nothing.
+func (s *Server) parseSince(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, s.loc); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -1,44 +1,228 @@
 package web
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/service"
 )
 
 const maxSearchQueryLen = 200
 
+// searchRecentLimit is how many recently added items a search with no query
+// shows instead of results.
+const searchRecentLimit = 20
+
+// fuzzyFallbackBelow is how few exact matches make a search also look for
+// close spellings.
+const fuzzyFallbackBelow = 3
+
+// handleSearch finds items whose name contains q. Repeated area_id params
+// narrow it to those areas, and in (name, quantity, comma-separated or
+// repeated) picks the fields q may match. When only a few items match and
+// there is no further page, items spelled close to q are listed after them.
+// With no q it shows the most recently added items instead.
 func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	query := strings.TrimSpace(r.URL.Query().Get("q"))
 	if len(query) > maxSearchQueryLen {
 		query = query[:maxSearchQueryLen]
 	}
 
+	page, paged, err := s.readItemPage(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter, err := readSearchFilter(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	filtered := len(filter.AreaIDs) > 0 || len(filter.Fields) > 0
+
 	var items []*domain.Item
-	if query != "" {
-		var err error
-		items, err = s.service.SearchItems(r.Context(), query)
+	var fuzzy []service.FuzzyMatch
+	var paths map[int64]string
+	var next string
+	recent := query == "" && page.Offset == 0
+	if recent {
+		if items, err = s.recentItems(r); err != nil {
+			http.Error(w, "search failed", http.StatusInternalServerError)
+			s.log(r).Error("list recent items for search failed", "error", err)
+			return
+		}
+	} else if query != "" {
+		if paged {
+			items, next, err = fetchPage(r.URL, page, func(p domain.ItemPage) ([]*domain.Item, error) {
+				return s.service.SearchItemsPage(r.Context(), query, filter, p)
+			})
+		} else if page.Status != "" || filtered {
+			items, err = s.service.SearchItemsPage(r.Context(), query, filter, page)
+		} else {
+			items, err = s.service.SearchItems(r.Context(), query)
+		}
 		if err != nil {
 			http.Error(w, "search failed", http.StatusInternalServerError)
-			s.logger.Error("search failed", "query", query, "error", err)
+			s.log(r).Error("search failed", "query", query, "error", err)
 			return
 		}
+		if len(items) < fuzzyFallbackBelow && next == "" && page.Offset == 0 && page.Status == "" {
+			if fuzzy, err = s.closeMatches(r, query, filter, items); err != nil {
+				http.Error(w, "search failed", http.StatusInternalServerError)
+				s.log(r).Error("fuzzy search failed", "query", query, "error", err)
+				return
+			}
+		}
+	}
+	if items != nil || fuzzy != nil {
+		if paths, err = s.areaPaths(r); err != nil {
+			http.Error(w, "search failed", http.StatusInternalServerError)
+			s.log(r).Error("list areas for search failed", "error", err)
+			return
+		}
+	}
+	results := map[string]any{
+		"Items": items, "Fuzzy": fuzzy, "Recent": recent, "AreaPaths": paths,
+		"Next": next, "Offset": page.Offset, "Query": query,
 	}
 
 	// HTMX partial update: return only results fragment.
-	if r.Header.Get("HX-Request") == "true" {
+	if isHTMX(r) {
 		w.Header().Set("Cache-Control", "no-store")
-		if err := s.renderPartial(w, "partials/search_results.html", items); err != nil {
-			s.logger.Error("render partial failed", "error", err)
+		if err := s.renderPartial(w, r, "partials/search_results.html", results); err != nil {
+			s.log(r).Error("render partial failed", "error", err)
 		}
 		return
 	}
 
-	if err := s.renderPage(w,
-		map[string]any{"Results": items, "Query": query, "ActiveNav": "search"},
-		"base.html", "pages/search.html", "partials/search_results.html",
+	areas, err := s.service.ListAreas(r.Context())
+	if err != nil {
+		http.Error(w, "search failed", http.StatusInternalServerError)
+		s.log(r).Error("list areas for search failed", "error", err)
+		return
+	}
+	var areaID int64
+	if len(filter.AreaIDs) == 1 {
+		areaID = filter.AreaIDs[0]
+	}
+	if err := s.renderPage(w, r, "search",
+		map[string]any{
+			"Results": results, "Query": query, "PageSize": s.itemPageSize,
+			"Areas": areas, "AreaID": areaID, "InQuantity": slices.Contains(filter.Fields, domain.SearchQuantity),
+			"CanAsk": s.service.CanSuggestRecipes(), "ActiveNav": "search",
+		},
 	); err != nil {
-		s.logger.Error("render page failed", "error", err)
+		s.log(r).Error("render page failed", "error", err)
+	}
+}
+
+// handleSearchSuggest offers up to five item names containing q as the
+// search box's datalist, or as a JSON list for Accept: application/json.
+// The search page asks for it as the user types.
+func (s *Server) handleSearchSuggest(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len(query) > maxSearchQueryLen {
+		query = query[:maxSearchQueryLen]
+	}
+	names, err := s.service.SuggestItemNames(r.Context(), query)
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "failed to suggest names")
+		s.log(r).Error("suggest names failed", "query", query, "error", err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if wantsJSON(r) {
+		if names == nil {
+			names = []string{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(names)
+		return
+	}
+	if err := s.renderPartial(w, r, "partials/search_suggest.html", names); err != nil {
+		s.log(r).Error("render partial failed", "error", err)
+	}
+}
+
+// recentItems returns the items a search with no query shows: the newest
+// across all areas.
+func (s *Server) recentItems(r *http.Request) ([]*domain.Item, error) {
+	recent, err := s.service.RecentItems(r.Context(), time.Time{}, searchRecentLimit, 0)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*domain.Item, len(recent))
+	for i, it := range recent {
+		items[i] = &it.Item
+	}
+	return items, nil
+}
+
+// closeMatches returns the fuzzy matches for query that aren't already
+// among the exact matches.
+func (s *Server) closeMatches(r *http.Request, query string, filter domain.SearchFilter, exact []*domain.Item) ([]service.FuzzyMatch, error) {
+	matches, err := s.service.SearchItemsFuzzy(r.Context(), query, filter)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(matches, func(m service.FuzzyMatch) bool {
+		return slices.ContainsFunc(exact, func(it *domain.Item) bool { return it.ID == m.Item.ID })
+	}), nil
+}
+
+// areaPaths maps each area's ID to its display path, so results in a
+// sub-area read "Freezer › Top basket".
+func (s *Server) areaPaths(r *http.Request) (map[int64]string, error) {
+	areas, err := s.service.ListAreas(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	paths := make(map[int64]string, len(areas))
+	for _, a := range areas {
+		paths[a.ID] = a.Path()
+	}
+	return paths, nil
+}
+
+// readSearchFilter reads the area_id and in query parameters. An empty
+// area_id, as the search page's "All areas" option sends, is ignored.
+func readSearchFilter(r *http.Request) (domain.SearchFilter, error) {
+	var f domain.SearchFilter
+	q := r.URL.Query()
+	for _, v := range q["area_id"] {
+		if v == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return f, errors.New("area_id must be a positive integer")
+		}
+		f.AreaIDs = append(f.AreaIDs, id)
+	}
+	for _, v := range q["in"] {
+		for name := range strings.SplitSeq(v, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			// Items had notes before migration 000005 dropped the column;
+			// old links and scripts asking for them get a reason.
+			if name == "notes" {
+				return f, errors.New("items have no notes to search; in must list name or quantity")
+			}
+			field, ok := domain.ParseSearchField(name)
+			if !ok {
+				return f, errors.New("in must list name or quantity")
+			}
+			if !slices.Contains(f.Fields, field) {
+				f.Fields = append(f.Fields, field)
+			}
+		}
 	}
+	return f, nil
 }
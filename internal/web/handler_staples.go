@@ -0,0 +1,129 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/service"
+	"github.com/vbonduro/kitchinv/internal/units"
+)
+
+// maxStaplePatternLen bounds a staple's pattern; item names are far shorter.
+const maxStaplePatternLen = 200
+
+// handleListStaples shows every staple with how much of it is in stock, as
+// the staples page or, for Accept: application/json, a JSON list.
+func (s *Server) handleListStaples(w http.ResponseWriter, r *http.Request) {
+	levels, err := s.service.StapleLevels(r.Context())
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "failed to list staples")
+		s.log(r).Error("list staples failed", "error", err)
+		return
+	}
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(levels)
+		return
+	}
+
+	areas, err := s.service.ListAreas(r.Context())
+	if err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, "failed to list staples")
+		s.log(r).Error("list areas failed", "error", err)
+		return
+	}
+	paths := make(map[int64]string, len(areas))
+	for _, a := range areas {
+		paths[a.ID] = a.Path()
+	}
+	if err := s.renderPage(w, r, "staples", map[string]any{
+		"Staples": levels, "Areas": areas, "AreaPaths": paths, "ActiveNav": "staples",
+	}); err != nil {
+		s.log(r).Error("render page failed", "error", err)
+	}
+}
+
+// handleCreateStaple adds a staple from pattern, min_quantity (default 1) and
+// optional area_id, given as JSON or form fields. min_quantity is a number or
+// an amount with a unit, such as "2 l". Forms are redirected back
+// to the staples page; JSON requests get the staple with 201.
+func (s *Server) handleCreateStaple(w http.ResponseWriter, r *http.Request) {
+	fields, err := readFields(r, "pattern", "min_quantity", "area_id")
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	st := domain.Staple{MinQuantity: 1}
+	if v := fields["pattern"]; v != nil {
+		st.Pattern = strings.TrimSpace(*v)
+	}
+	if len(st.Pattern) > maxStaplePatternLen {
+		s.renderError(w, r, http.StatusBadRequest, "pattern too long")
+		return
+	}
+	if v := fields["min_quantity"]; v != nil && strings.TrimSpace(*v) != "" {
+		q, ok := units.Parse(*v)
+		if !ok {
+			s.renderError(w, r, http.StatusBadRequest, "min_quantity must be a positive amount, such as 6 or 2 l")
+			return
+		}
+		st.MinQuantity, st.MinUnit = q.Value, string(q.Unit)
+	}
+	if v := fields["area_id"]; v != nil && *v != "" {
+		id, err := strconv.ParseInt(*v, 10, 64)
+		if err != nil {
+			s.renderError(w, r, http.StatusBadRequest, "invalid area id")
+			return
+		}
+		st.AreaID = &id
+	}
+
+	created, err := s.service.AddStaple(r.Context(), st)
+	switch {
+	case errors.Is(err, service.ErrStapleInvalid):
+		s.renderError(w, r, http.StatusBadRequest, "a staple needs a name and a positive min_quantity")
+		return
+	case errors.Is(err, service.ErrAreaNotFound):
+		s.renderError(w, r, http.StatusNotFound, "area not found")
+		return
+	case errors.Is(err, service.ErrStapleExists):
+		s.renderError(w, r, http.StatusConflict, "that staple already exists")
+		return
+	case err != nil:
+		s.renderError(w, r, http.StatusInternalServerError, "failed to add staple")
+		s.log(r).Error("add staple failed", "error", err)
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		http.Redirect(w, r, "/staples", http.StatusSeeOther)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(created)
+}
+
+func (s *Server) handleDeleteStaple(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		http.Error(w, "invalid staple id", http.StatusBadRequest)
+		return
+	}
+
+	found, err := s.service.DeleteStaple(r.Context(), id)
+	if err != nil {
+		http.Error(w, "failed to delete staple", http.StatusInternalServerError)
+		s.log(r).Error("delete staple failed", "id", id, "error", err)
+		return
+	}
+	if !found {
+		http.Error(w, "staple not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
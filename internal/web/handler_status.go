@@ -0,0 +1,45 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// handleReadyz reports whether the server is ready and whether the vision
+// backend passed its startup check. It always answers 200: browsing and
+// editing work without vision, so a failed check shouldn't take the instance
+// out of rotation; status is "degraded" instead.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	vs := s.service.VisionStatus()
+	status := "ok"
+	if !vs.OK {
+		status = "degraded"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"status": status, "vision": vs})
+}
+
+// statsMonths is how many months of usage /stats reports, including the
+// current one.
+const statsMonths = 12
+
+// handleStats reports vision analysis totals and items used up and thrown
+// away per month as JSON.
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	usage, err := s.service.MonthlyUsage(r.Context(), statsMonths)
+	if err != nil {
+		http.Error(w, "failed to get stats", http.StatusInternalServerError)
+		s.log(r).Error("get usage stats failed", "error", err)
+		return
+	}
+	waste, err := s.service.MonthlyWaste(r.Context(), statsMonths)
+	if err != nil {
+		http.Error(w, "failed to get stats", http.StatusInternalServerError)
+		s.log(r).Error("get waste stats failed", "error", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"monthly_usage": usage, "monthly_waste": waste})
+}
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/service"
+)
+
+// handleSuggestRecipes asks the model for recipe ideas from the items in the
+// area_id form values (every area if none) and streams the reply as
+// server-sent events: "delta" events whose data is a JSON string of text,
+// then "done", or "error" with a JSON string message if generation fails
+// part-way. Failures before any text arrives get an ordinary error response.
+func (s *Server) handleSuggestRecipes(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "invalid form")
+		return
+	}
+	var areaIDs []int64
+	for _, v := range r.Form["area_id"] {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			s.renderError(w, r, http.StatusBadRequest, "invalid area id")
+			return
+		}
+		areaIDs = append(areaIDs, id)
+	}
+
+	rc := http.NewResponseController(w)
+	started := false
+	send := func(event string, v any) {
+		data, _ := json.Marshal(v)
+		_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		_ = rc.Flush()
+	}
+	err := s.service.SuggestRecipes(r.Context(), areaIDs, func(text string) {
+		if !started {
+			started = true
+			// Generation can outlast the server's write timeout.
+			_ = rc.SetWriteDeadline(time.Time{})
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusOK)
+		}
+		send("delta", text)
+	})
+	if started {
+		if err != nil {
+			s.log(r).Error("recipe suggestion failed", "error", err)
+			send("error", "the model stopped before finishing")
+			return
+		}
+		send("done", "")
+		return
+	}
+
+	switch {
+	case err == nil:
+		s.renderError(w, r, http.StatusBadGateway, "the model returned no suggestions")
+	case errors.Is(err, service.ErrSuggestUnsupported):
+		s.renderError(w, r, http.StatusNotImplemented, "the configured model backend can't suggest recipes")
+	case errors.Is(err, service.ErrAreaNotFound):
+		s.renderError(w, r, http.StatusNotFound, "area not found")
+	case errors.Is(err, service.ErrNothingToCook):
+		s.renderError(w, r, http.StatusUnprocessableEntity, "there are no items in stock to cook with")
+	case errors.Is(err, service.ErrTooManyAnalyses):
+		w.Header().Set("Retry-After", "10")
+		s.renderError(w, r, http.StatusTooManyRequests, "the model is busy, try again shortly")
+	case errors.Is(err, service.ErrAnalysisTimeout):
+		s.renderError(w, r, http.StatusGatewayTimeout, "the model took too long, try again")
+	default:
+		s.log(r).Error("recipe suggestion failed", "error", err)
+		s.renderError(w, r, http.StatusBadGateway, "failed to get suggestions from the model")
+	}
+}
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/service"
+)
+
+type fakeSuggestService struct {
+	fakeOverrideService
+	areas   []*service.AreaSummary
+	deltas  []string
+	err     error
+	areaIDs []int64
+}
+
+func (f *fakeSuggestService) ListAreaSummaries(_ context.Context) ([]*service.AreaSummary, error) {
+	return f.areas, nil
+}
+
+func (f *fakeSuggestService) CanSuggestRecipes() bool { return true }
+
+func (f *fakeSuggestService) SuggestRecipes(_ context.Context, areaIDs []int64, onDelta func(string)) error {
+	f.areaIDs = areaIDs
+	for _, d := range f.deltas {
+		onDelta(d)
+	}
+	return f.err
+}
+
+func postSuggest(srv *Server, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/suggest", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAreasPageSuggestPanel(t *testing.T) {
+	svc := &fakeSuggestService{areas: []*service.AreaSummary{{Area: &domain.Area{ID: 4, Name: "Fridge"}}}}
+	rec := httptest.NewRecorder()
+	newOverrideTestServer(svc).ServeHTTP(rec, httptest.NewRequest("GET", "/areas", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `data-testid="suggest-panel"`)
+	assert.Contains(t, rec.Body.String(), `name="area_id" value="4"`)
+
+	rec = httptest.NewRecorder()
+	newOverrideTestServer(svc).WithDemoMode().ServeHTTP(rec, httptest.NewRequest("GET", "/areas", nil))
+	assert.NotContains(t, rec.Body.String(), `data-testid="suggest-panel"`, "the demo is read-only")
+}
+
+func TestHandleSuggestRecipes_Streams(t *testing.T) {
+	svc := &fakeSuggestService{deltas: []string{"1. Omelette", "\n2. Pancakes"}}
+	rec := postSuggest(newOverrideTestServer(svc), url.Values{"area_id": {"1", "3"}})
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	assert.Equal(t, []int64{1, 3}, svc.areaIDs)
+	assert.Equal(t, "event: delta\ndata: \"1. Omelette\"\n\n"+
+		"event: delta\ndata: \"\\n2. Pancakes\"\n\n"+
+		"event: done\ndata: \"\"\n\n", rec.Body.String())
+}
+
+func TestHandleSuggestRecipes_FailsMidStream(t *testing.T) {
+	svc := &fakeSuggestService{deltas: []string{"1. Omelette"}, err: errors.New("connection reset")}
+	rec := postSuggest(newOverrideTestServer(svc), nil)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.HasSuffix(rec.Body.String(), "event: error\ndata: \"the model stopped before finishing\"\n\n"))
+}
+
+func TestHandleSuggestRecipes_Errors(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		form   url.Values
+		err    error
+		status int
+	}{
+		{"invalid area", url.Values{"area_id": {"x"}}, nil, http.StatusBadRequest},
+		{"unsupported", nil, service.ErrSuggestUnsupported, http.StatusNotImplemented},
+		{"unknown area", url.Values{"area_id": {"9"}}, service.ErrAreaNotFound, http.StatusNotFound},
+		{"nothing in stock", nil, service.ErrNothingToCook, http.StatusUnprocessableEntity},
+		{"busy", nil, service.ErrTooManyAnalyses, http.StatusTooManyRequests},
+		{"backend down", nil, errors.New("dial tcp: refused"), http.StatusBadGateway},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postSuggest(newOverrideTestServer(&fakeSuggestService{err: tt.err}), tt.form)
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
@@ -1,13 +1,38 @@
 package web
 
 import (
-	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/vbonduro/kitchinv/internal/photostore"
+	"github.com/vbonduro/kitchinv/internal/service"
 )
 
-const maxPhotoSize = 50 * 1024 * 1024 // 50 MB
+const defaultMaxPhotoSize = 50 * 1024 * 1024 // 50 MB
+
+// multipartOverhead is the allowance on top of the photo size for the rest of
+// a multipart upload body: boundaries, part headers and small form fields.
+const multipartOverhead = 64 * 1024
+
+// maxPhotosPerUpload caps how many images one multipart upload may carry.
+const maxPhotosPerUpload = 5
+
+// multipartMemory is how much of a multipart upload is buffered in memory
+// before the remainder spills to a temporary file.
+const multipartMemory = 10 * 1024 * 1024
 
 // allowedImageTypes is the set of MIME types accepted for uploaded photos.
 // net/http.DetectContentType handles JPEG, PNG, and GIF via magic-byte
@@ -40,48 +65,260 @@ func allowedImageMIME(data []byte) (string, bool) {
 	return "", false
 }
 
+// correlationIDHeader echoes the client_correlation_id form field back on the
+// upload response so a tab can tell its own upload apart from one made
+// elsewhere when it next refreshes the card.
+const correlationIDHeader = "X-Client-Correlation-ID"
+
+// maxCorrelationIDLen bounds the client-supplied correlation ID; it is opaque
+// to the server and only ever echoed back.
+const maxCorrelationIDLen = 64
+
+// clientCorrelationID returns the sanitised client_correlation_id form value,
+// or "" if it is absent or not a valid opaque ID.
+func clientCorrelationID(r *http.Request) string {
+	id := r.FormValue("client_correlation_id")
+	if !validOpaqueID(id) {
+		return ""
+	}
+	return id
+}
+
+// validOpaqueID reports whether a client-supplied ID is non-empty, at most
+// maxCorrelationIDLen long and limited to [A-Za-z0-9_-], so it is safe to
+// echo in headers and logs.
+func validOpaqueID(id string) bool {
+	if id == "" || len(id) > maxCorrelationIDLen {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// handleUploadPhoto analyses the photos in the form's image fields, up to
+// maxPhotosPerUpload, as one upload: an area that takes several photos to
+// cover has its items replaced once, from all of them.
 func (s *Server) handleUploadPhoto(w http.ResponseWriter, r *http.Request) {
 	areaID, err := parseID(r)
 	if err != nil {
-		http.Error(w, "invalid area id", http.StatusBadRequest)
+		s.renderError(w, r, http.StatusBadRequest, "invalid area id")
 		return
 	}
 
-	if err := r.ParseMultipartForm(maxPhotoSize); err != nil {
-		http.Error(w, "failed to parse form", http.StatusBadRequest)
+	// ParseMultipartForm's argument only bounds memory use; without this cap
+	// an oversized upload would still be read in full.
+	r.Body = http.MaxBytesReader(w, r.Body, maxPhotosPerUpload*s.maxPhotoSize+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			s.photoTooLarge(w, r)
+			return
+		}
+		s.renderError(w, r, http.StatusBadRequest, "failed to parse form")
 		return
 	}
 
-	file, _, err := r.FormFile("image")
+	correlationID := clientCorrelationID(r)
+	if correlationID != "" {
+		w.Header().Set(correlationIDHeader, correlationID)
+	}
+
+	files := r.MultipartForm.File["image"]
+	switch {
+	case len(files) == 0:
+		s.renderError(w, r, http.StatusBadRequest, "image file required")
+		return
+	case len(files) > maxPhotosPerUpload:
+		s.renderError(w, r, http.StatusBadRequest, fmt.Sprintf("at most %d photos can be uploaded at once", maxPhotosPerUpload))
+		return
+	}
+
+	uploads := make([]service.PhotoUpload, 0, len(files))
+	for _, fh := range files {
+		imageData, err := s.readUploadFile(fh)
+		if err != nil {
+			s.renderError(w, r, http.StatusInternalServerError, "failed to read file")
+			s.log(r).Error("read upload failed", "area_id", areaID, "error", err)
+			return
+		}
+		if int64(len(imageData)) > s.maxPhotoSize {
+			s.photoTooLarge(w, r)
+			return
+		}
+
+		mimeType, ok := allowedImageMIME(imageData)
+		if !ok {
+			s.renderError(w, r, http.StatusBadRequest, "unsupported image format")
+			return
+		}
+		uploads = append(uploads, service.PhotoUpload{Data: imageData, MIMEType: mimeType})
+	}
+
+	// The service detaches the analysis from the request itself; passing the
+	// live context lets it tell an abandoned upload from one still awaited.
+	photos, items, err := s.service.UploadPhotos(r.Context(), areaID, uploads, uploadOptions(r))
 	if err != nil {
-		http.Error(w, "image file required", http.StatusBadRequest)
+		s.writeUploadError(w, r, areaID, err, "correlation_id", correlationID)
 		return
 	}
+	// The area page shows its incomplete-analysis warning from this header,
+	// as the item list alone can't say.
+	for _, photo := range photos {
+		if photo.Truncated {
+			w.Header().Set(truncatedHeader, "true")
+		}
+		if photo.ItemsKept {
+			w.Header().Set(itemsKeptHeader, "true")
+		}
+	}
+
+	data := map[string]any{"AreaID": areaID, "Items": items}
+	if err := s.renderPartial(w, r, "partials/item_list.html", data); err != nil {
+		s.log(r).Error("render partial failed", "error", err)
+	}
+}
+
+// readUploadFile reads one file of a multipart upload.
+func (s *Server) readUploadFile(fh *multipart.FileHeader) ([]byte, error) {
+	file, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
 	defer closeWithLog(file, "upload file", s.logger)
+	return io.ReadAll(file)
+}
+
+// truncatedHeader is set on an upload response when a photo's analysis
+// stopped at the model's output limit, so its items may be incomplete.
+const truncatedHeader = "X-Analysis-Truncated"
+
+// itemsKeptHeader is set on an upload response when the analysis detected
+// nothing and the area's previous items were kept.
+const itemsKeptHeader = "X-Analysis-Items-Kept"
+
+// handleAPIUploadPhoto accepts the raw image as the request body, for
+// clients where building a multipart form is awkward, and responds with the
+// stored photo and items as JSON.
+func (s *Server) handleAPIUploadPhoto(w http.ResponseWriter, r *http.Request) {
+	areaID, err := parseID(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "invalid area id")
+		return
+	}
 
-	imageData, err := io.ReadAll(file)
+	imageData, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxPhotoSize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		s.photoTooLarge(w, r)
+		return
+	}
 	if err != nil {
-		http.Error(w, "failed to read file", http.StatusInternalServerError)
-		s.logger.Error("read upload failed", "area_id", areaID, "error", err)
+		s.renderError(w, r, http.StatusBadRequest, "failed to read body")
+		return
+	}
+	if len(imageData) == 0 {
+		s.renderError(w, r, http.StatusBadRequest, "image body required")
 		return
 	}
 
 	mimeType, ok := allowedImageMIME(imageData)
 	if !ok {
-		http.Error(w, "unsupported image format", http.StatusBadRequest)
+		s.renderError(w, r, http.StatusUnsupportedMediaType, "unsupported image format")
+		return
+	}
+	if declared, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || declared != mimeType {
+		s.renderError(w, r, http.StatusUnsupportedMediaType, "Content-Type must declare the image's type ("+mimeType+")")
 		return
 	}
 
-	_, items, err := s.service.UploadPhoto(context.WithoutCancel(r.Context()), areaID, imageData, mimeType)
+	photo, items, err := s.service.UploadPhotoWithOptions(r.Context(), areaID, imageData, mimeType, uploadOptions(r))
 	if err != nil {
-		http.Error(w, "failed to process photo", http.StatusInternalServerError)
-		s.logger.Error("upload photo failed", "area_id", areaID, "error", err)
+		s.writeUploadError(w, r, areaID, err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"photo": photo, "items": items})
+}
+
+// uploadOptions reads the upload query flags. ?force=true analyses the photo
+// even if it is identical to the area's latest one; ?replace_empty=true
+// replaces the area's items even if the analysis detects none.
+func uploadOptions(r *http.Request) service.UploadOptions {
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	replaceEmpty, _ := strconv.ParseBool(r.URL.Query().Get("replace_empty"))
+	return service.UploadOptions{Force: force, ReplaceEmpty: replaceEmpty}
+}
+
+// handleResolveKeptItems answers the question an upload that detected
+// nothing leaves on the area page: the form field replace=true empties the
+// list, anything else keeps the previous items. It responds with the item
+// list.
+func (s *Server) handleResolveKeptItems(w http.ResponseWriter, r *http.Request) {
+	areaID, err := parseID(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "invalid area id")
+		return
+	}
+	replace, _ := strconv.ParseBool(r.FormValue("replace"))
+
+	items, err := s.service.ResolveKeptItems(r.Context(), areaID, replace)
+	switch {
+	case errors.Is(err, service.ErrAreaNotFound):
+		s.renderError(w, r, http.StatusNotFound, "area not found")
+		return
+	case errors.Is(err, service.ErrNoKeptItems):
+		s.renderError(w, r, http.StatusConflict, "the latest photo is not waiting for a decision on its items")
+		return
+	case errors.Is(err, service.ErrAnalysisInProgress):
+		s.renderError(w, r, http.StatusConflict, "this area's last photo is still being analysed")
+		return
+	case err != nil:
+		s.renderError(w, r, http.StatusInternalServerError, "failed to update items")
+		s.log(r).Error("resolve kept items failed", "area_id", areaID, "error", err)
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(items)
+		return
+	}
 	data := map[string]any{"AreaID": areaID, "Items": items}
-	if err := s.renderPartial(w, "partials/item_list.html", data); err != nil {
-		s.logger.Error("render partial failed", "error", err)
+	if err := s.renderPartial(w, r, "partials/item_list.html", data); err != nil {
+		s.log(r).Error("render partial failed", "error", err)
+	}
+}
+
+// photoTooLarge responds 413 with the configured limit.
+func (s *Server) photoTooLarge(w http.ResponseWriter, r *http.Request) {
+	msg := fmt.Sprintf("photo is too large; the limit is %s", humanize.IBytes(uint64(s.maxPhotoSize)))
+	s.renderError(w, r, http.StatusRequestEntityTooLarge, msg)
+}
+
+// writeUploadError maps an UploadPhoto error to a response, logging failures
+// that aren't the client's doing. logAttrs are added to that log line.
+func (s *Server) writeUploadError(w http.ResponseWriter, r *http.Request, areaID int64, err error, logAttrs ...any) {
+	switch {
+	case errors.Is(err, service.ErrAreaNotFound):
+		s.renderError(w, r, http.StatusNotFound, "area not found")
+	case errors.Is(err, service.ErrTooManyAnalyses):
+		w.Header().Set("Retry-After", "10")
+		s.renderError(w, r, http.StatusTooManyRequests, "too many photos are being analysed, try again shortly")
+	case errors.Is(err, service.ErrAnalysisInProgress):
+		s.renderError(w, r, http.StatusConflict, "this area's last photo is still being analysed; wait for it to finish before uploading another")
+	case errors.Is(err, service.ErrAnalysisTimeout):
+		s.renderError(w, r, http.StatusGatewayTimeout, "photo analysis took too long, try again")
+		s.log(r).Warn("upload photo timed out", append([]any{"area_id", areaID, "error", err}, logAttrs...)...)
+	default:
+		s.renderError(w, r, http.StatusInternalServerError, "failed to process photo")
+		s.log(r).Error("upload photo failed", append([]any{"area_id", areaID, "error", err}, logAttrs...)...)
 	}
 }
 
@@ -95,7 +332,7 @@ func (s *Server) handleGetPhoto(w http.ResponseWriter, r *http.Request) {
 	_, _, photo, err := s.service.GetAreaWithItems(r.Context(), areaID)
 	if err != nil {
 		http.Error(w, "failed to get area", http.StatusInternalServerError)
-		s.logger.Error("get area for photo failed", "area_id", areaID, "error", err)
+		s.log(r).Error("get area for photo failed", "area_id", areaID, "error", err)
 		return
 	}
 	if photo == nil {
@@ -103,6 +340,37 @@ func (s *Server) handleGetPhoto(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A new upload creates a new photo row, so a URL naming the photo ID
+	// (as the templates emit) always refers to the same bytes and can be
+	// cached for good. The bare URL must be revalidated.
+	etag := photoETag(photo.StorageKey)
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Last-Modified", photo.UploadedAt.UTC().Format(http.TimeFormat))
+	if r.URL.Query().Get("v") == strconv.FormatInt(photo.ID, 10) {
+		h.Set("Cache-Control", "private, max-age=31536000, immutable")
+	} else {
+		h.Set("Cache-Control", "private, no-cache")
+	}
+	if notModified(r, etag, photo.UploadedAt) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	// ServeContent handles Range requests, which Safari relies on for some
+	// images; stores that can't seek get the whole body.
+	if ss, ok := s.photoStore.(photostore.SeekerStore); ok {
+		f, mimeType, err := ss.GetSeeker(r.Context(), photo.StorageKey)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer closeWithLog(f, "photo reader", s.logger)
+		w.Header().Set("Content-Type", mimeType)
+		http.ServeContent(w, r, "", photo.UploadedAt, f)
+		return
+	}
+
 	reader, mimeType, err := s.photoStore.Get(r.Context(), photo.StorageKey)
 	if err != nil {
 		http.NotFound(w, r)
@@ -112,8 +380,115 @@ func (s *Server) handleGetPhoto(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", mimeType)
 	if _, err := io.Copy(w, reader); err != nil {
-		s.logger.Error("write photo failed", "area_id", areaID, "error", err)
+		s.log(r).Error("write photo failed", "area_id", areaID, "error", err)
+	}
+}
+
+// handleGetItemPhoto serves the thumbnail cropped around an item when its
+// photo was analysed, or 404 if it has none.
+func (s *Server) handleGetItemPhoto(w http.ResponseWriter, r *http.Request) {
+	areaID, err := parseID(r)
+	if err != nil {
+		http.Error(w, "invalid area id", http.StatusBadRequest)
+		return
+	}
+	itemID, err := parseItemID(r)
+	if err != nil {
+		http.Error(w, "invalid item id", http.StatusBadRequest)
+		return
+	}
+
+	item, err := s.service.GetItem(r.Context(), areaID, itemID)
+	if err != nil {
+		http.Error(w, "failed to get item", http.StatusInternalServerError)
+		s.log(r).Error("get item for thumbnail failed", "item_id", itemID, "error", err)
+		return
+	}
+	if item == nil || item.CropKey == "" || s.photoStore == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// An item keeps its thumbnail for life, but item IDs can be reused after
+	// a delete, so clients revalidate against the ETag.
+	etag := photoETag(item.CropKey)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if notModified(r, etag, item.CreatedAt) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	reader, mimeType, err := s.photoStore.Get(r.Context(), item.CropKey)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer closeWithLog(reader, "thumbnail reader", s.logger)
+
+	w.Header().Set("Content-Type", mimeType)
+	if _, err := io.Copy(w, reader); err != nil {
+		s.log(r).Error("write thumbnail failed", "item_id", itemID, "error", err)
+	}
+}
+
+// photoETag is a strong validator for the photo stored under storageKey.
+func photoETag(storageKey string) string {
+	sum := sha256.Sum256([]byte(storageKey))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified reports whether the request's conditional headers match the
+// photo, so a 304 can be sent. If-None-Match takes precedence over
+// If-Modified-Since, as RFC 9110 requires.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return matchesETag(inm, etag)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		// Last-Modified has second precision.
+		return err == nil && !modified.Truncate(time.Second).After(t)
 	}
+	return false
+}
+
+// matchesETag reports whether an If-None-Match value names etag. Tags are
+// compared weakly, as RFC 9110 requires for If-None-Match.
+func matchesETag(inm, etag string) bool {
+	for _, tag := range strings.Split(inm, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// handleGetRawResponse returns the vision backend's unparsed reply for the
+// area's latest photo. Registered behind debugOnly.
+func (s *Server) handleGetRawResponse(w http.ResponseWriter, r *http.Request) {
+	areaID, err := parseID(r)
+	if err != nil {
+		http.Error(w, "invalid area id", http.StatusBadRequest)
+		return
+	}
+
+	raw, err := s.service.LatestRawResponse(r.Context(), areaID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrAreaNotFound):
+			http.Error(w, "area not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrNoPhoto):
+			http.Error(w, "area has no photo", http.StatusNotFound)
+		default:
+			http.Error(w, "failed to get raw response", http.StatusInternalServerError)
+			s.log(r).Error("get raw response failed", "area_id", areaID, "error", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, raw)
 }
 
 // closeWithLog closes c and logs any error, using label to identify the resource.
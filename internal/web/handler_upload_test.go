@@ -1,7 +1,18 @@
 package web
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/service"
 )
 
 func TestAllowedImageMIME(t *testing.T) {
@@ -73,3 +84,171 @@ func TestAllowedImageMIME(t *testing.T) {
 		})
 	}
 }
+
+func TestClientCorrelationID(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "absent", value: "", want: ""},
+		{name: "uuid-like", value: "3f2b9c1e-tab_2", want: "3f2b9c1e-tab_2"},
+		{name: "header injection", value: "abc\r\nX-Evil: 1", want: ""},
+		{name: "too long", value: strings.Repeat("a", maxCorrelationIDLen+1), want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			if tt.value != "" {
+				form.Set("client_correlation_id", tt.value)
+			}
+			r := httptest.NewRequest(http.MethodPost, "/areas/1/photos", strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if got := clientCorrelationID(r); got != tt.want {
+				t.Errorf("clientCorrelationID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadPhoto_TooLarge(t *testing.T) {
+	const limit = 4 * 1024
+	srv := newOverrideTestServer(&fakeOverrideService{}).WithMaxPhotoSize(limit)
+	// A JPEG header followed by enough padding to exceed the limit.
+	image := append([]byte{0xFF, 0xD8, 0xFF, 0xE0}, make([]byte, limit)...)
+
+	tests := []struct {
+		name  string
+		build func() *http.Request
+	}{
+		{
+			name: "multipart image over the limit",
+			build: func() *http.Request {
+				body := &bytes.Buffer{}
+				mw := multipart.NewWriter(body)
+				fw, _ := mw.CreateFormFile("image", "photo.jpg")
+				_, _ = fw.Write(image)
+				_ = mw.Close()
+				r := httptest.NewRequest(http.MethodPost, "/areas/1/photos", body)
+				r.Header.Set("Content-Type", mw.FormDataContentType())
+				return r
+			},
+		},
+		{
+			name: "multipart body far over the limit",
+			build: func() *http.Request {
+				body := &bytes.Buffer{}
+				mw := multipart.NewWriter(body)
+				fw, _ := mw.CreateFormFile("image", "photo.jpg")
+				_, _ = fw.Write(bytes.Repeat(image, 32))
+				_ = mw.Close()
+				r := httptest.NewRequest(http.MethodPost, "/areas/1/photos", body)
+				r.Header.Set("Content-Type", mw.FormDataContentType())
+				return r
+			},
+		},
+		{
+			name: "raw body over the limit",
+			build: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPut, "/api/v1/areas/1/photo", bytes.NewReader(image))
+				r.Header.Set("Content-Type", "image/jpeg")
+				return r
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, tt.build())
+			if rec.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("status = %d, want 413: %s", rec.Code, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), "4.0 KiB") {
+				t.Errorf("body should state the limit, got %q", rec.Body)
+			}
+		})
+	}
+}
+
+func TestUploadOptions(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantForce bool
+	}{
+		{query: "", wantForce: false},
+		{query: "?force=true", wantForce: true},
+		{query: "?force=1", wantForce: true},
+		{query: "?force=false", wantForce: false},
+		{query: "?force=yes-please", wantForce: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/areas/1/photos"+tt.query, nil)
+			if got := uploadOptions(r).Force; got != tt.wantForce {
+				t.Errorf("uploadOptions(%q).Force = %v, want %v", tt.query, got, tt.wantForce)
+			}
+		})
+	}
+}
+
+// fakeUploadService records the photos of the last upload.
+type fakeUploadService struct {
+	fakeOverrideService
+	uploads []service.PhotoUpload
+}
+
+func (f *fakeUploadService) UploadPhotos(_ context.Context, _ int64, uploads []service.PhotoUpload, _ service.UploadOptions) ([]*domain.Photo, []*domain.Item, error) {
+	f.uploads = uploads
+	return nil, []*domain.Item{{ID: 1, AreaID: 1, Name: "Flour", Quantity: "1"}}, nil
+}
+
+// multipartPhotos builds an upload with one image field per image.
+func multipartPhotos(images ...[]byte) *http.Request {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for i, img := range images {
+		fw, _ := mw.CreateFormFile("image", fmt.Sprintf("photo%d", i))
+		_, _ = fw.Write(img)
+	}
+	_ = mw.Close()
+	r := httptest.NewRequest(http.MethodPost, "/areas/1/photos", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestUploadPhoto_MultiplePhotos(t *testing.T) {
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10}
+	png := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A, 0x00}
+	svc := &fakeUploadService{}
+	srv := newOverrideTestServer(svc)
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, multipartPhotos(jpeg, png))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if len(svc.uploads) != 2 || svc.uploads[0].MIMEType != "image/jpeg" || svc.uploads[1].MIMEType != "image/png" {
+		t.Errorf("uploads = %+v, want the JPEG then the PNG", svc.uploads)
+	}
+	if !strings.Contains(rec.Body.String(), "Flour") {
+		t.Errorf("body should list the merged items, got %q", rec.Body)
+	}
+
+	svc.uploads = nil
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, multipartPhotos(jpeg, []byte("%PDF-1.4")))
+	if rec.Code != http.StatusBadRequest || svc.uploads != nil {
+		t.Errorf("one unsupported photo should reject the upload, got %d", rec.Code)
+	}
+
+	images := make([][]byte, maxPhotosPerUpload+1)
+	for i := range images {
+		images[i] = jpeg
+	}
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, multipartPhotos(images...))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "at most 5 photos") {
+		t.Errorf("status = %d, body %q; want 400 for too many photos", rec.Code, rec.Body)
+	}
+}
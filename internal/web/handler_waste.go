@@ -0,0 +1,53 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/service"
+)
+
+// wasteMonths is how many months of waste the areas page summarises,
+// including the current one.
+const wasteMonths = 12
+
+// handleConsumeItem marks an item used up, taking it out of the inventory.
+func (s *Server) handleConsumeItem(w http.ResponseWriter, r *http.Request) {
+	s.retireItem(w, r, "consume", s.service.ConsumeItem)
+}
+
+// handleDiscardItem marks an item thrown away, counting it as waste.
+func (s *Server) handleDiscardItem(w http.ResponseWriter, r *http.Request) {
+	s.retireItem(w, r, "discard", s.service.DiscardItem)
+}
+
+// retireItem applies retire to the item in the path and responds with the
+// item in its new state.
+func (s *Server) retireItem(w http.ResponseWriter, r *http.Request, verb string,
+	retire func(ctx context.Context, areaID, itemID int64) (*domain.Item, error)) {
+	areaID, err := parseID(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "invalid area id")
+		return
+	}
+	itemID, err := parseItemID(r)
+	if err != nil {
+		s.renderError(w, r, http.StatusBadRequest, "invalid item id")
+		return
+	}
+
+	item, err := retire(r.Context(), areaID, itemID)
+	switch {
+	case errors.Is(err, service.ErrItemNotFound):
+		s.renderError(w, r, http.StatusNotFound, "item not found")
+	case errors.Is(err, service.ErrItemNotActive):
+		s.renderError(w, r, http.StatusConflict, "item has already been consumed or discarded")
+	case err != nil:
+		s.renderError(w, r, http.StatusInternalServerError, "failed to "+verb+" item")
+		s.log(r).Error(verb+" item failed", "item_id", itemID, "error", err)
+	default:
+		s.writeItem(w, r, http.StatusOK, item)
+	}
+}
@@ -0,0 +1,34 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+type fakeWasteService struct {
+	fakeOverrideService
+	waste []domain.MonthlyWaste
+}
+
+func (f *fakeWasteService) MonthlyWaste(_ context.Context, _ int) ([]domain.MonthlyWaste, error) {
+	return f.waste, nil
+}
+
+func TestListAreasWasteSummary(t *testing.T) {
+	svc := &fakeWasteService{waste: []domain.MonthlyWaste{{Month: "2026-10", Consumed: 4, Discarded: 1}}}
+	rec := httptest.NewRecorder()
+	newOverrideTestServer(svc).ServeHTTP(rec, httptest.NewRequest("GET", "/areas", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `data-testid="waste-summary"`)
+	assert.Contains(t, rec.Body.String(), `<td>2026-10</td><td>4</td><td>1</td>`)
+
+	rec = httptest.NewRecorder()
+	newOverrideTestServer(&fakeWasteService{}).ServeHTTP(rec, httptest.NewRequest("GET", "/areas", nil))
+	assert.NotContains(t, rec.Body.String(), `data-testid="waste-summary"`, "hidden until something is used up or thrown away")
+}
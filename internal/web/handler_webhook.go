@@ -0,0 +1,171 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/service"
+)
+
+// webhookRequest is the body of POST /webhooks and PUT /webhooks/{id}.
+// Enabled defaults to true; an empty secret generates one on create and
+// keeps the current one on update.
+type webhookRequest struct {
+	URL     string   `json:"url"`
+	Secret  string   `json:"secret"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// createdWebhook is a new webhook as returned by POST /webhooks, the only
+// response that includes the secret.
+type createdWebhook struct {
+	*domain.Webhook
+	Secret string
+}
+
+func (s *Server) handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := s.service.ListWebhooks(r.Context())
+	if err != nil {
+		http.Error(w, "failed to list webhooks", http.StatusInternalServerError)
+		s.log(r).Error("list webhooks failed", "error", err)
+		return
+	}
+	if hooks == nil {
+		hooks = []*domain.Webhook{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(hooks)
+}
+
+func (s *Server) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	h, ok := readWebhookRequest(w, r)
+	if !ok {
+		return
+	}
+	hook, err := s.service.CreateWebhook(r.Context(), h)
+	if err != nil {
+		s.writeWebhookError(w, r, err, "failed to create webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(createdWebhook{Webhook: hook, Secret: hook.Secret})
+}
+
+func (s *Server) handleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		http.Error(w, "invalid webhook id", http.StatusBadRequest)
+		return
+	}
+	h, ok := readWebhookRequest(w, r)
+	if !ok {
+		return
+	}
+	h.ID = id
+	hook, err := s.service.UpdateWebhook(r.Context(), h)
+	if err != nil {
+		s.writeWebhookError(w, r, err, "failed to update webhook")
+		return
+	}
+	if hook == nil {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(hook)
+}
+
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		http.Error(w, "invalid webhook id", http.StatusBadRequest)
+		return
+	}
+
+	found, err := s.service.DeleteWebhook(r.Context(), id)
+	if err != nil {
+		http.Error(w, "failed to delete webhook", http.StatusInternalServerError)
+		s.log(r).Error("delete webhook failed", "id", id, "error", err)
+		return
+	}
+	if !found {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		http.Error(w, "invalid webhook id", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := s.service.ListWebhookDeliveries(r.Context(), id)
+	if err != nil {
+		http.Error(w, "failed to list webhook deliveries", http.StatusInternalServerError)
+		s.log(r).Error("list webhook deliveries failed", "id", id, "error", err)
+		return
+	}
+	if deliveries == nil {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(deliveries)
+}
+
+// handleTestWebhook sends a webhook.test event and returns the logged
+// attempt. A failed delivery is still a 200: the attempt itself says why.
+func (s *Server) handleTestWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := parseID(r)
+	if err != nil {
+		http.Error(w, "invalid webhook id", http.StatusBadRequest)
+		return
+	}
+
+	d, err := s.service.SendTestWebhook(r.Context(), id)
+	if err != nil {
+		http.Error(w, "failed to send test event", http.StatusInternalServerError)
+		s.log(r).Error("send test webhook failed", "id", id, "error", err)
+		return
+	}
+	if d == nil {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(d)
+}
+
+func readWebhookRequest(w http.ResponseWriter, r *http.Request) (domain.Webhook, bool) {
+	var body webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return domain.Webhook{}, false
+	}
+	h := domain.Webhook{URL: body.URL, Secret: body.Secret, Events: body.Events, Enabled: true}
+	if body.Enabled != nil {
+		h.Enabled = *body.Enabled
+	}
+	return h, true
+}
+
+func (s *Server) writeWebhookError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	if errors.Is(err, service.ErrWebhookInvalid) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, msg, http.StatusInternalServerError)
+	s.log(r).Error(msg, "error", err)
+}
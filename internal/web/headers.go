@@ -0,0 +1,102 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/vbonduro/kitchinv/internal/config"
+)
+
+// noncePlaceholder in a Content-Security-Policy is replaced with the
+// request's script nonce.
+const noncePlaceholder = "{nonce}"
+
+// SecurityHeaders are the defensive headers added to every response. An
+// empty field leaves its header out, for when a reverse proxy sets it.
+type SecurityHeaders struct {
+	ContentSecurityPolicy   string // may contain noncePlaceholder
+	StrictTransportSecurity string // only sent over HTTPS
+	FrameOptions            string
+	ContentTypeOptions      string
+	ReferrerPolicy          string
+}
+
+// DefaultSecurityHeaders returns the headers sent unless configured
+// otherwise. HSTS is off, since on a LAN with a self-signed certificate it
+// would stop browsers offering to proceed past a certificate warning.
+func DefaultSecurityHeaders() SecurityHeaders {
+	return SecurityHeaders{
+		ContentSecurityPolicy: config.DefaultContentSecurityPolicy,
+		FrameOptions:          "DENY",
+		ContentTypeOptions:    "nosniff",
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+	}
+}
+
+// WithSecurityHeaders replaces the default security headers.
+func (s *Server) WithSecurityHeaders(h SecurityHeaders) *Server {
+	s.headers = h
+	return s
+}
+
+type nonceKey struct{}
+
+// securityHeaders adds the configured security headers to every response.
+// When the policy uses a nonce a fresh one is made for each request and
+// stored in its context, where renderPage finds it for the templates.
+func (s *Server) securityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		set := func(name, value string) {
+			if value != "" {
+				h.Set(name, value)
+			}
+		}
+		set("X-Content-Type-Options", s.headers.ContentTypeOptions)
+		set("X-Frame-Options", s.headers.FrameOptions)
+		set("Referrer-Policy", s.headers.ReferrerPolicy)
+		if clientScheme(r) == "https" {
+			set("Strict-Transport-Security", s.headers.StrictTransportSecurity)
+		}
+		csp := s.headers.ContentSecurityPolicy
+		if strings.Contains(csp, noncePlaceholder) {
+			nonce := newNonce()
+			csp = strings.ReplaceAll(csp, noncePlaceholder, nonce)
+			r = r.WithContext(context.WithValue(r.Context(), nonceKey{}, nonce))
+		}
+		set("Content-Security-Policy", csp)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validNonce reports whether s could have been made by newNonce, and so is
+// safe to put back in a policy.
+func validNonce(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// newNonce returns a random 128-bit nonce in URL-safe base64, which CSP
+// accepts and templates needn't escape.
+func newNonce() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// cspNonce returns the script nonce of the request, or "" when the policy
+// doesn't use one.
+func cspNonce(r *http.Request) string {
+	n, _ := r.Context().Value(nonceKey{}).(string)
+	return n
+}
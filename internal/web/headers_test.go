@@ -0,0 +1,58 @@
+package web
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var cspNonceRe = regexp.MustCompile(`'nonce-([^']+)'`)
+
+func TestCSPNonceMatchesPage(t *testing.T) {
+	srv := newOverrideTestServer(&fakeOverrideService{})
+	get := func() (nonce, body string) {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/overrides", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		m := cspNonceRe.FindStringSubmatch(rec.Header().Get("Content-Security-Policy"))
+		require.NotNil(t, m, "the default policy carries a nonce")
+		return m[1], rec.Body.String()
+	}
+
+	nonce, body := get()
+	assert.NotContains(t, body, "<script>", "every inline script carries the nonce")
+	assert.Contains(t, body, `<script nonce="`+nonce+`">`)
+	assert.Contains(t, body, `"inlineScriptNonce":"`+nonce+`"`, "htmx gives swapped-in scripts the page's nonce")
+
+	other, _ := get()
+	assert.NotEqual(t, nonce, other, "each response gets a fresh nonce")
+}
+
+func TestSecurityHeadersConfigurable(t *testing.T) {
+	srv := newOverrideTestServer(&fakeOverrideService{}).WithSecurityHeaders(SecurityHeaders{
+		ContentSecurityPolicy:   "default-src 'self'",
+		StrictTransportSecurity: "max-age=31536000",
+		ContentTypeOptions:      "nosniff",
+	})
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/overrides", nil))
+	h := rec.Header()
+	assert.Equal(t, "default-src 'self'", h.Get("Content-Security-Policy"))
+	assert.Equal(t, "nosniff", h.Get("X-Content-Type-Options"))
+	assert.Empty(t, h.Values("X-Frame-Options"), "an empty value leaves the header out")
+	assert.Empty(t, h.Values("Referrer-Policy"))
+	assert.Empty(t, h.Values("Strict-Transport-Security"), "HSTS is never sent over plain HTTP")
+	assert.Contains(t, rec.Body.String(), `<script nonce="">`, "no nonce without the placeholder")
+
+	req := httptest.NewRequest(http.MethodGet, "/overrides", nil)
+	req.TLS = &tls.ConnectionState{}
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, "max-age=31536000", rec.Header().Get("Strict-Transport-Security"))
+}
@@ -0,0 +1,26 @@
+package web
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/vbonduro/kitchinv/internal/textfold"
+)
+
+// highlight returns s escaped for HTML with each match of query, ignoring
+// case and diacritics as search does, wrapped in <mark>. Item names come
+// from the vision model, so s is only ever written through
+// template.HTMLEscapeString; the only markup added is the <mark> tags.
+func highlight(s, query string) template.HTML {
+	var b strings.Builder
+	prev := 0
+	for _, r := range textfold.Find(s, strings.TrimSpace(query)) {
+		b.WriteString(template.HTMLEscapeString(s[prev:r[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(template.HTMLEscapeString(s[r[0]:r[1]]))
+		b.WriteString("</mark>")
+		prev = r[1]
+	}
+	b.WriteString(template.HTMLEscapeString(s[prev:]))
+	return template.HTML(b.String())
+}
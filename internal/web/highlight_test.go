@@ -0,0 +1,49 @@
+package web
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name, s, query string
+		want           template.HTML
+	}{
+		{"plain", "Oat milk", "milk", "Oat <mark>milk</mark>"},
+		{"case and accents", "Crème Fraîche", "creme", "<mark>Crème</mark> Fraîche"},
+		{"every match", "Milk & milk", "MILK", "<mark>Milk</mark> &amp; <mark>milk</mark>"},
+		{"no match", "Butter", "milk", "Butter"},
+		{"empty query", "<b>Butter</b>", "  ", "&lt;b&gt;Butter&lt;/b&gt;"},
+		{"markup in the name stays text", `<script>alert("milk")</script>`, "milk", `&lt;script&gt;alert(&#34;<mark>milk</mark>&#34;)&lt;/script&gt;`},
+		{"query matching markup", `<img src=x onerror=alert(1)>`, "<img", `<mark>&lt;img</mark> src=x onerror=alert(1)&gt;`},
+		{"query matching an entity's source", "M&M's", "&", "M<mark>&amp;</mark>M&#39;s"},
+		{"query that looks like a tag", "Milk", "</mark><script>", "Milk"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, highlight(tt.s, tt.query))
+		})
+	}
+}
+
+// TestSearchResultsEscapeNames renders the results partial with a name a
+// model could plausibly return, checking only <mark> survives as markup.
+func TestSearchResultsEscapeNames(t *testing.T) {
+	srv := newOverrideTestServer(&fakeOverrideService{})
+	out, err := srv.renderFragment("partials/search_results.html", map[string]any{
+		"Items": []*domain.Item{{Name: `Milk"><script>alert(1)</script>`, Quantity: "<i>2</i>", AreaID: 1}},
+		"Query": "milk", "AreaPaths": map[int64]string{1: "<Fridge>"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, out, `<mark>Milk</mark>&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;`)
+	assert.Contains(t, out, `&lt;i&gt;2&lt;/i&gt;`)
+	assert.Contains(t, out, `&lt;Fridge&gt;`)
+	assert.NotContains(t, out, "<script>")
+	assert.Equal(t, 1, strings.Count(out, "<mark>"))
+}
@@ -3,6 +3,7 @@ package web_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -11,14 +12,20 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"regexp"
 	"strings"
-	"sync"
 	"testing"
+	"time"
 
+	"github.com/vbonduro/kitchinv/internal/audit"
 	"github.com/vbonduro/kitchinv/internal/db"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/photostore"
+	"github.com/vbonduro/kitchinv/internal/photostore/memory"
 	"github.com/vbonduro/kitchinv/internal/service"
 	"github.com/vbonduro/kitchinv/internal/store"
 	"github.com/vbonduro/kitchinv/internal/vision"
+	"github.com/vbonduro/kitchinv/internal/vision/visiontest"
 	"github.com/vbonduro/kitchinv/internal/web"
 	"github.com/vbonduro/kitchinv/internal/web/templates"
 )
@@ -43,92 +50,6 @@ var minimalJPEG = func() []byte {
 	return b
 }()
 
-// recordingVision captures the image bytes passed to it and returns a
-// pre-configured result.
-type recordingVision struct {
-	mu        sync.Mutex
-	lastBytes []byte
-	result    *vision.AnalysisResult
-}
-
-func (r *recordingVision) Analyze(_ context.Context, rd io.Reader, _ string) (*vision.AnalysisResult, error) {
-	data, err := io.ReadAll(rd)
-	if err != nil {
-		return nil, fmt.Errorf("recordingVision: read image: %w", err)
-	}
-	r.mu.Lock()
-	r.lastBytes = data
-	r.mu.Unlock()
-	return r.result, nil
-}
-
-func (r *recordingVision) LastBytes() []byte {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	return r.lastBytes
-}
-
-// memPhotoStore is a simple in-memory implementation of photostore.PhotoStore.
-type memPhotoStore struct {
-	mu      sync.Mutex
-	data    map[string][]byte
-	mimes   map[string]string
-	counter int
-}
-
-func newMemPhotoStore() *memPhotoStore {
-	return &memPhotoStore{
-		data:  make(map[string][]byte),
-		mimes: make(map[string]string),
-	}
-}
-
-func (m *memPhotoStore) Save(_ context.Context, prefix, mimeType string, r io.Reader) (string, error) {
-	data, err := io.ReadAll(r)
-	if err != nil {
-		return "", err
-	}
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.counter++
-	key := fmt.Sprintf("%s_%d", prefix, m.counter)
-	m.data[key] = data
-	m.mimes[key] = mimeType
-	return key, nil
-}
-
-func (m *memPhotoStore) Get(_ context.Context, key string) (io.ReadCloser, string, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	data, ok := m.data[key]
-	if !ok {
-		return nil, "", fmt.Errorf("key not found: %s", key)
-	}
-	return io.NopCloser(bytes.NewReader(data)), m.mimes[key], nil
-}
-
-func (m *memPhotoStore) Delete(_ context.Context, key string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	delete(m.data, key)
-	delete(m.mimes, key)
-	return nil
-}
-
-// blockingVision signals ready when Analyze is called, then blocks until
-// release is closed, allowing tests to inspect intermediate server state.
-type blockingVision struct {
-	ready   chan struct{}
-	release chan struct{}
-	result  *vision.AnalysisResult
-}
-
-func (b *blockingVision) Analyze(_ context.Context, _ io.Reader, _ string) (*vision.AnalysisResult, error) {
-	close(b.ready)
-	<-b.release
-	return b.result, nil
-}
-
 // newTestServer sets up a real web.Server backed by in-memory SQLite and the
 // provided vision stub. Returns the test server and a cleanup function.
 func newTestServer(t *testing.T, vis vision.VisionAnalyzer) (*httptest.Server, func()) {
@@ -138,6 +59,7 @@ func newTestServer(t *testing.T, vis vision.VisionAnalyzer) (*httptest.Server, f
 		t.Fatalf("OpenForTesting: %v", err)
 	}
 
+	photos := memory.New()
 	svc := service.NewAreaService(
 		store.NewAreaStore(database),
 		store.NewPhotoStore(database),
@@ -146,10 +68,14 @@ func newTestServer(t *testing.T, vis vision.VisionAnalyzer) (*httptest.Server, f
 		store.NewSnapshotStore(database),
 		store.NewOverrideStore(database),
 		vis,
-		newMemPhotoStore(),
+		photos,
 		slog.Default(),
-	).WithDB(database)
-	srv := httptest.NewServer(web.NewServer(svc, templates.FS, newMemPhotoStore(), slog.Default()))
+	).WithDB(database).WithIgnoreStore(store.NewIgnoreStore(database)).
+		WithAuditLog(audit.NewStore(database)).
+		WithWebhooks(store.NewWebhookStore(database)).
+		WithHistory(store.NewHistoryStore(database), service.DefaultHistoryKeep).
+		WithStapleStore(store.NewStapleStore(database))
+	srv := httptest.NewServer(web.NewServer(svc, templates.FS, photos, slog.Default()).WithBaseURL("http://kitchinv.test"))
 	return srv, func() {
 		srv.Close()
 		_ = database.Close()
@@ -199,7 +125,7 @@ func TestIntegration_CreateArea(t *testing.T) {
 		t.Skip("skipping integration test in short mode")
 	}
 
-	vis := &recordingVision{result: &vision.AnalysisResult{}}
+	vis := &visiontest.Recording{Result: &vision.AnalysisResult{}}
 	srv, cleanup := newTestServer(t, vis)
 	defer cleanup()
 
@@ -229,7 +155,7 @@ func TestIntegration_ListAreas(t *testing.T) {
 		t.Skip("skipping integration test in short mode")
 	}
 
-	vis := &recordingVision{result: &vision.AnalysisResult{}}
+	vis := &visiontest.Recording{Result: &vision.AnalysisResult{}}
 	srv, cleanup := newTestServer(t, vis)
 	defer cleanup()
 
@@ -260,7 +186,7 @@ func TestIntegration_DeleteArea(t *testing.T) {
 		t.Skip("skipping integration test in short mode")
 	}
 
-	vis := &recordingVision{result: &vision.AnalysisResult{}}
+	vis := &visiontest.Recording{Result: &vision.AnalysisResult{}}
 	srv, cleanup := newTestServer(t, vis)
 	defer cleanup()
 
@@ -294,8 +220,8 @@ func TestIntegration_UploadPhoto(t *testing.T) {
 		t.Skip("skipping integration test in short mode")
 	}
 
-	vis := &recordingVision{
-		result: &vision.AnalysisResult{
+	vis := &visiontest.Recording{
+		Result: &vision.AnalysisResult{
 			Items: []vision.DetectedItem{
 				{Name: "Orange Juice", Quantity: "1 carton", Notes: ""},
 			},
@@ -327,6 +253,141 @@ func TestIntegration_UploadPhoto(t *testing.T) {
 	}
 }
 
+// TestIntegration_UploadPhoto_Truncated checks that an analysis cut off at
+// the model's output limit is flagged on the upload response, the area page
+// and the JSON API.
+func TestIntegration_UploadPhoto_Truncated(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	vis := &visiontest.Recording{
+		Result: &vision.AnalysisResult{
+			Items:      []vision.DetectedItem{{Name: "Milk", Quantity: "1"}},
+			Truncated:  true,
+			StopReason: "max_tokens",
+		},
+	}
+	srv, cleanup := newTestServer(t, vis)
+	defer cleanup()
+
+	createArea(t, srv, "Fridge")
+
+	body, contentType := buildMultipartBody(t, minimalJPEG)
+	resp, err := http.Post(srv.URL+"/areas/1/photos", contentType, body)
+	if err != nil {
+		t.Fatalf("POST /areas/1/photos: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("X-Analysis-Truncated"); got != "true" {
+		t.Errorf("X-Analysis-Truncated = %q, want true", got)
+	}
+
+	resp, err = http.Get(srv.URL + "/areas/1")
+	if err != nil {
+		t.Fatalf("GET /areas/1: %v", err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if !strings.Contains(string(page), `data-testid="truncated-warning" role="status">`) {
+		t.Errorf("area page does not show the truncated warning:\n%s", page)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/api/v1/areas/1/photo?force=true", bytes.NewReader(minimalJPEG))
+	if err != nil {
+		t.Fatalf("new PUT request: %v", err)
+	}
+	req.Header.Set("Content-Type", "image/jpeg")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT /api/v1/areas/1/photo: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var got struct {
+		Photo struct {
+			StopReason string
+			Truncated  bool
+		} `json:"photo"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !got.Photo.Truncated || got.Photo.StopReason != "max_tokens" {
+		t.Errorf("unexpected photo in response: %+v", got.Photo)
+	}
+}
+
+func TestIntegration_UploadPhoto_NothingDetectedKeepsItems(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	vis := visiontest.NewScripted(
+		visiontest.Step{Result: &vision.AnalysisResult{Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1", BBox: &[4]float64{0.1, 0.2, 0.3, 0.4}}}}},
+		visiontest.Step{Result: &vision.AnalysisResult{RawResponse: "The photo is too dark to read."}},
+		visiontest.Step{Result: &vision.AnalysisResult{RawResponse: "The photo is too dark to read."}},
+	)
+	srv, cleanup := newTestServer(t, vis)
+	defer cleanup()
+
+	createArea(t, srv, "Fridge")
+
+	for i, wantKept := range []string{"", "true"} {
+		body, contentType := buildMultipartBody(t, minimalJPEG)
+		resp, err := http.Post(srv.URL+"/areas/1/photos?force=true", contentType, body)
+		if err != nil {
+			t.Fatalf("POST /areas/1/photos: %v", err)
+		}
+		page, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("upload %d: expected 200, got %d", i, resp.StatusCode)
+		}
+		if got := resp.Header.Get("X-Analysis-Items-Kept"); got != wantKept {
+			t.Errorf("upload %d: X-Analysis-Items-Kept = %q, want %q", i, got, wantKept)
+		}
+		if !strings.Contains(string(page), "Milk") {
+			t.Errorf("upload %d: item list lost Milk:\n%s", i, page)
+		}
+		resp, err = http.Get(srv.URL + "/areas/1/card")
+		if err != nil {
+			t.Fatalf("GET /areas/1/card: %v", err)
+		}
+		card, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		// The kept items were found in the previous photo, so their boxes
+		// would mark the wrong places on this one.
+		if got := strings.Contains(string(card), "bbox-rect"); got != (wantKept == "") {
+			t.Errorf("upload %d: card draws Milk's box = %v", i, got)
+		}
+	}
+
+	// replace_empty accepts the empty result, as uploads used to.
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/api/v1/areas/1/photo?force=true&replace_empty=true", bytes.NewReader(minimalJPEG))
+	if err != nil {
+		t.Fatalf("new PUT request: %v", err)
+	}
+	req.Header.Set("Content-Type", "image/jpeg")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT /api/v1/areas/1/photo: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var got struct {
+		Photo struct{ ItemsKept bool } `json:"photo"`
+		Items []json.RawMessage        `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Photo.ItemsKept || len(got.Items) != 0 {
+		t.Errorf("replace_empty kept items: %+v", got)
+	}
+}
+
 // TestIntegration_UploadPhoto_NonEmptyImageBytes is a regression test for
 // the bug where a disabled <input type="file"> produced empty FormData,
 // causing the server to receive zero image bytes. It verifies that the bytes
@@ -336,8 +397,8 @@ func TestIntegration_UploadPhoto_NonEmptyImageBytes(t *testing.T) {
 		t.Skip("skipping integration test in short mode")
 	}
 
-	vis := &recordingVision{
-		result: &vision.AnalysisResult{
+	vis := &visiontest.Recording{
+		Result: &vision.AnalysisResult{
 			Items: []vision.DetectedItem{
 				{Name: "Butter", Quantity: "1 pack", Notes: ""},
 			},
@@ -374,8 +435,8 @@ func TestIntegration_AreaDetail_PhotoServedAfterUpload(t *testing.T) {
 		t.Skip("skipping integration test in short mode")
 	}
 
-	vis := &recordingVision{
-		result: &vision.AnalysisResult{
+	vis := &visiontest.Recording{
+		Result: &vision.AnalysisResult{
 			Items: []vision.DetectedItem{
 				{Name: "Apple", Quantity: "3", Notes: ""},
 			},
@@ -424,8 +485,8 @@ func TestIntegration_GetAreaItems(t *testing.T) {
 		t.Skip("skipping integration test in short mode")
 	}
 
-	vis := &recordingVision{
-		result: &vision.AnalysisResult{
+	vis := &visiontest.Recording{
+		Result: &vision.AnalysisResult{
 			Items: []vision.DetectedItem{
 				{Name: "Butter", Quantity: "1 pack", Notes: ""},
 			},
@@ -504,8 +565,8 @@ func TestIntegration_Search(t *testing.T) {
 		t.Skip("skipping integration test in short mode")
 	}
 
-	vis := &recordingVision{
-		result: &vision.AnalysisResult{
+	vis := &visiontest.Recording{
+		Result: &vision.AnalysisResult{
 			Items: []vision.DetectedItem{
 				{Name: "Milk", Quantity: "2 liters", Notes: ""},
 			},
@@ -545,6 +606,152 @@ func TestIntegration_Search(t *testing.T) {
 	}
 }
 
+// TestIntegration_Search_EmptyQueryShowsRecent verifies that GET /search with
+// no query lists the most recently added items under their own heading, on
+// the full page and in the HTMX partial.
+func TestIntegration_Search_EmptyQueryShowsRecent(t *testing.T) {
+	srv, cleanup := newTestServer(t, &failingVision{err: errors.New("unused")})
+	t.Cleanup(cleanup)
+
+	createArea(t, srv, "Fridge")
+	for _, name := range []string{"Milk", "Eggs"} {
+		resp, err := http.Post(srv.URL+"/areas/1/items", "application/json",
+			strings.NewReader(`{"name":"`+name+`","quantity":"1"}`))
+		if err != nil {
+			t.Fatalf("POST /areas/1/items: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+
+	for _, htmx := range []bool{false, true} {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/search", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if htmx {
+			req.Header.Set("HX-Request", "true")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /search: %v", err)
+		}
+		b, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			t.Fatalf("read body: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("htmx=%v: expected 200, got %d: %s", htmx, resp.StatusCode, b)
+		}
+		page := string(b)
+		if !strings.Contains(page, "Recently added") {
+			t.Errorf("htmx=%v: missing recent heading:\n%s", htmx, page)
+		}
+		eggs, milk := strings.Index(page, "Eggs"), strings.Index(page, "Milk")
+		if eggs < 0 || milk < 0 || eggs > milk {
+			t.Errorf("htmx=%v: want Eggs listed before the older Milk:\n%s", htmx, page)
+		}
+	}
+}
+
+// TestIntegration_EscapesModelOutput verifies that item text from the vision
+// model reaches every page and partial that shows items escaped, so a name
+// or quantity carrying markup can't inject script.
+func TestIntegration_EscapesModelOutput(t *testing.T) {
+	vis := &visiontest.Recording{
+		Result: &vision.AnalysisResult{
+			Status: vision.StatusOK,
+			Items: []vision.DetectedItem{
+				{Name: "Milk<script>alert(1)</script>", Quantity: `"><img src=x onerror=alert(1)>`},
+				{Name: `"><img src=x onerror=alert(2)>`, Quantity: "<script>alert(2)</script>"},
+			},
+		},
+	}
+	srv, cleanup := newTestServer(t, vis)
+	t.Cleanup(cleanup)
+
+	createArea(t, srv, "Fridge")
+	body, contentType := buildMultipartBody(t, minimalJPEG)
+	resp, err := http.Post(srv.URL+"/areas/1/photos", contentType, body)
+	if err != nil {
+		t.Fatalf("POST /areas/1/photos: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("upload: expected 200, got %d", resp.StatusCode)
+	}
+
+	pages := []struct {
+		path string
+		htmx bool
+	}{
+		{"/areas", false},
+		{"/areas/1", false},
+		{"/areas/1/card", true},
+		{"/areas/1/items", true},
+		{"/search?q=alert", false},
+		{"/search?q=alert", true},
+		{"/search?q=img&in=name,quantity", true},
+		{"/search", true},
+		{"/recent", false},
+		{"/print", false},
+	}
+	for _, p := range pages {
+		t.Run(fmt.Sprintf("%s htmx=%v", p.path, p.htmx), func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, srv.URL+p.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.htmx {
+				req.Header.Set("HX-Request", "true")
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET %s: %v", p.path, err)
+			}
+			b, err := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if err != nil {
+				t.Fatalf("read body: %v", err)
+			}
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", resp.StatusCode, b)
+			}
+			page := string(b)
+			for _, raw := range []string{"<script>alert", "<img src=x"} {
+				if strings.Contains(page, raw) {
+					t.Errorf("page contains unescaped %q:\n%s", raw, page)
+				}
+			}
+			if !strings.Contains(page, "&lt;script&gt;") && !strings.Contains(page, "&lt;img src=x onerror=") {
+				t.Errorf("page shows neither item escaped:\n%s", page)
+			}
+		})
+	}
+
+	// The row the area card appends after adding an item by hand is the
+	// rendered partial, not markup built from JSON in the browser.
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/areas/1/items",
+		strings.NewReader(`{"name":"<script>alert(3)</script>","quantity":"1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("HX-Request", "true")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /areas/1/items: %v", err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(b), `data-testid="item-row"`) {
+		t.Fatalf("expected an item row, got %d: %s", resp.StatusCode, b)
+	}
+	if strings.Contains(string(b), "<script>alert") || !strings.Contains(string(b), "&lt;script&gt;alert(3)&lt;/script&gt;") {
+		t.Errorf("added row is not escaped:\n%s", b)
+	}
+}
+
 // TestIntegration_RenameArea_DuplicateName verifies that PUT /areas/{id} with a
 // name already used by another area returns 409 with a descriptive message.
 func TestIntegration_GetAreaCard_NoPhoto(t *testing.T) {
@@ -578,15 +785,9 @@ func TestIntegration_GetAreaCard_NoPhoto(t *testing.T) {
 func TestIntegration_GetAreaCard_AnalysingState_ShowsOverlay(t *testing.T) {
 	// A slow vision stub that blocks until released, so we can inspect the
 	// area card while the photo is committed but items not yet written.
-	ready := make(chan struct{})
-	release := make(chan struct{})
-	slowVis := &blockingVision{
-		ready:   ready,
-		release: release,
-		result: &vision.AnalysisResult{
-			Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}},
-		},
-	}
+	slowVis := visiontest.NewBlocking(&vision.AnalysisResult{
+		Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}},
+	})
 
 	srv, cleanup := newTestServer(t, slowVis)
 	t.Cleanup(cleanup)
@@ -605,7 +806,7 @@ func TestIntegration_GetAreaCard_AnalysingState_ShowsOverlay(t *testing.T) {
 	}()
 
 	// Wait until the photo is saved and vision analysis has started.
-	<-ready
+	<-slowVis.Ready
 
 	// At this point the area has a photo but no items — analysing state.
 	resp, err := http.Get(srv.URL + "/areas/1/card")
@@ -624,7 +825,7 @@ func TestIntegration_GetAreaCard_AnalysingState_ShowsOverlay(t *testing.T) {
 	}
 
 	// Release the vision stub and wait for upload to finish.
-	close(release)
+	close(slowVis.Release)
 	<-uploadDone
 
 	// After analysis completes, card should show items, no overlay.
@@ -644,6 +845,94 @@ func TestIntegration_GetAreaCard_AnalysingState_ShowsOverlay(t *testing.T) {
 	}
 }
 
+// TestIntegration_UpdateEncodings verifies that the area and item update
+// endpoints accept JSON, URL-encoded, and multipart bodies alike.
+func TestIntegration_UpdateEncodings(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	multipartBody := func(fields map[string]string) (io.Reader, string) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		for k, v := range fields {
+			_ = mw.WriteField(k, v)
+		}
+		_ = mw.Close()
+		return &buf, mw.FormDataContentType()
+	}
+	encodings := map[string]func(fields map[string]string) (io.Reader, string){
+		"json": func(fields map[string]string) (io.Reader, string) {
+			b, _ := json.Marshal(fields)
+			return bytes.NewReader(b), "application/json"
+		},
+		"urlencoded": func(fields map[string]string) (io.Reader, string) {
+			v := url.Values{}
+			for k, f := range fields {
+				v.Set(k, f)
+			}
+			return strings.NewReader(v.Encode()), "application/x-www-form-urlencoded"
+		},
+		"multipart": multipartBody,
+	}
+
+	for enc, encode := range encodings {
+		t.Run(enc, func(t *testing.T) {
+			srv, cleanup := newTestServer(t, &visiontest.Recording{Result: &vision.AnalysisResult{}})
+			defer cleanup()
+			createArea(t, srv, "Fridge")
+
+			send := func(method, path string, fields map[string]string) []byte {
+				t.Helper()
+				body, ct := encode(fields)
+				req, _ := http.NewRequest(method, srv.URL+path, body)
+				req.Header.Set("Content-Type", ct)
+				req.Header.Set("Accept", "application/json")
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatalf("%s %s: %v", method, path, err)
+				}
+				defer func() { _ = resp.Body.Close() }()
+				b, _ := io.ReadAll(resp.Body)
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("%s %s: expected 200, got %d: %s", method, path, resp.StatusCode, b)
+				}
+				return b
+			}
+
+			var area struct{ Name, Prompt string }
+			_ = json.Unmarshal(send(http.MethodPut, "/areas/1", map[string]string{"name": "Garage Fridge"}), &area)
+			if area.Name != "Garage Fridge" {
+				t.Errorf("rename: got area %+v", area)
+			}
+			_ = json.Unmarshal(send(http.MethodPut, "/areas/1", map[string]string{"prompt": "Count eggs."}), &area)
+			if area.Name != "Garage Fridge" || area.Prompt != "Count eggs." {
+				t.Errorf("set prompt should keep the name: got area %+v", area)
+			}
+
+			var item struct{ Name, Quantity string }
+			_ = json.Unmarshal(send(http.MethodPost, "/areas/1/items", map[string]string{"name": " Butter ", "quantity": "1"}), &item)
+			if item.Name != "Butter" || item.Quantity != "1" {
+				t.Errorf("create item: got %+v", item)
+			}
+			_ = json.Unmarshal(send(http.MethodPut, "/areas/1/items/1", map[string]string{"name": "Salted Butter", "quantity": "2"}), &item)
+			if item.Name != "Salted Butter" || item.Quantity != "2" {
+				t.Errorf("update item: got %+v", item)
+			}
+
+			body, ct := encode(map[string]string{"quantity": "3"})
+			resp, err := http.Post(srv.URL+"/areas/1/items", ct, body)
+			if err != nil {
+				t.Fatalf("POST item without name: %v", err)
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode != http.StatusUnprocessableEntity {
+				t.Errorf("item without name: expected 422, got %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
 func TestIntegration_RenameArea_DuplicateName(t *testing.T) {
 	srv, cleanup := newTestServer(t, &failingVision{err: errors.New("unused")})
 	t.Cleanup(cleanup)
@@ -676,6 +965,53 @@ func TestIntegration_RenameArea_DuplicateName(t *testing.T) {
 	}
 }
 
+// TestIntegration_UpdateArea_ValidatesBeforeWriting verifies that a PUT whose
+// prompt is too long is refused without renaming the area, and that setting
+// the prompt or kind of a missing area is a 404.
+func TestIntegration_UpdateArea_ValidatesBeforeWriting(t *testing.T) {
+	srv, cleanup := newTestServer(t, &failingVision{err: errors.New("unused")})
+	t.Cleanup(cleanup)
+
+	createArea(t, srv, "Fridge")
+
+	put := func(path, body string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPut, srv.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("build PUT request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("PUT %s: %v", path, err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	longPrompt := strings.Repeat("x", 2001) // one past maxAreaPromptLen
+	if code := put("/areas/1", `{"name":"Garage","prompt":"`+longPrompt+`"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a too-long prompt, got %d", code)
+	}
+	resp, err := http.Get(srv.URL + "/areas")
+	if err != nil {
+		t.Fatalf("GET /areas: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, _ := io.ReadAll(resp.Body)
+	if strings.Contains(string(b), "Garage") || !strings.Contains(string(b), "Fridge") {
+		t.Errorf("a refused update must not rename the area: %s", b)
+	}
+
+	if code := put("/areas/99", `{"prompt":"Count eggs."}`); code != http.StatusNotFound {
+		t.Errorf("expected 404 for a missing area, got %d", code)
+	}
+	if code := put("/areas/99", `{"kind":"pantry"}`); code != http.StatusNotFound {
+		t.Errorf("expected 404 setting the kind of a missing area, got %d", code)
+	}
+}
+
 // TestIntegration_ReorderAreas verifies that POST /areas/reorder persists the
 // new sort order and that a subsequent GET /areas returns cards in that order.
 func TestIntegration_ReorderAreas(t *testing.T) {
@@ -683,7 +1019,7 @@ func TestIntegration_ReorderAreas(t *testing.T) {
 		t.Skip("skipping integration test in short mode")
 	}
 
-	vis := &recordingVision{result: &vision.AnalysisResult{}}
+	vis := &visiontest.Recording{Result: &vision.AnalysisResult{}}
 	srv, cleanup := newTestServer(t, vis)
 	defer cleanup()
 
@@ -739,48 +1075,1074 @@ func TestIntegration_ReorderAreas(t *testing.T) {
 	}
 }
 
-// TestIntegration_PhotoTimestamp verifies that after uploading a photo the area
-// card includes a human-readable upload timestamp.
-func TestIntegration_PhotoTimestamp(t *testing.T) {
+// TestIntegration_SetAreaOrder verifies that PUT /areas/order with the IDs as
+// form fields, as a drag-and-drop form posts them, persists the order.
+func TestIntegration_SetAreaOrder(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test in short mode")
 	}
 
-	vis := &recordingVision{result: &vision.AnalysisResult{}}
+	vis := &visiontest.Recording{Result: &vision.AnalysisResult{}}
 	srv, cleanup := newTestServer(t, vis)
 	defer cleanup()
 
-	// Create area.
-	resp, err := http.PostForm(srv.URL+"/areas", url.Values{"name": {"Fridge"}})
-	if err != nil {
-		t.Fatalf("POST /areas: %v", err)
+	for _, name := range []string{"Alpha", "Beta", "Gamma"} {
+		resp, err := http.PostForm(srv.URL+"/areas", url.Values{"name": {name}})
+		if err != nil {
+			t.Fatalf("POST /areas: %v", err)
+		}
+		_ = resp.Body.Close()
 	}
-	_ = resp.Body.Close()
 
-	// Upload a photo.
-	body, ct := buildMultipartBody(t, minimalJPEG)
-	resp2, err := http.Post(srv.URL+"/areas/1/photos", ct, body)
+	req, err := http.NewRequest(http.MethodPut, srv.URL+"/areas/order", strings.NewReader("id=2&id=3&id=1"))
 	if err != nil {
-		t.Fatalf("POST photo: %v", err)
+		t.Fatalf("new request: %v", err)
 	}
-	t.Cleanup(func() { _ = resp2.Body.Close() })
-	if resp2.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp2.Body)
-		t.Fatalf("expected 200, got %d: %s", resp2.StatusCode, b)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT /areas/order: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
 	}
 
-	// GET /areas and verify a timestamp is present.
-	resp3, err := http.Get(srv.URL + "/areas")
+	resp2, err := http.Get(srv.URL + "/areas")
 	if err != nil {
 		t.Fatalf("GET /areas: %v", err)
 	}
-	t.Cleanup(func() { _ = resp3.Body.Close() })
-	html, err := io.ReadAll(resp3.Body)
+	t.Cleanup(func() { _ = resp2.Body.Close() })
+	html, err := io.ReadAll(resp2.Body)
 	if err != nil {
 		t.Fatalf("read body: %v", err)
 	}
+	page := string(html)
 
-	if !strings.Contains(string(html), "photo-timestamp") {
-		t.Errorf("expected photo-timestamp element in HTML, got:\n%s", html)
+	betaPos := strings.Index(page, "Beta")
+	gammaPos := strings.Index(page, "Gamma")
+	alphaPos := strings.Index(page, "Alpha")
+	if betaPos < 0 || gammaPos < 0 || alphaPos < 0 {
+		t.Fatalf("expected all area names in HTML, got:\n%s", page)
+	}
+	if betaPos > gammaPos || gammaPos > alphaPos {
+		t.Errorf("expected order Beta < Gamma < Alpha in HTML, got positions: Beta=%d Gamma=%d Alpha=%d",
+			betaPos, gammaPos, alphaPos)
+	}
+}
+
+// TestIntegration_AreaLastModified verifies that the area detail page's
+// Last-Modified header advances when an item is added to the area.
+func TestIntegration_AreaLastModified(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	database, err := db.OpenForTesting()
+	if err != nil {
+		t.Fatalf("OpenForTesting: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	photos := memory.New()
+	svc := service.NewAreaService(
+		store.NewAreaStore(database),
+		store.NewPhotoStore(database),
+		store.NewItemStore(database),
+		store.NewItemEditStore(database),
+		store.NewSnapshotStore(database),
+		store.NewOverrideStore(database),
+		&visiontest.Recording{Result: &vision.AnalysisResult{}},
+		photos,
+		slog.Default(),
+	).WithDB(database)
+	srv := httptest.NewServer(web.NewServer(svc, templates.FS, photos, slog.Default()))
+	t.Cleanup(srv.Close)
+
+	createArea(t, srv, "Fridge")
+	lastModified := func() time.Time {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/areas/1")
+		if err != nil {
+			t.Fatalf("GET /areas/1: %v", err)
+		}
+		_ = resp.Body.Close()
+		lm, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+		if err != nil {
+			t.Fatalf("parse Last-Modified %q: %v", resp.Header.Get("Last-Modified"), err)
+		}
+		return lm
+	}
+
+	// Last-Modified has second precision; backdate the area rather than
+	// waiting for the clock to tick.
+	if _, err := database.Exec(`UPDATE areas SET updated_at = ?`, time.Now().Add(-time.Hour).UTC()); err != nil {
+		t.Fatalf("backdate area: %v", err)
+	}
+	before := lastModified()
+	resp, err := http.PostForm(srv.URL+"/areas/1/items", url.Values{"name": {"Milk"}})
+	if err != nil {
+		t.Fatalf("POST item: %v", err)
+	}
+	_ = resp.Body.Close()
+	if after := lastModified(); !after.After(before) {
+		t.Errorf("expected Last-Modified to advance past %v after adding an item, got %v", before, after)
+	}
+}
+
+// TestIntegration_PhotoTimestamp verifies that after uploading a photo the area
+// card includes a human-readable upload timestamp.
+func TestIntegration_PhotoTimestamp(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	vis := &visiontest.Recording{Result: &vision.AnalysisResult{}}
+	srv, cleanup := newTestServer(t, vis)
+	defer cleanup()
+
+	// Create area.
+	resp, err := http.PostForm(srv.URL+"/areas", url.Values{"name": {"Fridge"}})
+	if err != nil {
+		t.Fatalf("POST /areas: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	// Upload a photo.
+	body, ct := buildMultipartBody(t, minimalJPEG)
+	resp2, err := http.Post(srv.URL+"/areas/1/photos", ct, body)
+	if err != nil {
+		t.Fatalf("POST photo: %v", err)
+	}
+	t.Cleanup(func() { _ = resp2.Body.Close() })
+	if resp2.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp2.Body)
+		t.Fatalf("expected 200, got %d: %s", resp2.StatusCode, b)
+	}
+
+	// GET /areas and verify a timestamp is present.
+	resp3, err := http.Get(srv.URL + "/areas")
+	if err != nil {
+		t.Fatalf("GET /areas: %v", err)
+	}
+	t.Cleanup(func() { _ = resp3.Body.Close() })
+	html, err := io.ReadAll(resp3.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+
+	if !strings.Contains(string(html), "photo-timestamp") {
+		t.Errorf("expected photo-timestamp element in HTML, got:\n%s", html)
+	}
+}
+
+// TestIntegration_ListAreas_SortByAttention verifies that ?sort=attention puts
+// areas that need a photo ahead of freshly photographed, stocked ones.
+func TestIntegration_ListAreas_SortByAttention(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	vis := &visiontest.Recording{Result: &vision.AnalysisResult{
+		Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}},
+	}}
+	srv, cleanup := newTestServer(t, vis)
+	defer cleanup()
+
+	// Alpha(1) gets a fresh photo with items; Beta(2) is never photographed.
+	for _, name := range []string{"Alpha", "Beta"} {
+		resp, err := http.PostForm(srv.URL+"/areas", url.Values{"name": {name}})
+		if err != nil {
+			t.Fatalf("POST /areas: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+	body, ct := buildMultipartBody(t, minimalJPEG)
+	resp, err := http.Post(srv.URL+"/areas/1/photos", ct, body)
+	if err != nil {
+		t.Fatalf("POST photo: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST photo status %d", resp.StatusCode)
+	}
+
+	resp2, err := http.Get(srv.URL + "/areas?sort=attention")
+	if err != nil {
+		t.Fatalf("GET /areas?sort=attention: %v", err)
+	}
+	t.Cleanup(func() { _ = resp2.Body.Close() })
+	html, err := io.ReadAll(resp2.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	page := string(html)
+
+	alphaPos := strings.Index(page, "Alpha")
+	betaPos := strings.Index(page, "Beta")
+	if alphaPos < 0 || betaPos < 0 {
+		t.Fatalf("expected both area names in HTML, got:\n%s", page)
+	}
+	if betaPos > alphaPos {
+		t.Errorf("expected Beta before Alpha, got positions: Alpha=%d Beta=%d", alphaPos, betaPos)
+	}
+	if !strings.Contains(page, `data-testid="attention-badge"`) {
+		t.Errorf("expected attention badge in HTML")
+	}
+}
+
+// TestIntegration_UploadPhoto_EchoesCorrelationID verifies that the optional
+// client_correlation_id form field is echoed back on the upload response.
+func TestIntegration_UploadPhoto_EchoesCorrelationID(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	vis := &visiontest.Recording{Result: &vision.AnalysisResult{}}
+	srv, cleanup := newTestServer(t, vis)
+	defer cleanup()
+
+	resp, err := http.PostForm(srv.URL+"/areas", url.Values{"name": {"Fridge"}})
+	if err != nil {
+		t.Fatalf("POST /areas: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	if err := mw.WriteField("client_correlation_id", "tab-1234"); err != nil {
+		t.Fatalf("write field: %v", err)
+	}
+	fw, err := mw.CreateFormFile("image", "photo.jpg")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	if _, err := fw.Write(minimalJPEG); err != nil {
+		t.Fatalf("write image data: %v", err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("close multipart writer: %v", err)
+	}
+
+	resp2, err := http.Post(srv.URL+"/areas/1/photos", mw.FormDataContentType(), body)
+	if err != nil {
+		t.Fatalf("POST photo: %v", err)
+	}
+	t.Cleanup(func() { _ = resp2.Body.Close() })
+	if resp2.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp2.Body)
+		t.Fatalf("expected 200, got %d: %s", resp2.StatusCode, b)
+	}
+	if got := resp2.Header.Get("X-Client-Correlation-ID"); got != "tab-1234" {
+		t.Errorf("expected correlation ID to round-trip, got %q", got)
+	}
+}
+
+// TestIntegration_DeleteArea_Undo verifies that a deleted area disappears from
+// the list, that the DELETE response advertises a restore URL, and that
+// restoring brings the area back.
+func TestIntegration_DeleteArea_Undo(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	vis := &visiontest.Recording{Result: &vision.AnalysisResult{}}
+	srv, cleanup := newTestServer(t, vis)
+	defer cleanup()
+
+	createArea(t, srv, "Garage")
+
+	req, err := http.NewRequest(http.MethodDelete, srv.URL+"/areas/1", nil)
+	if err != nil {
+		t.Fatalf("new DELETE request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE /areas/1: %v", err)
+	}
+	_ = resp.Body.Close()
+	if trig := resp.Header.Get("HX-Trigger"); !strings.Contains(trig, `"restoreUrl":"/areas/1/restore"`) {
+		t.Errorf("expected HX-Trigger with restore URL, got %q", trig)
+	}
+
+	listContains := func() bool {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/areas")
+		if err != nil {
+			t.Fatalf("GET /areas: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		return strings.Contains(string(body), "Garage")
+	}
+	if listContains() {
+		t.Fatal("deleted area should not be listed")
+	}
+
+	resp2, err := http.Post(srv.URL+"/areas/1/restore", "", nil)
+	if err != nil {
+		t.Fatalf("POST restore: %v", err)
+	}
+	t.Cleanup(func() { _ = resp2.Body.Close() })
+	if resp2.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp2.Body)
+		t.Fatalf("expected 200, got %d: %s", resp2.StatusCode, b)
+	}
+	if !listContains() {
+		t.Error("restored area should be listed again")
+	}
+}
+
+func TestIntegration_APIUploadPhoto(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	vis := &visiontest.Recording{
+		Result: &vision.AnalysisResult{
+			Items: []vision.DetectedItem{{Name: "Orange Juice", Quantity: "1"}},
+		},
+	}
+	srv, cleanup := newTestServer(t, vis)
+	defer cleanup()
+
+	createArea(t, srv, "Fridge")
+
+	put := func(body io.Reader, contentType string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPut, srv.URL+"/api/v1/areas/1/photo", body)
+		if err != nil {
+			t.Fatalf("new PUT request: %v", err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("PUT /api/v1/areas/1/photo: %v", err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	resp := put(bytes.NewReader(minimalJPEG), "image/jpeg")
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, b)
+	}
+	var got struct {
+		Photo struct{ ID, AreaID int64 } `json:"photo"`
+		Items []struct{ Name string }   `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Photo.ID == 0 || got.Photo.AreaID != 1 {
+		t.Errorf("unexpected photo in response: %+v", got.Photo)
+	}
+	if len(got.Items) != 1 || got.Items[0].Name != "Orange Juice" {
+		t.Errorf("unexpected items in response: %+v", got.Items)
+	}
+	if !bytes.Equal(vis.LastBytes(), minimalJPEG) {
+		t.Error("vision analyzer did not receive the raw request body")
+	}
+
+	// One byte over the 50 MB cap must be refused without analysing anything.
+	oversized := io.MultiReader(bytes.NewReader(minimalJPEG), io.LimitReader(zeroReader{}, 50<<20))
+	resp = put(oversized, "image/jpeg")
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for oversized body, got %d", resp.StatusCode)
+	}
+}
+
+// zeroReader yields an endless stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// TestIntegration_LatestRawResponse verifies the debug endpoint returns the
+// stored vision reply and stays hidden unless debug endpoints are enabled.
+func TestIntegration_LatestRawResponse(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	database, err := db.OpenForTesting()
+	if err != nil {
+		t.Fatalf("OpenForTesting: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	vis := &visiontest.Recording{Result: &vision.AnalysisResult{RawResponse: `{"status":"no_items","items":[]}`}}
+	svc := service.NewAreaService(
+		store.NewAreaStore(database),
+		store.NewPhotoStore(database),
+		store.NewItemStore(database),
+		store.NewItemEditStore(database),
+		store.NewSnapshotStore(database),
+		store.NewOverrideStore(database),
+		vis,
+		memory.New(),
+		slog.Default(),
+	).WithDB(database)
+	server := web.NewServer(svc, templates.FS, memory.New(), slog.Default())
+	srv := httptest.NewServer(server)
+	t.Cleanup(srv.Close)
+
+	get := func() (int, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/areas/1/photos/latest/raw")
+		if err != nil {
+			t.Fatalf("GET raw response: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+
+	createArea(t, srv, "Fridge")
+	if code, _ := get(); code != http.StatusNotFound {
+		t.Fatalf("expected 404 with debug endpoints off, got %d", code)
+	}
+
+	server.WithDebugEndpoints()
+	if code, body := get(); code != http.StatusNotFound || !strings.Contains(body, "no photo") {
+		t.Fatalf("expected 404 'no photo' before any upload, got %d: %s", code, body)
+	}
+
+	body, ct := buildMultipartBody(t, minimalJPEG)
+	resp, err := http.Post(srv.URL+"/areas/1/photos", ct, body)
+	if err != nil {
+		t.Fatalf("POST photo: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	code, raw := get()
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", code, raw)
+	}
+	if raw != `{"status":"no_items","items":[]}` {
+		t.Errorf("unexpected raw response: %q", raw)
+	}
+}
+
+// failingCheckVision is a visiontest.Recording whose pre-flight check fails.
+type failingCheckVision struct {
+	visiontest.Recording
+}
+
+func (f *failingCheckVision) Check(context.Context) error {
+	return errors.New(`model "llava" is not installed`)
+}
+
+func TestIntegration_VisionCheckFailed(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	database, err := db.OpenForTesting()
+	if err != nil {
+		t.Fatalf("OpenForTesting: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	svc := service.NewAreaService(
+		store.NewAreaStore(database),
+		store.NewPhotoStore(database),
+		store.NewItemStore(database),
+		store.NewItemEditStore(database),
+		store.NewSnapshotStore(database),
+		store.NewOverrideStore(database),
+		&failingCheckVision{},
+		memory.New(),
+		slog.Default(),
+	).WithDB(database)
+	srv := httptest.NewServer(web.NewServer(svc, templates.FS, memory.New(), slog.Default()))
+	t.Cleanup(srv.Close)
+
+	get := func(path string) string {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", path, resp.StatusCode)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+
+	createArea(t, srv, "Fridge")
+	if body := get("/areas/1"); strings.Contains(body, `data-testid="vision-warning"`) {
+		t.Fatal("warning shown before the check ran")
+	}
+
+	if err := svc.CheckVision(context.Background()); err == nil {
+		t.Fatal("expected CheckVision to fail")
+	}
+	if body := get("/areas/1"); !strings.Contains(body, `data-testid="vision-warning"`) || !strings.Contains(body, "is not installed") {
+		t.Errorf("expected vision warning on area page, got:\n%s", body)
+	}
+	if body := get("/readyz"); !strings.Contains(body, `"status":"degraded"`) {
+		t.Errorf("expected degraded readyz, got %s", body)
+	}
+}
+
+func TestIntegration_AnalysisUsage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	vis := &visiontest.Recording{Result: &vision.AnalysisResult{
+		Items: []vision.DetectedItem{{Name: "Milk", Quantity: "1"}},
+		Usage: vision.Usage{InputTokens: 2000, OutputTokens: 400, Duration: 1500 * time.Millisecond},
+	}}
+	srv, cleanup := newTestServer(t, vis)
+	defer cleanup()
+
+	createArea(t, srv, "Fridge")
+	body, ct := buildMultipartBody(t, minimalJPEG)
+	resp, err := http.Post(srv.URL+"/areas/1/photos", ct, body)
+	if err != nil {
+		t.Fatalf("POST photo: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	for path, want := range map[string]string{
+		"/areas/1": "Analysis used 2400 tokens in 1.5s",
+		"/stats":   `"Analyses":1,"InputTokens":2000,"OutputTokens":400,"EvalDurationMs":1500`,
+	} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if !strings.Contains(string(b), want) {
+			t.Errorf("GET %s: expected %q in body:\n%s", path, want, b)
+		}
+	}
+}
+
+func TestIntegration_StaleAreas(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	database, err := db.OpenForTesting()
+	if err != nil {
+		t.Fatalf("OpenForTesting: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	photos := memory.New()
+	svc := service.NewAreaService(
+		store.NewAreaStore(database),
+		store.NewPhotoStore(database),
+		store.NewItemStore(database),
+		store.NewItemEditStore(database),
+		store.NewSnapshotStore(database),
+		store.NewOverrideStore(database),
+		&visiontest.Recording{Result: &vision.AnalysisResult{}},
+		photos,
+		slog.Default(),
+	).WithDB(database).WithStaleAfter(14 * 24 * time.Hour)
+	srv := httptest.NewServer(web.NewServer(svc, templates.FS, photos, slog.Default()))
+	t.Cleanup(srv.Close)
+
+	createArea(t, srv, "Fridge")
+	body, ct := buildMultipartBody(t, minimalJPEG)
+	resp, err := http.Post(srv.URL+"/areas/1/photos", ct, body)
+	if err != nil {
+		t.Fatalf("POST photo: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	getStale := func() []map[string]any {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/areas/stale")
+		if err != nil {
+			t.Fatalf("GET /areas/stale: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		var got []map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatalf("decode stale areas: %v", err)
+		}
+		return got
+	}
+	if got := getStale(); len(got) != 0 {
+		t.Fatalf("fresh photo should not be stale, got %v", got)
+	}
+
+	if _, err := database.Exec(`UPDATE photos SET uploaded_at = ?`, time.Now().Add(-21*24*time.Hour).UTC()); err != nil {
+		t.Fatalf("backdate photo: %v", err)
+	}
+	got := getStale()
+	if len(got) != 1 || got[0]["name"] != "Fridge" || got[0]["days_since_photo"] != float64(21) {
+		t.Fatalf("expected Fridge stale for 21 days, got %v", got)
+	}
+
+	for _, path := range []string{"/areas", "/areas/1"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		page, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if !strings.Contains(string(page), `data-testid="stale-badge"`) || !strings.Contains(string(page), "Last photographed 3 weeks ago") {
+			t.Errorf("GET %s should show the stale badge", path)
+		}
+	}
+}
+
+func TestIntegration_StaticAssets(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	srv, cleanup := newTestServer(t, &visiontest.Recording{Result: &vision.AnalysisResult{}})
+	defer cleanup()
+
+	resp, err := http.Get(srv.URL + "/areas")
+	if err != nil {
+		t.Fatalf("GET /areas: %v", err)
+	}
+	page, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if csp := resp.Header.Get("Content-Security-Policy"); strings.Contains(csp, "unpkg.com") {
+		t.Errorf("CSP should not allow unpkg.com, got %q", csp)
+	}
+	if strings.Contains(string(page), "unpkg.com") {
+		t.Error("page should not load anything from unpkg.com")
+	}
+
+	for _, tc := range []struct{ pattern, contentType string }{
+		{`href="(/static/app\.[0-9a-f]+\.css)"`, "text/css"},
+		{`src="(/static/htmx\.min\.[0-9a-f]+\.js)"`, "text/javascript"},
+	} {
+		m := regexp.MustCompile(tc.pattern).FindStringSubmatch(string(page))
+		if m == nil {
+			t.Fatalf("page should link an asset matching %s", tc.pattern)
+		}
+		resp, err := http.Get(srv.URL + m[1])
+		if err != nil {
+			t.Fatalf("GET %s: %v", m[1], err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", m[1], resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, tc.contentType) {
+			t.Errorf("GET %s: expected Content-Type %s, got %q", m[1], tc.contentType, ct)
+		}
+		if cc := resp.Header.Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+			t.Errorf("GET %s: hashed asset should be cacheable for good, got %q", m[1], cc)
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+m[1], nil)
+		req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("conditional GET %s: %v", m[1], err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusNotModified {
+			t.Errorf("conditional GET %s: expected 304, got %d", m[1], resp.StatusCode)
+		}
+	}
+
+	// Only hashed names are served, so a stale link can't get a new file.
+	for _, p := range []string{"/static/app.css", "/static/app.0000000000.css", "/static/embed.go"} {
+		resp, err := http.Get(srv.URL + p)
+		if err != nil {
+			t.Fatalf("GET %s: %v", p, err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s: expected 404, got %d", p, resp.StatusCode)
+		}
+	}
+}
+
+// conditionalGet fetches path with If-None-Match set to etag, if any, and
+// returns the status and ETag of the response.
+func conditionalGet(t *testing.T, srv *httptest.Server, path, etag string, header http.Header) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode, resp.Header.Get("ETag")
+}
+
+func TestIntegration_AreaItemsNotModified(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	srv, cleanup := newTestServer(t, &visiontest.Recording{Result: &vision.AnalysisResult{}})
+	defer cleanup()
+	createArea(t, srv, "Fridge")
+	createArea(t, srv, "Pantry")
+	addItem := func(areaID int, name string) int64 {
+		resp, err := http.Post(fmt.Sprintf("%s/areas/%d/items", srv.URL, areaID), "application/json",
+			strings.NewReader(`{"name":"`+name+`","quantity":"1"}`))
+		if err != nil {
+			t.Fatalf("POST item: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		var item domain.Item
+		if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+			t.Fatalf("decode item: %v", err)
+		}
+		return item.ID
+	}
+	milk := addItem(1, "Milk")
+
+	htmx := http.Header{"Hx-Request": {"true"}}
+	status, etag := conditionalGet(t, srv, "/areas/1/items", "", htmx)
+	if status != http.StatusOK || etag == "" {
+		t.Fatalf("first fetch: expected 200 with an ETag, got %d %q", status, etag)
+	}
+	if status, _ := conditionalGet(t, srv, "/areas/1/items", etag, htmx); status != http.StatusNotModified {
+		t.Errorf("unchanged area: expected 304, got %d", status)
+	}
+	if status, _ := conditionalGet(t, srv, "/areas/1/items", etag, http.Header{"Accept": {"application/json"}}); status != http.StatusOK {
+		t.Errorf("JSON is another rendering: expected 200, got %d", status)
+	}
+	if status, _ := conditionalGet(t, srv, "/areas/1/items?sort=created_at", etag, htmx); status != http.StatusOK {
+		t.Errorf("another sort is another rendering: expected 200, got %d", status)
+	}
+
+	addItem(2, "Rice")
+	if status, _ := conditionalGet(t, srv, "/areas/1/items", etag, htmx); status != http.StatusNotModified {
+		t.Errorf("another area changed: expected 304, got %d", status)
+	}
+
+	// Each change, even within the same second, gives a new version.
+	for _, change := range []struct {
+		name string
+		do   func()
+	}{
+		{"item added", func() { addItem(1, "Eggs") }},
+		{"item edited", func() {
+			req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/areas/1/items/%d", srv.URL, milk),
+				strings.NewReader(`{"name":"Milk","quantity":"2"}`))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("PUT item: %v", err)
+			}
+			_ = resp.Body.Close()
+		}},
+		{"item used up", func() {
+			resp, err := http.Post(fmt.Sprintf("%s/areas/1/items/%d/consume", srv.URL, milk), "", nil)
+			if err != nil {
+				t.Fatalf("consume item: %v", err)
+			}
+			_ = resp.Body.Close()
+		}},
+	} {
+		status, next := conditionalGet(t, srv, "/areas/1/items", etag, htmx)
+		if status != http.StatusNotModified {
+			t.Fatalf("before %s: expected 304, got %d", change.name, status)
+		}
+		change.do()
+		status, next = conditionalGet(t, srv, "/areas/1/items", etag, htmx)
+		if status != http.StatusOK || next == etag {
+			t.Errorf("%s: expected 200 with a new ETag, got %d %q", change.name, status, next)
+		}
+		etag = next
+	}
+}
+
+func TestIntegration_AreaListNotModified(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	srv, cleanup := newTestServer(t, &visiontest.Recording{Result: &vision.AnalysisResult{}})
+	defer cleanup()
+	createArea(t, srv, "Fridge")
+	get := func(etag string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/areas", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /areas: %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	first := get("")
+	etag, policy := first.Header.Get("ETag"), first.Header.Get("Content-Security-Policy")
+	if first.StatusCode != http.StatusOK || etag == "" || !strings.Contains(policy, "'nonce-") {
+		t.Fatalf("first fetch: expected 200 with an ETag and a nonce policy, got %d %q %q", first.StatusCode, etag, policy)
+	}
+	// The version moves on every minute; retry once if one just passed.
+	again := get(etag)
+	if again.StatusCode != http.StatusNotModified {
+		first = get("")
+		etag, policy = first.Header.Get("ETag"), first.Header.Get("Content-Security-Policy")
+		again = get(etag)
+	}
+	if again.StatusCode != http.StatusNotModified {
+		t.Fatalf("unchanged areas: expected 304, got %d", again.StatusCode)
+	}
+	if got := again.Header.Get("Content-Security-Policy"); got != policy {
+		t.Errorf("a 304 must keep the cached page's nonce in its policy:\n got %q\nwant %q", got, policy)
+	}
+
+	// A tag naming another nonce isn't one this server made.
+	forged := etag[:strings.LastIndex(etag, ".")+1] + "forged\""
+	if resp := get(forged); resp.StatusCode != http.StatusOK {
+		t.Errorf("forged tag: expected 200, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/areas/1", strings.NewReader(`{"name":"Big fridge"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("rename area: %v", err)
+	}
+	_ = resp.Body.Close()
+	renamed := get(etag)
+	if renamed.StatusCode != http.StatusOK {
+		t.Errorf("area renamed: expected 200, got %d", renamed.StatusCode)
+	}
+	createArea(t, srv, "Pantry")
+	if resp := get(renamed.Header.Get("ETag")); resp.StatusCode != http.StatusOK {
+		t.Errorf("area added: expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestIntegration_PhotoCaching(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	vis := &visiontest.Recording{Result: &vision.AnalysisResult{}}
+	srv, cleanup := newTestServer(t, vis)
+	defer cleanup()
+
+	createArea(t, srv, "Fridge")
+	body, ct := buildMultipartBody(t, minimalJPEG)
+	resp, err := http.Post(srv.URL+"/areas/1/photos", ct, body)
+	if err != nil {
+		t.Fatalf("POST photo: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	get := func(path string, header http.Header) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	// The detail page links the photo with its version.
+	page, _ := io.ReadAll(get("/areas/1", nil).Body)
+	if !strings.Contains(string(page), `src="/areas/1/photo?v=1"`) {
+		t.Fatalf("area detail should link the versioned photo URL")
+	}
+
+	resp = get("/areas/1/photo?v=1", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if b, _ := io.ReadAll(resp.Body); !bytes.Equal(b, minimalJPEG) {
+		t.Error("photo body mismatch")
+	}
+	etag, lastMod := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" || lastMod == "" {
+		t.Fatalf("expected ETag and Last-Modified, got %q and %q", etag, lastMod)
+	}
+	if cc := resp.Header.Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+		t.Errorf("versioned URL should be cacheable for good, got Cache-Control %q", cc)
+	}
+	if cc := get("/areas/1/photo", nil).Header.Get("Cache-Control"); !strings.Contains(cc, "no-cache") {
+		t.Errorf("unversioned URL should be revalidated, got Cache-Control %q", cc)
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"matching etag", http.Header{"If-None-Match": {etag}}, http.StatusNotModified},
+		{"etag in list", http.Header{"If-None-Match": {`"other", ` + etag}}, http.StatusNotModified},
+		{"stale etag", http.Header{"If-None-Match": {`"other"`}}, http.StatusOK},
+		{"not modified since", http.Header{"If-Modified-Since": {lastMod}}, http.StatusNotModified},
+		{"modified since", http.Header{"If-Modified-Since": {"Mon, 01 Jan 2001 00:00:00 GMT"}}, http.StatusOK},
+		{"etag wins over date", http.Header{"If-None-Match": {`"other"`}, "If-Modified-Since": {lastMod}}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get("/areas/1/photo?v=1", tt.header)
+			if resp.StatusCode != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, resp.StatusCode)
+			}
+			if tt.want == http.StatusNotModified {
+				if b, _ := io.ReadAll(resp.Body); len(b) != 0 {
+					t.Errorf("304 should have no body, got %d bytes", len(b))
+				}
+			}
+		})
+	}
+}
+
+// plainPhotoStore hides the seeking of the store it wraps.
+type plainPhotoStore struct {
+	photostore.PhotoStore
+}
+
+func TestIntegration_PhotoRange(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	tests := []struct {
+		name     string
+		seekable bool
+		want     int
+		wantLen  int
+	}{
+		{"seekable store serves the range", true, http.StatusPartialContent, 100},
+		{"plain store sends the whole photo", false, http.StatusOK, len(minimalJPEG)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database, err := db.OpenForTesting()
+			if err != nil {
+				t.Fatalf("OpenForTesting: %v", err)
+			}
+			t.Cleanup(func() { _ = database.Close() })
+
+			var photos photostore.PhotoStore = memory.New()
+			if !tt.seekable {
+				photos = plainPhotoStore{photos}
+			}
+			svc := service.NewAreaService(
+				store.NewAreaStore(database),
+				store.NewPhotoStore(database),
+				store.NewItemStore(database),
+				store.NewItemEditStore(database),
+				store.NewSnapshotStore(database),
+				store.NewOverrideStore(database),
+				&visiontest.Recording{Result: &vision.AnalysisResult{}},
+				photos,
+				slog.Default(),
+			).WithDB(database)
+			srv := httptest.NewServer(web.NewServer(svc, templates.FS, photos, slog.Default()))
+			t.Cleanup(srv.Close)
+
+			createArea(t, srv, "Fridge")
+			body, ct := buildMultipartBody(t, minimalJPEG)
+			resp, err := http.Post(srv.URL+"/areas/1/photos", ct, body)
+			if err != nil {
+				t.Fatalf("POST photo: %v", err)
+			}
+			_ = resp.Body.Close()
+
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/areas/1/photo?v=1", nil)
+			req.Header.Set("Range", "bytes=0-99")
+			resp, err = http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET photo: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			got, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, resp.StatusCode)
+			}
+			if len(got) != tt.wantLen || !bytes.Equal(got, minimalJPEG[:tt.wantLen]) {
+				t.Errorf("expected the first %d bytes of the photo, got %d bytes", tt.wantLen, len(got))
+			}
+			if tt.seekable {
+				if cr := resp.Header.Get("Content-Range"); cr != fmt.Sprintf("bytes 0-99/%d", len(minimalJPEG)) {
+					t.Errorf("unexpected Content-Range %q", cr)
+				}
+				if ct := resp.Header.Get("Content-Type"); ct != "image/jpeg" {
+					t.Errorf("unexpected Content-Type %q", ct)
+				}
+			}
+		})
+	}
+}
+
+func TestIntegration_WebhookTestEvent(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	vis := &visiontest.Recording{Result: &vision.AnalysisResult{}}
+	srv, cleanup := newTestServer(t, vis)
+	defer cleanup()
+
+	events := make(chan string, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events <- r.Header.Get("X-Kitchinv-Event")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	body := `{"url":"` + receiver.URL + `","events":["analysis.completed"]}`
+	resp, err := http.Post(srv.URL+"/webhooks", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /webhooks: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(srv.URL+"/webhooks/1/test", "", nil)
+	if err != nil {
+		t.Fatalf("POST /webhooks/1/test: %v", err)
+	}
+	var delivery domain.WebhookDelivery
+	err = json.NewDecoder(resp.Body).Decode(&delivery)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatalf("decode delivery: %v", err)
+	}
+	if delivery.StatusCode != http.StatusNoContent || delivery.Error != "" {
+		t.Errorf("expected a successful delivery, got %+v", delivery)
+	}
+	if got := <-events; got != "webhook.test" {
+		t.Errorf("expected webhook.test event header, got %q", got)
+	}
+
+	resp, err = http.Get(srv.URL + "/webhooks/1/deliveries")
+	if err != nil {
+		t.Fatalf("GET deliveries: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var deliveries []domain.WebhookDelivery
+	if err := json.NewDecoder(resp.Body).Decode(&deliveries); err != nil {
+		t.Fatalf("decode deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].ID != delivery.ID {
+		t.Errorf("expected the test delivery to be logged, got %+v", deliveries)
 	}
 }
@@ -0,0 +1,166 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unixPrefix marks a LISTEN_ADDR that is a Unix socket path rather than a
+// TCP address.
+const unixPrefix = "unix:"
+
+// shutdownTimeout is how long Serve waits for in-flight requests once its
+// context is done.
+const shutdownTimeout = 10 * time.Second
+
+// Listen opens the listener the server is reached on. A socket passed by
+// systemd socket activation (LISTEN_FDS) is used whatever addr says.
+// Otherwise addr is a TCP address such as ":8080", or "unix:" followed by
+// the path of a Unix socket to create with the given permissions. A stale
+// socket left at the path by a crash is replaced; any other file there is
+// an error. The socket file is removed when the listener is closed.
+func Listen(addr string, socketMode fs.FileMode) (net.Listener, error) {
+	if ln, err := systemdListener(); ln != nil || err != nil {
+		return ln, err
+	}
+	path, ok := strings.CutPrefix(addr, unixPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		// Nothing can be listening on it unless another instance is running,
+		// which net.Listen can't tell; a dial can.
+		if conn, err := net.Dial("unix", path); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("%s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, socketMode); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return ln, nil
+}
+
+// systemdListener returns the first socket systemd passed, or nil if the
+// process wasn't socket-activated. The LISTEN_* variables are cleared so
+// child processes don't take the socket for their own.
+func systemdListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+	for _, k := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+		_ = os.Unsetenv(k)
+	}
+	// Passed descriptors start at 3, after stdin, stdout and stderr.
+	f := os.NewFile(3, "systemd-socket")
+	defer func() { _ = f.Close() }() // FileListener dups the descriptor
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use socket from systemd: %w", err)
+	}
+	return ln, nil
+}
+
+// IsLoopback reports whether addr, the address of an open listener, only
+// accepts connections from this host: a Unix socket, or TCP bound to a
+// loopback IP. It is checked after Listen because a socket passed by systemd
+// need not match LISTEN_ADDR.
+func IsLoopback(addr net.Addr) bool {
+	switch a := addr.(type) {
+	case *net.UnixAddr:
+		return true
+	case *net.TCPAddr:
+		return a.IP.IsLoopback()
+	}
+	return false
+}
+
+// Serve serves requests on ln until ctx is done, then stops accepting
+// connections and waits up to shutdownTimeout for requests in flight. ln is
+// closed either way, which removes a Unix socket's file. With WithTLS the
+// connections are served over HTTPS, and WithHTTPRedirect adds a plain-HTTP
+// listener that redirects to it.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:      s,
+		ReadTimeout:  60 * time.Second,
+		WriteTimeout: 120 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+	if s.tlsCertFile != "" {
+		cfg, err := s.tlsConfig()
+		if err != nil {
+			_ = ln.Close()
+			return err
+		}
+		srv.TLSConfig = cfg
+	}
+	redirect, err := s.redirectServer(ln.Addr())
+	if err != nil {
+		_ = ln.Close()
+		return err
+	}
+	if redirect != nil {
+		defer func() { _ = redirect.Close() }()
+	}
+
+	s.logger.Info("starting server", "addr", ln.Addr().String(), "network", ln.Addr().Network(), "tls", srv.TLSConfig != nil)
+	errc := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil {
+			errc <- srv.ServeTLS(ln, "", "")
+			return
+		}
+		errc <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	s.logger.Info("shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ListenAndServe listens on addr as Listen does, with owner and group
+// access to a Unix socket, and serves until the listener fails.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := Listen(addr, 0o660)
+	if err != nil {
+		return err
+	}
+	return s.Serve(context.Background(), ln)
+}
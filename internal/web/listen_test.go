@@ -0,0 +1,93 @@
+package web
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/web/templates"
+)
+
+func TestServeUnixSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "kitchinv.sock")
+	ln, err := Listen("unix:"+sock, 0o600)
+	require.NoError(t, err)
+	info, err := os.Stat(sock)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	srv := NewServer(&fakeOverrideService{}, templates.FS, nil, slog.Default())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		},
+	}}
+	resp, err := client.Get("http://kitchinv/readyz")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+
+	_, err = Listen("unix:"+sock, 0o600)
+	assert.ErrorContains(t, err, "in use", "a second instance can't take over a live socket")
+
+	cancel()
+	require.NoError(t, <-done)
+	_, err = os.Stat(sock)
+	assert.True(t, os.IsNotExist(err), "the socket file is removed on shutdown")
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "kitchinv.sock")
+	stale, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+	// Leave the file behind as a crashed process would.
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	ln, err := Listen("unix:"+sock, 0o660)
+	require.NoError(t, err)
+	require.NoError(t, ln.Close())
+}
+
+func TestListenRefusesToReplaceFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kitchinv.db")
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0o644))
+
+	_, err := Listen("unix:"+path, 0o660)
+	assert.ErrorContains(t, err, "not a socket")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+}
+
+func TestIsLoopback(t *testing.T) {
+	for _, tc := range []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1:0", true},
+		{"[::1]:0", true},
+		{"0.0.0.0:0", false},
+		{"unix:" + filepath.Join(t.TempDir(), "kitchinv.sock"), true},
+	} {
+		ln, err := Listen(tc.addr, 0o600)
+		if err != nil {
+			t.Logf("skipping %s: %v", tc.addr, err)
+			continue
+		}
+		assert.Equal(t, tc.want, IsLoopback(ln.Addr()), tc.addr)
+		require.NoError(t, ln.Close())
+	}
+}
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// methodOverrideField is the form field a plain HTML form sets to reach a
+// PUT, PATCH or DELETE route.
+const methodOverrideField = "_method"
+
+type formOverrideKey struct{}
+
+// overrideOrigins judges whether an overriding POST came from another site.
+var overrideOrigins = http.NewCrossOriginProtection()
+
+// methodOverride lets a POST stand in for PUT, PATCH or DELETE, rewriting
+// the method before routing, so pages still work when HTMX or fetch can't
+// send those methods. The method is taken from the _method field of a
+// urlencoded form or the X-HTTP-Method-Override header of any form post.
+// Multipart bodies aren't parsed for the field, as they may be photo
+// uploads. Other content types, such as JSON, are left alone: only form
+// posts can be sent cross-site without a preflight, and those are what
+// browsers without scripts make. For the same reason an override from
+// another origin, judged by Sec-Fetch-Site or Origin, is refused with 403,
+// so a page elsewhere can't turn a form post into a DELETE.
+func methodOverride(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/x-www-form-urlencoded" && mediaType != "multipart/form-data" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		method := r.Header.Get("X-HTTP-Method-Override")
+		fromForm := false
+		if method == "" && mediaType == "application/x-www-form-urlencoded" {
+			// Parsed while the method is still POST, so the handler finds
+			// the fields in PostForm whatever the new method.
+			if err := r.ParseForm(); err == nil {
+				method = r.PostForm.Get(methodOverrideField)
+				fromForm = method != ""
+			}
+		}
+		switch method = strings.ToUpper(strings.TrimSpace(method)); method {
+		case http.MethodPut, http.MethodPatch, http.MethodDelete:
+			if err := overrideOrigins.Check(r); err != nil {
+				http.Error(w, "cross-origin method override refused", http.StatusForbidden)
+				return
+			}
+			ctx := r.Context()
+			if fromForm {
+				ctx = context.WithValue(ctx, formOverrideKey{}, true)
+			}
+			r = r.WithContext(ctx)
+			r.Method = method
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// fromPlainForm reports whether r is a plain HTML form post whose method
+// was overridden with the _method field. Such a browser navigates to the
+// response, so handlers redirect it rather than answering with a fragment.
+func fromPlainForm(r *http.Request) bool {
+	v, _ := r.Context().Value(formOverrideKey{}).(bool)
+	return v
+}
@@ -0,0 +1,126 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// deleteRecordingService remembers which areas and items were deleted.
+type deleteRecordingService struct {
+	fakeOverrideService
+	deletedAreas []int64
+	deletedItems []int64
+	prompts      []string
+}
+
+func (d *deleteRecordingService) DeleteArea(_ context.Context, id int64) error {
+	d.deletedAreas = append(d.deletedAreas, id)
+	return nil
+}
+
+func (d *deleteRecordingService) DeleteItem(_ context.Context, id int64) error {
+	d.deletedItems = append(d.deletedItems, id)
+	return nil
+}
+
+func (d *deleteRecordingService) SetAreaPrompt(_ context.Context, id int64, prompt string) (*domain.Area, error) {
+	d.prompts = append(d.prompts, prompt)
+	return &domain.Area{ID: id, Prompt: prompt}, nil
+}
+
+func postForm(srv http.Handler, path string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMethodOverrideDeleteArea(t *testing.T) {
+	svc := &deleteRecordingService{}
+	srv := newOverrideTestServer(svc)
+
+	rec := postForm(srv, "/areas/7", url.Values{"_method": {"delete"}})
+
+	assert.Equal(t, []int64{7}, svc.deletedAreas, "the POST reaches handleDeleteArea")
+	assert.Equal(t, http.StatusSeeOther, rec.Code, "a plain form is sent back to the area list")
+	assert.Equal(t, "/areas", rec.Header().Get("Location"))
+}
+
+func TestMethodOverrideFormFlows(t *testing.T) {
+	svc := &deleteRecordingService{}
+	srv := newOverrideTestServer(svc)
+
+	rec := postForm(srv, "/areas/7/items/12", url.Values{"_method": {"DELETE"}})
+	assert.Equal(t, []int64{12}, svc.deletedItems)
+	assert.Equal(t, "/areas/7", rec.Header().Get("Location"))
+
+	rec = postForm(srv, "/areas/7", url.Values{"_method": {"PUT"}, "prompt": {"Read the jar labels"}})
+	assert.Equal(t, []string{"Read the jar labels"}, svc.prompts, "the form's other fields still reach the handler")
+	assert.Equal(t, "/areas/7", rec.Header().Get("Location"))
+}
+
+func TestMethodOverrideRefusesCrossSite(t *testing.T) {
+	svc := &deleteRecordingService{}
+	srv := newOverrideTestServer(svc)
+
+	for _, h := range []map[string]string{
+		{"Sec-Fetch-Site": "cross-site"},
+		{"Origin": "https://evil.example"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/areas/7", strings.NewReader(url.Values{"_method": {"DELETE"}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for k, v := range h {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code, "%v", h)
+	}
+	assert.Empty(t, svc.deletedAreas, "a cross-site form must not delete anything")
+
+	req := httptest.NewRequest(http.MethodPost, "/areas/7", strings.NewReader(url.Values{"_method": {"DELETE"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Sec-Fetch-Site", "same-origin")
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, []int64{7}, svc.deletedAreas, "the app's own forms still work")
+}
+
+func TestMethodOverrideHeader(t *testing.T) {
+	svc := &deleteRecordingService{}
+	srv := newOverrideTestServer(svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/areas/7", strings.NewReader(""))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-HTTP-Method-Override", "DELETE")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, []int64{7}, svc.deletedAreas)
+	assert.Equal(t, http.StatusOK, rec.Code, "a scripted client gets the usual response, not a redirect")
+}
+
+func TestMethodOverrideRestricted(t *testing.T) {
+	svc := &deleteRecordingService{}
+	srv := newOverrideTestServer(svc)
+
+	// Only PUT, PATCH and DELETE can be asked for.
+	postForm(srv, "/areas/7", url.Values{"_method": {"GET"}})
+	// JSON bodies aren't forms, whatever they carry.
+	req := httptest.NewRequest(http.MethodPost, "/areas/7", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-HTTP-Method-Override", "DELETE")
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+	// Nor is a GET overridden.
+	req = httptest.NewRequest(http.MethodGet, "/areas/7?_method=DELETE", nil)
+	req.Header.Set("X-HTTP-Method-Override", "DELETE")
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Empty(t, svc.deletedAreas)
+}
@@ -0,0 +1,92 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// DefaultItemPageSize is how many items a page holds when a request pages
+// without giving a limit.
+const DefaultItemPageSize = 50
+
+// maxItemPageSize caps the limit a client may ask for.
+const maxItemPageSize = 500
+
+// WithItemPageSize sets the default page size for paged item lists and
+// search results. Non-positive values keep DefaultItemPageSize.
+func (s *Server) WithItemPageSize(n int) *Server {
+	if n > 0 {
+		s.itemPageSize = min(n, maxItemPageSize)
+	}
+	return s
+}
+
+// readItemPage reads the limit, offset, sort and status query parameters.
+// paged is false when none of the first three is given, in which case the
+// caller lists everything with the status as it always has.
+func (s *Server) readItemPage(r *http.Request) (page domain.ItemPage, paged bool, err error) {
+	q := r.URL.Query()
+	var status domain.ItemStatus
+	if v := q.Get("status"); v != "" {
+		var ok bool
+		if status, ok = domain.ParseItemStatus(v); !ok {
+			return page, false, errors.New("status must be active, consumed, discarded or all")
+		}
+	}
+	if !q.Has("limit") && !q.Has("offset") && !q.Has("sort") {
+		return domain.ItemPage{Status: status}, false, nil
+	}
+
+	page = domain.ItemPage{Sort: domain.ItemSortName, Limit: s.itemPageSize, Status: status}
+	if v := q.Get("sort"); v != "" {
+		sort, ok := domain.ParseItemSort(v)
+		if !ok {
+			return page, true, errors.New("sort must be name, created_at or quantity")
+		}
+		page.Sort = sort
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return page, true, errors.New("limit must be a positive integer")
+		}
+		page.Limit = min(n, maxItemPageSize)
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return page, true, errors.New("offset must be a non-negative integer")
+		}
+		page.Offset = n
+	}
+	return page, true, nil
+}
+
+// fetchPage runs fetch for page with one extra row to learn whether another
+// page follows. It returns the page's items and the URL of the next page,
+// built from u, or "" on the last page.
+func fetchPage[T any](u *url.URL, page domain.ItemPage, fetch func(domain.ItemPage) ([]T, error)) ([]T, string, error) {
+	probe := page
+	probe.Limit++
+	items, err := fetch(probe)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(items) <= page.Limit {
+		return items, "", nil
+	}
+	return items[:page.Limit], nextPageURL(u, page), nil
+}
+
+// nextPageURL is u with its paging parameters advanced past page.
+func nextPageURL(u *url.URL, page domain.ItemPage) string {
+	q := u.Query()
+	q.Set("sort", string(page.Sort))
+	q.Set("limit", strconv.Itoa(page.Limit))
+	q.Set("offset", strconv.Itoa(page.Offset+page.Limit))
+	return u.Path + "?" + q.Encode()
+}
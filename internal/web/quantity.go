@@ -0,0 +1,21 @@
+package web
+
+import (
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/units"
+)
+
+// displayQuantity is how an item's quantity is shown: weights and volumes in
+// the units people read, "1.5 kg" for "1500g", and anything else as written.
+func displayQuantity(it domain.Item) string {
+	u := units.Unit(it.QuantityUnit)
+	if it.QuantityValue == nil || (u != units.Gram && u != units.Millilitre) {
+		return it.Quantity
+	}
+	return units.Quantity{Value: *it.QuantityValue, Unit: u}.String()
+}
+
+// displayAmount formats a staple's level or minimum in its unit.
+func displayAmount(v float64, unit string) string {
+	return units.Quantity{Value: v, Unit: units.Unit(unit)}.String()
+}
@@ -0,0 +1,28 @@
+package web
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/units"
+)
+
+func TestDisplayQuantity(t *testing.T) {
+	tests := map[string]string{
+		"":         "",
+		"3 cans":   "3 cans",
+		"a bunch":  "a bunch",
+		"some":     "some",
+		"1500g":    "1.5 kg",
+		"250 gr":   "250 g",
+		"2 x 500g": "1 kg",
+		"33 cl":    "330 ml",
+		"1,5 l":    "1.5 l",
+	}
+	for q, want := range tests {
+		value, unit := units.Nullable(q)
+		it := domain.Item{Quantity: q, QuantityValue: value, QuantityUnit: string(unit)}
+		assert.Equal(t, want, displayQuantity(it), "%q", q)
+	}
+}
@@ -0,0 +1,104 @@
+package web
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxTrackedClients bounds the limiter's memory; once exceeded, buckets that
+// have refilled completely are dropped since they carry no state.
+const maxTrackedClients = 1024
+
+// tokenBucket is one client's allowance.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter is a per-client token bucket keyed by remote IP. Each client
+// may burst up to burst requests, then is refilled at rate tokens per second.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+func newRateLimiter(perMinute float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    perMinute / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// allow takes a token for key. When none is available it returns false and
+// how long until one will be.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxTrackedClients {
+			l.prune(now)
+		}
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+func (l *rateLimiter) prune(now time.Time) {
+	for k, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// clientKey identifies the caller for rate limiting by IP, looking through
+// trusted proxies so clients behind one don't share an allowance.
+func clientKey(r *http.Request) string {
+	return clientIP(r)
+}
+
+// rateLimited wraps an upload handler with the server's upload limiter.
+func (s *Server) rateLimited(next http.HandlerFunc) http.HandlerFunc {
+	return s.limitWith(&s.uploadLimiter, "too many uploads, slow down and try again shortly", next)
+}
+
+// limitWith wraps next with the limiter *l, refusing clients over their
+// allowance with 429 and msg. The limiter is looked up per request so
+// options like WithUploadRateLimit can be applied after routes are
+// registered; a nil limiter lets every request through.
+func (s *Server) limitWith(l **rateLimiter, msg string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limiter := *l
+		if limiter == nil {
+			next(w, r)
+			return
+		}
+		if ok, wait := limiter.allow(clientKey(r)); !ok {
+			secs := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(max(secs, 1)))
+			http.Error(w, msg, http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	l := newRateLimiter(6, 2) // one token every 10s
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.allow("a")
+	assert.True(t, ok)
+	ok, _ = l.allow("a")
+	assert.True(t, ok)
+	ok, wait := l.allow("a")
+	assert.False(t, ok, "burst exhausted")
+	assert.Equal(t, 10*time.Second, wait)
+
+	ok, _ = l.allow("b")
+	assert.True(t, ok, "clients have independent buckets")
+
+	now = now.Add(10 * time.Second)
+	ok, _ = l.allow("a")
+	assert.True(t, ok, "one token refilled")
+	ok, _ = l.allow("a")
+	assert.False(t, ok)
+}
+
+func TestUploadRateLimit(t *testing.T) {
+	srv := newOverrideTestServer(&fakeOverrideService{}).WithUploadRateLimit(1, 1)
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/areas/1/photos", nil)
+		req.RemoteAddr = "192.0.2.7:5555"
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.NotEqual(t, http.StatusTooManyRequests, post().Code)
+	rec := post()
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+
+	// Other routes are not limited.
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/overrides", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/vbonduro/kitchinv/internal/logging"
+)
+
+// RequestLogOptions control the line requestLogger writes for each request.
+type RequestLogOptions struct {
+	// ExcludePaths are not logged unless they fail with a 5xx status or are
+	// slow. An entry ending in "/" excludes every path under it.
+	ExcludePaths []string
+
+	// Levels of successful (2xx and 3xx), client error (4xx) and server
+	// error (5xx) responses.
+	SuccessLevel     slog.Level
+	ClientErrorLevel slog.Level
+	ServerErrorLevel slog.Level
+
+	// SlowThreshold raises a request that took at least this long to warn,
+	// marked slow; 0 turns it off.
+	SlowThreshold time.Duration
+}
+
+// DefaultRequestLogOptions leaves out health checks and static assets,
+// logs successes at info, and warns about client errors and requests
+// slower than two seconds.
+func DefaultRequestLogOptions() RequestLogOptions {
+	return RequestLogOptions{
+		ExcludePaths:     []string{"/readyz", "/healthz", "/metrics", "/static/"},
+		SuccessLevel:     slog.LevelInfo,
+		ClientErrorLevel: slog.LevelWarn,
+		ServerErrorLevel: slog.LevelError,
+		SlowThreshold:    2 * time.Second,
+	}
+}
+
+// WithRequestLog replaces the default request logging options.
+func (s *Server) WithRequestLog(opts RequestLogOptions) *Server {
+	s.requestLog = opts
+	return s
+}
+
+func (o RequestLogOptions) excluded(path string) bool {
+	for _, p := range o.ExcludePaths {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// level returns the level to log a request at, and whether to log it at
+// all.
+func (o RequestLogOptions) level(path string, status int, slow bool) (slog.Level, bool) {
+	lvl := o.SuccessLevel
+	switch {
+	case status >= 500:
+		return o.ServerErrorLevel, true
+	case status >= 400:
+		lvl = o.ClientErrorLevel
+	}
+	if slow {
+		return max(lvl, slog.LevelWarn), true
+	}
+	return lvl, !o.excluded(path)
+}
+
+// requestLogger logs each request once it has been served, attributed to
+// the client clientInfo found, at the level opts gives its status.
+func requestLogger(logger *slog.Logger, opts RequestLogOptions, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		elapsed := time.Since(start)
+		slow := opts.SlowThreshold > 0 && elapsed >= opts.SlowThreshold
+
+		lvl, ok := opts.level(r.URL.Path, rec.status, slow)
+		l := logging.FromContext(r.Context(), logger)
+		if !ok || !l.Enabled(r.Context(), lvl) {
+			return
+		}
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Int64("duration_ms", elapsed.Milliseconds()),
+			slog.Int64("response_bytes", rec.bytes),
+			slog.String("remote_addr", clientIP(r)),
+			slog.String("user_agent", r.UserAgent()),
+		}
+		if slow {
+			attrs = append(attrs, slog.Bool("slow", true))
+		}
+		l.LogAttrs(r.Context(), lvl, "request", attrs...)
+	})
+}
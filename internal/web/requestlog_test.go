@@ -0,0 +1,77 @@
+package web
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLogLevel(t *testing.T) {
+	opts := DefaultRequestLogOptions()
+	opts.SuccessLevel = slog.LevelDebug
+
+	for _, tc := range []struct {
+		path   string
+		status int
+		slow   bool
+		want   slog.Level
+		logged bool
+	}{
+		{"/areas", http.StatusOK, false, slog.LevelDebug, true},
+		{"/areas/1", http.StatusSeeOther, false, slog.LevelDebug, true},
+		{"/areas/9", http.StatusNotFound, false, slog.LevelWarn, true},
+		{"/areas", http.StatusInternalServerError, false, slog.LevelError, true},
+		{"/areas", http.StatusOK, true, slog.LevelWarn, true},
+		{"/readyz", http.StatusOK, false, slog.LevelDebug, false},
+		{"/static/app.css", http.StatusNotModified, false, slog.LevelDebug, false},
+		{"/staticky", http.StatusOK, false, slog.LevelDebug, true},
+		{"/readyz", http.StatusServiceUnavailable, false, slog.LevelError, true},
+		{"/readyz", http.StatusOK, true, slog.LevelWarn, true},
+	} {
+		lvl, logged := opts.level(tc.path, tc.status, tc.slow)
+		assert.Equal(t, tc.logged, logged, "%s %d", tc.path, tc.status)
+		if logged {
+			assert.Equal(t, tc.want, lvl, "%s %d", tc.path, tc.status)
+		}
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	serve := func(opts RequestLogOptions, path string, h http.HandlerFunc) string {
+		buf.Reset()
+		requestLogger(logger, opts, h).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		return buf.String()
+	}
+	hello := func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("hello")) }
+
+	line := serve(DefaultRequestLogOptions(), "/areas", hello)
+	assert.Contains(t, line, "level=INFO")
+	assert.Contains(t, line, "status=200")
+	assert.Contains(t, line, "response_bytes=5")
+	assert.NotContains(t, line, "slow")
+
+	assert.Empty(t, serve(DefaultRequestLogOptions(), "/readyz", hello))
+
+	line = serve(DefaultRequestLogOptions(), "/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	assert.Contains(t, line, "level=WARN")
+	assert.Contains(t, line, "status=404")
+
+	slow := DefaultRequestLogOptions()
+	slow.SlowThreshold = time.Millisecond
+	line = serve(slow, "/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(2 * time.Millisecond)
+	})
+	assert.Contains(t, line, "level=WARN")
+	assert.Contains(t, line, "slow=true")
+	assert.Equal(t, 1, strings.Count(line, "\n"))
+}
@@ -1,68 +1,174 @@
 package web
 
 import (
+	"bytes"
 	"context"
-	"embed"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"html/template"
+	"io"
+	"io/fs"
 	"log/slog"
 	"net/http"
+	"path"
+	"runtime/debug"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/dustin/go-humanize"
-
+	"github.com/vbonduro/kitchinv/internal/audit"
 	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/export"
+	"github.com/vbonduro/kitchinv/internal/logging"
 	"github.com/vbonduro/kitchinv/internal/photostore"
 	"github.com/vbonduro/kitchinv/internal/service"
+	"github.com/vbonduro/kitchinv/internal/web/static"
 )
 
 // kitchenService is the subset of service.AreaService that the web layer uses.
 // Depending on this interface rather than the concrete type decouples the web
 // layer from service implementation details and enables testing with fakes.
 type kitchenService interface {
-	CreateArea(ctx context.Context, name string) (*domain.Area, error)
+	CreateAreaWithKind(ctx context.Context, name string, kind domain.AreaKind) (*domain.Area, error)
+	CreateChildArea(ctx context.Context, parentID int64, name string, kind domain.AreaKind) (*domain.Area, error)
 	ListAreas(ctx context.Context) ([]*domain.Area, error)
-	ListAreasWithItems(ctx context.Context) ([]*service.AreaSummary, error)
+	ListAreaSummaries(ctx context.Context) ([]*service.AreaSummary, error)
 	GetArea(ctx context.Context, areaID int64) (*domain.Area, error)
 	GetAreaWithItems(ctx context.Context, areaID int64) (*domain.Area, []*domain.Item, *domain.Photo, error)
+	GetAreaSummary(ctx context.Context, areaID int64) (*service.AreaSummary, error)
+	AreaItemsVersion(ctx context.Context, areaID int64) (string, error)
+	AreaListVersion(ctx context.Context) (string, error)
+	ListStaleAreas(ctx context.Context) ([]*service.AreaSummary, error)
 	UpdateArea(ctx context.Context, areaID int64, name string) (*domain.Area, error)
+	SetAreaPrompt(ctx context.Context, areaID int64, prompt string) (*domain.Area, error)
+	SetAreaKind(ctx context.Context, areaID int64, kind domain.AreaKind) (*domain.Area, error)
 	DeleteArea(ctx context.Context, areaID int64) error
+	RestoreArea(ctx context.Context, areaID int64) (*domain.Area, error)
 	DeletePhoto(ctx context.Context, areaID int64) error
-	UploadPhoto(ctx context.Context, areaID int64, imageData []byte, mimeType string) (*domain.Photo, []*domain.Item, error)
+	UploadPhotoWithOptions(ctx context.Context, areaID int64, imageData []byte, mimeType string, opts service.UploadOptions) (*domain.Photo, []*domain.Item, error)
+	UploadPhotos(ctx context.Context, areaID int64, uploads []service.PhotoUpload, opts service.UploadOptions) ([]*domain.Photo, []*domain.Item, error)
+	ResolveKeptItems(ctx context.Context, areaID int64, replace bool) ([]*domain.Item, error)
 	CreateItem(ctx context.Context, areaID int64, name, quantity string) (*domain.Item, error)
-	UpdateItem(ctx context.Context, itemID int64, name, quantity string) (*domain.Item, error)
+	CreateItemFromBarcode(ctx context.Context, areaID int64, barcode string) (*domain.Item, error)
+	GetItem(ctx context.Context, areaID, itemID int64) (*domain.Item, error)
+	UpdateItemIfCurrent(ctx context.Context, itemID, version int64, name, quantity string) (*domain.Item, error)
 	DeleteItem(ctx context.Context, itemID int64) error
+	ConsumeItem(ctx context.Context, areaID, itemID int64) (*domain.Item, error)
+	DiscardItem(ctx context.Context, areaID, itemID int64) (*domain.Item, error)
+	BulkEditItems(ctx context.Context, areaID int64, ops []domain.ItemOp) ([]*domain.Item, error)
 	ReorderAreas(ctx context.Context, ids []int64) error
 	SearchItems(ctx context.Context, query string) ([]*domain.Item, error)
+	RecentItems(ctx context.Context, since time.Time, limit, offset int) ([]*domain.RecentItem, error)
+	SearchItemsPage(ctx context.Context, query string, filter domain.SearchFilter, page domain.ItemPage) ([]*domain.Item, error)
+	SearchItemsFuzzy(ctx context.Context, query string, filter domain.SearchFilter) ([]service.FuzzyMatch, error)
+	SuggestItemNames(ctx context.Context, query string) ([]string, error)
+	ListItemsPage(ctx context.Context, areaID int64, page domain.ItemPage) ([]*domain.Item, error)
 	ListSnapshots(ctx context.Context, areaID int64) ([]*domain.Snapshot, error)
+	DiffLatestAnalysis(ctx context.Context, areaID int64) (*service.AreaDiff, error)
+	AreaHistory(ctx context.Context, areaID int64, since time.Time) ([]*domain.HistoryPoint, error)
+	ListAuditEntries(ctx context.Context, f audit.Filter) ([]*audit.Entry, error)
+	ListExpiringItems(ctx context.Context, loc *time.Location) ([]*service.ExpiringItem, error)
+	CanSuggestRecipes() bool
+	SuggestRecipes(ctx context.Context, areaIDs []int64, onDelta func(string)) error
+	Ask(ctx context.Context, question string) (*service.AskAnswer, error)
+	ListWebhooks(ctx context.Context) ([]*domain.Webhook, error)
+	CreateWebhook(ctx context.Context, h domain.Webhook) (*domain.Webhook, error)
+	UpdateWebhook(ctx context.Context, h domain.Webhook) (*domain.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) (bool, error)
+	ListWebhookDeliveries(ctx context.Context, id int64) ([]*domain.WebhookDelivery, error)
+	SendTestWebhook(ctx context.Context, id int64) (*domain.WebhookDelivery, error)
 	ListOverrideRules(ctx context.Context) ([]*domain.OverrideRule, error)
 	CreateOverrideRule(ctx context.Context, r domain.OverrideRule) (*domain.OverrideRule, error)
 	GetOverrideRule(ctx context.Context, id int64) (*domain.OverrideRule, error)
 	UpdateOverrideRule(ctx context.Context, r domain.OverrideRule) (*domain.OverrideRule, error)
 	DeleteOverrideRule(ctx context.Context, id int64) error
 	ReorderOverrideRules(ctx context.Context, ids []int64) error
+	ListIgnoredItems(ctx context.Context) ([]*domain.IgnoredItem, error)
+	AddIgnoredItem(ctx context.Context, pattern string) (*domain.IgnoredItem, error)
+	DeleteIgnoredItem(ctx context.Context, id int64) (bool, error)
+	StapleLevels(ctx context.Context) ([]*service.StapleLevel, error)
+	LowStaples(ctx context.Context) ([]*service.StapleLevel, error)
+	AddStaple(ctx context.Context, st domain.Staple) (*domain.Staple, error)
+	DeleteStaple(ctx context.Context, id int64) (bool, error)
+	LatestRawResponse(ctx context.Context, areaID int64) (string, error)
+	VisionStatus() service.VisionStatus
+	MonthlyUsage(ctx context.Context, months int) ([]domain.MonthlyUsage, error)
+	MonthlyWaste(ctx context.Context, months int) ([]domain.MonthlyWaste, error)
+	EachReportArea(ctx context.Context, includeEmpty bool, fn func(*service.ReportArea) error) error
+	WriteMarkdownReport(ctx context.Context, w io.Writer, generatedAt time.Time, includeEmpty bool) error
+	AreaThumbnail(ctx context.Context, areaID int64, maxSide int) ([]byte, error)
+	Counts(ctx context.Context) (service.Counts, error)
+	Seed(ctx context.Context, data *service.SeedData) (*service.SeedResult, error)
 }
 
 type Server struct {
 	service    kitchenService
-	templates  embed.FS
+	templates  fs.FS
+	assets     *assets
 	photoStore photostore.PhotoStore
 	mux        *http.ServeMux
 	tmplFuncs  template.FuncMap
+	pages      map[string]*template.Template // keyed by pageFiles name
+	partials   map[string]*template.Template // keyed by file, e.g. "partials/item_row.html"
+	reloadTmpl bool                          // re-parse templates on every render; see WithTemplateReload
+	loc        *time.Location                // zone dates are shown in; see WithLocation
 	logger     *slog.Logger
+	demoMode   bool
+	debug      bool // enables troubleshooting endpoints; see WithDebugEndpoints
+
+	maxPhotoSize  int64        // largest accepted photo upload, in bytes
+	uploadLimiter *rateLimiter // nil disables upload rate limiting
+	askLimiter    *rateLimiter // nil disables rate limiting of /ask
+	itemPageSize  int          // default page size; see WithItemPageSize
+	calendarToken string       // empty turns the calendar feed off; see WithCalendarToken
+	pdfPageSize   export.PageSize
+	baseURL       string // public URL QR labels link to; see WithBaseURL
+	qrCodes       qrCache
+	admin         *AdminInfo // nil hides /admin; see WithAdmin
+	testReset     *testReset // nil hides /control/reset; see EnableTestMode
+
+	tlsCertFile  string // empty serves plain HTTP; see WithTLS
+	tlsKeyFile   string
+	redirectAddr string // plain-HTTP redirect listener; see WithHTTPRedirect
+
+	trustedProxies TrustedProxies // see WithTrustedProxies
+	headers        SecurityHeaders
+	requestLog     RequestLogOptions
+	timeouts       RequestTimeouts
+	itemLimits     domain.ItemLimits
 }
 
-func NewServer(svc kitchenService, tmpl embed.FS, ps photostore.PhotoStore, logger *slog.Logger) *Server {
+func NewServer(svc kitchenService, tmpl fs.FS, ps photostore.PhotoStore, logger *slog.Logger) *Server {
+	a, err := loadAssets(static.FS)
+	if err != nil {
+		panic(err)
+	}
 	s := &Server{
 		service:    svc,
 		templates:  tmpl,
+		assets:     a,
 		photoStore: ps,
 		mux:        http.NewServeMux(),
 		logger:     logger,
+		loc:        time.Local,
+
+		maxPhotoSize: defaultMaxPhotoSize,
+		itemPageSize: DefaultItemPageSize,
+		itemLimits:   domain.DefaultItemLimits,
+		pdfPageSize:  export.A4,
+		headers:      DefaultSecurityHeaders(),
+		requestLog:   DefaultRequestLogOptions(),
+		timeouts:     DefaultRequestTimeouts(),
 		tmplFuncs: template.FuncMap{
 			"inc": func(i int) int { return i + 1 },
 			"sub": func(a, b int) int { return a - b },
-			"timeAgo": humanize.Time,
+			"timeAgo": func(t time.Time) string { return relativeTime(t, time.Now()) },
+			"ago": func(d time.Duration) string {
+				now := time.Now()
+				return relativeTime(now.Add(-d), now)
+			},
 			"dict": func(pairs ...any) map[string]any {
 				m := make(map[string]any, len(pairs)/2)
 				for i := 0; i+1 < len(pairs); i += 2 {
@@ -73,6 +179,11 @@ func NewServer(svc kitchenService, tmpl embed.FS, ps photostore.PhotoStore, logg
 				return m
 			},
 			"bboxDim": func(a, b float64) float64 { return b - a },
+			// onPhoto reports whether item was detected in the photo, so boxes
+			// from an area's other photos aren't drawn over it.
+			"onPhoto": func(item *domain.Item, photoID int64) bool {
+				return item.PhotoID != nil && *item.PhotoID == photoID
+			},
 			"not": func(v any) bool {
 				if v == nil {
 					return true
@@ -83,61 +194,116 @@ func NewServer(svc kitchenService, tmpl embed.FS, ps photostore.PhotoStore, logg
 			"areaName": func(m map[int64]string, id int64) string {
 				return m[id]
 			},
+			"highlight": highlight,
+			"quantity": displayQuantity,
+			"amount": displayAmount,
+			"asset": a.url,
 		},
 	}
+	// These read s.loc when run, so WithLocation applies after parsing.
+	s.tmplFuncs["formatDate"] = func(t time.Time) string { return formatIn(t, s.loc, dateLayout) }
+	s.tmplFuncs["formatDateTime"] = func(t time.Time) string { return formatIn(t, s.loc, dateTimeLayout) }
+	if err := s.parseTemplates(); err != nil {
+		// Templates are embedded, so this is a build defect: refuse to start
+		// rather than fail on the first request for the broken page.
+		panic(err)
+	}
 	s.registerRoutes()
 	return s
 }
 
+// WithTemplateReload re-parses templates from fsys on every render instead of
+// using the set parsed at startup, so template edits show without a rebuild.
+// Meant for development against the source tree.
+func (s *Server) WithTemplateReload(fsys fs.FS) *Server {
+	s.templates = fsys
+	s.reloadTmpl = true
+	return s
+}
+
 func (s *Server) registerRoutes() {
-	s.mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
-		http.Redirect(w, r, "/areas", http.StatusSeeOther)
-	})
+	s.mux.HandleFunc("GET /", s.handleRoot)
 	s.mux.HandleFunc("GET /areas", s.handleListAreas)
 	s.mux.HandleFunc("POST /areas", s.handleCreateArea)
 	s.mux.HandleFunc("POST /areas/reorder", s.handleReorderAreas)
+	s.mux.HandleFunc("PUT /areas/order", s.handleSetAreaOrder)
+	s.mux.HandleFunc("GET /areas/stale", s.handleListStaleAreas)
+	s.mux.HandleFunc("GET /areas/labels", s.handleAreaLabels)
 	s.mux.HandleFunc("GET /areas/{id}", s.handleGetAreaDetail)
 	s.mux.HandleFunc("PUT /areas/{id}", s.handleUpdateArea)
 	s.mux.HandleFunc("DELETE /areas/{id}", s.handleDeleteArea)
+	s.mux.HandleFunc("POST /areas/{id}/restore", s.handleRestoreArea)
 	s.mux.HandleFunc("DELETE /areas/{id}/photo", s.handleDeletePhoto)
-	s.mux.HandleFunc("POST /areas/{id}/photos", s.handleUploadPhoto)
+	s.mux.HandleFunc("POST /areas/{id}/kept-items", s.handleResolveKeptItems)
+	s.mux.HandleFunc("POST /areas/{id}/photos", s.rateLimited(s.handleUploadPhoto))
 	s.mux.HandleFunc("GET /areas/{id}/photo", s.handleGetPhoto)
+	s.mux.HandleFunc("GET /areas/{id}/qr.png", s.handleAreaQR)
+	s.mux.HandleFunc("GET /areas/{id}/photos/latest/raw", s.debugOnly(s.handleGetRawResponse))
+	s.mux.HandleFunc("PUT /api/v1/areas/{id}/photo", s.rateLimited(s.handleAPIUploadPhoto))
 	s.mux.HandleFunc("GET /areas/{id}/card", s.handleGetAreaCard)
 	s.mux.HandleFunc("GET /areas/{id}/items", s.handleGetAreaItems)
 	s.mux.HandleFunc("POST /areas/{id}/items", s.handleCreateItem)
+	s.mux.HandleFunc("POST /areas/{id}/items/bulk", s.handleBulkItems)
+	s.mux.HandleFunc("POST /areas/{id}/items/barcode", s.handleCreateItemFromBarcode)
 	s.mux.HandleFunc("PUT /areas/{id}/items/{itemId}", s.handleUpdateItem)
 	s.mux.HandleFunc("DELETE /areas/{id}/items/{itemId}", s.handleDeleteItem)
+	s.mux.HandleFunc("POST /areas/{id}/items/{itemId}/consume", s.handleConsumeItem)
+	s.mux.HandleFunc("POST /areas/{id}/items/{itemId}/discard", s.handleDiscardItem)
+	s.mux.HandleFunc("GET /areas/{id}/items/{itemId}/photo", s.handleGetItemPhoto)
 	s.mux.HandleFunc("GET /search", s.handleSearch)
+	s.mux.HandleFunc("GET /search/suggest", s.handleSearchSuggest)
+	s.mux.HandleFunc("GET /recent", s.handleRecent)
+	s.mux.HandleFunc("GET /audit", s.handleListAudit)
+	s.mux.HandleFunc("GET /calendar.ics", s.handleCalendar)
+	s.mux.HandleFunc("GET /export.md", s.handleExportMarkdown)
+	s.mux.HandleFunc("GET /export.pdf", s.handleExportPDF)
+	s.mux.HandleFunc("GET /print", s.handlePrint)
+	s.mux.HandleFunc("POST /suggest", s.handleSuggestRecipes)
+	s.mux.HandleFunc("GET /ask", s.limitWith(&s.askLimiter, "too many questions, slow down and try again shortly", s.handleAsk))
 	s.mux.HandleFunc("GET /areas/{id}/snapshots", s.handleListSnapshots)
+	s.mux.HandleFunc("GET /areas/{id}/diff", s.handleAreaDiff)
+	s.mux.HandleFunc("GET /areas/{id}/history", s.handleAreaHistory)
 	s.mux.HandleFunc("GET /overrides", s.handleListOverrides)
 	s.mux.HandleFunc("POST /overrides", s.handleCreateOverride)
 	s.mux.HandleFunc("PUT /overrides/{id}", s.handleUpdateOverride)
 	s.mux.HandleFunc("DELETE /overrides/{id}", s.handleDeleteOverride)
 	s.mux.HandleFunc("POST /overrides/reorder", s.handleReorderOverrides)
+	s.mux.HandleFunc("GET /ignored-items", s.handleListIgnoredItems)
+	s.mux.HandleFunc("POST /ignored-items", s.handleCreateIgnoredItem)
+	s.mux.HandleFunc("DELETE /ignored-items/{id}", s.handleDeleteIgnoredItem)
+	s.mux.HandleFunc("GET /staples", s.handleListStaples)
+	s.mux.HandleFunc("POST /staples", s.handleCreateStaple)
+	s.mux.HandleFunc("DELETE /staples/{id}", s.handleDeleteStaple)
+	s.mux.HandleFunc("GET /webhooks", s.handleListWebhooks)
+	s.mux.HandleFunc("POST /webhooks", s.handleCreateWebhook)
+	s.mux.HandleFunc("PUT /webhooks/{id}", s.handleUpdateWebhook)
+	s.mux.HandleFunc("DELETE /webhooks/{id}", s.handleDeleteWebhook)
+	s.mux.HandleFunc("GET /webhooks/{id}/deliveries", s.handleListWebhookDeliveries)
+	s.mux.HandleFunc("POST /webhooks/{id}/test", s.handleTestWebhook)
+	s.mux.HandleFunc("GET /readyz", s.handleReadyz)
+	s.mux.HandleFunc("GET /stats", s.handleStats)
+	s.mux.HandleFunc("GET /admin", s.handleAdmin)
+	s.mux.HandleFunc("POST /control/reset", s.handleControlReset)
+	s.mux.HandleFunc("POST /control/seed", s.handleControlSeed)
+	s.mux.HandleFunc("GET /static/{file}", s.handleStatic)
 }
 
-
-// securityHeaders adds defensive HTTP response headers to every response.
-func securityHeaders(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		h := w.Header()
-		h.Set("X-Content-Type-Options", "nosniff")
-		h.Set("X-Frame-Options", "DENY")
-		h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
-		h.Set("Content-Security-Policy",
-			"default-src 'self'; "+
-				"script-src 'self' 'unsafe-inline' https://unpkg.com; "+
-				"style-src 'self' 'unsafe-inline'; "+
-				"img-src 'self' data:; "+
-				"connect-src 'self'")
-		next.ServeHTTP(w, r)
-	})
+// handleRoot sends / to the areas list. It is also the mux's catch-all for
+// GET, so any other path it sees matched no route and gets the 404 page.
+func (s *Server) handleRoot(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		s.renderError(w, r, http.StatusNotFound, notFoundMessage)
+		return
+	}
+	http.Redirect(w, r, "/areas", http.StatusSeeOther)
 }
 
-// statusRecorder wraps http.ResponseWriter to capture the written status code.
+// statusRecorder wraps http.ResponseWriter to capture the written status
+// code and count the body bytes written.
 type statusRecorder struct {
 	http.ResponseWriter
 	status int
+	bytes  int64
 }
 
 func (r *statusRecorder) WriteHeader(code int) {
@@ -145,69 +311,388 @@ func (r *statusRecorder) WriteHeader(code int) {
 	r.ResponseWriter.WriteHeader(code)
 }
 
-func requestLogger(logger *slog.Logger, next http.Handler) http.Handler {
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// headerTracker records whether the response header has been sent so the
+// recovery middleware knows if it can still write an error response.
+type headerTracker struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (t *headerTracker) WriteHeader(code int) {
+	t.wroteHeader = true
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *headerTracker) Write(b []byte) (int, error) {
+	t.wroteHeader = true
+	return t.ResponseWriter.Write(b)
+}
+
+func (t *headerTracker) Flush() {
+	t.wroteHeader = true
+	_ = http.NewResponseController(t.ResponseWriter).Flush()
+}
+
+func (t *headerTracker) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// recoverPanics turns a panicking handler into a logged 500, shown with the
+// error page or toast. If the response has already started, the status can
+// no longer change, so the connection is aborted instead to avoid leaving a
+// truncated body looking complete.
+func (s *Server) recoverPanics(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
-		next.ServeHTTP(rec, r)
-		logger.Info("request",
-			"method", r.Method,
-			"path", r.URL.Path,
-			"status", rec.status,
-			"duration_ms", time.Since(start).Milliseconds(),
-		)
+		tw := &headerTracker{ResponseWriter: w}
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			s.log(r).Error("panic serving request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"panic", rec,
+				"stack", string(debug.Stack()),
+			)
+			if tw.wroteHeader {
+				panic(http.ErrAbortHandler)
+			}
+			s.renderError(w, r, http.StatusInternalServerError, "internal server error")
+		}()
+		next.ServeHTTP(tw, r)
 	})
 }
 
+const requestIDHeader = "X-Request-ID"
+
+// requestID tags each request with an ID, honouring a well-formed incoming
+// X-Request-ID, and echoes it in the response. The ID is attached to a
+// request-scoped logger stored in the context; handlers and the service layer
+// pick it up via logging.FromContext.
+func requestID(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validOpaqueID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		ctx := logging.NewContext(r.Context(), logger.With("request_id", id))
+		ctx = logging.WithRequestID(ctx, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// newRequestID returns a random 128-bit hex ID.
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// WithUploadRateLimit allows each client perMinute photo uploads on average,
+// with bursts of up to burst. perMinute <= 0 disables the limit.
+func (s *Server) WithUploadRateLimit(perMinute float64, burst int) *Server {
+	if perMinute <= 0 {
+		s.uploadLimiter = nil
+		return s
+	}
+	s.uploadLimiter = newRateLimiter(perMinute, max(burst, 1))
+	return s
+}
+
+// WithAskRateLimit allows each client perMinute questions to /ask on
+// average, with bursts of up to burst. perMinute <= 0 disables the limit.
+func (s *Server) WithAskRateLimit(perMinute float64, burst int) *Server {
+	if perMinute <= 0 {
+		s.askLimiter = nil
+		return s
+	}
+	s.askLimiter = newRateLimiter(perMinute, max(burst, 1))
+	return s
+}
+
+// WithMaxPhotoSize sets the largest photo upload accepted, in bytes. Larger
+// uploads are refused with 413. Non-positive values keep the default.
+func (s *Server) WithMaxPhotoSize(n int64) *Server {
+	if n > 0 {
+		s.maxPhotoSize = n
+	}
+	return s
+}
+
+// WithDemoMode makes the server read-only except for photo uploads, which
+// the demo wires to the fake vision backend.
+func (s *Server) WithDemoMode() *Server {
+	s.demoMode = true
+	return s
+}
+
+// WithDebugEndpoints enables endpoints meant for troubleshooting, such as the
+// raw vision response of an area's latest photo. They reveal model output
+// verbatim, so leave them off on shared deployments.
+func (s *Server) WithDebugEndpoints() *Server {
+	s.debug = true
+	return s
+}
+
+// debugOnly hides a handler behind WithDebugEndpoints, answering 404 as if
+// the route did not exist when debugging is off. Like rateLimited it checks
+// per request so the option can be applied after routes are registered.
+func (s *Server) debugOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.debug {
+			http.NotFound(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// demoReadOnly rejects every mutating request except a photo upload with 403.
+func demoReadOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if !isPhotoUpload(r) {
+				http.Error(w, "the demo is read-only; try uploading a photo instead", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isPhotoUpload reports whether r targets POST /areas/{id}/photos or its API
+// counterpart, PUT /api/v1/areas/{id}/photo.
+func isPhotoUpload(r *http.Request) bool {
+	prefix, suffix := "/areas/", "/photos"
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodPut:
+		prefix, suffix = "/api/v1/areas/", "/photo"
+	default:
+		return false
+	}
+	id, ok := strings.CutPrefix(r.URL.Path, prefix)
+	if !ok {
+		return false
+	}
+	id, ok = strings.CutSuffix(id, suffix)
+	if !ok {
+		return false
+	}
+	_, err := strconv.ParseInt(id, 10, 64)
+	return err == nil
+}
+
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	requestLogger(s.logger, securityHeaders(s.mux)).ServeHTTP(w, r)
+	var h http.Handler = s.withTimeout(traceRequests(s.mux))
+	if s.demoMode {
+		h = demoReadOnly(h)
+	}
+	requestID(s.logger, clientInfo(s.trustedProxies, methodOverride(requestLogger(s.logger, s.requestLog, s.securityHeaders(s.recoverPanics(h)))))).ServeHTTP(w, r)
+}
+
+// log returns the request-scoped logger, which carries the request ID.
+func (s *Server) log(r *http.Request) *slog.Logger {
+	return logging.FromContext(r.Context(), s.logger)
+}
+
+// pageFiles lists the files each page is parsed from, keyed by the name
+// handlers pass to renderPage.
+var pageFiles = map[string][]string{
+	"areas":       {"base.html", "pages/areas.html", "partials/area_card.html"},
+	"area_detail": {"base.html", "pages/area_detail.html", "partials/item_list.html", "partials/item_page.html", "partials/item_row.html"},
+	"search":      {"base.html", "pages/search.html", "partials/search_results.html"},
+	"recent":      {"base.html", "pages/recent.html", "partials/recent_items.html"},
+	"audit":       {"base.html", "pages/audit.html"},
+	"staples":     {"base.html", "pages/staples.html"},
+	"admin":       {"base.html", "pages/admin.html"},
+	"area_diff":   {"base.html", "pages/area_diff.html"},
+	"overrides":   {"base.html", "pages/overrides.html"},
+	"print":       {"pages/print.html"},
+	"labels":      {"pages/labels.html"},
+	"error":       {"base.html", "pages/error.html"},
 }
 
-func (s *Server) ListenAndServe(addr string) error {
-	s.logger.Info("starting server", "addr", addr)
-	srv := &http.Server{
-		Addr:         addr,
-		Handler:      s,
-		ReadTimeout:  60 * time.Second,
-		WriteTimeout: 120 * time.Second,
-		IdleTimeout:  120 * time.Second,
+// parseTemplates parses every page and partial once, so a broken template is
+// found at startup and requests only execute.
+func (s *Server) parseTemplates() error {
+	s.pages = make(map[string]*template.Template, len(pageFiles))
+	for name, files := range pageFiles {
+		tmpl, err := s.parse(files)
+		if err != nil {
+			return fmt.Errorf("failed to parse page %q: %w", name, err)
+		}
+		s.pages[name] = tmpl
+	}
+
+	files, err := fs.Glob(s.templates, "partials/*.html")
+	if err != nil {
+		return fmt.Errorf("failed to list partials: %w", err)
 	}
-	return srv.ListenAndServe()
+	s.partials = make(map[string]*template.Template, len(files))
+	for _, file := range files {
+		tmpl, err := s.parse(append([]string{file}, partialIncludes[file]...))
+		if err != nil {
+			return fmt.Errorf("failed to parse partial %q: %w", file, err)
+		}
+		s.partials[file] = tmpl
+	}
+	return nil
 }
 
-// renderPage parses and executes a full-page template set.
-func (s *Server) renderPage(w http.ResponseWriter, data any, files ...string) error {
-	tmpl, err := template.New("").Funcs(s.tmplFuncs).ParseFS(s.templates, files...)
+func (s *Server) parse(files []string) (*template.Template, error) {
+	return template.New("").Funcs(s.tmplFuncs).ParseFS(s.templates, files...)
+}
+
+// page returns the parsed template set for a pageFiles entry.
+func (s *Server) page(name string) (*template.Template, error) {
+	if s.reloadTmpl {
+		return s.parse(pageFiles[name])
+	}
+	tmpl, ok := s.pages[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown page %q", name)
+	}
+	return tmpl, nil
+}
+
+// partial returns the parsed template set for a partial file.
+func (s *Server) partial(file string) (*template.Template, error) {
+	if s.reloadTmpl {
+		return s.parse(append([]string{file}, partialIncludes[file]...))
+	}
+	tmpl, ok := s.partials[file]
+	if !ok {
+		return nil, fmt.Errorf("unknown partial %q", file)
+	}
+	return tmpl, nil
+}
+
+// renderPage executes the named full-page template set. Map data gains the
+// request's script nonce as .Nonce, and .DemoMode in demo mode. Output is
+// buffered so a template error shows the error page rather than half a page.
+func (s *Server) renderPage(w http.ResponseWriter, r *http.Request, name string, data any) error {
+	tmpl, err := s.page(name)
 	if err != nil {
-		http.Error(w, "template error", http.StatusInternalServerError)
+		s.renderError(w, r, http.StatusInternalServerError, renderFailedMessage)
+		return err
+	}
+	if m, ok := data.(map[string]any); ok {
+		m["Nonce"] = cspNonce(r)
+		if s.demoMode {
+			m["DemoMode"] = true
+		}
+	}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "base", data); err != nil {
+		s.renderError(w, r, http.StatusInternalServerError, renderFailedMessage)
 		return err
 	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	return tmpl.ExecuteTemplate(w, "base", data)
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+const (
+	notFoundMessage     = "there's nothing here; it may have been deleted"
+	renderFailedMessage = "something went wrong showing this page"
+)
+
+// renderError responds with status and a user-facing message in the form the
+// caller can show: a toast partial retargeted into the toast container for
+// HTMX, an error page for browser navigation, {"error": msg} for JSON
+// clients, and plain text otherwise, which is what the page scripts read.
+func (s *Server) renderError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	data := map[string]any{"Status": status, "Message": msg, "Title": http.StatusText(status), "ActiveNav": "", "DemoMode": s.demoMode, "Nonce": cspNonce(r)}
+	switch {
+	case isHTMX(r):
+		out, err := s.renderFragment("partials/error.html", data)
+		if err != nil {
+			s.log(r).Error("render error partial failed", "error", err)
+			http.Error(w, msg, status)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("HX-Retarget", "#toast-container")
+		w.Header().Set("HX-Reswap", "beforeend")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, out)
+	case wantsJSON(r):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+	case strings.Contains(r.Header.Get("Accept"), "text/html"):
+		tmpl, err := s.page("error")
+		if err != nil {
+			s.log(r).Error("render error page failed", "error", err)
+			http.Error(w, msg, status)
+			return
+		}
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, "base", data); err != nil {
+			s.log(r).Error("render error page failed", "error", err)
+			http.Error(w, msg, status)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		_, _ = buf.WriteTo(w)
+	default:
+		http.Error(w, msg, status)
+	}
 }
 
-// renderPartial parses and executes a single named partial template.
-// The file must contain exactly one {{define "name"}}...{{end}} block.
-func (s *Server) renderPartial(w http.ResponseWriter, file string, data any) error {
-	tmpl, err := template.New("").Funcs(s.tmplFuncs).ParseFS(s.templates, file)
+// partialIncludes lists the partials each partial invokes via {{template}},
+// so they are parsed alongside it.
+var partialIncludes = map[string][]string{
+	"partials/item_list.html": {"partials/item_page.html", "partials/item_row.html"},
+	"partials/item_page.html": {"partials/item_row.html"},
+}
+
+// renderPartial renders a single partial template to w. Output is buffered so
+// a template error still produces a clean 500 rather than a truncated body.
+func (s *Server) renderPartial(w http.ResponseWriter, r *http.Request, file string, data any) error {
+	out, err := s.renderFragment(file, data)
 	if err != nil {
-		http.Error(w, "template error", http.StatusInternalServerError)
+		s.renderError(w, r, http.StatusInternalServerError, renderFailedMessage)
 		return err
 	}
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	// ParseFS registers both the file-basename template and any {{define}} blocks.
-	// Find the {{define}} template: it is the one whose name is neither "" nor
-	// the file basename.
-	basename := file
-	if idx := strings.LastIndexByte(file, '/'); idx >= 0 {
-		basename = file[idx+1:]
-	}
-	for _, t := range tmpl.Templates() {
-		if n := t.Name(); n != "" && n != basename {
-			return t.Execute(w, data)
-		}
+	_, err = io.WriteString(w, out)
+	return err
+}
+
+// renderFragment executes a partial and returns the markup. The file must
+// contain a {{define}} block named after it, e.g. "item_row" for
+// partials/item_row.html. html/template escapes model-produced text such as
+// item names, so the result is safe to splice into a page.
+func (s *Server) renderFragment(file string, data any) (string, error) {
+	tmpl, err := s.partial(file)
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimSuffix(path.Base(file), path.Ext(file))
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", err
 	}
-	// Fallback: execute the file-basename template (no {{define}} blocks found).
-	return tmpl.ExecuteTemplate(w, basename, data)
+	return buf.String(), nil
 }
 
@@ -0,0 +1,305 @@
+package web
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/domain"
+	"github.com/vbonduro/kitchinv/internal/web/templates"
+)
+
+func TestDemoReadOnly(t *testing.T) {
+	srv := newOverrideTestServer(&fakeOverrideService{}).WithDemoMode()
+
+	tests := []struct {
+		method, path string
+		wantForbid   bool
+	}{
+		{http.MethodGet, "/overrides", false},
+		{http.MethodPost, "/areas", true},
+		{http.MethodDelete, "/areas/1", true},
+		{http.MethodPut, "/areas/1/items/2", true},
+		{http.MethodPost, "/overrides", true},
+		{http.MethodPost, "/areas/1/items/bulk", true},
+		{http.MethodPost, "/areas/abc/photos", true},
+		{http.MethodPost, "/areas/1/photos", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(""))
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+			if tt.wantForbid {
+				assert.Equal(t, http.StatusForbidden, rec.Code)
+			} else {
+				assert.NotEqual(t, http.StatusForbidden, rec.Code)
+			}
+		})
+	}
+}
+
+func TestDemoModeBanner(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/overrides", nil)
+
+	rec := httptest.NewRecorder()
+	newOverrideTestServer(&fakeOverrideService{}).ServeHTTP(rec, req)
+	assert.NotContains(t, rec.Body.String(), `data-testid="demo-banner"`)
+
+	rec = httptest.NewRecorder()
+	newOverrideTestServer(&fakeOverrideService{}).WithDemoMode().ServeHTTP(rec, req)
+	assert.Contains(t, rec.Body.String(), `data-testid="demo-banner"`)
+}
+
+func TestRecoverPanics(t *testing.T) {
+	srv := newOverrideTestServer(&fakeOverrideService{})
+	srv.mux.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		var m map[string]int
+		m["boom"]++ // nil map write panics
+	})
+	srv.mux.HandleFunc("GET /panic-mid-stream", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("partial"))
+		panic("template exploded")
+	})
+
+	t.Run("before headers", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Contains(t, rec.Body.String(), "internal server error")
+		assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	})
+
+	t.Run("browser gets the error page", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+		req.Header.Set("Accept", "text/html")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Contains(t, rec.Body.String(), `data-testid="error-page"`)
+	})
+
+	t.Run("after headers aborts the connection", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic-mid-stream", nil))
+		})
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	srv := NewServer(&fakeOverrideService{}, templates.FS, nil, logger)
+	srv.mux.HandleFunc("GET /log", func(w http.ResponseWriter, r *http.Request) {
+		srv.log(r).Info("handler ran")
+	})
+
+	t.Run("generated", func(t *testing.T) {
+		buf.Reset()
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/log", nil))
+
+		id := rec.Header().Get("X-Request-ID")
+		assert.Len(t, id, 32)
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2, "handler line and request line")
+		for _, line := range lines {
+			assert.Contains(t, line, `"request_id":"`+id+`"`)
+		}
+	})
+
+	t.Run("honours incoming", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/log", nil)
+		req.Header.Set("X-Request-ID", "edge-proxy-42")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		assert.Equal(t, "edge-proxy-42", rec.Header().Get("X-Request-ID"))
+	})
+
+	t.Run("replaces malformed incoming", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/log", nil)
+		req.Header.Set("X-Request-ID", "bad id\r\n")
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		assert.Len(t, rec.Header().Get("X-Request-ID"), 32)
+	})
+}
+
+func TestNotFoundPage(t *testing.T) {
+	srv := newOverrideTestServer(&fakeOverrideService{})
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+	browser := map[string]string{"Accept": "text/html"}
+
+	rec := get("/", browser)
+	assert.Equal(t, http.StatusSeeOther, rec.Code)
+	assert.Equal(t, "/areas", rec.Header().Get("Location"))
+
+	for _, path := range []string{"/no-such-page", "/areas/9"} {
+		rec = get(path, browser)
+		assert.Equal(t, http.StatusNotFound, rec.Code, path)
+		assert.Contains(t, rec.Body.String(), `data-testid="error-page" data-status="404"`, path)
+		assert.Contains(t, rec.Body.String(), `href="/areas"`, path)
+	}
+	assert.Contains(t, get("/areas/9", browser).Body.String(), "area not found")
+
+	rec = get("/areas/9", map[string]string{"HX-Request": "true"})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), `data-testid="error-toast"`)
+	assert.NotContains(t, rec.Body.String(), "<!DOCTYPE")
+}
+
+func TestRenderError(t *testing.T) {
+	srv := newOverrideTestServer(&fakeOverrideService{})
+	longName := "name=" + strings.Repeat("x", maxAreaNameLen+1)
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      string
+		headers   map[string]string
+		wantCT    string
+		wantParts []string
+		notParts  []string
+	}{
+		{
+			name: "htmx gets a retargeted toast", method: http.MethodPost, path: "/areas", body: longName,
+			headers:   map[string]string{"HX-Request": "true"},
+			wantCT:    "text/html; charset=utf-8",
+			wantParts: []string{`data-testid="error-toast"`, `data-status="400"`, "area name too long"},
+			notParts:  []string{"<!DOCTYPE"},
+		},
+		{
+			name: "htmx upload error", method: http.MethodPost, path: "/areas/1/photos",
+			body:      "",
+			headers:   map[string]string{"HX-Request": "true", "Accept": "application/json"},
+			wantCT:    "text/html; charset=utf-8",
+			wantParts: []string{`data-testid="error-toast"`, "failed to parse form"},
+		},
+		{
+			name: "json client", method: http.MethodPost, path: "/areas", body: longName,
+			headers:   map[string]string{"Accept": "application/json"},
+			wantCT:    "application/json",
+			wantParts: []string{`{"error":"area name too long"}`},
+		},
+		{
+			name: "browser navigation gets a page", method: http.MethodPost, path: "/areas", body: longName,
+			headers:   map[string]string{"Accept": "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8"},
+			wantCT:    "text/html; charset=utf-8",
+			wantParts: []string{"<!DOCTYPE html>", `data-testid="error-page"`, "Bad Request", "area name too long"},
+		},
+		{
+			name: "other callers get plain text", method: http.MethodPost, path: "/areas", body: longName,
+			wantCT:    "text/plain; charset=utf-8",
+			wantParts: []string{"area name too long"},
+			notParts:  []string{"<"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Equal(t, tt.wantCT, rec.Header().Get("Content-Type"))
+			for _, part := range tt.wantParts {
+				assert.Contains(t, rec.Body.String(), part)
+			}
+			for _, part := range tt.notParts {
+				assert.NotContains(t, rec.Body.String(), part)
+			}
+			if tt.headers["HX-Request"] == "true" {
+				assert.Equal(t, "#toast-container", rec.Header().Get("HX-Retarget"))
+				assert.Equal(t, "beforeend", rec.Header().Get("HX-Reswap"))
+			} else {
+				assert.Empty(t, rec.Header().Get("HX-Retarget"))
+			}
+		})
+	}
+}
+
+func TestRenderFragment_ItemRowEscapesName(t *testing.T) {
+	srv := newOverrideTestServer(&fakeOverrideService{})
+	item := domain.Item{ID: 7, AreaID: 3, Name: `<script>alert("x")</script>`, Quantity: "2"}
+
+	out, err := srv.renderFragment("partials/item_row.html", map[string]any{"Item": item})
+	require.NoError(t, err)
+	assert.Contains(t, out, `data-item-id="7"`)
+	assert.Contains(t, out, "&lt;script&gt;")
+	assert.NotContains(t, out, "<script>")
+	assert.NotContains(t, out, "item-row-hidden")
+
+	out, err = srv.renderFragment("partials/item_list.html", map[string]any{"AreaID": 3, "Items": []domain.Item{item}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(out, `data-testid="item-row"`))
+}
+
+func TestNewServer_ParsesTemplatesOnce(t *testing.T) {
+	srv := newOverrideTestServer(&fakeOverrideService{})
+	assert.Len(t, srv.pages, len(pageFiles))
+	assert.Contains(t, srv.partials, "partials/item_row.html")
+
+	// With the template files gone, rendering still works from the parsed
+	// set; only reload mode goes back to the files.
+	srv.templates = fstest.MapFS{}
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/overrides", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	_, err := srv.renderFragment("partials/item_row.html", map[string]any{"Item": domain.Item{ID: 1}})
+	require.NoError(t, err)
+
+	srv.WithTemplateReload(fstest.MapFS{})
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/overrides", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestNewServer_PanicsOnBrokenTemplate(t *testing.T) {
+	broken := fstest.MapFS{}
+	for _, files := range pageFiles {
+		for _, f := range files {
+			broken[f] = &fstest.MapFile{Data: []byte(`{{define "x"}}ok{{end}}`)}
+		}
+	}
+	broken["pages/search.html"] = &fstest.MapFile{Data: []byte(`{{if}}`)}
+
+	assert.PanicsWithError(t, `failed to parse page "search": template: search.html:1: missing value for if`, func() {
+		NewServer(&fakeOverrideService{}, broken, nil, slog.Default())
+	})
+}
+
+func BenchmarkRenderAreaDetail(b *testing.B) {
+	srv := newOverrideTestServer(&fakeOverrideService{})
+	data := map[string]any{
+		"Area":  &domain.Area{ID: 1, Name: "Fridge"},
+		"Items": []*domain.Item{{ID: 1, AreaID: 1, Name: "Milk", Quantity: "1"}},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/areas/1", nil)
+	for b.Loop() {
+		if err := srv.renderPage(httptest.NewRecorder(), req, "area_detail", data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package static
+
+import "embed"
+
+// FS holds the stylesheet and scripts served under /static/. htmx.min.js is
+// htmx 1.9.10, vendored so the UI works without internet access.
+//
+//go:embed app.css htmx.min.js
+var FS embed.FS
@@ -0,0 +1,58 @@
+package web
+
+import (
+	"fmt"
+	"time"
+)
+
+// Layouts for the formatDate and formatDateTime template funcs.
+const (
+	dateLayout     = "2 Jan 2006"
+	dateTimeLayout = "2 Jan 2006, 15:04"
+)
+
+// relativeTime describes how long before now t was, e.g. "3 weeks ago".
+// Times in the future, e.g. from clock skew, read as "just now".
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	day := 24 * time.Hour
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute") + " ago"
+	case d < day:
+		return plural(int(d/time.Hour), "hour") + " ago"
+	case d < 7*day:
+		return plural(int(d/day), "day") + " ago"
+	case d < 30*day:
+		return plural(int(d/(7*day)), "week") + " ago"
+	case d < 365*day:
+		return plural(int(d/(30*day)), "month") + " ago"
+	default:
+		return plural(int(d/(365*day)), "year") + " ago"
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// formatIn formats t in loc with layout. The zero time formats as "" so
+// templates need no guard for unset timestamps.
+func formatIn(t time.Time, loc *time.Location, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(loc).Format(layout)
+}
+
+// WithLocation sets the time zone dates are shown in. The default is the
+// process's local zone.
+func (s *Server) WithLocation(loc *time.Location) *Server {
+	s.loc = loc
+	return s
+}
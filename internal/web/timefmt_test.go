@@ -0,0 +1,54 @@
+package web
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{-time.Hour, "just now"},
+		{0, "just now"},
+		{59 * time.Second, "just now"},
+		{time.Minute, "1 minute ago"},
+		{59 * time.Minute, "59 minutes ago"},
+		{time.Hour, "1 hour ago"},
+		{23*time.Hour + 59*time.Minute, "23 hours ago"},
+		{day, "1 day ago"},
+		{6 * day, "6 days ago"},
+		{7 * day, "1 week ago"},
+		{29 * day, "4 weeks ago"},
+		{30 * day, "1 month ago"},
+		{364 * day, "12 months ago"},
+		{365 * day, "1 year ago"},
+		{3 * 365 * day, "3 years ago"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, relativeTime(now.Add(-tt.ago), now), "ago %s", tt.ago)
+	}
+}
+
+func TestFormatDate(t *testing.T) {
+	toronto, err := time.LoadLocation("America/Toronto")
+	require.NoError(t, err)
+
+	srv := newOverrideTestServer(&fakeOverrideService{}).WithLocation(toronto)
+	formatDate := srv.tmplFuncs["formatDate"].(func(time.Time) string)
+	formatDateTime := srv.tmplFuncs["formatDateTime"].(func(time.Time) string)
+
+	// 02:30 UTC is still the previous evening in Toronto.
+	ts := time.Date(2025, 6, 2, 2, 30, 0, 0, time.UTC)
+	assert.Equal(t, "1 Jun 2025", formatDate(ts))
+	assert.Equal(t, "1 Jun 2025, 22:30", formatDateTime(ts))
+	assert.Empty(t, formatDate(time.Time{}))
+	assert.Empty(t, formatDateTime(time.Time{}))
+}
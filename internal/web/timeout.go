@@ -0,0 +1,134 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// timeoutMessage is the error shown when a request runs out of time.
+const timeoutMessage = "the server took too long to answer, try again"
+
+// RequestTimeouts bound how long a handler may take. The budget is a
+// deadline on the request's context, so database queries and outbound calls
+// made with it give up once it passes, and a handler that fails because of
+// it is answered with 503 instead of its own error.
+type RequestTimeouts struct {
+	Default time.Duration            // routes not in Routes; 0 means no limit
+	Routes  map[string]time.Duration // by mux pattern, e.g. "GET /areas"; 0 exempts the route
+}
+
+// DefaultRequestTimeouts returns the budgets used unless configured
+// otherwise: 15s for most routes, longer for those waiting on the model,
+// and none for the streamed recipe suggestions.
+func DefaultRequestTimeouts() RequestTimeouts {
+	return RequestTimeouts{
+		Default: 15 * time.Second,
+		Routes: map[string]time.Duration{
+			"POST /areas/{id}/photos":      2 * time.Minute,
+			"PUT /api/v1/areas/{id}/photo": 2 * time.Minute,
+			"GET /ask":                     2 * time.Minute,
+			"GET /export.pdf":              time.Minute,
+			"POST /suggest":                0,
+		},
+	}
+}
+
+// WithRequestTimeouts replaces the default request timeouts.
+func (s *Server) WithRequestTimeouts(t RequestTimeouts) *Server {
+	s.timeouts = t
+	return s
+}
+
+// budget returns the timeout for the route pattern.
+func (t RequestTimeouts) budget(pattern string) time.Duration {
+	if d, ok := t.Routes[pattern]; ok {
+		return d
+	}
+	return t.Default
+}
+
+// withTimeout applies the route's budget to each request. Uploads detach
+// their analysis from the request's context, so they can still succeed past
+// the deadline; only an error response is replaced.
+func (s *Server) withTimeout(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := s.mux.Handler(r)
+		d := s.timeouts.budget(pattern)
+		if d <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		r = r.WithContext(ctx)
+		tw := &timeoutWriter{ResponseWriter: w, ctx: ctx, header: w.Header().Clone(), onTimeout: func() {
+			s.log(r).Warn("request timed out", "route", pattern, "timeout", d)
+			s.renderError(w, r, http.StatusServiceUnavailable, timeoutMessage)
+		}}
+		next.ServeHTTP(tw, r)
+	})
+}
+
+// timeoutWriter holds back the handler's headers until its status is known.
+// An error status after the deadline has passed is swapped for the 503 from
+// onTimeout, and the rest of the handler's response is discarded.
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx       context.Context
+	header    http.Header
+	onTimeout func()
+
+	wroteHeader bool
+	discard     bool
+}
+
+func (t *timeoutWriter) Header() http.Header {
+	return t.header
+}
+
+func (t *timeoutWriter) WriteHeader(code int) {
+	if t.wroteHeader || code < http.StatusOK {
+		if !t.discard {
+			t.ResponseWriter.WriteHeader(code)
+		}
+		return
+	}
+	t.wroteHeader = true
+	if code >= http.StatusInternalServerError && errors.Is(t.ctx.Err(), context.DeadlineExceeded) {
+		t.discard = true
+		t.onTimeout()
+		return
+	}
+	h := t.ResponseWriter.Header()
+	clear(h)
+	for k, v := range t.header {
+		h[k] = v
+	}
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *timeoutWriter) Write(b []byte) (int, error) {
+	if !t.wroteHeader {
+		t.WriteHeader(http.StatusOK)
+	}
+	if t.discard {
+		return len(b), nil
+	}
+	return t.ResponseWriter.Write(b)
+}
+
+func (t *timeoutWriter) FlushError() error {
+	if !t.wroteHeader {
+		t.WriteHeader(http.StatusOK)
+	}
+	if t.discard {
+		return nil
+	}
+	return http.NewResponseController(t.ResponseWriter).Flush()
+}
+
+func (t *timeoutWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
@@ -0,0 +1,56 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vbonduro/kitchinv/internal/domain"
+)
+
+// slowOverrideService blocks listing override rules until the request's
+// context is done, as a query stuck behind a database lock would.
+type slowOverrideService struct {
+	fakeOverrideService
+}
+
+func (s *slowOverrideService) ListOverrideRules(ctx context.Context) ([]*domain.OverrideRule, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRequestTimeout(t *testing.T) {
+	srv := newOverrideTestServer(&slowOverrideService{}).WithRequestTimeouts(RequestTimeouts{Default: 20 * time.Millisecond})
+
+	req := httptest.NewRequest(http.MethodGet, "/overrides", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	srv.ServeHTTP(rec, req)
+
+	assert.Less(t, time.Since(start), 5*time.Second, "the deadline cancels the stuck query")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error":"`+timeoutMessage+`"}`, rec.Body.String())
+	assert.NotEmpty(t, rec.Header().Get("X-Content-Type-Options"), "headers set before the handler are kept")
+}
+
+func TestRequestTimeoutPerRoute(t *testing.T) {
+	srv := newOverrideTestServer(&fakeOverrideService{}).WithRequestTimeouts(RequestTimeouts{
+		Default: time.Nanosecond,
+		Routes:  map[string]time.Duration{"GET /overrides": 0},
+	})
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/overrides", nil))
+	assert.Equal(t, http.StatusOK, rec.Code, "an exempt route has no deadline")
+
+	// A handler that succeeds despite the deadline, as a detached upload
+	// can, keeps its response.
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/staples", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,210 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// selfSignedValidity is how long a generated certificate lasts. Apple
+// platforms reject certificates valid for longer than 825 days even when
+// trusted by hand.
+const selfSignedValidity = 825 * 24 * time.Hour
+
+// WithTLS serves HTTPS with the certificate and key in the given PEM files,
+// which are read when serving starts.
+func (s *Server) WithTLS(certFile, keyFile string) *Server {
+	s.tlsCertFile = certFile
+	s.tlsKeyFile = keyFile
+	return s
+}
+
+// WithHTTPRedirect also listens for plain HTTP on addr and redirects every
+// request to the same path over HTTPS. It has no effect without WithTLS.
+func (s *Server) WithHTTPRedirect(addr string) *Server {
+	s.redirectAddr = addr
+	return s
+}
+
+// tlsConfig loads the certificate configured with WithTLS and logs its
+// fingerprint, so it can be checked when trusting it by hand.
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(s.tlsCertFile, s.tlsKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse TLS certificate: %w", err)
+	}
+	s.logger.Info("serving HTTPS", "cert", s.tlsCertFile, "sha256_fingerprint", Fingerprint(leaf),
+		"names", strings.Join(leaf.DNSNames, ","), "expires", leaf.NotAfter.Format(time.DateOnly))
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of cert as browsers show it:
+// upper-case hex bytes separated by colons.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = strings.ToUpper(hex.EncodeToString([]byte{b}))
+	}
+	return strings.Join(parts, ":")
+}
+
+// redirectServer starts the WithHTTPRedirect listener, pointing it at the
+// port of the HTTPS listener at addr. It returns nil when there is nothing
+// to redirect to.
+func (s *Server) redirectServer(addr net.Addr) (*http.Server, error) {
+	if s.redirectAddr == "" || s.tlsCertFile == "" {
+		return nil, nil
+	}
+	port := ""
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		port = strconv.Itoa(tcp.Port)
+	}
+	ln, err := net.Listen("tcp", s.redirectAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for HTTP redirects: %w", err)
+	}
+	srv := &http.Server{
+		Handler:      httpsRedirect(port),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+	s.logger.Info("redirecting HTTP to HTTPS", "addr", ln.Addr().String())
+	go func() {
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("HTTP redirect listener failed", "error", err)
+		}
+	}()
+	return srv, nil
+}
+
+// httpsRedirect sends each request to the same host and path over HTTPS on
+// port, which is left out when it is the default 443.
+func httpsRedirect(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]" // IPv6 literal
+		}
+		if port != "" && port != "443" {
+			host += ":" + port
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// SelfSignedCert returns the paths of a self-signed certificate and key in
+// dir, generating them first if they are missing or the certificate has
+// expired. The certificate names localhost, this host and every address of
+// its network interfaces, so the LAN addresses it is reached at match. A
+// certificate is kept across restarts so it only has to be trusted once.
+func SelfSignedCert(dir string) (certFile, keyFile string, err error) {
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil && time.Now().Before(leaf.NotAfter) {
+			return certFile, keyFile, nil
+		}
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", "", fmt.Errorf("failed to create certificate directory: %w", err)
+	}
+	certPEM, keyPEM, err := generateSelfSigned(hostNames(), hostIPs(), time.Now())
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		return "", "", fmt.Errorf("failed to write key: %w", err)
+	}
+	if err := os.WriteFile(certFile, certPEM, 0o644); err != nil {
+		return "", "", fmt.Errorf("failed to write certificate: %w", err)
+	}
+	return certFile, keyFile, nil
+}
+
+// generateSelfSigned creates a PEM-encoded ECDSA P-256 certificate and key
+// for names and ips, valid from now.
+func generateSelfSigned(names []string, ips []net.IP, now time.Time) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: names[0], Organization: []string{"kitchinv"}},
+		NotBefore:             now.Add(-time.Hour), // tolerate clock skew between devices
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              names,
+		IPAddresses:           ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode key: %w", err)
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// hostNames returns localhost and this host's name, with its mDNS .local
+// form, which phones on the LAN can often resolve.
+func hostNames() []string {
+	names := []string{"localhost"}
+	if h, err := os.Hostname(); err == nil && h != "" && h != "localhost" {
+		h = strings.TrimSuffix(h, ".local")
+		names = append(names, h, h+".local")
+	}
+	return names
+}
+
+// hostIPs returns the loopback addresses and the address of every network
+// interface.
+func hostIPs() []net.IP {
+	ips := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ips
+	}
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() || ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		ips = append(ips, ipnet.IP)
+	}
+	return ips
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/web/templates"
+)
+
+func TestSelfSignedCertIsKept(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tls")
+	certFile, keyFile, err := SelfSignedCert(dir)
+	require.NoError(t, err)
+	first, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	info, err := os.Stat(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	require.NoError(t, err)
+	assert.Contains(t, leaf.DNSNames, "localhost")
+	assert.NoError(t, leaf.VerifyHostname("127.0.0.1"))
+	assert.Len(t, Fingerprint(leaf), 32*3-1, "32 hex pairs separated by colons")
+
+	_, _, err = SelfSignedCert(dir)
+	require.NoError(t, err)
+	second, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	assert.Equal(t, first, second, "a certificate is reused so it only has to be trusted once")
+}
+
+func TestServeTLS(t *testing.T) {
+	certFile, keyFile, err := SelfSignedCert(t.TempDir())
+	require.NoError(t, err)
+	pem, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(pem))
+
+	// Find a free port for the redirect listener.
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	redirectAddr := probe.Addr().String()
+	require.NoError(t, probe.Close())
+
+	ln, err := Listen("127.0.0.1:0", 0)
+	require.NoError(t, err)
+	srv := NewServer(&fakeOverrideService{}, templates.FS, nil, slog.Default()).
+		WithTLS(certFile, keyFile).
+		WithHTTPRedirect(redirectAddr)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, ln) }()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := client.Get("https://" + ln.Addr().String() + "/readyz")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	noFollow := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	require.Eventually(t, func() bool {
+		resp, err = noFollow.Get("http://" + redirectAddr + "/areas?q=milk")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	assert.Equal(t, "https://127.0.0.1:"+port+"/areas?q=milk", resp.Header.Get("Location"))
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	for _, tc := range []struct{ host, port, want string }{
+		{"kitchen.local", "443", "https://kitchen.local/areas"},
+		{"kitchen.local:80", "", "https://kitchen.local/areas"},
+		{"192.168.1.20:8080", "8443", "https://192.168.1.20:8443/areas"},
+		{"[fe80::1]:80", "8443", "https://[fe80::1]:8443/areas"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/areas", nil)
+		req.Host = tc.host
+		rec := httptest.NewRecorder()
+		httpsRedirect(tc.port).ServeHTTP(rec, req)
+		assert.Equal(t, tc.want, rec.Header().Get("Location"), tc.host)
+	}
+}
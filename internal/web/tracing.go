@@ -0,0 +1,38 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/vbonduro/kitchinv/internal/tracing"
+)
+
+// traceRequests records a server span for each request, joining the
+// caller's trace when it sends a traceparent header. It wraps the mux
+// directly so the span can be named after the route the mux matched.
+func traceRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !tracing.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := tracing.FromTraceparent(r.Context(), r.Header.Get("traceparent"))
+		ctx, span := tracing.Start(ctx, r.Method, tracing.KindServer)
+		defer span.End()
+		span.SetString("http.request.method", r.Method)
+		span.SetString("url.path", r.URL.Path)
+		span.SetString("client.address", clientIP(r))
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		r = r.WithContext(ctx)
+		next.ServeHTTP(rec, r)
+
+		if r.Pattern != "" {
+			span.SetName(r.Pattern)
+			span.SetString("http.route", r.Pattern)
+		}
+		span.SetInt("http.response.status_code", int64(rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.Fail(http.StatusText(rec.status))
+		}
+	})
+}
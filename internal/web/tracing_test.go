@@ -0,0 +1,29 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vbonduro/kitchinv/internal/tracing/tracingtest"
+)
+
+func TestRequestSpan(t *testing.T) {
+	srv := newOverrideTestServer(&fakeOverrideService{})
+	c := tracingtest.Start(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/overrides", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	srv.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := c.Spans()
+	require.Len(t, spans, 1)
+	span := tracingtest.Named(spans, "GET /overrides")
+	require.NotNil(t, span, "the span is named after the matched route")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.TraceID, "the caller's trace is continued")
+	assert.Equal(t, "00f067aa0ba902b7", span.ParentID)
+	assert.Equal(t, "200", span.Attrs["http.response.status_code"])
+	assert.Equal(t, "/overrides", span.Attrs["url.path"])
+}